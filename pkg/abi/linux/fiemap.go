@@ -0,0 +1,107 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// FIBMAP is the ioctl(2) request to map a logical block to a physical block,
+// from uapi/linux/fs.h.
+const FIBMAP = 1
+
+// FS_IOC_FIEMAP is the ioctl(2) request to map file extents, from
+// uapi/linux/fs.h.
+var FS_IOC_FIEMAP = IOC(_IOC_READ|_IOC_WRITE, 'f', 11, SizeOfFIEMAP)
+
+// Flags for FIEMAP.Flags, from uapi/linux/fiemap.h.
+const (
+	FIEMAP_FLAG_SYNC  = 0x00000001
+	FIEMAP_FLAG_XATTR = 0x00000002
+	FIEMAP_FLAG_CACHE = 0x00000004
+
+	FIEMAP_FLAGS_COMPAT = FIEMAP_FLAG_SYNC | FIEMAP_FLAG_XATTR
+)
+
+// Flags for FIEMAPExtent.Flags, from uapi/linux/fiemap.h.
+const (
+	FIEMAP_EXTENT_LAST           = 0x00000001
+	FIEMAP_EXTENT_UNKNOWN        = 0x00000002
+	FIEMAP_EXTENT_DELALLOC       = 0x00000004
+	FIEMAP_EXTENT_ENCODED        = 0x00000008
+	FIEMAP_EXTENT_DATA_ENCRYPTED = 0x00000080
+	FIEMAP_EXTENT_NOT_ALIGNED    = 0x00000100
+	FIEMAP_EXTENT_DATA_INLINE    = 0x00000200
+	FIEMAP_EXTENT_DATA_TAIL      = 0x00000400
+	FIEMAP_EXTENT_UNWRITTEN      = 0x00000800
+	FIEMAP_EXTENT_MERGED         = 0x00001000
+	FIEMAP_EXTENT_SHARED         = 0x00002000
+)
+
+// FIEMAP_MAX_OFFSET is the largest logical offset that may be queried.
+const FIEMAP_MAX_OFFSET = ^uint64(0)
+
+// FIEMAP_MAX_EXTENTS is the maximum value of FIEMAP.ExtentCount, from
+// include/linux/fiemap.h.
+const FIEMAP_MAX_EXTENTS = (1<<32 - 1) / SizeOfFIEMAPExtent
+
+// FIEMAP is struct fiemap, from uapi/linux/fiemap.h. It is followed in memory
+// by ExtentCount FIEMAPExtents.
+//
+// +marshal
+type FIEMAP struct {
+	// Start is the logical offset (inclusive) at which to start mapping.
+	Start uint64
+
+	// Length is the logical length of the mapping which userspace wants.
+	Length uint64
+
+	// Flags is the set of FIEMAP_FLAG_* flags for the request.
+	Flags uint32
+
+	// MappedExtents is the number of extents that were mapped (out).
+	MappedExtents uint32
+
+	// ExtentCount is the size of the extent array following this struct.
+	ExtentCount uint32
+
+	// Reserved is unused.
+	Reserved uint32
+}
+
+// SizeOfFIEMAP is the size of struct fiemap.
+const SizeOfFIEMAP = 32
+
+// FIEMAPExtent is struct fiemap_extent, from uapi/linux/fiemap.h.
+//
+// +marshal
+type FIEMAPExtent struct {
+	// Logical is the logical offset in bytes for the start of the extent.
+	Logical uint64
+
+	// Physical is the physical offset in bytes for the start of the extent.
+	Physical uint64
+
+	// Length is the length in bytes for the extent.
+	Length uint64
+
+	// Reserved64 is unused.
+	Reserved64 [2]uint64
+
+	// Flags is the set of FIEMAP_EXTENT_* flags for the extent.
+	Flags uint32
+
+	// Reserved is unused.
+	Reserved [3]uint32
+}
+
+// SizeOfFIEMAPExtent is the size of struct fiemap_extent.
+const SizeOfFIEMAPExtent = 56
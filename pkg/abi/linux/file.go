@@ -85,11 +85,98 @@ const (
 	UMOUNT_NOFOLLOW = 0x8
 )
 
+// Mount attributes, as reported by statmount(2).
+const (
+	MOUNT_ATTR_RDONLY      = 0x00000001
+	MOUNT_ATTR_NOSUID      = 0x00000002
+	MOUNT_ATTR_NODEV       = 0x00000004
+	MOUNT_ATTR_NOEXEC      = 0x00000008
+	MOUNT_ATTR__ATIME      = 0x00000070
+	MOUNT_ATTR_RELATIME    = 0x00000000
+	MOUNT_ATTR_NOATIME     = 0x00000010
+	MOUNT_ATTR_STRICTATIME = 0x00000020
+	MOUNT_ATTR_NODIRATIME  = 0x00000080
+	MOUNT_ATTR_IDMAP       = 0x00100000
+	MOUNT_ATTR_NOSYMFOLLOW = 0x00200000
+)
+
+// Constants for statmount(2) and listmount(2).
+const (
+	// MNT_ID_REQ_SIZE_VER0 is the size of the first published MntIDReq.
+	MNT_ID_REQ_SIZE_VER0 = 24
+
+	// Bits in MntIDReq.Param for statmount(2), and in Statmount.Mask.
+	STATMOUNT_SB_BASIC       = 0x00000001
+	STATMOUNT_MNT_BASIC      = 0x00000002
+	STATMOUNT_PROPAGATE_FROM = 0x00000004
+	STATMOUNT_MNT_ROOT       = 0x00000008
+	STATMOUNT_MNT_POINT      = 0x00000010
+	STATMOUNT_FS_TYPE        = 0x00000020
+
+	// LSMT_ROOT is the MntIDReq.MntID that lists mounts under the caller's
+	// root for listmount(2).
+	LSMT_ROOT = 0xffffffffffffffff
+)
+
+// Superblock flags reported by statmount(2).
+const (
+	SB_RDONLY      = MS_RDONLY
+	SB_SYNCHRONOUS = MS_SYNCHRONOUS
+	SB_DIRSYNC     = MS_DIRSYNC
+	SB_LAZYTIME    = 0x2000000
+)
+
+// MntIDReq is struct mnt_id_req, from include/uapi/linux/mount.h.
+//
+// +marshal
+type MntIDReq struct {
+	Size  uint32
+	Spare uint32
+	MntID uint64
+	Param uint64
+}
+
+// Statmount is struct statmount, from include/uapi/linux/mount.h, excluding
+// the variable-length string table that follows it. String fields (FSType,
+// MntRoot and MntPoint) are offsets into the string table.
+//
+// +marshal
+type Statmount struct {
+	Size           uint32
+	_              uint32
+	Mask           uint64
+	SbDevMajor     uint32
+	SbDevMinor     uint32
+	SbMagic        uint64
+	SbFlags        uint32
+	FSType         uint32
+	MntID          uint64
+	MntParentID    uint64
+	MntIDOld       uint32
+	MntParentIDOld uint32
+	MntAttr        uint64
+	MntPropagation uint64
+	MntPeerGroup   uint64
+	MntMaster      uint64
+	PropagateFrom  uint64
+	MntRoot        uint32
+	MntPoint       uint32
+	_              [50]uint64
+}
+
+// SizeOfStatmount is the size of a Statmount struct.
+var SizeOfStatmount = (*Statmount)(nil).SizeBytes()
+
 // Constants for unlinkat(2).
 const (
 	AT_REMOVEDIR = 0x200
 )
 
+// Constants for faccessat2(2).
+const (
+	AT_EACCESS = 0x200
+)
+
 // Constants for linkat(2) and fchownat(2).
 const (
 	AT_SYMLINK_FOLLOW = 0x400
@@ -238,6 +325,7 @@ const (
 	STATX_ATTR_NODUMP     = 0x00000040
 	STATX_ATTR_ENCRYPTED  = 0x00000800
 	STATX_ATTR_AUTOMOUNT  = 0x00001000
+	STATX_ATTR_VERITY     = 0x00100000
 )
 
 // Statx represents struct statx.
@@ -116,9 +116,17 @@ const (
 // Constants from uapi/linux/fs.h.
 const (
 	FS_IOC_GETFLAGS = 2148034049
+	FS_IOC_SETFLAGS = 1074292226
+	FS_IMMUTABLE_FL = 16
+	FS_APPEND_FL    = 32
 	FS_VERITY_FL    = 1048576
 )
 
+// SettableInodeFlags is the set of inode flags that FS_IOC_SETFLAGS can change
+// on filesystems that support it. It is not part of the Linux ABI; Linux
+// filesystems each support a different set.
+const SettableInodeFlags = FS_IMMUTABLE_FL | FS_APPEND_FL
+
 // Constants from uapi/linux/fsverity.h.
 const (
 	FS_VERITY_HASH_ALG_SHA256 = 1
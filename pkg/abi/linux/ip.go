@@ -41,6 +41,7 @@ const (
 	IPPROTO_UDPLITE = 136
 	IPPROTO_MPLS    = 137
 	IPPROTO_RAW     = 255
+	IPPROTO_MPTCP   = 262
 )
 
 // Socket options from uapi/linux/in.h
@@ -27,22 +27,38 @@ const (
 
 // Flags for mmap(2).
 const (
-	MAP_SHARED     = 1 << 0
-	MAP_PRIVATE    = 1 << 1
-	MAP_FIXED      = 1 << 4
-	MAP_ANONYMOUS  = 1 << 5
-	MAP_32BIT      = 1 << 6 // arch/x86/include/uapi/asm/mman.h
-	MAP_GROWSDOWN  = 1 << 8
-	MAP_DENYWRITE  = 1 << 11
-	MAP_EXECUTABLE = 1 << 12
-	MAP_LOCKED     = 1 << 13
-	MAP_NORESERVE  = 1 << 14
-	MAP_POPULATE   = 1 << 15
-	MAP_NONBLOCK   = 1 << 16
-	MAP_STACK      = 1 << 17
-	MAP_HUGETLB    = 1 << 18
+	MAP_SHARED          = 1 << 0
+	MAP_PRIVATE         = 1 << 1
+	MAP_SHARED_VALIDATE = MAP_SHARED | MAP_PRIVATE
+	MAP_TYPE            = 0xf
+	MAP_FIXED           = 1 << 4
+	MAP_ANONYMOUS       = 1 << 5
+	MAP_32BIT           = 1 << 6 // arch/x86/include/uapi/asm/mman.h
+	MAP_GROWSDOWN       = 1 << 8
+	MAP_DENYWRITE       = 1 << 11
+	MAP_EXECUTABLE      = 1 << 12
+	MAP_LOCKED          = 1 << 13
+	MAP_NORESERVE       = 1 << 14
+	MAP_POPULATE        = 1 << 15
+	MAP_NONBLOCK        = 1 << 16
+	MAP_STACK           = 1 << 17
+	MAP_HUGETLB         = 1 << 18
+	MAP_SYNC            = 1 << 19
+	MAP_FIXED_NOREPLACE = 1 << 20
+	MAP_UNINITIALIZED   = 1 << 26
+	MAP_HUGE_SHIFT      = 26
+	MAP_HUGE_MASK       = 0x3f
 )
 
+// LEGACY_MAP_MASK is the set of mmap(2) flags that predate
+// MAP_SHARED_VALIDATE. Flags outside this set are silently ignored with
+// MAP_SHARED, and rejected with MAP_SHARED_VALIDATE unless the mapped file
+// supports them. Equivalent to Linux's include/linux/mman.h:LEGACY_MAP_MASK.
+const LEGACY_MAP_MASK = MAP_SHARED | MAP_PRIVATE | MAP_FIXED | MAP_ANONYMOUS |
+	MAP_DENYWRITE | MAP_EXECUTABLE | MAP_UNINITIALIZED | MAP_GROWSDOWN |
+	MAP_LOCKED | MAP_NORESERVE | MAP_POPULATE | MAP_NONBLOCK | MAP_STACK |
+	MAP_HUGETLB | MAP_32BIT | MAP_HUGE_MASK<<MAP_HUGE_SHIFT
+
 // Flags for mremap(2).
 const (
 	MREMAP_MAYMOVE = 1 << 0
@@ -77,6 +93,8 @@ const (
 	MADV_NOHUGEPAGE   = 15
 	MADV_DONTDUMP     = 16
 	MADV_DODUMP       = 17
+	MADV_COLD         = 20
+	MADV_PAGEOUT      = 21
 	MADV_HWPOISON     = 100
 	MADV_SOFT_OFFLINE = 101
 	MADV_NOMAJFAULT   = 200
@@ -128,3 +146,17 @@ const (
 
 	MPOL_MF_VALID = MPOL_MF_STRICT | MPOL_MF_MOVE | MPOL_MF_MOVE_ALL
 )
+
+// OOM score adjustments, from uapi/linux/oom.h.
+const (
+	// OOM_SCORE_ADJ_MIN and OOM_SCORE_ADJ_MAX bound /proc/[pid]/oom_score_adj.
+	// A thread group with OOM_SCORE_ADJ_MIN is never chosen by the OOM killer.
+	OOM_SCORE_ADJ_MIN = -1000
+	OOM_SCORE_ADJ_MAX = 1000
+
+	// OOM_DISABLE, OOM_ADJUST_MIN and OOM_ADJUST_MAX bound the deprecated
+	// /proc/[pid]/oom_adj.
+	OOM_DISABLE    = -17
+	OOM_ADJUST_MIN = -16
+	OOM_ADJUST_MAX = 15
+)
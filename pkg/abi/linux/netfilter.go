@@ -402,6 +402,58 @@ type XTSNATTarget struct {
 // SizeOfXTSNATTarget is the size of an XTSNATTarget.
 const SizeOfXTSNATTarget = 56
 
+// Flags for the CT target, from include/uapi/linux/netfilter/xt_CT.h.
+const (
+	XT_CT_NOTRACK       = 1 << 0
+	XT_CT_NOTRACK_ALIAS = 1 << 1
+	XT_CT_ZONE_DIR_ORIG = 1 << 2
+	XT_CT_ZONE_DIR_REPL = 1 << 3
+	XT_CT_ZONE_MARK     = 1 << 4
+	XT_CT_MASK          = XT_CT_NOTRACK | XT_CT_NOTRACK_ALIAS | XT_CT_ZONE_DIR_ORIG | XT_CT_ZONE_DIR_REPL | XT_CT_ZONE_MARK
+)
+
+// XTCTTargetV0 configures connection tracking for packets when reached. It
+// corresponds to struct xt_ct_target_info in
+// include/uapi/linux/netfilter/xt_CT.h. Adding 4 bytes of padding to make CT
+// 8 byte aligned.
+//
+// +marshal
+type XTCTTargetV0 struct {
+	Target    XTEntryTarget
+	Flags     uint16
+	Zone      uint16
+	CTEvents  uint32
+	ExpEvents uint32
+	Helper    [16]byte
+	_         [4]byte
+	// CT is a kernel pointer and is ignored.
+	CT uint64
+}
+
+// SizeOfXTCTTargetV0 is the size of an XTCTTargetV0.
+const SizeOfXTCTTargetV0 = 72
+
+// XTCTTargetV1 is revisions 1 and 2 of the CT target. It corresponds to
+// struct xt_ct_target_info_v1 in include/uapi/linux/netfilter/xt_CT.h.
+// Adding 4 bytes of padding to make CT 8 byte aligned.
+//
+// +marshal
+type XTCTTargetV1 struct {
+	Target    XTEntryTarget
+	Flags     uint16
+	Zone      uint16
+	CTEvents  uint32
+	ExpEvents uint32
+	Helper    [16]byte
+	Timeout   [32]byte
+	_         [4]byte
+	// CT is a kernel pointer and is ignored.
+	CT uint64
+}
+
+// SizeOfXTCTTargetV1 is the size of an XTCTTargetV1.
+const SizeOfXTCTTargetV1 = 104
+
 // IPTGetinfo is the argument for the IPT_SO_GET_INFO sockopt. It corresponds
 // to struct ipt_getinfo in include/uapi/linux/netfilter_ipv4/ip_tables.h.
 //
@@ -165,6 +165,110 @@ const (
 	IFLA_GSO_MAX_SIZE    = 41
 )
 
+// Interface link info attributes, nested in IFLA_LINKINFO, from
+// uapi/linux/if_link.h.
+const (
+	IFLA_INFO_UNSPEC     = 0
+	IFLA_INFO_KIND       = 1
+	IFLA_INFO_DATA       = 2
+	IFLA_INFO_XSTATS     = 3
+	IFLA_INFO_SLAVE_KIND = 4
+	IFLA_INFO_SLAVE_DATA = 5
+)
+
+// Bond attributes, nested in IFLA_INFO_DATA of bond links, from
+// uapi/linux/if_link.h.
+const (
+	IFLA_BOND_UNSPEC           = 0
+	IFLA_BOND_MODE             = 1
+	IFLA_BOND_ACTIVE_SLAVE     = 2
+	IFLA_BOND_MIIMON           = 3
+	IFLA_BOND_UPDELAY          = 4
+	IFLA_BOND_DOWNDELAY        = 5
+	IFLA_BOND_USE_CARRIER      = 6
+	IFLA_BOND_ARP_INTERVAL     = 7
+	IFLA_BOND_ARP_IP_TARGET    = 8
+	IFLA_BOND_ARP_VALIDATE     = 9
+	IFLA_BOND_ARP_ALL_TARGETS  = 10
+	IFLA_BOND_PRIMARY          = 11
+	IFLA_BOND_PRIMARY_RESELECT = 12
+	IFLA_BOND_FAIL_OVER_MAC    = 13
+)
+
+// Bond slave attributes, nested in IFLA_INFO_SLAVE_DATA of links enslaved to
+// a bond, from uapi/linux/if_link.h.
+const (
+	IFLA_BOND_SLAVE_UNSPEC             = 0
+	IFLA_BOND_SLAVE_STATE              = 1
+	IFLA_BOND_SLAVE_MII_STATUS         = 2
+	IFLA_BOND_SLAVE_LINK_FAILURE_COUNT = 3
+	IFLA_BOND_SLAVE_PERM_HWADDR        = 4
+)
+
+// Bonding modes, from uapi/linux/if_bonding.h.
+const (
+	BOND_MODE_ROUNDROBIN   = 0
+	BOND_MODE_ACTIVEBACKUP = 1
+)
+
+// Bond slave states, from uapi/linux/if_bonding.h.
+const (
+	BOND_STATE_ACTIVE = 0
+	BOND_STATE_BACKUP = 1
+)
+
+// Bond slave link states, from uapi/linux/if_bonding.h.
+const (
+	BOND_LINK_UP   = 0
+	BOND_LINK_FAIL = 1
+	BOND_LINK_DOWN = 2
+	BOND_LINK_BACK = 3
+)
+
+// IP tunnel attributes, nested in IFLA_INFO_DATA of ipip links, from
+// uapi/linux/if_tunnel.h.
+const (
+	IFLA_IPTUN_UNSPEC      = 0
+	IFLA_IPTUN_LINK        = 1
+	IFLA_IPTUN_LOCAL       = 2
+	IFLA_IPTUN_REMOTE      = 3
+	IFLA_IPTUN_TTL         = 4
+	IFLA_IPTUN_TOS         = 5
+	IFLA_IPTUN_ENCAP_LIMIT = 6
+	IFLA_IPTUN_FLOWINFO    = 7
+	IFLA_IPTUN_FLAGS       = 8
+	IFLA_IPTUN_PROTO       = 9
+	IFLA_IPTUN_PMTUDISC    = 10
+)
+
+// GRE tunnel attributes, nested in IFLA_INFO_DATA of gre, gretap and ip6gre
+// links, from uapi/linux/if_tunnel.h.
+const (
+	IFLA_GRE_UNSPEC      = 0
+	IFLA_GRE_LINK        = 1
+	IFLA_GRE_IFLAGS      = 2
+	IFLA_GRE_OFLAGS      = 3
+	IFLA_GRE_IKEY        = 4
+	IFLA_GRE_OKEY        = 5
+	IFLA_GRE_LOCAL       = 6
+	IFLA_GRE_REMOTE      = 7
+	IFLA_GRE_TTL         = 8
+	IFLA_GRE_TOS         = 9
+	IFLA_GRE_PMTUDISC    = 10
+	IFLA_GRE_ENCAP_LIMIT = 11
+	IFLA_GRE_FLOWINFO    = 12
+	IFLA_GRE_FLAGS       = 13
+)
+
+// GRE flags, carried in network byte order by IFLA_GRE_IFLAGS and
+// IFLA_GRE_OFLAGS, from uapi/linux/if_tunnel.h.
+const (
+	GRE_CSUM    = 0x8000
+	GRE_ROUTING = 0x4000
+	GRE_KEY     = 0x2000
+	GRE_SEQ     = 0x1000
+)
+
 // InterfaceAddrMessage is struct ifaddrmsg, from uapi/linux/if_addr.h.
 //
 // +marshal
@@ -194,6 +298,9 @@ const (
 	ARPHRD_NONE     = 65534
 	ARPHRD_ETHER    = 1
 	ARPHRD_LOOPBACK = 772
+	ARPHRD_TUNNEL   = 768
+	ARPHRD_IPGRE    = 778
+	ARPHRD_IP6GRE   = 823
 )
 
 // RouteMessage is struct rtmsg, from uapi/linux/rtnetlink.h.
@@ -352,3 +459,80 @@ type RtAttr struct {
 
 // SizeOfRtAttr is the size of RtAttr.
 const SizeOfRtAttr = 4
+
+// NeighborMessage is struct ndmsg, from uapi/linux/neighbour.h.
+//
+// +marshal
+type NeighborMessage struct {
+	Family  uint8
+	_       [3]uint8
+	Ifindex int32
+	State   uint16
+	Flags   uint8
+	Type    uint8
+}
+
+// Neighbor attributes, from uapi/linux/neighbour.h.
+const (
+	NDA_UNSPEC       = 0
+	NDA_DST          = 1
+	NDA_LLADDR       = 2
+	NDA_CACHEINFO    = 3
+	NDA_PROBES       = 4
+	NDA_VLAN         = 5
+	NDA_PORT         = 6
+	NDA_VNI          = 7
+	NDA_IFINDEX      = 8
+	NDA_MASTER       = 9
+	NDA_LINK_NETNSID = 10
+	NDA_SRC_VNI      = 11
+	NDA_PROTOCOL     = 12
+)
+
+// Neighbor flags, from uapi/linux/neighbour.h.
+const (
+	NTF_USE         = 0x01
+	NTF_SELF        = 0x02
+	NTF_MASTER      = 0x04
+	NTF_PROXY       = 0x08
+	NTF_EXT_LEARNED = 0x10
+	NTF_OFFLOADED   = 0x20
+	NTF_STICKY      = 0x40
+	NTF_ROUTER      = 0x80
+)
+
+// Neighbor cache entry states, from uapi/linux/neighbour.h.
+const (
+	NUD_NONE       = 0x00
+	NUD_INCOMPLETE = 0x01
+	NUD_REACHABLE  = 0x02
+	NUD_STALE      = 0x04
+	NUD_DELAY      = 0x08
+	NUD_PROBE      = 0x10
+	NUD_FAILED     = 0x20
+	NUD_NOARP      = 0x40
+	NUD_PERMANENT  = 0x80
+)
+
+// rtnetlink multicast groups, from uapi/linux/rtnetlink.h.
+const (
+	RTNLGRP_NONE          = 0
+	RTNLGRP_LINK          = 1
+	RTNLGRP_NOTIFY        = 2
+	RTNLGRP_NEIGH         = 3
+	RTNLGRP_TC            = 4
+	RTNLGRP_IPV4_IFADDR   = 5
+	RTNLGRP_IPV4_MROUTE   = 6
+	RTNLGRP_IPV4_ROUTE    = 7
+	RTNLGRP_IPV4_RULE     = 8
+	RTNLGRP_IPV6_IFADDR   = 9
+	RTNLGRP_IPV6_MROUTE   = 10
+	RTNLGRP_IPV6_ROUTE    = 11
+	RTNLGRP_IPV6_IFINFO   = 12
+	RTNLGRP_DECnet_IFADDR = 13
+	RTNLGRP_DECnet_ROUTE  = 15
+	RTNLGRP_DECnet_RULE   = 16
+	RTNLGRP_IPV6_PREFIX   = 18
+	RTNLGRP_IPV6_RULE     = 19
+	RTNLGRP_ND_USEROPT    = 20
+)
@@ -149,8 +149,22 @@ const (
 	// specified) to ptrace the current task.
 	PR_SET_PTRACER     = 0x59616d61
 	PR_SET_PTRACER_ANY = -1
+
+	// PR_SET_VMA sets an attribute specified in arg2 for virtual memory
+	// areas starting from the address specified in arg3 and spanning the
+	// size specified in arg4.
+	PR_SET_VMA = 0x53564d41
+
+	// PR_SET_VMA_ANON_NAME sets a name for anonymous virtual memory areas.
+	// arg5 points to the NUL-terminated name, or is NULL to clear it.
+	PR_SET_VMA_ANON_NAME = 0
 )
 
+// ANON_VMA_NAME_MAX_LEN is the maximum length of a name set by
+// prctl(PR_SET_VMA_ANON_NAME), including the terminating NUL. From
+// kernel/sys.c.
+const ANON_VMA_NAME_MAX_LEN = 80
+
 // From <asm/prctl.h>
 // Flags are used in syscall arch_prctl(2).
 const (
@@ -0,0 +1,34 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// ioctl(2) request numbers from linux/random.h.
+var (
+	RNDGETENTCNT   = IOC(_IOC_READ, 'R', 0x00, 4)
+	RNDADDTOENTCNT = IOC(_IOC_WRITE, 'R', 0x01, 4)
+	RNDADDENTROPY  = IOC(_IOC_WRITE, 'R', 0x03, 8)
+	RNDZAPENTCNT   = IOC(_IOC_NONE, 'R', 0x04, 0)
+	RNDCLEARPOOL   = IOC(_IOC_NONE, 'R', 0x06, 0)
+)
+
+// RandPoolInfo is equivalent to the fixed-length header of struct
+// rand_pool_info, from linux/random.h. It is followed by BufSize bytes of
+// entropy.
+//
+// +marshal
+type RandPoolInfo struct {
+	EntropyCount int32
+	BufSize      int32
+}
@@ -186,28 +186,25 @@ const (
 	SS_DISABLE = 2
 )
 
-// SIGPOLL si_codes.
+// SIGPOLL si_codes, from include/uapi/asm-generic/siginfo.h.
 const (
-	// SI_POLL is defined as __SI_POLL in Linux 2.6.
-	SI_POLL = 2 << 16
-
 	// POLL_IN indicates that data input available.
-	POLL_IN = SI_POLL | 1
+	POLL_IN = 1
 
 	// POLL_OUT indicates that output buffers available.
-	POLL_OUT = SI_POLL | 2
+	POLL_OUT = 2
 
 	// POLL_MSG indicates that an input message available.
-	POLL_MSG = SI_POLL | 3
+	POLL_MSG = 3
 
 	// POLL_ERR indicates that there was an i/o error.
-	POLL_ERR = SI_POLL | 4
+	POLL_ERR = 4
 
 	// POLL_PRI indicates that a high priority input available.
-	POLL_PRI = SI_POLL | 5
+	POLL_PRI = 5
 
 	// POLL_HUP indicates that a device disconnected.
-	POLL_HUP = SI_POLL | 6
+	POLL_HUP = 6
 )
 
 // Possible values for si_code.
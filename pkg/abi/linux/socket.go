@@ -101,6 +101,7 @@ const (
 	SOL_RAW     = 255
 	SOL_PACKET  = 263
 	SOL_NETLINK = 270
+	SOL_MPTCP   = 284
 )
 
 // A SockType is a type (as opposed to family) of sockets. These are enumerated
@@ -502,6 +503,7 @@ var SizeOfTCPInfo = (*TCPInfo)(nil).SizeBytes()
 const (
 	SCM_CREDENTIALS = 0x2
 	SCM_RIGHTS      = 0x1
+	SCM_SECURITY    = 0x3
 )
 
 // A ControlMessageHeader is the header for a socket control message.
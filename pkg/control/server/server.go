@@ -128,6 +128,11 @@ func (s *Server) Register(obj interface{}) {
 	s.server.Register(obj)
 }
 
+// Methods returns the sorted names of all registered control methods.
+func (s *Server) Methods() []string {
+	return s.server.Methods()
+}
+
 // CreateFromFD creates a new control bound to the given 'fd'. It has no
 // registered interfaces and will not start serving until StartServing is
 // called.
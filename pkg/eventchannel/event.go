@@ -22,6 +22,7 @@ package eventchannel
 import (
 	"encoding/binary"
 	"fmt"
+	"os"
 
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
@@ -137,21 +138,30 @@ func SocketEmitter(fd int) (Emitter, error) {
 	}, nil
 }
 
-// Emit implements Emitter.Emit.
-func (s *socketEmitter) Emit(msg proto.Message) (bool, error) {
+// marshalEvent returns msg in the event channel wire format, which is the
+// uvarint length of the marshaled Any wrapping msg, followed by the marshaled
+// Any.
+func marshalEvent(msg proto.Message) ([]byte, error) {
 	any, err := newAny(msg)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	bufMsg, err := proto.Marshal(any)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// Wire format is uvarint message length followed by binary proto.
 	p := make([]byte, binary.MaxVarintLen64)
 	n := binary.PutUvarint(p, uint64(len(bufMsg)))
-	p = append(p[:n], bufMsg...)
+	return append(p[:n], bufMsg...), nil
+}
+
+// Emit implements Emitter.Emit.
+func (s *socketEmitter) Emit(msg proto.Message) (bool, error) {
+	p, err := marshalEvent(msg)
+	if err != nil {
+		return false, err
+	}
 	for done := 0; done < len(p); {
 		n, err := s.socket.Write(p[done:])
 		if err != nil {
@@ -168,6 +178,39 @@ func (s *socketEmitter) Close() error {
 	return s.socket.Close()
 }
 
+// fileEmitter emits proto messages to a file, using the same wire format as
+// socketEmitter.
+type fileEmitter struct {
+	// mu serializes writes so that messages are not interleaved.
+	mu   sync.Mutex
+	file *os.File
+}
+
+// FileEmitter creates a new event channel that appends events to f.
+//
+// FileEmitter takes ownership of f.
+func FileEmitter(f *os.File) Emitter {
+	return &fileEmitter{file: f}
+}
+
+// Emit implements Emitter.Emit.
+func (f *fileEmitter) Emit(msg proto.Message) (bool, error) {
+	p, err := marshalEvent(msg)
+	if err != nil {
+		return false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// os.File.Write only returns once all of p is written or on error.
+	_, err = f.file.Write(p)
+	return false, err
+}
+
+// Close implements Emitter.Close.
+func (f *fileEmitter) Close() error {
+	return f.file.Close()
+}
+
 // debugEmitter wraps an emitter to emit stringified event messages. This is
 // useful for debugging -- when the messages are intended for humans.
 type debugEmitter struct {
@@ -15,11 +15,15 @@
 package eventchannel
 
 import (
+	"encoding/binary"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	pb "gvisor.dev/gvisor/pkg/eventchannel/eventchannel_go_proto"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
@@ -144,3 +148,49 @@ func TestRateLimitedEmitter(t *testing.T) {
 		t.Errorf("got %d events, want at most %d", got, wantAtMost)
 	}
 }
+
+func TestFileEmitter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "events")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	fe := FileEmitter(f)
+	want := []*pb.DebugEvent{
+		{Name: "first", Text: "1"},
+		{Name: "second", Text: "2"},
+	}
+	for _, ev := range want {
+		if _, err := fe.Emit(ev); err != nil {
+			t.Fatalf("Emit failed: %v", err)
+		}
+	}
+	if err := fe.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	buf, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	for i, w := range want {
+		l, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < l {
+			t.Fatalf("event %d: invalid length prefix", i)
+		}
+		var any anypb.Any
+		if err := proto.Unmarshal(buf[n:n+int(l)], &any); err != nil {
+			t.Fatalf("event %d: Unmarshal failed: %v", i, err)
+		}
+		var got pb.DebugEvent
+		if err := any.UnmarshalTo(&got); err != nil {
+			t.Fatalf("event %d: UnmarshalTo failed: %v", i, err)
+		}
+		if !proto.Equal(&got, w) {
+			t.Errorf("event %d: got %v, want %v", i, &got, w)
+		}
+		buf = buf[n+int(l):]
+	}
+	if len(buf) != 0 {
+		t.Errorf("got %d trailing bytes", len(buf))
+	}
+}
@@ -17,6 +17,7 @@ package p9
 import (
 	"fmt"
 	"io"
+	"math"
 	"sync/atomic"
 
 	"golang.org/x/sys/unix"
@@ -160,6 +161,18 @@ func (c *clientFile) FSync() error {
 	return c.client.sendRecv(&Tfsync{FID: c.fid}, &Rfsync{})
 }
 
+// SyncFS implements File.SyncFS.
+func (c *clientFile) SyncFS() error {
+	if atomic.LoadUint32(&c.closed) != 0 {
+		return unix.EBADF
+	}
+	if !versionSupportsTsyncfs(c.client.version) {
+		return unix.EOPNOTSUPP
+	}
+
+	return c.client.sendRecv(&Tsyncfs{FID: c.fid}, &Rsyncfs{})
+}
+
 // GetAttr implements File.GetAttr.
 func (c *clientFile) GetAttr(req AttrMask) (QID, AttrMask, Attr, error) {
 	if atomic.LoadUint32(&c.closed) != 0 {
@@ -257,6 +270,34 @@ func (c *clientFile) Allocate(mode AllocateMode, offset, length uint64) error {
 	return c.client.sendRecv(&Tallocate{FID: c.fid, Mode: mode, Offset: offset, Length: length}, &Rallocate{})
 }
 
+// GetFlags implements File.GetFlags.
+func (c *clientFile) GetFlags() (uint32, error) {
+	if atomic.LoadUint32(&c.closed) != 0 {
+		return 0, unix.EBADF
+	}
+	if !versionSupportsTgetsetflags(c.client.version) {
+		return 0, unix.EOPNOTSUPP
+	}
+
+	rgetflags := Rgetflags{}
+	if err := c.client.sendRecv(&Tgetflags{FID: c.fid}, &rgetflags); err != nil {
+		return 0, err
+	}
+	return rgetflags.Flags, nil
+}
+
+// SetFlags implements File.SetFlags.
+func (c *clientFile) SetFlags(flags uint32) error {
+	if atomic.LoadUint32(&c.closed) != 0 {
+		return unix.EBADF
+	}
+	if !versionSupportsTgetsetflags(c.client.version) {
+		return unix.EOPNOTSUPP
+	}
+
+	return c.client.sendRecv(&Tsetflags{FID: c.fid, Flags: flags}, &Rsetflags{})
+}
+
 // Remove implements File.Remove.
 //
 // N.B. This method is no longer part of the file interface and should be
@@ -301,6 +342,95 @@ func (c *clientFile) Close() error {
 	return nil
 }
 
+// CloseFiles closes all of files, using a single round trip per batch of files
+// obtained from c if the server supports it. It returns nil if all files were
+// closed successfully; otherwise it returns one error per file, in the same
+// order as files, with nil entries for files that were closed successfully.
+//
+// As with File.Close, files may not be used after CloseFiles returns,
+// regardless of whether closing them succeeded.
+func (c *Client) CloseFiles(files []File) []error {
+	var errs []error
+	setErr := func(i int, err error) {
+		if errs == nil {
+			errs = make([]error, len(files))
+		}
+		errs[i] = err
+	}
+
+	if !versionSupportsTmulticlunk(c.version) {
+		for i, f := range files {
+			if err := f.Close(); err != nil {
+				setErr(i, err)
+			}
+		}
+		return errs
+	}
+
+	// Each FID and each per-FID result takes 4 bytes, and both lists are
+	// prefixed with a 2-byte count.
+	maxFIDs := int(c.payloadSize / 4)
+	if maxFIDs > math.MaxUint16 {
+		maxFIDs = math.MaxUint16
+	}
+	var (
+		fids []FID
+		idxs []int
+	)
+	flush := func() {
+		if len(fids) == 0 {
+			return
+		}
+		var r Rmulticlunk
+		if err := c.sendRecv(&Tmulticlunk{FIDs: fids}, &r); err != nil {
+			// As in clientFile.Close, toss away FIDs whose state is unknown.
+			log.Warningf("Tmulticlunk failed, losing FIDs %v: %v", fids, err)
+			for _, i := range idxs {
+				setErr(i, err)
+			}
+		} else {
+			for j, i := range idxs {
+				if j >= len(r.Errors) {
+					log.Warningf("Tmulticlunk returned %d results for %d FIDs, losing FID %v", len(r.Errors), len(fids), fids[j])
+					setErr(i, unix.EIO)
+					continue
+				}
+				if r.Errors[j] != 0 {
+					err := unix.Errno(r.Errors[j])
+					log.Warningf("Tmulticlunk failed, losing FID %v: %v", fids[j], err)
+					setErr(i, err)
+					continue
+				}
+				c.fidPool.Put(uint64(fids[j]))
+			}
+		}
+		fids = fids[:0]
+		idxs = idxs[:0]
+	}
+
+	for i, f := range files {
+		cf, ok := f.(*clientFile)
+		if !ok || cf.client != c {
+			if err := f.Close(); err != nil {
+				setErr(i, err)
+			}
+			continue
+		}
+		// Avoid double close.
+		if !atomic.CompareAndSwapUint32(&cf.closed, 0, 1) {
+			setErr(i, unix.EBADF)
+			continue
+		}
+		fids = append(fids, cf.fid)
+		idxs = append(idxs, i)
+		if len(fids) == maxFIDs {
+			flush()
+		}
+	}
+	flush()
+	return errs
+}
+
 // SetAttrClose implements File.SetAttrClose.
 func (c *clientFile) SetAttrClose(valid SetAttrMask, attr SetAttr) error {
 	if !versionSupportsTsetattrclunk(c.client.version) {
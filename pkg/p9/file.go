@@ -135,6 +135,20 @@ type File interface {
 	// for the file. See fallocate(2) for more details.
 	Allocate(mode AllocateMode, offset, length uint64) error
 
+	// GetFlags returns the inode flags of this node, as reported by Linux's
+	// FS_IOC_GETFLAGS ioctl.
+	//
+	// On the server, GetFlags has a read concurrency guarantee.
+	GetFlags() (uint32, error)
+
+	// SetFlags sets the inode flags of this node, as for Linux's
+	// FS_IOC_SETFLAGS ioctl. Servers may support only a subset of flags, and
+	// fail with EOPNOTSUPP if other flags are set. Callers are responsible
+	// for checking that the caller is permitted to change the flags.
+	//
+	// On the server, SetFlags has a write concurrency guarantee.
+	SetFlags(flags uint32) error
+
 	// Close is called when all references are dropped on the server side,
 	// and Close should be called by the client to drop all references.
 	//
@@ -189,6 +203,12 @@ type File interface {
 	// On the server, FSync has a read concurrency guarantee.
 	FSync() error
 
+	// SyncFS syncs the filesystem containing this node, as for Linux's
+	// syncfs(2).
+	//
+	// On the server, SyncFS has a read concurrency guarantee.
+	SyncFS() error
+
 	// Create creates a new regular file and opens it according to the
 	// flags given. This file is already Open.
 	//
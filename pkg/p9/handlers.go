@@ -167,6 +167,17 @@ func (t *Tclunk) handle(cs *connState) message {
 	return &Rclunk{}
 }
 
+// handle implements handler.handle.
+func (t *Tmulticlunk) handle(cs *connState) message {
+	errs := make([]uint32, len(t.FIDs))
+	for i, fid := range t.FIDs {
+		if !cs.DeleteFID(fid) {
+			errs[i] = uint32(unix.EBADF)
+		}
+	}
+	return &Rmulticlunk{Errors: errs}
+}
+
 func (t *Tsetattrclunk) handle(cs *connState) message {
 	ref, ok := cs.LookupFID(t.FID)
 	if !ok {
@@ -1128,6 +1139,23 @@ func (t *Tfsync) handle(cs *connState) message {
 	return &Rfsync{}
 }
 
+// handle implements handler.handle.
+func (t *Tsyncfs) handle(cs *connState) message {
+	ref, ok := cs.LookupFID(t.FID)
+	if !ok {
+		return newErr(unix.EBADF)
+	}
+	defer ref.DecRef()
+
+	if err := ref.safelyRead(func() error {
+		return ref.file.SyncFS()
+	}); err != nil {
+		return newErr(err)
+	}
+
+	return &Rsyncfs{}
+}
+
 // handle implements handler.handle.
 func (t *Tstatfs) handle(cs *connState) message {
 	ref, ok := cs.LookupFID(t.FID)
@@ -1493,3 +1521,45 @@ func (t *Tmultigetattr) handle(cs *connState) message {
 	}
 	return &Rmultigetattr{Stats: stats}
 }
+
+// handle implements handler.handle.
+func (t *Tgetflags) handle(cs *connState) message {
+	ref, ok := cs.LookupFID(t.FID)
+	if !ok {
+		return newErr(unix.EBADF)
+	}
+	defer ref.DecRef()
+
+	var flags uint32
+	if err := ref.safelyRead(func() (err error) {
+		// Don't allow getflags on files that have been deleted.
+		if ref.isDeleted() {
+			return unix.EINVAL
+		}
+		flags, err = ref.file.GetFlags()
+		return err
+	}); err != nil {
+		return newErr(err)
+	}
+	return &Rgetflags{Flags: flags}
+}
+
+// handle implements handler.handle.
+func (t *Tsetflags) handle(cs *connState) message {
+	ref, ok := cs.LookupFID(t.FID)
+	if !ok {
+		return newErr(unix.EBADF)
+	}
+	defer ref.DecRef()
+
+	if err := ref.safelyWrite(func() error {
+		// Don't allow setflags on files that have been deleted.
+		if ref.isDeleted() {
+			return unix.EINVAL
+		}
+		return ref.file.SetFlags(t.Flags)
+	}); err != nil {
+		return newErr(err)
+	}
+	return &Rsetflags{}
+}
@@ -2626,6 +2626,233 @@ func (r *Rmultigetattr) String() string {
 	return fmt.Sprintf("Rmultigetattr{Stats: %v}", r.Stats)
 }
 
+// Tgetflags is a request to get the inode flags (as reported by Linux's
+// FS_IOC_GETFLAGS ioctl) of a file. This is an extension to 9P protocol, not
+// present in the 9P2000.L standard.
+type Tgetflags struct {
+	// FID is the FID to get flags for.
+	FID FID
+}
+
+// decode implements encoder.decode.
+func (t *Tgetflags) decode(b *buffer) {
+	t.FID = b.ReadFID()
+}
+
+// encode implements encoder.encode.
+func (t *Tgetflags) encode(b *buffer) {
+	b.WriteFID(t.FID)
+}
+
+// Type implements message.Type.
+func (*Tgetflags) Type() MsgType {
+	return MsgTgetflags
+}
+
+// String implements fmt.Stringer.
+func (t *Tgetflags) String() string {
+	return fmt.Sprintf("Tgetflags{FID: %d}", t.FID)
+}
+
+// Rgetflags is a getflags response.
+type Rgetflags struct {
+	// Flags are the file's inode flags.
+	Flags uint32
+}
+
+// decode implements encoder.decode.
+func (r *Rgetflags) decode(b *buffer) {
+	r.Flags = b.Read32()
+}
+
+// encode implements encoder.encode.
+func (r *Rgetflags) encode(b *buffer) {
+	b.Write32(r.Flags)
+}
+
+// Type implements message.Type.
+func (*Rgetflags) Type() MsgType {
+	return MsgRgetflags
+}
+
+// String implements fmt.Stringer.
+func (r *Rgetflags) String() string {
+	return fmt.Sprintf("Rgetflags{Flags: %#x}", r.Flags)
+}
+
+// Tsetflags is a request to set the inode flags (as for Linux's
+// FS_IOC_SETFLAGS ioctl) of a file. This is an extension to 9P protocol, not
+// present in the 9P2000.L standard.
+type Tsetflags struct {
+	// FID is the FID to set flags for.
+	FID FID
+
+	// Flags are the new inode flags.
+	Flags uint32
+}
+
+// decode implements encoder.decode.
+func (t *Tsetflags) decode(b *buffer) {
+	t.FID = b.ReadFID()
+	t.Flags = b.Read32()
+}
+
+// encode implements encoder.encode.
+func (t *Tsetflags) encode(b *buffer) {
+	b.WriteFID(t.FID)
+	b.Write32(t.Flags)
+}
+
+// Type implements message.Type.
+func (*Tsetflags) Type() MsgType {
+	return MsgTsetflags
+}
+
+// String implements fmt.Stringer.
+func (t *Tsetflags) String() string {
+	return fmt.Sprintf("Tsetflags{FID: %d, Flags: %#x}", t.FID, t.Flags)
+}
+
+// Rsetflags is a setflags response.
+type Rsetflags struct {
+}
+
+// decode implements encoder.decode.
+func (*Rsetflags) decode(*buffer) {
+}
+
+// encode implements encoder.encode.
+func (*Rsetflags) encode(*buffer) {
+}
+
+// Type implements message.Type.
+func (*Rsetflags) Type() MsgType {
+	return MsgRsetflags
+}
+
+// String implements fmt.Stringer.
+func (r *Rsetflags) String() string {
+	return "Rsetflags{}"
+}
+
+// Tsyncfs is a request to sync the filesystem containing a file. This is an
+// extension to 9P protocol, not present in the 9P2000.L standard.
+type Tsyncfs struct {
+	// FID is the FID of a file in the filesystem to sync.
+	FID FID
+}
+
+// decode implements encoder.decode.
+func (t *Tsyncfs) decode(b *buffer) {
+	t.FID = b.ReadFID()
+}
+
+// encode implements encoder.encode.
+func (t *Tsyncfs) encode(b *buffer) {
+	b.WriteFID(t.FID)
+}
+
+// Type implements message.Type.
+func (*Tsyncfs) Type() MsgType {
+	return MsgTsyncfs
+}
+
+// String implements fmt.Stringer.
+func (t *Tsyncfs) String() string {
+	return fmt.Sprintf("Tsyncfs{FID: %d}", t.FID)
+}
+
+// Rsyncfs is a syncfs response.
+type Rsyncfs struct {
+}
+
+// decode implements encoder.decode.
+func (*Rsyncfs) decode(*buffer) {
+}
+
+// encode implements encoder.encode.
+func (*Rsyncfs) encode(*buffer) {
+}
+
+// Type implements message.Type.
+func (*Rsyncfs) Type() MsgType {
+	return MsgRsyncfs
+}
+
+// String implements fmt.Stringer.
+func (r *Rsyncfs) String() string {
+	return "Rsyncfs{}"
+}
+
+// Tmulticlunk is a request to close multiple FIDs. This is an extension to
+// 9P protocol, not present in the 9P2000.L standard.
+type Tmulticlunk struct {
+	// FIDs are the FIDs to be closed.
+	FIDs []FID
+}
+
+// decode implements encoder.decode.
+func (t *Tmulticlunk) decode(b *buffer) {
+	n := b.Read16()
+	t.FIDs = t.FIDs[:0]
+	for i := 0; i < int(n); i++ {
+		t.FIDs = append(t.FIDs, b.ReadFID())
+	}
+}
+
+// encode implements encoder.encode.
+func (t *Tmulticlunk) encode(b *buffer) {
+	b.Write16(uint16(len(t.FIDs)))
+	for _, fid := range t.FIDs {
+		b.WriteFID(fid)
+	}
+}
+
+// Type implements message.Type.
+func (*Tmulticlunk) Type() MsgType {
+	return MsgTmulticlunk
+}
+
+// String implements fmt.Stringer.
+func (t *Tmulticlunk) String() string {
+	return fmt.Sprintf("Tmulticlunk{FIDs: %v}", t.FIDs)
+}
+
+// Rmulticlunk is a multiclunk response.
+type Rmulticlunk struct {
+	// Errors contains the result of closing each of the FIDs in the
+	// request, in the same order: 0 if the FID was closed successfully, or
+	// an errno otherwise. In either case the FID is no longer valid.
+	Errors []uint32
+}
+
+// decode implements encoder.decode.
+func (r *Rmulticlunk) decode(b *buffer) {
+	n := b.Read16()
+	r.Errors = r.Errors[:0]
+	for i := 0; i < int(n); i++ {
+		r.Errors = append(r.Errors, b.Read32())
+	}
+}
+
+// encode implements encoder.encode.
+func (r *Rmulticlunk) encode(b *buffer) {
+	b.Write16(uint16(len(r.Errors)))
+	for _, errno := range r.Errors {
+		b.Write32(errno)
+	}
+}
+
+// Type implements message.Type.
+func (*Rmulticlunk) Type() MsgType {
+	return MsgRmulticlunk
+}
+
+// String implements fmt.Stringer.
+func (r *Rmulticlunk) String() string {
+	return fmt.Sprintf("Rmulticlunk{Errors: %v}", r.Errors)
+}
+
 const maxCacheSize = 3
 
 // msgFactory is used to reduce allocations by caching messages for reuse.
@@ -2793,6 +3020,14 @@ func init() {
 	msgRegistry.register(MsgRsetattrclunk, func() message { return &Rsetattrclunk{} })
 	msgRegistry.register(MsgTmultigetattr, func() message { return &Tmultigetattr{} })
 	msgRegistry.register(MsgRmultigetattr, func() message { return &Rmultigetattr{} })
+	msgRegistry.register(MsgTgetflags, func() message { return &Tgetflags{} })
+	msgRegistry.register(MsgRgetflags, func() message { return &Rgetflags{} })
+	msgRegistry.register(MsgTsetflags, func() message { return &Tsetflags{} })
+	msgRegistry.register(MsgRsetflags, func() message { return &Rsetflags{} })
+	msgRegistry.register(MsgTsyncfs, func() message { return &Tsyncfs{} })
+	msgRegistry.register(MsgRsyncfs, func() message { return &Rsyncfs{} })
+	msgRegistry.register(MsgTmulticlunk, func() message { return &Tmulticlunk{} })
+	msgRegistry.register(MsgRmulticlunk, func() message { return &Rmulticlunk{} })
 	msgRegistry.register(MsgTchannel, func() message { return &Tchannel{} })
 	msgRegistry.register(MsgRchannel, func() message { return &Rchannel{} })
 }
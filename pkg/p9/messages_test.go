@@ -400,6 +400,25 @@ func TestEncodeDecode(t *testing.T) {
 				MTimeNanoSeconds: 8,
 			},
 		},
+		&Tgetflags{
+			FID: 1,
+		},
+		&Rgetflags{
+			Flags: 2,
+		},
+		&Tsetflags{
+			FID:   1,
+			Flags: 2,
+		},
+		&Tsyncfs{
+			FID: 1,
+		},
+		&Tmulticlunk{
+			FIDs: []FID{1, 2, 3},
+		},
+		&Rmulticlunk{
+			Errors: []uint32{0, 9 /* EBADF */, 0},
+		},
 	}
 
 	for _, enc := range objs {
@@ -404,6 +404,14 @@ const (
 	MsgRsetattrclunk MsgType = 141
 	MsgTmultigetattr MsgType = 142
 	MsgRmultigetattr MsgType = 143
+	MsgTgetflags     MsgType = 144
+	MsgRgetflags     MsgType = 145
+	MsgTsetflags     MsgType = 146
+	MsgRsetflags     MsgType = 147
+	MsgTsyncfs       MsgType = 148
+	MsgRsyncfs       MsgType = 149
+	MsgTmulticlunk   MsgType = 150
+	MsgRmulticlunk   MsgType = 151
 	MsgTchannel      MsgType = 250
 	MsgRchannel      MsgType = 251
 )
@@ -2250,3 +2250,54 @@ func TestReadWriteConcurrent(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCloseFiles(t *testing.T) {
+	h, c := NewHarness(t)
+	defer h.Finish()
+
+	const numFiles = 100
+	contents := make(map[string]Generator)
+	for i := 0; i < numFiles; i++ {
+		contents[fmt.Sprintf("file%d", i)] = h.NewFile()
+	}
+	root := h.NewDirectory(contents)(nil)
+	h.Attacher.EXPECT().Attach().Return(root, nil).Times(1)
+	rootFile, err := c.Attach("/")
+	if err != nil {
+		t.Fatalf("attach got err %v, want nil", err)
+	}
+	defer rootFile.Close()
+
+	files := make([]p9.File, 0, numFiles)
+	mocks := make([]*Mock, 0, numFiles)
+	for i := 0; i < numFiles; i++ {
+		_, f, err := rootFile.Walk([]string{fmt.Sprintf("file%d", i)})
+		if err != nil {
+			t.Fatalf("walk got err %v, want nil", err)
+		}
+		files = append(files, f)
+		mocks = append(mocks, h.Pop(f))
+	}
+
+	if errs := c.CloseFiles(files); errs != nil {
+		t.Fatalf("CloseFiles got errs %v, want nil", errs)
+	}
+	// All server-side files should have been released before CloseFiles
+	// returned, rather than when the client disconnects.
+	for i, m := range mocks {
+		if !m.closed {
+			t.Errorf("file%d not closed on the server", i)
+		}
+	}
+
+	// Closing the same files again must fail without reaching the server.
+	errs := c.CloseFiles(files)
+	if len(errs) != numFiles {
+		t.Fatalf("CloseFiles got %d errors, want %d", len(errs), numFiles)
+	}
+	for i, err := range errs {
+		if err != unix.EBADF {
+			t.Errorf("CloseFiles got err %v for file%d, want EBADF", err, i)
+		}
+	}
+}
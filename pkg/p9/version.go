@@ -26,7 +26,7 @@ const (
 	//
 	// Clients are expected to start requesting this version number and
 	// to continuously decrement it until a Tversion request succeeds.
-	highestSupportedVersion uint32 = 13
+	highestSupportedVersion uint32 = 16
 
 	// lowestSupportedVersion is the lowest supported version X in a
 	// version string of the format 9P2000.L.Google.X.
@@ -185,3 +185,21 @@ func versionSupportsTsetattrclunk(v uint32) bool {
 func versionSupportsTmultiGetAttr(v uint32) bool {
 	return v >= 13
 }
+
+// versionSupportsTgetsetflags returns true if version v supports the
+// Tgetflags and Tsetflags messages.
+func versionSupportsTgetsetflags(v uint32) bool {
+	return v >= 14
+}
+
+// versionSupportsTsyncfs returns true if version v supports the Tsyncfs
+// message.
+func versionSupportsTsyncfs(v uint32) bool {
+	return v >= 15
+}
+
+// versionSupportsTmulticlunk returns true if version v supports the
+// Tmulticlunk message.
+func versionSupportsTmulticlunk(v uint32) bool {
+	return v >= 16
+}
@@ -43,6 +43,20 @@ type Profile struct {
 	// mutexMu protects mutex profiling.
 	mutexMu sync.Mutex
 
+	// ratesMu protects the fields below.
+	ratesMu sync.Mutex
+
+	// blockRate and mutexFraction are the block profile rate and mutex
+	// profile fraction in effect while the corresponding profile isn't being
+	// collected. They are set by SetContentionRates.
+	blockRate     int
+	mutexFraction int
+
+	// blockProfiling and mutexProfiling are true while a block or mutex
+	// profile is being collected, respectively.
+	blockProfiling bool
+	mutexProfiling bool
+
 	// traceMu protects trace profiling.
 	traceMu sync.Mutex
 
@@ -184,8 +198,8 @@ func (p *Profile) Block(o *BlockProfileOpts, _ *struct{}) error {
 	if o.Rate != 0 {
 		rate = o.Rate
 	}
-	runtime.SetBlockProfileRate(rate)
-	defer runtime.SetBlockProfileRate(0)
+	p.startBlockProfile(rate)
+	defer p.stopBlockProfile()
 
 	// Collect the profile.
 	select {
@@ -226,8 +240,8 @@ func (p *Profile) Mutex(o *MutexProfileOpts, _ *struct{}) error {
 	if o.Fraction != 0 {
 		fraction = o.Fraction
 	}
-	runtime.SetMutexProfileFraction(fraction)
-	defer runtime.SetMutexProfileFraction(0)
+	p.startMutexProfile(fraction)
+	defer p.stopMutexProfile()
 
 	// Collect the profile.
 	select {
@@ -238,6 +252,138 @@ func (p *Profile) Mutex(o *MutexProfileOpts, _ *struct{}) error {
 	return pprof.Lookup("mutex").WriteTo(output, 0)
 }
 
+// ContentionProfileOpts contains options specifically for contention
+// profiles.
+type ContentionProfileOpts struct {
+	// FilePayload contains the destinations for the block and mutex profiles,
+	// in that order.
+	urpc.FilePayload
+
+	// Duration is the duration of the profile.
+	Duration time.Duration `json:"duration"`
+
+	// BlockRate is the block profile rate.
+	BlockRate int `json:"block_rate"`
+
+	// MutexFraction is the mutex profile fraction.
+	MutexFraction int `json:"mutex_fraction"`
+}
+
+// Contention collects block and mutex profiles over the same period.
+func (p *Profile) Contention(o *ContentionProfileOpts, _ *struct{}) error {
+	if len(o.FilePayload.Files) < 2 {
+		return nil // Allowed.
+	}
+
+	blockOutput := o.FilePayload.Files[0]
+	defer blockOutput.Close()
+	mutexOutput := o.FilePayload.Files[1]
+	defer mutexOutput.Close()
+
+	p.blockMu.Lock()
+	defer p.blockMu.Unlock()
+	p.mutexMu.Lock()
+	defer p.mutexMu.Unlock()
+
+	// Use the same defaults as Block and Mutex.
+	rate := 10
+	if o.BlockRate != 0 {
+		rate = o.BlockRate
+	}
+	fraction := 10
+	if o.MutexFraction != 0 {
+		fraction = o.MutexFraction
+	}
+	p.startBlockProfile(rate)
+	defer p.stopBlockProfile()
+	p.startMutexProfile(fraction)
+	defer p.stopMutexProfile()
+
+	// Collect the profiles.
+	select {
+	case <-time.After(o.Duration):
+	case <-p.done:
+	}
+
+	if err := pprof.Lookup("block").WriteTo(blockOutput, 0); err != nil {
+		return err
+	}
+	return pprof.Lookup("mutex").WriteTo(mutexOutput, 0)
+}
+
+// ContentionRates contains the block profile rate and mutex profile fraction.
+type ContentionRates struct {
+	// BlockRate is the block profile rate, as for
+	// runtime.SetBlockProfileRate. If negative, the rate is not changed.
+	BlockRate int `json:"block_rate"`
+
+	// MutexFraction is the mutex profile fraction, as for
+	// runtime.SetMutexProfileFraction. If negative, the fraction is not
+	// changed.
+	MutexFraction int `json:"mutex_fraction"`
+}
+
+// SetContentionRates is an RPC stub which sets the block profile rate and
+// mutex profile fraction used while no block or mutex profile is being
+// collected, allowing contention to be profiled continuously. It returns the
+// resulting rates.
+//
+// Profiles collected by Block, Mutex and Contention temporarily override these
+// rates, which are restored when collection completes.
+func (p *Profile) SetContentionRates(o *ContentionRates, out *ContentionRates) error {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	if o.BlockRate >= 0 {
+		p.blockRate = o.BlockRate
+		if !p.blockProfiling {
+			runtime.SetBlockProfileRate(p.blockRate)
+		}
+	}
+	if o.MutexFraction >= 0 {
+		p.mutexFraction = o.MutexFraction
+		if !p.mutexProfiling {
+			runtime.SetMutexProfileFraction(p.mutexFraction)
+		}
+	}
+	*out = ContentionRates{
+		BlockRate:     p.blockRate,
+		MutexFraction: p.mutexFraction,
+	}
+	return nil
+}
+
+// Preconditions: p.blockMu must be locked.
+func (p *Profile) startBlockProfile(rate int) {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	p.blockProfiling = true
+	runtime.SetBlockProfileRate(rate)
+}
+
+// Preconditions: p.blockMu must be locked.
+func (p *Profile) stopBlockProfile() {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	p.blockProfiling = false
+	runtime.SetBlockProfileRate(p.blockRate)
+}
+
+// Preconditions: p.mutexMu must be locked.
+func (p *Profile) startMutexProfile(fraction int) {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	p.mutexProfiling = true
+	runtime.SetMutexProfileFraction(fraction)
+}
+
+// Preconditions: p.mutexMu must be locked.
+func (p *Profile) stopMutexProfile() {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	p.mutexProfiling = false
+	runtime.SetMutexProfileFraction(p.mutexFraction)
+}
+
 // TraceProfileOpts contains options specifically for traces.
 type TraceProfileOpts struct {
 	// FilePayload is the destination for the profiling output.
@@ -0,0 +1,70 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/urpc"
+)
+
+func TestSetContentionRates(t *testing.T) {
+	p := NewProfile(nil)
+	defer runtime.SetBlockProfileRate(0)
+	defer runtime.SetMutexProfileFraction(0)
+
+	var got ContentionRates
+	if err := p.SetContentionRates(&ContentionRates{BlockRate: 100, MutexFraction: 5}, &got); err != nil {
+		t.Fatalf("SetContentionRates failed: %v", err)
+	}
+	if want := (ContentionRates{BlockRate: 100, MutexFraction: 5}); got != want {
+		t.Errorf("SetContentionRates: got %+v, want %+v", got, want)
+	}
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 5 {
+		t.Errorf("got mutex profile fraction %d, want 5", fraction)
+	}
+
+	// Negative values leave the rates unchanged.
+	if err := p.SetContentionRates(&ContentionRates{BlockRate: -1, MutexFraction: 7}, &got); err != nil {
+		t.Fatalf("SetContentionRates failed: %v", err)
+	}
+	if want := (ContentionRates{BlockRate: 100, MutexFraction: 7}); got != want {
+		t.Errorf("SetContentionRates: got %+v, want %+v", got, want)
+	}
+
+	// Collecting a profile restores the rates when done.
+	blockFile, err := os.CreateTemp(t.TempDir(), "block")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	mutexFile, err := os.CreateTemp(t.TempDir(), "mutex")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	opts := ContentionProfileOpts{
+		FilePayload:   urpc.FilePayload{Files: []*os.File{blockFile, mutexFile}},
+		Duration:      time.Millisecond,
+		MutexFraction: 1,
+	}
+	if err := p.Contention(&opts, nil); err != nil {
+		t.Fatalf("Contention failed: %v", err)
+	}
+	if fraction := runtime.SetMutexProfileFraction(-1); fraction != 7 {
+		t.Errorf("got mutex profile fraction %d after profiling, want 7", fraction)
+	}
+}
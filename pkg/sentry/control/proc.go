@@ -102,6 +102,70 @@ type ExecArgs struct {
 
 	// Limits is the limit set for the process being executed.
 	Limits *limits.LimitSet
+
+	// ExcludeNamespaces is the set of namespaces, named as in /proc/[pid]/ns,
+	// that the new process does not join. The new process is instead given a
+	// new namespace of each excluded type: a copy of the UTS namespace and of
+	// the mount tree, an empty IPC namespace, or a child PID namespace of
+	// which it is the init process.
+	ExcludeNamespaces []string `json:"exclude_namespaces"`
+
+	// UIDMap and GIDMap, if not empty, cause the new process to run in a new
+	// user namespace, a child of the root user namespace, with the given ID
+	// mappings. KUID and KGID must be mapped in the new user namespace.
+	UIDMap []auth.IDMapEntry `json:"uid_map"`
+	GIDMap []auth.IDMapEntry `json:"gid_map"`
+
+	// ResolveBeneath optionally confines path resolution by the new process.
+	// See kernel.CreateProcessArgs.ResolveBeneath.
+	ResolveBeneath string
+}
+
+// Names of namespaces, as in /proc/[pid]/ns.
+const (
+	NamespaceIPC   = "ipc"
+	NamespaceMount = "mnt"
+	NamespacePID   = "pid"
+	NamespaceUTS   = "uts"
+	NamespaceUser  = "user"
+)
+
+// excludedNamespaces validates args.ExcludeNamespaces and returns it as a
+// set.
+func (args *ExecArgs) excludedNamespaces() (map[string]struct{}, error) {
+	excluded := make(map[string]struct{}, len(args.ExcludeNamespaces))
+	for _, ns := range args.ExcludeNamespaces {
+		switch ns {
+		case NamespaceIPC, NamespacePID, NamespaceUTS:
+		case NamespaceMount:
+			// Without VFS2, the new process's filesystem context (its root
+			// and working directory) is taken from the container's mount
+			// namespace, which cannot be copied.
+			if !kernel.VFS2Enabled || args.MountNamespace != nil {
+				return nil, fmt.Errorf("excluding the %q namespace requires VFS2", ns)
+			}
+		case NamespaceUser:
+			return nil, fmt.Errorf("the %q namespace cannot be excluded, specify a user namespace mapping instead", ns)
+		default:
+			return nil, fmt.Errorf("the %q namespace cannot be excluded", ns)
+		}
+		excluded[ns] = struct{}{}
+	}
+	if (len(args.UIDMap) == 0) != (len(args.GIDMap) == 0) {
+		return nil, fmt.Errorf("both or neither of the UID and GID maps must be specified")
+	}
+	return excluded, nil
+}
+
+// ExecResult is the result of starting a new process.
+type ExecResult struct {
+	// PID is the thread group ID of the new process in the root PID
+	// namespace.
+	PID int32 `json:"pid"`
+
+	// Namespaces maps the names of the new process's namespaces, as in
+	// /proc/[pid]/ns, to their inode numbers.
+	Namespaces map[string]uint64 `json:"namespaces"`
 }
 
 // String prints the arguments as a string.
@@ -119,7 +183,7 @@ func (args ExecArgs) String() string {
 
 // Exec runs a new task.
 func (proc *Proc) Exec(args *ExecArgs, waitStatus *uint32) error {
-	newTG, _, _, _, err := proc.execAsync(args)
+	newTG, _, _, _, _, err := proc.execAsync(args)
 	if err != nil {
 		return err
 	}
@@ -132,14 +196,28 @@ func (proc *Proc) Exec(args *ExecArgs, waitStatus *uint32) error {
 
 // ExecAsync runs a new task, but doesn't wait for it to finish. It is defined
 // as a function rather than a method to avoid exposing execAsync as an RPC.
-func ExecAsync(proc *Proc, args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID, *host.TTYFileOperations, *hostvfs2.TTYFileDescription, error) {
+func ExecAsync(proc *Proc, args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID, map[string]uint64, *host.TTYFileOperations, *hostvfs2.TTYFileDescription, error) {
 	return proc.execAsync(args)
 }
 
 // execAsync runs a new task, but doesn't wait for it to finish. It returns the
-// newly created thread group and its PID. If the stdio FDs are TTYs, then a
-// TTYFileOperations that wraps the TTY is also returned.
-func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID, *host.TTYFileOperations, *hostvfs2.TTYFileDescription, error) {
+// newly created thread group, its PID and the inode numbers of its namespaces.
+// If the stdio FDs are TTYs, then a TTYFileOperations that wraps the TTY is
+// also returned.
+func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID, map[string]uint64, *host.TTYFileOperations, *hostvfs2.TTYFileDescription, error) {
+	excluded, err := args.excludedNamespaces()
+	if err != nil {
+		return nil, 0, nil, nil, nil, err
+	}
+
+	userns := proc.Kernel.RootUserNamespace()
+	if len(args.UIDMap) != 0 {
+		userns, err = proc.newUserNamespace(args)
+		if err != nil {
+			return nil, 0, nil, nil, nil, err
+		}
+	}
+
 	// Import file descriptors.
 	fdTable := proc.Kernel.NewFDTable()
 
@@ -148,12 +226,25 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 		args.KGID,
 		args.ExtraKGIDs,
 		args.Capabilities,
-		proc.Kernel.RootUserNamespace())
+		userns)
 
 	pidns := args.PIDNamespace
 	if pidns == nil {
 		pidns = proc.Kernel.RootPIDNamespace()
 	}
+	if _, ok := excluded[NamespacePID]; ok {
+		pidns = pidns.NewChild(userns)
+	}
+	utsns := proc.Kernel.RootUTSNamespace()
+	if _, ok := excluded[NamespaceUTS]; ok {
+		utsns = utsns.Clone(userns)
+	}
+	var ipcns *kernel.IPCNamespace
+	if _, ok := excluded[NamespaceIPC]; ok {
+		ipcns = kernel.NewIPCNamespace(userns)
+	} else {
+		ipcns = proc.Kernel.RootIPCNamespace()
+	}
 	limitSet := args.Limits
 	if limitSet == nil {
 		limitSet = limits.NewLimitSet()
@@ -170,11 +261,12 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 		Umask:                   0022,
 		Limits:                  limitSet,
 		MaxSymlinkTraversals:    linux.MaxSymlinkTraversals,
-		UTSNamespace:            proc.Kernel.RootUTSNamespace(),
-		IPCNamespace:            proc.Kernel.RootIPCNamespace(),
+		UTSNamespace:            utsns,
+		IPCNamespace:            ipcns,
 		AbstractSocketNamespace: proc.Kernel.RootAbstractSocketNamespace(),
 		ContainerID:             args.ContainerID,
 		PIDNamespace:            pidns,
+		ResolveBeneath:          args.ResolveBeneath,
 	}
 	if initArgs.MountNamespace != nil {
 		// initArgs must hold a reference on MountNamespace, which will
@@ -198,6 +290,12 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 			initArgs.MountNamespaceVFS2 = proc.Kernel.GlobalInit().Leader().MountNamespaceVFS2()
 			initArgs.MountNamespaceVFS2.IncRef()
 		}
+		if _, ok := excluded[NamespaceMount]; ok {
+			// Replace the donated reference with the one on the copy.
+			mntns := proc.Kernel.VFS().CloneMountNamespace(ctx, userns, initArgs.MountNamespaceVFS2)
+			initArgs.MountNamespaceVFS2.DecRef(ctx)
+			initArgs.MountNamespaceVFS2 = mntns
+		}
 	} else {
 		if initArgs.MountNamespace == nil {
 			// Set initArgs so that 'ctx' returns the namespace.
@@ -210,13 +308,13 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 	}
 	resolved, err := user.ResolveExecutablePath(ctx, &initArgs)
 	if err != nil {
-		return nil, 0, nil, nil, err
+		return nil, 0, nil, nil, nil, err
 	}
 	initArgs.Filename = resolved
 
 	fds, err := fd.NewFromFiles(args.Files)
 	if err != nil {
-		return nil, 0, nil, nil, fmt.Errorf("duplicating payload files: %w", err)
+		return nil, 0, nil, nil, nil, fmt.Errorf("duplicating payload files: %w", err)
 	}
 	defer func() {
 		for _, fd := range fds {
@@ -225,12 +323,21 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 	}()
 	ttyFile, ttyFileVFS2, err := fdimport.Import(ctx, fdTable, args.StdioIsPty, args.KUID, args.KGID, fds)
 	if err != nil {
-		return nil, 0, nil, nil, err
+		return nil, 0, nil, nil, nil, err
 	}
 
 	tg, tid, err := proc.Kernel.CreateProcess(initArgs)
 	if err != nil {
-		return nil, 0, nil, nil, err
+		return nil, 0, nil, nil, nil, err
+	}
+	namespaces := map[string]uint64{
+		NamespaceIPC:  ipcns.Inode(),
+		NamespacePID:  pidns.Inode(),
+		NamespaceUTS:  utsns.Inode(),
+		NamespaceUser: userns.Inode(),
+	}
+	if initArgs.MountNamespaceVFS2 != nil {
+		namespaces[NamespaceMount] = initArgs.MountNamespaceVFS2.Inode()
 	}
 
 	// Set the foreground process group on the TTY before starting the process.
@@ -244,7 +351,28 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 	// Start the newly created process.
 	proc.Kernel.StartProcess(tg)
 
-	return tg, tid, ttyFile, ttyFileVFS2, nil
+	return tg, tid, namespaces, ttyFile, ttyFileVFS2, nil
+}
+
+// newUserNamespace returns a new child of the root user namespace with the
+// ID mappings given by args.
+func (proc *Proc) newUserNamespace(args *ExecArgs) (*auth.UserNamespace, error) {
+	rootCreds := auth.NewRootCredentials(proc.Kernel.RootUserNamespace())
+	userns, err := rootCreds.NewChildUserNamespace()
+	if err != nil {
+		return nil, err
+	}
+	ctx := auth.ContextWithCredentials(proc.Kernel.SupervisorContext(), rootCreds)
+	if err := userns.SetUIDMap(ctx, args.UIDMap); err != nil {
+		return nil, fmt.Errorf("setting UID map: %w", err)
+	}
+	if err := userns.SetGIDMap(ctx, args.GIDMap); err != nil {
+		return nil, fmt.Errorf("setting GID map: %w", err)
+	}
+	if !args.KUID.In(userns).Ok() || !args.KGID.In(userns).Ok() {
+		return nil, fmt.Errorf("UID %d and GID %d must be mapped in the new user namespace", args.KUID, args.KGID)
+	}
+	return userns, nil
 }
 
 // PsArgs is the set of arguments to ps.
@@ -29,7 +29,7 @@ func Register(vfsObj *vfs.VirtualFilesystem) error {
 		nullDevMinor:    nullDevice{},
 		zeroDevMinor:    zeroDevice{},
 		fullDevMinor:    fullDevice{},
-		randomDevMinor:  randomDevice{},
+		randomDevMinor:  randomDevice{blocking: true},
 		urandomDevMinor: randomDevice{},
 	} {
 		if err := vfsObj.RegisterDevice(vfs.CharDevice, linux.MEM_MAJOR, minor, dev, &vfs.RegisterDeviceOptions{
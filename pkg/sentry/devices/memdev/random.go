@@ -15,13 +15,21 @@
 package memdev
 
 import (
+	"io"
 	"sync/atomic"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/syserror"
 	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 const (
@@ -32,11 +40,18 @@ const (
 // randomDevice implements vfs.Device for /dev/random and /dev/urandom.
 //
 // +stateify savable
-type randomDevice struct{}
+type randomDevice struct {
+	// blocking is true for /dev/random, whose reads block while the entropy
+	// pool is unseeded, and false for /dev/urandom.
+	blocking bool
+}
 
 // Open implements vfs.Device.Open.
-func (randomDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
-	fd := &randomFD{}
+func (dev randomDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &randomFD{
+		pool:     kernel.KernelFromContext(ctx).EntropyPool(),
+		blocking: dev.blocking,
+	}
 	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
 		UseDentryMetadata: true,
 	}); err != nil {
@@ -54,6 +69,12 @@ type randomFD struct {
 	vfs.DentryMetadataFileDescriptionImpl
 	vfs.NoLockFD
 
+	// pool is the kernel's entropy pool.
+	pool *kernel.EntropyPool
+
+	// blocking is copied from randomDevice.blocking.
+	blocking bool
+
 	// off is the "file offset". off is accessed using atomic memory
 	// operations.
 	off int64
@@ -66,11 +87,17 @@ func (fd *randomFD) Release(context.Context) {
 
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *randomFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	if fd.blocking && !fd.pool.Seeded() {
+		return 0, syserror.ErrWouldBlock
+	}
 	return dst.CopyOutFrom(ctx, safemem.FromIOReader{rand.Reader})
 }
 
 // Read implements vfs.FileDescriptionImpl.Read.
 func (fd *randomFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	if fd.blocking && !fd.pool.Seeded() {
+		return 0, syserror.ErrWouldBlock
+	}
 	n, err := dst.CopyOutFrom(ctx, safemem.FromIOReader{rand.Reader})
 	atomic.AddInt64(&fd.off, n)
 	return n, err
@@ -95,3 +122,94 @@ func (fd *randomFD) Seek(ctx context.Context, offset int64, whence int32) (int64
 	// == noop_llseek
 	return atomic.LoadInt64(&fd.off), nil
 }
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *randomFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	ready := waiter.ReadableEvents | waiter.WritableEvents
+	if fd.blocking && !fd.pool.Seeded() {
+		ready &^= waiter.ReadableEvents
+	}
+	return mask & ready
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *randomFD) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	if fd.blocking {
+		fd.pool.EventRegister(e, mask)
+	}
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *randomFD) EventUnregister(e *waiter.Entry) {
+	if fd.blocking {
+		fd.pool.EventUnregister(e)
+	}
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *randomFD) Ioctl(ctx context.Context, uio usermem.IO, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+
+	// Linux: drivers/char/random.c:random_ioctl().
+	addr := args[2].Pointer()
+	switch args[1].Uint() {
+	case linux.RNDGETENTCNT:
+		bits := primitive.Int32(fd.pool.Bits())
+		_, err := bits.CopyOut(t, addr)
+		return 0, err
+
+	case linux.RNDADDTOENTCNT:
+		if !t.HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		var bits primitive.Int32
+		if _, err := bits.CopyIn(t, addr); err != nil {
+			return 0, err
+		}
+		if bits < 0 {
+			return 0, linuxerr.EINVAL
+		}
+		fd.pool.Credit(int32(bits))
+		return 0, nil
+
+	case linux.RNDADDENTROPY:
+		if !t.HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		var info linux.RandPoolInfo
+		if _, err := info.CopyIn(t, addr); err != nil {
+			return 0, err
+		}
+		if info.EntropyCount < 0 || info.BufSize < 0 {
+			return 0, linuxerr.EINVAL
+		}
+		// As for writes, the entropy itself is thrown away, but it must
+		// still be readable.
+		bufAddr, ok := addr.AddLength(uint64(info.SizeBytes()))
+		if !ok {
+			return 0, linuxerr.EFAULT
+		}
+		src, err := t.SingleIOSequence(bufAddr, int(info.BufSize), usermem.IOOpts{})
+		if err != nil {
+			return 0, err
+		}
+		if _, err := src.CopyInTo(t, safemem.FromIOWriter{io.Discard}); err != nil {
+			return 0, err
+		}
+		fd.pool.Credit(info.EntropyCount)
+		return 0, nil
+
+	case linux.RNDZAPENTCNT, linux.RNDCLEARPOOL:
+		if !t.HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		fd.pool.Zap()
+		return 0, nil
+
+	default:
+		return 0, linuxerr.EINVAL
+	}
+}
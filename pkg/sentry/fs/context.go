@@ -29,6 +29,10 @@ const (
 
 	// CtxDirentCacheLimiter is a Context.Value key for DirentCacheLimiter.
 	CtxDirentCacheLimiter
+
+	// CtxResolveBeneath is a Context.Value key for a Dirent beneath which
+	// all path resolution must remain.
+	CtxResolveBeneath
 )
 
 // ContextCanAccessFile determines whether `file` can be accessed in the requested way
@@ -104,6 +108,17 @@ func RootFromContext(ctx context.Context) *Dirent {
 	return nil
 }
 
+// ResolveBeneathFromContext returns the Dirent beneath which path resolution
+// performed by ctx is confined, or nil if resolution is not confined. If
+// ResolveBeneathFromContext returns a non-nil fs.Dirent, a reference is taken
+// on it.
+func ResolveBeneathFromContext(ctx context.Context) *Dirent {
+	if v := ctx.Value(CtxResolveBeneath); v != nil {
+		return v.(*Dirent)
+	}
+	return nil
+}
+
 // DirentCacheLimiterFromContext returns the DirentCacheLimiter used by ctx, or
 // nil if ctx does not have a dirent cache limiter.
 func DirentCacheLimiterFromContext(ctx context.Context) *DirentCacheLimiter {
@@ -300,16 +300,20 @@ func (d *Dirent) hashChildParentSet(child *Dirent) (*refs.WeakRef, bool) {
 
 // SyncAll iterates through mount points under d and writes back their buffered
 // modifications to filesystems.
-func (d *Dirent) SyncAll(ctx context.Context) {
+func (d *Dirent) SyncAll(ctx context.Context) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	// For negative Dirents there is nothing to sync. By definition these are
 	// leaves (there is nothing left to traverse).
 	if d.IsNegative() {
-		return
+		return nil
 	}
 
+	// Return the first error we encounter, but sync everything we can
+	// regardless.
+	var retErr error
+
 	// There is nothing to sync for a read-only filesystem.
 	if !d.Inode.MountSource.Flags.ReadOnly {
 		// NOTE(b/34856369): This should be a mount traversal, not a Dirent
@@ -318,16 +322,22 @@ func (d *Dirent) SyncAll(ctx context.Context) {
 		//
 		// Write out metadata, dirty page cached pages, and sync disk/remote
 		// caches.
-		d.Inode.WriteOut(ctx)
+		if err := d.Inode.WriteOut(ctx); err != nil {
+			ctx.Infof("fs.Dirent.SyncAll: Inode.WriteOut failed: %v", err)
+			retErr = err
+		}
 	}
 
 	// Continue iterating through other mounted filesystems.
 	for _, w := range d.children {
 		if child := w.Get(); child != nil {
-			child.(*Dirent).SyncAll(ctx)
+			if err := child.(*Dirent).SyncAll(ctx); err != nil && retErr == nil {
+				retErr = err
+			}
 			child.DecRef(ctx)
 		}
 	}
+	return retErr
 }
 
 // BaseName returns the base name of the dirent.
@@ -392,6 +402,13 @@ func (d *Dirent) MountRoot() *Dirent {
 	return mountRoot
 }
 
+// IsDescendantOf returns true if d is equal to, or a descendant of, p.
+func (d *Dirent) IsDescendantOf(p *Dirent) bool {
+	renameMu.RLock()
+	defer renameMu.RUnlock()
+	return d.descendantOf(p)
+}
+
 // descendantOf returns true if the receiver dirent is equal to, or a
 // descendant of, the argument dirent.
 //
@@ -440,6 +457,12 @@ func (d *Dirent) walk(ctx context.Context, root *Dirent, name string, walkMayUnl
 		return d.parent, nil
 	}
 
+	// A deleted directory can't have children. See Linux's
+	// fs/namei.c:__lookup_slow.
+	if atomic.LoadInt32(&d.deleted) != 0 {
+		return nil, unix.ENOENT
+	}
+
 	if w, ok := d.children[name]; ok {
 		// Try to resolve the weak reference to a hard reference.
 		if child := w.Get(); child != nil {
@@ -615,6 +638,11 @@ func (d *Dirent) Create(ctx context.Context, root *Dirent, name string, flags Fi
 	d.lockDirectory()
 	defer d.unlockDirectory()
 
+	// Nothing can be created in a deleted directory.
+	if atomic.LoadInt32(&d.deleted) != 0 {
+		return nil, unix.ENOENT
+	}
+
 	// Does something already exist?
 	if d.exists(ctx, root, name) {
 		return nil, unix.EEXIST
@@ -678,6 +706,11 @@ func (d *Dirent) genericCreate(ctx context.Context, root *Dirent, name string, c
 	d.lockDirectory()
 	defer d.unlockDirectory()
 
+	// Nothing can be created in a deleted directory.
+	if atomic.LoadInt32(&d.deleted) != 0 {
+		return unix.ENOENT
+	}
+
 	// Does something already exist?
 	if d.exists(ctx, root, name) {
 		return unix.EEXIST
@@ -1118,6 +1151,12 @@ func (d *Dirent) RemoveDirectory(ctx context.Context, root *Dirent, name string)
 		return unix.EBUSY
 	}
 
+	// Hold child.dirMu while removing child, so that a concurrent creation in
+	// child either completes first (and the removal fails with ENOTEMPTY), or
+	// sees child.deleted and fails with ENOENT rather than creating an orphan.
+	child.dirMu.Lock()
+	defer child.dirMu.Unlock()
+
 	// Try to remove name on the file system.
 	if err := d.Inode.Remove(ctx, d, child); err != nil {
 		return err
@@ -97,8 +97,10 @@ type File struct {
 	// FileOperations implements file system specific behavior for this File.
 	FileOperations FileOperations `state:"wait"`
 
-	// offset is the File's offset. Updating offset is protected by mu but
-	// can be read atomically via File.Offset() outside of mu.
+	// offset is the File's offset. Reading and updating offset during
+	// Readv, Writev, Seek, Readdir and Splice is protected by mu, so that
+	// concurrent calls each observe and advance the offset atomically.
+	// offset can also be read atomically via File.Offset() outside of mu.
 	offset int64
 }
 
@@ -449,7 +451,10 @@ func (f *File) InodeID() uint64 {
 }
 
 // Msync implements memmap.MappingIdentity.Msync.
-func (f *File) Msync(ctx context.Context, mr memmap.MappableRange) error {
+func (f *File) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
+	if !opts.Sync {
+		return nil
+	}
 	return f.Fsync(ctx, int64(mr.Start), int64(mr.End-1), SyncData)
 }
 
@@ -547,6 +547,6 @@ func (omi *overlayMappingIdentity) MappedName(ctx context.Context) string {
 }
 
 // Msync implements MappingIdentity.Msync.
-func (omi *overlayMappingIdentity) Msync(ctx context.Context, mr memmap.MappableRange) error {
-	return omi.id.Msync(ctx, mr)
+func (omi *overlayMappingIdentity) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
+	return omi.id.Msync(ctx, mr, opts)
 }
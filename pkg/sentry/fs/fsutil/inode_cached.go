@@ -373,11 +373,13 @@ func (c *CachingInodeOperations) Allocate(ctx context.Context, offset, length in
 	c.dataMu.Lock()
 	defer c.dataMu.Unlock()
 
+	now := ktime.NowFromContext(ctx)
 	if newSize <= c.attr.Size {
+		// The size doesn't change, but Linux still updates ctime.
+		c.touchStatusChangeTimeLocked(now)
 		return nil
 	}
 
-	now := ktime.NowFromContext(ctx)
 	if err := c.backingFile.Allocate(ctx, offset, length); err != nil {
 		return err
 	}
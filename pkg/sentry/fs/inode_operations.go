@@ -243,6 +243,10 @@ type InodeOperations interface {
 
 	// Allocate allows the caller to reserve disk space for the inode.
 	// It's equivalent to fallocate(2) with 'mode=0'.
+	//
+	// Allocating a range that lies entirely within the file should not
+	// modify the file's data or mtime, though it still updates ctime as in
+	// Linux.
 	Allocate(ctx context.Context, inode *Inode, offset int64, length int64) error
 
 	// WriteOut writes cached Inode state to a backing filesystem in a
@@ -133,6 +133,27 @@ type Locks struct {
 
 	// blockedQueue is the queue of waiters that are waiting on a lock.
 	blockedQueue waiter.Queue `state:"zerovalue"`
+
+	// blocked contains the lock requests of blocked waiters, in the order
+	// in which they blocked. It is used to grant conflicting requests in
+	// FIFO order, so that a task that repeatedly unlocks and relocks a
+	// region can't starve other waiters.
+	blocked []*lockRequest `state:"nosave"`
+}
+
+// lockRequest is a request for a typed lock on a region of a file.
+type lockRequest struct {
+	uid UniqueID
+	t   LockType
+	r   LockRange
+}
+
+// conflicts returns true if lr and other can't be held at the same time.
+func (lr *lockRequest) conflicts(other *lockRequest) bool {
+	if lr.uid == other.uid || !lr.r.Overlaps(other.r) {
+		return false
+	}
+	return lr.t == WriteLock || other.t == WriteLock
 }
 
 // Blocker is the interface used for blocking locks. Passing a nil Blocker
@@ -154,32 +175,82 @@ const (
 // acquiring the lock in a non-blocking mode or "interrupted" if in a blocking mode.
 // Blocker is the interface used to provide blocking behavior, passing a nil Blocker
 // will result in non-blocking behavior.
+//
+// Conflicting blocking requests are granted in the order in which they
+// blocked. Non-blocking requests don't wait their turn and are granted
+// whenever the region is free, as in Linux.
 func (l *Locks) LockRegion(uid UniqueID, ownerPID int32, t LockType, r LockRange, block Blocker) bool {
+	req := &lockRequest{uid: uid, t: t, r: r}
+	queued := false
 	for {
 		l.mu.Lock()
 
 		// Blocking locks must run in a loop because we'll be woken up whenever an unlock event
 		// happens for this lock. We will then attempt to take the lock again and if it fails
 		// continue blocking.
-		res := l.locks.lock(uid, ownerPID, t, r)
+		res := (block == nil || !l.mustWaitLocked(req)) && l.locks.lock(uid, ownerPID, t, r)
 		if !res && block != nil {
+			if !queued {
+				l.blocked = append(l.blocked, req)
+				queued = true
+			}
 			e, ch := waiter.NewChannelEntry(nil)
 			l.blockedQueue.EventRegister(&e, EventMaskAll)
 			l.mu.Unlock()
 			if err := block.Block(ch); err != nil {
 				// We were interrupted, the caller can translate this to EINTR if applicable.
 				l.blockedQueue.EventUnregister(&e)
+				l.mu.Lock()
+				l.dequeueLocked(req)
+				l.mu.Unlock()
 				return false
 			}
 			l.blockedQueue.EventUnregister(&e)
 			continue // Try again now that someone has unlocked.
 		}
 
+		if queued {
+			l.dequeueLocked(req)
+		}
 		l.mu.Unlock()
 		return res
 	}
 }
 
+// mustWaitLocked returns true if req must wait for a conflicting request that
+// blocked before it. Only waiters that could be granted their lock right away
+// are waited for; waiting for a request blocked on a lock held by req.uid
+// would deadlock.
+//
+// Preconditions: l.mu must be locked.
+func (l *Locks) mustWaitLocked(req *lockRequest) bool {
+	for _, w := range l.blocked {
+		if w == req {
+			return false
+		}
+		if w.conflicts(req) && l.locks.canLock(w.uid, w.t, w.r) {
+			return true
+		}
+	}
+	return false
+}
+
+// dequeueLocked removes req from l.blocked, and wakes up the remaining
+// waiters since they may no longer need to wait for req.
+//
+// Preconditions: l.mu must be locked.
+func (l *Locks) dequeueLocked(req *lockRequest) {
+	for i, w := range l.blocked {
+		if w == req {
+			copy(l.blocked[i:], l.blocked[i+1:])
+			l.blocked[len(l.blocked)-1] = nil
+			l.blocked = l.blocked[:len(l.blocked)-1]
+			break
+		}
+	}
+	l.blockedQueue.Notify(EventMaskAll)
+}
+
 // LockRegionVFS1 is a wrapper around LockRegion for VFS1, which does not implement
 // F_GETLK (and does not care about storing PIDs as a result).
 //
@@ -17,6 +17,7 @@ package lock
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 type entry struct {
@@ -1058,3 +1059,147 @@ func TestUnlock(t *testing.T) {
 		})
 	}
 }
+
+// channelBlocker implements Blocker by waiting for the channel to be
+// notified.
+type channelBlocker struct{}
+
+// Block implements Blocker.Block.
+func (channelBlocker) Block(c <-chan struct{}) error {
+	<-c
+	return nil
+}
+
+// waitForBlocked waits until n lock requests are blocked on l.
+func waitForBlocked(t *testing.T, l *Locks, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		l.mu.Lock()
+		blocked := len(l.blocked)
+		l.mu.Unlock()
+		if blocked == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d blocked lock requests, want %d", blocked, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBlockedLocksAreGrantedInOrder(t *testing.T) {
+	const waiters = 5
+	var l Locks
+	r := LockRange{0, LockEOF}
+	if !l.LockRegion(0, 0, WriteLock, r, nil) {
+		t.Fatalf("failed to take the initial write lock")
+	}
+
+	order := make(chan int, waiters+1)
+	for i := 1; i <= waiters; i++ {
+		go func(uid int) {
+			if !l.LockRegion(uid, 0, WriteLock, r, channelBlocker{}) {
+				t.Errorf("LockRegion(%d) failed", uid)
+			}
+			order <- uid
+			l.UnlockRegion(uid, r)
+		}(i)
+		// Wait for each waiter to block before starting the next one, so
+		// that the blocking order is deterministic.
+		waitForBlocked(t, &l, i)
+	}
+
+	// Relocking immediately after unlocking must not jump the queue.
+	l.UnlockRegion(0, r)
+	if !l.LockRegion(0, 0, WriteLock, r, channelBlocker{}) {
+		t.Fatalf("failed to retake the write lock")
+	}
+	order <- 0
+	l.UnlockRegion(0, r)
+
+	for want := 1; want <= waiters; want++ {
+		if got := <-order; got != want {
+			t.Fatalf("got lock granted to %d, want %d", got, want)
+		}
+	}
+	if got := <-order; got != 0 {
+		t.Errorf("got lock granted to %d, want 0", got)
+	}
+	waitForBlocked(t, &l, 0)
+}
+
+func TestNonConflictingBlockedLocks(t *testing.T) {
+	var l Locks
+	if !l.LockRegion(0, 0, WriteLock, LockRange{0, 10}, nil) {
+		t.Fatalf("failed to take the initial write lock")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		if !l.LockRegion(1, 0, WriteLock, LockRange{0, 10}, channelBlocker{}) {
+			t.Errorf("LockRegion(1) failed")
+		}
+		close(done)
+	}()
+	waitForBlocked(t, &l, 1)
+
+	// A request that doesn't conflict with the blocked one isn't queued.
+	if !l.LockRegion(2, 0, WriteLock, LockRange{10, 20}, nil) {
+		t.Errorf("failed to take a non-conflicting write lock")
+	}
+
+	// The holder of the lock that a waiter is blocked on can still extend
+	// its lock without deadlocking.
+	if !l.LockRegion(0, 0, WriteLock, LockRange{0, 5}, channelBlocker{}) {
+		t.Errorf("failed to relock a held region")
+	}
+
+	l.UnlockRegion(0, LockRange{0, 10})
+	<-done
+}
+
+// gatedBlocker implements Blocker by waiting for the channel to be notified,
+// and then for release to be closed.
+type gatedBlocker struct {
+	release chan struct{}
+}
+
+// Block implements Blocker.Block.
+func (b gatedBlocker) Block(c <-chan struct{}) error {
+	<-c
+	<-b.release
+	return nil
+}
+
+func TestNonBlockingLockIgnoresQueue(t *testing.T) {
+	var l Locks
+	r := LockRange{0, LockEOF}
+	if !l.LockRegion(0, 0, WriteLock, r, nil) {
+		t.Fatalf("failed to take the initial write lock")
+	}
+
+	b := gatedBlocker{release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		if !l.LockRegion(1, 0, WriteLock, r, b) {
+			t.Errorf("LockRegion(1) failed")
+		}
+		close(done)
+	}()
+	waitForBlocked(t, &l, 1)
+
+	// The waiter could now be granted its lock, but hasn't retried yet.
+	l.UnlockRegion(0, r)
+
+	// A non-blocking request is granted without waiting behind it.
+	if !l.LockRegion(2, 0, ReadLock, r, nil) {
+		t.Errorf("failed to take a non-blocking read lock while a waiter is queued")
+	}
+	l.UnlockRegion(2, r)
+
+	close(b.release)
+	<-done
+	l.UnlockRegion(1, r)
+	waitForBlocked(t, &l, 0)
+}
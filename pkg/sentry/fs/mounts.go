@@ -462,6 +462,13 @@ func (mns *MountNamespace) FindLink(ctx context.Context, root, wd *Dirent, path
 		panic("MountNamespace.FindLink: path is empty")
 	}
 
+	// If resolution is confined, no step of the walk may leave the subtree
+	// rooted at beneath.
+	beneath := ResolveBeneathFromContext(ctx)
+	if beneath != nil {
+		defer beneath.DecRef(ctx)
+	}
+
 	// Split the path.
 	first, remainder := SplitFirst(path)
 
@@ -471,6 +478,12 @@ func (mns *MountNamespace) FindLink(ctx context.Context, root, wd *Dirent, path
 		current = root
 	}
 	for first == "/" {
+		// Absolute paths and symlinks restart at the root, which must
+		// itself be within the confinement.
+		if beneath != nil && !root.IsDescendantOf(beneath) {
+			return nil, linuxerr.EXDEV
+		}
+
 		// Special case: it's possible that we have nothing to walk at
 		// all. This is necessary since we're resplitting the path.
 		if remainder == "" {
@@ -484,6 +497,9 @@ func (mns *MountNamespace) FindLink(ctx context.Context, root, wd *Dirent, path
 		current = root
 		first, remainder = SplitFirst(remainder)
 	}
+	if beneath != nil && !current.IsDescendantOf(beneath) {
+		return nil, linuxerr.EXDEV
+	}
 
 	current.IncRef() // Transferred during walk.
 
@@ -518,6 +534,13 @@ func (mns *MountNamespace) FindLink(ctx context.Context, root, wd *Dirent, path
 		// Drop old reference.
 		current.DecRef(ctx)
 
+		// Walking ".." out of the confinement, including from the root
+		// of a mount back into its parent, is an escape.
+		if beneath != nil && !next.IsDescendantOf(beneath) {
+			next.DecRef(ctx)
+			return nil, linuxerr.EXDEV
+		}
+
 		if remainder != "" {
 			// Ensure it's resolved, unless it's the last level.
 			//
@@ -574,6 +597,18 @@ func (mns *MountNamespace) resolve(ctx context.Context, root, node *Dirent, rema
 			return nil, unix.ELOOP
 		}
 
+		// Links that jump directly to a dirent (e.g. /proc/[pid]/fd/N)
+		// may not leave the confinement either.
+		if beneath := ResolveBeneathFromContext(ctx); beneath != nil {
+			escaped := !target.IsDescendantOf(beneath)
+			beneath.DecRef(ctx)
+			if escaped {
+				target.DecRef(ctx)
+				node.DecRef(ctx)
+				return nil, linuxerr.EXDEV
+			}
+		}
+
 		node.DecRef(ctx) // Drop the original reference.
 		return target, nil
 
@@ -616,8 +651,8 @@ func (mns *MountNamespace) resolve(ctx context.Context, root, node *Dirent, rema
 }
 
 // SyncAll calls Dirent.SyncAll on the root.
-func (mns *MountNamespace) SyncAll(ctx context.Context) {
+func (mns *MountNamespace) SyncAll(ctx context.Context) error {
 	mns.mu.Lock()
 	defer mns.mu.Unlock()
-	mns.root.SyncAll(ctx)
+	return mns.root.SyncAll(ctx)
 }
@@ -17,7 +17,9 @@ package fs_test
 import (
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
 	"gvisor.dev/gvisor/pkg/sentry/fs/ramfs"
@@ -103,3 +105,120 @@ func TestFindLink(t *testing.T) {
 		}
 	}
 }
+
+// beneathContext confines path resolution to a subtree.
+type beneathContext struct {
+	context.Context
+	beneath *fs.Dirent
+}
+
+// Value implements context.Context.Value.
+func (bc *beneathContext) Value(key interface{}) interface{} {
+	if key == fs.CtxResolveBeneath {
+		bc.beneath.IncRef()
+		return bc.beneath
+	}
+	return bc.Context.Value(key)
+}
+
+func TestFindResolveBeneath(t *testing.T) {
+	ctx := contexttest.Context(t)
+	perms := fs.FilePermsFromMode(0777)
+	m := fs.NewPseudoMountSource(ctx)
+	symlink := func(target string) *fs.Inode {
+		return fs.NewInode(ctx, ramfs.NewSymlink(ctx, fs.RootOwner, target), m, fs.StableAttr{Type: fs.Symlink})
+	}
+
+	// /
+	// |-secret   (file)
+	// |-foo      (dir)
+	//   |-bar    (file)
+	//   |-rel    -> bar
+	//   |-abs    -> /foo/bar
+	//   |-up     -> ..
+	//   |-escape -> ../secret
+	secret := fsutil.NewSimpleFileInode(ctx, fs.RootOwner, perms, 0)
+	bar := fsutil.NewSimpleFileInode(ctx, fs.RootOwner, perms, 0)
+	fooDir := ramfs.NewDir(ctx, map[string]*fs.Inode{
+		"bar":    fs.NewInode(ctx, bar, m, fs.StableAttr{Type: fs.RegularFile}),
+		"rel":    symlink("bar"),
+		"abs":    symlink("/foo/bar"),
+		"up":     symlink(".."),
+		"escape": symlink("../secret"),
+	}, fs.RootOwner, perms)
+	rootDir := ramfs.NewDir(ctx, map[string]*fs.Inode{
+		"secret": fs.NewInode(ctx, secret, m, fs.StableAttr{Type: fs.RegularFile}),
+		"foo":    fs.NewInode(ctx, fooDir, m, fs.StableAttr{Type: fs.Directory}),
+	}, fs.RootOwner, perms)
+	mm, err := fs.NewMountNamespace(ctx, fs.NewInode(ctx, rootDir, m, fs.StableAttr{Type: fs.Directory}))
+	if err != nil {
+		t.Fatalf("NewMountNamespace failed: %v", err)
+	}
+
+	root := mm.Root()
+	defer root.DecRef(ctx)
+	foo, err := root.Walk(ctx, root, "foo")
+	if err != nil {
+		t.Fatalf("Error walking to foo: %v", err)
+	}
+	defer foo.DecRef(ctx)
+	bctx := &beneathContext{Context: ctx, beneath: foo}
+
+	// Resolutions that stay beneath foo succeed.
+	for _, tc := range []struct {
+		findPath string
+		wantPath string
+	}{
+		{".", "/foo"},
+		{"bar", "/foo/bar"},
+		{"./bar", "/foo/bar"},
+		{"rel", "/foo/bar"},
+	} {
+		maxTraversals := uint(linux.MaxSymlinkTraversals)
+		d, err := mm.FindInode(bctx, root, foo, tc.findPath, &maxTraversals)
+		if err != nil {
+			t.Errorf("FindInode(%q, wd=/foo) failed: %v", tc.findPath, err)
+			continue
+		}
+		if got, _ := d.FullName(root); got != tc.wantPath {
+			t.Errorf("FindInode(%q, wd=/foo) got dirent %q, want %q", tc.findPath, got, tc.wantPath)
+		}
+		d.DecRef(ctx)
+	}
+
+	// Escapes fail with EXDEV, even if the path would end up beneath foo
+	// again.
+	for _, tc := range []struct {
+		findPath string
+		wd       *fs.Dirent
+	}{
+		{"..", foo},
+		{"../secret", foo},
+		{"../foo/bar", foo},
+		{"/foo/bar", foo},
+		{"abs", foo},
+		{"up/foo/bar", foo},
+		{"escape", foo},
+		{"foo/bar", root},
+	} {
+		wdPath, _ := tc.wd.FullName(root)
+		maxTraversals := uint(linux.MaxSymlinkTraversals)
+		if d, err := mm.FindInode(bctx, root, tc.wd, tc.findPath, &maxTraversals); !linuxerr.Equals(linuxerr.EXDEV, err) {
+			if err == nil {
+				d.DecRef(ctx)
+			}
+			t.Errorf("FindInode(%q, wd=%q) got error %v, want EXDEV", tc.findPath, wdPath, err)
+		}
+	}
+
+	// Without the confinement, the same escapes are permitted.
+	maxTraversals := uint(linux.MaxSymlinkTraversals)
+	d, err := mm.FindInode(ctx, root, foo, "escape", &maxTraversals)
+	if err != nil {
+		t.Fatalf("FindInode(%q, wd=/foo) failed: %v", "escape", err)
+	}
+	if got, _ := d.FullName(root); got != "/secret" {
+		t.Errorf("FindInode(%q, wd=/foo) got dirent %q, want %q", "escape", got, "/secret")
+	}
+	d.DecRef(ctx)
+}
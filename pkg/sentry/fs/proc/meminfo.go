@@ -80,8 +80,16 @@ func (d *meminfoData) ReadSeqFileData(ctx context.Context, h seqfile.SeqHandle)
 	fmt.Fprintf(&buf, "Inactive(file): %8d kB\n", inactiveFile/1024)
 	fmt.Fprintf(&buf, "Unevictable:           0 kB\n") // TODO(b/31823263)
 	fmt.Fprintf(&buf, "Mlocked:               0 kB\n") // TODO(b/31823263)
-	fmt.Fprintf(&buf, "SwapTotal:             0 kB\n")
-	fmt.Fprintf(&buf, "SwapFree:              0 kB\n")
+	var swapTotal, swapFree uint64
+	if swapped, ok := mf.FakeSwap(); ok {
+		// Present compressed memory as swap large enough to hold all memory.
+		swapTotal = totalSize
+		if swapped < swapTotal {
+			swapFree = swapTotal - swapped
+		}
+	}
+	fmt.Fprintf(&buf, "SwapTotal:      %8d kB\n", swapTotal/1024)
+	fmt.Fprintf(&buf, "SwapFree:       %8d kB\n", swapFree/1024)
 	fmt.Fprintf(&buf, "Dirty:                 0 kB\n")
 	fmt.Fprintf(&buf, "Writeback:             0 kB\n")
 	fmt.Fprintf(&buf, "AnonPages:      %8d kB\n", anon/1024)
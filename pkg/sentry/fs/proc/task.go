@@ -98,7 +98,7 @@ func (p *proc) newTaskDir(ctx context.Context, t *kernel.Task, msrc *fs.MountSou
 		"mounts":        seqfile.NewSeqFileInode(ctx, &mountsFile{t: t}, msrc),
 		"net":           newNetDir(ctx, t, msrc),
 		"ns":            newNamespaceDir(ctx, t, msrc),
-		"oom_score":     newOOMScore(ctx, msrc),
+		"oom_score":     newOOMScore(ctx, t, msrc),
 		"oom_score_adj": newOOMScoreAdj(ctx, t, msrc),
 		"smaps":         newSmaps(ctx, t, msrc),
 		"stat":          newTaskStat(ctx, t, msrc, isThreadGroup, p.pidns),
@@ -715,6 +715,13 @@ func (s *statusData) ReadSeqFileData(ctx context.Context, h seqfile.SeqHandle) (
 
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "Name:\t%s\n", s.t.Name())
+	var fsCtx *kernel.FSContext
+	s.t.WithMuLocked(func(t *kernel.Task) {
+		fsCtx = t.FSContext()
+	})
+	if fsCtx != nil {
+		fmt.Fprintf(&buf, "Umask:\t%#04o\n", fsCtx.Umask())
+	}
 	fmt.Fprintf(&buf, "State:\t%s\n", s.t.StateStatus())
 	fmt.Fprintf(&buf, "Tgid:\t%d\n", s.pidns.IDOfThreadGroup(s.t.ThreadGroup()))
 	fmt.Fprintf(&buf, "Pid:\t%d\n", s.pidns.IDOfTask(s.t))
@@ -948,10 +955,29 @@ func (f *auxvecFile) Read(ctx context.Context, _ *fs.File, dst usermem.IOSequenc
 	return int64(n), err
 }
 
-// newOOMScore returns a oom_score file. It is a stub that always returns 0.
-// TODO(gvisor.dev/issue/1967)
-func newOOMScore(ctx context.Context, msrc *fs.MountSource) *fs.Inode {
-	return newStaticProcInode(ctx, msrc, []byte("0\n"))
+// oomScoreData implements seqfile.SeqSource for /proc/[pid]/oom_score.
+//
+// +stateify savable
+type oomScoreData struct {
+	t *kernel.Task
+}
+
+// newOOMScore returns a oom_score file.
+func newOOMScore(ctx context.Context, t *kernel.Task, msrc *fs.MountSource) *fs.Inode {
+	return newProcInode(ctx, seqfile.NewSeqFile(ctx, &oomScoreData{t}), msrc, fs.SpecialFile, t)
+}
+
+// NeedsUpdate implements seqfile.SeqSource.NeedsUpdate.
+func (o *oomScoreData) NeedsUpdate(generation int64) bool {
+	return true
+}
+
+// ReadSeqFileData implements seqfile.SeqSource.ReadSeqFileData.
+func (o *oomScoreData) ReadSeqFileData(ctx context.Context, h seqfile.SeqHandle) ([]seqfile.SeqData, int64) {
+	if h != nil {
+		return nil, 0
+	}
+	return []seqfile.SeqData{{Buf: []byte(fmt.Sprintf("%d\n", o.t.OOMScore())), Handle: (*oomScoreData)(nil)}}, 0
 }
 
 // oomScoreAdj is a file containing the oom_score adjustment for a task.
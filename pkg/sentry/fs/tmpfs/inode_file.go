@@ -272,7 +272,10 @@ func (f *fileInodeOperations) Allocate(ctx context.Context, _ *fs.Inode, offset,
 	f.dataMu.Lock()
 	defer f.dataMu.Unlock()
 
+	now := ktime.NowFromContext(ctx)
 	if newSize <= f.attr.Size {
+		// The size doesn't change, but Linux still updates ctime.
+		f.attr.StatusChangeTime = now
 		return nil
 	}
 
@@ -282,8 +285,6 @@ func (f *fileInodeOperations) Allocate(ctx context.Context, _ *fs.Inode, offset,
 	}
 
 	f.attr.Size = newSize
-
-	now := ktime.NowFromContext(ctx)
 	f.attr.ModificationTime = now
 	f.attr.StatusChangeTime = now
 
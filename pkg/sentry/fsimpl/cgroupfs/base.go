@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"sync/atomic"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
@@ -27,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/usermem"
 )
 
@@ -95,14 +97,29 @@ type cgroupInode struct {
 	//
 	// ts, and cgroup membership in general is protected by fs.tasksMu.
 	ts map[*kernel.Task]struct{}
+
+	// io is the state of the io controller for this cgroup, or nil if the io
+	// controller isn't attached to the hierarchy. io is immutable after the
+	// cgroup is created.
+	io *ioCgroup
+
+	// cpu is the state of the cpu controller for this cgroup, or nil if the
+	// cpu controller isn't attached to the hierarchy. cpu is immutable after
+	// the cgroup is created.
+	cpu *cpuCgroup
+
+	// parent is the parent cgroup, or nil for the hierarchy root. parent is
+	// immutable.
+	parent *cgroupInode
 }
 
 var _ kernel.CgroupImpl = (*cgroupInode)(nil)
 
-func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credentials) kernfs.Inode {
+func (fs *filesystem) newCgroupInode(ctx context.Context, creds *auth.Credentials, parent *cgroupInode) kernfs.Inode {
 	c := &cgroupInode{
-		dir: dir{fs: fs},
-		ts:  make(map[*kernel.Task]struct{}),
+		dir:    dir{fs: fs},
+		ts:     make(map[*kernel.Task]struct{}),
+		parent: parent,
 	}
 	c.dir.cgi = c
 
@@ -157,6 +174,28 @@ func (c *cgroupInode) Leave(t *kernel.Task) {
 	c.fs.tasksMu.Unlock()
 }
 
+// AccountIO implements kernel.CgroupImpl.AccountIO.
+func (c *cgroupInode) AccountIO(dev uint32, write bool, bytes int64) {
+	if c.io != nil {
+		c.io.account(dev, write, bytes)
+	}
+}
+
+// ChargeCPU implements kernel.CgroupImpl.ChargeCPU.
+func (c *cgroupInode) ChargeCPU(now ktime.Time, d time.Duration) {
+	if c.cpu != nil {
+		c.cpu.charge(now, d)
+	}
+}
+
+// CPUThrottled implements kernel.CgroupImpl.CPUThrottled.
+func (c *cgroupInode) CPUThrottled(now ktime.Time) (ktime.Time, bool) {
+	if c.cpu != nil {
+		return c.cpu.throttledUntil(now)
+	}
+	return ktime.Time{}, false
+}
+
 func sortTIDs(tids []kernel.ThreadID) {
 	sort.Slice(tids, func(i, j int) bool { return tids[i] < tids[j] })
 }
@@ -54,6 +54,8 @@
 //     kernel.Task.mu
 //       cgroupfs.filesystem.tasksMu.
 //         cgroupfs.dir.OrderedChildren.mu
+//       cgroupfs.ioCgroup.mu
+//       cgroupfs.cpuController.mu
 package cgroupfs
 
 import (
@@ -85,6 +87,7 @@ const (
 	controllerCPU     = kernel.CgroupControllerType("cpu")
 	controllerCPUAcct = kernel.CgroupControllerType("cpuacct")
 	controllerCPUSet  = kernel.CgroupControllerType("cpuset")
+	controllerIO      = kernel.CgroupControllerType("io")
 	controllerJob     = kernel.CgroupControllerType("job")
 	controllerMemory  = kernel.CgroupControllerType("memory")
 )
@@ -93,12 +96,13 @@ var allControllers = []kernel.CgroupControllerType{
 	controllerCPU,
 	controllerCPUAcct,
 	controllerCPUSet,
+	controllerIO,
 	controllerJob,
 	controllerMemory,
 }
 
 // SupportedMountOptions is the set of supported mount options for cgroupfs.
-var SupportedMountOptions = []string{"all", "cpu", "cpuacct", "cpuset", "job", "memory"}
+var SupportedMountOptions = []string{"all", "cpu", "cpuacct", "cpuset", "io", "job", "memory"}
 
 // FilesystemType implements vfs.FilesystemType.
 //
@@ -193,6 +197,10 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		delete(mopts, "cpuset")
 		wantControllers = append(wantControllers, controllerCPUSet)
 	}
+	if _, ok := mopts["io"]; ok {
+		delete(mopts, "io")
+		wantControllers = append(wantControllers, controllerIO)
+	}
 	if _, ok := mopts["job"]; ok {
 		delete(mopts, "job")
 		wantControllers = append(wantControllers, controllerJob)
@@ -270,6 +278,8 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 			c = newCPUAcctController(fs)
 		case controllerCPUSet:
 			c = newCPUSetController(fs)
+		case controllerIO:
+			c = newIOController(fs)
 		case controllerJob:
 			c = newJobController(fs)
 		case controllerMemory:
@@ -294,7 +304,7 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		fs.kcontrollers = append(fs.kcontrollers, c)
 	}
 
-	root := fs.newCgroupInode(ctx, creds)
+	root := fs.newCgroupInode(ctx, creds, nil)
 	var rootD kernfs.Dentry
 	rootD.InitRoot(&fs.Filesystem, root)
 	fs.root = &rootD
@@ -451,7 +461,7 @@ func (d *dir) NewDir(ctx context.Context, name string, opts vfs.MkdirOptions) (k
 	}
 	return d.OrderedChildren.Inserter(name, func() kernfs.Inode {
 		d.IncLinks(1)
-		return d.fs.newCgroupInode(ctx, auth.CredentialsFromContext(ctx))
+		return d.fs.newCgroupInode(ctx, auth.CredentialsFromContext(ctx), d.cgi)
 	})
 }
 
@@ -534,6 +544,9 @@ func (d *dir) RmDir(ctx context.Context, name string, child kernfs.Inode) error
 	err := d.OrderedChildren.RmDir(ctx, name, child)
 	if err == nil {
 		d.InodeAttrs.DecLinks()
+		if cgi.cpu != nil {
+			cgi.cpu.release(ctx)
+		}
 	}
 	return err
 }
@@ -15,18 +15,43 @@
 package cgroupfs
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Limits on CFS bandwidth control parameters, in microseconds, from Linux's
+// kernel/sched/core.c.
+const (
+	minCFSPeriod  = 1000    // 1ms
+	maxCFSPeriod  = 1000000 // 1s
+	minCFSQuota   = 1000    // 1ms
+	maxCFSRuntime = (1 << 44) - 1
 )
 
 // +stateify savable
 type cpuController struct {
 	controllerCommon
 
+	// mu protects the bandwidth control state of all cgroups in the
+	// hierarchy, since charging CPU time to a cgroup also charges its
+	// ancestors.
+	mu sync.Mutex `state:"nosave"`
+
 	// CFS bandwidth control parameters, values in microseconds.
 	cfsPeriod int64
 	cfsQuota  int64
@@ -63,8 +88,401 @@ func newCPUController(fs *filesystem, defaults map[string]int64) *cpuController
 }
 
 // AddControlFiles implements controller.AddControlFiles.
-func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, _ *cgroupInode, contents map[string]kernfs.Inode) {
-	contents["cpu.cfs_period_us"] = c.fs.newStaticControllerFile(ctx, creds, linux.FileMode(0644), fmt.Sprintf("%d\n", c.cfsPeriod))
-	contents["cpu.cfs_quota_us"] = c.fs.newStaticControllerFile(ctx, creds, linux.FileMode(0644), fmt.Sprintf("%d\n", c.cfsQuota))
+func (c *cpuController) AddControlFiles(ctx context.Context, creds *auth.Credentials, cg *cgroupInode, contents map[string]kernfs.Inode) {
+	cg.cpu = &cpuCgroup{
+		c:      c,
+		period: 100000,
+		quota:  -1,
+	}
+	if cg.parent == nil {
+		// The root cgroup reports the defaults, which typically reflect the
+		// limits imposed on the sandbox by the host. They are enforced by
+		// the host, and as in Linux, can't be changed.
+		cg.cpu.period = c.cfsPeriod
+		cg.cpu.quota = c.cfsQuota
+	} else {
+		cg.cpu.parent = cg.parent.cpu
+	}
+	contents["cpu.cfs_period_us"] = c.fs.newControllerWritableFile(ctx, creds, &cpuPeriodData{cg.cpu})
+	contents["cpu.cfs_quota_us"] = c.fs.newControllerWritableFile(ctx, creds, &cpuQuotaData{cg.cpu})
+	contents["cpu.cfs_burst_us"] = c.fs.newControllerWritableFile(ctx, creds, &cpuBurstData{cg.cpu})
 	contents["cpu.shares"] = c.fs.newStaticControllerFile(ctx, creds, linux.FileMode(0644), fmt.Sprintf("%d\n", c.shares))
+	contents["cpu.stat"] = c.fs.newControllerFile(ctx, creds, &cpuStatData{cg.cpu})
+}
+
+// cpuCgroup is the per-cgroup state of the cpu controller.
+//
+// CPU time is charged to a cgroup, and to each of its ancestors, by the
+// kernel's CPU clock ticker while its tasks are running. Once a cgroup with a
+// quota has used up its runtime for the current period, it is throttled: its
+// tasks, and those of its descendants, don't return to application code until
+// the runtime is replenished at the start of a later period. Periods are
+// accounted lazily, whenever the cgroup is charged or queried, rather than by
+// a timer.
+//
+// +stateify savable
+type cpuCgroup struct {
+	c *cpuController
+
+	// parent is the state of the parent cgroup, or nil for the root cgroup,
+	// whose bandwidth is not enforced. parent is immutable.
+	parent *cpuCgroup
+
+	// The following fields are protected by c.mu.
+
+	// CFS bandwidth control parameters, values in microseconds. A negative
+	// quota means that the cgroup's bandwidth is unlimited.
+	period int64
+	quota  int64
+	burst  int64
+
+	// periodStart is the start of the current period.
+	periodStart ktime.Time
+
+	// runtime is the CPU time that the cgroup may still use in the current
+	// period. It may be negative, since CPU time is charged at the
+	// granularity of clock ticks; the debt is then paid off in the next
+	// period.
+	runtime time.Duration
+
+	// used is the CPU time used in the current period.
+	used time.Duration
+
+	// throttled is true if runtime is exhausted. If so, throttledAt is when
+	// the cgroup was throttled.
+	throttled   bool
+	throttledAt ktime.Time
+
+	// Statistics reported by cpu.stat.
+	nrPeriods     uint64
+	nrThrottled   uint64
+	throttledTime time.Duration
+	nrBursts      uint64
+	burstTime     time.Duration
+}
+
+func usecs(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
+
+// limitedLocked returns true if the bandwidth of cg is enforced.
+//
+// Preconditions: cg.c.mu must be locked.
+func (cg *cpuCgroup) limitedLocked() bool {
+	return cg.parent != nil && cg.quota >= 0
+}
+
+// resetLocked starts a new period with a full quota at now.
+//
+// Preconditions: cg.c.mu must be locked.
+func (cg *cpuCgroup) resetLocked(now ktime.Time) {
+	if cg.throttled {
+		cg.throttled = false
+		cg.throttledTime += now.Sub(cg.throttledAt)
+	}
+	cg.periodStart = now
+	cg.runtime = usecs(cg.quota)
+	cg.used = 0
+}
+
+// replenishLocked accounts for all periods of cg that ended before now.
+//
+// Preconditions:
+// * cg.c.mu must be locked.
+// * cg.limitedLocked().
+func (cg *cpuCgroup) replenishLocked(now ktime.Time) {
+	period := usecs(cg.period)
+	elapsed := now.Sub(cg.periodStart)
+	if elapsed < period {
+		return
+	}
+	n := int64(elapsed / period)
+	end := cg.periodStart.Add(period)
+
+	// Only the period that just ended can have been active; nothing was
+	// charged in any that followed it.
+	quota := usecs(cg.quota)
+	if cg.used > 0 || cg.throttled {
+		cg.nrPeriods++
+	}
+	if cg.burst > 0 && cg.used > quota {
+		cg.nrBursts++
+		cg.burstTime += cg.used - quota
+	}
+	cg.used = 0
+
+	// As in Linux, unused runtime accumulates up to the burst.
+	limit := quota + usecs(cg.burst)
+	if n <= int64((limit-cg.runtime)/quota) {
+		cg.runtime += time.Duration(n) * quota
+	} else {
+		cg.runtime = limit
+	}
+	if cg.throttled && cg.runtime > 0 {
+		cg.throttled = false
+		cg.throttledTime += end.Sub(cg.throttledAt)
+	}
+	cg.periodStart = cg.periodStart.Add(time.Duration(n) * period)
+}
+
+// charge charges d of CPU time, used by a task in cg at now, to cg and its
+// ancestors.
+func (cg *cpuCgroup) charge(now ktime.Time, d time.Duration) {
+	cg.c.mu.Lock()
+	defer cg.c.mu.Unlock()
+	for ; cg != nil; cg = cg.parent {
+		if !cg.limitedLocked() {
+			continue
+		}
+		cg.replenishLocked(now)
+		cg.used += d
+		cg.runtime -= d
+		if cg.runtime <= 0 && !cg.throttled {
+			cg.throttled = true
+			cg.throttledAt = now
+			cg.nrThrottled++
+		}
+	}
+}
+
+// throttledUntil returns whether cg or one of its ancestors is throttled at
+// now, and if so, the end of its current period.
+func (cg *cpuCgroup) throttledUntil(now ktime.Time) (ktime.Time, bool) {
+	cg.c.mu.Lock()
+	defer cg.c.mu.Unlock()
+	for ; cg != nil; cg = cg.parent {
+		if !cg.limitedLocked() {
+			continue
+		}
+		cg.replenishLocked(now)
+		if cg.throttled {
+			return cg.periodStart.Add(usecs(cg.period)), true
+		}
+	}
+	return ktime.Time{}, false
+}
+
+// setBandwidth changes the bandwidth control parameters of cg.
+func (cg *cpuCgroup) setBandwidth(ctx context.Context, period, quota, burst int64) error {
+	if cg.parent == nil {
+		return linuxerr.EINVAL
+	}
+	if period < minCFSPeriod || period > maxCFSPeriod {
+		return linuxerr.EINVAL
+	}
+	if quota >= 0 && (quota < minCFSQuota || quota > maxCFSRuntime) {
+		return linuxerr.EINVAL
+	}
+	if burst < 0 || burst > maxCFSRuntime {
+		return linuxerr.EINVAL
+	}
+	if quota >= 0 && (burst > quota || quota+burst > maxCFSRuntime) {
+		return linuxerr.EINVAL
+	}
+
+	k := kernel.KernelFromContext(ctx)
+	cg.c.mu.Lock()
+	defer cg.c.mu.Unlock()
+	wasLimited := cg.limitedLocked()
+	cg.period = period
+	cg.quota = quota
+	cg.burst = burst
+	cg.resetLocked(k.MonotonicClock().Now())
+	if limited := cg.limitedLocked(); limited != wasLimited {
+		if limited {
+			k.AddCPUBandwidthLimits(1)
+		} else {
+			k.AddCPUBandwidthLimits(-1)
+		}
+	}
+	return nil
+}
+
+// release lifts the bandwidth limit of cg, which is being destroyed.
+func (cg *cpuCgroup) release(ctx context.Context) {
+	cg.c.mu.Lock()
+	defer cg.c.mu.Unlock()
+	if cg.limitedLocked() {
+		cg.quota = -1
+		kernel.KernelFromContext(ctx).AddCPUBandwidthLimits(-1)
+	}
+}
+
+// writeBandwidthParam parses a bandwidth control parameter written to one of
+// cg's control files, and applies it using set.
+func (cg *cpuCgroup) writeBandwidthParam(ctx context.Context, src usermem.IOSequence, offset int64, set func(val int64, period, quota, burst *int64)) (int64, error) {
+	val, n, err := parseInt64FromString(ctx, src, offset)
+	if err != nil {
+		return n, err
+	}
+	cg.c.mu.Lock()
+	period, quota, burst := cg.period, cg.quota, cg.burst
+	cg.c.mu.Unlock()
+	set(val, &period, &quota, &burst)
+	if err := cg.setBandwidth(ctx, period, quota, burst); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// +stateify savable
+type cpuPeriodData struct {
+	*cpuCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuPeriodData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.c.mu.Lock()
+	defer d.c.mu.Unlock()
+	fmt.Fprintf(buf, "%d\n", d.period)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuPeriodData) Write(ctx context.Context, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.writeBandwidthParam(ctx, src, offset, func(val int64, period, _, _ *int64) {
+		*period = val
+	})
+}
+
+// +stateify savable
+type cpuQuotaData struct {
+	*cpuCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuQuotaData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.c.mu.Lock()
+	defer d.c.mu.Unlock()
+	fmt.Fprintf(buf, "%d\n", d.quota)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuQuotaData) Write(ctx context.Context, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.writeBandwidthParam(ctx, src, offset, func(val int64, _, quota, _ *int64) {
+		// As in Linux, any negative value means unlimited.
+		if val < 0 {
+			val = -1
+		}
+		*quota = val
+	})
+}
+
+// +stateify savable
+type cpuBurstData struct {
+	*cpuCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuBurstData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	d.c.mu.Lock()
+	defer d.c.mu.Unlock()
+	fmt.Fprintf(buf, "%d\n", d.burst)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *cpuBurstData) Write(ctx context.Context, src usermem.IOSequence, offset int64) (int64, error) {
+	return d.writeBandwidthParam(ctx, src, offset, func(val int64, _, _, burst *int64) {
+		*burst = val
+	})
+}
+
+// +stateify savable
+type cpuStatData struct {
+	*cpuCgroup
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *cpuStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	k := kernel.KernelFromContext(ctx)
+	d.c.mu.Lock()
+	if d.limitedLocked() {
+		d.replenishLocked(k.MonotonicClock().Now())
+	}
+	s := cpuStats{
+		nrPeriods:     d.nrPeriods,
+		nrThrottled:   d.nrThrottled,
+		throttledTime: d.throttledTime,
+		nrBursts:      d.nrBursts,
+		burstTime:     d.burstTime,
+	}
+	d.c.mu.Unlock()
+
+	// The whole sandbox is subject to throttling by its host cgroup, which
+	// is reflected in the root cgroup.
+	if d.parent == nil {
+		if host := k.HostCPUStat(); host != nil {
+			hs, err := readHostCPUStats(host)
+			if err != nil {
+				ctx.Warningf("cgroupfs: failed to read host cpu.stat: %v", err)
+			} else {
+				s.add(hs)
+			}
+		}
+	}
+
+	fmt.Fprintf(buf, "nr_periods %d\n", s.nrPeriods)
+	fmt.Fprintf(buf, "nr_throttled %d\n", s.nrThrottled)
+	fmt.Fprintf(buf, "throttled_time %d\n", s.throttledTime.Nanoseconds())
+	fmt.Fprintf(buf, "nr_bursts %d\n", s.nrBursts)
+	fmt.Fprintf(buf, "burst_time %d\n", s.burstTime.Nanoseconds())
+	return nil
+}
+
+// cpuStats are the statistics reported by cpu.stat.
+type cpuStats struct {
+	nrPeriods     uint64
+	nrThrottled   uint64
+	throttledTime time.Duration
+	nrBursts      uint64
+	burstTime     time.Duration
+}
+
+func (s *cpuStats) add(o cpuStats) {
+	s.nrPeriods += o.nrPeriods
+	s.nrThrottled += o.nrThrottled
+	s.throttledTime += o.throttledTime
+	s.nrBursts += o.nrBursts
+	s.burstTime += o.burstTime
+}
+
+// readHostCPUStats parses a host cpu.stat file, in either the cgroup v1 or v2
+// format. Unknown fields are ignored.
+func readHostCPUStats(r io.ReaderAt) (cpuStats, error) {
+	var s cpuStats
+	buf := make([]byte, 4096)
+	n, err := r.ReadAt(buf, 0)
+	if n == 0 && err != nil {
+		return s, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(buf[:n]))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		val, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "nr_periods":
+			s.nrPeriods = val
+		case "nr_throttled":
+			s.nrThrottled = val
+		case "throttled_time":
+			s.throttledTime = time.Duration(val)
+		case "throttled_usec":
+			s.throttledTime = time.Duration(val) * time.Microsecond
+		case "nr_bursts":
+			s.nrBursts = val
+		case "burst_time":
+			s.burstTime = time.Duration(val)
+		case "burst_usec":
+			s.burstTime = time.Duration(val) * time.Microsecond
+		}
+	}
+	return s, nil
 }
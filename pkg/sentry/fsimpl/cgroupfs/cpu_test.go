@@ -0,0 +1,163 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroupfs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+)
+
+const tick = 10 * time.Millisecond
+
+// newTestCPUCgroup returns a cgroup limited to quota out of every period, with
+// the given burst, under the unlimited cgroup parent.
+func newTestCPUCgroup(parent *cpuCgroup, period, quota, burst int64) *cpuCgroup {
+	cg := &cpuCgroup{
+		c:      parent.c,
+		parent: parent,
+		period: period,
+		quota:  quota,
+		burst:  burst,
+	}
+	cg.runtime = usecs(quota)
+	return cg
+}
+
+func newTestRootCPUCgroup() *cpuCgroup {
+	return &cpuCgroup{
+		c:      &cpuController{},
+		period: 100000,
+		quota:  -1,
+	}
+}
+
+func at(d time.Duration) ktime.Time {
+	return ktime.FromNanoseconds(d.Nanoseconds())
+}
+
+func TestCPUThrottle(t *testing.T) {
+	root := newTestRootCPUCgroup()
+	// 20ms every 100ms.
+	cg := newTestCPUCgroup(root, 100000, 20000, 0)
+
+	cg.charge(at(tick), tick)
+	if _, ok := cg.throttledUntil(at(tick)); ok {
+		t.Fatalf("cgroup throttled after using half its quota")
+	}
+	cg.charge(at(2*tick), tick)
+	until, ok := cg.throttledUntil(at(2 * tick))
+	if !ok {
+		t.Fatalf("cgroup not throttled after using its quota")
+	}
+	if want := at(100 * time.Millisecond); until != want {
+		t.Errorf("throttled until %v, want %v", until, want)
+	}
+
+	// The quota is replenished in the next period.
+	if _, ok := cg.throttledUntil(at(100 * time.Millisecond)); ok {
+		t.Fatalf("cgroup still throttled in the next period")
+	}
+	if cg.nrPeriods != 1 || cg.nrThrottled != 1 {
+		t.Errorf("got nr_periods %d, nr_throttled %d, want 1, 1", cg.nrPeriods, cg.nrThrottled)
+	}
+	if want := 80 * time.Millisecond; cg.throttledTime != want {
+		t.Errorf("got throttled time %v, want %v", cg.throttledTime, want)
+	}
+
+	// Idle periods aren't counted.
+	cg.throttledUntil(at(time.Second))
+	if cg.nrPeriods != 1 {
+		t.Errorf("got nr_periods %d after idle periods, want 1", cg.nrPeriods)
+	}
+}
+
+func TestCPUThrottleNested(t *testing.T) {
+	root := newTestRootCPUCgroup()
+	parent := newTestCPUCgroup(root, 100000, 20000, 0)
+	unlimited := newTestCPUCgroup(parent, 100000, -1, 0)
+	other := newTestCPUCgroup(parent, 100000, -1, 0)
+
+	// Usage by siblings is charged to their common ancestor.
+	unlimited.charge(at(0), tick)
+	other.charge(at(0), tick)
+	if _, ok := unlimited.throttledUntil(at(tick)); !ok {
+		t.Errorf("child not throttled by its parent's quota")
+	}
+	if _, ok := root.throttledUntil(at(tick)); ok {
+		t.Errorf("root cgroup throttled")
+	}
+	if unlimited.nrThrottled != 0 || parent.nrThrottled != 1 {
+		t.Errorf("got nr_throttled %d for child, %d for parent, want 0, 1", unlimited.nrThrottled, parent.nrThrottled)
+	}
+}
+
+func TestCPUBurst(t *testing.T) {
+	root := newTestRootCPUCgroup()
+	// 20ms every 100ms, with up to 20ms of unused quota carried over.
+	cg := newTestCPUCgroup(root, 100000, 20000, 20000)
+
+	// Idle for a few periods, accumulating the full burst.
+	start := 300 * time.Millisecond
+	for i := time.Duration(0); i < 3; i++ {
+		cg.charge(at(start+i*tick), tick)
+	}
+	if _, ok := cg.throttledUntil(at(start + 3*tick)); ok {
+		t.Fatalf("cgroup throttled before exhausting its burst")
+	}
+	cg.charge(at(start+3*tick), tick)
+	if _, ok := cg.throttledUntil(at(start + 4*tick)); !ok {
+		t.Fatalf("cgroup not throttled after exhausting its burst")
+	}
+
+	cg.throttledUntil(at(start + 100*time.Millisecond))
+	if cg.nrBursts != 1 {
+		t.Errorf("got nr_bursts %d, want 1", cg.nrBursts)
+	}
+	if want := 20 * time.Millisecond; cg.burstTime != want {
+		t.Errorf("got burst time %v, want %v", cg.burstTime, want)
+	}
+}
+
+func TestReadHostCPUStats(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    cpuStats
+	}{
+		{
+			name:    "v1",
+			content: "nr_periods 10\nnr_throttled 2\nthrottled_time 3000\n",
+			want:    cpuStats{nrPeriods: 10, nrThrottled: 2, throttledTime: 3000},
+		},
+		{
+			name:    "v2",
+			content: "usage_usec 100\nnr_periods 10\nnr_throttled 2\nthrottled_usec 3\nnr_bursts 1\nburst_usec 4\n",
+			want:    cpuStats{nrPeriods: 10, nrThrottled: 2, throttledTime: 3 * time.Microsecond, nrBursts: 1, burstTime: 4 * time.Microsecond},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := readHostCPUStats(strings.NewReader(test.content))
+			if err != nil {
+				t.Fatalf("readHostCPUStats failed: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,789 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fanotify implements fanotify groups, as created by fanotify_init(2).
+//
+// Permission events (FAN_OPEN_PERM and FAN_ACCESS_PERM) block the task
+// performing the operation until the group's listener writes a struct
+// fanotify_response to the group's file descriptor. Two mechanisms prevent the
+// listener from deadlocking on its own permission events:
+//
+//   - Files passed to the listener in events are opened with
+//     vfs.OpenOptions.NoFanotify, so reading them generates no events.
+//
+//   - Operations by thread groups that created the group or have read events
+//     from it are never held for permission. Linux would queue these events,
+//     leaving the listener blocked on itself.
+//
+// Permission events that are not answered within the kernel's fanotify
+// permission timeout, or that are pending when the group is released, are
+// resolved by the kernel's fanotify permission policy; see
+// kernel.Kernel.FanotifyPermPolicy().
+//
+// Lock order:
+//
+// Group.mu
+//   FilesystemImpl locks
+package fanotify
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserror"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// Limits from Linux's fs/notify/fanotify/fanotify_user.c.
+const (
+	maxQueuedEvents = 16384
+	maxMarks        = 8192
+)
+
+// eventMetadataSize is sizeof(struct fanotify_event_metadata).
+var eventMetadataSize = (*linux.FanotifyEventMetadata)(nil).SizeBytes()
+
+// responseSize is sizeof(struct fanotify_response).
+var responseSize = (*linux.FanotifyResponse)(nil).SizeBytes()
+
+// markableEvents is the set of events that may be set in a mark's mask.
+// FAN_EVENT_ON_CHILD is not supported.
+const markableEvents = linux.FAN_ACCESS | linux.FAN_MODIFY | linux.FAN_CLOSE | linux.FAN_OPEN | linux.FAN_PERM_EVENTS | linux.FAN_ONDIR
+
+// A mark selects the events a Group reports for a file, mount or filesystem.
+//
+// +stateify savable
+type mark struct {
+	// mask is the set of events reported.
+	mask uint64
+
+	// ignoredMask is the set of events suppressed, even if another mark
+	// reports them.
+	ignoredMask uint64
+
+	// survModify is true if ignoredMask is preserved when the marked file is
+	// modified. Otherwise, FAN_MODIFY clears ignoredMask.
+	survModify bool
+}
+
+// empty returns true if m neither reports nor suppresses any events.
+func (m *mark) empty() bool {
+	return m.mask == 0 && m.ignoredMask == 0
+}
+
+// An event is a fanotify event queued on a Group.
+//
+// +stateify savable
+type event struct {
+	// mask is the set of events that occurred.
+	mask uint64
+
+	// vd is the file the event refers to. vd is a zero VirtualDentry for
+	// FAN_Q_OVERFLOW. For notification events, the event holds a reference on
+	// vd. For permission events, the waiting task does.
+	vd vfs.VirtualDentry
+
+	// tg is the thread group that caused the event, or nil if it was caused
+	// by the sentry.
+	tg *kernel.ThreadGroup
+
+	// The remaining fields are only used by permission events, and are
+	// protected by Group.mu.
+
+	// done is closed when response is set. Channels can't be saved, so done
+	// is recreated by afterLoad.
+	done chan struct{} `state:"nosave"`
+
+	// response is FAN_ALLOW or FAN_DENY once the event has been answered, or
+	// 0 before then.
+	response uint32
+
+	// fd is the listener's file descriptor for vd, or -1 if the event hasn't
+	// been read.
+	fd int32
+}
+
+func (e *event) isPerm() bool {
+	return e.mask&linux.FAN_PERM_EVENTS != 0
+}
+
+// afterLoad is invoked by stateify.
+func (e *event) afterLoad() {
+	if !e.isPerm() {
+		return
+	}
+	// A task waiting for e is interrupted by the save and stops waiting, so
+	// normally no permission events survive it. Still, make sure answering a
+	// restored event doesn't close a nil channel.
+	e.done = make(chan struct{})
+	if e.response != 0 {
+		close(e.done)
+	}
+}
+
+// Group implements vfs.FileDescriptionImpl and vfs.FanotifyGroup for
+// fanotify groups.
+//
+// +stateify savable
+type Group struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	// id is the group's vfs.FanotifyGroup.FanotifyID(). id is immutable.
+	id uint64
+
+	// flags is the set of flags passed to fanotify_init(2). flags is
+	// immutable.
+	flags uint32
+
+	// eventFlags is the set of open(2) flags for files passed in events.
+	// eventFlags is immutable.
+	eventFlags uint32
+
+	// queue is notified when events are queued.
+	queue waiter.Queue
+
+	// mu protects the fields below.
+	mu sync.Mutex `state:"nosave"`
+
+	// events is the queue of unread events.
+	events []*event
+
+	// overflowed is true if FAN_Q_OVERFLOW is in events.
+	overflowed bool
+
+	// pending maps the listener's file descriptors for permission events that
+	// have been read but not yet answered to those events.
+	pending map[int32]*event
+
+	// Marks, keyed by marked object. The group holds a reference on each
+	// marked object.
+	inodeMarks map[*vfs.Dentry]*mark
+	mountMarks map[*vfs.Mount]*mark
+	fsMarks    map[*vfs.Filesystem]*mark
+
+	// listeners contains the thread groups that created or read from the
+	// group. Their operations are never held for permission. Exited thread
+	// groups are removed by addListener.
+	listeners map[*kernel.ThreadGroup]struct{}
+
+	// released is true after Release has been called.
+	released bool
+}
+
+var _ vfs.FileDescriptionImpl = (*Group)(nil)
+var _ vfs.FanotifyGroup = (*Group)(nil)
+
+// New creates a new fanotify group on behalf of t and registers it with
+// vfsObj. flags and eventFlags are the arguments to fanotify_init(2), which
+// the caller must have validated.
+func New(t *kernel.Task, vfsObj *vfs.VirtualFilesystem, flags, eventFlags uint32) (*vfs.FileDescription, error) {
+	id := uniqueid.GlobalFromContext(t)
+	vd := vfsObj.NewAnonVirtualDentry(fmt.Sprintf("[fanotify:%d]", id))
+	defer vd.DecRef(t)
+	g := &Group{
+		id:         id,
+		flags:      flags,
+		eventFlags: eventFlags,
+		pending:    make(map[int32]*event),
+		inodeMarks: make(map[*vfs.Dentry]*mark),
+		mountMarks: make(map[*vfs.Mount]*mark),
+		fsMarks:    make(map[*vfs.Filesystem]*mark),
+		listeners:  map[*kernel.ThreadGroup]struct{}{t.ThreadGroup(): {}},
+	}
+	// Fanotify file descriptions are read-write, as listeners write
+	// responses.
+	fileFlags := uint32(linux.O_RDWR)
+	if flags&linux.FAN_NONBLOCK != 0 {
+		fileFlags |= linux.O_NONBLOCK
+	}
+	if err := g.vfsfd.Init(g, fileFlags, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+	}); err != nil {
+		return nil, err
+	}
+	vfsObj.RegisterFanotifyGroup(g)
+	return &g.vfsfd, nil
+}
+
+// FanotifyID implements vfs.FanotifyGroup.FanotifyID.
+func (g *Group) FanotifyID() uint64 {
+	return g.id
+}
+
+// Release implements vfs.FileDescriptionImpl.Release. Release removes all
+// marks and resolves all pending permission events according to the kernel's
+// fanotify permission policy.
+func (g *Group) Release(ctx context.Context) {
+	g.vfsfd.VirtualDentry().Mount().Filesystem().VirtualFilesystem().UnregisterFanotifyGroup(g)
+
+	response := uint32(linux.FAN_ALLOW)
+	if k := kernel.KernelFromContext(ctx); k != nil {
+		if _, deny := k.FanotifyPermPolicy(); deny {
+			response = linux.FAN_DENY
+		}
+	}
+
+	g.mu.Lock()
+	g.released = true
+	var vds []vfs.VirtualDentry
+	for _, e := range g.events {
+		if e.isPerm() {
+			g.answerLocked(e, response)
+		} else if e.vd.Ok() {
+			vds = append(vds, e.vd)
+		}
+	}
+	g.events = nil
+	for _, e := range g.pending {
+		g.answerLocked(e, response)
+	}
+	inodeMarks, mountMarks, fsMarks := g.inodeMarks, g.mountMarks, g.fsMarks
+	g.inodeMarks, g.mountMarks, g.fsMarks = nil, nil, nil
+	g.listeners = nil
+	g.mu.Unlock()
+
+	for _, vd := range vds {
+		vd.DecRef(ctx)
+	}
+	for d := range inodeMarks {
+		d.DecRef(ctx)
+	}
+	for mnt := range mountMarks {
+		mnt.DecRef(ctx)
+	}
+	for fs := range fsMarks {
+		fs.DecRef(ctx)
+	}
+}
+
+// answerLocked resolves the permission event e with response, and wakes the
+// task waiting for it.
+//
+// Preconditions: g.mu must be locked. e must not have been answered.
+func (g *Group) answerLocked(e *event, response uint32) {
+	if e.fd >= 0 {
+		delete(g.pending, e.fd)
+	}
+	e.response = response
+	close(e.done)
+}
+
+// removeEventLocked removes e from g.events or g.pending, wherever it is.
+//
+// Preconditions: g.mu must be locked.
+func (g *Group) removeEventLocked(e *event) {
+	if e.fd >= 0 {
+		delete(g.pending, e.fd)
+		return
+	}
+	for i, qe := range g.events {
+		if qe == e {
+			g.events = append(g.events[:i], g.events[i+1:]...)
+			return
+		}
+	}
+}
+
+// matchLocked returns the subset of the events in mask that g reports for fd.
+// Compare Linux's fs/notify/fanotify/fanotify.c:fanotify_group_event_mask().
+//
+// Preconditions: g.mu must be locked.
+func (g *Group) matchLocked(ctx context.Context, fd *vfs.FileDescription, mask uint64) uint64 {
+	if g.released {
+		return 0
+	}
+	var marks [3]*mark
+	n := 0
+	mnt := fd.Mount()
+	for _, m := range []*mark{g.inodeMarks[fd.Dentry()], g.mountMarks[mnt], g.fsMarks[mnt.Filesystem()]} {
+		if m != nil {
+			marks[n] = m
+			n++
+		}
+	}
+	var marked, ignored uint64
+	for _, m := range marks[:n] {
+		if mask&linux.FAN_MODIFY != 0 && !m.survModify {
+			m.ignoredMask = 0
+		}
+		marked |= m.mask
+		ignored |= m.ignoredMask
+	}
+	mask &= marked &^ ignored
+	if mask == 0 || marked&linux.FAN_ONDIR != 0 {
+		return mask
+	}
+	// Events on directories are only reported to marks with FAN_ONDIR.
+	stat, err := fd.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err == nil && stat.Mode&linux.S_IFMT == linux.S_IFDIR {
+		return 0
+	}
+	return mask
+}
+
+// queueLocked queues e, or FAN_Q_OVERFLOW if the queue is full. It returns
+// false in the latter case.
+//
+// Preconditions: g.mu must be locked.
+func (g *Group) queueLocked(e *event) bool {
+	if g.flags&linux.FAN_UNLIMITED_QUEUE == 0 && len(g.events) >= maxQueuedEvents {
+		if !g.overflowed {
+			g.overflowed = true
+			g.events = append(g.events, &event{mask: linux.FAN_Q_OVERFLOW, fd: -1})
+		}
+		return false
+	}
+	g.events = append(g.events, e)
+	return true
+}
+
+// CheckPermission implements vfs.FanotifyGroup.CheckPermission.
+func (g *Group) CheckPermission(ctx context.Context, fd *vfs.FileDescription, ev uint64) error {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		// Operations performed by the sentry itself are always allowed.
+		return nil
+	}
+
+	g.mu.Lock()
+	if g.matchLocked(ctx, fd, ev) == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	tg := t.ThreadGroup()
+	if _, ok := g.listeners[tg]; ok {
+		g.mu.Unlock()
+		return nil
+	}
+	vd := fd.VirtualDentry()
+	vd.IncRef()
+	defer vd.DecRef(ctx)
+	e := &event{
+		mask: ev,
+		vd:   vd,
+		tg:   tg,
+		done: make(chan struct{}),
+		fd:   -1,
+	}
+	if !g.queueLocked(e) {
+		// As in Linux, permission events that overflow the queue are
+		// allowed.
+		g.mu.Unlock()
+		g.queue.Notify(waiter.ReadableEvents)
+		return nil
+	}
+	g.mu.Unlock()
+	g.queue.Notify(waiter.ReadableEvents)
+
+	timeout, deny := t.Kernel().FanotifyPermPolicy()
+	_, err := t.BlockWithTimeout(e.done, timeout != 0, timeout)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e.response == 0 {
+		g.removeEventLocked(e)
+		if !linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+			// Interrupted. Ask again when the operation is restarted.
+			return syserror.ConvertIntr(err, syserror.ERESTARTSYS)
+		}
+		log.Warningf("fanotify: permission event %#x timed out after %v", ev, timeout)
+		e.response = linux.FAN_ALLOW
+		if deny {
+			e.response = linux.FAN_DENY
+		}
+	}
+	if e.response == linux.FAN_DENY {
+		return linuxerr.EPERM
+	}
+	return nil
+}
+
+// Notify implements vfs.FanotifyGroup.Notify.
+func (g *Group) Notify(ctx context.Context, fd *vfs.FileDescription, mask uint64) {
+	var tg *kernel.ThreadGroup
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		tg = t.ThreadGroup()
+	}
+
+	g.mu.Lock()
+	mask = g.matchLocked(ctx, fd, mask)
+	if mask == 0 {
+		g.mu.Unlock()
+		return
+	}
+	vd := fd.VirtualDentry()
+	// Merge with the last event if it is for the same file, as Linux does.
+	if n := len(g.events); n > 0 {
+		if last := g.events[n-1]; !last.isPerm() && last.vd == vd && last.tg == tg {
+			last.mask |= mask
+			g.mu.Unlock()
+			return
+		}
+	}
+	vd.IncRef()
+	if !g.queueLocked(&event{mask: mask, vd: vd, tg: tg, fd: -1}) {
+		g.mu.Unlock()
+		vd.DecRef(ctx)
+		return
+	}
+	g.mu.Unlock()
+	g.queue.Notify(waiter.ReadableEvents)
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (g *Group) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	g.queue.EventRegister(e, mask)
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (g *Group) EventUnregister(e *waiter.Entry) {
+	g.queue.EventUnregister(e)
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (g *Group) Readiness(mask waiter.EventMask) waiter.EventMask {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.events) != 0 {
+		return mask & waiter.ReadableEvents
+	}
+	return 0
+}
+
+// Read implements vfs.FileDescriptionImpl.Read. Each event read installs a
+// file descriptor for the event's file in the reader's file descriptor
+// table. Compare Linux's fs/notify/fanotify/fanotify_user.c:fanotify_read().
+func (g *Group) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	t := kernel.TaskFromContext(ctx)
+	g.addListener(t.ThreadGroup())
+	var n int64
+	for dst.NumBytes() > 0 {
+		g.mu.Lock()
+		if len(g.events) == 0 {
+			g.mu.Unlock()
+			break
+		}
+		if dst.NumBytes() < int64(eventMetadataSize) {
+			g.mu.Unlock()
+			if n == 0 {
+				return 0, linuxerr.EINVAL
+			}
+			return n, nil
+		}
+		e := g.events[0]
+		g.events = g.events[1:]
+		if e.mask == linux.FAN_Q_OVERFLOW {
+			g.overflowed = false
+		}
+		if e.isPerm() {
+			// The waiting task holds the only reference on e.vd, and may
+			// stop waiting at any time.
+			e.vd.IncRef()
+		}
+		g.mu.Unlock()
+
+		fd, err := g.installEventFile(t, e)
+		if e.vd.Ok() {
+			e.vd.DecRef(ctx)
+		}
+		if err == nil && e.isPerm() {
+			g.mu.Lock()
+			if e.response == 0 {
+				e.fd = fd
+				g.pending[fd] = e
+			}
+			g.mu.Unlock()
+		}
+		if err != nil {
+			if e.isPerm() {
+				g.mu.Lock()
+				if e.response == 0 {
+					g.answerLocked(e, linux.FAN_DENY)
+				}
+				g.mu.Unlock()
+			}
+			if n == 0 {
+				return 0, err
+			}
+			return n, nil
+		}
+
+		metadata := linux.FanotifyEventMetadata{
+			EventLen:    uint32(eventMetadataSize),
+			Vers:        linux.FANOTIFY_METADATA_VERSION,
+			MetadataLen: uint16(eventMetadataSize),
+			Mask:        e.mask,
+			FD:          fd,
+		}
+		if e.tg != nil {
+			metadata.PID = int32(t.PIDNamespace().IDOfThreadGroup(e.tg))
+		}
+		written, err := metadata.WriteTo(dst.Writer(ctx))
+		n += written
+		if err != nil {
+			if n == 0 {
+				return 0, err
+			}
+			return n, nil
+		}
+		dst = dst.DropFirst(eventMetadataSize)
+	}
+	if n == 0 {
+		return 0, syserror.ErrWouldBlock
+	}
+	return n, nil
+}
+
+// addListener adds tg to g.listeners. Listeners whose thread groups have
+// exited are removed at the same time, so that a long-lived group read by many
+// short-lived processes doesn't keep them all alive.
+func (g *Group) addListener(tg *kernel.ThreadGroup) {
+	g.mu.Lock()
+	if _, ok := g.listeners[tg]; ok {
+		g.mu.Unlock()
+		return
+	}
+	listeners := make([]*kernel.ThreadGroup, 0, len(g.listeners))
+	for l := range g.listeners {
+		listeners = append(listeners, l)
+	}
+	g.mu.Unlock()
+
+	// ThreadGroup.Count locks the TaskSet; do it without g.mu held so that
+	// g.mu needn't be ordered with the TaskSet lock.
+	var exited []*kernel.ThreadGroup
+	for _, l := range listeners {
+		if l.Count() == 0 {
+			exited = append(exited, l)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, l := range exited {
+		delete(g.listeners, l)
+	}
+	g.listeners[tg] = struct{}{}
+}
+
+// installEventFile opens the file referred to by e and installs it in t's file
+// descriptor table. It returns the new file descriptor, or FAN_NOFD if e does
+// not refer to a file. Compare Linux's
+// fs/notify/fanotify/fanotify_user.c:create_fd().
+func (g *Group) installEventFile(t *kernel.Task, e *event) (int32, error) {
+	if !e.vd.Ok() {
+		return linux.FAN_NOFD, nil
+	}
+	file, err := t.Kernel().VFS().OpenAt(t, t.Credentials(), &vfs.PathOperation{
+		Root:  e.vd,
+		Start: e.vd,
+	}, &vfs.OpenOptions{
+		Flags:      g.eventFlags &^ linux.O_CLOEXEC,
+		NoFanotify: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer file.DecRef(t)
+	return t.NewFDFromVFS2(0, file, kernel.FDFlags{
+		CloseOnExec: g.eventFlags&linux.O_CLOEXEC != 0,
+	})
+}
+
+// Write implements vfs.FileDescriptionImpl.Write. Listeners answer permission
+// events by writing a struct fanotify_response. Compare Linux's
+// fs/notify/fanotify/fanotify_user.c:fanotify_write().
+func (g *Group) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	if src.NumBytes() < int64(responseSize) {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, responseSize)
+	if _, err := src.CopyIn(ctx, buf); err != nil {
+		return 0, err
+	}
+	var resp linux.FanotifyResponse
+	resp.UnmarshalBytes(buf)
+	if resp.FD < 0 || (resp.Response != linux.FAN_ALLOW && resp.Response != linux.FAN_DENY) {
+		return 0, linuxerr.EINVAL
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e, ok := g.pending[resp.FD]
+	if !ok {
+		return 0, linuxerr.ENOENT
+	}
+	g.answerLocked(e, resp.Response)
+	return int64(responseSize), nil
+}
+
+// Mark implements fanotify_mark(2) for a single marked object, which must be
+// a *vfs.Dentry (FAN_MARK_INODE), *vfs.Mount (FAN_MARK_MOUNT) or
+// *vfs.Filesystem (FAN_MARK_FILESYSTEM). flags contains FAN_MARK_ADD or
+// FAN_MARK_REMOVE, and optionally FAN_MARK_IGNORED_MASK and
+// FAN_MARK_IGNORED_SURV_MODIFY. The caller must hold a reference on obj.
+func (g *Group) Mark(ctx context.Context, obj interface{}, flags uint32, mask uint64) error {
+	if mask&^markableEvents != 0 {
+		return linuxerr.EINVAL
+	}
+	if mask&linux.FAN_PERM_EVENTS != 0 && g.flags&linux.FAN_ALL_CLASS_BITS == linux.FAN_CLASS_NOTIF {
+		return linuxerr.EINVAL
+	}
+
+	g.mu.Lock()
+	m, ok := g.lookupMarkLocked(obj)
+	if flags&linux.FAN_MARK_REMOVE != 0 {
+		if !ok {
+			g.mu.Unlock()
+			return linuxerr.ENOENT
+		}
+		if flags&linux.FAN_MARK_IGNORED_MASK != 0 {
+			m.ignoredMask &^= mask
+		} else {
+			m.mask &^= mask
+		}
+		if !m.empty() {
+			g.mu.Unlock()
+			return nil
+		}
+		g.deleteMarkLocked(obj)
+		g.mu.Unlock()
+		decRefMarked(ctx, obj)
+		return nil
+	}
+
+	if !ok {
+		if g.flags&linux.FAN_UNLIMITED_MARKS == 0 && len(g.inodeMarks)+len(g.mountMarks)+len(g.fsMarks) >= maxMarks {
+			g.mu.Unlock()
+			return linuxerr.ENOSPC
+		}
+		m = &mark{}
+		g.insertMarkLocked(obj, m)
+		incRefMarked(obj)
+	}
+	if flags&linux.FAN_MARK_IGNORED_MASK != 0 {
+		m.ignoredMask |= mask
+		if flags&linux.FAN_MARK_IGNORED_SURV_MODIFY != 0 {
+			m.survModify = true
+		}
+	} else {
+		m.mask |= mask
+	}
+	g.mu.Unlock()
+	return nil
+}
+
+// Flush implements fanotify_mark(FAN_MARK_FLUSH). markType is
+// FAN_MARK_INODE, FAN_MARK_MOUNT or FAN_MARK_FILESYSTEM.
+func (g *Group) Flush(ctx context.Context, markType uint32) {
+	var objs []interface{}
+	g.mu.Lock()
+	switch markType {
+	case linux.FAN_MARK_INODE:
+		for d := range g.inodeMarks {
+			objs = append(objs, d)
+		}
+		g.inodeMarks = make(map[*vfs.Dentry]*mark)
+	case linux.FAN_MARK_MOUNT:
+		for mnt := range g.mountMarks {
+			objs = append(objs, mnt)
+		}
+		g.mountMarks = make(map[*vfs.Mount]*mark)
+	case linux.FAN_MARK_FILESYSTEM:
+		for fs := range g.fsMarks {
+			objs = append(objs, fs)
+		}
+		g.fsMarks = make(map[*vfs.Filesystem]*mark)
+	}
+	g.mu.Unlock()
+	for _, obj := range objs {
+		decRefMarked(ctx, obj)
+	}
+}
+
+// Preconditions: g.mu must be locked.
+func (g *Group) lookupMarkLocked(obj interface{}) (*mark, bool) {
+	var m *mark
+	var ok bool
+	switch obj := obj.(type) {
+	case *vfs.Dentry:
+		m, ok = g.inodeMarks[obj]
+	case *vfs.Mount:
+		m, ok = g.mountMarks[obj]
+	case *vfs.Filesystem:
+		m, ok = g.fsMarks[obj]
+	default:
+		panic(fmt.Sprintf("unknown fanotify mark object type %T", obj))
+	}
+	return m, ok
+}
+
+// Preconditions: g.mu must be locked.
+func (g *Group) insertMarkLocked(obj interface{}, m *mark) {
+	switch obj := obj.(type) {
+	case *vfs.Dentry:
+		g.inodeMarks[obj] = m
+	case *vfs.Mount:
+		g.mountMarks[obj] = m
+	case *vfs.Filesystem:
+		g.fsMarks[obj] = m
+	}
+}
+
+// Preconditions: g.mu must be locked.
+func (g *Group) deleteMarkLocked(obj interface{}) {
+	switch obj := obj.(type) {
+	case *vfs.Dentry:
+		delete(g.inodeMarks, obj)
+	case *vfs.Mount:
+		delete(g.mountMarks, obj)
+	case *vfs.Filesystem:
+		delete(g.fsMarks, obj)
+	}
+}
+
+func incRefMarked(obj interface{}) {
+	switch obj := obj.(type) {
+	case *vfs.Dentry:
+		obj.IncRef()
+	case *vfs.Mount:
+		obj.IncRef()
+	case *vfs.Filesystem:
+		obj.IncRef()
+	}
+}
+
+func decRefMarked(ctx context.Context, obj interface{}) {
+	switch obj := obj.(type) {
+	case *vfs.Dentry:
+		obj.DecRef(ctx)
+	case *vfs.Mount:
+		obj.DecRef(ctx)
+	case *vfs.Filesystem:
+		obj.DecRef(ctx)
+	}
+}
@@ -106,7 +106,7 @@ func (d *dentry) createSyntheticChildLocked(opts *createSyntheticOpts) {
 		readFD:    -1,
 		writeFD:   -1,
 		mmapFD:    -1,
-		nlink:     uint32(2),
+		nlink:     &linkCount{count: 2},
 	}
 	refsvfs2.Register(child)
 	switch opts.mode.FileType() {
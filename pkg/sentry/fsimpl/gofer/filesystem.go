@@ -74,6 +74,16 @@ func (fs *filesystem) Sync(ctx context.Context) error {
 		}
 	}
 
+	// Finally, ask the remote filesystem to sync itself, which also covers
+	// files that aren't open in the sentry. Older gofers don't support
+	// Tsyncfs.
+	if err := fs.root.file.syncFS(ctx); err != nil && !linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+		ctx.Infof("gofer.filesystem.Sync: p9file.syncFS failed: %v", err)
+		if retErr == nil {
+			retErr = err
+		}
+	}
+
 	return retErr
 }
 
@@ -357,6 +367,11 @@ func (fs *filesystem) doCreateAt(ctx context.Context, rp *vfs.ResolvingPath, dir
 	parent.dirMu.Lock()
 	defer parent.dirMu.Unlock()
 
+	// parent may have been removed since the check above; unlinkAt() holds
+	// parent.dirMu while deleting it, so this check is stable.
+	if parent.isDeleted() {
+		return linuxerr.ENOENT
+	}
 	if len(name) > maxFilenameLen {
 		return linuxerr.ENAMETOOLONG
 	}
@@ -600,6 +615,8 @@ func (fs *filesystem) unlinkAt(ctx context.Context, rp *vfs.ResolvingPath, dir b
 		parent.touchCMtime()
 		if dir {
 			parent.decLinks()
+		} else if child != nil {
+			child.decLinks()
 		}
 	}
 	return nil
@@ -666,24 +683,30 @@ func (fs *filesystem) LinkAt(ctx context.Context, rp *vfs.ResolvingPath, vd vfs.
 		if d.isDir() {
 			return linuxerr.EPERM
 		}
+		// Compare Linux's fs/namei.c:vfs_link().
+		if d.isImmutable() || d.isAppendOnly() {
+			return linuxerr.EPERM
+		}
 		gid := auth.KGID(atomic.LoadUint32(&d.gid))
 		uid := auth.KUID(atomic.LoadUint32(&d.uid))
 		mode := linux.FileMode(atomic.LoadUint32(&d.mode))
 		if err := vfs.MayLink(rp.Credentials(), mode, uid, gid); err != nil {
 			return err
 		}
-		if d.nlink == 0 {
+		nlink := atomic.LoadUint32(&d.nlink.count)
+		if nlink == 0 {
 			return linuxerr.ENOENT
 		}
-		if d.nlink == math.MaxUint32 {
+		if nlink == math.MaxUint32 {
 			return linuxerr.EMLINK
 		}
 		if err := parent.file.link(ctx, d.file, childName); err != nil {
 			return err
 		}
 
-		// Success!
-		atomic.AddUint32(&d.nlink, 1)
+		// Success! This is visible through all dentries for the same file,
+		// including the one that will be instantiated for the new link.
+		atomic.AddUint32(&d.nlink.count, 1)
 		return nil
 	}, nil)
 }
@@ -894,9 +917,24 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 	if err := d.checkPermissions(rp.Credentials(), ats); err != nil {
 		return nil, err
 	}
+	// Compare Linux's fs/namei.c:may_open().
+	if d.isAppendOnly() {
+		if (ats.MayWrite() && opts.Flags&linux.O_APPEND == 0) || opts.Flags&linux.O_TRUNC != 0 {
+			return nil, linuxerr.EPERM
+		}
+	}
 
 	trunc := opts.Flags&linux.O_TRUNC != 0 && d.fileType() == linux.S_IFREG
+	truncated := false
 	if trunc {
+		// If truncation changed the file size, generate IN_MODIFY after
+		// unlocking metadataMu below. This precedes IN_OPEN, which is
+		// generated by VFS, as in Linux.
+		defer func() {
+			if truncated {
+				d.InotifyWithParent(ctx, linux.IN_MODIFY, 0, vfs.InodeEvent)
+			}
+		}()
 		// Lock metadataMu *while* we open a regular file with O_TRUNC because
 		// open(2) will change the file size on server.
 		d.metadataMu.Lock()
@@ -960,6 +998,10 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 		if d.isSynthetic() {
 			return d.pipe.Open(ctx, mnt, &d.vfsd, opts.Flags, &d.locks)
 		}
+	case linux.S_IFCHR, linux.S_IFBLK:
+		if err := vfs.CheckOpenDevice(mnt); err != nil {
+			return nil, err
+		}
 	}
 
 	if vfd == nil {
@@ -973,7 +1015,8 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 		// step is required even if !d.cachedMetadataAuthoritative() because
 		// d.mappings has to be updated.
 		// d.metadataMu has already been acquired if trunc == true.
-		d.updateSizeLocked(0)
+		truncated = d.size != 0
+		accountWriteCancelled(ctx, d.updateSizeLocked(0))
 
 		if d.cachedMetadataAuthoritative() {
 			d.touchCMtimeLocked()
@@ -1278,6 +1321,10 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 		if opts.Flags&linux.RENAME_NOREPLACE != 0 {
 			return linuxerr.EEXIST
 		}
+		// Compare Linux's fs/namei.c:may_delete().
+		if newParent.isAppendOnly() || replaced.isImmutable() || replaced.isAppendOnly() {
+			return linuxerr.EPERM
+		}
 		replacedVFSD = &replaced.vfsd
 		if replaced.isDir() {
 			if !renamed.isDir() {
@@ -1370,8 +1417,15 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 			// Increase the link count if we did not replace another directory.
 			newParent.incLinks()
 		}
+		if replaced != nil && !replaced.isDir() {
+			replaced.decLinks()
+		}
 	}
-	vfs.InotifyRename(ctx, &renamed.watches, &oldParent.watches, &newParent.watches, oldName, newName, renamed.isDir())
+	var replacedWatches *vfs.Watches
+	if replaced != nil {
+		replacedWatches = &replaced.watches
+	}
+	vfs.InotifyRename(ctx, &renamed.watches, replacedWatches, &oldParent.watches, &newParent.watches, oldName, newName, renamed.isDir())
 	return nil
 }
 
@@ -1603,6 +1657,9 @@ func (fs *filesystem) MountOptions() string {
 			optsKV = append(optsKV, mopt{moptCache, cacheRemoteRevalidating})
 		}
 	}
+	if fs.opts.revalidateTTL != 0 {
+		optsKV = append(optsKV, mopt{moptRevalidateTTL, fs.opts.revalidateTTL})
+	}
 	if fs.opts.forcePageCache {
 		optsKV = append(optsKV, mopt{moptForcePageCache, nil})
 	}
@@ -1619,3 +1676,15 @@ func (fs *filesystem) MountOptions() string {
 	}
 	return strings.Join(opts, ",")
 }
+
+// MountStats implements vfs.MountStatsImpl.MountStats.
+func (fs *filesystem) MountStats() string {
+	fs.cacheMu.Lock()
+	cachedDentries := fs.cachedDentriesLen
+	fs.cacheMu.Unlock()
+	return fmt.Sprintf("revalidations=%d revalidations_skipped=%d invalidations=%d cached_dentries=%d",
+		atomic.LoadUint64(&fs.revalidations),
+		atomic.LoadUint64(&fs.revalidationsSkipped),
+		atomic.LoadUint64(&fs.invalidations),
+		cachedDentries)
+}
@@ -28,6 +28,7 @@
 //               *** "memmap.Mappable locks taken by Translate" below this point
 //               dentry.handleMu
 //                 dentry.dataMu
+//                   dentry.prefetchMu
 //             filesystem.inoMu
 //   specialFileFD.mu
 //     specialFileFD.bufMu
@@ -42,6 +43,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -49,11 +51,16 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/p9"
 	refs_vfs1 "gvisor.dev/gvisor/pkg/refs"
 	"gvisor.dev/gvisor/pkg/refsvfs2"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
 	fslock "gvisor.dev/gvisor/pkg/sentry/fs/lock"
+	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/pipe"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
@@ -63,6 +70,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/unet"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // Name is the default filesystem name.
@@ -83,6 +91,8 @@ const (
 	moptForcePageCache         = "force_page_cache"
 	moptLimitHostFDTranslation = "limit_host_fd_translation"
 	moptOverlayfsStaleRead     = "overlayfs_stale_read"
+	moptInodeFlags             = "inodeflags"
+	moptRevalidateTTL          = "revalidate_ttl"
 )
 
 // Valid values for the "cache" mount option.
@@ -154,12 +164,26 @@ type filesystem struct {
 	specialFileFDs   map[*specialFileFD]struct{}
 
 	// inoByQIDPath maps previously-observed QID.Paths to inode numbers
-	// assigned to those paths. inoByQIDPath is not preserved across
-	// checkpoint/restore because QIDs may be reused between different gofer
-	// processes, so QIDs may be repeated for different files across
-	// checkpoint/restore. inoByQIDPath is protected by inoMu.
-	inoMu        sync.Mutex        `state:"nosave"`
-	inoByQIDPath map[uint64]uint64 `state:"nosave"`
+	// assigned to those paths. inoByQIDPath is protected by inoMu.
+	//
+	// inoByQIDPath is preserved across checkpoint/restore so that files keep
+	// their inode numbers. However, QIDs are only guaranteed to be stable
+	// within the lifetime of a gofer process; for example, runsc's gofer
+	// numbers host devices other than the mount's own in the order it
+	// encounters them. If any restored dentry's QID has changed, the saved
+	// mapping is discarded except for restored dentries, since a saved QID
+	// may now refer to a different file; see filesystem.CompleteRestore.
+	inoMu        sync.Mutex `state:"nosave"`
+	inoByQIDPath map[uint64]uint64
+
+	// linkCounts maps inode numbers to the link counts shared by all
+	// dentries representing the corresponding files. linkCounts is protected
+	// by inoMu.
+	linkCounts map[uint64]*linkCount
+
+	// restoreQIDsChanged is set during restore if a restored dentry's QID
+	// differs from the one it had when saved. It is protected by inoMu.
+	restoreQIDsChanged bool `state:"nosave"`
 
 	// lastIno is the last inode number assigned to a file. lastIno is accessed
 	// using atomic memory operations.
@@ -171,6 +195,31 @@ type filesystem struct {
 	// released is nonzero once filesystem.Release has been called. It is accessed
 	// with atomic memory operations.
 	released int32
+
+	// prefetches counts goroutines started by dentry.startPrefetch that have
+	// not yet exited, so that PrepareSave can wait for them.
+	prefetches sync.WaitGroup `state:"nosave"`
+
+	// prefetchesCanceled is set to 1 by PrepareSave to cause in-flight
+	// prefetches to be abandoned at the next chunk boundary.
+	// prefetchesCanceled is accessed using atomic memory operations.
+	prefetchesCanceled uint32 `state:"nosave"`
+
+	// Cache statistics, reported in /proc/[pid]/mountstats. These fields are
+	// accessed using atomic memory operations.
+	//
+	// - revalidations is the number of times cached dentries were revalidated
+	// against the remote filesystem.
+	//
+	// - revalidationsSkipped is the number of revalidations that were elided
+	// because all dentries involved were revalidated within
+	// filesystemOptions.revalidateTTL.
+	//
+	// - invalidations is the number of cached dentries that were found to be
+	// stale during revalidation.
+	revalidations        uint64
+	revalidationsSkipped uint64
+	invalidations        uint64
 }
 
 // +stateify savable
@@ -214,6 +263,18 @@ type filesystemOptions struct {
 	// way that application FDs representing "special files" such as sockets
 	// do. Note that this disables client caching and mmap for regular files.
 	regularFilesUseSpecialFileFD bool
+
+	// If inodeFlags is true, inode flags (FS_IOC_GETFLAGS and
+	// FS_IOC_SETFLAGS) on regular files and directories are forwarded to the
+	// remote filesystem.
+	inodeFlags bool
+
+	// If revalidateTTL is non-zero, InteropModeShared is in effect, and a
+	// dentry's metadata was fetched from the remote filesystem less than
+	// revalidateTTL ago, the dentry is assumed to be up to date and is not
+	// revalidated. This trades coherence with other users of the remote
+	// filesystem for fewer round trips to the server.
+	revalidateTTL time.Duration
 }
 
 // InteropMode controls the client's interaction with other remote filesystem
@@ -427,9 +488,29 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		delete(mopts, moptOverlayfsStaleRead)
 		fsopts.overlayfsStaleRead = true
 	}
+	if _, ok := mopts[moptInodeFlags]; ok {
+		delete(mopts, moptInodeFlags)
+		fsopts.inodeFlags = true
+	}
 	// fsopts.regularFilesUseSpecialFileFD can only be enabled by specifying
 	// "cache=none".
 
+	// Parse the revalidation TTL, which is only meaningful if the remote
+	// filesystem is shared.
+	if str, ok := mopts[moptRevalidateTTL]; ok {
+		delete(mopts, moptRevalidateTTL)
+		ttl, err := time.ParseDuration(str)
+		if err != nil || ttl < 0 {
+			ctx.Warningf("gofer.FilesystemType.GetFilesystem: invalid revalidation TTL: %s=%s", moptRevalidateTTL, str)
+			return nil, nil, linuxerr.EINVAL
+		}
+		if fsopts.interop != InteropModeShared {
+			ctx.Warningf("gofer.FilesystemType.GetFilesystem: %s requires %s=%s or %s=%s", moptRevalidateTTL, moptCache, cacheRemoteRevalidating, moptCache, cacheNone)
+			return nil, nil, linuxerr.EINVAL
+		}
+		fsopts.revalidateTTL = ttl
+	}
+
 	// Check for unparsed options.
 	if len(mopts) != 0 {
 		ctx.Warningf("gofer.FilesystemType.GetFilesystem: unknown options: %v", mopts)
@@ -458,6 +539,7 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		syncableDentries: make(map[*dentry]struct{}),
 		specialFileFDs:   make(map[*specialFileFD]struct{}),
 		inoByQIDPath:     make(map[uint64]uint64),
+		linkCounts:       make(map[uint64]*linkCount),
 	}
 	fs.vfsfs.Init(vfsObj, &fstype, fs)
 
@@ -753,10 +835,25 @@ type dentry struct {
 	atimeDirty uint32
 	mtimeDirty uint32
 
-	// nlink counts the number of hard links to this dentry. It's updated and
-	// accessed using atomic operations. It's not protected by metadataMu like the
-	// other metadata fields.
-	nlink uint32
+	// nlink counts the number of hard links to the file represented by this
+	// dentry. It is shared with all other dentries for the same file (i.e.
+	// with the same inode number), so that hard links agree on it. nlink is
+	// immutable; nlink.count is updated and accessed using atomic operations
+	// and is not protected by metadataMu like the other metadata fields.
+	nlink *linkCount
+
+	// If filesystemOptions.inodeFlags is true and this dentry represents a
+	// regular file or directory, flags is the set of inode flags
+	// (linux.FS_*_FL) most recently observed on the remote file. Otherwise,
+	// flags is 0. flags is accessed using atomic memory operations.
+	flags uint32
+
+	// revalidated is the time, in nanoseconds according to filesystem.clock,
+	// at which d's metadata was last fetched from the remote filesystem. It is
+	// only used if filesystemOptions.revalidateTTL is non-zero, and is
+	// accessed using atomic memory operations. It is not saved, so that
+	// dentries are always revalidated after restore.
+	revalidated int64 `state:"nosave"`
 
 	mapsMu sync.Mutex `state:"nosave"`
 
@@ -810,6 +907,20 @@ type dentry struct {
 	// tracks dirty segments in cache. dirty is protected by dataMu.
 	dirty fsutil.DirtySet
 
+	// If this dentry represents a regular file that is client-cached,
+	// prefetched tracks pages in cache that were read by a prefetch (see
+	// dentry.startPrefetch) and have not been read by the application since.
+	// prefetched is protected by prefetchMu.
+	prefetchMu sync.Mutex  `state:"nosave"`
+	prefetched prefetchSet `state:"nosave"`
+
+	// prefetchedPages is the number of pages of the file that have been
+	// prefetched, and prefetchUsedPages is the number of those pages that
+	// were subsequently read. Both are accessed using atomic memory
+	// operations.
+	prefetchedPages   uint64 `state:"nosave"`
+	prefetchUsedPages uint64 `state:"nosave"`
+
 	// pf implements platform.File for mappings of hostFD.
 	pf dentryPlatformFile
 
@@ -845,6 +956,7 @@ type dentry struct {
 func dentryAttrMask() p9.AttrMask {
 	return p9.AttrMask{
 		Mode:  true,
+		NLink: true,
 		UID:   true,
 		GID:   true,
 		ATime: true,
@@ -909,8 +1021,16 @@ func (fs *filesystem) newDentry(ctx context.Context, file p9file, qid p9.QID, ma
 	if mask.BTime {
 		d.btime = dentryTimestampFromP9(attr.BTimeSeconds, attr.BTimeNanoSeconds)
 	}
+	d.nlink = fs.acquireLinkCount(d.ino)
 	if mask.NLink {
-		d.nlink = uint32(attr.NLink)
+		atomic.StoreUint32(&d.nlink.count, uint32(attr.NLink))
+	}
+	if fs.opts.inodeFlags && (attr.Mode.FileType() == p9.ModeRegular || attr.Mode.FileType() == p9.ModeDirectory) {
+		// If the remote filesystem doesn't support inode flags on this file,
+		// behave as if none are set.
+		if flags, err := file.getFlags(ctx); err == nil {
+			d.flags = flags
+		}
 	}
 	d.vfsd.Init(d)
 	refsvfs2.Register(d)
@@ -935,6 +1055,46 @@ func (fs *filesystem) nextIno() uint64 {
 	return atomic.AddUint64(&fs.lastIno, 1)
 }
 
+// linkCount is the hard link count of a remote file, shared by all dentries
+// representing it.
+//
+// +stateify savable
+type linkCount struct {
+	// count is the link count, or 0 if the remote filesystem doesn't report
+	// link counts. count is accessed using atomic memory operations.
+	count uint32
+
+	// dentries is the number of dentries sharing this linkCount. It is
+	// protected by filesystem.inoMu.
+	dentries int
+}
+
+// acquireLinkCount returns the linkCount for the file with inode number ino,
+// creating it if necessary. The caller must eventually call
+// filesystem.releaseLinkCount.
+func (fs *filesystem) acquireLinkCount(ino uint64) *linkCount {
+	fs.inoMu.Lock()
+	defer fs.inoMu.Unlock()
+	lc, ok := fs.linkCounts[ino]
+	if !ok {
+		lc = &linkCount{}
+		fs.linkCounts[ino] = lc
+	}
+	lc.dentries++
+	return lc
+}
+
+// releaseLinkCount releases a linkCount returned by acquireLinkCount.
+func (fs *filesystem) releaseLinkCount(ino uint64) {
+	fs.inoMu.Lock()
+	defer fs.inoMu.Unlock()
+	lc := fs.linkCounts[ino]
+	lc.dentries--
+	if lc.dentries == 0 {
+		delete(fs.linkCounts, ino)
+	}
+}
+
 func (d *dentry) isSynthetic() bool {
 	return d.file.isNil()
 }
@@ -979,11 +1139,30 @@ func (d *dentry) updateFromP9AttrsLocked(mask p9.AttrMask, attr *p9.Attr) {
 		atomic.StoreInt64(&d.btime, dentryTimestampFromP9(attr.BTimeSeconds, attr.BTimeNanoSeconds))
 	}
 	if mask.NLink {
-		atomic.StoreUint32(&d.nlink, uint32(attr.NLink))
+		atomic.StoreUint32(&d.nlink.count, uint32(attr.NLink))
 	}
 	if mask.Size {
 		d.updateSizeLocked(attr.Size)
 	}
+	if d.fs.opts.revalidateTTL != 0 {
+		atomic.StoreInt64(&d.revalidated, d.fs.clock.Now().Nanoseconds())
+	}
+}
+
+// revalidatedRecently returns true if d's metadata was fetched from the remote
+// filesystem within filesystemOptions.revalidateTTL, such that it does not need
+// to be revalidated yet.
+func (d *dentry) revalidatedRecently() bool {
+	if d.fs.opts.revalidateTTL == 0 || d.isSynthetic() {
+		return false
+	}
+	last := atomic.LoadInt64(&d.revalidated)
+	if last == 0 {
+		return false
+	}
+	// Treat the realtime clock going backwards as expiry.
+	elapsed := d.fs.clock.Now().Nanoseconds() - last
+	return elapsed >= 0 && elapsed < d.fs.opts.revalidateTTL.Nanoseconds()
 }
 
 // Preconditions: !d.isSynthetic().
@@ -1044,6 +1223,11 @@ func (d *dentry) updateFromGetattrLocked(ctx context.Context) error {
 	}
 
 	_, attrMask, attr, err := file.getAttr(ctx, dentryAttrMask())
+	if err == nil && d.fs.opts.inodeFlags && (d.isRegularFile() || d.isDir()) {
+		if flags, err := file.getFlags(ctx); err == nil {
+			atomic.StoreUint32(&d.flags, flags)
+		}
+	}
 	if handleMuRLocked {
 		// handleMu must be released before updateFromP9AttrsLocked().
 		d.handleMu.RUnlock() // +checklocksforce: complex case.
@@ -1062,7 +1246,7 @@ func (d *dentry) fileType() uint32 {
 func (d *dentry) statTo(stat *linux.Statx) {
 	stat.Mask = linux.STATX_TYPE | linux.STATX_MODE | linux.STATX_NLINK | linux.STATX_UID | linux.STATX_GID | linux.STATX_ATIME | linux.STATX_MTIME | linux.STATX_CTIME | linux.STATX_INO | linux.STATX_SIZE | linux.STATX_BLOCKS | linux.STATX_BTIME
 	stat.Blksize = atomic.LoadUint32(&d.blockSize)
-	stat.Nlink = atomic.LoadUint32(&d.nlink)
+	stat.Nlink = atomic.LoadUint32(&d.nlink.count)
 	if stat.Nlink == 0 {
 		// The remote filesystem doesn't support link count; just make
 		// something up. This is consistent with Linux, where
@@ -1085,6 +1269,15 @@ func (d *dentry) statTo(stat *linux.Statx) {
 	stat.Mtime = linux.NsecToStatxTimestamp(atomic.LoadInt64(&d.mtime))
 	stat.DevMajor = linux.UNNAMED_MAJOR
 	stat.DevMinor = d.fs.devMinor
+	if d.fs.opts.inodeFlags {
+		stat.AttributesMask = linux.STATX_ATTR_IMMUTABLE | linux.STATX_ATTR_APPEND
+		if d.isImmutable() {
+			stat.Attributes |= linux.STATX_ATTR_IMMUTABLE
+		}
+		if d.isAppendOnly() {
+			stat.Attributes |= linux.STATX_ATTR_APPEND
+		}
+	}
 }
 
 func (d *dentry) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.SetStatOptions, mnt *vfs.Mount) error {
@@ -1095,6 +1288,19 @@ func (d *dentry) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs
 	if stat.Mask&^(linux.STATX_MODE|linux.STATX_UID|linux.STATX_GID|linux.STATX_ATIME|linux.STATX_MTIME|linux.STATX_SIZE) != 0 {
 		return linuxerr.EPERM
 	}
+	// Compare Linux's fs/attr.c:notify_change() and fs/open.c:do_truncate().
+	// Only updating timestamps to the current time is permitted on
+	// append-only files.
+	if d.isImmutable() {
+		return linuxerr.EPERM
+	}
+	if d.isAppendOnly() {
+		if stat.Mask&(linux.STATX_MODE|linux.STATX_UID|linux.STATX_GID|linux.STATX_SIZE) != 0 ||
+			(stat.Mask&linux.STATX_ATIME != 0 && stat.Atime.Nsec != linux.UTIME_NOW) ||
+			(stat.Mask&linux.STATX_MTIME != 0 && stat.Mtime.Nsec != linux.UTIME_NOW) {
+			return linuxerr.EPERM
+		}
+	}
 	mode := linux.FileMode(atomic.LoadUint32(&d.mode))
 	if err := vfs.CheckSetStat(ctx, creds, opts, mode, auth.KUID(atomic.LoadUint32(&d.uid)), auth.KGID(atomic.LoadUint32(&d.gid))); err != nil {
 		return err
@@ -1195,7 +1401,8 @@ func (d *dentry) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs
 				// d.size should be kept up to date, and privatized
 				// copy-on-write mappings of truncated pages need to be
 				// invalidated, even if InteropModeShared is in effect.
-				d.updateSizeAndUnlockDataMuLocked(stat.Size) // +checklocksforce: locked conditionally above
+				cancelled := d.updateSizeAndUnlockDataMuLocked(stat.Size) // +checklocksforce: locked conditionally above
+				accountWriteCancelled(ctx, cancelled)
 			}
 		}
 		if d.fs.opts.interop == InteropModeShared {
@@ -1232,15 +1439,42 @@ func (d *dentry) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs
 	return nil
 }
 
+// allocate implements fallocate(2) with the given mode for a regular file d.
+// The allocate callback performs the allocation on the remote file.
+func (d *dentry) allocate(ctx context.Context, mode, offset, length uint64, allocate func() error) error {
+	// Compare Linux's fs/open.c:vfs_fallocate().
+	if d.isImmutable() || (d.isAppendOnly() && mode&^linux.FALLOC_FL_KEEP_SIZE != 0) {
+		return linuxerr.EPERM
+	}
+	if mode&(linux.FALLOC_FL_UNSHARE_RANGE|linux.FALLOC_FL_KEEP_SIZE) != 0 {
+		// Unsharing and preallocation beyond EOF affect the remote file's
+		// extents, so they must reach the remote file even if the file size
+		// doesn't change.
+		if err := allocate(); err != nil {
+			return err
+		}
+		if mode&linux.FALLOC_FL_KEEP_SIZE != 0 {
+			if d.cachedMetadataAuthoritative() {
+				d.touchCtime()
+			}
+			return nil
+		}
+		return d.doAllocate(ctx, offset, length, func() error { return nil })
+	}
+	return d.doAllocate(ctx, offset, length, allocate)
+}
+
 // doAllocate performs an allocate operation on d. Note that d.metadataMu will
 // be held when allocate is called.
 func (d *dentry) doAllocate(ctx context.Context, offset, length uint64, allocate func() error) error {
 	d.metadataMu.Lock()
 	defer d.metadataMu.Unlock()
 
-	// Allocating a smaller size is a noop.
+	// Allocating a smaller size doesn't change the file, but still updates
+	// ctime, consistent with Linux.
 	size := offset + length
 	if d.cachedMetadataAuthoritative() && size <= d.size {
+		atomic.StoreInt64(&d.ctime, d.fs.clock.Now().Nanoseconds())
 		return nil
 	}
 
@@ -1255,17 +1489,24 @@ func (d *dentry) doAllocate(ctx context.Context, offset, length uint64, allocate
 	return nil
 }
 
+// updateSizeLocked returns the number of bytes of dirty cached data that were
+// discarded without being written back.
+//
 // Preconditions: d.metadataMu must be locked.
-func (d *dentry) updateSizeLocked(newSize uint64) {
+func (d *dentry) updateSizeLocked(newSize uint64) uint64 {
 	d.dataMu.Lock()
-	d.updateSizeAndUnlockDataMuLocked(newSize)
+	return d.updateSizeAndUnlockDataMuLocked(newSize)
 }
 
+// updateSizeAndUnlockDataMuLocked returns the number of bytes of dirty cached
+// data that were discarded without being written back.
+//
 // Preconditions: d.metadataMu and d.dataMu must be locked.
 //
 // Postconditions: d.dataMu is unlocked.
 // +checklocksrelease:d.dataMu
-func (d *dentry) updateSizeAndUnlockDataMuLocked(newSize uint64) {
+func (d *dentry) updateSizeAndUnlockDataMuLocked(newSize uint64) uint64 {
+	var cancelled uint64
 	oldSize := d.size
 	atomic.StoreUint64(&d.size, newSize)
 	// d.dataMu must be unlocked to lock d.mapsMu and invalidate mappings
@@ -1293,12 +1534,32 @@ func (d *dentry) updateSizeAndUnlockDataMuLocked(newSize uint64) {
 		// should be dropped without being written back.
 		d.dataMu.Lock()
 		d.cache.Truncate(newSize, d.fs.mfp.MemoryFile())
-		d.dirty.KeepClean(memmap.MappableRange{newSize, oldpgend})
+		truncMR := memmap.MappableRange{newSize, oldpgend}
+		for seg := d.dirty.LowerBoundSegment(truncMR.Start); seg.Ok() && seg.Start() < truncMR.End; seg = seg.NextSegment() {
+			cancelled += seg.Range().Intersect(truncMR).Length()
+		}
+		d.dirty.KeepClean(truncMR)
 		d.dataMu.Unlock()
 	}
+	return cancelled
+}
+
+// accountWriteCancelled charges n bytes of dirty cached data that were
+// discarded without being written back to the task in ctx, if any.
+func accountWriteCancelled(ctx context.Context, n uint64) {
+	if n == 0 {
+		return
+	}
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		t.AccountWriteCancelledIO(int64(n))
+	}
 }
 
 func (d *dentry) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
+	// Compare Linux's fs/namei.c:inode_permission().
+	if ats.MayWrite() && d.isImmutable() {
+		return linuxerr.EPERM
+	}
 	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(atomic.LoadUint32(&d.mode)), auth.KUID(atomic.LoadUint32(&d.uid)), auth.KGID(atomic.LoadUint32(&d.gid)))
 }
 
@@ -1321,6 +1582,10 @@ func (d *dentry) checkXattrPermissions(creds *auth.Credentials, name string, ats
 }
 
 func (d *dentry) mayDelete(creds *auth.Credentials, child *dentry) error {
+	// Compare Linux's fs/namei.c:may_delete().
+	if d.isAppendOnly() || child.isImmutable() || child.isAppendOnly() {
+		return linuxerr.EPERM
+	}
 	return vfs.CheckDeleteSticky(
 		creds,
 		linux.FileMode(atomic.LoadUint32(&d.mode)),
@@ -1330,6 +1595,16 @@ func (d *dentry) mayDelete(creds *auth.Credentials, child *dentry) error {
 	)
 }
 
+// isImmutable returns true if d is marked immutable by FS_IMMUTABLE_FL.
+func (d *dentry) isImmutable() bool {
+	return atomic.LoadUint32(&d.flags)&linux.FS_IMMUTABLE_FL != 0
+}
+
+// isAppendOnly returns true if d is marked append-only by FS_APPEND_FL.
+func (d *dentry) isAppendOnly() bool {
+	return atomic.LoadUint32(&d.flags)&linux.FS_APPEND_FL != 0
+}
+
 func dentryUIDFromP9UID(uid p9.UID) uint32 {
 	if !uid.Ok() {
 		return uint32(auth.OverflowUID)
@@ -1652,12 +1927,14 @@ func (d *dentry) destroyLocked(ctx context.Context) {
 		d.dirty.RemoveAll()
 	}
 	d.dataMu.Unlock()
-	// Clunk open fids and close open host FDs.
+	// Clunk open fids and close open host FDs. Fids are clunked together
+	// with d.file below, in a single round trip if possible.
+	toClose := make([]p9file, 0, 3)
 	if !d.readFile.isNil() {
-		_ = d.readFile.close(ctx)
+		toClose = append(toClose, d.readFile)
 	}
 	if !d.writeFile.isNil() && d.readFile != d.writeFile {
-		_ = d.writeFile.close(ctx)
+		toClose = append(toClose, d.writeFile)
 	}
 	d.readFile = p9file{}
 	d.writeFile = p9file{}
@@ -1681,15 +1958,18 @@ func (d *dentry) destroyLocked(ctx context.Context) {
 		// instantiated for the same file would remain coherent. Unfortunately,
 		// this turns out to be too expensive in many cases, so for now we
 		// don't do this.
-		if err := d.file.close(ctx); err != nil {
-			log.Warningf("gofer.dentry.destroyLocked: failed to close file: %v", err)
-		}
+		toClose = append(toClose, d.file)
 		d.file = p9file{}
 
 		// Remove d from the set of syncable dentries.
 		d.fs.syncMu.Lock()
 		delete(d.fs.syncableDentries, d)
 		d.fs.syncMu.Unlock()
+
+		d.fs.releaseLinkCount(d.ino)
+	}
+	if err := closeFiles(ctx, d.fs.client, toClose); err != nil {
+		log.Warningf("gofer.dentry.destroyLocked: failed to close file: %v", err)
 	}
 
 	d.fs.renameMu.Lock()
@@ -1739,6 +2019,10 @@ func (d *dentry) setXattr(ctx context.Context, creds *auth.Credentials, opts *vf
 	if d.file.isNil() {
 		return linuxerr.EPERM
 	}
+	// Compare Linux's fs/xattr.c:xattr_permission().
+	if d.isImmutable() || d.isAppendOnly() {
+		return linuxerr.EPERM
+	}
 	if err := d.checkXattrPermissions(creds, opts.Name, vfs.MayWrite); err != nil {
 		return err
 	}
@@ -1749,6 +2033,10 @@ func (d *dentry) removeXattr(ctx context.Context, creds *auth.Credentials, name
 	if d.file.isNil() {
 		return linuxerr.EPERM
 	}
+	// Compare Linux's fs/xattr.c:xattr_permission().
+	if d.isImmutable() || d.isAppendOnly() {
+		return linuxerr.EPERM
+	}
 	if err := d.checkXattrPermissions(creds, name, vfs.MayWrite); err != nil {
 		return err
 	}
@@ -1928,16 +2216,20 @@ func (d *dentry) ensureSharedHandle(ctx context.Context, read, write, trunc bool
 // Preconditions: d.handleMu must be locked.
 func (d *dentry) readHandleLocked() handle {
 	return handle{
-		file: d.readFile,
-		fd:   d.readFD,
+		file:      d.readFile,
+		fd:        d.readFD,
+		accounted: true,
+		devMinor:  d.fs.devMinor,
 	}
 }
 
 // Preconditions: d.handleMu must be locked.
 func (d *dentry) writeHandleLocked() handle {
 	return handle{
-		file: d.writeFile,
-		fd:   d.writeFD,
+		file:      d.writeFile,
+		fd:        d.writeFD,
+		accounted: true,
+		devMinor:  d.fs.devMinor,
 	}
 }
 
@@ -1979,9 +2271,17 @@ func (d *dentry) syncCachedFile(ctx context.Context, forFilesystemSync bool) err
 	defer d.handleMu.RUnlock()
 	h := d.writeHandleLocked()
 	if h.isOpen() {
+		writeAt := h.writeFromBlocksAt
+		if forFilesystemSync {
+			writeAt = func(ctx context.Context, srcs safemem.BlockSeq, offset uint64) (uint64, error) {
+				n, err := h.writeFromBlocksAt(ctx, srcs, offset)
+				fsmetric.GoferSyncBytes.IncrementBy(n)
+				return n, err
+			}
+		}
 		// Write back dirty pages to the remote file.
 		d.dataMu.Lock()
-		err := fsutil.SyncDirtyAll(ctx, &d.cache, &d.dirty, d.size, d.fs.mfp.MemoryFile(), h.writeFromBlocksAt)
+		err := fsutil.SyncDirtyAll(ctx, &d.cache, &d.dirty, d.size, d.fs.mfp.MemoryFile(), writeAt)
 		d.dataMu.Unlock()
 		if err != nil {
 			return err
@@ -2003,20 +2303,20 @@ func (d *dentry) syncCachedFile(ctx context.Context, forFilesystemSync bool) err
 
 // incLinks increments link count.
 func (d *dentry) incLinks() {
-	if atomic.LoadUint32(&d.nlink) == 0 {
+	if atomic.LoadUint32(&d.nlink.count) == 0 {
 		// The remote filesystem doesn't support link count.
 		return
 	}
-	atomic.AddUint32(&d.nlink, 1)
+	atomic.AddUint32(&d.nlink.count, 1)
 }
 
 // decLinks decrements link count.
 func (d *dentry) decLinks() {
-	if atomic.LoadUint32(&d.nlink) == 0 {
+	if atomic.LoadUint32(&d.nlink.count) == 0 {
 		// The remote filesystem doesn't support link count.
 		return
 	}
-	atomic.AddUint32(&d.nlink, ^uint32(0))
+	atomic.AddUint32(&d.nlink.count, ^uint32(0))
 }
 
 // fileDescription is embedded by gofer implementations of
@@ -2044,10 +2344,15 @@ func (fd *fileDescription) Stat(ctx context.Context, opts vfs.StatOptions) (linu
 	d := fd.dentry()
 	const validMask = uint32(linux.STATX_MODE | linux.STATX_UID | linux.STATX_GID | linux.STATX_ATIME | linux.STATX_MTIME | linux.STATX_CTIME | linux.STATX_SIZE | linux.STATX_BLOCKS | linux.STATX_BTIME)
 	if !d.cachedMetadataAuthoritative() && opts.Mask&validMask != 0 && opts.Sync != linux.AT_STATX_DONT_SYNC {
-		// TODO(jamieliu): Use specialFileFD.handle.file for the getattr if
-		// available?
-		if err := d.updateFromGetattr(ctx); err != nil {
-			return linux.Statx{}, err
+		if opts.Sync != linux.AT_STATX_FORCE_SYNC && d.revalidatedRecently() {
+			atomic.AddUint64(&d.fs.revalidationsSkipped, 1)
+		} else {
+			// TODO(jamieliu): Use specialFileFD.handle.file for the getattr if
+			// available?
+			atomic.AddUint64(&d.fs.revalidations, 1)
+			if err := d.updateFromGetattr(ctx); err != nil {
+				return linux.Statx{}, err
+			}
 		}
 	}
 	var stat linux.Statx
@@ -2116,3 +2421,91 @@ func (fd *fileDescription) LockPOSIX(ctx context.Context, uid fslock.UniqueID, o
 func (fd *fileDescription) UnlockPOSIX(ctx context.Context, uid fslock.UniqueID, r fslock.LockRange) error {
 	return fd.Locks().UnlockPOSIX(ctx, uid, r)
 }
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *fileDescription) Ioctl(ctx context.Context, uio usermem.IO, args arch.SyscallArguments) (uintptr, error) {
+	d := fd.dentry()
+	if !d.fs.opts.inodeFlags || d.isSynthetic() || !(d.isRegularFile() || d.isDir()) {
+		return fd.FileDescriptionDefaultImpl.Ioctl(ctx, uio, args)
+	}
+	switch cmd := args[1].Uint(); cmd {
+	case linux.FS_IOC_GETFLAGS:
+		return 0, fd.getFlags(ctx, uio, args[2].Pointer())
+	case linux.FS_IOC_SETFLAGS:
+		return 0, fd.setFlags(ctx, uio, args[2].Pointer())
+	default:
+		return fd.FileDescriptionDefaultImpl.Ioctl(ctx, uio, args)
+	}
+}
+
+// getFlags copies the inode flags of fd's file out to addr.
+func (fd *fileDescription) getFlags(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	d := fd.dentry()
+	flags, err := d.file.getFlags(ctx)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint32(&d.flags, flags)
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	// Despite the ioctl's encoded argument size, Linux copies an int.
+	_, err = primitive.CopyInt32Out(&cc, addr, int32(flags))
+	return err
+}
+
+// setFlags sets the inode flags of fd's file to those at addr.
+//
+// Compare Linux's fs/ioctl.c:ioctl_setflags().
+func (fd *fileDescription) setFlags(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	var flags int32
+	if _, err := primitive.CopyInt32In(&cc, addr, &flags); err != nil {
+		return err
+	}
+	if uint32(flags)&^linux.SettableInodeFlags != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+
+	mnt := fd.vfsfd.Mount()
+	if err := mnt.CheckBeginWrite(); err != nil {
+		return err
+	}
+	defer mnt.EndWrite()
+
+	creds := auth.CredentialsFromContext(ctx)
+	d := fd.dentry()
+	if !vfs.CanActAsOwner(creds, auth.KUID(atomic.LoadUint32(&d.uid))) {
+		return linuxerr.EPERM
+	}
+
+	d.metadataMu.Lock()
+	defer d.metadataMu.Unlock()
+	// Another client may have changed the flags, so check against the remote
+	// file's current flags rather than our cached copy.
+	oldFlags, err := d.file.getFlags(ctx)
+	if err != nil {
+		return err
+	}
+	if (oldFlags^uint32(flags))&(linux.FS_IMMUTABLE_FL|linux.FS_APPEND_FL) != 0 && !creds.HasCapability(linux.CAP_LINUX_IMMUTABLE) {
+		return linuxerr.EPERM
+	}
+	if err := d.file.setFlags(ctx, uint32(flags)); err != nil {
+		return err
+	}
+	atomic.StoreUint32(&d.flags, uint32(flags))
+	if d.cachedMetadataAuthoritative() {
+		atomic.StoreInt64(&d.ctime, d.fs.clock.Now().Nanoseconds())
+	}
+	return nil
+}
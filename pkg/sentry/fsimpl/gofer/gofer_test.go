@@ -17,10 +17,16 @@ package gofer
 import (
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/p9"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 func TestDestroyIdempotent(t *testing.T) {
@@ -33,6 +39,7 @@ func TestDestroyIdempotent(t *testing.T) {
 		},
 		syncableDentries: make(map[*dentry]struct{}),
 		inoByQIDPath:     make(map[uint64]uint64),
+		linkCounts:       make(map[uint64]*linkCount),
 	}
 
 	attr := &p9.Attr{
@@ -66,3 +73,241 @@ func TestDestroyIdempotent(t *testing.T) {
 	child.checkCachingLocked(ctx, true /* renameMuWriteLocked */)
 	child.checkCachingLocked(ctx, true /* renameMuWriteLocked */)
 }
+
+func TestPrefetchAccounting(t *testing.T) {
+	var d dentry
+	const pageSize = hostarch.PageSize
+
+	d.notePrefetched(memmap.MappableRange{0, 4 * pageSize})
+	// A read of part of one page uses that page.
+	d.notePrefetchUsed(memmap.MappableRange{pageSize + 1, pageSize + 2})
+	// A read spanning two pages uses both, but pages that were already used
+	// aren't counted again.
+	d.notePrefetchUsed(memmap.MappableRange{pageSize + 1, 2*pageSize + 1})
+	d.notePrefetchUsed(memmap.MappableRange{pageSize, 2 * pageSize})
+	// Dropped pages are never used.
+	d.forgetPrefetched(memmap.MappableRange{3 * pageSize, 4 * pageSize})
+	d.notePrefetchUsed(memmap.MappableRange{0, 4 * pageSize})
+
+	if prefetched, used := d.prefetchStats(); prefetched != 4 || used != 3 {
+		t.Errorf("d.prefetchStats() = (%d, %d), want (4, 3)", prefetched, used)
+	}
+}
+
+func TestReservePrefetchBytes(t *testing.T) {
+	const pageSize = hostarch.PageSize
+	if got := reservePrefetchBytes(maxPrefetchBytes - pageSize); got != maxPrefetchBytes-pageSize {
+		t.Fatalf("reservePrefetchBytes(%d) = %d, want %d", maxPrefetchBytes-pageSize, got, maxPrefetchBytes-pageSize)
+	}
+	if got := reservePrefetchBytes(2 * pageSize); got != pageSize {
+		t.Errorf("reservePrefetchBytes(%d) = %d, want %d", 2*pageSize, got, pageSize)
+	}
+	if got := reservePrefetchBytes(pageSize); got != 0 {
+		t.Errorf("reservePrefetchBytes(%d) = %d, want 0", pageSize, got)
+	}
+	releasePrefetchBytes(maxPrefetchBytes)
+	if got := reservePrefetchBytes(pageSize); got != pageSize {
+		t.Errorf("reservePrefetchBytes(%d) = %d, want %d", pageSize, got, pageSize)
+	}
+	releasePrefetchBytes(pageSize)
+}
+
+// manualClock is a ktime.Clock whose time only changes when the test advances
+// it.
+type manualClock struct {
+	ktime.WallRateClock
+	ktime.NoClockEvents
+	now int64
+}
+
+// Now implements ktime.Clock.Now.
+func (c *manualClock) Now() ktime.Time {
+	return ktime.FromNanoseconds(c.now)
+}
+
+// getAttrFile is a p9.File that counts calls to MultiGetAttr and always
+// reports the same attributes. All other p9.File methods panic.
+type getAttrFile struct {
+	p9.File
+	qid           p9.QID
+	attr          p9.Attr
+	multiGetAttrs int
+}
+
+// MultiGetAttr implements p9.File.MultiGetAttr.
+func (f *getAttrFile) MultiGetAttr(names []string) ([]p9.FullStat, error) {
+	f.multiGetAttrs++
+	stats := make([]p9.FullStat, len(names))
+	for i := range stats {
+		stats[i] = p9.FullStat{
+			QID:   f.qid,
+			Valid: p9.AttrMask{Mode: true, Size: true},
+			Attr:  f.attr,
+		}
+	}
+	return stats, nil
+}
+
+func newRevalidateTTLFilesystem(ctx context.Context, clock *manualClock, ttl time.Duration) *filesystem {
+	return &filesystem{
+		mfp:   pgalloc.MemoryFileProviderFromContext(ctx),
+		clock: clock,
+		opts: filesystemOptions{
+			interop:       InteropModeShared,
+			revalidateTTL: ttl,
+		},
+		syncableDentries: make(map[*dentry]struct{}),
+		inoByQIDPath:     make(map[uint64]uint64),
+		linkCounts:       make(map[uint64]*linkCount),
+	}
+}
+
+func TestRevalidatedRecently(t *testing.T) {
+	ctx := contexttest.Context(t)
+	const ttl = time.Second
+	clock := &manualClock{now: int64(time.Hour)}
+	fs := newRevalidateTTLFilesystem(ctx, clock, ttl)
+
+	qid := p9.QID{Path: 1}
+	attr := p9.Attr{Mode: p9.ModeRegular}
+	mask := p9.AttrMask{Mode: true, Size: true}
+	d, err := fs.newDentry(ctx, p9file{&getAttrFile{qid: qid, attr: attr}}, qid, mask, &attr)
+	if err != nil {
+		t.Fatalf("fs.newDentry(): %v", err)
+	}
+	// A dentry whose metadata has never been refreshed must be revalidated.
+	if d.revalidatedRecently() {
+		t.Errorf("d.revalidatedRecently() = true before first refresh, want false")
+	}
+
+	d.metadataMu.Lock()
+	d.updateFromP9AttrsLocked(mask, &attr)
+	d.metadataMu.Unlock()
+	for _, tc := range []struct {
+		name    string
+		elapsed time.Duration
+		want    bool
+	}{
+		{name: "just refreshed", elapsed: 0, want: true},
+		{name: "within TTL", elapsed: ttl - 1, want: true},
+		{name: "TTL expired", elapsed: ttl, want: false},
+		{name: "clock went backwards", elapsed: -1, want: false},
+	} {
+		clock.now = d.revalidated + tc.elapsed.Nanoseconds()
+		if got := d.revalidatedRecently(); got != tc.want {
+			t.Errorf("%s: d.revalidatedRecently() = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+
+	// Synthetic dentries have no remote file to revalidate against, so the TTL
+	// never applies to them.
+	synthetic, err := fs.newDentry(ctx, p9file{}, qid, mask, &attr)
+	if err != nil {
+		t.Fatalf("fs.newDentry(): %v", err)
+	}
+	synthetic.metadataMu.Lock()
+	synthetic.updateFromP9AttrsLocked(mask, &attr)
+	synthetic.metadataMu.Unlock()
+	if synthetic.revalidatedRecently() {
+		t.Errorf("synthetic.revalidatedRecently() = true, want false")
+	}
+}
+
+func TestRevalidateTTL(t *testing.T) {
+	ctx := contexttest.Context(t)
+	const ttl = time.Second
+	clock := &manualClock{now: int64(time.Hour)}
+	fs := newRevalidateTTLFilesystem(ctx, clock, ttl)
+
+	qid := p9.QID{Path: 1}
+	attr := p9.Attr{Mode: p9.ModeRegular}
+	mask := p9.AttrMask{Mode: true, Size: true}
+	file := &getAttrFile{qid: qid, attr: attr}
+	d, err := fs.newDentry(ctx, p9file{file}, qid, mask, &attr)
+	if err != nil {
+		t.Fatalf("fs.newDentry(): %v", err)
+	}
+
+	revalidate := func() {
+		t.Helper()
+		fs.renameMu.RLock()
+		defer fs.renameMu.RUnlock()
+		state := makeRevalidateState(d)
+		defer state.release()
+		state.add("", d)
+		var ds *[]*dentry
+		if err := fs.revalidateHelper(ctx, nil /* vfsObj */, state, &ds); err != nil {
+			t.Fatalf("fs.revalidateHelper(): %v", err)
+		}
+	}
+	check := func(step string, wantGetAttrs int, wantRevalidations, wantSkipped uint64) {
+		t.Helper()
+		if file.multiGetAttrs != wantGetAttrs {
+			t.Errorf("%s: MultiGetAttr called %d times, want %d", step, file.multiGetAttrs, wantGetAttrs)
+		}
+		if got := atomic.LoadUint64(&fs.revalidations); got != wantRevalidations {
+			t.Errorf("%s: fs.revalidations = %d, want %d", step, got, wantRevalidations)
+		}
+		if got := atomic.LoadUint64(&fs.revalidationsSkipped); got != wantSkipped {
+			t.Errorf("%s: fs.revalidationsSkipped = %d, want %d", step, got, wantSkipped)
+		}
+	}
+
+	// The first revalidation always goes to the remote filesystem.
+	revalidate()
+	check("first revalidation", 1, 1, 0)
+
+	// Within the TTL, the cached metadata is trusted.
+	clock.now += (ttl / 2).Nanoseconds()
+	revalidate()
+	check("within TTL", 1, 1, 1)
+
+	// Once the TTL has expired since the last refresh, the dentry is
+	// revalidated again, which also restarts the TTL.
+	clock.now += (ttl / 2).Nanoseconds()
+	revalidate()
+	check("after TTL expiry", 2, 2, 1)
+	revalidate()
+	check("after refresh", 2, 2, 2)
+
+	const wantStats = "revalidations=2 revalidations_skipped=2 invalidations=0 cached_dentries=0"
+	if got := fs.MountStats(); got != wantStats {
+		t.Errorf("fs.MountStats() = %q, want %q", got, wantStats)
+	}
+}
+
+// closeCountingFile is a p9.File that counts calls to Close. All other p9.File
+// methods panic.
+type closeCountingFile struct {
+	p9.File
+	closes int
+}
+
+// Close implements p9.File.Close.
+func (f *closeCountingFile) Close() error {
+	f.closes++
+	return nil
+}
+
+func TestCloseFilesReleaseBatch(t *testing.T) {
+	ctx := contexttest.Context(t)
+	var batch vfs.ReleaseBatch
+	batchCtx := vfs.WithReleaseBatch(ctx, &batch)
+
+	file := &closeCountingFile{}
+	if err := closeFiles(batchCtx, nil /* client */, []p9file{{file}}); err != nil {
+		t.Fatalf("closeFiles(): %v", err)
+	}
+	if file.closes != 0 {
+		t.Fatalf("file closed %d times before the batch was flushed, want 0", file.closes)
+	}
+	batch.Flush(ctx)
+	if file.closes != 1 {
+		t.Errorf("file closed %d times after the batch was flushed, want 1", file.closes)
+	}
+	// Flushing an empty batch does nothing.
+	batch.Flush(ctx)
+	if file.closes != 1 {
+		t.Errorf("file closed %d times after the batch was flushed twice, want 1", file.closes)
+	}
+}
@@ -16,10 +16,12 @@ package gofer
 
 import (
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/p9"
 	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/hostfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
 )
 
 // handle represents a remote "open file descriptor", consisting of an opened
@@ -29,6 +31,11 @@ import (
 type handle struct {
 	file p9file
 	fd   int32 // -1 if unavailable
+
+	// If accounted is true, I/O through the handle is charged to the calling
+	// task as I/O to the filesystem with minor device number devMinor.
+	accounted bool
+	devMinor  uint32
 }
 
 // Preconditions: read || write.
@@ -77,7 +84,41 @@ func (h *handle) close(ctx context.Context) {
 	}
 }
 
+// release is equivalent to close, except that h.file is closed by closeFiles,
+// so that closing it may be deferred to a vfs.ReleaseBatch carried by ctx.
+func (h *handle) release(ctx context.Context, client *p9.Client) {
+	closeFiles(ctx, client, []p9file{h.file})
+	h.file = p9file{}
+	if h.fd >= 0 {
+		unix.Close(int(h.fd))
+		h.fd = -1
+	}
+}
+
+// accountIO charges n bytes of I/O through h to the task in ctx, if any.
+func (h *handle) accountIO(ctx context.Context, n uint64, write bool) {
+	if !h.accounted || n == 0 {
+		return
+	}
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return
+	}
+	dev := linux.MakeDeviceID(linux.UNNAMED_MAJOR, h.devMinor)
+	if write {
+		t.AccountWriteIO(dev, int64(n))
+	} else {
+		t.AccountReadIO(dev, int64(n))
+	}
+}
+
 func (h *handle) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, offset uint64) (uint64, error) {
+	n, err := h.readToBlocksAtUnaccounted(ctx, dsts, offset)
+	h.accountIO(ctx, n, false /* write */)
+	return n, err
+}
+
+func (h *handle) readToBlocksAtUnaccounted(ctx context.Context, dsts safemem.BlockSeq, offset uint64) (uint64, error) {
 	if dsts.IsEmpty() {
 		return 0, nil
 	}
@@ -104,6 +145,12 @@ func (h *handle) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, offs
 }
 
 func (h *handle) writeFromBlocksAt(ctx context.Context, srcs safemem.BlockSeq, offset uint64) (uint64, error) {
+	n, err := h.writeFromBlocksAtUnaccounted(ctx, srcs, offset)
+	h.accountIO(ctx, n, true /* write */)
+	return n, err
+}
+
+func (h *handle) writeFromBlocksAtUnaccounted(ctx context.Context, srcs safemem.BlockSeq, offset uint64) (uint64, error) {
 	if srcs.IsEmpty() {
 		return 0, nil
 	}
@@ -18,7 +18,10 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/p9"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 // p9file is a wrapper around p9.File that provides methods that are
@@ -27,19 +30,29 @@ type p9file struct {
 	file p9.File
 }
 
+// goferRPCSleepStart is equivalent to ctx.UninterruptibleSleepStart(false),
+// but additionally records reason as the task's wait reason for
+// /proc/[pid]/stack.
+func goferRPCSleepStart(ctx context.Context, reason string) {
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		t.SetWaitReason(reason)
+	}
+	ctx.UninterruptibleSleepStart(false)
+}
+
 func (f p9file) isNil() bool {
 	return f.file == nil
 }
 
 func (f p9file) walk(ctx context.Context, names []string) ([]p9.QID, p9file, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Walk")
 	qids, newfile, err := f.file.Walk(names)
 	ctx.UninterruptibleSleepFinish(false)
 	return qids, p9file{newfile}, err
 }
 
 func (f p9file) walkGetAttr(ctx context.Context, names []string) ([]p9.QID, p9file, p9.AttrMask, p9.Attr, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:WalkGetAttr")
 	qids, newfile, attrMask, attr, err := f.file.WalkGetAttr(names)
 	ctx.UninterruptibleSleepFinish(false)
 	return qids, p9file{newfile}, attrMask, attr, err
@@ -48,7 +61,7 @@ func (f p9file) walkGetAttr(ctx context.Context, names []string) ([]p9.QID, p9fi
 // walkGetAttrOne is a wrapper around p9.File.WalkGetAttr that takes a single
 // path component and returns a single qid.
 func (f p9file) walkGetAttrOne(ctx context.Context, name string) (p9.QID, p9file, p9.AttrMask, p9.Attr, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:WalkGetAttr")
 	qids, newfile, attrMask, attr, err := f.file.WalkGetAttr([]string{name})
 	ctx.UninterruptibleSleepFinish(false)
 	if err != nil {
@@ -65,182 +78,255 @@ func (f p9file) walkGetAttrOne(ctx context.Context, name string) (p9.QID, p9file
 }
 
 func (f p9file) statFS(ctx context.Context) (p9.FSStat, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:StatFS")
 	fsstat, err := f.file.StatFS()
 	ctx.UninterruptibleSleepFinish(false)
 	return fsstat, err
 }
 
 func (f p9file) getAttr(ctx context.Context, req p9.AttrMask) (p9.QID, p9.AttrMask, p9.Attr, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:GetAttr")
 	qid, attrMask, attr, err := f.file.GetAttr(req)
 	ctx.UninterruptibleSleepFinish(false)
 	return qid, attrMask, attr, err
 }
 
 func (f p9file) setAttr(ctx context.Context, valid p9.SetAttrMask, attr p9.SetAttr) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:SetAttr")
 	err := f.file.SetAttr(valid, attr)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) listXattr(ctx context.Context, size uint64) (map[string]struct{}, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:ListXattr")
 	xattrs, err := f.file.ListXattr(size)
 	ctx.UninterruptibleSleepFinish(false)
 	return xattrs, err
 }
 
 func (f p9file) getXattr(ctx context.Context, name string, size uint64) (string, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:GetXattr")
 	val, err := f.file.GetXattr(name, size)
 	ctx.UninterruptibleSleepFinish(false)
 	return val, err
 }
 
 func (f p9file) setXattr(ctx context.Context, name, value string, flags uint32) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:SetXattr")
 	err := f.file.SetXattr(name, value, flags)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) removeXattr(ctx context.Context, name string) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:RemoveXattr")
 	err := f.file.RemoveXattr(name)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) allocate(ctx context.Context, mode p9.AllocateMode, offset, length uint64) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Allocate")
 	err := f.file.Allocate(mode, offset, length)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
+func (f p9file) getFlags(ctx context.Context) (uint32, error) {
+	goferRPCSleepStart(ctx, "gofer_rpc:GetFlags")
+	flags, err := f.file.GetFlags()
+	ctx.UninterruptibleSleepFinish(false)
+	return flags, err
+}
+
+func (f p9file) setFlags(ctx context.Context, flags uint32) error {
+	goferRPCSleepStart(ctx, "gofer_rpc:SetFlags")
+	err := f.file.SetFlags(flags)
+	ctx.UninterruptibleSleepFinish(false)
+	return err
+}
+
 func (f p9file) close(ctx context.Context) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Close")
 	err := f.file.Close()
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
+// closeFiles closes all of files, in as few round trips to the remote
+// filesystem as possible. It returns the first error encountered, if any.
+//
+// If ctx carries a vfs.ReleaseBatch, files are instead queued on it, to be
+// closed together with all other files released in the batch, and closeFiles
+// returns nil.
+func closeFiles(ctx context.Context, client *p9.Client, files []p9file) error {
+	if len(files) != 0 {
+		if batch := vfs.ReleaseBatchFromContext(ctx); batch != nil {
+			cb := batch.Batcher(client, func() vfs.ReleaseBatcher {
+				return &closeBatch{client: client}
+			}).(*closeBatch)
+			cb.files = append(cb.files, files...)
+			return nil
+		}
+	}
+	switch len(files) {
+	case 0:
+		return nil
+	case 1:
+		return files[0].close(ctx)
+	}
+	p9files := make([]p9.File, len(files))
+	for i, f := range files {
+		p9files[i] = f.file
+	}
+	goferRPCSleepStart(ctx, "gofer_rpc:CloseFiles")
+	errs := client.CloseFiles(p9files)
+	ctx.UninterruptibleSleepFinish(false)
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeBatch is a vfs.ReleaseBatcher that accumulates files to be closed by
+// closeFiles.
+type closeBatch struct {
+	client *p9.Client
+	files  []p9file
+}
+
+// FlushRelease implements vfs.ReleaseBatcher.FlushRelease.
+func (cb *closeBatch) FlushRelease(ctx context.Context) {
+	if err := closeFiles(ctx, cb.client, cb.files); err != nil {
+		log.Warningf("gofer.closeBatch.FlushRelease: failed to close file: %v", err)
+	}
+	cb.files = nil
+}
+
 func (f p9file) setAttrClose(ctx context.Context, valid p9.SetAttrMask, attr p9.SetAttr) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:SetAttrClose")
 	err := f.file.SetAttrClose(valid, attr)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) open(ctx context.Context, flags p9.OpenFlags) (*fd.FD, p9.QID, uint32, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Open")
 	fdobj, qid, iounit, err := f.file.Open(flags)
 	ctx.UninterruptibleSleepFinish(false)
 	return fdobj, qid, iounit, err
 }
 
 func (f p9file) readAt(ctx context.Context, p []byte, offset uint64) (int, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:ReadAt")
 	n, err := f.file.ReadAt(p, offset)
 	ctx.UninterruptibleSleepFinish(false)
 	return n, err
 }
 
 func (f p9file) writeAt(ctx context.Context, p []byte, offset uint64) (int, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:WriteAt")
 	n, err := f.file.WriteAt(p, offset)
 	ctx.UninterruptibleSleepFinish(false)
 	return n, err
 }
 
 func (f p9file) fsync(ctx context.Context) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:FSync")
 	err := f.file.FSync()
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
+func (f p9file) syncFS(ctx context.Context) error {
+	goferRPCSleepStart(ctx, "gofer_rpc:SyncFS")
+	err := f.file.SyncFS()
+	ctx.UninterruptibleSleepFinish(false)
+	return err
+}
+
 func (f p9file) create(ctx context.Context, name string, flags p9.OpenFlags, permissions p9.FileMode, uid p9.UID, gid p9.GID) (*fd.FD, p9file, p9.QID, uint32, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Create")
 	fdobj, newfile, qid, iounit, err := f.file.Create(name, flags, permissions, uid, gid)
 	ctx.UninterruptibleSleepFinish(false)
 	return fdobj, p9file{newfile}, qid, iounit, err
 }
 
 func (f p9file) mkdir(ctx context.Context, name string, permissions p9.FileMode, uid p9.UID, gid p9.GID) (p9.QID, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Mkdir")
 	qid, err := f.file.Mkdir(name, permissions, uid, gid)
 	ctx.UninterruptibleSleepFinish(false)
 	return qid, err
 }
 
 func (f p9file) symlink(ctx context.Context, oldName string, newName string, uid p9.UID, gid p9.GID) (p9.QID, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Symlink")
 	qid, err := f.file.Symlink(oldName, newName, uid, gid)
 	ctx.UninterruptibleSleepFinish(false)
 	return qid, err
 }
 
 func (f p9file) link(ctx context.Context, target p9file, newName string) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Link")
 	err := f.file.Link(target.file, newName)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) mknod(ctx context.Context, name string, mode p9.FileMode, major uint32, minor uint32, uid p9.UID, gid p9.GID) (p9.QID, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Mknod")
 	qid, err := f.file.Mknod(name, mode, major, minor, uid, gid)
 	ctx.UninterruptibleSleepFinish(false)
 	return qid, err
 }
 
 func (f p9file) rename(ctx context.Context, newDir p9file, newName string) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Rename")
 	err := f.file.Rename(newDir.file, newName)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) unlinkAt(ctx context.Context, name string, flags uint32) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:UnlinkAt")
 	err := f.file.UnlinkAt(name, flags)
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) readdir(ctx context.Context, offset uint64, count uint32) ([]p9.Dirent, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Readdir")
 	dirents, err := f.file.Readdir(offset, count)
 	ctx.UninterruptibleSleepFinish(false)
 	return dirents, err
 }
 
 func (f p9file) readlink(ctx context.Context) (string, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Readlink")
 	target, err := f.file.Readlink()
 	ctx.UninterruptibleSleepFinish(false)
 	return target, err
 }
 
 func (f p9file) flush(ctx context.Context) error {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Flush")
 	err := f.file.Flush()
 	ctx.UninterruptibleSleepFinish(false)
 	return err
 }
 
 func (f p9file) connect(ctx context.Context, flags p9.ConnectFlags) (*fd.FD, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:Connect")
 	fdobj, err := f.file.Connect(flags)
 	ctx.UninterruptibleSleepFinish(false)
 	return fdobj, err
 }
 
 func (f p9file) multiGetAttr(ctx context.Context, names []string) ([]p9.FullStat, error) {
-	ctx.UninterruptibleSleepStart(false)
+	goferRPCSleepStart(ctx, "gofer_rpc:MultiGetAttr")
 	stats, err := f.file.MultiGetAttr(names)
 	ctx.UninterruptibleSleepFinish(false)
 	return stats, err
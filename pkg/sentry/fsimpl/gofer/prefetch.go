@@ -0,0 +1,293 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofer
+
+import (
+	"math"
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+const (
+	// maxPrefetchBytes is the maximum number of bytes of file data that may
+	// be prefetched concurrently in response to POSIX_FADV_WILLNEED, across
+	// all gofer filesystems in the sandbox. Advice that would exceed this
+	// budget is truncated.
+	maxPrefetchBytes = 64 << 20
+
+	// prefetchChunkBytes is the granularity at which prefetched data is read
+	// into the page cache, and thus at which prefetches may be canceled.
+	prefetchChunkBytes = 1 << 20
+)
+
+// prefetchBytesInFlight is the number of bytes of file data that are
+// currently reserved for prefetching. It is accessed using atomic memory
+// operations.
+var prefetchBytesInFlight int64
+
+// reservePrefetchBytes reserves up to want bytes of the prefetch budget and
+// returns the number of bytes reserved, which is always page-aligned.
+//
+// Preconditions: want is page-aligned.
+func reservePrefetchBytes(want uint64) uint64 {
+	for {
+		inFlight := atomic.LoadInt64(&prefetchBytesInFlight)
+		n := uint64(maxPrefetchBytes - inFlight)
+		if inFlight >= maxPrefetchBytes {
+			n = 0
+		}
+		if n > want {
+			n = want
+		}
+		n = hostarch.PageRoundDown(n)
+		if n == 0 {
+			return 0
+		}
+		if atomic.CompareAndSwapInt64(&prefetchBytesInFlight, inFlight, inFlight+int64(n)) {
+			return n
+		}
+	}
+}
+
+// releasePrefetchBytes returns n bytes to the prefetch budget.
+func releasePrefetchBytes(n uint64) {
+	atomic.AddInt64(&prefetchBytesInFlight, -int64(n))
+}
+
+// prefetchSetFunctions implements segment.Functions for prefetchSet.
+type prefetchSetFunctions struct{}
+
+// MinKey implements segment.Functions.MinKey.
+func (prefetchSetFunctions) MinKey() uint64 {
+	return 0
+}
+
+// MaxKey implements segment.Functions.MaxKey.
+func (prefetchSetFunctions) MaxKey() uint64 {
+	return math.MaxUint64
+}
+
+// ClearValue implements segment.Functions.ClearValue.
+func (prefetchSetFunctions) ClearValue(*struct{}) {
+}
+
+// Merge implements segment.Functions.Merge.
+func (prefetchSetFunctions) Merge(memmap.MappableRange, struct{}, memmap.MappableRange, struct{}) (struct{}, bool) {
+	return struct{}{}, true
+}
+
+// Split implements segment.Functions.Split.
+func (prefetchSetFunctions) Split(memmap.MappableRange, struct{}, uint64) (struct{}, struct{}) {
+	return struct{}{}, struct{}{}
+}
+
+// startPrefetch begins asynchronously reading the given range of the file
+// into d.cache, unless d's data is not cached by the sentry. The prefetch is
+// abandoned if fd is released, or the filesystem is saved, before it
+// completes.
+//
+// Preconditions: fd.dentry() == d.
+func (d *dentry) startPrefetch(fd *regularFileFD, start, end uint64) {
+	if d.fs.opts.interop == InteropModeShared {
+		// We can't cache file contents.
+		return
+	}
+	if !fd.vfsfd.IsReadable() {
+		// d's read handle may not be open.
+		return
+	}
+	if atomic.LoadInt32(&d.mmapFD) >= 0 && !d.fs.opts.forcePageCache {
+		// Reads go directly to the host FD, whose page cache is managed by
+		// the host.
+		return
+	}
+	if !d.fs.mfp.MemoryFile().ShouldCacheEvictable() {
+		return
+	}
+
+	d.dataMu.RLock()
+	size := d.size
+	d.dataMu.RUnlock()
+	if sizeEnd, ok := hostarch.PageRoundUp(size); ok && end > sizeEnd {
+		end = sizeEnd
+	}
+	start = hostarch.PageRoundDown(start)
+	if start >= end {
+		return
+	}
+	mr := memmap.MappableRange{start, end}
+	mr.End = mr.Start + reservePrefetchBytes(mr.Length())
+	if mr.Length() == 0 {
+		return
+	}
+
+	// The prefetch outlives the fadvise(2) call that started it, so it can't
+	// use that call's context.
+	d.IncRef()
+	d.fs.prefetches.Add(1)
+	go func() { // S/R-SAFE: filesystem.PrepareSave cancels and waits for prefetches.
+		ctx := context.Background()
+		defer d.fs.prefetches.Done()
+		defer d.DecRef(ctx)
+		for mr.Length() != 0 {
+			chunk := mr
+			if chunk.Length() > prefetchChunkBytes {
+				chunk.End = chunk.Start + prefetchChunkBytes
+			}
+			if atomic.LoadUint32(&fd.prefetchCanceled) != 0 || atomic.LoadUint32(&d.fs.prefetchesCanceled) != 0 {
+				break
+			}
+			if err := d.prefetchRange(ctx, chunk); err != nil {
+				log.Debugf("gofer.dentry.startPrefetch: failed to prefetch %v: %v", chunk, err)
+				break
+			}
+			releasePrefetchBytes(chunk.Length())
+			mr.Start = chunk.End
+		}
+		releasePrefetchBytes(mr.Length())
+	}()
+}
+
+// prefetchRange reads any parts of mr that are not already cached into
+// d.cache.
+//
+// Preconditions: mr is page-aligned.
+func (d *dentry) prefetchRange(ctx context.Context, mr memmap.MappableRange) error {
+	d.handleMu.RLock()
+	defer d.handleMu.RUnlock()
+	if d.mmapFD >= 0 && !d.fs.opts.forcePageCache {
+		// A host FD usable for mmap became available after the prefetch
+		// started; see startPrefetch.
+		return nil
+	}
+	h := d.readHandleLocked()
+	mf := d.fs.mfp.MemoryFile()
+	if !mf.ShouldCacheEvictable() {
+		return nil
+	}
+	d.dataMu.Lock()
+	defer d.dataMu.Unlock()
+
+	// The file may have been truncated since the prefetch started.
+	if sizeEnd, ok := hostarch.PageRoundUp(d.size); ok && mr.End > sizeEnd {
+		mr.End = sizeEnd
+	}
+	if mr.Start >= mr.End {
+		return nil
+	}
+
+	var gaps []memmap.MappableRange
+	for gap := d.cache.LowerBoundGap(mr.Start); gap.Ok() && gap.Start() < mr.End; gap = gap.NextGap() {
+		if gapMR := gap.Range().Intersect(mr); gapMR.Length() != 0 {
+			gaps = append(gaps, gapMR)
+		}
+	}
+	if len(gaps) == 0 {
+		return nil
+	}
+	err := d.cache.Fill(ctx, mr, mr, d.size, mf, usage.PageCache, h.readToBlocksAt)
+	mf.MarkEvictable(d, pgalloc.EvictableRange{mr.Start, mr.End})
+	if err != nil {
+		return err
+	}
+	for _, gapMR := range gaps {
+		d.notePrefetched(gapMR)
+	}
+	return nil
+}
+
+// notePrefetched records that mr was read into d.cache by a prefetch.
+//
+// Preconditions: mr is page-aligned.
+func (d *dentry) notePrefetched(mr memmap.MappableRange) {
+	pages := mr.Length() / hostarch.PageSize
+	d.prefetchMu.Lock()
+	d.prefetched.RemoveRange(mr)
+	d.prefetched.Add(mr, struct{}{})
+	d.prefetchMu.Unlock()
+	atomic.AddUint64(&d.prefetchedPages, pages)
+	fsmetric.GoferPrefetchedPages.IncrementBy(pages)
+}
+
+// notePrefetchUsed records that the cached data in mr was read. Pages in mr
+// that were prefetched and have not been read since are counted as used.
+func (d *dentry) notePrefetchUsed(mr memmap.MappableRange) {
+	if atomic.LoadUint64(&d.prefetchedPages) == 0 {
+		// Fast path: nothing has ever been prefetched.
+		return
+	}
+	d.prefetchMu.Lock()
+	used := d.forgetPrefetchedLocked(mr)
+	d.prefetchMu.Unlock()
+	if used != 0 {
+		atomic.AddUint64(&d.prefetchUsedPages, used)
+		fsmetric.GoferPrefetchUsedPages.IncrementBy(used)
+	}
+}
+
+// forgetPrefetched records that the cached data in mr was dropped, such that
+// any prefetched pages in mr that have not been read will never be used.
+func (d *dentry) forgetPrefetched(mr memmap.MappableRange) {
+	if atomic.LoadUint64(&d.prefetchedPages) == 0 {
+		return
+	}
+	d.prefetchMu.Lock()
+	d.forgetPrefetchedLocked(mr)
+	d.prefetchMu.Unlock()
+}
+
+// forgetPrefetchedLocked removes all pages overlapping mr from d.prefetched
+// and returns the number of pages removed.
+//
+// Preconditions: d.prefetchMu must be locked.
+func (d *dentry) forgetPrefetchedLocked(mr memmap.MappableRange) uint64 {
+	mr.Start = hostarch.PageRoundDown(mr.Start)
+	if end, ok := hostarch.PageRoundUp(mr.End); ok {
+		mr.End = end
+	}
+	var bytes uint64
+	seg := d.prefetched.LowerBoundSegment(mr.Start)
+	for seg.Ok() && seg.Start() < mr.End {
+		seg = d.prefetched.Isolate(seg, mr)
+		bytes += seg.Range().Length()
+		seg = d.prefetched.Remove(seg).NextSegment()
+	}
+	return bytes / hostarch.PageSize
+}
+
+// prefetchStats returns the number of pages of d that have been prefetched,
+// and the number of those pages that were subsequently read.
+func (d *dentry) prefetchStats() (prefetched, used uint64) {
+	return atomic.LoadUint64(&d.prefetchedPages), atomic.LoadUint64(&d.prefetchUsedPages)
+}
+
+// cancelPrefetches abandons all prefetches of files in fs, and waits for them
+// to stop mutating file caches.
+//
+// Preconditions: The kernel must be paused, so that no new prefetches can be
+// started.
+func (fs *filesystem) cancelPrefetches() {
+	atomic.StoreUint32(&fs.prefetchesCanceled, 1)
+	fs.prefetches.Wait()
+	// Prefetches started after the kernel resumes are unaffected.
+	atomic.StoreUint32(&fs.prefetchesCanceled, 0)
+}
@@ -49,6 +49,11 @@ type regularFileFD struct {
 	// off is the file offset. off is protected by mu.
 	mu  sync.Mutex `state:"nosave"`
 	off int64
+
+	// prefetchCanceled is set to 1 when the FD is released, causing any
+	// prefetches started through it to be abandoned. prefetchCanceled is
+	// accessed using atomic memory operations.
+	prefetchCanceled uint32 `state:"nosave"`
 }
 
 func newRegularFileFD(mnt *vfs.Mount, d *dentry, flags uint32) (*regularFileFD, error) {
@@ -72,6 +77,10 @@ func newRegularFileFD(mnt *vfs.Mount, d *dentry, flags uint32) (*regularFileFD,
 
 // Release implements vfs.FileDescriptionImpl.Release.
 func (fd *regularFileFD) Release(context.Context) {
+	atomic.StoreUint32(&fd.prefetchCanceled, 1)
+	if prefetched, used := fd.dentry().prefetchStats(); prefetched != 0 {
+		log.Debugf("gofer.regularFileFD.Release: %d pages prefetched, %d used", prefetched, used)
+	}
 }
 
 // OnClose implements vfs.FileDescriptionImpl.OnClose.
@@ -107,7 +116,7 @@ func (fd *regularFileFD) OnClose(ctx context.Context) error {
 // Allocate implements vfs.FileDescriptionImpl.Allocate.
 func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	d := fd.dentry()
-	return d.doAllocate(ctx, offset, length, func() error {
+	return d.allocate(ctx, mode, offset, length, func() error {
 		d.handleMu.RLock()
 		defer d.handleMu.RUnlock()
 		return d.writeFile.allocate(ctx, p9.ToAllocateMode(mode), offset, length)
@@ -214,6 +223,12 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	d.metadataMu.Lock()
 	defer d.metadataMu.Unlock()
 
+	// The file may have been made immutable or append-only after fd was
+	// opened. Flags are changed with d.metadataMu locked.
+	if d.isImmutable() || (d.isAppendOnly() && fd.vfsfd.StatusFlags()&linux.O_APPEND == 0) {
+		return 0, offset, linuxerr.EPERM
+	}
+
 	// If the fd was opened with O_APPEND, make sure the file size is updated.
 	// There is a possible race here if size is modified externally after
 	// metadata cache is updated.
@@ -425,6 +440,7 @@ func (rw *dentryReadWriter) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error)
 
 			// Copy from internal mappings.
 			n, err := safemem.CopySeq(dsts, ims)
+			rw.d.notePrefetchUsed(memmap.MappableRange{rw.off, rw.off + n})
 			done += n
 			rw.off += n
 			dsts = dsts.DropFirst64(n)
@@ -680,6 +696,66 @@ func (fd *regularFileFD) Sync(ctx context.Context) error {
 	return fd.dentry().syncCachedFile(ctx, false /* lowSyncExpectations */)
 }
 
+// Fadvise implements vfs.FileAdvisor.Fadvise.
+func (fd *regularFileFD) Fadvise(ctx context.Context, start, end int64, advice int32) error {
+	if advice != linux.POSIX_FADV_WILLNEED && advice != linux.POSIX_FADV_DONTNEED {
+		return nil
+	}
+	if start < 0 {
+		start = 0
+	}
+	d := fd.dentry()
+	d.dataMu.RLock()
+	size := d.size
+	d.dataMu.RUnlock()
+	if uint64(start) >= size {
+		// Nothing is cached beyond EOF.
+		return nil
+	}
+
+	if advice == linux.POSIX_FADV_WILLNEED {
+		d.startPrefetch(fd, uint64(start), uint64(end))
+		return nil
+	}
+
+	// As in Linux, write back and drop only the cached pages that lie
+	// entirely within the range; if the range extends to EOF, that includes
+	// the file's last partial page.
+	er := pgalloc.EvictableRange{End: hostarch.PageRoundDown(uint64(end))}
+	if uint64(end) >= size {
+		er.End = math.MaxUint64 &^ (hostarch.PageSize - 1)
+	}
+	var ok bool
+	if er.Start, ok = hostarch.PageRoundUp(uint64(start)); !ok || er.Start >= er.End {
+		return nil
+	}
+	d.Evict(ctx, er)
+	return nil
+}
+
+// Msync implements vfs.FileMsyncer.Msync.
+func (fd *regularFileFD) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
+	d := fd.dentry()
+	// Write back dirty cached data in mr even for MS_ASYNC, so that it isn't
+	// lost if the sandbox exits before the file is otherwise synced. If
+	// application mappings use a host FD rather than the sentry's cache, the
+	// host is responsible for writeback, and the cache is empty.
+	if err := d.writeback(ctx, int64(mr.Start), int64(mr.Length())); err != nil {
+		return err
+	}
+	if opts.Invalidate {
+		// Drop cached pages so that they are re-read from the remote file.
+		// Pages that are still mapped can't be dropped, since their memory
+		// is in use; this is the case for at least the caller's mapping, so
+		// MS_INVALIDATE only affects file data cached beyond it.
+		d.Evict(ctx, pgalloc.EvictableRange{Start: mr.Start, End: mr.End})
+	}
+	if !opts.Sync {
+		return nil
+	}
+	return d.syncRemoteFile(ctx)
+}
+
 // ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
 func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
 	d := fd.dentry()
@@ -916,6 +992,7 @@ func (d *dentry) Evict(ctx context.Context, er pgalloc.EvictableRange) {
 		}
 		d.cache.Drop(mgapMR, mf)
 		d.dirty.KeepClean(mgapMR)
+		d.forgetPrefetched(mgapMR)
 	}
 }
 
@@ -15,6 +15,8 @@
 package gofer
 
 import (
+	"sync/atomic"
+
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
@@ -232,6 +234,11 @@ func (fs *filesystem) revalidateHelper(ctx context.Context, vfsObj *vfs.VirtualF
 	if len(state.names) == 0 {
 		return nil
 	}
+	if state.revalidatedRecently() {
+		atomic.AddUint64(&fs.revalidationsSkipped, 1)
+		return nil
+	}
+	atomic.AddUint64(&fs.revalidations, 1)
 	// Lock metadata on all dentries *before* getting attributes for them.
 	state.lockAllMetadata()
 	stats, err := state.start.file.multiGetAttr(ctx, state.names)
@@ -266,6 +273,7 @@ func (fs *filesystem) revalidateHelper(ctx context.Context, vfsObj *vfs.VirtualF
 			// dentry invalidated, and re-evaluate its caching status (i.e. if it
 			// has 0 references, drop it). The dentry will be reloaded next time it's
 			// accessed.
+			atomic.AddUint64(&fs.invalidations, 1)
 			vfsObj.InvalidateDentry(ctx, &d.vfsd)
 
 			name := state.names[i]
@@ -354,6 +362,19 @@ func (r *revalidateState) add(name string, d *dentry) {
 	r.dentries = append(r.dentries, d)
 }
 
+// revalidatedRecently returns true if every dentry in r was revalidated within
+// the filesystem's revalidation TTL. Since a single stale path component may
+// change the meaning of every component after it, all of them must be fresh
+// for revalidation to be skipped.
+func (r *revalidateState) revalidatedRecently() bool {
+	for _, d := range r.dentries {
+		if !d.revalidatedRecently() {
+			return false
+		}
+	}
+	return true
+}
+
 // +checklocksignore
 func (r *revalidateState) lockAllMetadata() {
 	for _, d := range r.dentries {
@@ -51,6 +51,11 @@ func (fs *filesystem) PrepareSave(ctx context.Context) error {
 		return fmt.Errorf("gofer.filesystem with no UniqueID cannot be saved")
 	}
 
+	// Prefetches fill file caches asynchronously, and hold references on
+	// dentries; stop them before anything that depends on either. Since
+	// prefetching is advisory, abandoned prefetches are not resumed.
+	fs.cancelPrefetches()
+
 	// Purge cached dentries, which may not be reopenable after restore due to
 	// permission changes.
 	fs.renameMu.Lock()
@@ -176,7 +181,12 @@ func (fs *filesystem) CompleteRestore(ctx context.Context, opts vfs.CompleteRest
 	if err := fs.dial(ctx); err != nil {
 		return err
 	}
+	// Restored dentries re-register their (possibly new) QIDs below; the
+	// remainder of the saved mapping is merged back in afterward if it's
+	// still valid.
+	savedInoByQIDPath := fs.inoByQIDPath
 	fs.inoByQIDPath = make(map[uint64]uint64)
+	fs.restoreQIDsChanged = false
 
 	// Restore the filesystem root.
 	ctx.UninterruptibleSleepStart(false)
@@ -199,6 +209,21 @@ func (fs *filesystem) CompleteRestore(ctx context.Context, opts vfs.CompleteRest
 		return err
 	}
 
+	// If every restored dentry kept its QID, the gofer's QIDs are stable
+	// across checkpoint/restore, so QIDs observed before checkpoint still
+	// identify the same files and can keep their inode numbers. Otherwise,
+	// a saved QID may now refer to a different file, so only mappings for
+	// restored dentries are retained.
+	fs.inoMu.Lock()
+	if !fs.restoreQIDsChanged {
+		for qidPath, ino := range savedInoByQIDPath {
+			if _, ok := fs.inoByQIDPath[qidPath]; !ok {
+				fs.inoByQIDPath[qidPath] = ino
+			}
+		}
+	}
+	fs.inoMu.Unlock()
+
 	// Re-open handles for specialFileFDs. Unlike the initial open
 	// (dentry.openSpecialFile()), pipes are always opened without blocking;
 	// non-readable pipe FDs are opened last to ensure that they don't get
@@ -233,17 +258,21 @@ func (fs *filesystem) CompleteRestore(ctx context.Context, opts vfs.CompleteRest
 func (d *dentry) restoreFile(ctx context.Context, file p9file, qid p9.QID, attrMask p9.AttrMask, attr *p9.Attr, opts *vfs.CompleteRestoreOptions) error {
 	d.file = file
 
-	// Gofers do not preserve QID across checkpoint/restore, so:
+	// Gofers are not required to preserve QIDs across checkpoint/restore,
+	// so:
 	//
 	// - We must assume that the remote filesystem did not change in a way that
 	// would invalidate dentries, since we can't revalidate dentries by
 	// checking QIDs.
 	//
 	// - We need to associate the new QID.Path with the existing d.ino.
-	d.qidPath = qid.Path
 	d.fs.inoMu.Lock()
+	if d.qidPath != qid.Path {
+		d.fs.restoreQIDsChanged = true
+	}
 	d.fs.inoByQIDPath[qid.Path] = d.ino
 	d.fs.inoMu.Unlock()
+	d.qidPath = qid.Path
 
 	// Check metadata stability before updating metadata.
 	d.metadataMu.Lock()
@@ -318,6 +347,10 @@ func (fd *specialFileFD) completeRestore(ctx context.Context) error {
 		return err
 	}
 	fd.handle = h
+	if fd.isRegularFile {
+		fd.handle.accounted = true
+		fd.handle.devMinor = d.fs.devMinor
+	}
 
 	ftype := d.fileType()
 	fd.haveQueue = (ftype == linux.S_IFIFO || ftype == linux.S_IFSOCK) && fd.handle.fd >= 0
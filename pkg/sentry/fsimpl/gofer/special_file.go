@@ -88,6 +88,10 @@ func newSpecialFileFD(h handle, mnt *vfs.Mount, d *dentry, flags uint32) (*speci
 		seekable:      seekable,
 		haveQueue:     haveQueue,
 	}
+	if fd.isRegularFile {
+		fd.handle.accounted = true
+		fd.handle.devMinor = d.fs.devMinor
+	}
 	fd.LockFD.Init(&d.locks)
 	if haveQueue {
 		if err := fdnotifier.AddFD(h.fd, &fd.queue); err != nil {
@@ -122,11 +126,11 @@ func (fd *specialFileFD) Release(ctx context.Context) {
 	if fd.haveQueue {
 		fdnotifier.RemoveFD(fd.handle.fd)
 	}
+	fs := fd.vfsfd.Mount().Filesystem().Impl().(*filesystem)
 	fd.releaseMu.Lock()
-	fd.handle.close(ctx)
+	fd.handle.release(ctx, fs.client)
 	fd.releaseMu.Unlock()
 
-	fs := fd.vfsfd.Mount().Filesystem().Impl().(*filesystem)
 	fs.syncMu.Lock()
 	delete(fs.specialFileFDs, fd)
 	fs.syncMu.Unlock()
@@ -168,10 +172,10 @@ func (fd *specialFileFD) EventUnregister(e *waiter.Entry) {
 	fd.fileDescription.EventUnregister(e)
 }
 
+// Allocate implements vfs.FileDescriptionImpl.Allocate.
 func (fd *specialFileFD) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	if fd.isRegularFile {
-		d := fd.dentry()
-		return d.doAllocate(ctx, offset, length, func() error {
+		return fd.dentry().allocate(ctx, mode, offset, length, func() error {
 			return fd.handle.file.allocate(ctx, p9.ToAllocateMode(mode), offset, length)
 		})
 	}
@@ -686,7 +686,9 @@ func (f *fileDescription) PRead(ctx context.Context, dst usermem.IOSequence, off
 		return 0, linuxerr.ESPIPE
 	}
 
-	return readFromHostFD(ctx, i.hostFD, dst, offset, opts.Flags)
+	n, err := readFromHostFD(ctx, i.hostFD, dst, offset, opts.Flags)
+	f.accountIO(ctx, n, false /* write */)
+	return n, err
 }
 
 // Read implements vfs.FileDescriptionImpl.Read.
@@ -722,6 +724,7 @@ func (f *fileDescription) Read(ctx context.Context, dst usermem.IOSequence, opts
 	n, err := readFromHostFD(ctx, i.hostFD, dst, f.offset, opts.Flags)
 	f.offset += n
 	f.offsetMu.Unlock()
+	f.accountIO(ctx, n, false /* write */)
 	return n, err
 }
 
@@ -799,6 +802,7 @@ func (f *fileDescription) writeToHostFD(ctx context.Context, src usermem.IOSeque
 	writer := hostfd.GetReadWriterAt(int32(hostFD), offset, flags)
 	n, err := src.CopyInTo(ctx, writer)
 	hostfd.PutReadWriterAt(writer)
+	f.accountIO(ctx, n, true /* write */)
 	// NOTE(gvisor.dev/issue/2979): We always sync everything, even for O_DSYNC.
 	if n > 0 && f.vfsfd.StatusFlags()&(linux.O_DSYNC|linux.O_SYNC) != 0 {
 		if syncErr := unix.Fsync(hostFD); syncErr != nil {
@@ -808,6 +812,26 @@ func (f *fileDescription) writeToHostFD(ctx context.Context, src usermem.IOSeque
 	return int64(n), err
 }
 
+// accountIO charges n bytes of I/O through the host FD to the task in ctx, if
+// any. I/O to non-seekable files, such as pipes and terminals, is not
+// accounted since it doesn't reach a backing store.
+func (f *fileDescription) accountIO(ctx context.Context, n int64, write bool) {
+	if !f.inode.seekable || n <= 0 {
+		return
+	}
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return
+	}
+	fs := f.vfsfd.Mount().Filesystem().Impl().(*filesystem)
+	dev := linux.MakeDeviceID(linux.UNNAMED_MAJOR, fs.devMinor)
+	if write {
+		t.AccountWriteIO(dev, n)
+	} else {
+		t.AccountReadIO(dev, n)
+	}
+}
+
 // Seek implements vfs.FileDescriptionImpl.Seek.
 //
 // Note that we do not support seeking on directories, since we do not even
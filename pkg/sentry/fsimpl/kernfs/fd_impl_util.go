@@ -200,9 +200,9 @@ func (fd *GenericDirectoryFD) IterDirents(ctx context.Context, cb vfs.IterDirent
 	fd.children.mu.RLock()
 	defer fd.children.mu.RUnlock()
 	// fd.off accounts for "." and "..", but fd.children do not track
-	// these.
-	childIdx := fd.off - 2
-	for it := fd.children.nthLocked(childIdx); it != nil; it = it.Next() {
+	// these. Child offsets are stable across insertions and removals, so
+	// resume from the first child at or after the current offset.
+	for it := fd.children.firstAtOrAfterLocked(fd.off - 2); it != nil; it = it.Next() {
 		stat, err := it.inode.Stat(ctx, fd.filesystem(), opts)
 		if err != nil {
 			return err
@@ -211,16 +211,20 @@ func (fd *GenericDirectoryFD) IterDirents(ctx context.Context, cb vfs.IterDirent
 			Name:    it.name,
 			Type:    linux.FileMode(stat.Mode).DirentType(),
 			Ino:     stat.Ino,
-			NextOff: fd.off + 1,
+			NextOff: it.off + 2 + 1,
 		}
 		if err := cb.Handle(dirent); err != nil {
 			return err
 		}
-		fd.off++
+		fd.off = it.off + 2 + 1
+	}
+	// Skip offsets belonging to removed children.
+	if end := fd.children.nextOff + 2; fd.off < end {
+		fd.off = end
 	}
 
 	var err error
-	relOffset := fd.off - int64(len(fd.children.set)) - 2
+	relOffset := fd.off - fd.children.nextOff - 2
 	fd.off, err = fd.inode().IterDirents(ctx, fd.vfsfd.Mount(), cb, fd.off, relOffset)
 	return err
 }
@@ -239,7 +243,7 @@ func (fd *GenericDirectoryFD) Seek(ctx context.Context, offset int64, whence int
 		switch fd.seekEnd {
 		case SeekEndStaticEntries:
 			fd.children.mu.RLock()
-			offset += int64(len(fd.children.set))
+			offset += fd.children.nextOff
 			offset += 2 // '.' and '..' aren't tracked in children.
 			fd.children.mu.RUnlock()
 		case SeekEndZero:
@@ -292,8 +292,16 @@ func (a *InodeAttrs) SetStat(ctx context.Context, fs *vfs.Filesystem, creds *aut
 	if opts.Stat.Mask&^(linux.STATX_MODE|linux.STATX_UID|linux.STATX_GID|linux.STATX_ATIME|linux.STATX_MTIME|linux.STATX_SIZE) != 0 {
 		return linuxerr.EPERM
 	}
-	if opts.Stat.Mask&linux.STATX_SIZE != 0 && a.Mode().IsDir() {
-		return linuxerr.EISDIR
+	if opts.Stat.Mask&linux.STATX_SIZE != 0 {
+		switch a.Mode().FileType() {
+		case linux.ModeRegular:
+			// ok
+		case linux.ModeDirectory:
+			return linuxerr.EISDIR
+		default:
+			// Sockets, pipes, devices, etc. can't be truncated.
+			return linuxerr.EINVAL
+		}
 	}
 	if err := vfs.CheckSetStat(ctx, creds, &opts, a.Mode(), auth.KUID(atomic.LoadUint32(&a.uid)), auth.KGID(atomic.LoadUint32(&a.gid))); err != nil {
 		return err
@@ -384,6 +392,12 @@ type slot struct {
 	name   string
 	inode  Inode
 	static bool
+
+	// off is the directory offset of this child, relative to the first
+	// child. Offsets are assigned in insertion order and never reused, so
+	// they remain valid across unrelated insertions and removals.
+	off int64
+
 	slotEntry
 }
 
@@ -425,6 +439,10 @@ type OrderedChildren struct {
 	mu    sync.RWMutex `state:"nosave"`
 	order slotList
 	set   map[string]*slot
+
+	// nextOff is the offset that will be assigned to the next inserted
+	// child. It is protected by mu.
+	nextOff int64
 }
 
 // Init initializes an OrderedChildren.
@@ -525,7 +543,7 @@ func (o *OrderedChildren) Inserter(name string, makeChild func() Inode) (Inode,
 		inode:  child,
 		static: false,
 	}
-	o.order.PushBack(s)
+	o.pushBackLocked(s)
 	o.set[name] = s
 	return child, nil
 }
@@ -546,11 +564,20 @@ func (o *OrderedChildren) insert(name string, child Inode, static bool) error {
 		inode:  child,
 		static: static,
 	}
-	o.order.PushBack(s)
+	o.pushBackLocked(s)
 	o.set[name] = s
 	return nil
 }
 
+// pushBackLocked appends s to o.order and assigns it the next offset.
+//
+// Precondition: caller must hold o.mu for writing.
+func (o *OrderedChildren) pushBackLocked(s *slot) {
+	s.off = o.nextOff
+	o.nextOff++
+	o.order.PushBack(s)
+}
+
 // Precondition: caller must hold o.mu for writing.
 func (o *OrderedChildren) removeLocked(name string) {
 	if s, ok := o.set[name]; ok {
@@ -571,6 +598,7 @@ func (o *OrderedChildren) replaceChildLocked(ctx context.Context, name string, n
 
 		// Existing slot with given name, simply replace the dentry.
 		s.inode = newI
+		return
 	}
 
 	// No existing slot with given name, create and hash new slot.
@@ -579,7 +607,7 @@ func (o *OrderedChildren) replaceChildLocked(ctx context.Context, name string, n
 		inode:  newI,
 		static: false,
 	}
-	o.order.PushBack(s)
+	o.pushBackLocked(s)
 	o.set[name] = s
 }
 
@@ -658,17 +686,16 @@ func (o *OrderedChildren) Rename(ctx context.Context, oldname, newname string, c
 	return nil
 }
 
-// nthLocked returns an iterator to the nth child tracked by this object. The
-// iterator is valid until the caller releases o.mu. Returns nil if the
-// requested index falls out of bounds.
+// firstAtOrAfterLocked returns an iterator to the first child tracked by this
+// object whose offset is at least off. The iterator is valid until the caller
+// releases o.mu. Returns nil if there is no such child.
 //
 // Preconditon: Caller must hold o.mu for reading.
-func (o *OrderedChildren) nthLocked(i int64) *slot {
-	for it := o.order.Front(); it != nil && i >= 0; it = it.Next() {
-		if i == 0 {
+func (o *OrderedChildren) firstAtOrAfterLocked(off int64) *slot {
+	for it := o.order.Front(); it != nil; it = it.Next() {
+		if it.off >= off {
 			return it
 		}
-		i--
 	}
 	return nil
 }
@@ -1262,7 +1262,11 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 		}
 	}
 
-	vfs.InotifyRename(ctx, &renamed.watches, &oldParent.watches, &newParent.watches, oldName, newName, renamed.isDir())
+	var replacedWatches *vfs.Watches
+	if replaced != nil {
+		replacedWatches = &replaced.watches
+	}
+	vfs.InotifyRename(ctx, &renamed.watches, replacedWatches, &oldParent.watches, &newParent.watches, oldName, newName, renamed.isDir())
 	return nil
 }
 
@@ -152,6 +152,10 @@ func (i *inode) SetStat(ctx context.Context, vfsfs *vfs.Filesystem, creds *auth.
 	if opts.Stat.Mask == 0 {
 		return nil
 	}
+	if opts.Stat.Mask&linux.STATX_SIZE != 0 {
+		// Pipes can't be truncated.
+		return linuxerr.EINVAL
+	}
 	return linuxerr.EPERM
 }
 
@@ -750,6 +750,13 @@ func (s *statusFD) SetStat(ctx context.Context, opts vfs.SetStatOptions) error {
 // Generate implements vfs.DynamicBytesSource.Generate.
 func (s *statusFD) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "Name:\t%s\n", s.task.Name())
+	var fsCtx *kernel.FSContext
+	s.task.WithMuLocked(func(t *kernel.Task) {
+		fsCtx = t.FSContext()
+	})
+	if fsCtx != nil {
+		fmt.Fprintf(buf, "Umask:\t%#04o\n", fsCtx.Umask())
+	}
 	fmt.Fprintf(buf, "State:\t%s\n", s.task.StateStatus())
 	fmt.Fprintf(buf, "Tgid:\t%d\n", s.pidns.IDOfThreadGroup(s.task.ThreadGroup()))
 	fmt.Fprintf(buf, "Pid:\t%d\n", s.pidns.IDOfTask(s.task))
@@ -892,6 +899,72 @@ func (o *oomScoreAdj) Write(ctx context.Context, src usermem.IOSequence, offset
 	return n, nil
 }
 
+// oomAdj is the deprecated /proc/<pid>/oom_adj file, which is a scaled view
+// of oom_score_adj.
+//
+// +stateify savable
+type oomAdj struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ vfs.WritableDynamicBytesSource = (*oomAdj)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (o *oomAdj) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if o.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	fmt.Fprintf(buf, "%d\n", o.task.OOMAdj())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (o *oomAdj) Write(ctx context.Context, src usermem.IOSequence, offset int64) (int64, error) {
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	// Limit input size so as not to impact performance if input size is large.
+	src = src.TakeFirst(hostarch.PageSize - 1)
+
+	var v int32
+	n, err := usermem.CopyInt32StringInVec(ctx, src.IO, src.Addrs, &v, src.Opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if o.task.ExitState() == kernel.TaskExitDead {
+		return 0, linuxerr.ESRCH
+	}
+	if err := o.task.SetOOMAdj(v); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// oomScore is the /proc/<pid>/oom_score file.
+//
+// +stateify savable
+type oomScore struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*oomScore)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (o *oomScore) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if o.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	fmt.Fprintf(buf, "%d\n", o.task.OOMScore())
+	return nil
+}
+
 // exeSymlink is an symlink for the /proc/[pid]/exe file.
 //
 // +stateify savable
@@ -1097,6 +1170,38 @@ func (i *mountsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
+// mountStatsData is used to implement /proc/[pid]/mountstats.
+//
+// +stateify savable
+type mountStatsData struct {
+	kernfs.DynamicBytesFile
+
+	fs   *filesystem
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*mountStatsData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (i *mountStatsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	var fsctx *kernel.FSContext
+	i.task.WithMuLocked(func(t *kernel.Task) {
+		fsctx = t.FSContext()
+	})
+	if fsctx == nil {
+		// The task has been destroyed. Nothing to show here.
+		return nil
+	}
+	rootDir := fsctx.RootDirectoryVFS2()
+	if !rootDir.Ok() {
+		// Root has been destroyed. Don't try to read mounts.
+		return nil
+	}
+	defer i.fs.SafeDecRef(ctx, rootDir)
+	i.task.Kernel().VFS().GenerateProcMountStats(ctx, rootDir, buf)
+	return nil
+}
+
 // +stateify savable
 type namespaceSymlink struct {
 	kernfs.StaticSymlink
@@ -1239,3 +1344,35 @@ func (d *taskCgroupData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	d.task.GenerateProcTaskCgroup(buf)
 	return nil
 }
+
+// stackData implements vfs.DynamicBytesSource for /proc/[pid]/stack.
+//
+// The sentry has no kernel stack to report, so this renders the task's
+// pseudo-stack; see kernel.Task.PseudoStack.
+//
+// +stateify savable
+type stackData struct {
+	dynamicBytesFileSetAttr
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*stackData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *stackData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// As in Linux, reading another task's stack requires both ptrace access
+	// and CAP_SYS_ADMIN in the root user namespace.
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
+		return linuxerr.EACCES
+	}
+	if !kernel.ContextCanTrace(ctx, d.task, true) {
+		return linuxerr.EACCES
+	}
+	if err := checkTaskState(d.task); err != nil {
+		return err
+	}
+	buf.WriteString(kernel.FormatPseudoStack(d.task.PseudoStack()))
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -342,6 +343,27 @@ func writeInetAddr(w io.Writer, family int, i linux.SockAddr) {
 	}
 }
 
+// tcpListenQueueLen returns the number of connections waiting to be accepted
+// on sops, which must be a listening TCP socket. Linux reports this as the
+// rx_queue of listening sockets.
+func tcpListenQueueLen(t *kernel.Task, sops socket.SocketOps) uint32 {
+	if t == nil {
+		return 0
+	}
+	var info linux.TCPInfo
+	v, err := sops.GetSockOpt(t, linux.SOL_TCP, linux.TCP_INFO, 0, info.SizeBytes())
+	if err != nil {
+		return 0
+	}
+	buf, ok := v.(*primitive.ByteSlice)
+	if !ok || len(*buf) < info.SizeBytes() {
+		return 0
+	}
+	info.UnmarshalUnsafe(*buf)
+	// The accept queue length is reported in place of the unacked count.
+	return info.Unacked
+}
+
 func commonGenerateTCP(ctx context.Context, buf *bytes.Buffer, k *kernel.Kernel, family int) error {
 	// t may be nil here if our caller is not part of a task goroutine. This can
 	// happen for example if we're here for "sentryctl cat". When t is nil,
@@ -394,8 +416,13 @@ func commonGenerateTCP(ctx context.Context, buf *bytes.Buffer, k *kernel.Kernel,
 		fmt.Fprintf(buf, "%02X ", sops.State())
 
 		// Field: tx_queue, rx_queue; number of packets in the transmit and
-		// receive queue. Unimplemented.
-		fmt.Fprintf(buf, "%08X:%08X ", 0, 0)
+		// receive queue. For listening sockets, rx_queue is the number of
+		// connections waiting to be accepted. Otherwise unimplemented.
+		var rxQueue uint32
+		if sops.State() == linux.TCP_LISTEN {
+			rxQueue = tcpListenQueueLen(t, sops)
+		}
+		fmt.Fprintf(buf, "%08X:%08X ", 0, rxQueue)
 
 		// Field: tr, tm->when; timer active state and number of jiffies
 		// until timer expires. Unimplemented.
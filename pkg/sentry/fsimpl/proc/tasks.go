@@ -65,17 +65,18 @@ var _ kernfs.Inode = (*tasksInode)(nil)
 func (fs *filesystem) newTasksInode(ctx context.Context, k *kernel.Kernel, pidns *kernel.PIDNamespace, fakeCgroupControllers map[string]string) *tasksInode {
 	root := auth.NewRootCredentials(pidns.UserNamespace())
 	contents := map[string]kernfs.Inode{
-		"cmdline":     fs.newInode(ctx, root, 0444, &cmdLineData{}),
-		"cpuinfo":     fs.newInode(ctx, root, 0444, newStaticFileSetStat(cpuInfoData(k))),
-		"filesystems": fs.newInode(ctx, root, 0444, &filesystemsData{}),
-		"loadavg":     fs.newInode(ctx, root, 0444, &loadavgData{}),
-		"sys":         fs.newSysDir(ctx, root, k),
-		"meminfo":     fs.newInode(ctx, root, 0444, &meminfoData{}),
-		"mounts":      kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/mounts"),
-		"net":         kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/net"),
-		"stat":        fs.newInode(ctx, root, 0444, &statData{}),
-		"uptime":      fs.newInode(ctx, root, 0444, &uptimeData{}),
-		"version":     fs.newInode(ctx, root, 0444, &versionData{}),
+		"cmdline":       fs.newInode(ctx, root, 0444, &cmdLineData{}),
+		"cpuinfo":       fs.newInode(ctx, root, 0444, newStaticFileSetStat(cpuInfoData(k))),
+		"filesystems":   fs.newInode(ctx, root, 0444, &filesystemsData{}),
+		"loadavg":       fs.newInode(ctx, root, 0444, &loadavgData{}),
+		"sys":           fs.newSysDir(ctx, root, k),
+		"sysrq-trigger": fs.newInode(ctx, root, 0200, &sysrqTriggerData{}),
+		"meminfo":       fs.newInode(ctx, root, 0444, &meminfoData{}),
+		"mounts":        kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/mounts"),
+		"net":           kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/net"),
+		"stat":          fs.newInode(ctx, root, 0444, &statData{}),
+		"uptime":        fs.newInode(ctx, root, 0444, &uptimeData{}),
+		"version":       fs.newInode(ctx, root, 0444, &versionData{}),
 	}
 	// If fakeCgroupControllers are provided, don't create a cgroupfs backed
 	// /proc/cgroup as it will not match the fake controllers.
@@ -29,6 +29,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // +stateify savable
@@ -296,8 +297,16 @@ func (*meminfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "Inactive(file): %8d kB\n", inactiveFile/1024)
 	fmt.Fprintf(buf, "Unevictable:           0 kB\n") // TODO(b/31823263)
 	fmt.Fprintf(buf, "Mlocked:               0 kB\n") // TODO(b/31823263)
-	fmt.Fprintf(buf, "SwapTotal:             0 kB\n")
-	fmt.Fprintf(buf, "SwapFree:              0 kB\n")
+	var swapTotal, swapFree uint64
+	if swapped, ok := mf.FakeSwap(); ok {
+		// Present compressed memory as swap large enough to hold all memory.
+		swapTotal = totalSize
+		if swapped < swapTotal {
+			swapFree = swapTotal - swapped
+		}
+	}
+	fmt.Fprintf(buf, "SwapTotal:      %8d kB\n", swapTotal/1024)
+	fmt.Fprintf(buf, "SwapFree:       %8d kB\n", swapFree/1024)
 	fmt.Fprintf(buf, "Dirty:                 0 kB\n")
 	fmt.Fprintf(buf, "Writeback:             0 kB\n")
 	fmt.Fprintf(buf, "AnonPages:      %8d kB\n", anon/1024)
@@ -419,3 +428,65 @@ func kernelVersion(ctx context.Context) kernel.Version {
 	}
 	return init.Leader().SyscallTable().Version
 }
+
+// sysrqTriggerData implements vfs.WritableDynamicBytesSource for
+// /proc/sysrq-trigger.
+//
+// Only the diagnostic and termination commands are supported: 'l' and 't'
+// log the pseudo-stacks of all tasks, and 'e' and 'i' send SIGTERM and
+// SIGKILL respectively to all tasks in the writer's PID namespace other than
+// its init.
+//
+// +stateify savable
+type sysrqTriggerData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*sysrqTriggerData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*sysrqTriggerData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*sysrqTriggerData) Write(ctx context.Context, src usermem.IOSequence, offset int64) (int64, error) {
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	// As in Linux, only the first byte of each write is interpreted.
+	var key [1]byte
+	if _, err := src.CopyIn(ctx, key[:]); err != nil {
+		return 0, err
+	}
+
+	switch key[0] {
+	case 'l', 't':
+		kernel.KernelFromContext(ctx).LogPseudoStacks()
+	case 'e', 'i':
+		t := kernel.TaskFromContext(ctx)
+		if t == nil {
+			return 0, linuxerr.EPERM
+		}
+		creds := t.Credentials()
+		if !creds.HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		sig := linux.SIGTERM
+		if key[0] == 'i' {
+			sig = linux.SIGKILL
+		}
+		pidns := t.PIDNamespace()
+		for _, tg := range pidns.ThreadGroups() {
+			if pidns.IDOfThreadGroup(tg) == kernel.InitTID {
+				continue
+			}
+			// Ignore errors: the thread group may have exited.
+			_ = tg.SendSignal(kernel.SignalInfoPriv(sig))
+		}
+	default:
+		return 0, linuxerr.EPERM
+	}
+	return src.NumBytes(), nil
+}
@@ -46,10 +46,14 @@ func (fs *filesystem) newSysDir(ctx context.Context, root *auth.Credentials, k *
 	return fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 		"kernel": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 			"hostname": fs.newInode(ctx, root, 0444, &hostnameData{}),
-			"sem":      fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\t%d\t%d\t%d\n", linux.SEMMSL, linux.SEMMNS, linux.SEMOPM, linux.SEMMNI))),
-			"shmall":   fs.newInode(ctx, root, 0444, shmData(linux.SHMALL)),
-			"shmmax":   fs.newInode(ctx, root, 0444, shmData(linux.SHMMAX)),
-			"shmmni":   fs.newInode(ctx, root, 0444, shmData(linux.SHMMNI)),
+			"random": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
+				"entropy_avail": fs.newInode(ctx, root, 0444, &entropyAvailData{k: k}),
+				"poolsize":      fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", kernel.EntropyPoolBits))),
+			}),
+			"sem":    fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\t%d\t%d\t%d\n", linux.SEMMSL, linux.SEMMNS, linux.SEMOPM, linux.SEMMNI))),
+			"shmall": fs.newInode(ctx, root, 0444, shmData(linux.SHMALL)),
+			"shmmax": fs.newInode(ctx, root, 0444, shmData(linux.SHMMAX)),
+			"shmmni": fs.newInode(ctx, root, 0444, shmData(linux.SHMMNI)),
 			"yama": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 				"ptrace_scope": fs.newYAMAPtraceScopeFile(ctx, k, root),
 			}),
@@ -154,6 +158,24 @@ func (d *mmapMinAddrData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	return nil
 }
 
+// entropyAvailData implements vfs.DynamicBytesSource for
+// /proc/sys/kernel/random/entropy_avail.
+//
+// +stateify savable
+type entropyAvailData struct {
+	kernfs.DynamicBytesFile
+
+	k *kernel.Kernel
+}
+
+var _ dynamicInode = (*entropyAvailData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *entropyAvailData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%d\n", d.k.EntropyPool().Bits())
+	return nil
+}
+
 // hostnameData implements vfs.DynamicBytesSource for /proc/sys/kernel/hostname.
 //
 // +stateify savable
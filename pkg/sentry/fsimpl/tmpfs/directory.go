@@ -47,6 +47,11 @@ type directory struct {
 	// efficiently. childList is protected by iterMu.
 	iterMu    sync.Mutex `state:"nosave"`
 	childList dentryList
+
+	// numChildOffs is the number of directory offsets that have been
+	// assigned to children; see dentry.dirOff. numChildOffs is protected by
+	// iterMu.
+	numChildOffs int64
 }
 
 func (fs *filesystem) newDirectory(kuid auth.KUID, kgid auth.KGID, mode linux.FileMode, parentDir *directory) *directory {
@@ -70,6 +75,9 @@ func (dir *directory) insertChildLocked(child *dentry, name string) {
 	dir.childMap[name] = child
 	atomic.AddInt64(&dir.numChildren, 1)
 	dir.iterMu.Lock()
+	// Offsets 0 and 1 are used by "." and "..".
+	child.dirOff = dir.numChildOffs + 2
+	dir.numChildOffs++
 	dir.childList.PushBack(child)
 	dir.iterMu.Unlock()
 }
@@ -84,13 +92,20 @@ func (dir *directory) removeChildLocked(child *dentry) {
 }
 
 func (dir *directory) mayDelete(creds *auth.Credentials, child *dentry) error {
-	return vfs.CheckDeleteSticky(
+	if err := vfs.CheckDeleteSticky(
 		creds,
 		linux.FileMode(atomic.LoadUint32(&dir.inode.mode)),
 		auth.KUID(atomic.LoadUint32(&dir.inode.uid)),
 		auth.KUID(atomic.LoadUint32(&child.inode.uid)),
 		auth.KGID(atomic.LoadUint32(&child.inode.gid)),
-	)
+	); err != nil {
+		return err
+	}
+	// Compare Linux's fs/namei.c:may_delete().
+	if dir.inode.isAppendOnly() || child.inode.isImmutable() || child.inode.isAppendOnly() {
+		return linuxerr.EPERM
+	}
+	return nil
 }
 
 // +stateify savable
@@ -171,12 +186,12 @@ func (fd *directoryFD) IterDirents(ctx context.Context, cb vfs.IterDirentsCallba
 				Name:    child.name,
 				Type:    child.inode.direntType(),
 				Ino:     child.inode.ino,
-				NextOff: fd.off + 1,
+				NextOff: child.dirOff + 1,
 			}); err != nil {
 				dir.childList.InsertBefore(child, fd.iter)
 				return err
 			}
-			fd.off++
+			fd.off = child.dirOff + 1
 		}
 		child = child.Next()
 	}
@@ -210,11 +225,6 @@ func (fd *directoryFD) Seek(ctx context.Context, offset int64, whence int32) (in
 	}
 
 	fd.off = offset
-	// Compensate for "." and "..".
-	remChildren := int64(0)
-	if offset >= 2 {
-		remChildren = offset - 2
-	}
 
 	// Ensure that fd.iter exists and is not linked into dir.childList.
 	if fd.iter == nil {
@@ -222,19 +232,17 @@ func (fd *directoryFD) Seek(ctx context.Context, offset int64, whence int32) (in
 	} else {
 		dir.childList.Remove(fd.iter)
 	}
-	// Insert fd.iter before the remChildren'th child, or at the end of the
-	// list if remChildren >= number of children.
-	child := dir.childList.Front()
-	for child != nil {
+	// Insert fd.iter before the first child whose offset is at least offset,
+	// or at the end of the list if there is no such child. Since child
+	// offsets are stable, this resumes iteration correctly at an offset
+	// previously returned by IterDirents even if the directory has changed
+	// since.
+	for child := dir.childList.Front(); child != nil; child = child.Next() {
 		// Skip other directoryFD iterators.
-		if child.inode != nil {
-			if remChildren == 0 {
-				dir.childList.InsertBefore(child, fd.iter)
-				return offset, nil
-			}
-			remChildren--
+		if child.inode != nil && child.dirOff >= offset {
+			dir.childList.InsertBefore(child, fd.iter)
+			return offset, nil
 		}
-		child = child.Next()
 	}
 	dir.childList.PushBack(fd.iter)
 	return offset, nil
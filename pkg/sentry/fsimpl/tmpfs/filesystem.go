@@ -253,6 +253,10 @@ func (fs *filesystem) LinkAt(ctx context.Context, rp *vfs.ResolvingPath, vd vfs.
 		if i.isDir() {
 			return linuxerr.EPERM
 		}
+		// Compare Linux's fs/namei.c:vfs_link().
+		if i.isImmutable() || i.isAppendOnly() {
+			return linuxerr.EPERM
+		}
 		if err := vfs.MayLink(auth.CredentialsFromContext(ctx), linux.FileMode(atomic.LoadUint32(&i.mode)), auth.KUID(atomic.LoadUint32(&i.uid)), auth.KGID(atomic.LoadUint32(&i.gid))); err != nil {
 			return err
 		}
@@ -377,6 +381,11 @@ afterTrailingSymlink:
 	// Determine whether or not we need to create a file.
 	child, ok := parentDir.childMap[name]
 	if !ok {
+		// Nothing can be created in a deleted directory. As in doCreateAt(),
+		// parentDir.dentry can only be dead if it was deleted.
+		if parentDir.dentry.vfsd.IsDead() {
+			return nil, linuxerr.ENOENT
+		}
 		// Already checked for searchability above; now check for writability.
 		if err := parentDir.inode.checkPermissions(rp.Credentials(), vfs.MayWrite); err != nil {
 			return nil, err
@@ -434,6 +443,12 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 		if err := d.inode.checkPermissions(rp.Credentials(), ats); err != nil {
 			return nil, err
 		}
+		// Compare Linux's fs/namei.c:may_open().
+		if d.inode.isAppendOnly() {
+			if (ats.MayWrite() && opts.Flags&linux.O_APPEND == 0) || opts.Flags&linux.O_TRUNC != 0 {
+				return nil, linuxerr.EPERM
+			}
+		}
 	}
 	switch impl := d.inode.impl.(type) {
 	case *regularFile:
@@ -443,9 +458,14 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 			return nil, err
 		}
 		if !afterCreate && opts.Flags&linux.O_TRUNC != 0 {
-			if _, err := impl.truncate(0); err != nil {
+			updated, err := impl.truncate(0)
+			if err != nil {
 				return nil, err
 			}
+			// This precedes IN_OPEN, which is generated by VFS, as in Linux.
+			if updated {
+				d.InotifyWithParent(ctx, linux.IN_MODIFY, 0, vfs.InodeEvent)
+			}
 		}
 		if fd.vfsfd.IsWritable() {
 			fsmetric.TmpfsOpensW.Increment()
@@ -504,7 +524,7 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 		return err
 	}
 
-	if opts.Flags&^linux.RENAME_NOREPLACE != 0 {
+	if opts.Flags&^(linux.RENAME_NOREPLACE|linux.RENAME_EXCHANGE) != 0 {
 		// TODO(b/145974740): Support other renameat2 flags.
 		return linuxerr.EINVAL
 	}
@@ -536,6 +556,9 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 	if err := oldParentDir.mayDelete(rp.Credentials(), renamed); err != nil {
 		return err
 	}
+	if opts.Flags&linux.RENAME_EXCHANGE != 0 {
+		return fs.exchangeLocked(ctx, rp, oldParentDir, renamed, newParentDir, opts)
+	}
 	// Note that we don't need to call rp.CheckMount(), since if renamed is a
 	// mount point then we want to rename the mount point, not anything in the
 	// mounted filesystem.
@@ -563,6 +586,11 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 		if opts.Flags&linux.RENAME_NOREPLACE != 0 {
 			return linuxerr.EEXIST
 		}
+		if replaced != renamed {
+			if err := newParentDir.mayDelete(rp.Credentials(), replaced); err != nil {
+				return err
+			}
+		}
 		replacedDir, ok := replaced.inode.impl.(*directory)
 		if ok {
 			if !renamed.inode.isDir() {
@@ -629,7 +657,103 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 	}
 	renamed.inode.touchCtime()
 
-	vfs.InotifyRename(ctx, &renamed.inode.watches, &oldParentDir.inode.watches, &newParentDir.inode.watches, oldName, newName, renamed.inode.isDir())
+	var replacedWatches *vfs.Watches
+	if replaced != nil {
+		replacedWatches = &replaced.inode.watches
+	}
+	vfs.InotifyRename(ctx, &renamed.inode.watches, replacedWatches, &oldParentDir.inode.watches, &newParentDir.inode.watches, oldName, newName, renamed.inode.isDir())
+	return nil
+}
+
+// exchangeLocked implements RenameAt for RENAME_EXCHANGE, which atomically
+// swaps renamed, the child of oldParentDir, with the existing file at rp,
+// which may be of a different type.
+//
+// Preconditions:
+// * fs.mu must be locked for writing.
+// * The caller must have checked that renamed may be deleted from
+//   oldParentDir, and must hold a write on rp.Mount().
+func (fs *filesystem) exchangeLocked(ctx context.Context, rp *vfs.ResolvingPath, oldParentDir *directory, renamed *dentry, newParentDir *directory, opts vfs.RenameOptions) error {
+	creds := rp.Credentials()
+	if err := newParentDir.inode.checkPermissions(creds, vfs.MayWrite|vfs.MayExec); err != nil {
+		return err
+	}
+	newName := rp.Component()
+	exchanged, ok := newParentDir.childMap[newName]
+	if !ok {
+		return linuxerr.ENOENT
+	}
+	if err := newParentDir.mayDelete(creds, exchanged); err != nil {
+		return err
+	}
+	// A trailing slash on either path requires that file to be a directory.
+	if (opts.MustBeDir && !renamed.inode.isDir()) || (rp.MustBeDir() && !exchanged.inode.isDir()) {
+		return linuxerr.ENOTDIR
+	}
+	// Neither directory may be moved into itself. Unlike other renames,
+	// neither file is replaced, so there is no ENOTEMPTY check.
+	if renamed.inode.isDir() && (renamed == &newParentDir.dentry || genericIsAncestorDentry(renamed, &newParentDir.dentry)) {
+		return linuxerr.EINVAL
+	}
+	if exchanged.inode.isDir() && (exchanged == &oldParentDir.dentry || genericIsAncestorDentry(exchanged, &oldParentDir.dentry)) {
+		return linuxerr.EINVAL
+	}
+	if renamed == exchanged {
+		return nil
+	}
+	if oldParentDir != newParentDir {
+		for _, d := range [...]*dentry{renamed, exchanged} {
+			if d.inode.isDir() {
+				// Writability is needed to change d's "..".
+				if err := d.inode.checkPermissions(creds, vfs.MayWrite); err != nil {
+					return err
+				}
+			}
+		}
+		// A parent gains a link if it receives a directory in exchange
+		// for a non-directory.
+		if renamed.inode.isDir() && !exchanged.inode.isDir() && newParentDir.inode.nlink == maxLinks {
+			return linuxerr.EMLINK
+		}
+		if exchanged.inode.isDir() && !renamed.inode.isDir() && oldParentDir.inode.nlink == maxLinks {
+			return linuxerr.EMLINK
+		}
+	}
+	if newParentDir.dentry.vfsd.IsDead() || oldParentDir.dentry.vfsd.IsDead() {
+		return linuxerr.ENOENT
+	}
+
+	vfsObj := rp.VirtualFilesystem()
+	mntns := vfs.MountNamespaceFromContext(ctx)
+	defer mntns.DecRef(ctx)
+	if err := vfsObj.PrepareRenameDentry(mntns, &renamed.vfsd, &exchanged.vfsd); err != nil {
+		return err
+	}
+	oldName := renamed.name
+	oldParentDir.removeChildLocked(renamed)
+	newParentDir.removeChildLocked(exchanged)
+	oldParentDir.insertChildLocked(exchanged, oldName)
+	newParentDir.insertChildLocked(renamed, newName)
+	vfsObj.CommitRenameExchangeDentry(&renamed.vfsd, &exchanged.vfsd)
+	oldParentDir.inode.touchCMtime()
+	if oldParentDir != newParentDir {
+		// Each directory's ".." now refers to its new parent.
+		if renamed.inode.isDir() {
+			oldParentDir.inode.decLinksLocked(ctx)
+			newParentDir.inode.incLinksLocked()
+		}
+		if exchanged.inode.isDir() {
+			newParentDir.inode.decLinksLocked(ctx)
+			oldParentDir.inode.incLinksLocked()
+		}
+		newParentDir.inode.touchCMtime()
+	}
+	renamed.inode.touchCtime()
+	exchanged.inode.touchCtime()
+
+	// Linux reports an exchange as two moves.
+	vfs.InotifyRename(ctx, &renamed.inode.watches, nil, &oldParentDir.inode.watches, &newParentDir.inode.watches, oldName, newName, renamed.inode.isDir())
+	vfs.InotifyRename(ctx, &exchanged.inode.watches, nil, &newParentDir.inode.watches, &oldParentDir.inode.watches, newName, oldName, exchanged.inode.isDir())
 	return nil
 }
 
@@ -695,7 +819,7 @@ func (fs *filesystem) SetStatAt(ctx context.Context, rp *vfs.ResolvingPath, opts
 		fs.mu.RUnlock()
 		return err
 	}
-	err = d.inode.setStat(ctx, rp.Credentials(), &opts)
+	err = d.inode.setStat(ctx, rp.Credentials(), &opts, rp.Mount())
 	fs.mu.RUnlock()
 	if err != nil {
 		return err
@@ -0,0 +1,91 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+func TestCloneMountNamespace(t *testing.T) {
+	ctx := contexttest.Context(t)
+	creds := auth.CredentialsFromContext(ctx)
+
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	vfsObj.MustRegisterFilesystemType("tmpfs", FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+	})
+	mntns, err := vfsObj.NewMountNamespace(ctx, creds, "", "tmpfs", &vfs.MountOptions{})
+	if err != nil {
+		t.Fatalf("failed to create tmpfs root mount: %v", err)
+	}
+	defer mntns.DecRef(ctx)
+	root := mntns.Root()
+	root.IncRef()
+	defer root.DecRef(ctx)
+
+	pop := func(root vfs.VirtualDentry, path string) *vfs.PathOperation {
+		return &vfs.PathOperation{
+			Root:  root,
+			Start: root,
+			Path:  fspath.Parse(path),
+		}
+	}
+
+	// Mount a tmpfs at /mnt containing a single file.
+	if err := vfsObj.MkdirAt(ctx, creds, pop(root, "mnt"), &vfs.MkdirOptions{Mode: 0755}); err != nil {
+		t.Fatalf("MkdirAt(mnt): %v", err)
+	}
+	if _, err := vfsObj.MountAt(ctx, creds, "", pop(root, "mnt"), "tmpfs", &vfs.MountOptions{}); err != nil {
+		t.Fatalf("MountAt(mnt): %v", err)
+	}
+	if err := vfsObj.MknodAt(ctx, creds, pop(root, "mnt/file"), &vfs.MknodOptions{Mode: linux.S_IFREG | 0644}); err != nil {
+		t.Fatalf("MknodAt(mnt/file): %v", err)
+	}
+
+	clone := vfsObj.CloneMountNamespace(ctx, creds.UserNamespace, mntns)
+	defer clone.DecRef(ctx)
+	if clone.Inode() == mntns.Inode() {
+		t.Errorf("got clone.Inode() = %d, want != %d", clone.Inode(), mntns.Inode())
+	}
+	cloneRoot := clone.Root()
+	cloneRoot.IncRef()
+	defer cloneRoot.DecRef(ctx)
+
+	// The copy of the mount tree includes the mount at /mnt.
+	if _, err := vfsObj.StatAt(ctx, creds, pop(cloneRoot, "mnt/file"), &vfs.StatOptions{}); err != nil {
+		t.Fatalf("StatAt(mnt/file) in cloned namespace: %v", err)
+	}
+
+	// Unmounting /mnt in the copy does not affect the original namespace.
+	if err := vfsObj.UmountAt(ctx, creds, pop(cloneRoot, "mnt"), &vfs.UmountOptions{}); err != nil {
+		t.Fatalf("UmountAt(mnt) in cloned namespace: %v", err)
+	}
+	if _, err := vfsObj.StatAt(ctx, creds, pop(cloneRoot, "mnt/file"), &vfs.StatOptions{}); !linuxerr.Equals(linuxerr.ENOENT, err) {
+		t.Errorf("StatAt(mnt/file) in cloned namespace after umount: got %v, want ENOENT", err)
+	}
+	if _, err := vfsObj.StatAt(ctx, creds, pop(root, "mnt/file"), &vfs.StatOptions{}); err != nil {
+		t.Errorf("StatAt(mnt/file) in original namespace after umount in clone: %v", err)
+	}
+}
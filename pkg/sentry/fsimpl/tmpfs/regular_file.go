@@ -24,7 +24,9 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
 	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
@@ -347,16 +349,33 @@ func (fd *regularFileFD) Release(context.Context) {
 // Allocate implements vfs.FileDescriptionImpl.Allocate.
 func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint64) error {
 	f := fd.inode().impl.(*regularFile)
-
 	f.inode.mu.Lock()
 	defer f.inode.mu.Unlock()
-	oldSize := f.size
-	size := offset + length
-	if oldSize >= size {
+
+	// Compare Linux's fs/open.c:vfs_fallocate().
+	if f.inode.isImmutable() || (f.inode.isAppendOnly() && mode&^linux.FALLOC_FL_KEEP_SIZE != 0) {
+		return linuxerr.EPERM
+	}
+
+	if mode&linux.FALLOC_FL_UNSHARE_RANGE != 0 {
+		// tmpfs files never share pages with other files, so there is nothing
+		// to unshare.
+		if offset%hostarch.PageSize != 0 || length%hostarch.PageSize != 0 {
+			return linuxerr.EINVAL
+		}
+	}
+
+	// tmpfs doesn't preallocate pages, so fallocate(2) only changes the file
+	// size. As in Linux, ctime is updated even if the size doesn't change.
+	if mode&linux.FALLOC_FL_KEEP_SIZE != 0 || f.size >= offset+length {
+		atomic.StoreInt64(&f.inode.ctime, f.inode.fs.clock.Now().Nanoseconds())
 		return nil
 	}
-	_, err := f.truncateLocked(size)
-	return err
+	if _, err := f.truncateLocked(offset + length); err != nil {
+		return err
+	}
+	f.inode.touchCMtimeLocked()
+	return nil
 }
 
 // PRead implements vfs.FileDescriptionImpl.PRead.
@@ -425,6 +444,12 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	f := fd.inode().impl.(*regularFile)
 	f.inode.mu.Lock()
 	defer f.inode.mu.Unlock()
+	// The file may have been made immutable or append-only after fd was
+	// opened. Flags are changed with f.inode.mu locked, so they can't change
+	// again before the write completes.
+	if f.inode.isImmutable() || (f.inode.isAppendOnly() && fd.vfsfd.StatusFlags()&linux.O_APPEND == 0) {
+		return 0, offset, linuxerr.EPERM
+	}
 	// If the file is opened with O_APPEND, update offset to file size.
 	if fd.vfsfd.StatusFlags()&linux.O_APPEND != 0 {
 		// Locking f.inode.mu is sufficient for reading f.size.
@@ -492,6 +517,124 @@ func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpt
 	return vfs.GenericConfigureMMap(&fd.vfsfd, file, opts)
 }
 
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *regularFileFD) Ioctl(ctx context.Context, uio usermem.IO, args arch.SyscallArguments) (uintptr, error) {
+	switch cmd := args[1].Uint(); cmd {
+	case linux.FIBMAP:
+		return 0, fd.fibmap(ctx, uio, args[2].Pointer())
+	case linux.FS_IOC_FIEMAP:
+		return 0, fd.fiemap(ctx, uio, args[2].Pointer())
+	default:
+		return fd.fileDescription.Ioctl(ctx, uio, args)
+	}
+}
+
+// fibmap maps the logical block (of size hostarch.PageSize) at addr to a
+// physical block. tmpfs has no backing device, and offsets into the sentry's
+// memory file must not be exposed to applications, so every block is
+// reported as unmapped (0), as for a hole.
+//
+// Compare Linux's fs/ioctl.c:ioctl_fibmap().
+func (fd *regularFileFD) fibmap(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	if !auth.CredentialsFromContext(ctx).HasCapability(linux.CAP_SYS_RAWIO) {
+		return linuxerr.EPERM
+	}
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	var block int32
+	if _, err := primitive.CopyInt32In(&cc, addr, &block); err != nil {
+		return err
+	}
+	if block < 0 {
+		return linuxerr.EINVAL
+	}
+	_, err := primitive.CopyInt32Out(&cc, addr, 0)
+	return err
+}
+
+// fiemap reports the allocated extents of the file that overlap the range
+// requested by the struct fiemap at addr. Holes in the file are not reported.
+// If fm_extent_count is 0, only the number of extents is reported.
+//
+// Extents are stored in the sentry's memory file, whose offsets must not be
+// exposed to applications, so their physical location is reported as
+// unknown. tmpfs doesn't preallocate pages in fallocate(2), so there are no
+// unwritten extents.
+//
+// Compare Linux's fs/ioctl.c:ioctl_fiemap().
+func (fd *regularFileFD) fiemap(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	var fm linux.FIEMAP
+	if _, err := fm.CopyIn(&cc, addr); err != nil {
+		return err
+	}
+	if fm.ExtentCount > linux.FIEMAP_MAX_EXTENTS {
+		return linuxerr.EINVAL
+	}
+	if fm.Length == 0 {
+		return linuxerr.EINVAL
+	}
+	// tmpfs has no extended attribute blocks to map, and all file data is
+	// always "synced".
+	if unsupported := fm.Flags &^ linux.FIEMAP_FLAG_SYNC; unsupported != 0 {
+		// Report the unsupported flags back to the caller.
+		fm.Flags = unsupported
+		if _, err := fm.CopyOut(&cc, addr); err != nil {
+			return err
+		}
+		return linuxerr.EBADR
+	}
+	end := fm.Start + fm.Length
+	if end < fm.Start {
+		// Overflow.
+		end = linux.FIEMAP_MAX_OFFSET
+	}
+
+	rf := fd.inode().impl.(*regularFile)
+	rf.dataMu.RLock()
+	defer rf.dataMu.RUnlock()
+	fm.MappedExtents = 0
+	for seg := rf.data.LowerBoundSegment(fm.Start); seg.Ok() && seg.Start() < end; seg = seg.NextSegment() {
+		if fm.ExtentCount == 0 {
+			// Only count extents.
+			fm.MappedExtents++
+			continue
+		}
+		if fm.MappedExtents == fm.ExtentCount {
+			break
+		}
+		fe := linux.FIEMAPExtent{
+			Logical: seg.Start(),
+			Length:  seg.Range().Length(),
+			Flags:   linux.FIEMAP_EXTENT_UNKNOWN,
+		}
+		if !seg.NextSegment().Ok() {
+			fe.Flags |= linux.FIEMAP_EXTENT_LAST
+		}
+		feAddr, ok := addr.AddLength(linux.SizeOfFIEMAP + uint64(fm.MappedExtents)*linux.SizeOfFIEMAPExtent)
+		if !ok {
+			return linuxerr.EFAULT
+		}
+		if _, err := fe.CopyOut(&cc, feAddr); err != nil {
+			return err
+		}
+		fm.MappedExtents++
+	}
+	_, err := fm.CopyOut(&cc, addr)
+	return err
+}
+
 // regularFileReadWriter implements safemem.Reader and Safemem.Writer.
 type regularFileReadWriter struct {
 	file *regularFile
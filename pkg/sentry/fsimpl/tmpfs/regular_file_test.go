@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
 	"gvisor.dev/gvisor/pkg/sentry/fs/lock"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -347,3 +349,90 @@ func TestTruncate(t *testing.T) {
 		t.Errorf("fd.Stat got Ctime %v, want %v", got, statAfterTruncateUp.Ctime)
 	}
 }
+
+func TestFIEMAP(t *testing.T) {
+	ctx := contexttest.RootContext(t)
+	fd, cleanup, err := newFileFD(ctx, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// Write one page at the start of the file and one page at offset
+	// 4*PageSize, leaving a hole in between.
+	data := bytes.Repeat([]byte{'a'}, hostarch.PageSize)
+	for _, off := range []int64{0, 4 * hostarch.PageSize} {
+		if _, err := fd.PWrite(ctx, usermem.BytesIOSequence(data), off, vfs.WriteOptions{}); err != nil {
+			t.Fatalf("fd.PWrite failed: %v", err)
+		}
+	}
+
+	const maxExtents = 4
+	fiemap := func(fm linux.FIEMAP) (linux.FIEMAP, []linux.FIEMAPExtent) {
+		t.Helper()
+		buf := make([]byte, linux.SizeOfFIEMAP+maxExtents*linux.SizeOfFIEMAPExtent)
+		fm.MarshalBytes(buf)
+		args := arch.SyscallArguments{
+			{},
+			{Value: uintptr(linux.FS_IOC_FIEMAP)},
+			{Value: 0},
+		}
+		if _, err := fd.Ioctl(ctx, &usermem.BytesIO{Bytes: buf}, args); err != nil {
+			t.Fatalf("ioctl(FS_IOC_FIEMAP) failed: %v", err)
+		}
+		fm.UnmarshalBytes(buf)
+		extents := make([]linux.FIEMAPExtent, fm.MappedExtents)
+		for i := range extents {
+			off := linux.SizeOfFIEMAP + i*linux.SizeOfFIEMAPExtent
+			extents[i].UnmarshalBytes(buf[off:])
+		}
+		return fm, extents
+	}
+
+	// Count-only query.
+	if fm, _ := fiemap(linux.FIEMAP{Length: linux.FIEMAP_MAX_OFFSET}); fm.MappedExtents != 2 {
+		t.Errorf("count-only FIEMAP got %d extents, want 2", fm.MappedExtents)
+	}
+
+	// Full query.
+	_, extents := fiemap(linux.FIEMAP{Length: linux.FIEMAP_MAX_OFFSET, ExtentCount: maxExtents})
+	want := []struct {
+		logical uint64
+		length  uint64
+		flags   uint32
+	}{
+		{0, hostarch.PageSize, linux.FIEMAP_EXTENT_UNKNOWN},
+		{4 * hostarch.PageSize, hostarch.PageSize, linux.FIEMAP_EXTENT_UNKNOWN | linux.FIEMAP_EXTENT_LAST},
+	}
+	if len(extents) != len(want) {
+		t.Fatalf("FIEMAP got %d extents, want %d", len(extents), len(want))
+	}
+	for i, w := range want {
+		if got := extents[i]; got.Logical != w.logical || got.Physical != 0 || got.Length != w.length || got.Flags != w.flags {
+			t.Errorf("FIEMAP extent %d got {logical=%d length=%d flags=%#x}, want {logical=%d length=%d flags=%#x}", i, got.Logical, got.Length, got.Flags, w.logical, w.length, w.flags)
+		}
+	}
+
+	// A query of the hole only should return no extents.
+	if fm, _ := fiemap(linux.FIEMAP{Start: hostarch.PageSize, Length: 3 * hostarch.PageSize, ExtentCount: maxExtents}); fm.MappedExtents != 0 {
+		t.Errorf("FIEMAP of hole got %d extents, want 0", fm.MappedExtents)
+	}
+
+	// FIBMAP should return 0 for a block in the hole, and doesn't expose the
+	// location of written blocks either.
+	for _, block := range []uint32{0, 2} {
+		buf := make([]byte, 4)
+		hostarch.ByteOrder.PutUint32(buf, block)
+		args := arch.SyscallArguments{
+			{},
+			{Value: linux.FIBMAP},
+			{Value: 0},
+		}
+		if _, err := fd.Ioctl(ctx, &usermem.BytesIO{Bytes: buf}, args); err != nil {
+			t.Fatalf("ioctl(FIBMAP) failed: %v", err)
+		}
+		if got := hostarch.ByteOrder.Uint32(buf); got != 0 {
+			t.Errorf("FIBMAP of block %d got %d, want 0", block, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+func TestRenameExchangeFileAndDirectory(t *testing.T) {
+	ctx := contexttest.Context(t)
+	creds := auth.CredentialsFromContext(ctx)
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	vfsObj.MustRegisterFilesystemType("tmpfs", FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+	})
+	mntns, err := vfsObj.NewMountNamespace(ctx, creds, "", "tmpfs", &vfs.MountOptions{})
+	if err != nil {
+		t.Fatalf("failed to create tmpfs root mount: %v", err)
+	}
+	defer mntns.DecRef(ctx)
+	// Renames need the mount namespace to check for mount points.
+	ctx = vfs.WithMountNamespace(ctx, mntns)
+	root := mntns.Root()
+
+	pop := func(path string) *vfs.PathOperation {
+		return &vfs.PathOperation{Root: root, Start: root, Path: fspath.Parse(path)}
+	}
+	stat := func(path string) linux.Statx {
+		t.Helper()
+		st, err := vfsObj.StatAt(ctx, creds, pop(path), &vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_NLINK | linux.STATX_INO})
+		if err != nil {
+			t.Fatalf("StatAt(%q) failed: %v", path, err)
+		}
+		return st
+	}
+
+	for _, dir := range []string{"/p1", "/p2", "/p2/dir"} {
+		if err := vfsObj.MkdirAt(ctx, creds, pop(dir), &vfs.MkdirOptions{Mode: 0755}); err != nil {
+			t.Fatalf("MkdirAt(%q) failed: %v", dir, err)
+		}
+	}
+	for _, file := range []string{"/p1/file", "/p2/dir/child"} {
+		fd, err := vfsObj.OpenAt(ctx, creds, pop(file), &vfs.OpenOptions{Flags: linux.O_CREAT | linux.O_WRONLY, Mode: 0644})
+		if err != nil {
+			t.Fatalf("OpenAt(%q) failed: %v", file, err)
+		}
+		fd.DecRef(ctx)
+	}
+	dirIno := stat("/p2/dir").Ino
+	fileIno := stat("/p1/file").Ino
+
+	// Exchange requires both files to exist.
+	if err := vfsObj.RenameAt(ctx, creds, pop("/p1/file"), pop("/p2/nonexistent"), &vfs.RenameOptions{Flags: linux.RENAME_EXCHANGE}); !linuxerr.Equals(linuxerr.ENOENT, err) {
+		t.Errorf("RenameAt(RENAME_EXCHANGE) with nonexistent target got error %v, want ENOENT", err)
+	}
+	// A directory can't be exchanged with its own descendant.
+	if err := vfsObj.RenameAt(ctx, creds, pop("/p2"), pop("/p2/dir"), &vfs.RenameOptions{Flags: linux.RENAME_EXCHANGE}); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("RenameAt(RENAME_EXCHANGE) with descendant got error %v, want EINVAL", err)
+	}
+
+	if err := vfsObj.RenameAt(ctx, creds, pop("/p1/file"), pop("/p2/dir"), &vfs.RenameOptions{Flags: linux.RENAME_EXCHANGE}); err != nil {
+		t.Fatalf("RenameAt(RENAME_EXCHANGE) failed: %v", err)
+	}
+
+	if st := stat("/p1/file"); st.Ino != dirIno || st.Mode&linux.S_IFMT != linux.S_IFDIR {
+		t.Errorf("/p1/file got ino %d mode %#o, want directory with ino %d", st.Ino, st.Mode, dirIno)
+	}
+	if st := stat("/p2/dir"); st.Ino != fileIno || st.Mode&linux.S_IFMT != linux.S_IFREG {
+		t.Errorf("/p2/dir got ino %d mode %#o, want regular file with ino %d", st.Ino, st.Mode, fileIno)
+	}
+	stat("/p1/file/child")
+	// The directory's ".." link moved from p2 to p1.
+	if got := stat("/p1").Nlink; got != 3 {
+		t.Errorf("/p1 got nlink %d, want 3", got)
+	}
+	if got := stat("/p2").Nlink; got != 2 {
+		t.Errorf("/p2 got nlink %d, want 2", got)
+	}
+	if got, want := stat("/p1/file/..").Ino, stat("/p1").Ino; got != want {
+		t.Errorf("/p1/file/.. got ino %d, want %d", got, want)
+	}
+}
@@ -38,12 +38,15 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sentry/vfs/memxattr"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // Name is the default filesystem name.
@@ -287,6 +290,12 @@ type dentry struct {
 	// dentryEntry (ugh) links dentries into their parent directory.childList.
 	dentryEntry
 
+	// dirOff is the offset of this dentry's entry in its parent directory.
+	// Offsets are assigned in increasing order as children are inserted and
+	// are never reused, so they remain valid across unrelated insertions and
+	// removals. dirOff is protected by the parent directory's iterMu.
+	dirOff int64
+
 	// inode is the inode represented by this dentry. Multiple Dentries may
 	// share a single non-directory inode (with hard links). inode is
 	// immutable.
@@ -375,6 +384,11 @@ type inode struct {
 	gid   uint32     // auth.KGID, but ...
 	ino   uint64     // immutable
 
+	// flags is the set of inode flags (linux.FS_*_FL) set by FS_IOC_SETFLAGS.
+	// flags is accessed using atomic memory operations, and may only be
+	// mutated while holding mu.
+	flags uint32
+
 	// Linux's tmpfs has no concept of btime.
 	atime int64 // nanoseconds
 	ctime int64 // nanoseconds
@@ -470,10 +484,24 @@ func (i *inode) decRef(ctx context.Context) {
 }
 
 func (i *inode) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
+	// Compare Linux's fs/namei.c:inode_permission().
+	if ats.MayWrite() && i.isImmutable() {
+		return linuxerr.EPERM
+	}
 	mode := linux.FileMode(atomic.LoadUint32(&i.mode))
 	return vfs.GenericCheckPermissions(creds, ats, mode, auth.KUID(atomic.LoadUint32(&i.uid)), auth.KGID(atomic.LoadUint32(&i.gid)))
 }
 
+// isImmutable returns true if i is marked immutable by FS_IMMUTABLE_FL.
+func (i *inode) isImmutable() bool {
+	return atomic.LoadUint32(&i.flags)&linux.FS_IMMUTABLE_FL != 0
+}
+
+// isAppendOnly returns true if i is marked append-only by FS_APPEND_FL.
+func (i *inode) isAppendOnly() bool {
+	return atomic.LoadUint32(&i.flags)&linux.FS_APPEND_FL != 0
+}
+
 // Go won't inline this function, and returning linux.Statx (which is quite
 // big) means spending a lot of time in runtime.duffcopy(), so instead it's an
 // output parameter.
@@ -496,6 +524,14 @@ func (i *inode) statTo(stat *linux.Statx) {
 	stat.Mtime = linux.NsecToStatxTimestamp(atomic.LoadInt64(&i.mtime))
 	stat.DevMajor = linux.UNNAMED_MAJOR
 	stat.DevMinor = i.fs.devMinor
+	stat.AttributesMask = linux.STATX_ATTR_IMMUTABLE | linux.STATX_ATTR_APPEND
+	flags := atomic.LoadUint32(&i.flags)
+	if flags&linux.FS_IMMUTABLE_FL != 0 {
+		stat.Attributes |= linux.STATX_ATTR_IMMUTABLE
+	}
+	if flags&linux.FS_APPEND_FL != 0 {
+		stat.Attributes |= linux.STATX_ATTR_APPEND
+	}
 	switch impl := i.impl.(type) {
 	case *regularFile:
 		stat.Mask |= linux.STATX_SIZE | linux.STATX_BLOCKS
@@ -521,7 +557,7 @@ func (i *inode) statTo(stat *linux.Statx) {
 	}
 }
 
-func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.SetStatOptions) error {
+func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.SetStatOptions, mnt *vfs.Mount) error {
 	stat := &opts.Stat
 	if stat.Mask == 0 {
 		return nil
@@ -529,10 +565,27 @@ func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.
 	if stat.Mask&^(linux.STATX_MODE|linux.STATX_UID|linux.STATX_GID|linux.STATX_ATIME|linux.STATX_MTIME|linux.STATX_CTIME|linux.STATX_SIZE) != 0 {
 		return linuxerr.EPERM
 	}
+	// Compare Linux's fs/attr.c:notify_change() and fs/open.c:do_truncate().
+	// Only updating timestamps to the current time is permitted on
+	// append-only files.
+	if i.isImmutable() {
+		return linuxerr.EPERM
+	}
+	if i.isAppendOnly() {
+		if stat.Mask&(linux.STATX_MODE|linux.STATX_UID|linux.STATX_GID|linux.STATX_SIZE) != 0 ||
+			(stat.Mask&linux.STATX_ATIME != 0 && stat.Atime.Nsec != linux.UTIME_NOW) ||
+			(stat.Mask&linux.STATX_MTIME != 0 && stat.Mtime.Nsec != linux.UTIME_NOW) {
+			return linuxerr.EPERM
+		}
+	}
 	mode := linux.FileMode(atomic.LoadUint32(&i.mode))
 	if err := vfs.CheckSetStat(ctx, creds, opts, mode, auth.KUID(atomic.LoadUint32(&i.uid)), auth.KGID(atomic.LoadUint32(&i.gid))); err != nil {
 		return err
 	}
+	if err := mnt.CheckBeginWrite(); err != nil {
+		return err
+	}
+	defer mnt.EndWrite()
 
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -754,6 +807,10 @@ func (i *inode) setXattr(creds *auth.Credentials, opts *vfs.SetXattrOptions) err
 	if err := checkXattrName(opts.Name); err != nil {
 		return err
 	}
+	// Compare Linux's fs/xattr.c:xattr_permission().
+	if i.isImmutable() || i.isAppendOnly() {
+		return linuxerr.EPERM
+	}
 	mode := linux.FileMode(atomic.LoadUint32(&i.mode))
 	kuid := auth.KUID(atomic.LoadUint32(&i.uid))
 	kgid := auth.KGID(atomic.LoadUint32(&i.gid))
@@ -767,6 +824,10 @@ func (i *inode) removeXattr(creds *auth.Credentials, name string) error {
 	if err := checkXattrName(name); err != nil {
 		return err
 	}
+	// Compare Linux's fs/xattr.c:xattr_permission().
+	if i.isImmutable() || i.isAppendOnly() {
+		return linuxerr.EPERM
+	}
 	mode := linux.FileMode(atomic.LoadUint32(&i.mode))
 	kuid := auth.KUID(atomic.LoadUint32(&i.uid))
 	kgid := auth.KGID(atomic.LoadUint32(&i.gid))
@@ -809,7 +870,7 @@ func (fd *fileDescription) Stat(ctx context.Context, opts vfs.StatOptions) (linu
 func (fd *fileDescription) SetStat(ctx context.Context, opts vfs.SetStatOptions) error {
 	creds := auth.CredentialsFromContext(ctx)
 	d := fd.dentry()
-	if err := d.inode.setStat(ctx, creds, &opts); err != nil {
+	if err := d.inode.setStat(ctx, creds, &opts, fd.vfsfd.Mount()); err != nil {
 		return err
 	}
 
@@ -858,6 +919,74 @@ func (fd *fileDescription) RemoveXattr(ctx context.Context, name string) error {
 	return nil
 }
 
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *fileDescription) Ioctl(ctx context.Context, uio usermem.IO, args arch.SyscallArguments) (uintptr, error) {
+	switch cmd := args[1].Uint(); cmd {
+	case linux.FS_IOC_GETFLAGS:
+		return 0, fd.getFlags(ctx, uio, args[2].Pointer())
+	case linux.FS_IOC_SETFLAGS:
+		return 0, fd.setFlags(ctx, uio, args[2].Pointer())
+	default:
+		return fd.FileDescriptionDefaultImpl.Ioctl(ctx, uio, args)
+	}
+}
+
+// getFlags copies the inode flags of fd's file out to addr.
+func (fd *fileDescription) getFlags(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	// Despite the ioctl's encoded argument size, Linux copies an int.
+	_, err := primitive.CopyInt32Out(&cc, addr, int32(atomic.LoadUint32(&fd.inode().flags)))
+	return err
+}
+
+// setFlags sets the inode flags of fd's file to those at addr.
+//
+// Compare Linux's fs/ioctl.c:ioctl_setflags() and
+// mm/shmem.c:shmem_fileattr_set().
+func (fd *fileDescription) setFlags(ctx context.Context, uio usermem.IO, addr hostarch.Addr) error {
+	cc := usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  uio,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	var flags int32
+	if _, err := primitive.CopyInt32In(&cc, addr, &flags); err != nil {
+		return err
+	}
+	if uint32(flags)&^linux.SettableInodeFlags != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+
+	mnt := fd.vfsfd.Mount()
+	if err := mnt.CheckBeginWrite(); err != nil {
+		return err
+	}
+	defer mnt.EndWrite()
+
+	creds := auth.CredentialsFromContext(ctx)
+	i := fd.inode()
+	if !vfs.CanActAsOwner(creds, auth.KUID(atomic.LoadUint32(&i.uid))) {
+		return linuxerr.EPERM
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if (atomic.LoadUint32(&i.flags)^uint32(flags))&(linux.FS_IMMUTABLE_FL|linux.FS_APPEND_FL) != 0 && !creds.HasCapability(linux.CAP_LINUX_IMMUTABLE) {
+		return linuxerr.EPERM
+	}
+	atomic.StoreUint32(&i.flags, uint32(flags))
+	atomic.StoreInt64(&i.ctime, i.fs.clock.Now().Nanoseconds())
+	return nil
+}
+
 // Sync implements vfs.FileDescriptionImpl.Sync. It does nothing because all
 // filesystem state is in-memory.
 func (*fileDescription) Sync(context.Context) error {
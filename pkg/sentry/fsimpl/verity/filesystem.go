@@ -1003,6 +1003,7 @@ func (fs *filesystem) StatAt(ctx context.Context, rp *vfs.ResolvingPath, opts vf
 		}
 	}
 	d.dirMu.Unlock()
+	d.statVerityAttribute(&stat)
 	return stat, nil
 }
 
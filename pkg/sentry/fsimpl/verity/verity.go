@@ -746,6 +746,17 @@ func (d *dentry) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes)
 	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(atomic.LoadUint32(&d.mode)), auth.KUID(atomic.LoadUint32(&d.uid)), auth.KGID(atomic.LoadUint32(&d.gid)))
 }
 
+// statVerityAttribute reports in stat whether d has a verity hash, consistent
+// with FS_VERITY_FL in FS_IOC_GETFLAGS.
+func (d *dentry) statVerityAttribute(stat *linux.Statx) {
+	stat.AttributesMask |= linux.STATX_ATTR_VERITY
+	d.hashMu.RLock()
+	if len(d.hash) != 0 {
+		stat.Attributes |= linux.STATX_ATTR_VERITY
+	}
+	d.hashMu.RUnlock()
+}
+
 // verityEnabled checks whether the file is enabled with verity features. It
 // should always be true if runtime enable is not allowed. In runtime enable
 // mode, it returns true if the target has been enabled with
@@ -869,6 +880,7 @@ func (fd *fileDescription) Stat(ctx context.Context, opts vfs.StatOptions) (linu
 		}
 	}
 	fd.d.dirMu.Unlock()
+	fd.d.statVerityAttribute(&stat)
 	return stat, nil
 }
 
@@ -1226,7 +1238,7 @@ func (fd *fileDescription) Ioctl(ctx context.Context, uio usermem.IO, args arch.
 	case linux.FS_IOC_GETFLAGS:
 		return fd.verityFlags(ctx, args[2].Pointer())
 	default:
-		return 0, linuxerr.ENOSYS
+		return 0, linuxerr.ENOTTY
 	}
 }
 
@@ -38,16 +38,20 @@ var (
 	Opens    = metric.MustCreateNewUint64Metric("/fs/opens", false /* sync */, "Number of file opens.")
 	Reads    = metric.MustCreateNewUint64Metric("/fs/reads", false /* sync */, "Number of file reads.")
 	ReadWait = metric.MustCreateNewUint64NanosecondsMetric("/fs/read_wait", false /* sync */, "Time waiting on file reads, in nanoseconds.")
+	Syncs    = metric.MustCreateNewUint64Metric("/fs/syncs", false /* sync */, "Number of filesystem syncs requested by sync(2) and syncfs(2).")
 )
 
 // Metrics that only apply to fs/gofer and fsimpl/gofer.
 var (
-	GoferOpens9P      = metric.MustCreateNewUint64Metric("/gofer/opens_9p", false /* sync */, "Number of times a file was opened from a gofer and did not have a host file descriptor.")
-	GoferOpensHost    = metric.MustCreateNewUint64Metric("/gofer/opens_host", false /* sync */, "Number of times a file was opened from a gofer and did have a host file descriptor.")
-	GoferReads9P      = metric.MustCreateNewUint64Metric("/gofer/reads_9p", false /* sync */, "Number of 9P file reads from a gofer.")
-	GoferReadWait9P   = metric.MustCreateNewUint64NanosecondsMetric("/gofer/read_wait_9p", false /* sync */, "Time waiting on 9P file reads from a gofer, in nanoseconds.")
-	GoferReadsHost    = metric.MustCreateNewUint64Metric("/gofer/reads_host", false /* sync */, "Number of host file reads from a gofer.")
-	GoferReadWaitHost = metric.MustCreateNewUint64NanosecondsMetric("/gofer/read_wait_host", false /* sync */, "Time waiting on host file reads from a gofer, in nanoseconds.")
+	GoferOpens9P           = metric.MustCreateNewUint64Metric("/gofer/opens_9p", false /* sync */, "Number of times a file was opened from a gofer and did not have a host file descriptor.")
+	GoferOpensHost         = metric.MustCreateNewUint64Metric("/gofer/opens_host", false /* sync */, "Number of times a file was opened from a gofer and did have a host file descriptor.")
+	GoferReads9P           = metric.MustCreateNewUint64Metric("/gofer/reads_9p", false /* sync */, "Number of 9P file reads from a gofer.")
+	GoferReadWait9P        = metric.MustCreateNewUint64NanosecondsMetric("/gofer/read_wait_9p", false /* sync */, "Time waiting on 9P file reads from a gofer, in nanoseconds.")
+	GoferReadsHost         = metric.MustCreateNewUint64Metric("/gofer/reads_host", false /* sync */, "Number of host file reads from a gofer.")
+	GoferReadWaitHost      = metric.MustCreateNewUint64NanosecondsMetric("/gofer/read_wait_host", false /* sync */, "Time waiting on host file reads from a gofer, in nanoseconds.")
+	GoferSyncBytes         = metric.MustCreateNewUint64Metric("/gofer/sync_bytes", false /* sync */, "Number of bytes of dirty cached file data written back to a gofer by sync(2) and syncfs(2).")
+	GoferPrefetchedPages   = metric.MustCreateNewUint64Metric("/gofer/prefetched_pages", false /* sync */, "Number of pages of gofer file data read into the page cache in response to POSIX_FADV_WILLNEED.")
+	GoferPrefetchUsedPages = metric.MustCreateNewUint64Metric("/gofer/prefetch_used_pages", false /* sync */, "Number of prefetched pages of gofer file data that were subsequently read.")
 )
 
 // Metrics that only apply to fs/tmpfs and fsimpl/tmpfs.
@@ -73,6 +77,7 @@ func StartReadWait() time.Time {
 // FinishReadWait is marked nosplit for performance since it's often called
 // from defer statements, which prevents it from being inlined
 // (https://github.com/golang/go/issues/38471).
+//
 //go:nosplit
 func FinishReadWait(m *metric.Uint64Metric, start time.Time) {
 	if !RecordWaitTime {
@@ -30,6 +30,19 @@ type Stack interface {
 	// RemoveInterface removes the specified network interface.
 	RemoveInterface(idx int32) error
 
+	// AddTunnelInterface adds a tunnel interface named name, as described by
+	// config, and returns its index. If name is empty, a name is chosen.
+	AddTunnelInterface(name string, config TunnelConfig) (int32, error)
+
+	// AddBondInterface adds a bond interface named name, as described by
+	// config, and returns its index. If name is empty, a name is chosen.
+	AddBondInterface(name string, config BondConfig) (int32, error)
+
+	// SetInterfaceMaster enslaves the network interface identified by idx to
+	// the bond identified by master. If master is zero, the interface is
+	// released from its bond, if any.
+	SetInterfaceMaster(idx, master int32) error
+
 	// InterfaceAddrs returns all network interface addresses as a mapping from
 	// interface indexes to a slice of associated interface address properties.
 	InterfaceAddrs() map[int32][]InterfaceAddr
@@ -101,6 +114,18 @@ type Stack interface {
 	// SetPortRange sets the UDP and TCP IPv4 and IPv6 ephemeral port range
 	// (inclusive).
 	SetPortRange(start uint16, end uint16) error
+
+	// Neighbors returns the entries of the ARP and NDP neighbor tables of all
+	// network interfaces.
+	Neighbors() ([]Neighbor, error)
+
+	// AddNeighbor adds a static entry to the neighbor table of the network
+	// interface n.Index, replacing any existing entry for n.Addr.
+	AddNeighbor(n Neighbor) error
+
+	// RemoveNeighbor removes the entry for n.Addr from the neighbor table of
+	// the network interface n.Index.
+	RemoveNeighbor(n Neighbor) error
 }
 
 // Interface contains information about a network interface.
@@ -120,6 +145,41 @@ type Interface struct {
 
 	// MTU is the maximum transmission unit.
 	MTU uint32
+
+	// Master is the index of the bond the device is enslaved to, or zero.
+	Master int32
+
+	// Bond holds the state of a bond device. It is nil for other devices.
+	Bond *BondInfo
+
+	// BondSlave holds the state of the device in the bond it is enslaved to.
+	// It is nil if the device is not enslaved.
+	BondSlave *BondSlaveInfo
+}
+
+// BondInfo contains information about a bond network interface.
+type BondInfo struct {
+	// Mode is the bonding mode, a Linux BOND_MODE_* constant.
+	Mode uint8
+
+	// MIIMon is the link monitoring interval in milliseconds.
+	MIIMon uint32
+
+	// ActiveSlave is the index of the active slave, or zero.
+	ActiveSlave int32
+}
+
+// BondSlaveInfo contains information about a network interface enslaved to a
+// bond.
+type BondSlaveInfo struct {
+	// State is the state of the slave, a Linux BOND_STATE_* constant.
+	State uint8
+
+	// MIIStatus is the link state of the slave, a Linux BOND_LINK_* constant.
+	MIIStatus uint8
+
+	// LinkFailureCount is the number of times the link of the slave failed.
+	LinkFailureCount uint32
 }
 
 // InterfaceAddr contains information about a network interface address.
@@ -137,6 +197,109 @@ type InterfaceAddr struct {
 	Addr []byte
 }
 
+// Neighbor contains information about an entry of a neighbor table, i.e. the
+// ARP table for IPv4 and the NDP neighbor cache for IPv6.
+type Neighbor struct {
+	// Family is the address family of Addr, a Linux AF_* constant.
+	Family uint8
+
+	// Index is the index of the network interface the entry belongs to.
+	Index int32
+
+	// State is the state of the entry, a Linux NUD_* constant.
+	State uint16
+
+	// Addr is the network address of the neighbor.
+	Addr []byte
+
+	// LinkAddr is the link address of the neighbor. It is empty if the link
+	// address is not known.
+	LinkAddr []byte
+}
+
+// NeighborObserver is notified of changes to the neighbor tables of network
+// stacks.
+type NeighborObserver interface {
+	// NeighborChanged is called when an entry of a neighbor table of stack is
+	// added or changes state.
+	NeighborChanged(stack Stack, n Neighbor)
+
+	// NeighborRemoved is called when an entry is removed from a neighbor table
+	// of stack.
+	NeighborRemoved(stack Stack, n Neighbor)
+}
+
+// neighborObserver is the registered NeighborObserver, if any. It is only
+// written during initialization.
+var neighborObserver NeighborObserver
+
+// RegisterNeighborObserver registers o to be notified of changes to the
+// neighbor tables of all network stacks.
+//
+// Preconditions: May only be called before any network stacks are created.
+func RegisterNeighborObserver(o NeighborObserver) {
+	if neighborObserver != nil {
+		panic("NeighborObserver already registered")
+	}
+	neighborObserver = o
+}
+
+// NotifyNeighborChanged notifies the registered NeighborObserver, if any, that
+// the neighbor table entry n of stack was added or changed state.
+func NotifyNeighborChanged(stack Stack, n Neighbor) {
+	if neighborObserver != nil {
+		neighborObserver.NeighborChanged(stack, n)
+	}
+}
+
+// NotifyNeighborRemoved notifies the registered NeighborObserver, if any, that
+// the neighbor table entry n of stack was removed.
+func NotifyNeighborRemoved(stack Stack, n Neighbor) {
+	if neighborObserver != nil {
+		neighborObserver.NeighborRemoved(stack, n)
+	}
+}
+
+// TunnelConfig describes a tunnel interface.
+type TunnelConfig struct {
+	// Kind is the kind of the tunnel, one of "gre", "gretap", "ip6gre" and
+	// "ipip".
+	Kind string
+
+	// Local is the local address of the tunnel. It may be empty.
+	Local []byte
+
+	// Remote is the address of the remote end of the tunnel.
+	Remote []byte
+
+	// TTL is the TTL of encapsulated packets. Zero means that it is inherited
+	// from the packets being encapsulated.
+	TTL uint8
+
+	// IFlags and OFlags are the GRE options, Linux GRE_* constants, of
+	// received and sent packets.
+	IFlags uint16
+	OFlags uint16
+
+	// IKey and OKey are the GRE keys of received and sent packets.
+	IKey uint32
+	OKey uint32
+
+	// MTU is the MTU of the tunnel. Zero means that it is derived from the
+	// path to the remote end of the tunnel.
+	MTU uint32
+}
+
+// BondConfig describes a bond interface.
+type BondConfig struct {
+	// Mode is the bonding mode, a Linux BOND_MODE_* constant.
+	Mode uint8
+
+	// MIIMon is the link monitoring interval in milliseconds. Zero disables
+	// link monitoring.
+	MIIMon uint32
+}
+
 // TCPBufferSize contains settings controlling TCP buffer sizing.
 //
 // +stateify savable
@@ -33,6 +33,7 @@ type TestStack struct {
 	TCPSACKFlag       bool
 	Recovery          TCPLossRecovery
 	IPForwarding      bool
+	NeighborList      []Neighbor
 }
 
 // NewTestStack returns a TestStack with no network interfaces. The value of
@@ -56,6 +57,63 @@ func (s *TestStack) RemoveInterface(idx int32) error {
 	return nil
 }
 
+// AddTunnelInterface implements Stack.
+func (s *TestStack) AddTunnelInterface(name string, config TunnelConfig) (int32, error) {
+	idx := int32(len(s.InterfacesMap) + 1)
+	for {
+		if _, ok := s.InterfacesMap[idx]; !ok {
+			break
+		}
+		idx++
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s%d", config.Kind, idx)
+	}
+	s.InterfacesMap[idx] = Interface{
+		Name: name,
+		MTU:  config.MTU,
+	}
+	return idx, nil
+}
+
+// AddBondInterface implements Stack.
+func (s *TestStack) AddBondInterface(name string, config BondConfig) (int32, error) {
+	idx := int32(len(s.InterfacesMap) + 1)
+	for {
+		if _, ok := s.InterfacesMap[idx]; !ok {
+			break
+		}
+		idx++
+	}
+	if name == "" {
+		name = fmt.Sprintf("bond%d", idx)
+	}
+	s.InterfacesMap[idx] = Interface{
+		Name: name,
+		Bond: &BondInfo{
+			Mode:   config.Mode,
+			MIIMon: config.MIIMon,
+		},
+	}
+	return idx, nil
+}
+
+// SetInterfaceMaster implements Stack.
+func (s *TestStack) SetInterfaceMaster(idx, master int32) error {
+	i, ok := s.InterfacesMap[idx]
+	if !ok {
+		return fmt.Errorf("unknown idx: %d", idx)
+	}
+	if master != 0 {
+		if m, ok := s.InterfacesMap[master]; !ok || m.Bond == nil {
+			return fmt.Errorf("unknown bond idx: %d", master)
+		}
+	}
+	i.Master = master
+	s.InterfacesMap[idx] = i
+	return nil
+}
+
 // InterfaceAddrs implements Stack.
 func (s *TestStack) InterfaceAddrs() map[int32][]InterfaceAddr {
 	return s.InterfaceAddrsMap
@@ -177,3 +235,31 @@ func (*TestStack) SetPortRange(start uint16, end uint16) error {
 	// No-op.
 	return nil
 }
+
+// Neighbors implements Stack.
+func (s *TestStack) Neighbors() ([]Neighbor, error) {
+	return s.NeighborList, nil
+}
+
+// AddNeighbor implements Stack.
+func (s *TestStack) AddNeighbor(n Neighbor) error {
+	for i, old := range s.NeighborList {
+		if old.Index == n.Index && bytes.Equal(old.Addr, n.Addr) {
+			s.NeighborList[i] = n
+			return nil
+		}
+	}
+	s.NeighborList = append(s.NeighborList, n)
+	return nil
+}
+
+// RemoveNeighbor implements Stack.
+func (s *TestStack) RemoveNeighbor(n Neighbor) error {
+	for i, old := range s.NeighborList {
+		if old.Index == n.Index && bytes.Equal(old.Addr, n.Addr) {
+			s.NeighborList = append(s.NeighborList[:i], s.NeighborList[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown neighbor %v on idx %d", n.Addr, n.Index)
+}
@@ -44,8 +44,18 @@ type AbstractSocketNamespace struct {
 	// destroyed. It is the responsibility of the socket to remove itself from the
 	// abstract socket namespace when it is destroyed.
 	endpoints map[string]abstractEndpoint
+
+	// nextAutobind is the next candidate name tried by Autobind.
+	//
+	// nextAutobind is protected by mu.
+	nextAutobind uint32
 }
 
+// autobindNames is the number of distinct names that can be assigned by
+// Autobind. This matches net/unix/af_unix.c:unix_autobind, which assigns
+// names of 5 hex digits.
+const autobindNames = 0x100000
+
 // NewAbstractSocketNamespace returns a new AbstractSocketNamespace.
 func NewAbstractSocketNamespace() *AbstractSocketNamespace {
 	return &AbstractSocketNamespace{
@@ -107,6 +117,32 @@ func (a *AbstractSocketNamespace) Bind(ctx context.Context, name string, ep tran
 	return nil
 }
 
+// Autobind binds the given socket to a kernel-assigned name consisting of 5
+// hex digits, as is done by Linux when binding a unix socket to an empty
+// address. The chosen name is returned.
+//
+// If every name is already in use, Autobind returns ENOSPC.
+func (a *AbstractSocketNamespace) Autobind(ctx context.Context, ep transport.BoundEndpoint, socket refsvfs2.TryRefCounter) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := 0; i < autobindNames; i++ {
+		name := fmt.Sprintf("%05x", a.nextAutobind)
+		a.nextAutobind = (a.nextAutobind + 1) % autobindNames
+
+		if ep, ok := a.endpoints[name]; ok {
+			if ep.socket.TryIncRef() {
+				ep.socket.DecRef(ctx)
+				continue
+			}
+		}
+
+		a.endpoints[name] = abstractEndpoint{ep: ep, socket: socket, name: name, ns: a}
+		return name, nil
+	}
+	return "", unix.ENOSPC
+}
+
 // Remove removes the specified socket at name from the abstract socket
 // namespace, if it has not yet been replaced.
 func (a *AbstractSocketNamespace) Remove(name string, socket refsvfs2.TryRefCounter) {
@@ -0,0 +1,46 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"sync/atomic"
+)
+
+// firstNamespaceInode is the first inode number allocated to a namespace.
+// This is the same as Linux's PROC_DYNAMIC_FIRST, from which Linux allocates
+// namespace inode numbers.
+const firstNamespaceInode = 0xF0000000
+
+// lastNamespaceInode is the last allocated namespace inode number.
+// lastNamespaceInode is accessed using atomic memory operations.
+var lastNamespaceInode uint64 = firstNamespaceInode - 1
+
+// NewNamespaceInode returns a new inode number that uniquely identifies a
+// namespace, analogous to Linux's struct ns_common::inum.
+func NewNamespaceInode() uint64 {
+	return atomic.AddUint64(&lastNamespaceInode, 1)
+}
+
+// ObserveNamespaceInode ensures that NewNamespaceInode never returns an inode
+// number that is less than or equal to ino. It is called when restoring
+// namespaces whose inode numbers were allocated before save.
+func ObserveNamespaceInode(ino uint64) {
+	for {
+		last := atomic.LoadUint64(&lastNamespaceInode)
+		if ino <= last || atomic.CompareAndSwapUint64(&lastNamespaceInode, last, ino) {
+			return
+		}
+	}
+}
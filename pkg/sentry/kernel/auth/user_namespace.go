@@ -34,6 +34,9 @@ type UserNamespace struct {
 	// namespace. owner is immutable.
 	owner KUID
 
+	// inode is the namespace's inode number. inode is immutable.
+	inode uint64
+
 	// mu protects the following fields.
 	//
 	// If mu will be locked in multiple UserNamespaces, it must be locked in
@@ -55,7 +58,9 @@ type UserNamespace struct {
 // NewRootUserNamespace returns a UserNamespace that is appropriate for a
 // system's root user namespace.
 func NewRootUserNamespace() *UserNamespace {
-	var ns UserNamespace
+	ns := UserNamespace{
+		inode: NewNamespaceInode(),
+	}
 	// """
 	// The initial user namespace has no parent namespace, but, for
 	// consistency, the kernel provides dummy user and group ID mapping files
@@ -78,6 +83,16 @@ func NewRootUserNamespace() *UserNamespace {
 	return &ns
 }
 
+// Inode returns the inode number of the user namespace.
+func (ns *UserNamespace) Inode() uint64 {
+	return ns.inode
+}
+
+// afterLoad is invoked by stateify.
+func (ns *UserNamespace) afterLoad() {
+	ObserveNamespaceInode(ns.inode)
+}
+
 // Root returns the root of the user namespace tree containing ns.
 func (ns *UserNamespace) Root() *UserNamespace {
 	for ns.parent != nil {
@@ -122,6 +137,7 @@ func (c *Credentials) NewChildUserNamespace() (*UserNamespace, error) {
 	return &UserNamespace{
 		parent: c.UserNamespace,
 		owner:  c.EffectiveKUID,
+		inode:  NewNamespaceInode(),
 		// "When a user namespace is created, it starts without a mapping of
 		// user IDs (group IDs) to the parent user namespace." -
 		// user_namespaces(7)
@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"sort"
 	"sync/atomic"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 )
@@ -91,6 +93,22 @@ type CgroupImpl interface {
 	Controllers() []CgroupController
 	Enter(t *Task)
 	Leave(t *Task)
+
+	// AccountIO charges bytes of I/O to the device dev, performed by a task in
+	// this cgroup, to the cgroup. It is a no-op for cgroups that don't account
+	// I/O.
+	AccountIO(dev uint32, write bool, bytes int64)
+
+	// ChargeCPU charges d of CPU time, used at now by a task in this cgroup,
+	// to the cgroup and its ancestors. It is a no-op for cgroups that don't
+	// limit CPU bandwidth.
+	ChargeCPU(now ktime.Time, d time.Duration)
+
+	// CPUThrottled returns whether tasks in this cgroup may not run at now
+	// because the cgroup, or one of its ancestors, has exhausted its CPU
+	// bandwidth. If so, it also returns the time at which the bandwidth is
+	// next replenished.
+	CPUThrottled(now ktime.Time) (ktime.Time, bool)
 }
 
 // hierarchy represents a cgroupfs filesystem instance, with a unique set of
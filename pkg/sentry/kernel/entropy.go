@@ -0,0 +1,124 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// EntropyPoolBits is the size of the input entropy pool in bits, as reported
+// by /proc/sys/kernel/random/poolsize. This is also the amount of entropy
+// that must be credited to an unseeded pool before it becomes seeded, as for
+// Linux's CRNG_INIT_BITS.
+const EntropyPoolBits = 256
+
+// EntropyPool is the sentry's model of Linux's input entropy pool.
+//
+// Random bytes are always drawn from the host (see pkg/rand), so the pool
+// holds no randomness of its own. It only tracks an entropy estimate, which
+// applications can inspect and credit via /dev/random ioctls and
+// /proc/sys/kernel/random/entropy_avail, and whether the pool is seeded.
+// Reads from /dev/random block while the pool is unseeded.
+//
+// +stateify savable
+type EntropyPool struct {
+	// queue is notified when the pool becomes seeded.
+	queue waiter.Queue
+
+	// mu protects the below.
+	mu sync.Mutex `state:"nosave"`
+
+	// bits is the estimated entropy in the pool, in [0, EntropyPoolBits].
+	bits int32
+
+	// unseeded is true if entropy must be credited before /dev/random may be
+	// read. The zero value (seeded) matches modern Linux after boot.
+	unseeded bool
+}
+
+// init initializes the pool as full and seeded.
+func (p *EntropyPool) init() {
+	p.bits = EntropyPoolBits
+}
+
+// Bits returns the estimated entropy in the pool in bits.
+func (p *EntropyPool) Bits() int32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bits
+}
+
+// Credit adds bits to the entropy estimate, saturating at EntropyPoolBits. If
+// the pool is unseeded and becomes full, it is seeded and blocked readers are
+// woken.
+//
+// Preconditions: bits >= 0.
+func (p *EntropyPool) Credit(bits int32) {
+	p.mu.Lock()
+	if bits > EntropyPoolBits-p.bits {
+		p.bits = EntropyPoolBits
+	} else {
+		p.bits += bits
+	}
+	seeded := p.unseeded && p.bits == EntropyPoolBits
+	if seeded {
+		p.unseeded = false
+	}
+	p.mu.Unlock()
+	if seeded {
+		p.queue.Notify(waiter.ReadableEvents)
+	}
+}
+
+// Zap sets the entropy estimate to zero. As in Linux, this does not unseed the
+// pool.
+func (p *EntropyPool) Zap() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bits = 0
+}
+
+// Seeded returns true if the pool is seeded.
+func (p *EntropyPool) Seeded() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.unseeded
+}
+
+// SetSeeded marks the pool as seeded or unseeded. Marking the pool unseeded
+// also empties it, so that EntropyPoolBits must be credited to seed it again.
+func (p *EntropyPool) SetSeeded(seeded bool) {
+	p.mu.Lock()
+	wasUnseeded := p.unseeded
+	p.unseeded = !seeded
+	if !seeded {
+		p.bits = 0
+	}
+	p.mu.Unlock()
+	if wasUnseeded && seeded {
+		p.queue.Notify(waiter.ReadableEvents)
+	}
+}
+
+// EventRegister registers e to be notified when the pool becomes seeded.
+func (p *EntropyPool) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	p.queue.EventRegister(e, mask)
+}
+
+// EventUnregister unregisters e.
+func (p *EntropyPool) EventUnregister(e *waiter.Entry) {
+	p.queue.EventUnregister(e)
+}
@@ -26,19 +26,19 @@ import (
 	"gvisor.dev/gvisor/pkg/waiter"
 )
 
-// Table to convert waiter event masks into si_band siginfo codes.
-// Taken from fs/fcntl.c:band_table.
-var bandTable = map[waiter.EventMask]int64{
-	// POLL_IN
-	waiter.EventIn: linux.EPOLLIN | linux.EPOLLRDNORM,
-	// POLL_OUT
-	waiter.EventOut: linux.EPOLLOUT | linux.EPOLLWRNORM | linux.EPOLLWRBAND,
-	// POLL_ERR
-	waiter.EventErr: linux.EPOLLERR,
-	// POLL_PRI
-	waiter.EventPri: linux.EPOLLPRI | linux.EPOLLRDBAND,
-	// POLL_HUP
-	waiter.EventHUp: linux.EPOLLHUP | linux.EPOLLERR,
+// pollReasons maps waiter events to the si_code and si_band values sent with
+// an F_SETSIG signal. Each reason is reported in a separate signal, in the
+// order of this table. Taken from fs/fcntl.c:band_table.
+var pollReasons = []struct {
+	mask waiter.EventMask
+	code int32
+	band int64
+}{
+	{waiter.EventIn, linux.POLL_IN, linux.EPOLLIN | linux.EPOLLRDNORM},
+	{waiter.EventOut, linux.POLL_OUT, linux.EPOLLOUT | linux.EPOLLWRNORM | linux.EPOLLWRBAND},
+	{waiter.EventErr, linux.POLL_ERR, linux.EPOLLERR},
+	{waiter.EventPri, linux.POLL_PRI, linux.EPOLLPRI | linux.EPOLLRDBAND},
+	{waiter.EventHUp, linux.POLL_HUP, linux.EPOLLHUP | linux.EPOLLERR},
 }
 
 // New returns a function that creates a new fs.FileAsync with the given file
@@ -93,6 +93,14 @@ type FileAsync struct {
 	recipientPG *kernel.ProcessGroup
 	recipientTG *kernel.ThreadGroup
 	recipientT  *kernel.Task
+
+	// ownerNS and ownerID are the PID namespace of the task that set the
+	// recipient and the recipient's ID in that namespace. Linux holds a
+	// reference on the recipient's struct pid, whose IDs remain valid after
+	// the recipient exits; ownerID is reported once the recipient has been
+	// reaped.
+	ownerNS *kernel.PIDNamespace
+	ownerID int32
 }
 
 // Callback sends a signal.
@@ -102,16 +110,25 @@ func (a *FileAsync) Callback(e *waiter.Entry, mask waiter.EventMask) {
 	if !a.registered {
 		return
 	}
-	t := a.recipientT
-	tg := a.recipientTG
-	if a.recipientPG != nil {
-		tg = a.recipientPG.Originator()
-	}
-	if tg != nil {
-		t = tg.Leader()
+	switch {
+	case a.recipientT != nil:
+		a.sendLocked(a.recipientT, false /* group */, mask)
+	case a.recipientTG != nil:
+		a.sendLocked(a.recipientTG.Leader(), true /* group */, mask)
+	case a.recipientPG != nil:
+		for _, tg := range a.recipientPG.ThreadGroups() {
+			a.sendLocked(tg.Leader(), true /* group */, mask)
+		}
 	}
-	if t == nil {
-		// No recipient has been registered.
+}
+
+// sendLocked sends the signals for the events in mask to t, or to t's thread
+// group if group is true. Signals to recipients that have exited are silently
+// dropped. This is analogous to fs/fcntl.c:send_sigio_to_task.
+//
+// Preconditions: a.mu must be locked.
+func (a *FileAsync) sendLocked(t *kernel.Task, group bool, mask waiter.EventMask) {
+	if t == nil || t.ExitState() == kernel.TaskExitDead {
 		return
 	}
 	c := t.Credentials()
@@ -124,22 +141,38 @@ func (a *FileAsync) Callback(e *waiter.Entry, mask waiter.EventMask) {
 	if !permCheck {
 		return
 	}
-	signalInfo := &linux.SignalInfo{
-		Signo: int32(linux.SIGIO),
-		Code:  linux.SI_KERNEL,
+	send := func(info *linux.SignalInfo) error {
+		if group {
+			return t.SendGroupSignal(info)
+		}
+		return t.SendSignal(info)
 	}
 	if a.signal != 0 {
-		signalInfo.Signo = int32(a.signal)
-		signalInfo.SetFD(uint32(a.fd))
-		var band int64
-		for m, bandCode := range bandTable {
-			if m&mask != 0 {
-				band |= bandCode
+		queued := true
+		for _, r := range pollReasons {
+			if mask&r.mask == 0 {
+				continue
 			}
+			signalInfo := &linux.SignalInfo{
+				Signo: int32(a.signal),
+				Code:  r.code,
+			}
+			signalInfo.SetFD(uint32(a.fd))
+			signalInfo.SetBand(r.band)
+			if err := send(signalInfo); err != nil {
+				queued = false
+			}
+		}
+		if queued {
+			return
 		}
-		signalInfo.SetBand(band)
+		// As in Linux, fall back to a plain SIGIO if a realtime signal could
+		// not be queued.
 	}
-	t.SendSignal(signalInfo)
+	send(&linux.SignalInfo{
+		Signo: int32(linux.SIGIO),
+		Code:  linux.SI_KERNEL,
+	})
 }
 
 // Register sets the file which will be monitored for IO events.
@@ -191,6 +224,35 @@ func (a *FileAsync) Owner() (*kernel.Task, *kernel.ThreadGroup, *kernel.ProcessG
 	return a.recipientT, a.recipientTG, a.recipientPG
 }
 
+// OwnerEx returns the current owner as seen from ns, in the form used by
+// F_GETOWN_EX. The returned value is empty if no one is set to receive
+// signals.
+//
+// As in Linux, the owner's ID is still reported after the owner has exited,
+// until the owner is changed.
+func (a *FileAsync) OwnerEx(ns *kernel.PIDNamespace) linux.FOwnerEx {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var owner linux.FOwnerEx
+	switch {
+	case a.recipientT != nil:
+		owner.Type = linux.F_OWNER_TID
+		owner.PID = int32(ns.IDOfTask(a.recipientT))
+	case a.recipientTG != nil:
+		owner.Type = linux.F_OWNER_PID
+		owner.PID = int32(ns.IDOfThreadGroup(a.recipientTG))
+	case a.recipientPG != nil:
+		owner.Type = linux.F_OWNER_PGRP
+		owner.PID = int32(ns.IDOfProcessGroup(a.recipientPG))
+	default:
+		return linux.FOwnerEx{}
+	}
+	if owner.PID == 0 && ns == a.ownerNS {
+		owner.PID = a.ownerID
+	}
+	return owner
+}
+
 // SetOwnerTask sets the owner (who will receive signals) to a specified task.
 // Only this owner will receive signals.
 func (a *FileAsync) SetOwnerTask(requester *kernel.Task, recipient *kernel.Task) {
@@ -200,6 +262,11 @@ func (a *FileAsync) SetOwnerTask(requester *kernel.Task, recipient *kernel.Task)
 	a.recipientT = recipient
 	a.recipientTG = nil
 	a.recipientPG = nil
+	a.ownerNS = requester.PIDNamespace()
+	a.ownerID = 0
+	if recipient != nil {
+		a.ownerID = int32(a.ownerNS.IDOfTask(recipient))
+	}
 }
 
 // SetOwnerThreadGroup sets the owner (who will receive signals) to a specified
@@ -211,6 +278,11 @@ func (a *FileAsync) SetOwnerThreadGroup(requester *kernel.Task, recipient *kerne
 	a.recipientT = nil
 	a.recipientTG = recipient
 	a.recipientPG = nil
+	a.ownerNS = requester.PIDNamespace()
+	a.ownerID = 0
+	if recipient != nil {
+		a.ownerID = int32(a.ownerNS.IDOfThreadGroup(recipient))
+	}
 }
 
 // SetOwnerProcessGroup sets the owner (who will receive signals) to a
@@ -222,6 +294,11 @@ func (a *FileAsync) SetOwnerProcessGroup(requester *kernel.Task, recipient *kern
 	a.recipientT = nil
 	a.recipientTG = nil
 	a.recipientPG = recipient
+	a.ownerNS = requester.PIDNamespace()
+	a.ownerID = 0
+	if recipient != nil {
+		a.ownerID = int32(a.ownerNS.IDOfProcessGroup(recipient))
+	}
 }
 
 // ClearOwner unsets the current signal recipient.
@@ -232,6 +309,8 @@ func (a *FileAsync) ClearOwner() {
 	a.recipientT = nil
 	a.recipientTG = nil
 	a.recipientPG = nil
+	a.ownerNS = nil
+	a.ownerID = 0
 }
 
 // Signal returns which signal will be sent to the signal recipient.
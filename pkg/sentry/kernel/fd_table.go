@@ -166,6 +166,22 @@ func (f *FDTable) dropVFS2(ctx context.Context, file *vfs.FileDescription) {
 	file.DecRef(ctx)
 }
 
+// dropAllVFS2 drops the table's references on files, which have all been
+// removed from the table at once. Cleanup deferred by the files' release to a
+// vfs.ReleaseBatch, such as closing gofer FIDs, is done in bulk once all
+// files have been dropped.
+func (f *FDTable) dropAllVFS2(ctx context.Context, files []*vfs.FileDescription) {
+	if len(files) == 0 {
+		return
+	}
+	var batch vfs.ReleaseBatch
+	batchCtx := vfs.WithReleaseBatch(ctx, &batch)
+	for _, file := range files {
+		f.dropVFS2(batchCtx, file)
+	}
+	batch.Flush(ctx)
+}
+
 // NewFDTable allocates a new FDTable that may be used by tasks in k.
 func (k *Kernel) NewFDTable() *FDTable {
 	f := &FDTable{k: k}
@@ -635,11 +651,43 @@ func (f *FDTable) Remove(ctx context.Context, fd int32) (*fs.File, *vfs.FileDesc
 
 // RemoveIf removes all FDs where cond is true.
 func (f *FDTable) RemoveIf(ctx context.Context, cond func(*fs.File, *vfs.FileDescription, FDFlags) bool) {
+	files, filesVFS2 := f.removeIf(ctx, cond)
+
+	for _, file := range files {
+		f.drop(ctx, file)
+	}
+
+	f.dropAllVFS2(ctx, filesVFS2)
+}
+
+// RemoveCloseOnExec removes all FDs with the CloseOnExec flag set, flushing
+// each as close(2) would. As in Linux, errors from flushing are ignored.
+func (f *FDTable) RemoveCloseOnExec(ctx context.Context) {
+	files, filesVFS2 := f.removeIf(ctx, func(_ *fs.File, _ *vfs.FileDescription, flags FDFlags) bool {
+		return flags.CloseOnExec
+	})
+
+	for _, file := range files {
+		file.Flush(ctx)
+		f.drop(ctx, file)
+	}
+
+	for _, file := range filesVFS2 {
+		file.OnClose(ctx)
+	}
+	f.dropAllVFS2(ctx, filesVFS2)
+}
+
+// removeIf removes all FDs where cond is true from the table, and returns the
+// removed files. The caller takes ownership of the table's references on the
+// returned files.
+func (f *FDTable) removeIf(ctx context.Context, cond func(*fs.File, *vfs.FileDescription, FDFlags) bool) ([]*fs.File, []*vfs.FileDescription) {
 	// TODO(gvisor.dev/issue/1624): Remove fs.File slice.
 	var files []*fs.File
 	var filesVFS2 []*vfs.FileDescription
 
 	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.forEach(ctx, func(fd int32, file *fs.File, fileVFS2 *vfs.FileDescription, flags FDFlags) {
 		if cond(file, fileVFS2, flags) {
 			df, dfVFS2 := f.setAll(ctx, fd, nil, nil, FDFlags{}) // Clear from table.
@@ -652,13 +700,5 @@ func (f *FDTable) RemoveIf(ctx context.Context, cond func(*fs.File, *vfs.FileDes
 			}
 		}
 	})
-	f.mu.Unlock()
-
-	for _, file := range files {
-		f.drop(ctx, file)
-	}
-
-	for _, file := range filesVFS2 {
-		f.dropVFS2(ctx, file)
-	}
+	return files, filesVFS2
 }
@@ -48,6 +48,12 @@ type FSContext struct {
 	// cwdVFS2 is the current working directory.
 	cwdVFS2 vfs.VirtualDentry
 
+	// beneath, if not nil, is the directory beneath which all path
+	// resolution must remain. Resolutions that would leave it via "..",
+	// absolute paths or symlinks, or mount traversal fail with EXDEV,
+	// regardless of the flags passed to the syscall.
+	beneath *fs.Dirent
+
 	// umask is the current file mode creation mask. When a thread using this
 	// context invokes a syscall that creates a file, bits set in umask are
 	// removed from the permissions that the file is created with.
@@ -105,6 +111,10 @@ func (f *FSContext) DecRef(ctx context.Context) {
 			f.root = nil
 			f.cwd.DecRef(ctx)
 			f.cwd = nil
+			if f.beneath != nil {
+				f.beneath.DecRef(ctx)
+				f.beneath = nil
+			}
 		}
 	})
 }
@@ -128,6 +138,9 @@ func (f *FSContext) Fork() *FSContext {
 		}
 		f.cwd.IncRef()
 		f.root.IncRef()
+		if f.beneath != nil {
+			f.beneath.IncRef()
+		}
 	}
 
 	ctx := &FSContext{
@@ -135,6 +148,7 @@ func (f *FSContext) Fork() *FSContext {
 		root:     f.root,
 		cwdVFS2:  f.cwdVFS2,
 		rootVFS2: f.rootVFS2,
+		beneath:  f.beneath,
 		umask:    f.umask,
 	}
 	ctx.InitRefs()
@@ -280,6 +294,43 @@ func (f *FSContext) SetRootDirectoryVFS2(ctx context.Context, vd vfs.VirtualDent
 	old.DecRef(ctx)
 }
 
+// ResolveBeneath returns the directory beneath which path resolution is
+// confined.
+//
+// This will return nil if resolution is not confined or if called after f is
+// destroyed, otherwise it will return a Dirent with a reference taken.
+func (f *FSContext) ResolveBeneath() *fs.Dirent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.beneath != nil {
+		f.beneath.IncRef()
+	}
+	return f.beneath
+}
+
+// SetResolveBeneath confines all path resolution to the subtree rooted at d,
+// or lifts the confinement if d is nil. This will take an extra reference on
+// the Dirent.
+//
+// This is not a valid call after f is destroyed.
+func (f *FSContext) SetResolveBeneath(ctx context.Context, d *fs.Dirent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.root == nil {
+		panic(fmt.Sprintf("FSContext.SetResolveBeneath(%v)) called after destroy", d))
+	}
+
+	old := f.beneath
+	f.beneath = d
+	if d != nil {
+		d.IncRef()
+	}
+	if old != nil {
+		old.DecRef(ctx)
+	}
+}
+
 // Umask returns the current umask.
 func (f *FSContext) Umask() uint {
 	f.mu.Lock()
@@ -31,6 +31,9 @@ type IPCNamespace struct {
 	// User namespace which owns this IPC namespace. Immutable.
 	userNS *auth.UserNamespace
 
+	// inode is the namespace's inode number. inode is immutable.
+	inode uint64
+
 	queues     *msgqueue.Registry
 	semaphores *semaphore.Registry
 	shms       *shm.Registry
@@ -40,6 +43,7 @@ type IPCNamespace struct {
 func NewIPCNamespace(userNS *auth.UserNamespace) *IPCNamespace {
 	ns := &IPCNamespace{
 		userNS:     userNS,
+		inode:      auth.NewNamespaceInode(),
 		queues:     msgqueue.NewRegistry(userNS),
 		semaphores: semaphore.NewRegistry(userNS),
 		shms:       shm.NewRegistry(userNS),
@@ -63,6 +67,16 @@ func (i *IPCNamespace) ShmRegistry() *shm.Registry {
 	return i.shms
 }
 
+// Inode returns the inode number of this IPC namespace.
+func (i *IPCNamespace) Inode() uint64 {
+	return i.inode
+}
+
+// afterLoad is invoked by stateify.
+func (i *IPCNamespace) afterLoad() {
+	auth.ObserveNamespaceInode(i.inode)
+}
+
 // DecRef implements refsvfs2.RefCounter.DecRef.
 func (i *IPCNamespace) DecRef(ctx context.Context) {
 	i.IPCNamespaceRefs.DecRef(func() {
@@ -34,6 +34,7 @@ package kernel
 import (
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"sync/atomic"
 	"time"
@@ -60,6 +61,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/epoll"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/loader"
@@ -128,6 +130,9 @@ type Kernel struct {
 	rootUTSNamespace            *UTSNamespace
 	rootIPCNamespace            *IPCNamespace
 	rootAbstractSocketNamespace *AbstractSocketNamespace
+	maxPathLen                  int
+	fanotifyPermTimeout         time.Duration
+	fanotifyPermDeny            bool
 
 	// futexes is the "root" futex.Manager, from which all others are forked.
 	// This is necessary to ensure that shared futexes are coherent across all
@@ -146,6 +151,9 @@ type Kernel struct {
 	// syslog is the kernel log.
 	syslog syslog
 
+	// entropyPool backs /dev/random.
+	entropyPool EntropyPool
+
 	// runningTasksMu synchronizes disable/enable of cpuClockTicker when
 	// the kernel is idle (runningTasks == 0).
 	//
@@ -193,6 +201,23 @@ type Kernel struct {
 	// cpuClockTickerSetting is protected by runningTasksMu.
 	cpuClockTickerSetting ktime.Setting
 
+	// cpuBandwidthLimits is the number of cgroups whose CPU bandwidth is
+	// limited. While it is zero, CPU time isn't charged to cgroups.
+	//
+	// cpuBandwidthLimits is accessed using atomic memory operations.
+	cpuBandwidthLimits int32
+
+	// oomMemoryLimit is the memory usage limit enforced by the OOM handler,
+	// or 0 if there is none. It is set again when the OOM handler is
+	// started after restore.
+	//
+	// oomMemoryLimit is accessed using atomic memory operations.
+	oomMemoryLimit uint64 `state:"nosave"`
+
+	// hostCPUStat, if not nil, is the host cgroup cpu.stat file for the
+	// sandbox, which reports throttling imposed by the host.
+	hostCPUStat io.ReaderAt `state:"nosave"`
+
 	// uniqueID is used to generate unique identifiers.
 	//
 	// uniqueID is mutable, and is accessed using atomic memory operations.
@@ -293,6 +318,13 @@ type Kernel struct {
 	// system. It is controller by cgroupfs. Nil if cgroupfs is unavailable on
 	// the system.
 	cgroupRegistry *CgroupRegistry
+
+	// syscallPolicies maps container IDs to the sentry syscall policies
+	// enforced on tasks in those containers. Containers without a policy
+	// have no entry.
+	//
+	// syscallPolicies is protected by the TaskSet mutex.
+	syscallPolicies map[string]*syspolicy.Policy
 }
 
 // InitKernelArgs holds arguments to Init.
@@ -340,6 +372,21 @@ type InitKernelArgs struct {
 
 	// PIDNamespace is the root PID namespace.
 	PIDNamespace *PIDNamespace
+
+	// MaxPathLen is the maximum length in bytes, including the terminating
+	// NUL, of paths copied in from application memory. Longer paths fail with
+	// ENAMETOOLONG. If MaxPathLen is 0, linux.PATH_MAX is used.
+	MaxPathLen int
+
+	// FanotifyPermTimeout is how long an operation waits for a fanotify
+	// listener to answer a permission event. If FanotifyPermTimeout is 0,
+	// operations wait indefinitely.
+	FanotifyPermTimeout time.Duration
+
+	// If FanotifyPermDeny is true, permission events that time out, or whose
+	// listener closes its fanotify file descriptor without answering, are
+	// denied. Otherwise they are allowed, as in Linux.
+	FanotifyPermDeny bool
 }
 
 // Init initialize the Kernel with no tasks.
@@ -362,6 +409,9 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	if args.ApplicationCores == 0 {
 		return fmt.Errorf("args.ApplicationCores is 0")
 	}
+	if args.MaxPathLen < 0 || args.MaxPathLen > linux.PATH_MAX {
+		return fmt.Errorf("args.MaxPathLen %d is not between 0 and %d", args.MaxPathLen, linux.PATH_MAX)
+	}
 
 	k.featureSet = args.FeatureSet
 	k.timekeeper = args.Timekeeper
@@ -375,6 +425,9 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 		k.rootNetworkNamespace = inet.NewRootNamespace(nil, nil)
 	}
 	k.applicationCores = args.ApplicationCores
+	k.maxPathLen = args.MaxPathLen
+	k.fanotifyPermTimeout = args.FanotifyPermTimeout
+	k.fanotifyPermDeny = args.FanotifyPermDeny
 	if args.UseHostCores {
 		k.useHostCores = true
 		maxCPU, err := hostcpu.MaxPossibleCPU()
@@ -393,6 +446,7 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	k.netlinkPorts = port.New()
 	k.ptraceExceptions = make(map[*Task]*Task)
 	k.YAMAPtraceScope = linux.YAMA_SCOPE_RELATIONAL
+	k.entropyPool.init()
 
 	if VFS2Enabled {
 		ctx := k.SupervisorContext()
@@ -455,6 +509,15 @@ func (k *Kernel) SaveTo(ctx context.Context, w wire.Writer) error {
 	k.pauseTimeLocked(ctx)
 	defer k.resumeTimeLocked(ctx)
 
+	// Stop the MemoryFile's compressor, which may otherwise call into
+	// MemoryManagers while they are being saved, and decompress all memory,
+	// since compressed memory isn't saved.
+	err := k.mf.SuspendCompression()
+	defer k.mf.ResumeCompression()
+	if err != nil {
+		return fmt.Errorf("failed to decompress memory: %v", err)
+	}
+
 	// Evict all evictable MemoryFile allocations.
 	k.mf.StartEvictions()
 	k.mf.WaitForEvictions()
@@ -796,6 +859,11 @@ type CreateProcessArgs struct {
 	// Umask is the initial umask.
 	Umask uint
 
+	// ResolveBeneath optionally names a directory beneath which all path
+	// resolution by the process is confined. See FSContext.SetResolveBeneath.
+	// It is only supported with VFS1.
+	ResolveBeneath string
+
 	// Limits is the initial resource limits.
 	Limits *limits.LimitSet
 
@@ -995,8 +1063,23 @@ func (k *Kernel) CreateProcess(args CreateProcessArgs) (*ThreadGroup, ThreadID,
 			}
 			defer wd.DecRef(ctx)
 		}
+		var beneath *fs.Dirent
+		if args.ResolveBeneath != "" {
+			var err error
+			beneath, err = mntns.FindInode(ctx, root, nil, args.ResolveBeneath, &remainingTraversals)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to find resolve-beneath directory %q: %v", args.ResolveBeneath, err)
+			}
+			defer beneath.DecRef(ctx)
+			if !fs.IsDir(beneath.Inode.StableAttr) {
+				return nil, 0, fmt.Errorf("resolve-beneath path %q is not a directory", args.ResolveBeneath)
+			}
+		}
 		opener = fsbridge.NewFSLookup(mntns, root, wd)
 		fsContext = newFSContext(root, wd, args.Umask)
+		if beneath != nil {
+			fsContext.SetResolveBeneath(ctx, beneath)
+		}
 	}
 
 	tg := k.NewThreadGroup(mntns, args.PIDNamespace, NewSignalHandlers(), linux.SIGCHLD, args.Limits)
@@ -1463,6 +1546,15 @@ func (k *Kernel) ApplicationCores() uint {
 	return k.applicationCores
 }
 
+// MaxPathLen returns the maximum length in bytes, including the terminating
+// NUL, of paths copied in from application memory.
+func (k *Kernel) MaxPathLen() int {
+	if k.maxPathLen == 0 {
+		return linux.PATH_MAX
+	}
+	return k.maxPathLen
+}
+
 // RealtimeClock returns the application CLOCK_REALTIME clock.
 func (k *Kernel) RealtimeClock() ktime.Clock {
 	return k.timekeeper.realtimeClock
@@ -1473,6 +1565,25 @@ func (k *Kernel) MonotonicClock() ktime.Clock {
 	return k.timekeeper.monotonicClock
 }
 
+// AddCPUBandwidthLimits adjusts the number of cgroups whose CPU bandwidth is
+// limited by delta.
+func (k *Kernel) AddCPUBandwidthLimits(delta int32) {
+	atomic.AddInt32(&k.cpuBandwidthLimits, delta)
+}
+
+// SetHostCPUStat sets the host cgroup cpu.stat file for the sandbox.
+//
+// Preconditions: The kernel must not have started running tasks.
+func (k *Kernel) SetHostCPUStat(r io.ReaderAt) {
+	k.hostCPUStat = r
+}
+
+// HostCPUStat returns the host cgroup cpu.stat file for the sandbox, or nil if
+// it is unavailable.
+func (k *Kernel) HostCPUStat() io.ReaderAt {
+	return k.hostCPUStat
+}
+
 // CPUClockNow returns the current value of k.cpuClock.
 func (k *Kernel) CPUClockNow() uint64 {
 	return atomic.LoadUint64(&k.cpuClock)
@@ -1483,6 +1594,18 @@ func (k *Kernel) Syslog() *syslog {
 	return &k.syslog
 }
 
+// FanotifyPermPolicy returns how long operations wait for fanotify listeners
+// to answer permission events (0 meaning indefinitely), and whether
+// unanswered permission events are denied.
+func (k *Kernel) FanotifyPermPolicy() (timeout time.Duration, deny bool) {
+	return k.fanotifyPermTimeout, k.fanotifyPermDeny
+}
+
+// EntropyPool returns the entropy pool backing /dev/random.
+func (k *Kernel) EntropyPool() *EntropyPool {
+	return &k.entropyPool
+}
+
 // GenerateInotifyCookie generates a unique inotify event cookie.
 //
 // Returned values may overlap with previously returned values if the value
@@ -1831,11 +1954,11 @@ func (k *Kernel) PopulateNewCgroupHierarchy(root Cgroup) {
 		if t.exitState != TaskExitNone {
 			return
 		}
-		t.mu.Lock()
+		t.cgroupsMu.Lock()
 		// A task can be in the cgroup if it has been created after the
 		// cgroup hierarchy was registered.
 		t.enterCgroupIfNotYetLocked(root)
-		t.mu.Unlock()
+		t.cgroupsMu.Unlock()
 	})
 	k.tasks.mu.RUnlock()
 }
@@ -1849,13 +1972,13 @@ func (k *Kernel) ReleaseCgroupHierarchy(hid uint32) {
 		if t.exitState != TaskExitNone {
 			return
 		}
-		t.mu.Lock()
+		t.cgroupsMu.Lock()
 		for cg := range t.cgroups {
 			if cg.HierarchyID() == hid {
 				t.leaveCgroupLocked(cg)
 			}
 		}
-		t.mu.Unlock()
+		t.cgroupsMu.Unlock()
 	})
 	k.tasks.mu.RUnlock()
 }
@@ -0,0 +1,231 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+// oomScoreMaxAge bounds how stale the OOM score reported by
+// /proc/[pid]/oom_score may be. Caching it keeps reading oom_score for every
+// process in a large sandbox cheap.
+const oomScoreMaxAge = time.Second
+
+// OOMAdj returns the task's thread group's OOM adjustment, as reported by the
+// deprecated /proc/[pid]/oom_adj.
+func (t *Task) OOMAdj() int32 {
+	adj := t.OOMScoreAdj()
+	if adj == linux.OOM_SCORE_ADJ_MAX {
+		return linux.OOM_ADJUST_MAX
+	}
+	return adj * -linux.OOM_DISABLE / linux.OOM_SCORE_ADJ_MAX
+}
+
+// SetOOMAdj sets the task's thread group's OOM score adjustment from a value
+// written to the deprecated /proc/[pid]/oom_adj. The value should be
+// OOM_DISABLE or between OOM_ADJUST_MIN and OOM_ADJUST_MAX inclusive.
+func (t *Task) SetOOMAdj(adj int32) error {
+	if adj != linux.OOM_DISABLE && (adj < linux.OOM_ADJUST_MIN || adj > linux.OOM_ADJUST_MAX) {
+		return linuxerr.EINVAL
+	}
+	if adj == linux.OOM_ADJUST_MAX {
+		return t.SetOOMScoreAdj(linux.OOM_SCORE_ADJ_MAX)
+	}
+	if adj == linux.OOM_DISABLE {
+		return t.SetOOMScoreAdj(linux.OOM_SCORE_ADJ_MIN)
+	}
+	return t.SetOOMScoreAdj(adj * linux.OOM_SCORE_ADJ_MAX / -linux.OOM_DISABLE)
+}
+
+// OOMScore returns the task's thread group's OOM score as reported by
+// /proc/[pid]/oom_score, which scales its badness into the range [0, 2000].
+// The score is relative to the same amount of memory as the OOM handler's
+// victim selection, and may be up to oomScoreMaxAge old.
+func (t *Task) OOMScore() int64 {
+	tg := t.tg
+	now := t.k.MonotonicClock().Now()
+	tg.oomScoreMu.Lock()
+	if !tg.oomScoreTime.IsZero() && now.Sub(tg.oomScoreTime) < oomScoreMaxAge {
+		score := tg.oomScore
+		tg.oomScoreMu.Unlock()
+		return score
+	}
+	tg.oomScoreMu.Unlock()
+
+	// Don't hold oomScoreMu while computing the score, which requires
+	// locking TaskSet.mu.
+	total := t.k.OOMTotalMemory()
+	points, _, ok := tg.oomBadness(total)
+	score := oomScore(points, total, ok)
+	tg.cacheOOMScore(score, now)
+	return score
+}
+
+// oomScore scales the OOM badness points of a thread group into the range of
+// /proc/[pid]/oom_score. ok is the validity of points, as returned by
+// ThreadGroup.oomBadness.
+func oomScore(points int64, total uint64, ok bool) int64 {
+	if !ok || total == 0 {
+		return 0
+	}
+	return (1000 + points*1000/int64(total)) * 2 / 3
+}
+
+// cacheOOMScore records score as tg's OOM score at now.
+func (tg *ThreadGroup) cacheOOMScore(score int64, now ktime.Time) {
+	tg.oomScoreMu.Lock()
+	defer tg.oomScoreMu.Unlock()
+	tg.oomScore = score
+	tg.oomScoreTime = now
+}
+
+// invalidateOOMScore forces tg's OOM score to be recomputed when it is next
+// read.
+func (tg *ThreadGroup) invalidateOOMScore() {
+	tg.oomScoreMu.Lock()
+	defer tg.oomScoreMu.Unlock()
+	tg.oomScoreTime = ktime.Time{}
+}
+
+// SetOOMMemoryLimit sets the memory usage limit enforced by the OOM handler,
+// which OOM badness is relative to. If limit is 0, badness is relative to the
+// total memory available to the sandbox.
+func (k *Kernel) SetOOMMemoryLimit(limit uint64) {
+	atomic.StoreUint64(&k.oomMemoryLimit, limit)
+}
+
+// OOMTotalMemory returns the number of bytes of memory that OOM badness is
+// relative to.
+func (k *Kernel) OOMTotalMemory() uint64 {
+	if limit := atomic.LoadUint64(&k.oomMemoryLimit); limit != 0 {
+		return limit
+	}
+	_, totalUsage := usage.MemoryAccounting.Copy()
+	return usage.TotalMemory(k.mf.TotalSize(), totalUsage)
+}
+
+// oomBadness returns tg's badness in bytes, which is the amount of memory
+// resident in its address space biased by its OOM score adjustment, and the
+// resident memory itself. This is similar to Linux's oom_badness(), except
+// that the memory in a gVisor address space includes both anonymous and
+// mapped tmpfs pages and can't be split further. ok is false if tg can't be
+// chosen by the OOM killer: it is exiting, has no address space, or its OOM
+// score adjustment is OOM_SCORE_ADJ_MIN.
+func (tg *ThreadGroup) oomBadness(total uint64) (points int64, rss uint64, ok bool) {
+	adj := int64(atomic.LoadInt32(&tg.oomScoreAdj))
+	if adj == linux.OOM_SCORE_ADJ_MIN {
+		return 0, 0, false
+	}
+	m := tg.oomMemoryManager()
+	if m == nil {
+		return 0, 0, false
+	}
+	rss = m.ResidentSetSize()
+	return int64(rss) + adj*int64(total/linux.OOM_SCORE_ADJ_MAX), rss, true
+}
+
+// oomMemoryManager returns the address space of a live task in tg, or nil if
+// tg is exiting.
+func (tg *ThreadGroup) oomMemoryManager() *mm.MemoryManager {
+	tg.pidns.owner.mu.RLock()
+	defer tg.pidns.owner.mu.RUnlock()
+	if tg.tasks.Front() == nil {
+		return nil
+	}
+	tg.signalHandlers.mu.Lock()
+	exiting := tg.exiting
+	tg.signalHandlers.mu.Unlock()
+	if exiting {
+		return nil
+	}
+	for t := tg.tasks.Front(); t != nil; t = t.Next() {
+		t.mu.Lock()
+		m := t.MemoryManager()
+		t.mu.Unlock()
+		if m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// OOMVictim describes a thread group chosen by Kernel.SelectOOMVictim.
+type OOMVictim struct {
+	// ThreadGroup is the chosen thread group.
+	ThreadGroup *ThreadGroup
+
+	// Leader is the thread group's leader at the time it was chosen.
+	Leader *Task
+
+	// RSS is the number of bytes of memory resident in the thread group's
+	// address space.
+	RSS uint64
+
+	// OOMScoreAdj is the thread group's OOM score adjustment.
+	OOMScoreAdj int32
+}
+
+// SelectOOMVictim returns the thread group with the highest OOM badness, or
+// nil if no thread group can be killed. total is the number of bytes of memory
+// available to the sandbox. As in Linux, the init process of the root PID
+// namespace is never chosen.
+//
+// The OOM scores of all thread groups are refreshed from the badness computed
+// here, so that /proc/[pid]/oom_score agrees with the choice of victim.
+func (k *Kernel) SelectOOMVictim(total uint64) *OOMVictim {
+	var (
+		victim *OOMVictim
+		best   int64
+	)
+	now := k.MonotonicClock().Now()
+	root := k.tasks.Root
+	for _, tg := range root.ThreadGroups() {
+		points, rss, ok := tg.oomBadness(total)
+		tg.cacheOOMScore(oomScore(points, total, ok), now)
+		if root.IDOfThreadGroup(tg) == InitTID {
+			continue
+		}
+		if !ok || (victim != nil && points <= best) {
+			continue
+		}
+		leader := tg.Leader()
+		if leader == nil {
+			continue
+		}
+		best = points
+		victim = &OOMVictim{
+			ThreadGroup: tg,
+			Leader:      leader,
+			RSS:         rss,
+			OOMScoreAdj: atomic.LoadInt32(&tg.oomScoreAdj),
+		}
+	}
+	return victim
+}
+
+// OOMKill sends SIGKILL to the victim's thread group.
+func (k *Kernel) OOMKill(victim *OOMVictim) error {
+	return k.SendExternalSignalThreadGroup(victim.ThreadGroup, &linux.SignalInfo{
+		Signo: int32(linux.SIGKILL),
+		Code:  linux.SI_KERNEL,
+	})
+}
@@ -0,0 +1,292 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oom implements the sentry's OOM handler. When memory usage inside
+// the sandbox exceeds a limit, or an allocation from the memory file fails, the
+// handler either kills the thread group with the highest OOM badness or fails
+// further allocations with ENOMEM. Either way, it keeps the host from killing
+// the whole sandbox when only one of its processes misbehaves.
+package oom
+
+import (
+	"fmt"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/eventchannel"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	pb "gvisor.dev/gvisor/pkg/sentry/kernel/oom/oom_events_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+var (
+	oomKills        = metric.MustCreateNewUint64Metric("/oom/kills", false /* sync */, "Number of thread groups killed by the OOM handler.")
+	oomDeniedEvents = metric.MustCreateNewUint64Metric("/oom/allocations_denied", false /* sync */, "Number of times the OOM handler started failing allocations.")
+)
+
+// checkPeriod is how often the handler compares memory usage to the limit.
+const checkPeriod = 100 * time.Millisecond
+
+// Policy is the action taken by the OOM handler.
+type Policy int
+
+const (
+	// PolicyNone disables the OOM handler, leaving OOM handling to the host.
+	PolicyNone Policy = iota
+
+	// PolicyKill kills the thread group with the highest OOM badness.
+	PolicyKill
+
+	// PolicyFail fails allocations by application tasks with ENOMEM while
+	// memory usage exceeds the limit.
+	PolicyFail
+)
+
+// Set implements flag.Value.
+func (p *Policy) Set(v string) error {
+	switch v {
+	case "none":
+		*p = PolicyNone
+	case "kill":
+		*p = PolicyKill
+	case "fail":
+		*p = PolicyFail
+	default:
+		return fmt.Errorf("invalid OOM policy %q", v)
+	}
+	return nil
+}
+
+// Get implements flag.Value.
+func (p *Policy) Get() interface{} {
+	return *p
+}
+
+// String returns Policy's string representation.
+func (p Policy) String() string {
+	switch p {
+	case PolicyNone:
+		return "none"
+	case PolicyKill:
+		return "kill"
+	case PolicyFail:
+		return "fail"
+	default:
+		panic(fmt.Sprintf("Invalid OOM policy: %d", p))
+	}
+}
+
+// Handler is the sentry's OOM handler.
+type Handler struct {
+	k *kernel.Kernel
+
+	// limit is the memory usage in bytes above which the handler acts. If
+	// limit is 0, the handler only acts when allocations fail.
+	limit uint64
+
+	policy Policy
+
+	// allocFailed is signalled when an allocation from the memory file
+	// fails.
+	allocFailed chan struct{}
+
+	// Writing to this channel indicates the handler goroutine should stop.
+	stop chan struct{}
+
+	// done is used to signal when the handler goroutine has exited.
+	done sync.WaitGroup
+
+	// The following fields are only accessed by the handler goroutine.
+
+	// denied is true if the handler has denied allocations.
+	denied bool
+
+	// victimExited is closed once the last thread group killed by the
+	// handler has exited, or nil if there is no such thread group. No other
+	// thread group is killed until then, since its memory is still in use.
+	victimExited chan struct{}
+}
+
+// New creates a new Handler. policy must not be PolicyNone.
+func New(k *kernel.Kernel, limit uint64, policy Policy) *Handler {
+	return &Handler{
+		k:           k,
+		limit:       limit,
+		policy:      policy,
+		allocFailed: make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start starts the handler goroutine. Start must not be called concurrently
+// with Stop and may only be called once.
+func (h *Handler) Start() {
+	h.k.SetOOMMemoryLimit(h.limit)
+	h.k.MemoryFile().SetOOMHandler(h.notifyAllocationFailure)
+	h.done.Add(1)
+	go h.run() // S/R-SAFE: doesn't interact with saved state.
+}
+
+// Stop stops the handler goroutine. Stop must not be called concurrently
+// with Start and may only be called once.
+func (h *Handler) Stop() {
+	h.k.MemoryFile().SetOOMHandler(nil)
+	close(h.stop)
+	h.done.Wait()
+	if h.denied {
+		h.k.MemoryFile().SetAllocationsDenied(false)
+	}
+	h.k.SetOOMMemoryLimit(0)
+}
+
+// notifyAllocationFailure is called by the memory file when an allocation
+// fails. It must not block.
+func (h *Handler) notifyAllocationFailure() {
+	select {
+	case h.allocFailed <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Handler) run() {
+	defer h.done.Done()
+
+	ticker := time.NewTicker(checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.check()
+		case <-h.allocFailed:
+			h.handle(pb.OOMEvent_REASON_ALLOCATION_FAILURE)
+		}
+	}
+}
+
+// check acts if memory usage exceeds the limit, and lifts the denial of
+// allocations once it no longer does.
+func (h *Handler) check() {
+	if h.limit == 0 {
+		return
+	}
+	total, err := h.totalUsage()
+	if err != nil {
+		log.Warningf("Failed to fetch memory usage for OOM handler: %v", err)
+		return
+	}
+	if total <= h.limit {
+		if h.denied {
+			log.Infof("Memory usage %d is below limit %d, allowing allocations", total, h.limit)
+			h.k.MemoryFile().SetAllocationsDenied(false)
+			h.denied = false
+		}
+		return
+	}
+	h.handle(pb.OOMEvent_REASON_LIMIT)
+}
+
+// handle relieves memory pressure according to the handler's policy.
+func (h *Handler) handle(reason pb.OOMEvent_Reason) {
+	switch h.policy {
+	case PolicyFail:
+		// Allocations that fail already return ENOMEM to the allocating
+		// task, so only a usage limit needs enforcing.
+		if reason != pb.OOMEvent_REASON_LIMIT || h.denied {
+			return
+		}
+		h.k.MemoryFile().SetAllocationsDenied(true)
+		h.denied = true
+		oomDeniedEvents.Increment()
+		log.Warningf("Memory usage exceeds limit %d, failing allocations", h.limit)
+		h.emit(reason, pb.OOMEvent_ACTION_FAIL_ALLOCATIONS, nil)
+
+	default:
+		if h.victimExited != nil {
+			select {
+			case <-h.victimExited:
+				h.victimExited = nil
+			default:
+				// Wait for the last victim to release its memory.
+				return
+			}
+		}
+		victim := h.k.SelectOOMVictim(h.k.OOMTotalMemory())
+		if victim == nil {
+			log.Warningf("OOM handler found no process to kill")
+			return
+		}
+		if err := h.k.OOMKill(victim); err != nil {
+			log.Warningf("OOM handler failed to kill PID %d: %v", h.k.RootPIDNamespace().IDOfThreadGroup(victim.ThreadGroup), err)
+			return
+		}
+		oomKills.Increment()
+		exited := make(chan struct{})
+		go func() { // S/R-SAFE: only waits for a thread group to exit.
+			victim.ThreadGroup.WaitExited()
+			close(exited)
+		}()
+		h.victimExited = exited
+		h.emit(reason, pb.OOMEvent_ACTION_KILL, victim)
+	}
+}
+
+// totalUsage returns the sandbox's memory usage in bytes, calculated using the
+// 'fast' method.
+func (h *Handler) totalUsage() (uint64, error) {
+	totalPlatform, err := h.k.MemoryFile().TotalUsage()
+	if err != nil {
+		return 0, err
+	}
+	snapshot, _ := usage.MemoryAccounting.Copy()
+	return totalPlatform + snapshot.Mapped, nil
+}
+
+func (h *Handler) emit(reason pb.OOMEvent_Reason, action pb.OOMEvent_Action, victim *kernel.OOMVictim) {
+	mf := h.k.MemoryFile()
+	if err := mf.UpdateUsage(); err != nil {
+		log.Warningf("Failed to update memory usage for OOM event: %v", err)
+	}
+	snapshot, total := usage.MemoryAccounting.Copy()
+	ev := &pb.OOMEvent{
+		Reason: reason,
+		Action: action,
+		Limit:  h.limit,
+		Usage: &pb.OOMEvent_Usage{
+			Total:     total,
+			System:    snapshot.System,
+			Anonymous: snapshot.Anonymous,
+			PageCache: snapshot.PageCache,
+			Tmpfs:     snapshot.Tmpfs,
+			Mapped:    snapshot.Mapped,
+			Ramdiskfs: snapshot.Ramdiskfs,
+		},
+	}
+	if victim != nil {
+		ev.Victim = &pb.OOMEvent_Victim{
+			Pid:         int32(h.k.RootPIDNamespace().IDOfThreadGroup(victim.ThreadGroup)),
+			Comm:        victim.Leader.Name(),
+			ContainerId: victim.Leader.ContainerID(),
+			Rss:         victim.RSS,
+			OomScoreAdj: victim.OOMScoreAdj,
+		}
+		log.Warningf("OOM handler killed PID %d (%s) in container %q: rss %d bytes, oom_score_adj %d, sandbox usage %d bytes, limit %d bytes",
+			ev.Victim.Pid, ev.Victim.Comm, ev.Victim.ContainerId, ev.Victim.Rss, ev.Victim.OomScoreAdj, total, h.limit)
+	}
+	eventchannel.Emit(ev)
+}
@@ -27,6 +27,7 @@ import (
 	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserror"
 	"gvisor.dev/gvisor/pkg/usermem"
 	"gvisor.dev/gvisor/pkg/waiter"
 )
@@ -49,9 +50,25 @@ func (p *Pipe) Read(ctx context.Context, dst usermem.IOSequence) (int64, error)
 	if n > 0 {
 		p.Notify(waiter.WritableEvents)
 	}
+	if err == syserror.ErrWouldBlock {
+		setWaitReason(ctx, "pipe_read")
+	}
 	return n, err
 }
 
+// waitReasonSetter is implemented by kernel.Task, which this package cannot
+// import.
+type waitReasonSetter interface {
+	SetWaitReason(reason string)
+}
+
+// setWaitReason records reason for /proc/[pid]/stack if ctx is a task.
+func setWaitReason(ctx context.Context, reason string) {
+	if s, ok := ctx.(waitReasonSetter); ok {
+		s.SetWaitReason(reason)
+	}
+}
+
 // ReadToBlocks implements safemem.Reader.ReadToBlocks for Pipe.Read.
 func (p *Pipe) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
 	n, err := p.read(int64(dsts.NumBytes()), func(srcs safemem.BlockSeq) (uint64, error) {
@@ -87,6 +104,9 @@ func (p *Pipe) Write(ctx context.Context, src usermem.IOSequence) (int64, error)
 	if n > 0 {
 		p.Notify(waiter.ReadableEvents)
 	}
+	if err == syserror.ErrWouldBlock {
+		setWaitReason(ctx, "pipe_write")
+	}
 	if linuxerr.Equals(linuxerr.EPIPE, err) {
 		// If we are returning EPIPE send SIGPIPE to the task.
 		if sendSig := linux.SignalNoInfoFuncFromContext(ctx); sendSig != nil {
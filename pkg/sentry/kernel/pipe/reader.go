@@ -41,3 +41,10 @@ func (r *Reader) Release(context.Context) {
 func (r *Reader) Readiness(mask waiter.EventMask) waiter.EventMask {
 	return r.Pipe.rReadiness() & mask
 }
+
+// EventRegister implements waiter.Waitable.EventRegister.
+//
+// Only events that the read end can report are registered.
+func (r *Reader) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	r.Pipe.EventRegister(e, mask&^waiter.WritableEvents)
+}
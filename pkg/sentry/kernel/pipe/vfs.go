@@ -229,6 +229,16 @@ func (fd *VFSPipeFD) Allocate(ctx context.Context, mode, offset, length uint64)
 
 // EventRegister implements waiter.Waitable.EventRegister.
 func (fd *VFSPipeFD) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	// Both ends share the pipe's queue; only wake waiters for events that
+	// this end can report, so that e.g. O_ASYNC on the read end is not
+	// signalled with POLL_OUT when the reader drains the pipe.
+	switch {
+	case fd.vfsfd.IsReadable() && fd.vfsfd.IsWritable():
+	case fd.vfsfd.IsReadable():
+		mask &^= waiter.WritableEvents
+	case fd.vfsfd.IsWritable():
+		mask &^= waiter.ReadableEvents
+	}
 	fd.pipe.EventRegister(e, mask)
 }
 
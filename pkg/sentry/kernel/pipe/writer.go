@@ -41,3 +41,10 @@ func (w *Writer) Release(context.Context) {
 func (w *Writer) Readiness(mask waiter.EventMask) waiter.EventMask {
 	return w.Pipe.wReadiness() & mask
 }
+
+// EventRegister implements waiter.Waitable.EventRegister.
+//
+// Only events that the write end can report are registered.
+func (w *Writer) EventRegister(e *waiter.Entry, mask waiter.EventMask) {
+	w.Pipe.EventRegister(e, mask&^waiter.ReadableEvents)
+}
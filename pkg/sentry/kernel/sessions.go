@@ -252,6 +252,21 @@ func (pg *ProcessGroup) SendSignal(info *linux.SignalInfo) error {
 	return lastErr
 }
 
+// ThreadGroups returns the thread groups that are currently members of pg.
+func (pg *ProcessGroup) ThreadGroups() []*ThreadGroup {
+	tasks := pg.originator.TaskSet()
+	tasks.mu.RLock()
+	defer tasks.mu.RUnlock()
+
+	var tgs []*ThreadGroup
+	for tg := range tasks.Root.tgids {
+		if tg.processGroup == pg {
+			tgs = append(tgs, tg)
+		}
+	}
+	return tgs
+}
+
 // CreateSession creates a new Session, with the ThreadGroup as the leader.
 //
 // EPERM may be returned if either the given ThreadGroup is already a Session
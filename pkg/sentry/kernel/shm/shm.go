@@ -435,7 +435,7 @@ func (s *Shm) DecRef(ctx context.Context) {
 
 // Msync implements memmap.MappingIdentity.Msync. Msync is a no-op for shm
 // segments.
-func (s *Shm) Msync(context.Context, memmap.MappableRange) error {
+func (s *Shm) Msync(context.Context, memmap.MappableRange, memmap.MSyncOpts) error {
 	return nil
 }
 
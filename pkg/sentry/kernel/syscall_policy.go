@@ -0,0 +1,100 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/security_events_go_proto"
+)
+
+// SetSyscallPolicy sets the sentry syscall policy enforced on tasks in the
+// given container. It only affects tasks created afterward, so it must be
+// called before the container's init process is created. A nil policy removes
+// the container's policy.
+func (k *Kernel) SetSyscallPolicy(cid string, p *syspolicy.Policy) {
+	k.tasks.mu.Lock()
+	defer k.tasks.mu.Unlock()
+	if p == nil {
+		delete(k.syscallPolicies, cid)
+		return
+	}
+	if k.syscallPolicies == nil {
+		k.syscallPolicies = make(map[string]*syspolicy.Policy)
+	}
+	k.syscallPolicies[cid] = p
+}
+
+// SyscallPolicyStats returns the counters of the given container's syscall
+// policy. ok is false if the container has no policy.
+func (k *Kernel) SyscallPolicyStats(cid string) (stats []syspolicy.RuleStats, ok bool) {
+	k.tasks.mu.RLock()
+	p := k.syscallPolicies[cid]
+	k.tasks.mu.RUnlock()
+	if p == nil {
+		return nil, false
+	}
+	return p.Stats(), true
+}
+
+// checkSyscallPolicy applies t's syscall policy to the given syscall. If the
+// syscall may run, checkSyscallPolicy returns (nil, true). Otherwise, it
+// returns the task's next run state.
+//
+// Preconditions: t.syscallPolicy != nil.
+func (t *Task) checkSyscallPolicy(sysno uintptr, args *arch.SyscallArguments) (taskRunState, bool) {
+	r := t.syscallPolicy.Check(sysno, args)
+	if r == nil {
+		return nil, true
+	}
+	switch r.Action {
+	case syspolicy.ActionAllow:
+		return nil, true
+	case syspolicy.ActionLog:
+		t.Infof("Syscall %d (%s): matched syscall policy rule %q", sysno, t.SyscallTable().LookupName(sysno), r.Name)
+		return nil, true
+	case syspolicy.ActionErrno:
+		t.Debugf("Syscall %d: denied by syscall policy rule %q", sysno, r.Name)
+		t.emitSyscallPolicyDenial(sysno, r)
+		t.Arch().SetReturn(uintptr(-int(r.Errno)))
+		t.haveSyscallReturn = true
+		return (*runSyscallExit)(nil), false
+	case syspolicy.ActionKill:
+		t.Warningf("Syscall %d (%s): killed by syscall policy rule %q", sysno, t.SyscallTable().LookupName(sysno), r.Name)
+		t.emitSyscallPolicyDenial(sysno, r)
+		t.PrepareGroupExit(linux.WaitStatusTerminationSignal(linux.SIGSYS))
+		return (*runExit)(nil), false
+	default:
+		panic("unknown syscall policy action " + r.Action.String())
+	}
+}
+
+// emitSyscallPolicyDenial emits a SyscallPolicyDenialEvent for the given
+// syscall and the rule that denied it.
+func (t *Task) emitSyscallPolicyDenial(sysno uintptr, r *syspolicy.Rule) {
+	if !seccheck.Global.Enabled(seccheck.PointSyscallPolicyDenial) {
+		return
+	}
+	seccheck.Global.Emit(seccheck.PointSyscallPolicyDenial, &pb.SyscallPolicyDenialEvent{
+		Task:    t.SecurityEventTask(),
+		Sysno:   int32(sysno),
+		Syscall: t.SyscallTable().LookupName(sysno),
+		Rule:    r.Name,
+		Action:  r.Action.String(),
+		Errno:   int32(r.Errno),
+	})
+}
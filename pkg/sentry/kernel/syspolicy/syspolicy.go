@@ -0,0 +1,368 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syspolicy implements sentry-level syscall policies.
+//
+// A policy is a list of rules enforced by the sentry before a syscall handler
+// runs, independently of any seccomp filters installed by the application. It
+// is configured per container from a JSON profile, for example:
+//
+//	{
+//	  "rules": [
+//	    {"name": "no-ptrace", "syscalls": ["ptrace"], "action": "errno", "errno": 1},
+//	    {"name": "no-packet", "syscalls": ["socket"], "action": "kill", "socketFamilies": [17]},
+//	    {"name": "audit-mount", "syscalls": ["mount", "umount2"], "action": "log"}
+//	  ]
+//	}
+//
+// Rules are evaluated in order and the first matching rule applies. Syscalls
+// that match no rule are handled by the profile's default action, which allows
+// them unless set otherwise.
+package syspolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/abi/linux/errno"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+// Action is the action taken when a syscall matches a rule.
+type Action int
+
+const (
+	// ActionAllow runs the syscall.
+	ActionAllow Action = iota
+
+	// ActionErrno fails the syscall with the rule's errno without running it.
+	ActionErrno
+
+	// ActionKill kills the calling thread group with SIGSYS.
+	ActionKill
+
+	// ActionLog logs the syscall and runs it.
+	ActionLog
+)
+
+// String implements fmt.Stringer.
+func (a Action) String() string {
+	switch a {
+	case ActionAllow:
+		return "allow"
+	case ActionErrno:
+		return "errno"
+	case ActionKill:
+		return "kill"
+	case ActionLog:
+		return "log"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Action) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	switch s {
+	case "", "allow":
+		*a = ActionAllow
+	case "errno":
+		*a = ActionErrno
+	case "kill":
+		*a = ActionKill
+	case "log":
+		*a = ActionLog
+	default:
+		return fmt.Errorf("invalid action %q", s)
+	}
+	return nil
+}
+
+// Profile is the JSON representation of a syscall policy.
+type Profile struct {
+	// DefaultAction applies to syscalls that match no rule.
+	DefaultAction Action `json:"defaultAction,omitempty"`
+
+	// DefaultErrno is the errno returned if DefaultAction is ActionErrno. If
+	// zero, EPERM is used.
+	DefaultErrno uint16 `json:"defaultErrno,omitempty"`
+
+	// Rules are evaluated in order; the first matching rule applies.
+	Rules []RuleSpec `json:"rules,omitempty"`
+}
+
+// RuleSpec is the JSON representation of a single rule.
+type RuleSpec struct {
+	// Name identifies the rule in logs and counters. If empty, the rule's
+	// index is used.
+	Name string `json:"name,omitempty"`
+
+	// Syscalls are the names of the syscalls the rule applies to.
+	Syscalls []string `json:"syscalls"`
+
+	// Action is taken when the rule matches.
+	Action Action `json:"action"`
+
+	// Errno is returned if Action is ActionErrno. If zero, EPERM is used.
+	Errno uint16 `json:"errno,omitempty"`
+
+	// SocketFamilies, if set, restricts a rule for socket(2) to the given
+	// address families.
+	SocketFamilies []int32 `json:"socketFamilies,omitempty"`
+
+	// CloneFlags, if set, restricts a rule for clone(2) to calls passing any
+	// of the given flags.
+	CloneFlags uint64 `json:"cloneFlags,omitempty"`
+
+	// PrctlOptions, if set, restricts a rule for prctl(2) to the given
+	// options.
+	PrctlOptions []int32 `json:"prctlOptions,omitempty"`
+}
+
+// ParseProfile reads and validates a JSON profile.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var p Profile
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing syscall policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks that the profile is well formed. Syscall names are checked
+// by Compile.
+func (p *Profile) Validate() error {
+	for i := range p.Rules {
+		rs := &p.Rules[i]
+		if len(rs.Syscalls) == 0 {
+			return fmt.Errorf("syscall policy rule %q has no syscalls", rs.name(i))
+		}
+		conds := 0
+		if len(rs.SocketFamilies) != 0 {
+			conds++
+			if err := rs.checkOnly(i, "socketFamilies", "socket"); err != nil {
+				return err
+			}
+		}
+		if rs.CloneFlags != 0 {
+			conds++
+			if err := rs.checkOnly(i, "cloneFlags", "clone"); err != nil {
+				return err
+			}
+		}
+		if len(rs.PrctlOptions) != 0 {
+			conds++
+			if err := rs.checkOnly(i, "prctlOptions", "prctl"); err != nil {
+				return err
+			}
+		}
+		if conds > 1 {
+			return fmt.Errorf("syscall policy rule %q has more than one argument condition", rs.name(i))
+		}
+	}
+	return nil
+}
+
+// checkOnly returns an error if rs applies to any syscall other than sysname.
+func (rs *RuleSpec) checkOnly(i int, field, sysname string) error {
+	for _, name := range rs.Syscalls {
+		if name != sysname {
+			return fmt.Errorf("syscall policy rule %q: %s only applies to %s, not %s", rs.name(i), field, sysname, name)
+		}
+	}
+	return nil
+}
+
+func (rs *RuleSpec) name(i int) string {
+	if rs.Name != "" {
+		return rs.Name
+	}
+	return fmt.Sprintf("rule%d", i)
+}
+
+// Rule is a compiled rule.
+//
+// +stateify savable
+type Rule struct {
+	// Name identifies the rule. Immutable.
+	Name string
+
+	// Action is taken when the rule matches. Immutable.
+	Action Action
+
+	// Errno is returned if Action is ActionErrno. Immutable.
+	Errno uint16
+
+	// If argIn is not empty, the rule only matches if the syscall's first
+	// argument is in argIn. Immutable.
+	argIn []uint64
+
+	// If argMask is not zero, the rule only matches if the syscall's first
+	// argument has any bit of argMask set. Immutable.
+	argMask uint64
+
+	// hits is the number of syscalls the rule has matched. Accessed
+	// atomically.
+	hits uint64
+}
+
+// matches returns true if the syscall's arguments satisfy r's condition.
+func (r *Rule) matches(args *arch.SyscallArguments) bool {
+	arg := args[0].Uint64()
+	if r.argMask != 0 && arg&r.argMask == 0 {
+		return false
+	}
+	if len(r.argIn) == 0 {
+		return true
+	}
+	for _, v := range r.argIn {
+		if arg == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Hits returns the number of syscalls that have matched r.
+func (r *Rule) Hits() uint64 {
+	return atomic.LoadUint64(&r.hits)
+}
+
+// Policy is a compiled profile.
+//
+// +stateify savable
+type Policy struct {
+	// rules maps syscall numbers to the rules that apply to them, in profile
+	// order. Immutable.
+	rules map[uintptr][]*Rule
+
+	// all is every rule, in profile order. Immutable.
+	all []*Rule
+
+	// def applies to syscalls that match no rule, or is nil if such syscalls
+	// are allowed. Immutable.
+	def *Rule
+}
+
+// Compile compiles a profile. lookup returns the number of the named syscall.
+// Compile returns nil if the profile allows every syscall.
+func Compile(p *Profile, lookup func(name string) (uintptr, error)) (*Policy, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	pol := &Policy{rules: make(map[uintptr][]*Rule)}
+	for i := range p.Rules {
+		rs := &p.Rules[i]
+		r := &Rule{
+			Name:    rs.name(i),
+			Action:  rs.Action,
+			Errno:   errnoOrEPERM(rs.Action, rs.Errno),
+			argMask: rs.CloneFlags,
+		}
+		for _, f := range rs.SocketFamilies {
+			r.argIn = append(r.argIn, uint64(f))
+		}
+		for _, o := range rs.PrctlOptions {
+			r.argIn = append(r.argIn, uint64(o))
+		}
+		for _, name := range rs.Syscalls {
+			sysno, err := lookup(name)
+			if err != nil {
+				return nil, fmt.Errorf("syscall policy rule %q: unknown syscall %q", r.Name, name)
+			}
+			pol.rules[sysno] = append(pol.rules[sysno], r)
+		}
+		pol.all = append(pol.all, r)
+	}
+	if p.DefaultAction != ActionAllow {
+		pol.def = &Rule{
+			Name:   "default",
+			Action: p.DefaultAction,
+			Errno:  errnoOrEPERM(p.DefaultAction, p.DefaultErrno),
+		}
+		pol.all = append(pol.all, pol.def)
+	}
+	if pol.def == nil {
+		allowAll := true
+		for _, r := range pol.all {
+			if r.Action != ActionAllow {
+				allowAll = false
+				break
+			}
+		}
+		if allowAll {
+			return nil, nil
+		}
+	}
+	return pol, nil
+}
+
+func errnoOrEPERM(a Action, e uint16) uint16 {
+	if a == ActionErrno && e == 0 {
+		return uint16(errno.EPERM)
+	}
+	return e
+}
+
+// Check returns the rule that applies to the given syscall, or nil if the
+// syscall is allowed without matching any rule. The returned rule's counter is
+// incremented.
+func (p *Policy) Check(sysno uintptr, args *arch.SyscallArguments) *Rule {
+	for _, r := range p.rules[sysno] {
+		if r.matches(args) {
+			atomic.AddUint64(&r.hits, 1)
+			return r
+		}
+	}
+	if p.def != nil {
+		atomic.AddUint64(&p.def.hits, 1)
+	}
+	return p.def
+}
+
+// RuleStats reports the number of syscalls matched by a rule.
+type RuleStats struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Hits   uint64 `json:"hits"`
+}
+
+// Stats returns the counters of every rule in p, in profile order, followed by
+// the default rule if it isn't ActionAllow.
+func (p *Policy) Stats() []RuleStats {
+	stats := make([]RuleStats, 0, len(p.all))
+	for _, r := range p.all {
+		stats = append(stats, RuleStats{
+			Name:   r.Name,
+			Action: r.Action.String(),
+			Hits:   r.Hits(),
+		})
+	}
+	return stats
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syspolicy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/abi/linux/errno"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+var testSyscalls = map[string]uintptr{
+	"socket": 41,
+	"clone":  56,
+	"ptrace": 101,
+	"prctl":  157,
+	"mount":  165,
+}
+
+func testLookup(name string) (uintptr, error) {
+	if sysno, ok := testSyscalls[name]; ok {
+		return sysno, nil
+	}
+	return 0, fmt.Errorf("no syscall %q", name)
+}
+
+func args(arg0 uint64) *arch.SyscallArguments {
+	return &arch.SyscallArguments{{Value: uintptr(arg0)}}
+}
+
+func compile(t *testing.T, profile string) *Policy {
+	t.Helper()
+	p, err := ParseProfile(strings.NewReader(profile))
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	pol, err := Compile(p, testLookup)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	return pol
+}
+
+func TestCheck(t *testing.T) {
+	pol := compile(t, `{
+		"rules": [
+			{"name": "no-ptrace", "syscalls": ["ptrace"], "action": "errno"},
+			{"name": "no-packet", "syscalls": ["socket"], "action": "kill", "socketFamilies": [17]},
+			{"name": "no-userns", "syscalls": ["clone"], "action": "errno", "errno": 22, "cloneFlags": 268435456},
+			{"syscalls": ["prctl"], "action": "log", "prctlOptions": [22]}
+		]
+	}`)
+
+	for _, tc := range []struct {
+		name   string
+		sysno  uintptr
+		arg0   uint64
+		rule   string
+		action Action
+		errno  uint16
+	}{
+		{name: "ptrace", sysno: 101, rule: "no-ptrace", action: ActionErrno, errno: uint16(errno.EPERM)},
+		{name: "packet socket", sysno: 41, arg0: linux.AF_PACKET, rule: "no-packet", action: ActionKill},
+		{name: "inet socket", sysno: 41, arg0: linux.AF_INET},
+		{name: "clone newuser", sysno: 56, arg0: linux.CLONE_NEWUSER | uint64(linux.SIGCHLD), rule: "no-userns", action: ActionErrno, errno: uint16(errno.EINVAL)},
+		{name: "clone thread", sysno: 56, arg0: linux.CLONE_THREAD},
+		{name: "prctl seccomp", sysno: 157, arg0: linux.PR_SET_SECCOMP, rule: "rule3", action: ActionLog},
+		{name: "prctl name", sysno: 157, arg0: linux.PR_SET_NAME},
+		{name: "unlisted", sysno: 165},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := pol.Check(tc.sysno, args(tc.arg0))
+			if tc.rule == "" {
+				if r != nil {
+					t.Fatalf("Check matched rule %q, want none", r.Name)
+				}
+				return
+			}
+			if r == nil {
+				t.Fatalf("Check matched no rule, want %q", tc.rule)
+			}
+			if r.Name != tc.rule || r.Action != tc.action || r.Errno != tc.errno {
+				t.Errorf("Check got rule {%q, %v, %d}, want {%q, %v, %d}", r.Name, r.Action, r.Errno, tc.rule, tc.action, tc.errno)
+			}
+		})
+	}
+
+	want := map[string]uint64{"no-ptrace": 1, "no-packet": 1, "no-userns": 1, "rule3": 1}
+	stats := pol.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("Stats got %d rules, want %d: %+v", len(stats), len(want), stats)
+	}
+	for _, s := range stats {
+		if s.Hits != want[s.Name] {
+			t.Errorf("rule %q got %d hits, want %d", s.Name, s.Hits, want[s.Name])
+		}
+	}
+}
+
+func TestDefaultAction(t *testing.T) {
+	pol := compile(t, `{
+		"defaultAction": "errno",
+		"defaultErrno": 38,
+		"rules": [{"name": "allow-socket", "syscalls": ["socket"], "action": "allow"}]
+	}`)
+
+	if r := pol.Check(41, args(linux.AF_INET)); r == nil || r.Action != ActionAllow {
+		t.Errorf("socket got rule %+v, want allow-socket", r)
+	}
+	r := pol.Check(165, args(0))
+	if r == nil || r.Name != "default" || r.Action != ActionErrno || r.Errno != uint16(errno.ENOSYS) {
+		t.Errorf("mount got rule %+v, want default errno ENOSYS", r)
+	}
+}
+
+func TestAllowAllCompilesToNil(t *testing.T) {
+	for _, profile := range []string{
+		`{}`,
+		`{"rules": [{"syscalls": ["mount"], "action": "allow"}]}`,
+	} {
+		if pol := compile(t, profile); pol != nil {
+			t.Errorf("Compile(%s) = %+v, want nil", profile, pol)
+		}
+	}
+}
+
+func TestInvalidProfile(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		profile string
+	}{
+		{name: "unknown field", profile: `{"rules": [{"syscalls": ["mount"], "action": "kill", "bogus": 1}]}`},
+		{name: "bad action", profile: `{"rules": [{"syscalls": ["mount"], "action": "explode"}]}`},
+		{name: "no syscalls", profile: `{"rules": [{"action": "kill"}]}`},
+		{name: "family on mount", profile: `{"rules": [{"syscalls": ["mount"], "action": "kill", "socketFamilies": [17]}]}`},
+		{name: "clone flags on socket", profile: `{"rules": [{"syscalls": ["socket"], "action": "kill", "cloneFlags": 1}]}`},
+		{name: "prctl option on clone", profile: `{"rules": [{"syscalls": ["clone"], "action": "kill", "prctlOptions": [22]}]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseProfile(strings.NewReader(tc.profile)); err == nil {
+				t.Errorf("ParseProfile(%s) succeeded, want error", tc.profile)
+			}
+		})
+	}
+
+	p, err := ParseProfile(strings.NewReader(`{"rules": [{"syscalls": ["nosuchcall"], "action": "kill"}]}`))
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	if _, err := Compile(p, testLookup); err == nil {
+		t.Errorf("Compile succeeded with unknown syscall, want error")
+	}
+}
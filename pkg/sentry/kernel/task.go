@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
@@ -442,9 +443,11 @@ type Task struct {
 	// ipcns is protected by mu. ipcns is owned by the task goroutine.
 	ipcns *IPCNamespace
 
-	// abstractSockets tracks abstract sockets that are in use.
+	// abstractSockets tracks abstract sockets that are in use in the task's
+	// network namespace.
 	//
-	// abstractSockets is protected by mu.
+	// abstractSockets is protected by mu. abstractSockets is owned by the task
+	// goroutine.
 	abstractSockets *AbstractSocketNamespace
 
 	// mountNamespaceVFS2 is the task's mount namespace.
@@ -464,6 +467,24 @@ type Task struct {
 	// syscallFilters is owned by the task goroutine.
 	syscallFilters atomic.Value `state:".([]bpf.Program)"`
 
+	// curSyscall is the number of the syscall being executed by the task
+	// plus one, or zero if the task is not executing a syscall. waitReason
+	// is the interned index of the reason given for the task's current or
+	// next block, or zero if none was given. Both are owned by the task
+	// goroutine; see task_stack.go.
+	curSyscall uintptr `state:"nosave"`
+	waitReason uint32  `state:"nosave"`
+
+	// pseudoStack is a packed copy of curSyscall and waitReason that may be
+	// read by any goroutine. pseudoStack is accessed using atomic memory
+	// operations.
+	pseudoStack uint64 `state:"nosave"`
+
+	// syscallPolicy is the sentry syscall policy of the task's container, or
+	// nil if the container has none. It is inherited from the container when
+	// the task is created and is immutable.
+	syscallPolicy *syspolicy.Policy
+
 	// If cleartid is non-zero, treat it as a pointer to a ThreadID in the
 	// task's virtual address space; when the task exits, set the pointed-to
 	// ThreadID to 0, and wake any futex waiters.
@@ -587,10 +608,14 @@ type Task struct {
 	// kcov is exclusive to the task goroutine.
 	kcov *Kcov
 
+	// cgroupsMu protects cgroups. It is separate from mu because I/O and CPU
+	// usage are charged to t's cgroups on every read, write and tick.
+	cgroupsMu sync.RWMutex `state:"nosave"`
+
 	// cgroups is the set of cgroups this task belongs to. This may be empty if
-	// no cgroup controllers are enabled. Protected by mu.
+	// no cgroup controllers are enabled. Protected by cgroupsMu.
 	//
-	// +checklocks:mu
+	// +checklocks:cgroupsMu
 	cgroups map[Cgroup]struct{}
 }
 
@@ -849,6 +874,7 @@ func (t *Task) SetOOMScoreAdj(adj int32) error {
 		return linuxerr.EINVAL
 	}
 	atomic.StoreInt32(&t.tg.oomScoreAdj, adj)
+	t.tg.invalidateOOMScore()
 	return nil
 }
 
@@ -119,6 +119,34 @@ func (t *Task) IOUsage() *usage.IO {
 	return t.ioUsage
 }
 
+// AccountReadIO accounts bytes read from the backing store of the device dev,
+// a device number as encoded by linux.MakeDeviceID, to t and the cgroups that
+// t is a member of.
+func (t *Task) AccountReadIO(dev uint32, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	t.ioUsage.AccountReadIO(bytes)
+	t.accountCgroupIO(dev, false /* write */, bytes)
+}
+
+// AccountWriteIO accounts bytes written to the backing store of the device
+// dev, a device number as encoded by linux.MakeDeviceID, to t and the cgroups
+// that t is a member of.
+func (t *Task) AccountWriteIO(dev uint32, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	t.ioUsage.AccountWriteIO(bytes)
+	t.accountCgroupIO(dev, true /* write */, bytes)
+}
+
+// AccountWriteCancelledIO accounts bytes of cached writes that t caused to be
+// discarded before they reached the backing store.
+func (t *Task) AccountWriteCancelledIO(bytes int64) {
+	t.ioUsage.AccountWriteCancelledIO(bytes)
+}
+
 // IOUsage returns the total io usage of all dead and live threads in the group.
 func (tg *ThreadGroup) IOUsage() *usage.IO {
 	tg.pidns.owner.mu.RLock()
@@ -127,6 +127,7 @@ func (t *Task) block(C <-chan struct{}, timerChan <-chan struct{}) error {
 	// Fast path if the request is already done.
 	select {
 	case <-C:
+		t.clearWaitReason()
 		return nil
 	default:
 	}
@@ -183,6 +184,7 @@ func (t *Task) SleepFinish(success bool) {
 		t.interruptSelf()
 	}
 	t.accountTaskGoroutineLeave(TaskGoroutineBlockedInterruptible)
+	t.clearWaitReason()
 	t.Activate()
 }
 
@@ -209,6 +211,7 @@ func (t *Task) UninterruptibleSleepStart(deactivate bool) {
 // UninterruptibleSleepFinish implements context.Context.UninterruptibleSleepFinish.
 func (t *Task) UninterruptibleSleepFinish(activate bool) {
 	t.accountTaskGoroutineLeave(TaskGoroutineBlockedUninterruptible)
+	t.clearWaitReason()
 	if activate {
 		t.Activate()
 	}
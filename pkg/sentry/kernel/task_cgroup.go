@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/log"
+	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 )
 
 // EnterInitialCgroups moves t into an initial set of cgroups.
@@ -30,14 +32,14 @@ import (
 func (t *Task) EnterInitialCgroups(parent *Task) {
 	var inherit map[Cgroup]struct{}
 	if parent != nil {
-		parent.mu.Lock()
-		defer parent.mu.Unlock()
+		parent.cgroupsMu.RLock()
+		defer parent.cgroupsMu.RUnlock()
 		inherit = parent.cgroups
 	}
 	joinSet := t.k.cgroupRegistry.computeInitialGroups(inherit)
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.cgroupsMu.Lock()
+	defer t.cgroupsMu.Unlock()
 	// Transfer ownership of joinSet refs to the task's cgset.
 	t.cgroups = joinSet
 	for c, _ := range t.cgroups {
@@ -54,8 +56,8 @@ func (t *Task) EnterCgroup(c Cgroup) error {
 		newControllers[ctl.Type()] = struct{}{}
 	}
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.cgroupsMu.Lock()
+	defer t.cgroupsMu.Unlock()
 
 	for oldCG, _ := range t.cgroups {
 		for _, oldCtl := range oldCG.Controllers() {
@@ -76,14 +78,14 @@ func (t *Task) EnterCgroup(c Cgroup) error {
 	return nil
 }
 
-// +checklocks:t.mu
+// +checklocks:t.cgroupsMu
 func (t *Task) enterCgroupLocked(c Cgroup) {
 	c.IncRef()
 	t.cgroups[c] = struct{}{}
 	c.Enter(t)
 }
 
-// +checklocks:t.mu
+// +checklocks:t.cgroupsMu
 func (t *Task) enterCgroupIfNotYetLocked(c Cgroup) {
 	if _, ok := t.cgroups[c]; ok {
 		return
@@ -91,16 +93,75 @@ func (t *Task) enterCgroupIfNotYetLocked(c Cgroup) {
 	t.enterCgroupLocked(c)
 }
 
+// accountCgroupIO charges I/O performed by t to all of t's cgroups.
+func (t *Task) accountCgroupIO(dev uint32, write bool, bytes int64) {
+	t.cgroupsMu.RLock()
+	defer t.cgroupsMu.RUnlock()
+	for c, _ := range t.cgroups {
+		c.AccountIO(dev, write, bytes)
+	}
+}
+
+// chargeCgroupCPU charges d of CPU time used by t at now to all of t's
+// cgroups, and returns true if t is now throttled by any of them.
+func (t *Task) chargeCgroupCPU(now ktime.Time, d time.Duration) bool {
+	t.cgroupsMu.RLock()
+	defer t.cgroupsMu.RUnlock()
+	throttled := false
+	for c, _ := range t.cgroups {
+		c.ChargeCPU(now, d)
+		if _, ok := c.CPUThrottled(now); ok {
+			throttled = true
+		}
+	}
+	return throttled
+}
+
+// cgroupCPUThrottledUntil returns whether t may not run at now because one of
+// its cgroups has exhausted its CPU bandwidth, and if so, the latest time at
+// which one of them is replenished.
+func (t *Task) cgroupCPUThrottledUntil(now ktime.Time) (ktime.Time, bool) {
+	t.cgroupsMu.RLock()
+	defer t.cgroupsMu.RUnlock()
+	var until ktime.Time
+	throttled := false
+	for c, _ := range t.cgroups {
+		if u, ok := c.CPUThrottled(now); ok {
+			throttled = true
+			if until.Before(u) {
+				until = u
+			}
+		}
+	}
+	return until, throttled
+}
+
+// waitCgroupCPUThrottle blocks t until none of its cgroups are throttled. It
+// returns a non-nil error if t is interrupted first.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) waitCgroupCPUThrottle() error {
+	for {
+		until, ok := t.cgroupCPUThrottledUntil(t.k.MonotonicClock().Now())
+		if !ok {
+			return nil
+		}
+		if err := t.BlockWithDeadline(nil, true, until); err != nil && !linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+			return err
+		}
+	}
+}
+
 // LeaveCgroups removes t out from all its cgroups.
 func (t *Task) LeaveCgroups() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.cgroupsMu.Lock()
+	defer t.cgroupsMu.Unlock()
 	for c, _ := range t.cgroups {
 		t.leaveCgroupLocked(c)
 	}
 }
 
-// +checklocks:t.mu
+// +checklocks:t.cgroupsMu
 func (t *Task) leaveCgroupLocked(c Cgroup) {
 	c.Leave(t)
 	delete(t.cgroups, c)
@@ -117,8 +178,8 @@ type taskCgroupEntry struct {
 
 // GenerateProcTaskCgroup writes the contents of /proc/<pid>/cgroup for t to buf.
 func (t *Task) GenerateProcTaskCgroup(buf *bytes.Buffer) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.cgroupsMu.RLock()
+	defer t.cgroupsMu.RUnlock()
 
 	cgEntries := make([]taskCgroupEntry, 0, len(t.cgroups))
 	for c, _ := range t.cgroups {
@@ -111,8 +111,11 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	defer cu.Clean()
 
 	netns := t.NetworkNamespace()
+	abstractSockets := t.abstractSockets
 	if args.Flags&linux.CLONE_NEWNET != 0 {
 		netns = inet.NewNamespace(netns)
+		// Abstract socket names are scoped to the network namespace.
+		abstractSockets = NewAbstractSocketNamespace()
 	}
 
 	// TODO(b/63601033): Implement CLONE_NEWNS.
@@ -195,7 +198,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		AllowedCPUMask:          t.CPUMask(),
 		UTSNamespace:            utsns,
 		IPCNamespace:            ipcns,
-		AbstractSocketNamespace: t.abstractSockets,
+		AbstractSocketNamespace: abstractSockets,
 		MountNamespaceVFS2:      mntnsVFS2,
 		RSeqAddr:                rseqAddr,
 		RSeqSignature:           rseqSignature,
@@ -411,6 +414,7 @@ func (t *Task) Unshare(flags int32) error {
 			return linuxerr.EPERM
 		}
 		t.netns = inet.NewNamespace(t.netns)
+		t.abstractSockets = NewAbstractSocketNamespace()
 	}
 	if flags&linux.CLONE_NEWUTS != 0 {
 		if !haveCapSysAdmin {
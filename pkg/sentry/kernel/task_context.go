@@ -27,6 +27,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -93,6 +94,15 @@ func (t *Task) contextValue(key interface{}, isTaskGoroutine bool) interface{} {
 			defer t.mu.Unlock()
 		}
 		return t.fsContext.RootDirectory()
+	case fs.CtxResolveBeneath:
+		if !isTaskGoroutine {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+		}
+		if d := t.fsContext.ResolveBeneath(); d != nil {
+			return d
+		}
+		return nil
 	case vfs.CtxRoot:
 		if !isTaskGoroutine {
 			t.mu.Lock()
@@ -132,6 +142,8 @@ func (t *Task) contextValue(key interface{}, isTaskGoroutine bool) interface{} {
 		return t.k.GenerateInotifyCookie()
 	case unimpl.CtxEvents:
 		return t.k
+	case transport.CtxSecurityLabel:
+		return t.SecurityLabel()
 	default:
 		return nil
 	}
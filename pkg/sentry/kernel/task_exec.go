@@ -67,9 +67,7 @@ package kernel
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
-	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/mm"
-	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 // execStop is a TaskStop that a task sets on itself when it wants to execve
@@ -202,10 +200,10 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	t.fdTable = t.fdTable.Fork(t)
 	oldFDTable.DecRef(t)
 
-	// Remove FDs with the CloseOnExec flag set.
-	t.fdTable.RemoveIf(t, func(_ *fs.File, _ *vfs.FileDescription, flags FDFlags) bool {
-		return flags.CloseOnExec
-	})
+	// Remove FDs with the CloseOnExec flag set. This must happen after the
+	// table is unshared above, so that no other task can observe or race
+	// with a partially-closed table.
+	t.fdTable.RemoveCloseOnExec(t)
 
 	// Handle the robust futex list.
 	t.exitRobustList()
@@ -227,6 +225,8 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	t.mu.Unlock()
 	t.unstopVforkParent()
 	t.p.FullStateChanged()
+	// Pick up the new name.
+	t.updateProfileLabels()
 	// NOTE(b/30316266): All locks must be dropped prior to calling Activate.
 	t.MemoryManager().Activate(t)
 
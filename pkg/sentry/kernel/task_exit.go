@@ -360,10 +360,26 @@ func (t *Task) findReparentTargetLocked() *Task {
 	if t2 := t.tg.anyNonExitingTaskLocked(); t2 != nil {
 		return t2
 	}
+	init := t.tg.pidns.tasks[InitTID]
+	// Reparent to the nearest ancestor that is a child subreaper, but only
+	// within the same PID namespace: the search stops at the namespace's
+	// init or at a task in another namespace. (Compare
+	// kernel/exit.c:find_new_reaper().)
+	for a := t.tg.leader.parent; a != nil && a.tg.pidns == t.tg.pidns; a = a.tg.leader.parent {
+		if init != nil && a.tg == init.tg {
+			break
+		}
+		if !a.tg.isChildSubreaper {
+			continue
+		}
+		if t2 := a.tg.anyNonExitingTaskLocked(); t2 != nil {
+			return t2
+		}
+	}
 	// "A child process that is orphaned within the namespace will be
 	// reparented to [the init process for the namespace] ..." -
 	// pid_namespaces(7)
-	if init := t.tg.pidns.tasks[InitTID]; init != nil {
+	if init != nil {
 		return init.tg.anyNonExitingTaskLocked()
 	}
 	return nil
@@ -1029,6 +1045,11 @@ func (t *Task) waitCollectZombieLocked(target *Task, opts *WaitOptions, asPtrace
 			t.tg.childCPUStats.Accumulate(target.CPUStats())
 			t.tg.childCPUStats.Accumulate(target.tg.exitedCPUStats)
 			t.tg.childCPUStats.Accumulate(target.tg.childCPUStats)
+			// Similarly, fold target's I/O usage into t's, as in Linux's
+			// wait_task_zombie(). target.tg.ioUsage already includes the I/O
+			// usage of target's joined descendants.
+			t.tg.ioUsage.Accumulate(target.ioUsage)
+			t.tg.ioUsage.Accumulate(target.tg.ioUsage)
 			// Update t's child max resident set size. The size will be the maximum
 			// of this thread's size and all its childrens' sizes.
 			if t.tg.childMaxRSS < target.tg.maxRSS {
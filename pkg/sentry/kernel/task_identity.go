@@ -28,6 +28,16 @@ func (t *Task) Credentials() *auth.Credentials {
 	return t.creds.Load()
 }
 
+// DefaultSecurityLabel is the security label reported for tasks, e.g. by
+// SO_PEERSEC. The sentry doesn't implement any LSM, so this is the label that
+// AppArmor gives to unconfined tasks.
+const DefaultSecurityLabel = "unconfined"
+
+// SecurityLabel returns t's security label.
+func (t *Task) SecurityLabel() string {
+	return DefaultSecurityLabel
+}
+
 // UserNamespace returns the user namespace associated with the task.
 func (t *Task) UserNamespace() *auth.UserNamespace {
 	return t.Credentials().UserNamespace
@@ -50,6 +60,7 @@ func (t *Task) SetUID(uid auth.UID) error {
 		return linuxerr.EINVAL
 	}
 
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -78,6 +89,7 @@ func (t *Task) SetUID(uid auth.UID) error {
 
 // SetREUID implements the semantics of setreuid(2).
 func (t *Task) SetREUID(r, e auth.UID) error {
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	// "Supplying a value of -1 for either the real or effective user ID forces
@@ -122,6 +134,7 @@ func (t *Task) SetREUID(r, e auth.UID) error {
 
 // SetRESUID implements the semantics of the setresuid(2) syscall.
 func (t *Task) SetRESUID(r, e, s auth.UID) error {
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	// "Unprivileged user processes may change the real UID, effective UID, and
@@ -226,6 +239,7 @@ func (t *Task) SetGID(gid auth.GID) error {
 		return linuxerr.EINVAL
 	}
 
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -247,6 +261,7 @@ func (t *Task) SetGID(gid auth.GID) error {
 
 // SetREGID implements the semantics of setregid(2).
 func (t *Task) SetREGID(r, e auth.GID) error {
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -285,6 +300,7 @@ func (t *Task) SetREGID(r, e auth.GID) error {
 func (t *Task) SetRESGID(r, e, s auth.GID) error {
 	var err error
 
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -362,6 +378,7 @@ func (t *Task) SetExtraGIDs(gids []auth.GID) error {
 // SetCapabilitySets attempts to change t's permitted, inheritable, and
 // effective capability sets.
 func (t *Task) SetCapabilitySets(permitted, inheritable, effective auth.CapabilitySet) error {
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	// "Permitted: This is a limiting superset for the effective capabilities
@@ -398,6 +415,7 @@ func (t *Task) SetCapabilitySets(permitted, inheritable, effective auth.Capabili
 // DropBoundingCapability attempts to drop capability cp from t's capability
 // bounding set.
 func (t *Task) DropBoundingCapability(cp linux.Capability) error {
+	defer t.emitCredentialsEvent(t.Credentials())
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	creds := t.Credentials()
@@ -16,8 +16,10 @@ package kernel
 
 import (
 	"fmt"
+	"runtime/pprof"
 	"runtime/trace"
 	"sort"
+	"strconv"
 
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/hostarch"
@@ -34,6 +36,22 @@ const (
 	maxCodeDebugBytes = 128
 )
 
+// Keys of the pprof labels applied to task goroutines, which allow CPU and
+// goroutine profiles of a sandbox running several containers to be filtered
+// per container (e.g. with pprof -tagfocus).
+const (
+	// ProfileLabelContainer is the ID of the container the task belongs to.
+	ProfileLabelContainer = "container"
+
+	// ProfileLabelTGID is the ID of the task's thread group in the root PID
+	// namespace.
+	ProfileLabelTGID = "tgid"
+
+	// ProfileLabelComm is the task's name as of its creation or last execve;
+	// later changes by prctl(PR_SET_NAME) are not reflected.
+	ProfileLabelComm = "comm"
+)
+
 // Infof logs an formatted info message by calling log.Infof.
 func (t *Task) Infof(fmt string, v ...interface{}) {
 	if log.IsLogging(log.Info) {
@@ -251,3 +269,15 @@ func (t *Task) traceExecEvent(image *TaskImage) {
 	defer file.DecRef(t)
 	trace.Logf(t.traceContext, traceCategory, "exec: %s", file.PathnameWithDeleted(t))
 }
+
+// updateProfileLabels sets the pprof labels of the task goroutine, and of any
+// goroutines it subsequently starts, to identify t.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) updateProfileLabels() {
+	labels := pprof.Labels(
+		ProfileLabelContainer, t.containerID,
+		ProfileLabelTGID, strconv.Itoa(int(t.k.tasks.Root.IDOfThreadGroup(t.tg))),
+		ProfileLabelComm, t.Name())
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), labels))
+}
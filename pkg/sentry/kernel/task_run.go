@@ -60,6 +60,7 @@ type taskRunState interface {
 // searching for Task.run()'s argument value.
 func (t *Task) run(threadID uintptr) {
 	atomic.StoreInt64(&t.goid, goid.Get())
+	t.updateProfileLabels()
 
 	// Construct t.blockingTimer here. We do this here because we can't
 	// reconstruct t.blockingTimer during restore in Task.afterLoad(), because
@@ -190,6 +191,14 @@ func (app *runApp) execute(t *Task) taskRunState {
 		}
 	}
 
+	// Don't return to user space while any of t's cgroups has exhausted its
+	// CPU bandwidth.
+	if atomic.LoadInt32(&t.k.cpuBandwidthLimits) != 0 {
+		if err := t.waitCgroupCPUThrottle(); err != nil {
+			return (*runInterrupt)(nil)
+		}
+	}
+
 	// We're about to switch to the application again. If there's still an
 	// unhandled SyscallRestartErrno that wasn't translated to an EINTR,
 	// restart the syscall that was interrupted. If there's a saved signal
@@ -341,8 +341,9 @@ type kernelCPUClockTicker struct {
 
 	// These are essentially kernelCPUClockTicker.Notify local variables that
 	// are cached between calls to reduce allocations.
-	rng *rand.Rand
-	tgs []*ThreadGroup
+	rng     *rand.Rand
+	tgs     []*ThreadGroup
+	running []*Task
 }
 
 func newKernelCPUClockTicker(k *Kernel) *kernelCPUClockTicker {
@@ -352,6 +353,38 @@ func newKernelCPUClockTicker(k *Kernel) *kernelCPUClockTicker {
 	}
 }
 
+// chargeCgroupCPU charges a clock tick to the cgroups of all running tasks in
+// tgs, and interrupts tasks whose cgroups have exhausted their CPU bandwidth so
+// that they stop executing application code.
+func (ticker *kernelCPUClockTicker) chargeCgroupCPU(tgs []*ThreadGroup) {
+	running := ticker.running
+	ticker.k.tasks.mu.RLock()
+	for _, tg := range tgs {
+		for t := tg.tasks.Front(); t != nil; t = t.Next() {
+			switch t.TaskGoroutineSchedInfo().State {
+			case TaskGoroutineRunningApp, TaskGoroutineRunningSys:
+				running = append(running, t)
+			}
+		}
+	}
+	ticker.k.tasks.mu.RUnlock()
+
+	// Charging cgroups requires locking Task.mu, so it must be done without
+	// holding TaskSet.mu.
+	now := ticker.k.MonotonicClock().Now()
+	for _, t := range running {
+		if t.chargeCgroupCPU(now, linux.ClockTick) {
+			t.interrupt()
+		}
+	}
+
+	// Retain running between calls to reduce allocations.
+	for i := range running {
+		running[i] = nil
+	}
+	ticker.running = running[:0]
+}
+
 // Notify implements ktime.TimerListener.Notify.
 func (ticker *kernelCPUClockTicker) Notify(exp uint64, setting ktime.Setting) (ktime.Setting, bool) {
 	// Only increment cpuClock by 1 regardless of the number of expirations.
@@ -444,6 +477,12 @@ func (ticker *kernelCPUClockTicker) Notify(exp uint64, setting ktime.Setting) (k
 		ticker.k.tasks.mu.RUnlock()
 	}
 
+	// Charge the tick to the cgroups of running tasks, if any cgroup's CPU
+	// bandwidth is limited.
+	if atomic.LoadInt32(&ticker.k.cpuBandwidthLimits) != 0 {
+		ticker.chargeCgroupCPU(tgs)
+	}
+
 	// Retain tgs between calls to Notify to reduce allocations.
 	for i := range tgs {
 		tgs[i] = nil
@@ -0,0 +1,97 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"crypto/sha256"
+
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/security_events_go_proto"
+)
+
+// SecurityEventTask returns the SecurityEventTask identifying t in security
+// events.
+//
+// Preconditions: The caller must not be holding t.mu or the TaskSet mutex.
+func (t *Task) SecurityEventTask() *pb.SecurityEventTask {
+	creds := t.Credentials()
+	root := t.k.tasks.Root
+	return &pb.SecurityEventTask{
+		ContainerId: t.ContainerID(),
+		Pid:         int32(root.IDOfThreadGroup(t.tg)),
+		Tid:         int32(root.IDOfTask(t)),
+		Comm:        t.Name(),
+		Uid:         uint32(creds.EffectiveKUID),
+		Gid:         uint32(creds.EffectiveKGID),
+	}
+}
+
+// EmitExecveEvent emits an ExecveEvent for a successful execve(2) of the given
+// path and arguments.
+func (t *Task) EmitExecveEvent(path string, argv []string) {
+	if !seccheck.Global.Enabled(seccheck.PointExecve) {
+		return
+	}
+	h := sha256.New()
+	for _, arg := range argv {
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+	}
+	seccheck.Global.Emit(seccheck.PointExecve, &pb.ExecveEvent{
+		Task:       t.SecurityEventTask(),
+		BinaryPath: path,
+		Argc:       int32(len(argv)),
+		ArgvSha256: h.Sum(nil),
+	})
+}
+
+// emitCredentialsEvent emits a CredentialsEvent if t's credentials differ
+// from old in any way reported by the event. Credential setters defer it
+// before locking t.mu, so that it runs after t.mu is unlocked.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) emitCredentialsEvent(old *auth.Credentials) {
+	if !seccheck.Global.Enabled(seccheck.PointCredentials) {
+		return
+	}
+	creds := t.Credentials()
+	if creds == old ||
+		(creds.RealKUID == old.RealKUID && creds.EffectiveKUID == old.EffectiveKUID && creds.SavedKUID == old.SavedKUID &&
+			creds.RealKGID == old.RealKGID && creds.EffectiveKGID == old.EffectiveKGID && creds.SavedKGID == old.SavedKGID &&
+			creds.PermittedCaps == old.PermittedCaps && creds.InheritableCaps == old.InheritableCaps &&
+			creds.EffectiveCaps == old.EffectiveCaps && creds.BoundingCaps == old.BoundingCaps) {
+		return
+	}
+	seccheck.Global.Emit(seccheck.PointCredentials, &pb.CredentialsEvent{
+		Task:    t.SecurityEventTask(),
+		Syscall: t.SyscallTable().LookupName(t.Arch().SyscallNo()),
+		OldUid:  &pb.CredentialsEvent_IDs{Real: uint32(old.RealKUID), Effective: uint32(old.EffectiveKUID), Saved: uint32(old.SavedKUID)},
+		NewUid:  &pb.CredentialsEvent_IDs{Real: uint32(creds.RealKUID), Effective: uint32(creds.EffectiveKUID), Saved: uint32(creds.SavedKUID)},
+		OldGid:  &pb.CredentialsEvent_IDs{Real: uint32(old.RealKGID), Effective: uint32(old.EffectiveKGID), Saved: uint32(old.SavedKGID)},
+		NewGid:  &pb.CredentialsEvent_IDs{Real: uint32(creds.RealKGID), Effective: uint32(creds.EffectiveKGID), Saved: uint32(creds.SavedKGID)},
+		OldCaps: credsCaps(old),
+		NewCaps: credsCaps(creds),
+	})
+}
+
+func credsCaps(creds *auth.Credentials) *pb.CredentialsEvent_Caps {
+	return &pb.CredentialsEvent_Caps{
+		Permitted:   uint64(creds.PermittedCaps),
+		Inheritable: uint64(creds.InheritableCaps),
+		Effective:   uint64(creds.EffectiveCaps),
+		Bounding:    uint64(creds.BoundingCaps),
+	}
+}
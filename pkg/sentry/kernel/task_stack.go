@@ -0,0 +1,189 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Tasks publish a "pseudo-stack" describing what they are doing in the
+// sentry: the syscall being executed, if any, and a symbolic reason for the
+// current block, if any (e.g. "futex_wait", "pipe_read", "gofer_rpc:Read").
+// This is rendered by /proc/[pid]/stack and /proc/sysrq-trigger, and stands
+// in for the kernel stack traces that Linux reports there.
+//
+// Reasons are interned so that they can be published with a single atomic
+// store; the set of distinct reasons is small and fixed by the sentry's
+// source, so the table never shrinks.
+
+var waitReasons struct {
+	mu sync.RWMutex
+
+	// names maps reason indices to names. Index 0 is reserved for "no
+	// reason".
+	names []string
+
+	// indices is the inverse of names.
+	indices map[string]uint32
+}
+
+func init() {
+	waitReasons.names = []string{""}
+	waitReasons.indices = make(map[string]uint32)
+}
+
+// waitReasonIndex returns the interned index for reason.
+func waitReasonIndex(reason string) uint32 {
+	if reason == "" {
+		return 0
+	}
+	waitReasons.mu.RLock()
+	idx, ok := waitReasons.indices[reason]
+	waitReasons.mu.RUnlock()
+	if ok {
+		return idx
+	}
+	waitReasons.mu.Lock()
+	defer waitReasons.mu.Unlock()
+	if idx, ok := waitReasons.indices[reason]; ok {
+		return idx
+	}
+	idx = uint32(len(waitReasons.names))
+	waitReasons.names = append(waitReasons.names, reason)
+	waitReasons.indices[reason] = idx
+	return idx
+}
+
+// waitReasonName returns the name of the reason with the given index.
+func waitReasonName(idx uint32) string {
+	waitReasons.mu.RLock()
+	defer waitReasons.mu.RUnlock()
+	if int(idx) < len(waitReasons.names) {
+		return waitReasons.names[idx]
+	}
+	return ""
+}
+
+// pseudoStack packs the syscall number (plus one, so that zero means "not in
+// a syscall") into the upper 32 bits and the wait reason index into the lower
+// 32 bits.
+func packPseudoStack(sysnoPlusOne uintptr, reason uint32) uint64 {
+	return uint64(sysnoPlusOne)<<32 | uint64(reason)
+}
+
+// SetWaitReason records reason as the cause of the task's next block. It is
+// cleared when that block ends, or when the current syscall returns.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) SetWaitReason(reason string) {
+	t.assertTaskGoroutine()
+	t.waitReason = waitReasonIndex(reason)
+	atomic.StoreUint64(&t.pseudoStack, packPseudoStack(t.curSyscall, t.waitReason))
+}
+
+// clearWaitReason clears the task's wait reason.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) clearWaitReason() {
+	if t.waitReason == 0 {
+		return
+	}
+	t.waitReason = 0
+	atomic.StoreUint64(&t.pseudoStack, packPseudoStack(t.curSyscall, 0))
+}
+
+// setCurrentSyscall records that the task is executing syscall sysno.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) setCurrentSyscall(sysno uintptr) {
+	t.curSyscall = sysno + 1
+	t.waitReason = 0
+	atomic.StoreUint64(&t.pseudoStack, packPseudoStack(t.curSyscall, 0))
+}
+
+// clearCurrentSyscall records that the task is no longer executing a syscall.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) clearCurrentSyscall() {
+	t.curSyscall = 0
+	t.waitReason = 0
+	atomic.StoreUint64(&t.pseudoStack, 0)
+}
+
+// PseudoStack returns t's current pseudo-stack, innermost frame first. A task
+// that is running application code or has exited has an empty pseudo-stack.
+func (t *Task) PseudoStack() []string {
+	state := t.TaskGoroutineSchedInfo().State
+	packed := atomic.LoadUint64(&t.pseudoStack)
+	sysnoPlusOne := uintptr(packed >> 32)
+	reason := waitReasonName(uint32(packed))
+
+	var frames []string
+	switch state {
+	case TaskGoroutineBlockedInterruptible:
+		if reason == "" {
+			reason = "wait_event"
+		}
+		frames = append(frames, reason)
+	case TaskGoroutineBlockedUninterruptible:
+		if reason == "" {
+			reason = "uninterruptible_sleep"
+		}
+		frames = append(frames, reason)
+	case TaskGoroutineStopped:
+		frames = append(frames, "task_stop")
+	case TaskGoroutineRunningSys:
+		// Running in the sentry; only the syscall frame is meaningful.
+	default:
+		return nil
+	}
+	if sysnoPlusOne != 0 {
+		t.mu.Lock()
+		st := t.image.st
+		t.mu.Unlock()
+		if st != nil {
+			frames = append(frames, "sys_"+st.LookupName(sysnoPlusOne-1))
+		}
+		frames = append(frames, "syscall_entry")
+	}
+	return frames
+}
+
+// FormatPseudoStack formats a pseudo-stack in the style of /proc/[pid]/stack.
+func FormatPseudoStack(frames []string) string {
+	var b strings.Builder
+	for _, f := range frames {
+		fmt.Fprintf(&b, "[<0>] %s\n", f)
+	}
+	return b.String()
+}
+
+// LogPseudoStacks logs the pseudo-stacks of all tasks in the root PID
+// namespace.
+func (k *Kernel) LogPseudoStacks() {
+	pidns := k.tasks.Root
+	for _, t := range pidns.Tasks() {
+		tid := pidns.IDOfTask(t)
+		if tid == 0 {
+			continue
+		}
+		log.Infof("Task %d (%s) pseudo-stack:\n%s", tid, t.Name(), FormatPseudoStack(t.PseudoStack()))
+	}
+}
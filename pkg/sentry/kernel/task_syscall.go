@@ -99,6 +99,7 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		if trace.IsEnabled() {
 			region = trace.StartRegion(t.traceContext, s.LookupName(sysno))
 		}
+		t.setCurrentSyscall(sysno)
 		if fn != nil {
 			// Call our syscall implementation.
 			rval, ctrl, err = fn(t, args)
@@ -106,6 +107,7 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			// Use the missing function if not found.
 			rval, err = t.SyscallTable().Missing(t, sysno, args)
 		}
+		t.clearCurrentSyscall()
 		if region != nil {
 			region.End()
 		}
@@ -236,6 +238,14 @@ func (*runSyscallAfterSysemuStop) execute(t *Task) taskRunState {
 }
 
 func (t *Task) doSyscallInvoke(sysno uintptr, args arch.SyscallArguments) taskRunState {
+	// Check the container's syscall policy. The nil check keeps the overhead
+	// negligible for containers without one.
+	if t.syscallPolicy != nil {
+		if next, ok := t.checkSyscallPolicy(sysno, &args); !ok {
+			return next
+		}
+	}
+
 	rval, ctrl, err := t.executeSyscall(sysno, args)
 
 	if ctrl != nil {
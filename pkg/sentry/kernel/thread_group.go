@@ -151,6 +151,15 @@ type ThreadGroup struct {
 	// terminationSignal is protected by the TaskSet mutex.
 	terminationSignal linux.Signal
 
+	// isChildSubreaper is true if this thread group was marked as a "child
+	// subreaper" by prctl(PR_SET_CHILD_SUBREAPER). When a task exits,
+	// orphaned children are reparented to the nearest subreaper ancestor
+	// in the same PID namespace, if any, rather than to the namespace's
+	// init.
+	//
+	// isChildSubreaper is protected by the TaskSet mutex.
+	isChildSubreaper bool
+
 	// liveGoroutines is the number of non-exited task goroutines in the thread
 	// group.
 	//
@@ -202,8 +211,9 @@ type ThreadGroup struct {
 	// group. childCPUStats is protected by the TaskSet mutex.
 	childCPUStats usage.CPUStats
 
-	// ioUsage is the I/O usage for all exited tasks in the thread group.
-	// The ioUsage pointer is immutable.
+	// ioUsage is the I/O usage for all exited tasks in the thread group,
+	// and all joined descendants of the thread group. The ioUsage pointer is
+	// immutable.
 	ioUsage *usage.IO
 
 	// maxRSS is the historical maximum resident set size of the thread group, updated when:
@@ -255,12 +265,21 @@ type ThreadGroup struct {
 	// tty is protected by the signal mutex.
 	tty *TTY
 
-	// oomScoreAdj is the thread group's OOM score adjustment. This is
-	// currently not used but is maintained for consistency.
-	// TODO(gvisor.dev/issue/1967)
+	// oomScoreAdj is the thread group's OOM score adjustment.
 	//
 	// oomScoreAdj is accessed using atomic memory operations.
 	oomScoreAdj int32
+
+	// oomScoreMu protects oomScore and oomScoreTime.
+	oomScoreMu sync.Mutex `state:"nosave"`
+
+	// oomScore is the thread group's most recently computed OOM score, as
+	// reported by /proc/[pid]/oom_score.
+	oomScore int64 `state:"nosave"`
+
+	// oomScoreTime is the time at which oomScore was computed, or the zero
+	// Time if it must be recomputed.
+	oomScoreTime ktime.Time `state:"nosave"`
 }
 
 // NewThreadGroup returns a new, empty thread group in PID namespace pidns. The
@@ -302,6 +321,21 @@ func (tg *ThreadGroup) SignalHandlers() *SignalHandlers {
 	return tg.signalHandlers
 }
 
+// SetChildSubreaper marks tg as a "child subreaper" if isSubreaper is true,
+// and unmarks it otherwise.
+func (tg *ThreadGroup) SetChildSubreaper(isSubreaper bool) {
+	tg.pidns.owner.mu.Lock()
+	defer tg.pidns.owner.mu.Unlock()
+	tg.isChildSubreaper = isSubreaper
+}
+
+// IsChildSubreaper returns whether tg is marked as a "child subreaper".
+func (tg *ThreadGroup) IsChildSubreaper() bool {
+	tg.pidns.owner.mu.RLock()
+	defer tg.pidns.owner.mu.RUnlock()
+	return tg.isChildSubreaper
+}
+
 // Limits returns tg's limits.
 func (tg *ThreadGroup) Limits() *limits.LimitSet {
 	return tg.limits
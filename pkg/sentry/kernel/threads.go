@@ -148,6 +148,9 @@ type PIDNamespace struct {
 	// appropriate capabilities in userns. The userns pointer is immutable.
 	userns *auth.UserNamespace
 
+	// inode is the namespace's inode number. inode is immutable.
+	inode uint64
+
 	// The following fields are protected by owner.mu.
 
 	// last is the last ThreadID to be allocated in this namespace.
@@ -194,6 +197,7 @@ func newPIDNamespace(ts *TaskSet, parent *PIDNamespace, userns *auth.UserNamespa
 		owner:         ts,
 		parent:        parent,
 		userns:        userns,
+		inode:         auth.NewNamespaceInode(),
 		tasks:         make(map[ThreadID]*Task),
 		tids:          make(map[*Task]ThreadID),
 		tgids:         make(map[*ThreadGroup]ThreadID),
@@ -216,6 +220,16 @@ func (ns *PIDNamespace) NewChild(userns *auth.UserNamespace) *PIDNamespace {
 	return newPIDNamespace(ns.owner, ns, userns)
 }
 
+// Inode returns the inode number of this PID namespace.
+func (ns *PIDNamespace) Inode() uint64 {
+	return ns.inode
+}
+
+// afterLoad is invoked by stateify.
+func (ns *PIDNamespace) afterLoad() {
+	auth.ObserveNamespaceInode(ns.inode)
+}
+
 // TaskWithID returns the task with thread ID tid in PID namespace ns. If no
 // task has that TID, TaskWithID returns nil.
 func (ns *PIDNamespace) TaskWithID(tid ThreadID) *Task {
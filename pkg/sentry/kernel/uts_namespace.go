@@ -35,6 +35,9 @@ type UTSNamespace struct {
 	//
 	// userns is immutable.
 	userns *auth.UserNamespace
+
+	// inode is the namespace's inode number. inode is immutable.
+	inode uint64
 }
 
 // NewUTSNamespace creates a new UTS namespace.
@@ -43,6 +46,7 @@ func NewUTSNamespace(hostName, domainName string, userns *auth.UserNamespace) *U
 		hostName:   hostName,
 		domainName: domainName,
 		userns:     userns,
+		inode:      auth.NewNamespaceInode(),
 	}
 }
 
@@ -88,6 +92,16 @@ func (u *UTSNamespace) UserNamespace() *auth.UserNamespace {
 	return u.userns
 }
 
+// Inode returns the inode number of this UTS namespace.
+func (u *UTSNamespace) Inode() uint64 {
+	return u.inode
+}
+
+// afterLoad is invoked by stateify.
+func (u *UTSNamespace) afterLoad() {
+	auth.ObserveNamespaceInode(u.inode)
+}
+
 // Clone makes a copy of this UTS namespace, associating the given user
 // namespace.
 func (u *UTSNamespace) Clone(userns *auth.UserNamespace) *UTSNamespace {
@@ -97,5 +111,6 @@ func (u *UTSNamespace) Clone(userns *auth.UserNamespace) *UTSNamespace {
 		hostName:   u.hostName,
 		domainName: u.domainName,
 		userns:     userns,
+		inode:      auth.NewNamespaceInode(),
 	}
 }
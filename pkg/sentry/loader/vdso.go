@@ -373,7 +373,7 @@ func loadVDSO(ctx context.Context, m *mm.MemoryManager, v *VDSO, bin loadedELF)
 
 		perms := progFlagsAsPerms(phdr.Flags)
 		if perms != hostarch.Read {
-			if err := m.MProtect(segPage, uint64(segSize), perms, false); err != nil {
+			if err := m.MProtect(ctx, segPage, uint64(segSize), perms, false); err != nil {
 				ctx.Warningf("Unable to set PT_LOAD segment protections %+v at [%#x, %#x): %v", perms, segAddr, segEnd, err)
 				return 0, linuxerr.ENOEXEC
 			}
@@ -258,12 +258,27 @@ type MappingIdentity interface {
 	// InodeID returns the inode number shown in /proc/[pid]/maps.
 	InodeID() uint64
 
-	// Msync has the same semantics as fs.FileOperations.Fsync(ctx,
-	// int64(mr.Start), int64(mr.End-1), fs.SyncData).
-	// (fs.FileOperations.Fsync() takes an inclusive end, but mr.End is
-	// exclusive, hence mr.End-1.) It is defined rather than Fsync so that
-	// implementors don't need to depend on the fs package for fs.SyncType.
-	Msync(ctx context.Context, mr MappableRange) error
+	// Msync is called by msync(2) for shared mappings of mr.
+	//
+	// If opts.Sync is true, Msync has the same semantics as
+	// fs.FileOperations.Fsync(ctx, int64(mr.Start), int64(mr.End-1),
+	// fs.SyncData). (fs.FileOperations.Fsync() takes an inclusive end, but
+	// mr.End is exclusive, hence mr.End-1.) It is defined rather than Fsync
+	// so that implementors don't need to depend on the fs package for
+	// fs.SyncType.
+	//
+	// Otherwise, Msync may start writing back dirty data in mr, but need not
+	// wait for it to become durable.
+	Msync(ctx context.Context, mr MappableRange, opts MSyncOpts) error
+}
+
+// MSyncOpts holds options to MappingIdentity.Msync.
+type MSyncOpts struct {
+	// Sync has the semantics of MS_SYNC.
+	Sync bool
+
+	// Invalidate has the semantics of MS_INVALIDATE.
+	Invalidate bool
 }
 
 // MLockMode specifies the memory locking behavior of a memory mapping.
@@ -328,7 +343,9 @@ type MMapOpts struct {
 	Fixed bool
 
 	// Unmap specifies whether existing mappings in the range being mapped may
-	// be replaced. If Unmap is true, Fixed must be true.
+	// be replaced. If Unmap is true, Fixed must be true. If Fixed is true and
+	// Unmap is false, the mapping fails with EEXIST if the range overlaps an
+	// existing mapping (equivalent to Linux's MAP_FIXED_NOREPLACE).
 	Unmap bool
 
 	// If Map32Bit is true, all addresses in the created mapping must fit in a
@@ -206,6 +206,12 @@ func (mm *MemoryManager) mapASLocked(pseg pmaIterator, ar hostarch.AddrRange, pr
 			perms.Write = false
 		}
 		if perms.Any() { // MapFile precondition
+			if pma.private {
+				// Private pages may have been compressed by the MemoryFile.
+				if err := mm.mfp.MemoryFile().EnsureResident(pseg.fileRangeOf(pmaMapAR)); err != nil {
+					return err
+				}
+			}
 			if err := mm.as.MapFile(pmaMapAR.Start, pma.file, pseg.fileRangeOf(pmaMapAR), perms, precommit); err != nil {
 				return err
 			}
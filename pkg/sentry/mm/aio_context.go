@@ -295,7 +295,10 @@ func (m *aioMappable) InodeID() uint64 {
 }
 
 // Msync implements memmap.MappingIdentity.Msync.
-func (m *aioMappable) Msync(ctx context.Context, mr memmap.MappableRange) error {
+func (m *aioMappable) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
+	if !opts.Sync {
+		return nil
+	}
 	// Linux: aio_ring_fops.fsync == NULL
 	return linuxerr.EINVAL
 }
@@ -29,7 +29,7 @@ import (
 
 // NewMemoryManager returns a new MemoryManager with no mappings and 1 user.
 func NewMemoryManager(p platform.Platform, mfp pgalloc.MemoryFileProvider, sleepForActivation bool) *MemoryManager {
-	return &MemoryManager{
+	mm := &MemoryManager{
 		p:                  p,
 		mfp:                mfp,
 		haveASIO:           p.SupportsAddressSpaceIO(),
@@ -40,6 +40,8 @@ func NewMemoryManager(p platform.Platform, mfp pgalloc.MemoryFileProvider, sleep
 		aioManager:         aioManager{contexts: make(map[uint64]*AIOContext)},
 		sleepForActivation: sleepForActivation,
 	}
+	mfp.MemoryFile().RegisterCompressibleMemoryUser(mm)
+	return mm
 }
 
 // SetMmapLayout initializes mm's layout from the given arch.Context.
@@ -122,6 +124,9 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 		// above.
 	}
 
+	// mm2 will share private pages with mm, which may be compressible.
+	mm2.mfp.MemoryFile().RegisterCompressibleMemoryUser(mm2)
+
 	// Copy pmas. We have to lock mm.activeMu for writing to make existing
 	// private pmas copy-on-write. We also have to lock mm2.activeMu since
 	// after copying vmas above, memmap.Mappables may call mm2.Invalidate. We
@@ -263,6 +268,8 @@ func (mm *MemoryManager) DecUsers(ctx context.Context) {
 		exe.DecRef(ctx)
 	}
 
+	mm.mfp.MemoryFile().UnregisterCompressibleMemoryUser(mm)
+
 	mm.activeMu.Lock()
 	// Sanity check.
 	if atomic.LoadInt32(&mm.active) != 0 {
@@ -313,6 +313,12 @@ type vma struct {
 	// If hint is non-empty, it is a description of the vma printed in
 	// /proc/[pid]/maps. hint takes priority over id.MappedName().
 	hint string
+
+	// If anonName is non-empty, it is the name of this anonymous vma set by
+	// prctl(PR_SET_VMA_ANON_NAME), printed as "[anon:<anonName>]" in
+	// /proc/[pid]/maps if neither hint nor id apply. anonName is only set if
+	// mappable is nil.
+	anonName string
 }
 
 const (
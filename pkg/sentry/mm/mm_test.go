@@ -15,11 +15,15 @@
 package mm
 
 import (
+	"bytes"
+	"math"
+	"os"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/memutil"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
@@ -30,8 +34,11 @@ import (
 )
 
 func testMemoryManager(ctx context.Context) *MemoryManager {
+	return testMemoryManagerWithProvider(ctx, pgalloc.MemoryFileProviderFromContext(ctx))
+}
+
+func testMemoryManagerWithProvider(ctx context.Context, mfp pgalloc.MemoryFileProvider) *MemoryManager {
 	p := platform.FromContext(ctx)
-	mfp := pgalloc.MemoryFileProviderFromContext(ctx)
 	mm := NewMemoryManager(p, mfp, false)
 	mm.layout = arch.MmapLayout{
 		MinAddr:      p.MinUserAddress(),
@@ -109,7 +116,7 @@ func TestDataASUpdates(t *testing.T) {
 		t.Fatalf("dataAS believes %v bytes are mapped; %v bytes are actually mapped", mm.dataAS, realDataAS)
 	}
 
-	mm.MProtect(addr+hostarch.PageSize, hostarch.PageSize, hostarch.Read, false)
+	mm.MProtect(ctx, addr+hostarch.PageSize, hostarch.PageSize, hostarch.Read, false)
 	realDataAS = mm.realDataAS()
 	if mm.dataAS != realDataAS {
 		t.Fatalf("dataAS believes %v bytes are mapped; %v bytes are actually mapped", mm.dataAS, realDataAS)
@@ -139,6 +146,232 @@ func TestBrkDataLimitUpdates(t *testing.T) {
 	}
 }
 
+// TestDataLimit tests that RLIMIT_DATA limits all private data mappings.
+func TestDataLimit(t *testing.T) {
+	limitSet := limits.NewLimitSet()
+	limitSet.Set(limits.Data, limits.Limit{Cur: 2 * hostarch.PageSize, Max: 2 * hostarch.PageSize}, true /* privileged */)
+
+	ctx := contexttest.WithLimitSet(contexttest.Context(t), limitSet)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   2 * hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+
+	// Further private writable mappings exceed the limit.
+	if _, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	}); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MMap got err %v want ENOMEM", err)
+	}
+	if _, err := mm.MRemap(ctx, addr, 2*hostarch.PageSize, 3*hostarch.PageSize, MRemapOpts{
+		Move: MRemapMayMove,
+	}); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MRemap got err %v want ENOMEM", err)
+	}
+
+	// Read-only mappings aren't data, but can't be made writable.
+	roAddr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.Read,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	if err := mm.MProtect(ctx, roAddr, hostarch.PageSize, hostarch.ReadWrite, false); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MProtect got err %v want ENOMEM", err)
+	}
+
+	// Replacing an existing data mapping doesn't increase usage.
+	if _, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Addr:     addr,
+		Fixed:    true,
+		Unmap:    true,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	}); err != nil {
+		t.Errorf("MMap(MAP_FIXED) got err %v want nil", err)
+	}
+}
+
+// TestASLimit tests that RLIMIT_AS limits all mappings.
+func TestASLimit(t *testing.T) {
+	limitSet := limits.NewLimitSet()
+	ctx := contexttest.WithLimitSet(contexttest.Context(t), limitSet)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	// Lowering the limit below current usage only affects new mappings.
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   2 * hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.Read,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	limitSet.Set(limits.AS, limits.Limit{Cur: hostarch.PageSize, Max: hostarch.PageSize}, true /* privileged */)
+
+	if _, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.Read,
+		MaxPerms: hostarch.AnyAccess,
+	}); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MMap got err %v want ENOMEM", err)
+	}
+	if _, err := mm.MRemap(ctx, addr, 2*hostarch.PageSize, 3*hostarch.PageSize, MRemapOpts{
+		Move: MRemapMayMove,
+	}); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MRemap got err %v want ENOMEM", err)
+	}
+	mm.BrkSetup(ctx, 0x10000000)
+	oldBrk, _ := mm.Brk(ctx, 0)
+	if newBrk, err := mm.Brk(ctx, oldBrk+hostarch.PageSize); !linuxerr.Equals(linuxerr.ENOMEM, err) || newBrk != oldBrk {
+		t.Errorf("Brk got (%#x, %v) want (%#x, ENOMEM)", newBrk, err, oldBrk)
+	}
+
+	// Shrinking is still allowed.
+	if _, err := mm.MRemap(ctx, addr, 2*hostarch.PageSize, hostarch.PageSize, MRemapOpts{
+		Move: MRemapMayMove,
+	}); err != nil {
+		t.Errorf("MRemap got err %v want nil", err)
+	}
+}
+
+// TestMMapFixedNoReplace tests that fixed mappings without Unmap fail with
+// EEXIST if they overlap an existing mapping.
+func TestMMapFixedNoReplace(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:  hostarch.PageSize,
+		Private: true,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+
+	// Both exact and partial overlaps fail.
+	for _, ar := range []hostarch.AddrRange{
+		{addr, addr + hostarch.PageSize},
+		{addr - hostarch.PageSize, addr + hostarch.PageSize},
+	} {
+		if _, err := mm.MMap(ctx, memmap.MMapOpts{
+			Length:  ar.Length(),
+			Addr:    ar.Start,
+			Fixed:   true,
+			Private: true,
+		}); !linuxerr.Equals(linuxerr.EEXIST, err) {
+			t.Errorf("MMap(%v, MAP_FIXED_NOREPLACE) got err %v want EEXIST", ar, err)
+		}
+	}
+
+	want := addr - hostarch.PageSize
+	if got, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:  hostarch.PageSize,
+		Addr:    want,
+		Fixed:   true,
+		Private: true,
+	}); err != nil || got != want {
+		t.Errorf("MMap(%#x, MAP_FIXED_NOREPLACE) got (%#x, %v) want (%#x, nil)", want, got, err, want)
+	}
+}
+
+// TestSetVMAAnonName tests that naming part of an anonymous mapping splits
+// it, and that vmas with equal names merge again.
+func TestSetVMAAnonName(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   3 * hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+
+	if err := mm.SetVMAAnonName(addr+hostarch.PageSize, hostarch.PageSize, "a"); err != nil {
+		t.Fatalf("SetVMAAnonName got err %v want nil", err)
+	}
+	if got, want := mm.vmas.countSegments(), 3; got != want {
+		t.Errorf("Got %d vmas after naming middle page, want %d", got, want)
+	}
+	if got := mm.vmas.FindSegment(addr + hostarch.PageSize).ValuePtr().anonName; got != "a" {
+		t.Errorf("Got name %q, want %q", got, "a")
+	}
+
+	if err := mm.SetVMAAnonName(addr, 3*hostarch.PageSize, "a"); err != nil {
+		t.Fatalf("SetVMAAnonName got err %v want nil", err)
+	}
+	if got, want := mm.vmas.countSegments(), 1; got != want {
+		t.Errorf("Got %d vmas after naming all pages, want %d", got, want)
+	}
+
+	// Clearing the name of an unmapped range renames the mapped part.
+	if err := mm.SetVMAAnonName(addr, 4*hostarch.PageSize, ""); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("SetVMAAnonName got err %v want ENOMEM", err)
+	}
+	if got := mm.vmas.FindSegment(addr).ValuePtr().anonName; got != "" {
+		t.Errorf("Got name %q, want empty", got)
+	}
+
+	if err := mm.SetVMAAnonName(addr+1, hostarch.PageSize, "a"); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("SetVMAAnonName with unaligned address got err %v want EINVAL", err)
+	}
+}
+
+func TestMarkCold(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   2 * hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+
+	if err := mm.MarkCold(addr, 2*hostarch.PageSize); err != nil {
+		t.Errorf("MarkCold got err %v want nil", err)
+	}
+	if got, want := mm.vmas.countSegments(), 1; got != want {
+		t.Errorf("Got %d vmas after MarkCold, want %d", got, want)
+	}
+
+	if err := mm.MUnmap(ctx, addr, hostarch.PageSize); err != nil {
+		t.Fatalf("MUnmap got err %v want nil", err)
+	}
+	if err := mm.MarkCold(addr, 2*hostarch.PageSize); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("MarkCold of partially unmapped range got err %v want ENOMEM", err)
+	}
+}
+
 // TestIOAfterUnmap ensures that IO fails after unmap.
 func TestIOAfterUnmap(t *testing.T) {
 	ctx := contexttest.Context(t)
@@ -205,7 +438,7 @@ func TestIOAfterMProtect(t *testing.T) {
 		t.Errorf("CopyOut got %d want 1", n)
 	}
 
-	err = mm.MProtect(addr, hostarch.PageSize, hostarch.Read, false)
+	err = mm.MProtect(ctx, addr, hostarch.PageSize, hostarch.Read, false)
 	if err != nil {
 		t.Errorf("MProtect got err %v want nil", err)
 	}
@@ -273,3 +506,151 @@ func TestAIOLookupAfterDestroy(t *testing.T) {
 		t.Errorf("AIOContext found even after AIOContext manager is destroyed")
 	}
 }
+
+// testMemoryFileProvider is a pgalloc.MemoryFileProvider whose MemoryFile can
+// be replaced, as it is on restore.
+type testMemoryFileProvider struct {
+	mf *pgalloc.MemoryFile
+}
+
+// MemoryFile implements pgalloc.MemoryFileProvider.MemoryFile.
+func (p *testMemoryFileProvider) MemoryFile() *pgalloc.MemoryFile {
+	return p.mf
+}
+
+// newCompressingMemoryFile returns a MemoryFile with the compressed memory
+// tier enabled. Its compression watermark can't be reached, so its compressor
+// goroutine never runs a pass; tests call MemoryFile.Compress instead.
+func newCompressingMemoryFile(t *testing.T) *pgalloc.MemoryFile {
+	memfd, err := memutil.CreateMemFD("mm-test", 0)
+	if err != nil {
+		t.Fatalf("CreateMemFD failed: %v", err)
+	}
+	mf, err := pgalloc.NewMemoryFile(os.NewFile(uintptr(memfd), "mm-test"), pgalloc.MemoryFileOpts{
+		CompressionWatermark: math.MaxUint64,
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	t.Cleanup(mf.Destroy)
+	return mf
+}
+
+const compressTestLength = 4 * hostarch.PageSize
+
+// compressTestData is compressTestLength bytes of easily-compressed data.
+var compressTestData = bytes.Repeat([]byte("compressible"), compressTestLength/12+1)[:compressTestLength]
+
+// mapCompressTestData maps compressTestData into private anonymous memory in
+// mm, and returns its address.
+func mapCompressTestData(ctx context.Context, t *testing.T, mm *MemoryManager) hostarch.Addr {
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   compressTestLength,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	if _, err := mm.CopyOut(ctx, addr, compressTestData, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+	return addr
+}
+
+// compressAll runs the two passes of mf's compressor that are needed to
+// compress memory that is not accessed in between, and returns the number of
+// bytes that they compressed.
+func compressAll(ctx context.Context, mf *pgalloc.MemoryFile) uint64 {
+	before := pgalloc.CompressionStatistics().CompressedBytes
+	// The first pass marks memory idle and asks MemoryManagers to drop their
+	// mappings of it; the second compresses it.
+	mf.Compress(ctx, compressTestLength)
+	mf.Compress(ctx, compressTestLength)
+	return pgalloc.CompressionStatistics().CompressedBytes - before
+}
+
+// checkCompressTestData checks that mm maps compressTestData at addr.
+func checkCompressTestData(ctx context.Context, t *testing.T, mm *MemoryManager, addr hostarch.Addr) {
+	t.Helper()
+	got := make([]byte, compressTestLength)
+	if _, err := mm.CopyIn(ctx, addr, got, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if !bytes.Equal(got, compressTestData) {
+		t.Errorf("CopyIn got different data than was written")
+	}
+}
+
+// TestCompressedPageFault tests that accessing compressed private memory
+// through a MemoryManager decompresses it.
+func TestCompressedPageFault(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mfp := &testMemoryFileProvider{newCompressingMemoryFile(t)}
+	mm := testMemoryManagerWithProvider(ctx, mfp)
+	defer mm.DecUsers(ctx)
+
+	addr := mapCompressTestData(ctx, t, mm)
+	if got := compressAll(ctx, mfp.mf); got != compressTestLength {
+		t.Fatalf("compressed %d bytes, want %d", got, compressTestLength)
+	}
+
+	before := pgalloc.CompressionStatistics()
+	checkCompressTestData(ctx, t, mm, addr)
+	after := pgalloc.CompressionStatistics()
+	if got := before.CompressedBytes - after.CompressedBytes; got != compressTestLength {
+		t.Errorf("access decompressed %d bytes, want %d", got, compressTestLength)
+	}
+	if got := after.Faults - before.Faults; got != 1 {
+		t.Errorf("access caused %d decompression faults, want 1", got)
+	}
+}
+
+// TestCompressedPageSaveRestore tests that compressed private memory is saved
+// in uncompressed form, and that a MemoryManager can use and compress it
+// again after restore.
+func TestCompressedPageSaveRestore(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mfp := &testMemoryFileProvider{newCompressingMemoryFile(t)}
+	mm := testMemoryManagerWithProvider(ctx, mfp)
+	defer mm.DecUsers(ctx)
+
+	addr := mapCompressTestData(ctx, t, mm)
+	if got := compressAll(ctx, mfp.mf); got != compressTestLength {
+		t.Fatalf("compressed %d bytes, want %d", got, compressTestLength)
+	}
+
+	// Save the MemoryFile as Kernel.SaveTo does.
+	before := pgalloc.CompressionStatistics()
+	if err := mfp.mf.SuspendCompression(); err != nil {
+		t.Fatalf("SuspendCompression got err %v want nil", err)
+	}
+	if got := before.CompressedBytes - pgalloc.CompressionStatistics().CompressedBytes; got != compressTestLength {
+		t.Errorf("SuspendCompression decompressed %d bytes, want %d", got, compressTestLength)
+	}
+	// No memory may be compressed while compression is suspended.
+	if got := compressAll(ctx, mfp.mf); got != 0 {
+		t.Errorf("compressed %d bytes while compression was suspended, want 0", got)
+	}
+	var buf bytes.Buffer
+	err := mfp.mf.SaveTo(ctx, &buf)
+	mfp.mf.ResumeCompression()
+	if err != nil {
+		t.Fatalf("SaveTo got err %v want nil", err)
+	}
+
+	// Restore into a new MemoryFile, which mm picks up in afterLoad.
+	mfp.mf = newCompressingMemoryFile(t)
+	if err := mfp.mf.LoadFrom(ctx, &buf); err != nil {
+		t.Fatalf("LoadFrom got err %v want nil", err)
+	}
+	mm.afterLoad()
+	checkCompressTestData(ctx, t, mm, addr)
+
+	// The restored memory remains compressible.
+	if got := compressAll(ctx, mfp.mf); got != compressTestLength {
+		t.Errorf("compressed %d bytes after restore, want %d", got, compressTestLength)
+	}
+	checkCompressTestData(ctx, t, mm, addr)
+}
@@ -16,6 +16,7 @@ package mm
 
 import (
 	"fmt"
+	"sort"
 
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -242,6 +243,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					mm.addRSSLocked(allocAR)
 					mm.incPrivateRef(fr)
 					mf.IncRef(fr)
+					mf.MarkCompressible(fr)
 					pseg, pgap = mm.pmas.Insert(pgap, allocAR, pma{
 						file:           mf,
 						off:            fr.Start,
@@ -378,6 +380,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					oldpma.file.DecRef(pseg.fileRange())
 					mm.incPrivateRef(fr)
 					mf.IncRef(fr)
+					mf.MarkCompressible(fr)
 					oldpma.file = mf
 					oldpma.off = fr.Start
 					oldpma.translatePerms = hostarch.AnyAccess
@@ -927,6 +930,28 @@ func (mm *MemoryManager) decPrivateRef(fr memmap.FileRange) {
 	}
 }
 
+// InvalidateCompressible implements pgalloc.CompressibleMemoryUser.InvalidateCompressible.
+func (mm *MemoryManager) InvalidateCompressible(ctx context.Context, frs []memmap.FileRange) {
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		if !pma.private {
+			continue
+		}
+		pfr := pseg.fileRange()
+		i := sort.Search(len(frs), func(i int) bool { return frs[i].End > pfr.Start })
+		for ; i < len(frs) && frs[i].Start < pfr.End; i++ {
+			ifr := frs[i].Intersect(pfr)
+			mm.unmapASLocked(hostarch.AddrRange{
+				Start: pseg.Start() + hostarch.Addr(ifr.Start-pma.off),
+				End:   pseg.Start() + hostarch.Addr(ifr.End-pma.off),
+			})
+			pma.internalMappings = safemem.BlockSeq{}
+		}
+	}
+}
+
 // addRSSLocked updates the current and maximum resident set size of a
 // MemoryManager to reflect the insertion of a pma at ar.
 //
@@ -161,6 +161,8 @@ func (mm *MemoryManager) appendVMAMapsEntryLocked(ctx context.Context, vseg vmaI
 		// However, it's not clear that fs.File.MappedName() is actually
 		// consistent with this lock order.
 		s = vma.id.MappedName(ctx)
+	} else if vma.anonName != "" {
+		s = "[anon:" + vma.anonName + "]"
 	}
 	if s != "" {
 		// Per linux, we pad until the 74th character.
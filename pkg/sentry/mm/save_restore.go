@@ -54,4 +54,5 @@ func (mm *MemoryManager) afterLoad() {
 	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
 		pseg.ValuePtr().file = mf
 	}
+	mf.RegisterCompressibleMemoryUser(mm)
 }
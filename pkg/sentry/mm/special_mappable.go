@@ -71,7 +71,7 @@ func (m *SpecialMappable) InodeID() uint64 {
 }
 
 // Msync implements memmap.MappingIdentity.Msync.
-func (m *SpecialMappable) Msync(ctx context.Context, mr memmap.MappableRange) error {
+func (m *SpecialMappable) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
 	// Linux: vm_file is NULL, causing msync to skip it entirely.
 	return nil
 }
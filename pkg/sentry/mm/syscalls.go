@@ -119,6 +119,14 @@ func (mm *MemoryManager) MMap(ctx context.Context, opts memmap.MMapOpts) (hostar
 	if opts.MLockMode < mm.defMLockMode {
 		opts.MLockMode = mm.defMLockMode
 	}
+	// Fixed mappings that may not replace existing ones fail with EEXIST if
+	// they overlap an existing vma, as for Linux's MAP_FIXED_NOREPLACE.
+	if opts.Fixed && !opts.Unmap {
+		if ar, ok := opts.Addr.ToRange(opts.Length); ok && !mm.vmas.IsEmptyRange(ar) {
+			mm.mappingMu.Unlock()
+			return 0, linuxerr.EEXIST
+		}
+	}
 	vseg, ar, err := mm.createVMALocked(ctx, opts)
 	if err != nil {
 		mm.mappingMu.Unlock()
@@ -411,6 +419,9 @@ func (mm *MemoryManager) MRemap(ctx context.Context, oldAddr hostarch.Addr, oldS
 		if vma.mappable != nil {
 			newOffset = vseg.mappableRange().End
 		}
+		// vma is invalidated by createVMALocked.
+		mlockMode := vma.mlockMode
+		anonName := vma.anonName
 		vseg, ar, err := mm.createVMALocked(ctx, memmap.MMapOpts{
 			Length:          newSize - oldSize,
 			MappingIdentity: vma.id,
@@ -426,7 +437,15 @@ func (mm *MemoryManager) MRemap(ctx context.Context, oldAddr hostarch.Addr, oldS
 			Hint:            vma.hint,
 		})
 		if err == nil {
-			if vma.mlockMode == memmap.MLockEager {
+			// createVMALocked doesn't know about anonName; apply it to the
+			// new vma so that it can merge with the old one.
+			if anonName != "" {
+				vseg = mm.vmas.Isolate(vseg, ar)
+				vseg.ValuePtr().anonName = anonName
+				mm.vmas.MergeAdjacent(ar)
+				vseg = mm.vmas.FindSegment(ar.Start)
+			}
+			if mlockMode == memmap.MLockEager {
 				mm.populateVMA(ctx, vseg, ar, true)
 			}
 			return oldAddr, nil
@@ -502,6 +521,14 @@ func (mm *MemoryManager) MRemap(ctx context.Context, oldAddr hostarch.Addr, oldS
 		return 0, linuxerr.ENOMEM
 	}
 
+	// Check against RLIMIT_DATA.
+	if vseg.ValuePtr().isPrivateDataLocked() {
+		newDataAS := mm.dataAS - uint64(oldAR.Length()) + uint64(newAR.Length())
+		if limitData := limits.FromContext(ctx).Get(limits.Data).Cur; newDataAS > limitData {
+			return 0, linuxerr.ENOMEM
+		}
+	}
+
 	if vma := vseg.ValuePtr(); vma.mappable != nil {
 		// Check that offset+length does not overflow.
 		if vma.off+uint64(newAR.Length()) < vma.off {
@@ -589,7 +616,7 @@ func (mm *MemoryManager) MRemap(ctx context.Context, oldAddr hostarch.Addr, oldS
 }
 
 // MProtect implements the semantics of Linux's mprotect(2).
-func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms hostarch.AccessType, growsDown bool) error {
+func (mm *MemoryManager) MProtect(ctx context.Context, addr hostarch.Addr, length uint64, realPerms hostarch.AccessType, growsDown bool) error {
 	if addr.RoundDown() != addr {
 		return linuxerr.EINVAL
 	}
@@ -641,6 +668,7 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 	}()
 	pseg := mm.pmas.LowerBoundSegment(ar.Start)
 	var didUnmapAS bool
+	limitData := limits.FromContext(ctx).Get(limits.Data).Cur
 	for {
 		// Check for permission validity before splitting vmas, for consistency
 		// with Linux.
@@ -654,6 +682,10 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 		vmaLength := vseg.Range().Length()
 		if vma.isPrivateDataLocked() {
 			mm.dataAS -= uint64(vmaLength)
+		} else if isPrivateData(realPerms, vma.private, vma.growsDown) && mm.dataAS+uint64(vmaLength) > limitData {
+			// The vma would become a data segment exceeding RLIMIT_DATA.
+			// Compare Linux's mm/mprotect.c:mprotect_fixup().
+			return linuxerr.ENOMEM
 		}
 
 		vma.realPerms = realPerms
@@ -715,11 +747,10 @@ func (mm *MemoryManager) Brk(ctx context.Context, addr hostarch.Addr) (hostarch.
 		return addr, linuxerr.EINVAL
 	}
 
-	// TODO(gvisor.dev/issue/156): This enforces RLIMIT_DATA, but is
-	// slightly more permissive than the usual data limit. In particular,
-	// this only limits the size of the heap; a true RLIMIT_DATA limits the
-	// size of heap + data + bss. The segment sizes need to be plumbed from
-	// the loader package to fully enforce RLIMIT_DATA.
+	// This limits the size of the heap, whereas Linux's brk() limits the size
+	// of heap + data + bss. Growing the heap is also subject to the limit on
+	// all private data mappings, enforced by createVMALocked, which includes
+	// data and bss.
 	if uint64(addr-mm.brk.Start) > limits.FromContext(ctx).Get(limits.Data).Cur {
 		addr = mm.brk.End
 		mm.mappingMu.Unlock()
@@ -1044,6 +1075,47 @@ func (mm *MemoryManager) SetDontFork(addr hostarch.Addr, length uint64, dontfork
 	return nil
 }
 
+// SetVMAAnonName implements the semantics of Linux's prctl(PR_SET_VMA,
+// PR_SET_VMA_ANON_NAME). If name is empty, existing names are cleared.
+func (mm *MemoryManager) SetVMAAnonName(addr hostarch.Addr, length uint64, name string) error {
+	if addr.RoundDown() != addr {
+		return linuxerr.EINVAL
+	}
+	la, ok := hostarch.Addr(length).RoundUp()
+	if !ok {
+		return linuxerr.EINVAL
+	}
+	ar, ok := addr.ToRange(uint64(la))
+	if !ok {
+		return linuxerr.EINVAL
+	}
+	if ar.Length() == 0 {
+		return nil
+	}
+
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+	defer func() {
+		mm.vmas.MergeRange(ar)
+		mm.vmas.MergeAdjacent(ar)
+	}()
+
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		// Only anonymous mappings can be named. As in Linux, vmas preceding
+		// the first file-backed one are still renamed.
+		if vseg.ValuePtr().mappable != nil {
+			return linuxerr.EBADF
+		}
+		vseg = mm.vmas.Isolate(vseg, ar)
+		vseg.ValuePtr().anonName = name
+	}
+
+	if mm.vmas.SpanRange(ar) != ar.Length() {
+		return linuxerr.ENOMEM
+	}
+	return nil
+}
+
 // Decommit implements the semantics of Linux's madvise(MADV_DONTNEED).
 func (mm *MemoryManager) Decommit(addr hostarch.Addr, length uint64) error {
 	ar, ok := addr.ToRange(length)
@@ -1103,17 +1175,34 @@ func (mm *MemoryManager) Decommit(addr hostarch.Addr, length uint64) error {
 	return nil
 }
 
-// MSyncOpts holds options to MSync.
-type MSyncOpts struct {
-	// Sync has the semantics of MS_SYNC.
-	Sync bool
+// MarkCold implements the semantics of Linux's madvise(MADV_COLD) and
+// madvise(MADV_PAGEOUT). The sentry has no page LRU or swap, so these are
+// only hints; however, as in Linux, mlocked vmas are rejected and unmapped
+// addresses are reported.
+func (mm *MemoryManager) MarkCold(addr hostarch.Addr, length uint64) error {
+	ar, ok := addr.ToRange(length)
+	if !ok {
+		return linuxerr.EINVAL
+	}
+
+	mm.mappingMu.RLock()
+	defer mm.mappingMu.RUnlock()
+
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		// Compare Linux's mm/madvise.c:can_madv_lru_vma().
+		if vseg.ValuePtr().mlockMode != memmap.MLockNone {
+			return linuxerr.EINVAL
+		}
+	}
 
-	// Invalidate has the semantics of MS_INVALIDATE.
-	Invalidate bool
+	if mm.vmas.SpanRange(ar) != ar.Length() {
+		return linuxerr.ENOMEM
+	}
+	return nil
 }
 
 // MSync implements the semantics of Linux's msync().
-func (mm *MemoryManager) MSync(ctx context.Context, addr hostarch.Addr, length uint64, opts MSyncOpts) error {
+func (mm *MemoryManager) MSync(ctx context.Context, addr hostarch.Addr, length uint64, opts memmap.MSyncOpts) error {
 	if addr != addr.RoundDown() {
 		return linuxerr.EINVAL
 	}
@@ -1156,14 +1245,14 @@ func (mm *MemoryManager) MSync(ctx context.Context, addr hostarch.Addr, length u
 		// It's only possible to have dirtied the Mappable through a shared
 		// mapping. Don't check if the mapping is writable, because mprotect
 		// may have changed this, and also because Linux doesn't.
-		if id := vma.id; opts.Sync && id != nil && vma.mappable != nil && !vma.private {
+		if id := vma.id; id != nil && vma.mappable != nil && !vma.private {
 			// We can't call memmap.MappingIdentity.Msync while holding
 			// mm.mappingMu since it may take fs locks that precede it in the
 			// lock order.
 			id.IncRef()
 			mr := vseg.mappableRangeOf(vseg.Range().Intersect(ar))
 			mm.mappingMu.RUnlock()
-			err := id.Msync(ctx, mr)
+			err := id.Msync(ctx, mr, opts)
 			id.DecRef(ctx)
 			if err != nil {
 				return err
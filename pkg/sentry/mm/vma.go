@@ -61,6 +61,19 @@ func (mm *MemoryManager) createVMALocked(ctx context.Context, opts memmap.MMapOp
 		return vmaIterator{}, hostarch.AddrRange{}, linuxerr.ENOMEM
 	}
 
+	// Check against RLIMIT_DATA, which applies to all private data mappings
+	// rather than just the heap since Linux 4.7. Compare Linux's
+	// mm/mmap.c:may_expand_vm().
+	if isPrivateData(opts.Perms, opts.Private, opts.GrowsDown) {
+		newDataAS := mm.dataAS + opts.Length
+		if opts.Unmap {
+			newDataAS -= mm.privateDataBytesRangeLocked(ar)
+		}
+		if limitData := limits.FromContext(ctx).Get(limits.Data).Cur; newDataAS > limitData {
+			return vmaIterator{}, hostarch.AddrRange{}, linuxerr.ENOMEM
+		}
+	}
+
 	if opts.MLockMode != memmap.MLockNone {
 		// Check against RLIMIT_MEMLOCK.
 		if creds := auth.CredentialsFromContext(ctx); !creds.HasCapabilityIn(linux.CAP_IPC_LOCK, creds.UserNamespace.Root()) {
@@ -249,6 +262,20 @@ func (mm *MemoryManager) mlockedBytesRangeLocked(ar hostarch.AddrRange) uint64 {
 	return total
 }
 
+// privateDataBytesRangeLocked returns the number of bytes in ar that belong to
+// private data vmas.
+//
+// Preconditions: mm.mappingMu must be locked (for reading or writing).
+func (mm *MemoryManager) privateDataBytesRangeLocked(ar hostarch.AddrRange) uint64 {
+	var total uint64
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		if vseg.ValuePtr().isPrivateDataLocked() {
+			total += uint64(vseg.Range().Intersect(ar).Length())
+		}
+	}
+	return total
+}
+
 // getVMAsLocked ensures that vmas exist for all addresses in ar, and support
 // access of type (at, ignorePermissions). It returns:
 //
@@ -420,7 +447,13 @@ func (vma *vma) canWriteMappableLocked() bool {
 //
 // Preconditions: mm.mappingMu must be locked.
 func (vma *vma) isPrivateDataLocked() bool {
-	return vma.realPerms.Write && vma.private && !vma.growsDown
+	return isPrivateData(vma.realPerms, vma.private, vma.growsDown)
+}
+
+// isPrivateData returns true if a vma with the given properties is a data
+// segment. It is equivalent to Linux's mm/internal.h:is_data_mapping().
+func isPrivateData(realPerms hostarch.AccessType, private, growsDown bool) bool {
+	return realPerms.Write && private && !growsDown
 }
 
 // vmaSetFunctions implements segment.Functions for vmaSet.
@@ -438,6 +471,7 @@ func (vmaSetFunctions) ClearValue(vma *vma) {
 	vma.mappable = nil
 	vma.id = nil
 	vma.hint = ""
+	vma.anonName = ""
 }
 
 func (vmaSetFunctions) Merge(ar1 hostarch.AddrRange, vma1 vma, ar2 hostarch.AddrRange, vma2 vma) (vma, bool) {
@@ -452,7 +486,8 @@ func (vmaSetFunctions) Merge(ar1 hostarch.AddrRange, vma1 vma, ar2 hostarch.Addr
 		vma1.numaNodemask != vma2.numaNodemask ||
 		vma1.dontfork != vma2.dontfork ||
 		vma1.id != vma2.id ||
-		vma1.hint != vma2.hint {
+		vma1.hint != vma2.hint ||
+		vma1.anonName != vma2.anonName {
 		return vma{}, false
 	}
 
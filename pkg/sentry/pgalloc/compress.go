@@ -0,0 +1,575 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+// The compressed memory tier is analogous to Linux's zram swap: when
+// MemoryFileOpts.CompressionWatermark is non-zero and the MemoryFile's usage
+// exceeds it, the compressor goroutine copies the contents of cold pages into
+// compressed form, stored in separately-allocated pages of the same file, and
+// decommits the originals. The originals remain allocated, so the only
+// difference visible to their users is that they must call
+// MemoryFile.EnsureResident before accessing them other than through
+// MemoryFile.MapInternal.
+//
+// Only pages marked by MemoryFile.MarkCompressible are eligible for
+// compression. No platform reports page accesses to the sentry, so coldness
+// is detected by sampling: each pass of the compressor marks some eligible
+// pages idle and asks all CompressibleMemoryUsers to drop their mappings of
+// them, such that the next access to an idle page goes through
+// EnsureResident, which clears the mark. Pages that are still idle on the
+// next pass are compressed.
+//
+// Compressed data is never saved. Kernel.SaveTo calls SuspendCompression
+// before saving any state, which waits for an ongoing pass of the compressor
+// (including its calls to CompressibleMemoryUsers, whose state is about to be
+// saved) to finish, decompresses all compressed memory, and stops further
+// passes until ResumeCompression. SaveTo and LoadFrom also suspend
+// compression themselves, so that they never observe the compressor
+// mutating f.usage. Since f.compressed is not saved, a restored MemoryFile
+// starts with no idle or compressed pages; pages that were marked
+// compressible remain so, since usageInfo.compressibleRefs is saved with
+// f.usage.
+
+const (
+	// compressInterval is the period of the compressor goroutine.
+	compressInterval = time.Second
+
+	// compressChunkSize is the maximum length of a range that is compressed
+	// as a unit. Since compressed ranges are decompressed in their entirety,
+	// this bounds the cost of an access to compressed memory.
+	compressChunkSize = 16 * hostarch.PageSize
+)
+
+var (
+	// compressedBytes is the number of bytes of memory currently stored in
+	// compressed form, and compressedStoredBytes is the number of bytes used
+	// to store them. Both are accessed using atomic memory operations and
+	// aggregated over all MemoryFiles, like usage.MemoryAccounting.
+	compressedBytes       uint64
+	compressedStoredBytes uint64
+
+	compressionFaults = metric.MustCreateNewUint64Metric("/memory/compression/faults", false /* sync */, "Number of accesses to memory that required decompression.")
+)
+
+func init() {
+	metric.MustRegisterCustomUint64Metric("/memory/compression/compressed_bytes", false /* cumulative */, false /* sync */, "Bytes of memory currently stored in compressed form.", func(...string) uint64 {
+		return atomic.LoadUint64(&compressedBytes)
+	})
+	metric.MustRegisterCustomUint64Metric("/memory/compression/stored_bytes", false /* cumulative */, false /* sync */, "Bytes of memory used to store compressed memory.", func(...string) uint64 {
+		return atomic.LoadUint64(&compressedStoredBytes)
+	})
+	metric.MustRegisterCustomUint64Metric("/memory/compression/ratio_percent", false /* cumulative */, false /* sync */, "Size of compressed memory as a percentage of its uncompressed size.", func(...string) uint64 {
+		return CompressionStatistics().RatioPercent()
+	})
+}
+
+// CompressionStats contains statistics for the compressed memory tier.
+type CompressionStats struct {
+	// CompressedBytes is the number of bytes of memory currently stored in
+	// compressed form.
+	CompressedBytes uint64
+
+	// StoredBytes is the number of bytes used to store CompressedBytes.
+	StoredBytes uint64
+
+	// Faults is the number of accesses to memory that required
+	// decompression.
+	Faults uint64
+}
+
+// RatioPercent returns StoredBytes as a percentage of CompressedBytes, or 0 if
+// no memory is compressed.
+func (s CompressionStats) RatioPercent() uint64 {
+	if s.CompressedBytes == 0 {
+		return 0
+	}
+	return s.StoredBytes * 100 / s.CompressedBytes
+}
+
+// CompressionStatistics returns statistics for the compressed memory tier,
+// aggregated over all MemoryFiles.
+func CompressionStatistics() CompressionStats {
+	return CompressionStats{
+		CompressedBytes: atomic.LoadUint64(&compressedBytes),
+		StoredBytes:     atomic.LoadUint64(&compressedStoredBytes),
+		Faults:          compressionFaults.Value(),
+	}
+}
+
+// FakeSwap returns the number of bytes of memory compressed by f, and true if
+// f was created with MemoryFileOpts.FakeSwap, such that compressed memory
+// should be presented to applications as swap.
+func (f *MemoryFile) FakeSwap() (uint64, bool) {
+	if !f.opts.FakeSwap {
+		return 0, false
+	}
+	return atomic.LoadUint64(&compressedBytes), true
+}
+
+// A CompressibleMemoryUser represents a user of MemoryFile-allocated memory
+// that has marked some of that memory compressible using
+// MemoryFile.MarkCompressible.
+type CompressibleMemoryUser interface {
+	// InvalidateCompressible requests that the CompressibleMemoryUser drop
+	// all mappings of the given ranges of the MemoryFile, including internal
+	// mappings returned by MemoryFile.MapInternal, such that its next access
+	// to each range calls MemoryFile.EnsureResident or MemoryFile.MapInternal.
+	// frs is sorted and non-overlapping.
+	//
+	// InvalidateCompressible is called without holding any MemoryFile locks.
+	InvalidateCompressible(ctx context.Context, frs []memmap.FileRange)
+}
+
+// compressSetValue is the value type of compressSet.
+type compressSetValue struct {
+	// blob is the range of the MemoryFile storing the compressed contents of
+	// the segment. If blob is empty, the segment is idle but not yet
+	// compressed.
+	blob memmap.FileRange
+
+	// size is the number of bytes of compressed data at the start of blob.
+	size uint64
+}
+
+type compressSetFunctions struct{}
+
+func (compressSetFunctions) MinKey() uint64 {
+	return 0
+}
+
+func (compressSetFunctions) MaxKey() uint64 {
+	return math.MaxUint64
+}
+
+func (compressSetFunctions) ClearValue(val *compressSetValue) {
+}
+
+func (compressSetFunctions) Merge(_ memmap.FileRange, val1 compressSetValue, _ memmap.FileRange, val2 compressSetValue) (compressSetValue, bool) {
+	// Idle ranges can be merged, but compressed ranges can only be
+	// decompressed as a unit.
+	return val1, val1.blob.Length() == 0 && val2.blob.Length() == 0
+}
+
+func (compressSetFunctions) Split(r memmap.FileRange, val compressSetValue, _ uint64) (compressSetValue, compressSetValue) {
+	if val.blob.Length() != 0 {
+		panic(fmt.Sprintf("compressed range %v cannot be split", r))
+	}
+	return val, val
+}
+
+// compressible returns true if the tracked region may be compressed.
+func (u *usageInfo) compressible() bool {
+	return u.compressibleRefs != 0 && u.refs == u.compressibleRefs
+}
+
+// RegisterCompressibleMemoryUser informs f that user may own compressible
+// memory. Memory marked compressible by MarkCompressible may be compressed
+// only if every user that may access it is registered.
+func (f *MemoryFile) RegisterCompressibleMemoryUser(user CompressibleMemoryUser) {
+	if f.opts.CompressionWatermark == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compressUsers[user] = struct{}{}
+}
+
+// UnregisterCompressibleMemoryUser reverses the effect of a previous call to
+// RegisterCompressibleMemoryUser.
+func (f *MemoryFile) UnregisterCompressibleMemoryUser(user CompressibleMemoryUser) {
+	if f.opts.CompressionWatermark == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.compressUsers, user)
+}
+
+// MarkCompressible allows f to compress the contents of pages in fr for as
+// long as their reference count remains at its current value; taking
+// additional references, e.g. to pin the pages, makes them incompressible
+// until those references are dropped. Users that access pages in fr must be
+// registered by RegisterCompressibleMemoryUser.
+//
+// Preconditions: fr must be allocated.
+func (f *MemoryFile) MarkCompressible(fr memmap.FileRange) {
+	if f.opts.CompressionWatermark == 0 {
+		return
+	}
+	if !fr.WellFormed() || fr.Length() == 0 || fr.Start%hostarch.PageSize != 0 || fr.End%hostarch.PageSize != 0 {
+		panic(fmt.Sprintf("invalid range: %v", fr))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	gap := f.usage.ApplyContiguous(fr, func(seg usageIterator) {
+		val := seg.ValuePtr()
+		val.compressibleRefs = val.refs
+	})
+	if gap.Ok() {
+		panic(fmt.Sprintf("MarkCompressible(%v): attempted to mark unallocated pages %v:\n%v", fr, gap.Range(), &f.usage))
+	}
+	f.usage.MergeAdjacent(fr)
+}
+
+// EnsureResident ensures that no page in fr is stored in compressed form, and
+// that no page in fr will be compressed until it is next invalidated by
+// CompressibleMemoryUser.InvalidateCompressible. Users of compressible memory
+// must call EnsureResident before mapping it other than through MapInternal,
+// which calls EnsureResident itself.
+func (f *MemoryFile) EnsureResident(fr memmap.FileRange) error {
+	if atomic.LoadUint32(&f.haveCompressed) == 0 {
+		return nil
+	}
+	f.compressMu.Lock()
+	defer f.compressMu.Unlock()
+	defer f.updateHaveCompressedLocked()
+	seg := f.compressed.LowerBoundSegment(fr.Start)
+	for seg.Ok() && seg.Start() < fr.End {
+		if seg.Value().blob.Length() != 0 {
+			if err := f.decompressLocked(seg); err != nil {
+				return err
+			}
+			compressionFaults.Increment()
+		}
+		seg = f.compressed.Remove(seg).NextSegment()
+	}
+	return nil
+}
+
+// forgetCompressed stops tracking pages in fr, which are being reclaimed.
+func (f *MemoryFile) forgetCompressed(fr memmap.FileRange) {
+	if atomic.LoadUint32(&f.haveCompressed) == 0 {
+		return
+	}
+	f.compressMu.Lock()
+	defer f.compressMu.Unlock()
+	defer f.updateHaveCompressedLocked()
+	seg := f.compressed.LowerBoundSegment(fr.Start)
+	for seg.Ok() && seg.Start() < fr.End {
+		if seg.Value().blob.Length() != 0 {
+			if fr.IsSupersetOf(seg.Range()) {
+				f.dropBlobLocked(seg)
+			} else if err := f.decompressLocked(seg); err != nil {
+				// The remainder of the range is still in use.
+				panic(fmt.Sprintf("failed to decompress %v: %v", seg.Range(), err))
+			}
+		}
+		seg = f.compressed.Remove(seg).NextSegment()
+	}
+}
+
+// SuspendCompression waits for any ongoing pass of the compressor to finish,
+// decompresses all compressed memory in f, and prevents further compression
+// until a matching call to ResumeCompression. The caller must call
+// ResumeCompression even if SuspendCompression returns an error.
+func (f *MemoryFile) SuspendCompression() error {
+	f.compressPassMu.Lock()
+	defer f.compressPassMu.Unlock()
+	f.compressMu.Lock()
+	defer f.compressMu.Unlock()
+	defer f.updateHaveCompressedLocked()
+	f.compressSuspended++
+	for seg := f.compressed.FirstSegment(); seg.Ok(); seg = f.compressed.Remove(seg).NextSegment() {
+		if seg.Value().blob.Length() != 0 {
+			if err := f.decompressLocked(seg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ResumeCompression reverses the effect of a previous call to
+// SuspendCompression.
+func (f *MemoryFile) ResumeCompression() {
+	f.compressPassMu.Lock()
+	defer f.compressPassMu.Unlock()
+	f.compressMu.Lock()
+	defer f.compressMu.Unlock()
+	f.compressSuspended--
+}
+
+// Preconditions: f.compressMu must be locked.
+func (f *MemoryFile) updateHaveCompressedLocked() {
+	if f.compressed.IsEmpty() {
+		atomic.StoreUint32(&f.haveCompressed, 0)
+	} else {
+		atomic.StoreUint32(&f.haveCompressed, 1)
+	}
+}
+
+// runCompressor implements the compressor goroutine.
+func (f *MemoryFile) runCompressor() {
+	ctx := context.Background()
+	for {
+		time.Sleep(compressInterval)
+		f.mu.Lock()
+		destroyed := f.destroyed
+		f.mu.Unlock()
+		if destroyed {
+			return
+		}
+		cur, err := f.TotalUsage()
+		if err != nil {
+			log.Warningf("Compressor failed to get memory usage: %v", err)
+			continue
+		}
+		if cur <= f.opts.CompressionWatermark {
+			continue
+		}
+		f.Compress(ctx, cur-f.opts.CompressionWatermark)
+	}
+}
+
+// Compress runs one pass of the compressor: it compresses up to want bytes of
+// memory that was marked idle by previous passes, then marks enough memory
+// idle for the next pass to do the same. The compressor goroutine calls
+// Compress once per compressInterval while f's usage exceeds
+// MemoryFileOpts.CompressionWatermark.
+//
+// Compress does nothing if f was not created with a non-zero
+// CompressionWatermark, or while compression is suspended.
+func (f *MemoryFile) Compress(ctx context.Context, want uint64) {
+	if f.opts.CompressionWatermark == 0 {
+		return
+	}
+	f.compressPassMu.Lock()
+	defer f.compressPassMu.Unlock()
+	if f.compressSuspended != 0 {
+		return
+	}
+	if f.compressWriter == nil {
+		w, err := flate.NewWriter(io.Discard, flate.BestSpeed)
+		if err != nil {
+			panic(fmt.Sprintf("failed to create flate.Writer: %v", err))
+		}
+		f.compressWriter = w
+	}
+	f.compressPassLocked(ctx, want)
+}
+
+// compressPassLocked implements Compress.
+//
+// Preconditions: f.compressPassMu must be locked. Compression must not be
+// suspended.
+func (f *MemoryFile) compressPassLocked(ctx context.Context, want uint64) {
+	var frs []memmap.FileRange
+	f.compressMu.Lock()
+	var done uint64
+	seg := f.compressed.FirstSegment()
+	for seg.Ok() && done < want {
+		if seg.Value().blob.Length() != 0 {
+			seg = seg.NextSegment()
+			continue
+		}
+		if fr := seg.Range(); fr.Length() > compressChunkSize {
+			seg = f.compressed.Isolate(seg, memmap.FileRange{fr.Start, fr.Start + compressChunkSize})
+		}
+		if !f.compressLocked(f.compressWriter, seg) {
+			seg = f.compressed.Remove(seg).NextSegment()
+			continue
+		}
+		done += seg.Range().Length()
+		seg = seg.NextSegment()
+	}
+	if done < want {
+		// Sample more than we need, since some of the sampled memory will be
+		// accessed before the next pass.
+		budget, ok := hostarch.Addr(2 * (want - done)).RoundUp()
+		if !ok {
+			budget = hostarch.Addr(maxPage)
+		}
+		frs = f.markIdleLocked(uint64(budget))
+	}
+	f.updateHaveCompressedLocked()
+	f.compressMu.Unlock()
+
+	if len(frs) == 0 {
+		return
+	}
+	f.mu.Lock()
+	users := make([]CompressibleMemoryUser, 0, len(f.compressUsers))
+	for user := range f.compressUsers {
+		users = append(users, user)
+	}
+	f.mu.Unlock()
+	// Invalidation must happen before the next pass can compress frs, which
+	// is guaranteed by holding f.compressPassMu.
+	for _, user := range users {
+		user.InvalidateCompressible(ctx, frs)
+	}
+}
+
+// markIdleLocked marks up to budget bytes of compressible memory idle, and
+// returns the newly-idle ranges. It prefers memory at the start of the file,
+// since Allocate works from the end of the file inwards, so the start of the
+// file is likely to hold the oldest allocations.
+//
+// Preconditions: f.compressMu must be locked. budget must be page-aligned.
+func (f *MemoryFile) markIdleLocked(budget uint64) []memmap.FileRange {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var frs []memmap.FileRange
+	for seg := f.usage.FirstSegment(); seg.Ok() && budget > 0; seg = seg.NextSegment() {
+		if !seg.ValuePtr().compressible() {
+			continue
+		}
+		fr := seg.Range()
+		gap := f.compressed.LowerBoundGap(fr.Start)
+		for gap.Ok() && gap.Start() < fr.End && budget > 0 {
+			gfr := gap.Range().Intersect(fr)
+			if gfr.Length() == 0 {
+				gap = gap.NextGap()
+				continue
+			}
+			if gfr.Length() > budget {
+				gfr.End = gfr.Start + budget
+			}
+			gap = f.compressed.Insert(gap, gfr, compressSetValue{}).NextGap()
+			frs = append(frs, gfr)
+			budget -= gfr.Length()
+		}
+	}
+	return frs
+}
+
+// compressLocked compresses the idle range represented by seg, and returns
+// true if it succeeds.
+//
+// Preconditions: f.compressMu must be locked. seg must be idle.
+func (f *MemoryFile) compressLocked(w *flate.Writer, seg compressIterator) bool {
+	fr := seg.Range()
+
+	// Ensure that no references have been taken on fr since it was marked
+	// compressible. References taken after this point can only be used to
+	// access fr after calling EnsureResident, which is blocked by
+	// f.compressMu.
+	f.mu.Lock()
+	ok := true
+	for pos := fr.Start; pos < fr.End; {
+		useg := f.usage.FindSegment(pos)
+		if !useg.Ok() || !useg.ValuePtr().compressible() {
+			ok = false
+			break
+		}
+		pos = useg.End()
+	}
+	f.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	var werr error
+	if err := f.forEachMappingSlice(fr, func(bs []byte) {
+		if werr == nil {
+			_, werr = w.Write(bs)
+		}
+	}); err != nil {
+		log.Warningf("Compressor failed to map %v: %v", fr, err)
+		return false
+	}
+	if werr == nil {
+		werr = w.Close()
+	}
+	if werr != nil {
+		log.Warningf("Compressor failed to compress %v: %v", fr, werr)
+		return false
+	}
+	// Don't bother storing memory that doesn't compress well.
+	size := uint64(buf.Len())
+	blobLen, ok := hostarch.Addr(size).RoundUp()
+	if !ok || uint64(blobLen) > fr.Length()*3/4 {
+		return false
+	}
+
+	blob, err := f.Allocate(uint64(blobLen), usage.System)
+	if err != nil {
+		return false
+	}
+	data := buf.Bytes()
+	f.forEachMappingSlice(blob, func(bs []byte) {
+		data = data[copy(bs, data):]
+	})
+	if err := f.Decommit(fr); err != nil {
+		log.Warningf("Compressor failed to decommit %v: %v", fr, err)
+		f.DecRef(blob)
+		return false
+	}
+	seg.SetValue(compressSetValue{blob: blob, size: size})
+	atomic.AddUint64(&compressedBytes, fr.Length())
+	atomic.AddUint64(&compressedStoredBytes, blob.Length())
+	return true
+}
+
+// decompressLocked restores the contents of the compressed range represented
+// by seg and releases its compressed storage. The caller is responsible for
+// removing seg from f.compressed.
+//
+// Preconditions: f.compressMu must be locked. seg must be compressed.
+func (f *MemoryFile) decompressLocked(seg compressIterator) error {
+	val := seg.Value()
+	data := make([]byte, 0, val.size)
+	if err := f.forEachMappingSlice(val.blob, func(bs []byte) {
+		data = append(data, bs...)
+	}); err != nil {
+		return err
+	}
+	r := flate.NewReader(bytes.NewReader(data[:val.size]))
+	var rerr error
+	if err := f.forEachMappingSlice(seg.Range(), func(bs []byte) {
+		if rerr == nil {
+			_, rerr = io.ReadFull(r, bs)
+		}
+	}); err != nil {
+		return err
+	}
+	if rerr != nil {
+		return fmt.Errorf("failed to decompress %v: %v", seg.Range(), rerr)
+	}
+	f.dropBlobLocked(seg)
+	return nil
+}
+
+// dropBlobLocked releases the compressed storage for the compressed range
+// represented by seg.
+//
+// Preconditions: f.compressMu must be locked. seg must be compressed.
+func (f *MemoryFile) dropBlobLocked(seg compressIterator) {
+	val := seg.Value()
+	f.DecRef(val.blob)
+	atomic.AddUint64(&compressedStoredBytes, ^(val.blob.Length() - 1))
+	atomic.AddUint64(&compressedBytes, ^(seg.Range().Length() - 1))
+}
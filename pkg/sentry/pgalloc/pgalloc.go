@@ -17,11 +17,15 @@
 //
 // Lock order:
 //
-// pgalloc.MemoryFile.mu
-//   pgalloc.MemoryFile.mappingsMu
+// pgalloc.MemoryFile.compressPassMu
+//   pgalloc.CompressibleMemoryUser locks (e.g. mm.MemoryManager.activeMu)
+//     pgalloc.MemoryFile.compressMu
+//       pgalloc.MemoryFile.mu
+//         pgalloc.MemoryFile.mappingsMu
 package pgalloc
 
 import (
+	"compress/flate"
 	"fmt"
 	"math"
 	"os"
@@ -161,6 +165,48 @@ type MemoryFile struct {
 	// notifications used to drive eviction. stopNotifyPressure is
 	// immutable.
 	stopNotifyPressure func()
+
+	// oomHandler, if not nil, is called when an allocation fails for lack
+	// of memory. oomHandler is protected by mu.
+	oomHandler func()
+
+	// If allocationsDenied is true, allocations of memory that isn't
+	// accounted to usage.System fail with ENOMEM. allocationsDenied is
+	// protected by mu.
+	allocationsDenied bool
+
+	// compressPassMu is held for the duration of each pass of the
+	// compressor, including calls to CompressibleMemoryUsers, so that
+	// SuspendCompression can wait for an ongoing pass to finish.
+	compressPassMu sync.Mutex
+
+	// compressWriter is reused by all passes of the compressor.
+	// compressWriter is protected by compressPassMu.
+	compressWriter *flate.Writer
+
+	// compressMu serializes operations on the compressed memory tier; see
+	// compress.go. compressMu is ordered before mu.
+	compressMu sync.Mutex
+
+	// compressed tracks idle and compressed pages. compressed is protected
+	// by compressMu.
+	compressed compressSet
+
+	// haveCompressed is 1 if compressed may be non-empty, allowing
+	// EnsureResident to avoid locking compressMu in the common case.
+	// haveCompressed is accessed using atomic memory operations.
+	haveCompressed uint32
+
+	// compressSuspended is the number of calls to SuspendCompression that
+	// have not yet been matched by a call to ResumeCompression. While
+	// compressSuspended is non-zero, no memory is compressed or marked idle.
+	// compressSuspended is protected by both compressPassMu and compressMu;
+	// it may be read while holding either, and is mutated while holding both.
+	compressSuspended int
+
+	// compressUsers is the set of registered CompressibleMemoryUsers.
+	// compressUsers is protected by mu.
+	compressUsers map[CompressibleMemoryUser]struct{}
 }
 
 // MemoryFileOpts provides options to NewMemoryFile.
@@ -178,6 +224,17 @@ type MemoryFileOpts struct {
 	// obtained from the host are zero-filled, such that MemoryFile must manually
 	// zero newly-allocated pages.
 	ManualZeroing bool
+
+	// If CompressionWatermark is non-zero, MemoryFile compresses the contents
+	// of cold pages marked by MarkCompressible when its usage exceeds
+	// CompressionWatermark bytes. This option has no effect if ManualZeroing
+	// is true, since decommitting compressed pages then doesn't release them.
+	CompressionWatermark uint64
+
+	// If FakeSwap is true, memory compressed by MemoryFile is presented to
+	// applications as swap. This option has no effect unless
+	// CompressionWatermark is non-zero.
+	FakeSwap bool
 }
 
 // DelayedEvictionType is the type of MemoryFileOpts.DelayedEviction.
@@ -223,6 +280,10 @@ type usageInfo struct {
 	knownCommitted bool
 
 	refs uint64
+
+	// If compressibleRefs is non-zero, the tracked region may be compressed
+	// while refs == compressibleRefs. See MemoryFile.MarkCompressible.
+	compressibleRefs uint64
 }
 
 // canCommit returns true if the tracked region can be committed.
@@ -301,6 +362,13 @@ func NewMemoryFile(file *os.File, opts MemoryFileOpts) (*MemoryFile, error) {
 		return nil, fmt.Errorf("invalid MemoryFileOpts.DelayedEviction: %v", opts.DelayedEviction)
 	}
 
+	if opts.ManualZeroing {
+		opts.CompressionWatermark = 0
+	}
+	if opts.CompressionWatermark == 0 {
+		opts.FakeSwap = false
+	}
+
 	// Truncate the file to 0 bytes first to ensure that it's empty.
 	if err := file.Truncate(0); err != nil {
 		return nil, err
@@ -310,6 +378,9 @@ func NewMemoryFile(file *os.File, opts MemoryFileOpts) (*MemoryFile, error) {
 		file:      file,
 		evictable: make(map[EvictableMemoryUser]*evictableMemoryUserInfo),
 	}
+	if f.opts.CompressionWatermark != 0 {
+		f.compressUsers = make(map[CompressibleMemoryUser]struct{})
+	}
 	f.mappings.Store(make([]uintptr, 0))
 	f.reclaimCond.L = &f.mu
 
@@ -329,6 +400,9 @@ func NewMemoryFile(file *os.File, opts MemoryFileOpts) (*MemoryFile, error) {
 	}
 
 	go f.runReclaim() // S/R-SAFE: f.mu
+	if f.opts.CompressionWatermark != 0 {
+		go f.runCompressor() // S/R-SAFE: Kernel.SaveTo and LoadFrom suspend compression.
+	}
 
 	// The Linux kernel contains an optional feature called "Integrity
 	// Measurement Architecture" (IMA). If IMA is enabled, it will checksum
@@ -393,6 +467,10 @@ func (f *MemoryFile) Allocate(length uint64, kind usage.MemoryKind) (memmap.File
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.allocationsDenied && kind != usage.System {
+		return memmap.FileRange{}, linuxerr.ENOMEM
+	}
+
 	// Align hugepage-and-larger allocations on hugepage boundaries to try
 	// to take advantage of hugetmpfs.
 	alignment := uint64(hostarch.PageSize)
@@ -403,6 +481,7 @@ func (f *MemoryFile) Allocate(length uint64, kind usage.MemoryKind) (memmap.File
 	// Find a range in the underlying file.
 	fr, ok := findAvailableRange(&f.usage, f.fileSize, length, alignment)
 	if !ok {
+		f.notifyOOMLocked()
 		return memmap.FileRange{}, linuxerr.ENOMEM
 	}
 
@@ -411,6 +490,7 @@ func (f *MemoryFile) Allocate(length uint64, kind usage.MemoryKind) (memmap.File
 		// Round the new file size up to be chunk-aligned.
 		newFileSize := (int64(fr.End) + chunkMask) &^ chunkMask
 		if err := f.file.Truncate(newFileSize); err != nil {
+			f.notifyOOMLocked()
 			return memmap.FileRange{}, err
 		}
 		f.fileSize = newFileSize
@@ -438,6 +518,31 @@ func (f *MemoryFile) Allocate(length uint64, kind usage.MemoryKind) (memmap.File
 	return fr, nil
 }
 
+// SetOOMHandler sets a function that is called when an allocation fails for
+// lack of memory. fn is called with f's internal lock held, so it must not
+// block or call back into f.
+func (f *MemoryFile) SetOOMHandler(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.oomHandler = fn
+}
+
+// SetAllocationsDenied sets whether allocations of memory that isn't accounted
+// to usage.System fail with ENOMEM. This lets the OOM handler fail allocating
+// tasks instead of killing them while memory usage is over its limit.
+func (f *MemoryFile) SetAllocationsDenied(denied bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allocationsDenied = denied
+}
+
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) notifyOOMLocked() {
+	if f.oomHandler != nil {
+		f.oomHandler()
+	}
+}
+
 // findAvailableRange returns an available range in the usageSet.
 //
 // Note that scanning for available slots takes place from end first backwards,
@@ -658,6 +763,7 @@ func (f *MemoryFile) DecRef(fr memmap.FileRange) {
 				usage.MemoryAccounting.Move(seg.Range().Length(), usage.System, val.kind)
 			}
 			val.kind = usage.System
+			val.compressibleRefs = 0
 		}
 	}
 	f.usage.MergeAdjacent(fr)
@@ -676,6 +782,9 @@ func (f *MemoryFile) MapInternal(fr memmap.FileRange, at hostarch.AccessType) (s
 	if at.Execute {
 		return safemem.BlockSeq{}, linuxerr.EACCES
 	}
+	if err := f.EnsureResident(fr); err != nil {
+		return safemem.BlockSeq{}, err
+	}
 
 	chunks := ((fr.End + chunkMask) >> chunkShift) - (fr.Start >> chunkShift)
 	if chunks == 1 {
@@ -1062,6 +1171,11 @@ func (f *MemoryFile) runReclaim() {
 			break
 		}
 
+		// Compressed copies of reclaimed pages must be discarded before
+		// decommitting them, since discarding a compressed range that is
+		// only partially reclaimed writes to all of it.
+		f.forgetCompressed(fr)
+
 		if f.opts.ManualZeroing {
 			// If ManualZeroing is in effect, only hugepage-aligned regions may
 			// be safely passed to decommitFile. Pages will be zeroed on
@@ -15,9 +15,18 @@
 package pgalloc
 
 import (
+	"bytes"
+	"math"
+	"os"
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/memutil"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 )
 
 const (
@@ -244,3 +253,104 @@ func TestFindUnallocatedRange(t *testing.T) {
 		})
 	}
 }
+
+func TestAllocationsDenied(t *testing.T) {
+	memfd, err := memutil.CreateMemFD("pgalloc-test", 0)
+	if err != nil {
+		t.Fatalf("CreateMemFD failed: %v", err)
+	}
+	f, err := NewMemoryFile(os.NewFile(uintptr(memfd), "pgalloc-test"), MemoryFileOpts{})
+	if err != nil {
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	defer f.Destroy()
+
+	f.SetAllocationsDenied(true)
+	if _, err := f.Allocate(page, usage.Anonymous); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("Allocate(Anonymous) with allocations denied: got err %v, want ENOMEM", err)
+	}
+	// The sentry's own allocations are never denied.
+	fr, err := f.Allocate(page, usage.System)
+	if err != nil {
+		t.Fatalf("Allocate(System) with allocations denied failed: %v", err)
+	}
+	f.DecRef(fr)
+
+	f.SetAllocationsDenied(false)
+	fr, err = f.Allocate(page, usage.Anonymous)
+	if err != nil {
+		t.Fatalf("Allocate(Anonymous) failed: %v", err)
+	}
+	f.DecRef(fr)
+}
+
+type testCompressibleUser struct {
+	invalidated []memmap.FileRange
+}
+
+func (u *testCompressibleUser) InvalidateCompressible(ctx context.Context, frs []memmap.FileRange) {
+	u.invalidated = append(u.invalidated, frs...)
+}
+
+func TestCompression(t *testing.T) {
+	memfd, err := memutil.CreateMemFD("pgalloc-test", 0)
+	if err != nil {
+		t.Fatalf("CreateMemFD failed: %v", err)
+	}
+	// Set a watermark that can't be reached so that the compressor goroutine
+	// doesn't race with the passes below.
+	f, err := NewMemoryFile(os.NewFile(uintptr(memfd), "pgalloc-test"), MemoryFileOpts{
+		CompressionWatermark: math.MaxUint64,
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	defer f.Destroy()
+
+	const length = 4 * page
+	fr, err := f.Allocate(length, usage.Anonymous)
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	defer f.DecRef(fr)
+	want := bytes.Repeat([]byte("compressible"), length/12+1)[:length]
+	f.forEachMappingSlice(fr, func(bs []byte) {
+		copy(bs, want)
+	})
+	user := &testCompressibleUser{}
+	f.RegisterCompressibleMemoryUser(user)
+	f.MarkCompressible(fr)
+
+	// The first pass marks fr idle, and the second compresses it.
+	f.Compress(context.Background(), length)
+	if len(user.invalidated) != 1 || user.invalidated[0] != fr {
+		t.Fatalf("after first pass: got invalidated ranges %v, want [%v]", user.invalidated, fr)
+	}
+	before := CompressionStatistics()
+	f.Compress(context.Background(), length)
+	if seg := f.compressed.FindSegment(fr.Start); !seg.Ok() || seg.Value().blob.Length() == 0 {
+		t.Fatalf("after second pass: %v is not compressed", fr)
+	}
+	if got := CompressionStatistics().CompressedBytes - before.CompressedBytes; got != length {
+		t.Errorf("after second pass: compressed bytes increased by %d, want %d", got, length)
+	}
+
+	bs, err := f.MapInternal(fr, hostarch.Read)
+	if err != nil {
+		t.Fatalf("MapInternal failed: %v", err)
+	}
+	got := make([]byte, length)
+	if _, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(got)), bs); err != nil {
+		t.Fatalf("CopySeq failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("contents changed after decompression")
+	}
+	after := CompressionStatistics()
+	if after.CompressedBytes != before.CompressedBytes {
+		t.Errorf("after decompression: got compressed bytes %d, want %d", after.CompressedBytes, before.CompressedBytes)
+	}
+	if got := after.Faults - before.Faults; got != 1 {
+		t.Errorf("after decompression: faults increased by %d, want 1", got)
+	}
+}
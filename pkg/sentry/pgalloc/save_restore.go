@@ -32,6 +32,14 @@ import (
 
 // SaveTo writes f's state to the given stream.
 func (f *MemoryFile) SaveTo(ctx context.Context, w wire.Writer) error {
+	// Only uncompressed memory is saved. Kernel.SaveTo has normally already
+	// suspended compression; this is only needed for direct callers.
+	err := f.SuspendCompression()
+	defer f.ResumeCompression()
+	if err != nil {
+		return err
+	}
+
 	// Wait for reclaim.
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -50,7 +58,7 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w wire.Writer) error {
 	// Ensure that all pages that contain data have knownCommitted set, since
 	// we only store knownCommitted pages below.
 	zeroPage := make([]byte, hostarch.PageSize)
-	err := f.updateUsageLocked(0, func(bs []byte, committed []byte) error {
+	err = f.updateUsageLocked(0, func(bs []byte, committed []byte) error {
 		for pgoff := 0; pgoff < len(bs); pgoff += hostarch.PageSize {
 			i := pgoff / hostarch.PageSize
 			pg := bs[pgoff : pgoff+hostarch.PageSize]
@@ -117,6 +125,13 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w wire.Writer) error {
 
 // LoadFrom loads MemoryFile state from the given stream.
 func (f *MemoryFile) LoadFrom(ctx context.Context, r wire.Reader) error {
+	// The compressor must not observe f.usage while it is being replaced.
+	// f.compressed remains empty, since no compressed memory is saved.
+	if err := f.SuspendCompression(); err != nil {
+		return err
+	}
+	defer f.ResumeCompression()
+
 	// Load metadata.
 	if _, err := state.Load(ctx, r, &f.fileSize); err != nil {
 		return err
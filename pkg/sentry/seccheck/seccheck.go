@@ -0,0 +1,278 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package seccheck delivers security events from checkpoints in the sentry to
+// an event sink outside of the sandbox.
+//
+// Each checkpoint is a Point. Code at a checkpoint first checks whether its
+// point is enabled, which is cheap, and only then builds and emits an event
+// message defined in security_events.proto. Events are queued in a bounded
+// queue and written to the sink by a separate goroutine, so checkpoints never
+// block on the sink. Events that exceed the configured rate or that don't fit
+// in the queue are dropped and counted.
+package seccheck
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/eventchannel"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+var (
+	eventsSent              = metric.MustCreateNewUint64Metric("/seccheck/events_sent", false /* sync */, "Number of security events written to the sink.")
+	eventsDroppedRateLimit  = metric.MustCreateNewUint64Metric("/seccheck/events_dropped_rate_limited", false /* sync */, "Number of security events dropped because they exceeded the configured rate.")
+	eventsDroppedQueueFull  = metric.MustCreateNewUint64Metric("/seccheck/events_dropped_queue_full", false /* sync */, "Number of security events dropped because the event queue was full.")
+	eventsDroppedSinkErrors = metric.MustCreateNewUint64Metric("/seccheck/sink_errors", false /* sync */, "Number of security events that could not be written to the sink.")
+)
+
+// DefaultQueueSize is the number of events queued for the sink if
+// Config.QueueSize is 0.
+const DefaultQueueSize = 1024
+
+// Point identifies a checkpoint.
+type Point uint
+
+const (
+	// PointExecve is reached when a task successfully loads a new executable.
+	// Its event is an ExecveEvent.
+	PointExecve Point = iota
+
+	// PointCredentials is reached when a task's user IDs, group IDs or
+	// capabilities change. Its event is a CredentialsEvent.
+	PointCredentials
+
+	// PointListen is reached when a socket starts listening. Its event is a
+	// ListenEvent.
+	PointListen
+
+	// PointSyscallPolicyDenial is reached when a sentry syscall policy fails a
+	// syscall or kills a task. Its event is a SyscallPolicyDenialEvent.
+	PointSyscallPolicyDenial
+
+	// numPoints must be last.
+	numPoints
+)
+
+var pointNames = [numPoints]string{
+	PointExecve:              "execve",
+	PointCredentials:         "credentials",
+	PointListen:              "listen",
+	PointSyscallPolicyDenial: "syscall-policy-denial",
+}
+
+// String implements fmt.Stringer.
+func (p Point) String() string {
+	if p < numPoints {
+		return pointNames[p]
+	}
+	return fmt.Sprintf("Point(%d)", uint(p))
+}
+
+// PointSet is a set of Points.
+type PointSet uint32
+
+// AllPoints contains every Point.
+const AllPoints = PointSet(1<<numPoints - 1)
+
+// PointSetOf returns a PointSet containing only p.
+func PointSetOf(p Point) PointSet {
+	return PointSet(1) << p
+}
+
+// Contains returns true if s contains p.
+func (s PointSet) Contains(p Point) bool {
+	return s&PointSetOf(p) != 0
+}
+
+// ParsePointSet parses a comma-separated list of point names. "all" stands for
+// AllPoints. An empty list is the empty set.
+func ParsePointSet(v string) (PointSet, error) {
+	var s PointSet
+	if v == "" {
+		return s, nil
+	}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			s |= AllPoints
+			continue
+		}
+		found := false
+		for p, pn := range pointNames {
+			if name == pn {
+				s |= PointSetOf(Point(p))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid security event point %q", name)
+		}
+	}
+	return s, nil
+}
+
+// Config configures a State.
+type Config struct {
+	// Points are the enabled checkpoints.
+	Points PointSet
+
+	// Sink receives events. State takes ownership of Sink.
+	Sink eventchannel.Emitter
+
+	// QueueSize is the maximum number of events waiting to be written to
+	// Sink. If 0, DefaultQueueSize is used.
+	QueueSize int
+
+	// MaxRate is the maximum number of events per second, with bursts of up
+	// to Burst events. If MaxRate is 0, the rate is unlimited.
+	MaxRate float64
+	Burst   int
+}
+
+// State delivers events from checkpoints to a sink.
+type State struct {
+	// enabled is the PointSet of enabled checkpoints. Accessed atomically.
+	enabled uint32
+
+	// mu protects the fields below.
+	mu sync.RWMutex
+
+	// queue holds events waiting to be written to the sink. It is nil if the
+	// State is stopped.
+	queue chan proto.Message
+
+	// limiter limits the rate of events, or is nil if the rate is unlimited.
+	limiter *rate.Limiter
+
+	// done is closed when the goroutine draining queue exits.
+	done chan struct{}
+}
+
+// Global is the State used by the sentry's checkpoints.
+var Global State
+
+// Enabled returns true if checkpoint p is enabled. Checkpoints should call it
+// before building an event.
+func (s *State) Enabled(p Point) bool {
+	return PointSet(atomic.LoadUint32(&s.enabled)).Contains(p)
+}
+
+// Start starts delivering events for the configured points to cfg.Sink.
+func (s *State) Start(cfg Config) error {
+	if cfg.Sink == nil {
+		return fmt.Errorf("security events require a sink")
+	}
+	if cfg.QueueSize < 0 || cfg.MaxRate < 0 || cfg.Burst < 0 {
+		return fmt.Errorf("invalid security event config: %+v", cfg)
+	}
+	queueSize := cfg.QueueSize
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue != nil {
+		return fmt.Errorf("security events already started")
+	}
+	s.queue = make(chan proto.Message, queueSize)
+	s.limiter = nil
+	if cfg.MaxRate > 0 {
+		burst := cfg.Burst
+		if burst == 0 {
+			burst = 1
+		}
+		s.limiter = rate.NewLimiter(rate.Limit(cfg.MaxRate), burst)
+	}
+	s.done = make(chan struct{})
+	go s.drain(s.queue, cfg.Sink, s.done) // S/R-SAFE: not saved.
+	atomic.StoreUint32(&s.enabled, uint32(cfg.Points))
+	return nil
+}
+
+// Stop disables all checkpoints, waits for queued events to be written and
+// closes the sink. Stop is a no-op if s isn't started.
+func (s *State) Stop() {
+	atomic.StoreUint32(&s.enabled, 0)
+	s.mu.Lock()
+	if s.queue == nil {
+		s.mu.Unlock()
+		return
+	}
+	close(s.queue)
+	s.queue = nil
+	done := s.done
+	s.mu.Unlock()
+	<-done
+}
+
+// Emit queues an event for checkpoint p. It never blocks; if the event can't
+// be queued, it is dropped.
+func (s *State) Emit(p Point, msg proto.Message) {
+	if !s.Enabled(p) {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.queue == nil {
+		return
+	}
+	if s.limiter != nil && !s.limiter.Allow() {
+		eventsDroppedRateLimit.Increment()
+		return
+	}
+	select {
+	case s.queue <- msg:
+	default:
+		eventsDroppedQueueFull.Increment()
+	}
+}
+
+// drain writes events from queue to sink until queue is closed, then closes
+// sink and done.
+func (s *State) drain(queue <-chan proto.Message, sink eventchannel.Emitter, done chan<- struct{}) {
+	defer close(done)
+	hungUp := false
+	for msg := range queue {
+		if hungUp {
+			eventsDroppedSinkErrors.Increment()
+			continue
+		}
+		hangup, err := sink.Emit(msg)
+		if err != nil {
+			eventsDroppedSinkErrors.Increment()
+			log.Warningf("Failed to write security event: %v", err)
+		} else {
+			eventsSent.Increment()
+		}
+		if hangup {
+			// The other end is gone; stop building events but keep
+			// draining the queue so that Stop doesn't block.
+			log.Warningf("Security event sink hung up, disabling security events")
+			atomic.StoreUint32(&s.enabled, 0)
+			hungUp = true
+		}
+	}
+	if err := sink.Close(); err != nil {
+		log.Warningf("Failed to close security event sink: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/security_events_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// testSink records emitted events. If block is not nil, Emit waits for it to
+// be closed before recording.
+type testSink struct {
+	block chan struct{}
+
+	mu     sync.Mutex
+	events []proto.Message
+	closed bool
+}
+
+// Emit implements eventchannel.Emitter.Emit.
+func (ts *testSink) Emit(msg proto.Message) (bool, error) {
+	if ts.block != nil {
+		<-ts.block
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.events = append(ts.events, msg)
+	return false, nil
+}
+
+// Close implements eventchannel.Emitter.Close.
+func (ts *testSink) Close() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.closed = true
+	return nil
+}
+
+func TestEnabledPoints(t *testing.T) {
+	var s State
+	sink := &testSink{}
+	if err := s.Start(Config{Points: PointSetOf(PointExecve), Sink: sink}); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !s.Enabled(PointExecve) || s.Enabled(PointListen) {
+		t.Errorf("got execve enabled %t, listen enabled %t; want true, false", s.Enabled(PointExecve), s.Enabled(PointListen))
+	}
+	s.Emit(PointExecve, &pb.ExecveEvent{BinaryPath: "/bin/true"})
+	s.Emit(PointListen, &pb.ListenEvent{Fd: 3})
+	s.Stop()
+
+	if !sink.closed {
+		t.Errorf("sink not closed by Stop")
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(sink.events))
+	}
+	if ev, ok := sink.events[0].(*pb.ExecveEvent); !ok || ev.GetBinaryPath() != "/bin/true" {
+		t.Errorf("got event %v, want execve of /bin/true", sink.events[0])
+	}
+	if s.Enabled(PointExecve) {
+		t.Errorf("execve still enabled after Stop")
+	}
+	// Emitting after Stop is a no-op.
+	s.Emit(PointExecve, &pb.ExecveEvent{})
+}
+
+func TestQueueFull(t *testing.T) {
+	var s State
+	sink := &testSink{block: make(chan struct{})}
+	if err := s.Start(Config{Points: AllPoints, Sink: sink, QueueSize: 2}); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	before := eventsDroppedQueueFull.Value()
+	// The drain goroutine holds at most one event while blocked, so at most
+	// three events can be accepted.
+	for i := 0; i < 10; i++ {
+		s.Emit(PointListen, &pb.ListenEvent{Fd: int32(i)})
+	}
+	close(sink.block)
+	s.Stop()
+
+	dropped := eventsDroppedQueueFull.Value() - before
+	if got := uint64(len(sink.events)); got+dropped != 10 || got > 3 || got < 2 {
+		t.Errorf("got %d events and %d dropped, want 2-3 events and the rest dropped", got, dropped)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var s State
+	sink := &testSink{}
+	if err := s.Start(Config{Points: AllPoints, Sink: sink, MaxRate: 0.001, Burst: 3}); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	before := eventsDroppedRateLimit.Value()
+	for i := 0; i < 10; i++ {
+		s.Emit(PointCredentials, &pb.CredentialsEvent{})
+	}
+	s.Stop()
+
+	if got, want := len(sink.events), 3; got != want {
+		t.Errorf("got %d events, want %d", got, want)
+	}
+	if got, want := eventsDroppedRateLimit.Value()-before, uint64(7); got != want {
+		t.Errorf("got %d events dropped, want %d", got, want)
+	}
+}
+
+func TestParsePointSet(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want PointSet
+	}{
+		{in: "execve", want: PointSetOf(PointExecve)},
+		{in: "listen, syscall-policy-denial", want: PointSetOf(PointListen) | PointSetOf(PointSyscallPolicyDenial)},
+		{in: "all", want: AllPoints},
+		{in: "", want: 0},
+	} {
+		got, err := ParsePointSet(tc.in)
+		if err != nil {
+			t.Errorf("ParsePointSet(%q) failed: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParsePointSet(%q) = %#x, want %#x", tc.in, got, tc.want)
+		}
+	}
+	if _, err := ParsePointSet("bogus"); err == nil {
+		t.Errorf("ParsePointSet(bogus) succeeded, want error")
+	}
+}
@@ -232,28 +232,31 @@ func putCmsg(buf []byte, flags int, msgType uint32, align uint, data []int32) ([
 	return alignSlice(buf, align), flags
 }
 
-func putCmsgStruct(buf []byte, msgLevel, msgType uint32, align uint, data marshal.Marshallable) []byte {
-	if cap(buf)-len(buf) < linux.SizeOfControlMessageHeader {
-		return buf
+// putCmsgStruct writes a control message header and as much of data as will
+// fit into the unused capacity of a buffer. As in Linux's
+// net/core/scm.c:put_cmsg, a truncated control message is still written, with
+// its length reduced to what fits, and MSG_CTRUNC is set in flags.
+func putCmsgStruct(buf []byte, flags int, msgLevel, msgType uint32, align uint, data marshal.Marshallable) ([]byte, int) {
+	space := cap(buf) - len(buf)
+	if space < linux.SizeOfControlMessageHeader {
+		flags |= linux.MSG_CTRUNC
+		return buf, flags
 	}
-	ob := buf
-
-	buf = putUint64(buf, uint64(linux.SizeOfControlMessageHeader))
-	buf = putUint32(buf, msgLevel)
-	buf = putUint32(buf, msgType)
 
-	hdrBuf := buf
-	buf = append(buf, marshal.Marshal(data)...)
-
-	// If the control message data brought us over capacity, omit it.
-	if cap(buf) != cap(ob) {
-		return hdrBuf
+	b := marshal.Marshal(data)
+	length := linux.SizeOfControlMessageHeader + len(b)
+	if length > space {
+		flags |= linux.MSG_CTRUNC
+		length = space
+		b = b[:length-linux.SizeOfControlMessageHeader]
 	}
 
-	// Update control message length to include data.
-	putUint64(ob, uint64(len(buf)-len(ob)))
+	buf = putUint64(buf, uint64(length))
+	buf = putUint32(buf, msgLevel)
+	buf = putUint32(buf, msgType)
+	buf = append(buf, b...)
 
-	return alignSlice(buf, align)
+	return alignSlice(buf, align), flags
 }
 
 // Credentials implements SCMCredentials.Credentials.
@@ -287,6 +290,27 @@ func PackCredentials(t *kernel.Task, creds SCMCredentials, buf []byte, flags int
 	return putCmsg(buf, flags, linux.SCM_CREDENTIALS, align, c)
 }
 
+// PackSecurityLabel packs an SCM_SECURITY control message containing the
+// given security label into a buffer.
+func PackSecurityLabel(t *kernel.Task, label string, buf []byte, flags int) ([]byte, int) {
+	space := cap(buf) - len(buf)
+	if space < linux.SizeOfControlMessageHeader {
+		flags |= linux.MSG_CTRUNC
+		return buf, flags
+	}
+
+	length := linux.SizeOfControlMessageHeader + len(label)
+	if length > space {
+		length = space
+		flags |= linux.MSG_CTRUNC
+	}
+	buf = putUint64(buf, uint64(length))
+	buf = putUint32(buf, linux.SOL_SOCKET)
+	buf = putUint32(buf, linux.SCM_SECURITY)
+	buf = append(buf, label[:length-linux.SizeOfControlMessageHeader]...)
+	return alignSlice(buf, t.Arch().Width()), flags
+}
+
 // alignSlice extends a slice's length (up to the capacity) to align it.
 func alignSlice(buf []byte, align uint) []byte {
 	aligned := bits.AlignUp(len(buf), align)
@@ -300,10 +324,11 @@ func alignSlice(buf []byte, align uint) []byte {
 }
 
 // PackTimestamp packs a SO_TIMESTAMP socket control message.
-func PackTimestamp(t *kernel.Task, timestamp int64, buf []byte) []byte {
+func PackTimestamp(t *kernel.Task, timestamp int64, buf []byte, flags int) ([]byte, int) {
 	timestampP := linux.NsecToTimeval(timestamp)
 	return putCmsgStruct(
 		buf,
+		flags,
 		linux.SOL_SOCKET,
 		linux.SO_TIMESTAMP,
 		t.Arch().Width(),
@@ -312,9 +337,10 @@ func PackTimestamp(t *kernel.Task, timestamp int64, buf []byte) []byte {
 }
 
 // PackInq packs a TCP_INQ socket control message.
-func PackInq(t *kernel.Task, inq int32, buf []byte) []byte {
+func PackInq(t *kernel.Task, inq int32, buf []byte, flags int) ([]byte, int) {
 	return putCmsgStruct(
 		buf,
+		flags,
 		linux.SOL_TCP,
 		linux.TCP_INQ,
 		t.Arch().Width(),
@@ -323,9 +349,10 @@ func PackInq(t *kernel.Task, inq int32, buf []byte) []byte {
 }
 
 // PackTOS packs an IP_TOS socket control message.
-func PackTOS(t *kernel.Task, tos uint8, buf []byte) []byte {
+func PackTOS(t *kernel.Task, tos uint8, buf []byte, flags int) ([]byte, int) {
 	return putCmsgStruct(
 		buf,
+		flags,
 		linux.SOL_IP,
 		linux.IP_TOS,
 		t.Arch().Width(),
@@ -334,9 +361,10 @@ func PackTOS(t *kernel.Task, tos uint8, buf []byte) []byte {
 }
 
 // PackTClass packs an IPV6_TCLASS socket control message.
-func PackTClass(t *kernel.Task, tClass uint32, buf []byte) []byte {
+func PackTClass(t *kernel.Task, tClass uint32, buf []byte, flags int) ([]byte, int) {
 	return putCmsgStruct(
 		buf,
+		flags,
 		linux.SOL_IPV6,
 		linux.IPV6_TCLASS,
 		t.Arch().Width(),
@@ -345,9 +373,10 @@ func PackTClass(t *kernel.Task, tClass uint32, buf []byte) []byte {
 }
 
 // PackIPPacketInfo packs an IP_PKTINFO socket control message.
-func PackIPPacketInfo(t *kernel.Task, packetInfo *linux.ControlMessageIPPacketInfo, buf []byte) []byte {
+func PackIPPacketInfo(t *kernel.Task, packetInfo *linux.ControlMessageIPPacketInfo, buf []byte, flags int) ([]byte, int) {
 	return putCmsgStruct(
 		buf,
+		flags,
 		linux.SOL_IP,
 		linux.IP_PKTINFO,
 		t.Arch().Width(),
@@ -356,7 +385,7 @@ func PackIPPacketInfo(t *kernel.Task, packetInfo *linux.ControlMessageIPPacketIn
 }
 
 // PackOriginalDstAddress packs an IP_RECVORIGINALDSTADDR socket control message.
-func PackOriginalDstAddress(t *kernel.Task, originalDstAddress linux.SockAddr, buf []byte) []byte {
+func PackOriginalDstAddress(t *kernel.Task, originalDstAddress linux.SockAddr, buf []byte, flags int) ([]byte, int) {
 	var level uint32
 	var optType uint32
 	switch originalDstAddress.(type) {
@@ -370,13 +399,14 @@ func PackOriginalDstAddress(t *kernel.Task, originalDstAddress linux.SockAddr, b
 		panic("invalid address type, must be an IP address for IP_RECVORIGINALDSTADDR cmsg")
 	}
 	return putCmsgStruct(
-		buf, level, optType, t.Arch().Width(), originalDstAddress)
+		buf, flags, level, optType, t.Arch().Width(), originalDstAddress)
 }
 
 // PackSockExtendedErr packs an IP*_RECVERR socket control message.
-func PackSockExtendedErr(t *kernel.Task, sockErr linux.SockErrCMsg, buf []byte) []byte {
+func PackSockExtendedErr(t *kernel.Task, sockErr linux.SockErrCMsg, buf []byte, flags int) ([]byte, int) {
 	return putCmsgStruct(
 		buf,
+		flags,
 		sockErr.CMsgLevel(),
 		sockErr.CMsgType(),
 		t.Arch().Width(),
@@ -389,38 +419,38 @@ func PackSockExtendedErr(t *kernel.Task, sockErr linux.SockErrCMsg, buf []byte)
 // We skip control messages specific to Unix domain sockets.
 //
 // Note that some control messages may be truncated if they do not fit under
-// the capacity of buf.
-func PackControlMessages(t *kernel.Task, cmsgs socket.ControlMessages, buf []byte) []byte {
+// the capacity of buf, in which case MSG_CTRUNC is set in the returned flags.
+func PackControlMessages(t *kernel.Task, cmsgs socket.ControlMessages, buf []byte, flags int) ([]byte, int) {
 	if cmsgs.IP.HasTimestamp {
-		buf = PackTimestamp(t, cmsgs.IP.Timestamp, buf)
+		buf, flags = PackTimestamp(t, cmsgs.IP.Timestamp, buf, flags)
 	}
 
 	if cmsgs.IP.HasInq {
 		// In Linux, TCP_CM_INQ is added after SO_TIMESTAMP.
-		buf = PackInq(t, cmsgs.IP.Inq, buf)
+		buf, flags = PackInq(t, cmsgs.IP.Inq, buf, flags)
 	}
 
 	if cmsgs.IP.HasTOS {
-		buf = PackTOS(t, cmsgs.IP.TOS, buf)
+		buf, flags = PackTOS(t, cmsgs.IP.TOS, buf, flags)
 	}
 
 	if cmsgs.IP.HasTClass {
-		buf = PackTClass(t, cmsgs.IP.TClass, buf)
+		buf, flags = PackTClass(t, cmsgs.IP.TClass, buf, flags)
 	}
 
 	if cmsgs.IP.HasIPPacketInfo {
-		buf = PackIPPacketInfo(t, &cmsgs.IP.PacketInfo, buf)
+		buf, flags = PackIPPacketInfo(t, &cmsgs.IP.PacketInfo, buf, flags)
 	}
 
 	if cmsgs.IP.OriginalDstAddress != nil {
-		buf = PackOriginalDstAddress(t, cmsgs.IP.OriginalDstAddress, buf)
+		buf, flags = PackOriginalDstAddress(t, cmsgs.IP.OriginalDstAddress, buf, flags)
 	}
 
 	if cmsgs.IP.SockErr != nil {
-		buf = PackSockExtendedErr(t, cmsgs.IP.SockErr, buf)
+		buf, flags = PackSockExtendedErr(t, cmsgs.IP.SockErr, buf, flags)
 	}
 
-	return buf
+	return buf, flags
 }
 
 // cmsgSpace is equivalent to CMSG_SPACE in Linux.
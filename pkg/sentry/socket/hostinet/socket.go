@@ -145,17 +145,20 @@ func (p *socketProvider) Socket(t *kernel.Task, stypeflags linux.SockType, proto
 	if stack == nil {
 		return nil, nil
 	}
-	if _, ok := stack.(*Stack); !ok {
+	hstack, ok := stack.(*Stack)
+	if !ok {
 		return nil, nil
 	}
 
-	// Only accept TCP and UDP.
+	// Only accept TCP, MPTCP and UDP.
 	stype := stypeflags & linux.SOCK_TYPE_MASK
 	switch stype {
 	case unix.SOCK_STREAM:
 		switch protocol {
 		case 0, unix.IPPROTO_TCP:
 			// ok
+		case linux.IPPROTO_MPTCP:
+			// ok
 		default:
 			return nil, nil
 		}
@@ -173,7 +176,13 @@ func (p *socketProvider) Socket(t *kernel.Task, stypeflags linux.SockType, proto
 	// Conservatively ignore all flags specified by the application and add
 	// SOCK_NONBLOCK since socketOperations requires it. Pass a protocol of 0
 	// to simplify the syscall filters, since 0 and IPPROTO_* are equivalent.
-	fd, err := unix.Socket(p.family, int(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	// IPPROTO_MPTCP is passed through if the host supports it, otherwise the
+	// socket falls back to TCP.
+	hostProtocol := 0
+	if protocol == linux.IPPROTO_MPTCP && hstack.mptcpEnabled {
+		hostProtocol = protocol
+	}
+	fd, err := unix.Socket(p.family, int(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, hostProtocol)
 	if err != nil {
 		return nil, syserr.FromError(err)
 	}
@@ -270,13 +279,12 @@ func (s *socketOpsCommon) Connect(t *kernel.Task, sockaddr []byte, blocking bool
 }
 
 // Accept implements socket.Socket.Accept.
-func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
-	var peerAddr linux.SockAddr
+func (s *socketOpsCommon) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	var peerAddrBuf []byte
 	var peerAddrlen uint32
 	var peerAddrPtr *byte
 	var peerAddrlenPtr *uint32
-	if peerRequested {
+	if writePeer != nil {
 		peerAddrBuf = make([]byte, sizeofSockaddr)
 		peerAddrlen = uint32(len(peerAddrBuf))
 		peerAddrPtr = &peerAddrBuf[0]
@@ -303,11 +311,16 @@ func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int,
 		}
 	}
 
-	if peerRequested {
-		peerAddr = socket.UnmarshalSockAddr(s.family, peerAddrBuf[:peerAddrlen])
-	}
 	if syscallErr != nil {
-		return 0, peerAddr, peerAddrlen, syserr.FromError(syscallErr)
+		return 0, syserr.FromError(syscallErr)
+	}
+
+	if writePeer != nil {
+		peerAddr := socket.UnmarshalSockAddr(s.family, peerAddrBuf[:peerAddrlen])
+		if err := writePeer(peerAddr, peerAddrlen); err != nil {
+			unix.Close(fd)
+			return 0, syserr.FromError(err)
+		}
 	}
 
 	var (
@@ -318,7 +331,7 @@ func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int,
 		f, err := newVFS2Socket(t, s.family, s.stype, s.protocol, fd, uint32(flags&unix.SOCK_NONBLOCK))
 		if err != nil {
 			unix.Close(fd)
-			return 0, nil, 0, err
+			return 0, err
 		}
 		defer f.DecRef(t)
 
@@ -330,7 +343,7 @@ func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int,
 		f, err := newSocketFile(t, s.family, s.stype, s.protocol, fd, flags&unix.SOCK_NONBLOCK != 0)
 		if err != nil {
 			unix.Close(fd)
-			return 0, nil, 0, err
+			return 0, err
 		}
 		defer f.DecRef(t)
 
@@ -340,7 +353,7 @@ func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int,
 		t.Kernel().RecordSocket(f)
 	}
 
-	return kfd, peerAddr, peerAddrlen, syserr.FromError(kerr)
+	return kfd, syserr.FromError(kerr)
 }
 
 // Bind implements socket.Socket.Bind.
@@ -404,6 +417,27 @@ func (s *socketOpsCommon) GetSockOpt(t *kernel.Task, level int, name int, outPtr
 		case linux.TCP_INFO:
 			optlen = int(linux.SizeOfTCPInfo)
 		}
+	case linux.SOL_MPTCP:
+		if s.protocol != linux.IPPROTO_MPTCP {
+			break
+		}
+		switch name {
+		case linux.MPTCP_INFO:
+			if stack, ok := t.NetworkContext().(*Stack); ok && !stack.mptcpEnabled {
+				// The host socket is a TCP socket, report the fallback.
+				info := linux.MPTCPInfo{
+					Flags: linux.MPTCP_INFO_FLAG_FALLBACK,
+				}
+				buf := t.CopyScratchBuffer(info.SizeBytes())
+				info.MarshalUnsafe(buf)
+				if len(buf) > outLen {
+					buf = buf[:outLen]
+				}
+				bufP := primitive.ByteSlice(buf)
+				return &bufP, nil
+			}
+			optlen = int(linux.SizeOfMPTCPInfo)
+		}
 	}
 
 	if optlen == 0 {
@@ -664,7 +698,7 @@ func (s *socketOpsCommon) SendMsg(t *kernel.Task, src usermem.IOSequence, to []b
 	}
 	controlBuf := make([]byte, 0, space)
 	// PackControlMessages will append up to space bytes to controlBuf.
-	controlBuf = control.PackControlMessages(t, controlMessages, controlBuf)
+	controlBuf, _ = control.PackControlMessages(t, controlMessages, controlBuf, 0)
 
 	sendmsgFromBlocks := safemem.WriterFunc(func(srcs safemem.BlockSeq) (uint64, error) {
 		// Refuse to do anything if any part of src.Addrs was unusable.
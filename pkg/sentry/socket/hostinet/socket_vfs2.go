@@ -152,17 +152,20 @@ func (p *socketProviderVFS2) Socket(t *kernel.Task, stypeflags linux.SockType, p
 	if stack == nil {
 		return nil, nil
 	}
-	if _, ok := stack.(*Stack); !ok {
+	hstack, ok := stack.(*Stack)
+	if !ok {
 		return nil, nil
 	}
 
-	// Only accept TCP and UDP.
+	// Only accept TCP, MPTCP and UDP.
 	stype := stypeflags & linux.SOCK_TYPE_MASK
 	switch stype {
 	case unix.SOCK_STREAM:
 		switch protocol {
 		case 0, unix.IPPROTO_TCP:
 			// ok
+		case linux.IPPROTO_MPTCP:
+			// ok
 		default:
 			return nil, nil
 		}
@@ -180,7 +183,13 @@ func (p *socketProviderVFS2) Socket(t *kernel.Task, stypeflags linux.SockType, p
 	// Conservatively ignore all flags specified by the application and add
 	// SOCK_NONBLOCK since socketOperations requires it. Pass a protocol of 0
 	// to simplify the syscall filters, since 0 and IPPROTO_* are equivalent.
-	fd, err := unix.Socket(p.family, int(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	// IPPROTO_MPTCP is passed through if the host supports it, otherwise the
+	// socket falls back to TCP.
+	hostProtocol := 0
+	if protocol == linux.IPPROTO_MPTCP && hstack.mptcpEnabled {
+		hostProtocol = protocol
+	}
+	fd, err := unix.Socket(p.family, int(stype)|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, hostProtocol)
 	if err != nil {
 		return nil, syserr.FromError(err)
 	}
@@ -62,6 +62,7 @@ type Stack struct {
 	tcpRecvBufSize inet.TCPBufferSize
 	tcpSendBufSize inet.TCPBufferSize
 	tcpSACKEnabled bool
+	mptcpEnabled   bool
 	netDevFile     *os.File
 	netSNMPFile    *os.File
 }
@@ -111,6 +112,12 @@ func (s *Stack) Configure() error {
 		log.Warningf("Failed to read if TCP SACK if enabled, setting to true")
 	}
 
+	// MPTCP sockets are only passed through to the host if it supports them,
+	// otherwise they fall back to TCP.
+	if mptcp, err := ioutil.ReadFile("/proc/sys/net/mptcp/enabled"); err == nil {
+		s.mptcpEnabled = strings.TrimSpace(string(mptcp)) != "0"
+	}
+
 	if f, err := os.Open("/proc/net/dev"); err != nil {
 		log.Warningf("Failed to open /proc/net/dev: %v", err)
 	} else {
@@ -314,6 +321,21 @@ func (*Stack) RemoveInterface(int32) error {
 	return linuxerr.EACCES
 }
 
+// AddTunnelInterface implements inet.Stack.AddTunnelInterface.
+func (*Stack) AddTunnelInterface(string, inet.TunnelConfig) (int32, error) {
+	return 0, linuxerr.EACCES
+}
+
+// AddBondInterface implements inet.Stack.AddBondInterface.
+func (*Stack) AddBondInterface(string, inet.BondConfig) (int32, error) {
+	return 0, linuxerr.EACCES
+}
+
+// SetInterfaceMaster implements inet.Stack.SetInterfaceMaster.
+func (*Stack) SetInterfaceMaster(int32, int32) error {
+	return linuxerr.EACCES
+}
+
 // InterfaceAddrs implements inet.Stack.InterfaceAddrs.
 func (s *Stack) InterfaceAddrs() map[int32][]inet.InterfaceAddr {
 	addrs := make(map[int32][]inet.InterfaceAddr)
@@ -501,3 +523,19 @@ func (*Stack) PortRange() (uint16, uint16) {
 func (*Stack) SetPortRange(uint16, uint16) error {
 	return linuxerr.EACCES
 }
+
+// Neighbors implements inet.Stack.Neighbors.
+func (*Stack) Neighbors() ([]inet.Neighbor, error) {
+	// The host's neighbor tables aren't exposed.
+	return nil, nil
+}
+
+// AddNeighbor implements inet.Stack.AddNeighbor.
+func (*Stack) AddNeighbor(inet.Neighbor) error {
+	return linuxerr.EACCES
+}
+
+// RemoveNeighbor implements inet.Stack.RemoveNeighbor.
+func (*Stack) RemoveNeighbor(inet.Neighbor) error {
+	return linuxerr.EACCES
+}
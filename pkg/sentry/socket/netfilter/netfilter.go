@@ -47,6 +47,7 @@ const (
 	natTable    = "nat"
 	mangleTable = "mangle"
 	filterTable = "filter"
+	rawTable    = "raw"
 )
 
 // nameToID is immutable.
@@ -54,6 +55,7 @@ var nameToID = map[string]stack.TableID{
 	natTable:    stack.NATID,
 	mangleTable: stack.MangleID,
 	filterTable: stack.FilterID,
+	rawTable:    stack.RawID,
 }
 
 // DefaultLinuxTables returns the rules of stack.DefaultTables() wrapped for
@@ -186,6 +188,8 @@ func SetEntries(task *kernel.Task, stk *stack.Stack, optVal []byte, ipv6 bool) *
 		table = stack.EmptyFilterTable()
 	case natTable:
 		table = stack.EmptyNATTable()
+	case rawTable:
+		table = stack.EmptyRawTable()
 	default:
 		nflog("unknown iptables table %q", replace.Name.String())
 		return syserr.ErrInvalidArgument
@@ -252,6 +256,17 @@ func SetEntries(task *kernel.Task, stk *stack.Stack, optVal []byte, ipv6 bool) *
 		}
 	}
 
+	// NOTRACK and CT targets are only valid in the raw table, which is
+	// traversed before connections are tracked.
+	if replace.Name.String() != rawTable {
+		for _, rule := range table.Rules {
+			if nt, ok := rule.Target.(*noTrackTarget); ok {
+				nflog("%s target is only valid in the raw table", nt.name)
+				return syserr.ErrInvalidArgument
+			}
+		}
+	}
+
 	// Set each jump to point to the appropriate rule. Right now they hold byte
 	// offsets.
 	for ruleIdx, rule := range table.Rules {
@@ -41,6 +41,15 @@ const RedirectTargetName = "REDIRECT"
 // and/or IP for packets.
 const SNATTargetName = "SNAT"
 
+// NoTrackTargetName is used to mark targets as NOTRACK targets. NOTRACK
+// targets are only valid in the raw table, and exempt packets from connection
+// tracking.
+const NoTrackTargetName = "NOTRACK"
+
+// CTTargetName is used to mark targets as CT targets. Only CT targets that
+// exempt packets from connection tracking (--notrack) are supported.
+const CTTargetName = "CT"
+
 func init() {
 	// Standard targets include ACCEPT, DROP, RETURN, and JUMP.
 	registerTargetMaker(&standardTargetMaker{
@@ -72,6 +81,18 @@ func init() {
 	registerTargetMaker(&snatTargetMakerV6{
 		NetworkProtocol: header.IPv6ProtocolNumber,
 	})
+
+	for _, netProto := range []tcpip.NetworkProtocolNumber{header.IPv4ProtocolNumber, header.IPv6ProtocolNumber} {
+		registerTargetMaker(&noTrackTargetMaker{
+			NetworkProtocol: netProto,
+		})
+		for rev := uint8(0); rev <= 2; rev++ {
+			registerTargetMaker(&ctTargetMaker{
+				NetworkProtocol: netProto,
+				revision:        rev,
+			})
+		}
+	}
 }
 
 // The stack package provides some basic, useful targets for us. The following
@@ -155,6 +176,25 @@ func (st *snatTarget) id() targetID {
 	}
 }
 
+type noTrackTarget struct {
+	stack.NoTrackTarget
+
+	// name, revision, and flags must be (un)marshalled when reading and
+	// writing the target to userspace, as either a NOTRACK or CT target may
+	// have been used to create it. They do not affect behavior.
+	name     string
+	revision uint8
+	flags    uint16
+}
+
+func (nt *noTrackTarget) id() targetID {
+	return targetID{
+		name:            nt.name,
+		networkProtocol: nt.NetworkProtocol,
+		revision:        nt.revision,
+	}
+}
+
 type standardTargetMaker struct {
 	NetworkProtocol tcpip.NetworkProtocolNumber
 }
@@ -587,6 +627,135 @@ func (*snatTargetMakerV6) unmarshal(buf []byte, filter stack.IPHeaderFilter) (ta
 	return &target, nil
 }
 
+type noTrackTargetMaker struct {
+	NetworkProtocol tcpip.NetworkProtocolNumber
+}
+
+func (nm *noTrackTargetMaker) id() targetID {
+	return targetID{
+		name:            NoTrackTargetName,
+		networkProtocol: nm.NetworkProtocol,
+	}
+}
+
+func (*noTrackTargetMaker) marshal(target target) []byte {
+	// NOTRACK targets have no data.
+	xt := linux.XTEntryTarget{
+		TargetSize: linux.SizeOfXTEntryTarget,
+	}
+	copy(xt.Name[:], NoTrackTargetName)
+	return marshal.Marshal(&xt)
+}
+
+func (*noTrackTargetMaker) unmarshal(buf []byte, filter stack.IPHeaderFilter) (target, *syserr.Error) {
+	if len(buf) != linux.SizeOfXTEntryTarget {
+		nflog("noTrackTargetMaker: buf has wrong size for NOTRACK target %d", len(buf))
+		return nil, syserr.ErrInvalidArgument
+	}
+	return &noTrackTarget{
+		NoTrackTarget: stack.NoTrackTarget{
+			NetworkProtocol: filter.NetworkProtocol(),
+		},
+		name: NoTrackTargetName,
+	}, nil
+}
+
+// ctTargetMaker handles the CT target. Revision 0 uses linux.XTCTTargetV0,
+// while revisions 1 and 2 use linux.XTCTTargetV1.
+type ctTargetMaker struct {
+	NetworkProtocol tcpip.NetworkProtocolNumber
+	revision        uint8
+}
+
+func (cm *ctTargetMaker) id() targetID {
+	return targetID{
+		name:            CTTargetName,
+		networkProtocol: cm.NetworkProtocol,
+		revision:        cm.revision,
+	}
+}
+
+func (cm *ctTargetMaker) marshal(target target) []byte {
+	nt := target.(*noTrackTarget)
+	entry := linux.XTEntryTarget{
+		Revision: cm.revision,
+	}
+	copy(entry.Name[:], CTTargetName)
+	if cm.revision == 0 {
+		entry.TargetSize = linux.SizeOfXTCTTargetV0
+		xt := linux.XTCTTargetV0{
+			Target: entry,
+			Flags:  nt.flags,
+		}
+		return marshal.Marshal(&xt)
+	}
+	entry.TargetSize = linux.SizeOfXTCTTargetV1
+	xt := linux.XTCTTargetV1{
+		Target: entry,
+		Flags:  nt.flags,
+	}
+	return marshal.Marshal(&xt)
+}
+
+func (cm *ctTargetMaker) unmarshal(buf []byte, filter stack.IPHeaderFilter) (target, *syserr.Error) {
+	// Only the fields common to all revisions are relevant, as we only
+	// support CT targets without helpers, timeouts, zones, or events.
+	var (
+		flags, zone         uint16
+		ctEvents, expEvents uint32
+		helper              [16]byte
+		timeout             [32]byte
+	)
+	if cm.revision == 0 {
+		if len(buf) < linux.SizeOfXTCTTargetV0 {
+			nflog("ctTargetMaker: buf has insufficient size for CT target %d", len(buf))
+			return nil, syserr.ErrInvalidArgument
+		}
+		var xt linux.XTCTTargetV0
+		xt.UnmarshalUnsafe(buf[:linux.SizeOfXTCTTargetV0])
+		flags, zone, ctEvents, expEvents, helper = xt.Flags, xt.Zone, xt.CTEvents, xt.ExpEvents, xt.Helper
+	} else {
+		if len(buf) < linux.SizeOfXTCTTargetV1 {
+			nflog("ctTargetMaker: buf has insufficient size for CT target %d", len(buf))
+			return nil, syserr.ErrInvalidArgument
+		}
+		var xt linux.XTCTTargetV1
+		xt.UnmarshalUnsafe(buf[:linux.SizeOfXTCTTargetV1])
+		flags, zone, ctEvents, expEvents, helper, timeout = xt.Flags, xt.Zone, xt.CTEvents, xt.ExpEvents, xt.Helper, xt.Timeout
+	}
+
+	// Revision 2 added the remaining flags. See
+	// net/netfilter/xt_CT.c:xt_ct_tg_check_v1.
+	validFlags := uint16(linux.XT_CT_NOTRACK)
+	if cm.revision >= 2 {
+		validFlags = linux.XT_CT_MASK
+	}
+	if flags&^validFlags != 0 {
+		nflog("ctTargetMaker: invalid flags %#x for revision %d", flags, cm.revision)
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	// TODO(gvisor.dev/issue/6167): Support CT targets that configure
+	// tracked connections.
+	if flags&linux.XT_CT_NOTRACK == 0 || flags&^(linux.XT_CT_NOTRACK|linux.XT_CT_NOTRACK_ALIAS) != 0 {
+		nflog("ctTargetMaker: only --notrack is supported, got flags %#x", flags)
+		return nil, syserr.ErrInvalidArgument
+	}
+	if zone != 0 || ctEvents != 0 || expEvents != 0 || helper != ([16]byte{}) || timeout != ([32]byte{}) {
+		nflog("ctTargetMaker: only --notrack is supported")
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	return &noTrackTarget{
+		NoTrackTarget: stack.NoTrackTarget{
+			NetworkProtocol: filter.NetworkProtocol(),
+		},
+		name:     CTTargetName,
+		revision: cm.revision,
+		flags:    flags,
+	}, nil
+}
+
 // translateToStandardTarget translates from the value in a
 // linux.XTStandardTarget to an stack.Verdict.
 func translateToStandardTarget(val int32, netProto tcpip.NetworkProtocolNumber) (target, *syserr.Error) {
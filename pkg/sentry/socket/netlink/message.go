@@ -189,6 +189,30 @@ func (m *Message) PutAttrString(atype uint16, s string) {
 	m.putZeros(aligned - l)
 }
 
+// BeginNestedAttr starts a netlink attribute of type atype holding the
+// attributes added to the message until EndNestedAttr is called with the
+// returned value.
+func (m *Message) BeginNestedAttr(atype uint16) int {
+	start := len(m.buf)
+	m.Put(&linux.NetlinkAttrHeader{
+		Type:   atype,
+		Length: linux.NetlinkAttrHeaderSize,
+	})
+	return start
+}
+
+// EndNestedAttr completes the nested attribute started by BeginNestedAttr.
+//
+// Preconditions: The nested attribute fits in math.MaxUint16 bytes.
+func (m *Message) EndNestedAttr(start int) {
+	l := len(m.buf) - start
+	if l > math.MaxUint16 {
+		panic(fmt.Sprintf("attribute too large: %d", l))
+	}
+	// Length is the first field of struct nlattr.
+	hostarch.ByteOrder.PutUint16(m.buf[start:], uint16(l))
+}
+
 // MessageSet contains a series of netlink messages.
 type MessageSet struct {
 	// Multi indicates that this a multi-part message, to be terminated by
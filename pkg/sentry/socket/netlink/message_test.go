@@ -328,3 +328,54 @@ func TestAttrView(t *testing.T) {
 		}
 	}
 }
+
+func TestNestedAttr(t *testing.T) {
+	m := netlink.NewMessage(linux.NetlinkMessageHeader{})
+	m.Put(primitive.AllocateUint32(0x1234))
+	start := m.BeginNestedAttr(1)
+	m.PutAttrString(2, "ab")
+	m.PutAttr(3, primitive.AllocateUint32(0x5678))
+	m.EndNestedAttr(start)
+	m.PutAttr(4, primitive.AllocateUint16(0x9abc))
+
+	msg, _, ok := netlink.ParseMessage(m.Finalize())
+	if !ok {
+		t.Fatal("ParseMessage failed")
+	}
+	var data primitive.Uint32
+	attrs, ok := msg.GetData(&data)
+	if !ok {
+		t.Fatal("GetData failed")
+	}
+
+	hdr, value, rest, ok := attrs.ParseFirst()
+	if !ok {
+		t.Fatal("ParseFirst failed for the nested attribute")
+	}
+	// 4 bytes of header, 4+3 bytes of string attribute padded to 8, and 4+4
+	// bytes of uint32 attribute.
+	if want := (linux.NetlinkAttrHeader{Type: 1, Length: 20}); hdr != want {
+		t.Errorf("got hdr = %+v, want = %+v", hdr, want)
+	}
+
+	nested := netlink.AttrsView(value)
+	hdr, value, nested, ok = nested.ParseFirst()
+	if !ok || hdr.Type != 2 || !bytes.Equal(value, []byte("ab\x00")) {
+		t.Errorf("got first nested attribute = (%+v, %v, %t), want type 2 with value %v", hdr, value, ok, []byte("ab\x00"))
+	}
+	hdr, value, nested, ok = nested.ParseFirst()
+	if !ok || hdr.Type != 3 || len(value) != 4 {
+		t.Errorf("got second nested attribute = (%+v, %v, %t), want type 3 with a 4 byte value", hdr, value, ok)
+	}
+	if !nested.Empty() {
+		t.Errorf("got %d bytes after the nested attributes, want 0", len(nested))
+	}
+
+	hdr, _, rest, ok = rest.ParseFirst()
+	if !ok || hdr.Type != 4 {
+		t.Errorf("got attribute after the nested attribute = (%+v, %t), want type 4", hdr, ok)
+	}
+	if !rest.Empty() {
+		t.Errorf("got %d bytes after the attributes, want 0", len(rest))
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netlink
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// maxGroups is the number of multicast groups a socket may be a member of.
+// Groups are numbered from 1 to maxGroups.
+//
+// Linux supports an arbitrary number of groups per protocol, but only the
+// first 32 can be joined with bind(2), and no protocol we implement has more.
+const maxGroups = 32
+
+// multicastMu protects multicastSockets and socketOpsCommon.groups.
+var multicastMu sync.Mutex
+
+// multicastSockets is the set of netlink sockets that are members of at least
+// one multicast group.
+var multicastSockets = make(map[*socketOpsCommon]struct{})
+
+// checkGroups returns an error if the bitmask groups contains a group to which
+// the protocol of s never sends messages. Like before multicast was
+// supported, such memberships are refused rather than leaving the socket to
+// wait for messages that never arrive.
+func (s *socketOpsCommon) checkGroups(groups uint32) *syserr.Error {
+	if groups&^s.protocol.MulticastGroups() != 0 {
+		return syserr.ErrPermissionDenied
+	}
+	return nil
+}
+
+// setGroupsLocked sets the multicast groups of s to the bitmask groups, where
+// bit n-1 stands for group n.
+//
+// Preconditions: multicastMu is locked.
+func (s *socketOpsCommon) setGroupsLocked(groups uint32) {
+	s.groups = groups
+	if groups != 0 {
+		multicastSockets[s] = struct{}{}
+	} else {
+		delete(multicastSockets, s)
+	}
+}
+
+// setGroups sets the multicast groups of s to the bitmask groups.
+func (s *socketOpsCommon) setGroups(groups uint32) {
+	multicastMu.Lock()
+	defer multicastMu.Unlock()
+	s.setGroupsLocked(groups)
+}
+
+// joinGroup adds s to the multicast group, or removes it if join is false.
+func (s *socketOpsCommon) joinGroup(group uint32, join bool) {
+	multicastMu.Lock()
+	defer multicastMu.Unlock()
+	if join {
+		s.setGroupsLocked(s.groups | 1<<(group-1))
+	} else {
+		s.setGroupsLocked(s.groups &^ (1 << (group - 1)))
+	}
+}
+
+// getGroups returns the bitmask of the multicast groups of s.
+func (s *socketOpsCommon) getGroups() uint32 {
+	multicastMu.Lock()
+	defer multicastMu.Unlock()
+	return s.groups
+}
+
+// afterLoad is invoked by stateify.
+func (s *socketOpsCommon) afterLoad() {
+	if s.groups != 0 {
+		multicastMu.Lock()
+		multicastSockets[s] = struct{}{}
+		multicastMu.Unlock()
+	}
+}
+
+// Multicast sends the messages in ms to all sockets of the given netlink
+// protocol that are members of group in the network namespace of stack.
+//
+// Messages are delivered as if sent by the kernel without being requested,
+// i.e. with a zero port ID and sequence number. Like Linux, messages are
+// dropped for sockets whose receive buffer is full.
+//
+// Multicast may be called with stack locks held, so it must not call back
+// into stack.
+func Multicast(ctx context.Context, stack inet.Stack, protocol int, group uint32, ms *MessageSet) {
+	if group == 0 || group > maxGroups {
+		return
+	}
+	mask := uint32(1) << (group - 1)
+
+	multicastMu.Lock()
+	var members []*socketOpsCommon
+	for s := range multicastSockets {
+		if s.groups&mask == 0 || s.protocol.Protocol() != protocol {
+			continue
+		}
+		if s.netns == nil || s.netns.Stack() != stack {
+			continue
+		}
+		members = append(members, s)
+	}
+	multicastMu.Unlock()
+
+	if len(members) == 0 {
+		return
+	}
+
+	bufs := make([][]byte, 0, len(ms.Messages))
+	for _, m := range ms.Messages {
+		bufs = append(bufs, m.Finalize())
+	}
+	cms := transport.ControlMessages{
+		Credentials: kernelCreds,
+	}
+	for _, s := range members {
+		_, notify, err := s.connection.Send(ctx, bufs, cms, tcpip.FullAddress{})
+		if err != nil && err != syserr.ErrWouldBlock {
+			log.Debugf("Failed to multicast netlink message to group %d: %v", group, err)
+			continue
+		}
+		if notify {
+			s.connection.SendNotify()
+		}
+	}
+}
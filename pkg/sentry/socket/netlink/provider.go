@@ -37,6 +37,11 @@ type Protocol interface {
 	// that will never send messages, thus making those features no-ops.
 	CanSend() bool
 
+	// MulticastGroups returns the bitmask of multicast groups to which this
+	// protocol sends messages, where bit n-1 stands for group n. Sockets may
+	// only join these groups.
+	MulticastGroups() uint32
+
 	// ProcessMessage processes a single message from userspace.
 	//
 	// If err == nil, any messages added to ms will be sent back to the
@@ -17,10 +17,12 @@ package route
 
 import (
 	"bytes"
+	"encoding/binary"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -68,6 +70,13 @@ func (p *Protocol) CanSend() bool {
 	return true
 }
 
+// MulticastGroups implements netlink.Protocol.MulticastGroups.
+//
+// Only neighbor table changes are announced; see neighborObserver.
+func (p *Protocol) MulticastGroups() uint32 {
+	return 1 << (linux.RTNLGRP_NEIGH - 1)
+}
+
 // dumpLinks handles RTM_GETLINK dump requests.
 func (p *Protocol) dumpLinks(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
 	// NLM_F_DUMP + RTM_GETLINK messages are supposed to include an
@@ -202,6 +211,304 @@ func (p *Protocol) delLink(ctx context.Context, msg *netlink.Message, ms *netlin
 	return syserr.FromError(stack.RemoveInterface(ifinfomsg.Index))
 }
 
+// linkRequest holds the attributes of RTM_NEWLINK and RTM_SETLINK requests.
+type linkRequest struct {
+	name      string
+	mtu       uint32
+	kind      string
+	infoData  netlink.AttrsView
+	master    int32
+	hasMaster bool
+}
+
+// parseLinkRequest parses the attributes of RTM_NEWLINK and RTM_SETLINK
+// requests.
+func parseLinkRequest(attrs netlink.AttrsView) (linkRequest, *syserr.Error) {
+	var req linkRequest
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return req, syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.IFLA_IFNAME:
+			req.name = attrString(value)
+		case linux.IFLA_MTU:
+			if len(value) < 4 {
+				return req, syserr.ErrInvalidArgument
+			}
+			req.mtu = hostarch.ByteOrder.Uint32(value)
+		case linux.IFLA_MASTER:
+			if len(value) < 4 {
+				return req, syserr.ErrInvalidArgument
+			}
+			req.master = int32(hostarch.ByteOrder.Uint32(value))
+			req.hasMaster = true
+		case linux.IFLA_LINKINFO:
+			info := netlink.AttrsView(value)
+			for !info.Empty() {
+				ahdr, value, rest, ok := info.ParseFirst()
+				if !ok {
+					return req, syserr.ErrInvalidArgument
+				}
+				info = rest
+
+				switch ahdr.Type & linux.NLA_TYPE_MASK {
+				case linux.IFLA_INFO_KIND:
+					req.kind = attrString(value)
+				case linux.IFLA_INFO_DATA:
+					req.infoData = netlink.AttrsView(value)
+				}
+			}
+		}
+	}
+	return req, nil
+}
+
+// findLink returns the index of the link identified by the index in ifinfomsg
+// or, if it is zero, by name. It returns zero if there is no such link.
+func findLink(stack inet.Stack, ifinfomsg *linux.InterfaceInfoMessage, name string) int32 {
+	interfaces := stack.Interfaces()
+	if ifinfomsg.Index != 0 {
+		if _, ok := interfaces[ifinfomsg.Index]; ok {
+			return ifinfomsg.Index
+		}
+		return 0
+	}
+	if name == "" {
+		return 0
+	}
+	for idx, i := range interfaces {
+		if i.Name == name {
+			return idx
+		}
+	}
+	return 0
+}
+
+// changeLink applies the changes requested by req to the link idx.
+//
+// Only enslaving links to bonds and releasing them is supported.
+func changeLink(stack inet.Stack, idx int32, req *linkRequest) *syserr.Error {
+	if !req.hasMaster {
+		// TODO(gvisor.dev/issue/578): Support changing other attributes of
+		// existing links.
+		return syserr.ErrNotSupported
+	}
+	return syserr.FromError(stack.SetInterfaceMaster(idx, req.master))
+}
+
+// newLink handles RTM_NEWLINK requests.
+//
+// Only the creation of tunnel and bond links, and the enslaving of existing
+// links, are supported.
+func (p *Protocol) newLink(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
+	stack := inet.StackFromContext(ctx)
+	if stack == nil {
+		// No network stack.
+		return syserr.ErrProtocolNotSupported
+	}
+
+	var ifinfomsg linux.InterfaceInfoMessage
+	attrs, ok := msg.GetData(&ifinfomsg)
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	req, err := parseLinkRequest(attrs)
+	if err != nil {
+		return err
+	}
+
+	if idx := findLink(stack, &ifinfomsg, req.name); idx != 0 {
+		if msg.Header().Flags&linux.NLM_F_EXCL != 0 {
+			return syserr.ErrExists
+		}
+		return changeLink(stack, idx, &req)
+	}
+	if ifinfomsg.Index != 0 {
+		// Links can't be created with a given index.
+		return syserr.ErrNotSupported
+	}
+	if msg.Header().Flags&linux.NLM_F_CREATE == 0 {
+		return syserr.ErrNoDevice
+	}
+
+	var idx int32
+	switch req.kind {
+	case "gre", "gretap", "ip6gre", "ipip":
+		config := inet.TunnelConfig{
+			Kind: req.kind,
+			MTU:  req.mtu,
+		}
+		var err *syserr.Error
+		if req.kind == "ipip" {
+			err = parseIPTunnelInfoData(req.infoData, &config)
+		} else {
+			err = parseGREInfoData(req.infoData, &config)
+		}
+		if err != nil {
+			return err
+		}
+		i, e := stack.AddTunnelInterface(req.name, config)
+		if e != nil {
+			return syserr.FromError(e)
+		}
+		idx = i
+	case "bond":
+		var config inet.BondConfig
+		if err := parseBondInfoData(req.infoData, &config); err != nil {
+			return err
+		}
+		i, e := stack.AddBondInterface(req.name, config)
+		if e != nil {
+			return syserr.FromError(e)
+		}
+		idx = i
+	default:
+		return syserr.ErrNotSupported
+	}
+
+	if req.hasMaster {
+		return syserr.FromError(stack.SetInterfaceMaster(idx, req.master))
+	}
+	return nil
+}
+
+// setLink handles RTM_SETLINK requests.
+func (p *Protocol) setLink(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
+	stack := inet.StackFromContext(ctx)
+	if stack == nil {
+		// No network stack.
+		return syserr.ErrProtocolNotSupported
+	}
+
+	var ifinfomsg linux.InterfaceInfoMessage
+	attrs, ok := msg.GetData(&ifinfomsg)
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	req, err := parseLinkRequest(attrs)
+	if err != nil {
+		return err
+	}
+
+	idx := findLink(stack, &ifinfomsg, req.name)
+	if idx == 0 {
+		return syserr.ErrNoDevice
+	}
+	return changeLink(stack, idx, &req)
+}
+
+// parseBondInfoData parses the IFLA_INFO_DATA attributes of a bond link into
+// config.
+func parseBondInfoData(attrs netlink.AttrsView, config *inet.BondConfig) *syserr.Error {
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.IFLA_BOND_MODE:
+			if len(value) < 1 {
+				return syserr.ErrInvalidArgument
+			}
+			config.Mode = value[0]
+		case linux.IFLA_BOND_MIIMON:
+			if len(value) < 4 {
+				return syserr.ErrInvalidArgument
+			}
+			config.MIIMon = hostarch.ByteOrder.Uint32(value)
+		}
+	}
+	return nil
+}
+
+// parseGREInfoData parses the IFLA_INFO_DATA attributes of a GRE link into
+// config.
+func parseGREInfoData(attrs netlink.AttrsView, config *inet.TunnelConfig) *syserr.Error {
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		var minLen int
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.IFLA_GRE_TTL:
+			minLen = 1
+		case linux.IFLA_GRE_IFLAGS, linux.IFLA_GRE_OFLAGS:
+			minLen = 2
+		case linux.IFLA_GRE_IKEY, linux.IFLA_GRE_OKEY:
+			minLen = 4
+		}
+		if len(value) < minLen {
+			return syserr.ErrInvalidArgument
+		}
+
+		// Flags and keys are in network byte order.
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.IFLA_GRE_LOCAL:
+			config.Local = value
+		case linux.IFLA_GRE_REMOTE:
+			config.Remote = value
+		case linux.IFLA_GRE_TTL:
+			config.TTL = value[0]
+		case linux.IFLA_GRE_IFLAGS:
+			config.IFlags = binary.BigEndian.Uint16(value)
+		case linux.IFLA_GRE_OFLAGS:
+			config.OFlags = binary.BigEndian.Uint16(value)
+		case linux.IFLA_GRE_IKEY:
+			config.IKey = binary.BigEndian.Uint32(value)
+		case linux.IFLA_GRE_OKEY:
+			config.OKey = binary.BigEndian.Uint32(value)
+		}
+	}
+
+	if (config.IFlags|config.OFlags)&^(linux.GRE_CSUM|linux.GRE_KEY|linux.GRE_SEQ) != 0 {
+		return syserr.ErrInvalidArgument
+	}
+	return nil
+}
+
+// parseIPTunnelInfoData parses the IFLA_INFO_DATA attributes of an IP-in-IP
+// link into config.
+func parseIPTunnelInfoData(attrs netlink.AttrsView, config *inet.TunnelConfig) *syserr.Error {
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.IFLA_IPTUN_LOCAL:
+			config.Local = value
+		case linux.IFLA_IPTUN_REMOTE:
+			config.Remote = value
+		case linux.IFLA_IPTUN_TTL:
+			if len(value) < 1 {
+				return syserr.ErrInvalidArgument
+			}
+			config.TTL = value[0]
+		}
+	}
+	return nil
+}
+
+// attrString returns the string held by a netlink attribute, which may or may
+// not be NUL-terminated.
+func attrString(value []byte) string {
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return string(value)
+}
+
 // addNewLinkMessage appends RTM_NEWLINK message for the given interface into
 // the message set.
 func addNewLinkMessage(ms *netlink.MessageSet, idx int32, i inet.Interface) {
@@ -228,6 +535,32 @@ func addNewLinkMessage(ms *netlink.MessageSet, idx int32, i inet.Interface) {
 	m.PutAttr(linux.IFLA_ADDRESS, primitive.AsByteSlice(mac))
 	m.PutAttr(linux.IFLA_BROADCAST, primitive.AsByteSlice(brd))
 
+	if i.Master != 0 {
+		m.PutAttr(linux.IFLA_MASTER, primitive.AllocateUint32(uint32(i.Master)))
+	}
+	if i.Bond != nil || i.BondSlave != nil {
+		linkInfo := m.BeginNestedAttr(linux.IFLA_LINKINFO)
+		if b := i.Bond; b != nil {
+			m.PutAttrString(linux.IFLA_INFO_KIND, "bond")
+			data := m.BeginNestedAttr(linux.IFLA_INFO_DATA)
+			m.PutAttr(linux.IFLA_BOND_MODE, primitive.AllocateUint8(b.Mode))
+			if b.ActiveSlave != 0 {
+				m.PutAttr(linux.IFLA_BOND_ACTIVE_SLAVE, primitive.AllocateUint32(uint32(b.ActiveSlave)))
+			}
+			m.PutAttr(linux.IFLA_BOND_MIIMON, primitive.AllocateUint32(b.MIIMon))
+			m.EndNestedAttr(data)
+		}
+		if bs := i.BondSlave; bs != nil {
+			m.PutAttrString(linux.IFLA_INFO_SLAVE_KIND, "bond")
+			data := m.BeginNestedAttr(linux.IFLA_INFO_SLAVE_DATA)
+			m.PutAttr(linux.IFLA_BOND_SLAVE_STATE, primitive.AllocateUint8(bs.State))
+			m.PutAttr(linux.IFLA_BOND_SLAVE_MII_STATUS, primitive.AllocateUint8(bs.MIIStatus))
+			m.PutAttr(linux.IFLA_BOND_SLAVE_LINK_FAILURE_COUNT, primitive.AllocateUint32(bs.LinkFailureCount))
+			m.EndNestedAttr(data)
+		}
+		m.EndNestedAttr(linkInfo)
+	}
+
 	// TODO(gvisor.dev/issue/578): There are many more attributes.
 }
 
@@ -541,6 +874,207 @@ func (p *Protocol) delAddr(ctx context.Context, msg *netlink.Message, ms *netlin
 	return nil
 }
 
+// addNeighborMessage appends a message of type typ describing the neighbor
+// table entry n into the message set.
+func addNeighborMessage(ms *netlink.MessageSet, typ uint16, n inet.Neighbor) {
+	m := ms.AddMessage(linux.NetlinkMessageHeader{
+		Type: typ,
+	})
+
+	m.Put(&linux.NeighborMessage{
+		Family:  n.Family,
+		Ifindex: n.Index,
+		State:   n.State,
+		Type:    linux.RTN_UNICAST,
+	})
+
+	m.PutAttr(linux.NDA_DST, primitive.AsByteSlice(n.Addr))
+	if len(n.LinkAddr) > 0 {
+		m.PutAttr(linux.NDA_LLADDR, primitive.AsByteSlice(n.LinkAddr))
+	}
+
+	// TODO(gvisor.dev/issue/578): Support NDA_CACHEINFO and NDA_PROBES.
+}
+
+// dumpNeighbors handles RTM_GETNEIGH dump requests.
+func (p *Protocol) dumpNeighbors(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
+	// We always send back an NLMSG_DONE.
+	ms.Multi = true
+
+	stack := inet.StackFromContext(ctx)
+	if stack == nil {
+		// No network devices.
+		return nil
+	}
+
+	// Dump requests may contain a struct ndmsg, optionally followed by an
+	// NDA_IFINDEX attribute, to filter the output by family and interface.
+	// Older versions of iproute2 only send a struct rtgenmsg.
+	var ndm linux.NeighborMessage
+	attrs, ok := msg.GetData(&ndm)
+	if !ok {
+		var family primitive.Uint8
+		msg.GetData(&family)
+		ndm = linux.NeighborMessage{Family: uint8(family)}
+	}
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		if ahdr.Type&linux.NLA_TYPE_MASK == linux.NDA_IFINDEX {
+			if len(value) < 4 {
+				return syserr.ErrInvalidArgument
+			}
+			ndm.Ifindex = int32(hostarch.ByteOrder.Uint32(value))
+		}
+	}
+
+	neighbors, err := stack.Neighbors()
+	if err != nil {
+		return syserr.FromError(err)
+	}
+	for _, n := range neighbors {
+		if ndm.Family != linux.AF_UNSPEC && ndm.Family != n.Family {
+			continue
+		}
+		if ndm.Ifindex != 0 && ndm.Ifindex != n.Index {
+			continue
+		}
+		addNeighborMessage(ms, linux.RTM_NEWNEIGH, n)
+	}
+	return nil
+}
+
+// parseNeighbor parses the struct ndmsg and attributes of RTM_NEWNEIGH and
+// RTM_DELNEIGH requests.
+func parseNeighbor(stack inet.Stack, msg *netlink.Message) (inet.Neighbor, *syserr.Error) {
+	var ndm linux.NeighborMessage
+	attrs, ok := msg.GetData(&ndm)
+	if !ok {
+		return inet.Neighbor{}, syserr.ErrInvalidArgument
+	}
+	n := inet.Neighbor{
+		Family: ndm.Family,
+		Index:  ndm.Ifindex,
+		State:  ndm.State,
+	}
+	for !attrs.Empty() {
+		ahdr, value, rest, ok := attrs.ParseFirst()
+		if !ok {
+			return n, syserr.ErrInvalidArgument
+		}
+		attrs = rest
+
+		switch ahdr.Type & linux.NLA_TYPE_MASK {
+		case linux.NDA_DST:
+			n.Addr = value
+		case linux.NDA_LLADDR:
+			n.LinkAddr = value
+		}
+	}
+
+	if ndm.Flags&linux.NTF_PROXY != 0 {
+		// TODO(gvisor.dev/issue/578): Support proxy ARP and NDP.
+		return n, syserr.ErrNotSupported
+	}
+	if n.Addr == nil || n.Index == 0 {
+		return n, syserr.ErrInvalidArgument
+	}
+	if _, ok := stack.Interfaces()[n.Index]; !ok {
+		return n, syserr.ErrNoDevice
+	}
+	return n, nil
+}
+
+// findNeighbor returns whether the neighbor table of stack has an entry for
+// the address and interface of n.
+func findNeighbor(stack inet.Stack, n *inet.Neighbor) (bool, error) {
+	neighbors, err := stack.Neighbors()
+	if err != nil {
+		return false, err
+	}
+	for _, old := range neighbors {
+		if old.Index == n.Index && old.Family == n.Family && bytes.Equal(old.Addr, n.Addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newNeighbor handles RTM_NEWNEIGH requests.
+//
+// Only static entries, i.e. NUD_PERMANENT and NUD_NOARP ones, can be added.
+func (p *Protocol) newNeighbor(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
+	stack := inet.StackFromContext(ctx)
+	if stack == nil {
+		// No network stack.
+		return syserr.ErrProtocolNotSupported
+	}
+
+	n, err := parseNeighbor(stack, msg)
+	if err != nil {
+		return err
+	}
+	if n.State&(linux.NUD_PERMANENT|linux.NUD_NOARP) == 0 {
+		// Dynamic entries are managed by the stack.
+		return syserr.ErrNotSupported
+	}
+
+	exists, e := findNeighbor(stack, &n)
+	if e != nil {
+		return syserr.FromError(e)
+	}
+	flags := msg.Header().Flags
+	if exists && flags&linux.NLM_F_EXCL != 0 {
+		return syserr.ErrExists
+	}
+	if !exists && flags&linux.NLM_F_CREATE == 0 {
+		return syserr.ErrNoFileOrDir
+	}
+	return syserr.FromError(stack.AddNeighbor(n))
+}
+
+// delNeighbor handles RTM_DELNEIGH requests.
+func (p *Protocol) delNeighbor(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
+	stack := inet.StackFromContext(ctx)
+	if stack == nil {
+		// No network stack.
+		return syserr.ErrProtocolNotSupported
+	}
+
+	n, err := parseNeighbor(stack, msg)
+	if err != nil {
+		return err
+	}
+	return syserr.FromError(stack.RemoveNeighbor(n))
+}
+
+// neighborObserver implements inet.NeighborObserver by multicasting changes to
+// neighbor tables to the RTNLGRP_NEIGH group.
+type neighborObserver struct{}
+
+// NeighborChanged implements inet.NeighborObserver.NeighborChanged.
+func (neighborObserver) NeighborChanged(stack inet.Stack, n inet.Neighbor) {
+	notifyNeighbor(stack, linux.RTM_NEWNEIGH, n)
+}
+
+// NeighborRemoved implements inet.NeighborObserver.NeighborRemoved.
+func (neighborObserver) NeighborRemoved(stack inet.Stack, n inet.Neighbor) {
+	notifyNeighbor(stack, linux.RTM_DELNEIGH, n)
+}
+
+// notifyNeighbor sends a message of type typ describing n to the RTNLGRP_NEIGH
+// group of the network namespace of stack.
+func notifyNeighbor(stack inet.Stack, typ uint16, n inet.Neighbor) {
+	ms := netlink.NewMessageSet(0, 0)
+	addNeighborMessage(ms, typ, n)
+	// Notifications are sent from netstack rather than on behalf of a task.
+	netlink.Multicast(context.Background(), stack, linux.NETLINK_ROUTE, linux.RTNLGRP_NEIGH, ms)
+}
+
 // ProcessMessage implements netlink.Protocol.ProcessMessage.
 func (p *Protocol) ProcessMessage(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
 	hdr := msg.Header()
@@ -571,6 +1105,8 @@ func (p *Protocol) ProcessMessage(ctx context.Context, msg *netlink.Message, ms
 			return p.dumpAddrs(ctx, msg, ms)
 		case linux.RTM_GETROUTE:
 			return p.dumpRoutes(ctx, msg, ms)
+		case linux.RTM_GETNEIGH:
+			return p.dumpNeighbors(ctx, msg, ms)
 		default:
 			return syserr.ErrNotSupported
 		}
@@ -578,14 +1114,22 @@ func (p *Protocol) ProcessMessage(ctx context.Context, msg *netlink.Message, ms
 		switch hdr.Type {
 		case linux.RTM_GETLINK:
 			return p.getLink(ctx, msg, ms)
+		case linux.RTM_NEWLINK:
+			return p.newLink(ctx, msg, ms)
 		case linux.RTM_DELLINK:
 			return p.delLink(ctx, msg, ms)
+		case linux.RTM_SETLINK:
+			return p.setLink(ctx, msg, ms)
 		case linux.RTM_GETROUTE:
 			return p.dumpRoutes(ctx, msg, ms)
 		case linux.RTM_NEWADDR:
 			return p.newAddr(ctx, msg, ms)
 		case linux.RTM_DELADDR:
 			return p.delAddr(ctx, msg, ms)
+		case linux.RTM_NEWNEIGH:
+			return p.newNeighbor(ctx, msg, ms)
+		case linux.RTM_DELNEIGH:
+			return p.delNeighbor(ctx, msg, ms)
 		default:
 			return syserr.ErrNotSupported
 		}
@@ -593,7 +1137,9 @@ func (p *Protocol) ProcessMessage(ctx context.Context, msg *netlink.Message, ms
 	return syserr.ErrNotSupported
 }
 
-// init registers the NETLINK_ROUTE provider.
+// init registers the NETLINK_ROUTE provider and the observer of neighbor
+// tables.
 func init() {
 	netlink.RegisterProvider(linux.NETLINK_ROUTE, NewProtocol)
+	inet.RegisterNeighborObserver(neighborObserver{})
 }
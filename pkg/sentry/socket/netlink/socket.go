@@ -30,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/device"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
@@ -127,6 +128,14 @@ type socketOpsCommon struct {
 	// TODO(gvisor.dev/issue/1119): We don't actually support filtering,
 	// this is just bookkeeping for tracking add/remove.
 	filter bool
+
+	// netns is the network namespace the socket was created in. Multicast
+	// messages are only delivered to sockets of the namespace they concern.
+	netns *inet.Namespace
+
+	// groups is the bitmask of multicast groups the socket is a member of.
+	// Bit n-1 stands for group n. It is protected by multicastMu.
+	groups uint32
 }
 
 var _ socket.Socket = (*Socket)(nil)
@@ -159,12 +168,14 @@ func NewSocket(t *kernel.Task, skType linux.SockType, protocol Protocol) (*Socke
 			ep:             ep,
 			connection:     connection,
 			sendBufferSize: defaultSendBufferSize,
+			netns:          t.NetworkNamespace(),
 		},
 	}, nil
 }
 
 // Release implements fs.FileOperations.Release.
 func (s *socketOpsCommon) Release(ctx context.Context) {
+	s.setGroups(0)
 	s.connection.Release(ctx)
 	s.ep.Close(ctx)
 
@@ -268,15 +279,20 @@ func (s *socketOpsCommon) Bind(t *kernel.Task, sockaddr []byte) *syserr.Error {
 		return err
 	}
 
-	// No support for multicast groups yet.
-	if a.Groups != 0 {
-		return syserr.ErrPermissionDenied
+	if err := s.checkGroups(a.Groups); err != nil {
+		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.bindPort(t, int32(a.PortID))
+	if err := s.bindPort(t, int32(a.PortID)); err != nil {
+		return err
+	}
+
+	// Like Linux, bind(2) replaces the memberships of the first 32 groups.
+	s.setGroups(a.Groups)
+	return nil
 }
 
 // Connect implements socket.Socket.Connect.
@@ -286,7 +302,7 @@ func (s *socketOpsCommon) Connect(t *kernel.Task, sockaddr []byte, blocking bool
 		return err
 	}
 
-	// No support for multicast groups yet.
+	// We don't support sending to multicast groups.
 	if a.Groups != 0 {
 		return syserr.ErrPermissionDenied
 	}
@@ -311,9 +327,9 @@ func (s *socketOpsCommon) Connect(t *kernel.Task, sockaddr []byte, blocking bool
 }
 
 // Accept implements socket.Socket.Accept.
-func (s *socketOpsCommon) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+func (s *socketOpsCommon) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	// Netlink sockets never support accept.
-	return 0, nil, 0, syserr.ErrNotSupported
+	return 0, syserr.ErrNotSupported
 }
 
 // Listen implements socket.Socket.Listen.
@@ -364,11 +380,16 @@ func (s *socketOpsCommon) GetSockOpt(t *kernel.Task, level int, name int, outPtr
 
 	case linux.SOL_NETLINK:
 		switch name {
+		case linux.NETLINK_LIST_MEMBERSHIPS:
+			if outLen < sizeOfInt32 {
+				return nil, syserr.ErrInvalidArgument
+			}
+			return primitive.AllocateUint32(s.getGroups()), nil
+
 		case linux.NETLINK_BROADCAST_ERROR,
 			linux.NETLINK_CAP_ACK,
 			linux.NETLINK_DUMP_STRICT_CHK,
 			linux.NETLINK_EXT_ACK,
-			linux.NETLINK_LIST_MEMBERSHIPS,
 			linux.NETLINK_NO_ENOBUFS,
 			linux.NETLINK_PKTINFO:
 
@@ -456,10 +477,24 @@ func (s *socketOpsCommon) SetSockOpt(t *kernel.Task, level int, name int, opt []
 
 	case linux.SOL_NETLINK:
 		switch name {
-		case linux.NETLINK_ADD_MEMBERSHIP,
-			linux.NETLINK_BROADCAST_ERROR,
+		case linux.NETLINK_ADD_MEMBERSHIP, linux.NETLINK_DROP_MEMBERSHIP:
+			if len(opt) < sizeOfInt32 {
+				return syserr.ErrInvalidArgument
+			}
+			group := hostarch.ByteOrder.Uint32(opt)
+			if group == 0 || group > maxGroups {
+				return syserr.ErrInvalidArgument
+			}
+			if name == linux.NETLINK_ADD_MEMBERSHIP {
+				if err := s.checkGroups(1 << (group - 1)); err != nil {
+					return err
+				}
+			}
+			s.joinGroup(group, name == linux.NETLINK_ADD_MEMBERSHIP)
+			return nil
+
+		case linux.NETLINK_BROADCAST_ERROR,
 			linux.NETLINK_CAP_ACK,
-			linux.NETLINK_DROP_MEMBERSHIP,
 			linux.NETLINK_DUMP_STRICT_CHK,
 			linux.NETLINK_EXT_ACK,
 			linux.NETLINK_LISTEN_ALL_NSID,
@@ -517,11 +552,10 @@ func (s *socketOpsCommon) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags
 		return dst.CopyOutFrom(t, &r)
 	}
 
-	// If MSG_TRUNC is set with a zero byte destination then we still need
-	// to read the message and discard it, or in the case where MSG_PEEK is
-	// set, leave it be. In both cases the full message length must be
-	// returned.
-	if trunc && dst.Addrs.NumBytes() == 0 {
+	// A zero byte destination can't be read into, but the message must still
+	// be read and discarded, or in the case where MSG_PEEK is set, left be.
+	// With MSG_TRUNC, the full message length must be returned.
+	if dst.Addrs.NumBytes() == 0 {
 		doRead = func() (int64, error) {
 			err := r.Truncate()
 			// Always return zero for bytes read since the destination size is
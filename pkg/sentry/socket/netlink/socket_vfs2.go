@@ -77,6 +77,7 @@ func NewVFS2(t *kernel.Task, skType linux.SockType, protocol Protocol) (*SocketV
 			ep:             ep,
 			connection:     connection,
 			sendBufferSize: defaultSendBufferSize,
+			netns:          t.NetworkNamespace(),
 		},
 	}
 	fd.LockFD.Init(&vfs.FileLocks{})
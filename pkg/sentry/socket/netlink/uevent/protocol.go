@@ -48,6 +48,11 @@ func (p *Protocol) CanSend() bool {
 	return false
 }
 
+// MulticastGroups implements netlink.Protocol.MulticastGroups.
+func (p *Protocol) MulticastGroups() uint32 {
+	return 0
+}
+
 // ProcessMessage implements netlink.Protocol.ProcessMessage.
 func (p *Protocol) ProcessMessage(ctx context.Context, msg *netlink.Message, ms *netlink.MessageSet) *syserr.Error {
 	// Silently ignore all messages.
@@ -440,15 +440,44 @@ func (s *socketOpsCommon) Release(ctx context.Context) {
 
 	v := s.Endpoint.SocketOptions().GetLinger()
 	// The case for zero timeout is handled in tcp endpoint close function.
+	if !v.Enabled || v.Timeout == 0 {
+		return
+	}
+
+	// Like Linux, don't linger if the socket is released by an exiting task
+	// (or outside of any task).
+	t := kernel.TaskFromContext(ctx)
+	if t == nil || t.ExitState() != kernel.TaskExitNone {
+		return
+	}
+
 	// Close is blocked until either:
-	// 1. The endpoint state is not in any of the states: FIN-WAIT1,
-	// CLOSING and LAST_ACK.
+	// 1. All sent data and the FIN have been acknowledged, i.e. the endpoint
+	// is not in any of the states: FIN-WAIT1, CLOSING and LAST_ACK.
 	// 2. Timeout is reached.
-	if v.Enabled && v.Timeout != 0 {
-		t := kernel.TaskFromContext(ctx)
-		start := t.Kernel().MonotonicClock().Now()
-		deadline := start.Add(v.Timeout)
-		t.BlockWithDeadline(ch, true, deadline)
+	// 3. The task is interrupted.
+	//
+	// This applies to non-blocking sockets as well, and close(2) succeeds in
+	// all cases, as in Linux.
+	deadline := t.Kernel().MonotonicClock().Now().Add(v.Timeout)
+	for tcpLingering(tcp.EndpointState(s.Endpoint.State())) {
+		if err := t.BlockWithDeadline(ch, true, deadline); err != nil {
+			return
+		}
+	}
+}
+
+// tcpLingering returns true if a closed TCP endpoint in state st still has
+// unacknowledged data or an unacknowledged FIN.
+func tcpLingering(st tcp.EndpointState) bool {
+	switch st {
+	case tcp.StateFinWait1, tcp.StateClosing, tcp.StateLastAck:
+		return true
+	case tcp.StateEstablished, tcp.StateCloseWait:
+		// The FIN is still queued behind data that couldn't be sent yet.
+		return true
+	default:
+		return false
 	}
 }
 
@@ -716,27 +745,27 @@ func (s *socketOpsCommon) blockingAccept(t *kernel.Task, peerAddr *tcpip.FullAdd
 
 // Accept implements the linux syscall accept(2) for sockets backed by
 // tcpip.Endpoint.
-func (s *SocketOperations) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+func (s *SocketOperations) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	var peerAddr *tcpip.FullAddress
-	if peerRequested {
+	if writePeer != nil {
 		peerAddr = &tcpip.FullAddress{}
 	}
 	ep, wq, terr := s.Endpoint.Accept(peerAddr)
 	if terr != nil {
 		if _, ok := terr.(*tcpip.ErrWouldBlock); !ok || !blocking {
-			return 0, nil, 0, syserr.TranslateNetstackError(terr)
+			return 0, syserr.TranslateNetstackError(terr)
 		}
 
 		var err *syserr.Error
 		ep, wq, err = s.blockingAccept(t, peerAddr)
 		if err != nil {
-			return 0, nil, 0, err
+			return 0, err
 		}
 	}
 
 	ns, err := New(t, s.family, s.skType, s.protocol, wq, ep)
 	if err != nil {
-		return 0, nil, 0, err
+		return 0, err
 	}
 	defer ns.DecRef(t)
 
@@ -746,10 +775,10 @@ func (s *SocketOperations) Accept(t *kernel.Task, peerRequested bool, flags int,
 		ns.SetFlags(flags.Settable())
 	}
 
-	var addr linux.SockAddr
-	var addrLen uint32
-	if peerAddr != nil {
-		addr, addrLen = socket.ConvertAddress(s.family, *peerAddr)
+	if writePeer != nil {
+		if err := writePeer(socket.ConvertAddress(s.family, *peerAddr)); err != nil {
+			return 0, syserr.FromError(err)
+		}
 	}
 
 	fd, e := t.NewFDFrom(0, ns, kernel.FDFlags{
@@ -758,7 +787,7 @@ func (s *SocketOperations) Accept(t *kernel.Task, peerRequested bool, flags int,
 
 	t.Kernel().RecordSocket(ns)
 
-	return fd, addr, addrLen, syserr.FromError(e)
+	return fd, syserr.FromError(e)
 }
 
 // ConvertShutdown converts Linux shutdown flags into tcpip shutdown flags.
@@ -841,6 +870,9 @@ func GetSockOpt(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, family in
 	case linux.SOL_IP:
 		return getSockOptIP(t, s, ep, name, outPtr, outLen, family)
 
+	case linux.SOL_MPTCP:
+		return getSockOptMPTCP(t, s, name, outLen)
+
 	case linux.SOL_UDP,
 		linux.SOL_ICMPV6,
 		linux.SOL_RAW,
@@ -899,6 +931,14 @@ func getSockOptSocket(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, fam
 		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetPassCred()))
 		return &v, nil
 
+	case linux.SO_PASSSEC:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetPassSec()))
+		return &v, nil
+
 	case linux.SO_SNDBUF:
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -927,6 +967,14 @@ func getSockOptSocket(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, fam
 		sizeP := primitive.Int32(size)
 		return &sizeP, nil
 
+	case linux.SO_RCVLOWAT:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(ep.SocketOptions().GetRcvlowat())
+		return &v, nil
+
 	case linux.SO_REUSEADDR:
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -1176,6 +1224,13 @@ func getSockOptTCP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name,
 			info.ReordSeen = 1
 		}
 
+		// Like Linux, report the accept queue's length and capacity in
+		// place of the unacked and sacked counts for listening sockets.
+		if v.State == tcpip.EndpointState(tcp.StateListen) {
+			info.Unacked = v.AcceptQueueLen
+			info.Sacked = v.AcceptQueueCap
+		}
+
 		// Linux truncates the output binary to outLen.
 		buf := t.CopyScratchBuffer(info.SizeBytes())
 		info.MarshalUnsafe(buf)
@@ -1276,6 +1331,38 @@ func getSockOptTCP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name,
 	return nil, syserr.ErrProtocolNotAvailable
 }
 
+// getSockOptMPTCP implements GetSockOpt when level is SOL_MPTCP.
+func getSockOptMPTCP(t *kernel.Task, s socket.SocketOps, name, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if _, skType, skProto := s.Type(); !isMPTCPSocket(skType, skProto) {
+		return nil, syserr.ErrProtocolNotAvailable
+	}
+
+	switch name {
+	case linux.MPTCP_INFO:
+		if outLen < 0 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		// MPTCP sockets always fall back to TCP.
+		info := linux.MPTCPInfo{
+			Flags: linux.MPTCP_INFO_FLAG_FALLBACK,
+		}
+
+		// Linux truncates the output binary to outLen.
+		buf := t.CopyScratchBuffer(info.SizeBytes())
+		info.MarshalUnsafe(buf)
+		if len(buf) > outLen {
+			buf = buf[:outLen]
+		}
+		bufP := primitive.ByteSlice(buf)
+		return &bufP, nil
+
+	default:
+		t.Kernel().EmitUnimplementedEvent(t)
+	}
+	return nil, syserr.ErrProtocolNotAvailable
+}
+
 // getSockOptIPv6 implements GetSockOpt when level is SOL_IPV6.
 func getSockOptIPv6(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
@@ -1297,6 +1384,32 @@ func getSockOptIPv6(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name
 		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetV6Only()))
 		return &v, nil
 
+	case linux.IPV6_MTU_DISCOVER:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.MTUDiscoverOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+
+		vP := primitive.Int32(pmtudToLinux(v))
+		return &vP, nil
+
+	case linux.IPV6_MTU:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.MTUOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+
+		vP := primitive.Int32(v)
+		return &vP, nil
+
 	case linux.IPV6_PATHMTU:
 		t.Kernel().EmitUnimplementedEvent(t)
 
@@ -1450,6 +1563,32 @@ func getSockOptIP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name in
 
 		return &vP, nil
 
+	case linux.IP_MTU_DISCOVER:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.MTUDiscoverOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+
+		vP := primitive.Int32(pmtudToLinux(v))
+		return &vP, nil
+
+	case linux.IP_MTU:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v, err := ep.GetSockOptInt(tcpip.MTUOption)
+		if err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+
+		vP := primitive.Int32(v)
+		return &vP, nil
+
 	case linux.IP_MULTICAST_TTL:
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -1729,6 +1868,19 @@ func setSockOptSocket(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, nam
 		ep.SocketOptions().SetReceiveBufferSize(clamped, true /* notify */)
 		return nil
 
+	case linux.SO_RCVLOWAT:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		// As in Linux, negative values mean "as large as possible".
+		v := int32(hostarch.ByteOrder.Uint32(optVal))
+		if v < 0 {
+			v = math.MaxInt32
+		}
+		ep.SocketOptions().SetRcvlowat(v)
+		return nil
+
 	case linux.SO_RCVBUFFORCE:
 		if len(optVal) < sizeOfInt32 {
 			return syserr.ErrInvalidArgument
@@ -1800,6 +1952,15 @@ func setSockOptSocket(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, nam
 		ep.SocketOptions().SetPassCred(v != 0)
 		return nil
 
+	case linux.SO_PASSSEC:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+
+		v := hostarch.ByteOrder.Uint32(optVal)
+		ep.SocketOptions().SetPassSec(v != 0)
+		return nil
+
 	case linux.SO_KEEPALIVE:
 		if len(optVal) < sizeOfInt32 {
 			return syserr.ErrInvalidArgument
@@ -2101,6 +2262,16 @@ func setSockOptIPv6(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name
 		ep.SocketOptions().SetReceiveOriginalDstAddress(v != 0)
 		return nil
 
+	case linux.IPV6_MTU_DISCOVER:
+		if len(optVal) < sizeOfInt32 {
+			return syserr.ErrInvalidArgument
+		}
+		v, err := pmtudFromLinux(int32(hostarch.ByteOrder.Uint32(optVal)))
+		if err != nil {
+			return err
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.MTUDiscoverOption, v))
+
 	case linux.IPV6_TCLASS:
 		if len(optVal) < sizeOfInt32 {
 			return syserr.ErrInvalidArgument
@@ -2223,6 +2394,39 @@ func parseIntOrChar(buf []byte) (int32, *syserr.Error) {
 	return int32(buf[0]), nil
 }
 
+// pmtudFromLinux converts an IP_MTU_DISCOVER or IPV6_MTU_DISCOVER value to a
+// tcpip.MTUDiscoverOption setting.
+func pmtudFromLinux(v int32) (int, *syserr.Error) {
+	switch v {
+	case linux.IP_PMTUDISC_DONT:
+		return tcpip.PMTUDiscoveryDont, nil
+	case linux.IP_PMTUDISC_WANT:
+		return tcpip.PMTUDiscoveryWant, nil
+	case linux.IP_PMTUDISC_DO:
+		return tcpip.PMTUDiscoveryDo, nil
+	case linux.IP_PMTUDISC_PROBE:
+		return tcpip.PMTUDiscoveryProbe, nil
+	default:
+		// IP_PMTUDISC_INTERFACE and IP_PMTUDISC_OMIT are not supported.
+		return 0, syserr.ErrInvalidArgument
+	}
+}
+
+// pmtudToLinux converts a tcpip.MTUDiscoverOption setting to an
+// IP_MTU_DISCOVER or IPV6_MTU_DISCOVER value.
+func pmtudToLinux(v int) int32 {
+	switch v {
+	case tcpip.PMTUDiscoveryWant:
+		return linux.IP_PMTUDISC_WANT
+	case tcpip.PMTUDiscoveryDo:
+		return linux.IP_PMTUDISC_DO
+	case tcpip.PMTUDiscoveryProbe:
+		return linux.IP_PMTUDISC_PROBE
+	default:
+		return linux.IP_PMTUDISC_DONT
+	}
+}
+
 // setSockOptIP implements SetSockOpt when level is SOL_IP.
 func setSockOptIP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
@@ -2313,6 +2517,17 @@ func setSockOptIP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name in
 		}
 		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TTLOption, int(v)))
 
+	case linux.IP_MTU_DISCOVER:
+		v, err := parseIntOrChar(optVal)
+		if err != nil {
+			return err
+		}
+		pmtud, err := pmtudFromLinux(v)
+		if err != nil {
+			return err
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.MTUDiscoverOption, pmtud))
+
 	case linux.IP_TOS:
 		if len(optVal) == 0 {
 			return nil
@@ -2406,7 +2621,6 @@ func setSockOptIP(t *kernel.Task, s socket.SocketOps, ep commonEndpoint, name in
 		linux.IP_IPSEC_POLICY,
 		linux.IP_MINTTL,
 		linux.IP_MSFILTER,
-		linux.IP_MTU_DISCOVER,
 		linux.IP_MULTICAST_ALL,
 		linux.IP_NODEFRAG,
 		linux.IP_OPTIONS,
@@ -2782,7 +2996,13 @@ func (s *socketOpsCommon) recvErr(t *kernel.Task, dst usermem.IOSequence) (int,
 
 	// The original destination address of the datagram that caused the error is
 	// supplied via msg_name.  -- recvmsg(2)
-	dstAddr, dstAddrLen := socket.ConvertAddress(addrFamilyFromNetProto(sockErr.NetProto), sockErr.Dst)
+	// Like Linux, IPv6 sockets report the destination of IPv4 datagrams as a
+	// v4-mapped IPv6 address.
+	family := addrFamilyFromNetProto(sockErr.NetProto)
+	if s.family == linux.AF_INET6 {
+		family = linux.AF_INET6
+	}
+	dstAddr, dstAddrLen := socket.ConvertAddress(family, sockErr.Dst)
 	cmgs := socket.ControlMessages{IP: socket.NewIPControlMessages(s.family, tcpip.ControlMessages{SockErr: sockErr})}
 	return n, msgFlags, dstAddr, dstAddrLen, cmgs, syserr.FromError(err)
 }
@@ -2802,6 +3022,20 @@ func (s *socketOpsCommon) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags
 		// Stream sockets ignore the sender address.
 		senderRequested = false
 	}
+	// A read from a stream socket returns once target bytes have been read:
+	// all of them if MSG_WAITALL is set, or otherwise (as in Linux, unless
+	// MSG_PEEK is set) SO_RCVLOWAT bytes.
+	var target int64
+	if !s.isPacketBased() {
+		if waitAll {
+			target = dst.NumBytes()
+		} else if !peek {
+			target = int64(s.Endpoint.SocketOptions().GetRcvlowat())
+			if target > dst.NumBytes() {
+				target = dst.NumBytes()
+			}
+		}
+	}
 	n, msgFlags, senderAddr, senderAddrLen, controlMessages, err = s.nonBlockingRead(t, dst, peek, trunc, senderRequested)
 
 	if s.isPacketBased() && err == syserr.ErrClosedForReceive && flags&linux.MSG_DONTWAIT != 0 {
@@ -2814,7 +3048,7 @@ func (s *socketOpsCommon) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags
 		return 0, 0, nil, 0, socket.ControlMessages{}, err
 	}
 
-	if err == nil && (dontWait || !waitAll || s.isPacketBased() || int64(n) >= dst.NumBytes()) {
+	if err == nil && (dontWait || int64(n) >= target) {
 		// We got all the data we need.
 		return
 	}
@@ -2841,7 +3075,7 @@ func (s *socketOpsCommon) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags
 			}
 			return
 		}
-		if err == nil && (s.isPacketBased() || !waitAll || int64(rn) >= dst.NumBytes()) {
+		if err == nil && int64(n) >= target {
 			// We got all the data we need.
 			return
 		}
@@ -3281,7 +3515,11 @@ func nicStateFlagsToLinux(f stack.NICStateFlags) uint32 {
 }
 
 func isTCPSocket(skType linux.SockType, skProto int) bool {
-	return skType == linux.SOCK_STREAM && (skProto == 0 || skProto == unix.IPPROTO_TCP)
+	return skType == linux.SOCK_STREAM && (skProto == 0 || skProto == unix.IPPROTO_TCP || skProto == linux.IPPROTO_MPTCP)
+}
+
+func isMPTCPSocket(skType linux.SockType, skProto int) bool {
+	return skType == linux.SOCK_STREAM && skProto == linux.IPPROTO_MPTCP
 }
 
 func isUDPSocket(skType linux.SockType, skProto int) bool {
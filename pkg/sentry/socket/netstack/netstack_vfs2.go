@@ -147,40 +147,41 @@ func (s *SocketVFS2) Write(ctx context.Context, src usermem.IOSequence, opts vfs
 
 // Accept implements the linux syscall accept(2) for sockets backed by
 // tcpip.Endpoint.
-func (s *SocketVFS2) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+func (s *SocketVFS2) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	// Issue the accept request to get the new endpoint.
 	var peerAddr *tcpip.FullAddress
-	if peerRequested {
+	if writePeer != nil {
 		peerAddr = &tcpip.FullAddress{}
 	}
 	ep, wq, terr := s.Endpoint.Accept(peerAddr)
 	if terr != nil {
 		if _, ok := terr.(*tcpip.ErrWouldBlock); !ok || !blocking {
-			return 0, nil, 0, syserr.TranslateNetstackError(terr)
+			return 0, syserr.TranslateNetstackError(terr)
 		}
 
 		var err *syserr.Error
 		ep, wq, err = s.blockingAccept(t, peerAddr)
 		if err != nil {
-			return 0, nil, 0, err
+			return 0, err
 		}
 	}
 
 	ns, err := NewVFS2(t, s.family, s.skType, s.protocol, wq, ep)
 	if err != nil {
-		return 0, nil, 0, err
+		return 0, err
 	}
 	defer ns.DecRef(t)
 
 	if err := ns.SetStatusFlags(t, t.Credentials(), uint32(flags&linux.SOCK_NONBLOCK)); err != nil {
-		return 0, nil, 0, syserr.FromError(err)
+		return 0, syserr.FromError(err)
 	}
 
-	var addr linux.SockAddr
-	var addrLen uint32
-	if peerAddr != nil {
-		// Get address of the peer and write it to peer slice.
-		addr, addrLen = socket.ConvertAddress(s.family, *peerAddr)
+	if writePeer != nil {
+		// Write the address of the peer out while the new socket can
+		// still be dropped without ever having been visible.
+		if err := writePeer(socket.ConvertAddress(s.family, *peerAddr)); err != nil {
+			return 0, syserr.FromError(err)
+		}
 	}
 
 	fd, e := t.NewFDFromVFS2(0, ns, kernel.FDFlags{
@@ -189,7 +190,7 @@ func (s *SocketVFS2) Accept(t *kernel.Task, peerRequested bool, flags int, block
 
 	t.Kernel().RecordSocketVFS2(ns)
 
-	return fd, addr, addrLen, syserr.FromError(e)
+	return fd, syserr.FromError(e)
 }
 
 // Ioctl implements vfs.FileDescriptionImpl.
@@ -44,10 +44,13 @@ type provider struct {
 // UDP, and ICMP are supported. The bool return value is true when this socket
 // is associated with a transport protocol. This is only false for SOCK_RAW,
 // IPPROTO_IP sockets.
+//
+// IPPROTO_MPTCP sockets are backed by TCP, as if the connection always fell
+// back to TCP.
 func getTransportProtocol(ctx context.Context, stype linux.SockType, protocol int) (tcpip.TransportProtocolNumber, bool, *syserr.Error) {
 	switch stype {
 	case linux.SOCK_STREAM:
-		if protocol != 0 && protocol != unix.IPPROTO_TCP {
+		if protocol != 0 && protocol != unix.IPPROTO_TCP && protocol != linux.IPPROTO_MPTCP {
 			return 0, true, syserr.ErrInvalidArgument
 		}
 		return tcp.ProtocolNumber, true, nil
@@ -134,7 +137,17 @@ func (p *provider) Socket(t *kernel.Task, stype linux.SockType, protocol int) (*
 		return nil, syserr.TranslateNetstackError(e)
 	}
 
-	return New(t, p.family, stype, int(transProto), wq, ep)
+	return New(t, p.family, stype, socketProtocol(transProto, protocol), wq, ep)
+}
+
+// socketProtocol returns the protocol reported by the socket, e.g. for
+// SO_PROTOCOL, given its transport protocol and the protocol passed to
+// socket(2).
+func socketProtocol(transProto tcpip.TransportProtocolNumber, protocol int) int {
+	if protocol == linux.IPPROTO_MPTCP {
+		return protocol
+	}
+	return int(transProto)
 }
 
 func packetSocket(t *kernel.Task, epStack *Stack, stype linux.SockType, protocol int) (*fs.File, *syserr.Error) {
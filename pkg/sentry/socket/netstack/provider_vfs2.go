@@ -79,7 +79,7 @@ func (p *providerVFS2) Socket(t *kernel.Task, stype linux.SockType, protocol int
 		return nil, syserr.TranslateNetstackError(e)
 	}
 
-	return NewVFS2(t, p.family, stype, int(transProto), wq, ep)
+	return NewVFS2(t, p.family, stype, socketProtocol(transProto, protocol), wq, ep)
 }
 
 func packetSocketVFS2(t *kernel.Task, epStack *Stack, stype linux.SockType, protocol int) (*vfs.FileDescription, *syserr.Error) {
@@ -16,14 +16,20 @@ package netstack
 
 import (
 	"fmt"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/bond"
+	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
@@ -35,6 +41,11 @@ import (
 // +stateify savable
 type Stack struct {
 	Stack *stack.Stack `state:"manual"`
+
+	// bondMu serializes changes to bond membership and the removal of NICs,
+	// so that the NICs and bonds looked up for a change are still the same
+	// when the change is made.
+	bondMu sync.Mutex `state:"nosave"`
 }
 
 // SupportsIPv6 implements Stack.SupportsIPv6.
@@ -51,6 +62,12 @@ func toLinuxARPHardwareType(t header.ARPHardwareType) uint16 {
 		return linux.ARPHRD_LOOPBACK
 	case header.ARPHardwareEther:
 		return linux.ARPHRD_ETHER
+	case header.ARPHardwareTunnel:
+		return linux.ARPHRD_TUNNEL
+	case header.ARPHardwareIPGRE:
+		return linux.ARPHRD_IPGRE
+	case header.ARPHardwareIP6GRE:
+		return linux.ARPHRD_IP6GRE
 	default:
 		panic(fmt.Sprintf("unknown ARPHRD type: %d", t))
 	}
@@ -59,24 +76,218 @@ func toLinuxARPHardwareType(t header.ARPHardwareType) uint16 {
 // Interfaces implements inet.Stack.Interfaces.
 func (s *Stack) Interfaces() map[int32]inet.Interface {
 	is := make(map[int32]inet.Interface)
+	var bonds []int32
 	for id, ni := range s.Stack.NICInfo() {
-		is[int32(id)] = inet.Interface{
+		i := inet.Interface{
 			Name:       ni.Name,
 			Addr:       []byte(ni.LinkAddress),
 			Flags:      uint32(nicStateFlagsToLinux(ni.Flags)),
 			DeviceType: toLinuxARPHardwareType(ni.ARPHardwareType),
 			MTU:        ni.MTU,
 		}
+		if b := s.bond(id); b != nil {
+			bonds = append(bonds, int32(id))
+			info := b.Info()
+			i.Flags |= linux.IFF_MASTER
+			i.Bond = &inet.BondInfo{
+				Mode:        uint8(info.Mode),
+				MIIMon:      uint32(info.MIIMon / time.Millisecond),
+				ActiveSlave: int32(info.ActiveSlave),
+			}
+		}
+		is[int32(id)] = i
+	}
+
+	// Slaves are reported once all the interfaces are known.
+	for _, idx := range bonds {
+		b := s.bond(tcpip.NICID(idx))
+		if b == nil {
+			continue
+		}
+		for _, si := range b.Info().Slaves {
+			i, ok := is[int32(si.ID)]
+			if !ok {
+				continue
+			}
+			miiStatus := uint8(linux.BOND_LINK_UP)
+			if !si.LinkUp {
+				miiStatus = linux.BOND_LINK_DOWN
+			}
+			i.Flags |= linux.IFF_SLAVE
+			i.Master = idx
+			i.BondSlave = &inet.BondSlaveInfo{
+				State:            uint8(si.State),
+				MIIStatus:        miiStatus,
+				LinkFailureCount: si.LinkFailures,
+			}
+			is[int32(si.ID)] = i
+		}
 	}
 	return is
 }
 
+// bond returns the bond endpoint of the NIC identified by id, or nil if it is
+// not a bond.
+func (s *Stack) bond(id tcpip.NICID) *bond.Endpoint {
+	ep, _, err := s.Stack.NICLinkEndpoint(id)
+	if err != nil {
+		return nil
+	}
+	b, _ := ep.(*bond.Endpoint)
+	return b
+}
+
+// master returns the bond the NIC identified by id is enslaved to, or nil.
+func (s *Stack) master(id tcpip.NICID) *bond.Endpoint {
+	for nicID := range s.Stack.NICInfo() {
+		b := s.bond(nicID)
+		if b == nil {
+			continue
+		}
+		for _, si := range b.Info().Slaves {
+			if si.ID == id {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
 // RemoveInterface implements inet.Stack.RemoveInterface.
 func (s *Stack) RemoveInterface(idx int32) error {
+	s.bondMu.Lock()
+	defer s.bondMu.Unlock()
 	nic := tcpip.NICID(idx)
+	if b := s.master(nic); b != nil {
+		b.Release(nic)
+	}
 	return syserr.TranslateNetstackError(s.Stack.RemoveNIC(nic)).ToError()
 }
 
+// AddTunnelInterface implements inet.Stack.AddTunnelInterface.
+func (s *Stack) AddTunnelInterface(name string, config inet.TunnelConfig) (int32, error) {
+	opts := tunnel.Options{
+		Local:  tcpip.Address(config.Local),
+		Remote: tcpip.Address(config.Remote),
+		TTL:    config.TTL,
+		IFlags: header.GREFlags(config.IFlags),
+		OFlags: header.GREFlags(config.OFlags),
+		IKey:   config.IKey,
+		OKey:   config.OKey,
+		MTU:    config.MTU,
+	}
+	addrSize := header.IPv4AddressSize
+	switch config.Kind {
+	case "gre":
+		opts.Mode = tunnel.ModeGRE
+	case "gretap":
+		opts.Mode = tunnel.ModeGRETap
+		mac := make([]byte, header.EthernetAddressSize)
+		if _, err := rand.Read(mac); err != nil {
+			return 0, err
+		}
+		// Use a unicast, locally administered address.
+		mac[0] = mac[0]&^0x01 | 0x02
+		opts.LinkAddress = tcpip.LinkAddress(mac)
+	case "ip6gre":
+		opts.Mode = tunnel.ModeGRE
+		addrSize = header.IPv6AddressSize
+	case "ipip":
+		opts.Mode = tunnel.ModeIPIP
+	default:
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	if len(opts.Remote) != addrSize || (len(opts.Local) != 0 && len(opts.Local) != addrSize) {
+		return 0, linuxerr.EINVAL
+	}
+
+	ep, err := tunnel.New(s.Stack, opts)
+	if err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	var linkEP stack.LinkEndpoint = ep
+	if opts.Mode == tunnel.ModeGRETap {
+		linkEP = ethernet.New(ep)
+	}
+
+	id := tcpip.NICID(s.Stack.UniqueID())
+	if name == "" {
+		name = fmt.Sprintf("%s%d", config.Kind, id)
+	}
+	if err := s.Stack.CreateNICWithOptions(id, linkEP, stack.NICOptions{Name: name}); err != nil {
+		// Detaching the endpoint stops the tunnel from receiving packets.
+		ep.Attach(nil)
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	return int32(id), nil
+}
+
+// AddBondInterface implements inet.Stack.AddBondInterface.
+func (s *Stack) AddBondInterface(name string, config inet.BondConfig) (int32, error) {
+	var mode bond.Mode
+	switch config.Mode {
+	case linux.BOND_MODE_ROUNDROBIN:
+		mode = bond.ModeBalanceRR
+	case linux.BOND_MODE_ACTIVEBACKUP:
+		mode = bond.ModeActiveBackup
+	default:
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	ep, err := bond.New(bond.Options{
+		Mode:   mode,
+		MIIMon: time.Duration(config.MIIMon) * time.Millisecond,
+		Clock:  s.Stack.Clock(),
+	})
+	if err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+
+	id := tcpip.NICID(s.Stack.UniqueID())
+	if name == "" {
+		name = fmt.Sprintf("bond%d", id)
+	}
+	if err := s.Stack.CreateNICWithOptions(id, ep, stack.NICOptions{Name: name}); err != nil {
+		return 0, syserr.TranslateNetstackError(err).ToError()
+	}
+	return int32(id), nil
+}
+
+// SetInterfaceMaster implements inet.Stack.SetInterfaceMaster.
+func (s *Stack) SetInterfaceMaster(idx, master int32) error {
+	s.bondMu.Lock()
+	defer s.bondMu.Unlock()
+	id := tcpip.NICID(idx)
+	ep, d, err := s.Stack.NICLinkEndpoint(id)
+	if err != nil {
+		return syserr.TranslateNetstackError(err).ToError()
+	}
+	current := s.master(id)
+	if master == 0 {
+		if current != nil {
+			current.Release(id)
+		}
+		return nil
+	}
+
+	if !s.Stack.HasNIC(tcpip.NICID(master)) {
+		return linuxerr.ENODEV
+	}
+	b := s.bond(tcpip.NICID(master))
+	if b == nil {
+		return linuxerr.EOPNOTSUPP
+	}
+	if current == b {
+		return nil
+	}
+	if current != nil {
+		return linuxerr.EBUSY
+	}
+	if idx == master {
+		return linuxerr.EINVAL
+	}
+	return syserr.TranslateNetstackError(b.Enslave(id, ep, d)).ToError()
+}
+
 // InterfaceAddrs implements inet.Stack.InterfaceAddrs.
 func (s *Stack) InterfaceAddrs() map[int32][]inet.InterfaceAddr {
 	nicAddrs := make(map[int32][]inet.InterfaceAddr)
@@ -481,3 +692,134 @@ func (s *Stack) PortRange() (uint16, uint16) {
 func (s *Stack) SetPortRange(start uint16, end uint16) error {
 	return syserr.TranslateNetstackError(s.Stack.SetPortRange(start, end)).ToError()
 }
+
+// neighborProtocol returns the network protocol whose neighbor table holds
+// entries of the given address family, i.e. ARP for IPv4 and NDP for IPv6.
+func neighborProtocol(family uint8) (tcpip.NetworkProtocolNumber, int, error) {
+	switch family {
+	case linux.AF_INET:
+		return ipv4.ProtocolNumber, header.IPv4AddressSize, nil
+	case linux.AF_INET6:
+		return ipv6.ProtocolNumber, header.IPv6AddressSize, nil
+	default:
+		return 0, 0, linuxerr.EAFNOSUPPORT
+	}
+}
+
+// toLinuxNeighborState converts a netstack neighbor state to the equivalent
+// Linux NUD_* constant.
+func toLinuxNeighborState(state stack.NeighborState) uint16 {
+	switch state {
+	case stack.Incomplete:
+		return linux.NUD_INCOMPLETE
+	case stack.Reachable:
+		return linux.NUD_REACHABLE
+	case stack.Stale:
+		return linux.NUD_STALE
+	case stack.Delay:
+		return linux.NUD_DELAY
+	case stack.Probe:
+		return linux.NUD_PROBE
+	case stack.Static:
+		return linux.NUD_PERMANENT
+	case stack.Unreachable:
+		return linux.NUD_FAILED
+	default:
+		return linux.NUD_NONE
+	}
+}
+
+// toInetNeighbor converts an entry of the neighbor table of the NIC id to an
+// inet.Neighbor.
+func toInetNeighbor(id tcpip.NICID, e stack.NeighborEntry) inet.Neighbor {
+	family := uint8(linux.AF_INET)
+	if len(e.Addr) == header.IPv6AddressSize {
+		family = linux.AF_INET6
+	}
+	return inet.Neighbor{
+		Family:   family,
+		Index:    int32(id),
+		State:    toLinuxNeighborState(e.State),
+		Addr:     []byte(e.Addr),
+		LinkAddr: []byte(e.LinkAddr),
+	}
+}
+
+// Neighbors implements inet.Stack.Neighbors.
+func (s *Stack) Neighbors() ([]inet.Neighbor, error) {
+	var neighbors []inet.Neighbor
+	for id := range s.Stack.NICInfo() {
+		for _, family := range []uint8{linux.AF_INET, linux.AF_INET6} {
+			protocol, _, _ := neighborProtocol(family)
+			entries, err := s.Stack.Neighbors(id, protocol)
+			if err != nil {
+				// The NIC doesn't resolve link addresses for protocol,
+				// e.g. because it is a loopback or tunnel device.
+				continue
+			}
+			for _, e := range entries {
+				neighbors = append(neighbors, toInetNeighbor(id, e))
+			}
+		}
+	}
+	return neighbors, nil
+}
+
+// AddNeighbor implements inet.Stack.AddNeighbor.
+func (s *Stack) AddNeighbor(n inet.Neighbor) error {
+	protocol, addrSize, err := neighborProtocol(n.Family)
+	if err != nil {
+		return err
+	}
+	if len(n.Addr) != addrSize {
+		return linuxerr.EINVAL
+	}
+	nicID := tcpip.NICID(n.Index)
+	ni, ok := s.Stack.NICInfo()[nicID]
+	if !ok {
+		return linuxerr.ENODEV
+	}
+	if len(n.LinkAddr) == 0 || len(n.LinkAddr) != len(ni.LinkAddress) {
+		return linuxerr.EINVAL
+	}
+	if err := s.Stack.AddStaticNeighbor(nicID, protocol, tcpip.Address(n.Addr), tcpip.LinkAddress(n.LinkAddr)); err != nil {
+		return syserr.TranslateNetstackError(err).ToError()
+	}
+	return nil
+}
+
+// RemoveNeighbor implements inet.Stack.RemoveNeighbor.
+func (s *Stack) RemoveNeighbor(n inet.Neighbor) error {
+	protocol, addrSize, err := neighborProtocol(n.Family)
+	if err != nil {
+		return err
+	}
+	if len(n.Addr) != addrSize {
+		return linuxerr.EINVAL
+	}
+	switch err := s.Stack.RemoveNeighbor(tcpip.NICID(n.Index), protocol, tcpip.Address(n.Addr)); err.(type) {
+	case nil:
+		return nil
+	case *tcpip.ErrBadAddress:
+		return linuxerr.ENOENT
+	default:
+		return syserr.TranslateNetstackError(err).ToError()
+	}
+}
+
+var _ stack.NUDDispatcher = (*Stack)(nil)
+
+// OnNeighborAdded implements stack.NUDDispatcher.OnNeighborAdded.
+func (s *Stack) OnNeighborAdded(id tcpip.NICID, e stack.NeighborEntry) {
+	inet.NotifyNeighborChanged(s, toInetNeighbor(id, e))
+}
+
+// OnNeighborChanged implements stack.NUDDispatcher.OnNeighborChanged.
+func (s *Stack) OnNeighborChanged(id tcpip.NICID, e stack.NeighborEntry) {
+	inet.NotifyNeighborChanged(s, toInetNeighbor(id, e))
+}
+
+// OnNeighborRemoved implements stack.NUDDispatcher.OnNeighborRemoved.
+func (s *Stack) OnNeighborRemoved(id tcpip.NICID, e stack.NeighborEntry) {
+	inet.NotifyNeighborRemoved(s, toInetNeighbor(id, e))
+}
@@ -20,6 +20,8 @@ package socket
 import (
 	"bytes"
 	"fmt"
+	"net"
+	"strconv"
 	"sync/atomic"
 
 	"golang.org/x/sys/unix"
@@ -32,6 +34,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/security_events_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/syserr"
@@ -202,9 +206,14 @@ type SocketOps interface {
 	Connect(t *kernel.Task, sockaddr []byte, blocking bool) *syserr.Error
 
 	// Accept implements the accept4(2) linux unix.
-	// Returns fd, real peer address length and error. Real peer address
-	// length is only set if len(peer) > 0.
-	Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error)
+	//
+	// If writePeer is not nil, it is called with the peer address and its
+	// real length before the new file is installed in the FD table. If it
+	// fails, the connection is dropped and its error returned, so that no
+	// file descriptor is ever visible for it (cf. Linux's
+	// __sys_accept4_file(), which calls move_addr_to_user() before
+	// fd_install()).
+	Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error)
 
 	// Bind implements the bind(2) linux unix.
 	Bind(t *kernel.Task, sockaddr []byte) *syserr.Error
@@ -542,7 +551,6 @@ func emitUnimplementedEvent(t *kernel.Task, name int) {
 		linux.SO_PEEK_OFF,
 		linux.SO_PRIORITY,
 		linux.SO_RCVBUF,
-		linux.SO_RCVLOWAT,
 		linux.SO_RCVTIMEO,
 		linux.SO_REUSEADDR,
 		linux.SO_REUSEPORT,
@@ -561,6 +569,54 @@ func emitUnimplementedEvent(t *kernel.Task, name int) {
 	}
 }
 
+// EmitListenEvent emits a ListenEvent for socket s, installed at fd, which has
+// just started listening. backlog is the value passed to listen(2).
+func EmitListenEvent(t *kernel.Task, fd int32, s SocketOps, backlog int) {
+	if !seccheck.Global.Enabled(seccheck.PointListen) {
+		return
+	}
+	family, _, _ := s.Type()
+	var address string
+	if addr, _, err := s.GetSockName(t); err == nil {
+		address = sockAddrString(addr)
+	}
+	seccheck.Global.Emit(seccheck.PointListen, &pb.ListenEvent{
+		Task:    t.SecurityEventTask(),
+		Fd:      fd,
+		Family:  int32(family),
+		Address: address,
+		Backlog: int32(backlog),
+	})
+}
+
+// sockAddrString returns a human readable representation of addr.
+func sockAddrString(addr linux.SockAddr) string {
+	switch a := addr.(type) {
+	case *linux.SockAddrInet:
+		return net.JoinHostPort(net.IP(a.Addr[:]).String(), strconv.Itoa(int(Ntohs(a.Port))))
+	case *linux.SockAddrInet6:
+		return net.JoinHostPort(net.IP(a.Addr[:]).String(), strconv.Itoa(int(Ntohs(a.Port))))
+	case *linux.SockAddrUnix:
+		var path []byte
+		for _, c := range a.Path {
+			path = append(path, byte(c))
+		}
+		if len(path) > 0 && path[0] == 0 {
+			// Abstract socket; display it like ss(8) does.
+			path[0] = '@'
+			return string(bytes.TrimRight(path, "\x00"))
+		}
+		if i := bytes.IndexByte(path, 0); i >= 0 {
+			path = path[:i]
+		}
+		return string(path)
+	case *linux.SockAddrNetlink:
+		return fmt.Sprintf("port %d", a.PortID)
+	default:
+		return ""
+	}
+}
+
 // UnmarshalSockAddr unmarshals memory representing a struct sockaddr to one of
 // the ABI socket address types.
 //
@@ -625,10 +681,11 @@ func ConvertAddress(family int, addr tcpip.FullAddress) (linux.SockAddr, uint32)
 
 		// Linux returns the used length of the address struct (including the
 		// null terminator) for filesystem paths. The Family field is 2 bytes.
-		// It is sometimes allowed to exclude the null terminator if the
-		// address length is the max. Abstract and empty paths always return
-		// the full exact length.
-		if l == 0 || out.Path[0] == 0 || l == len(out.Path) {
+		// This is the case even if the path fills sun_path, so that the
+		// reported length exceeds the size of struct sockaddr_un and the
+		// address is truncated when copied out (cf. unix_mkname_bsd()).
+		// Abstract and empty paths always return the full exact length.
+		if l == 0 || out.Path[0] == 0 {
 			return &out, uint32(2 + l)
 		}
 		return &out, uint32(3 + l)
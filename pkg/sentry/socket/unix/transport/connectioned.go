@@ -50,6 +50,9 @@ type ConnectingEndpoint interface {
 	// GetLocalAddress returns the bound path.
 	GetLocalAddress() (tcpip.FullAddress, tcpip.Error)
 
+	// SecurityLabel returns the security label of the endpoint.
+	SecurityLabel() string
+
 	// Locker protects the following methods. While locked, only the holder of
 	// the lock can change the return value of the protected methods.
 	sync.Locker
@@ -123,10 +126,13 @@ func NewConnectioned(ctx context.Context, stype linux.SockType, uid UniqueIDProv
 
 func newConnectioned(ctx context.Context, stype linux.SockType, uid UniqueIDProvider) *connectionedEndpoint {
 	ep := &connectionedEndpoint{
-		baseEndpoint: baseEndpoint{Queue: &waiter.Queue{}},
-		id:           uid.UniqueID(),
-		idGenerator:  uid,
-		stype:        stype,
+		baseEndpoint: baseEndpoint{
+			Queue:    &waiter.Queue{},
+			secLabel: SecurityLabelFromContext(ctx),
+		},
+		id:          uid.UniqueID(),
+		idGenerator: uid,
+		stype:       stype,
 	}
 
 	ep.ops.InitHandler(ep, &stackHandler{}, getSendBufferLimits, getReceiveBufferLimits)
@@ -171,10 +177,15 @@ func NewPair(ctx context.Context, stype linux.SockType, uid UniqueIDProvider) (E
 // socketpair.
 func NewExternal(ctx context.Context, stype linux.SockType, uid UniqueIDProvider, queue *waiter.Queue, receiver Receiver, connected ConnectedEndpoint) Endpoint {
 	ep := &connectionedEndpoint{
-		baseEndpoint: baseEndpoint{Queue: queue, receiver: receiver, connected: connected},
-		id:           uid.UniqueID(),
-		idGenerator:  uid,
-		stype:        stype,
+		baseEndpoint: baseEndpoint{
+			Queue:     queue,
+			receiver:  receiver,
+			connected: connected,
+			secLabel:  SecurityLabelFromContext(ctx),
+		},
+		id:          uid.UniqueID(),
+		idGenerator: uid,
+		stype:       stype,
 	}
 	ep.ops.InitHandler(ep, &stackHandler{}, getSendBufferLimits, getReceiveBufferLimits)
 	ep.ops.SetSendBufferSize(connected.SendMaxQueueSize(), false /* notify */)
@@ -291,11 +302,13 @@ func (e *connectionedEndpoint) BidirectionalConnect(ctx context.Context, ce Conn
 		return syserr.ErrConnectionRefused
 	}
 
-	// Create a newly bound connectionedEndpoint.
+	// Create a newly bound connectionedEndpoint. Like Linux, it inherits the
+	// security label of the listening socket.
 	ne := &connectionedEndpoint{
 		baseEndpoint: baseEndpoint{
-			path:  e.path,
-			Queue: &waiter.Queue{},
+			path:     e.path,
+			Queue:    &waiter.Queue{},
+			secLabel: e.secLabel,
 		},
 		id:          e.idGenerator.UniqueID(),
 		idGenerator: e.idGenerator,
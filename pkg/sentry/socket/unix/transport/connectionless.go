@@ -40,7 +40,10 @@ var (
 
 // NewConnectionless creates a new unbound dgram endpoint.
 func NewConnectionless(ctx context.Context) Endpoint {
-	ep := &connectionlessEndpoint{baseEndpoint{Queue: &waiter.Queue{}}}
+	ep := &connectionlessEndpoint{baseEndpoint{
+		Queue:    &waiter.Queue{},
+		secLabel: SecurityLabelFromContext(ctx),
+	}}
 	q := queue{ReaderQueue: ep.Queue, WriterQueue: &waiter.Queue{}, limit: defaultBufferSize}
 	q.InitRefs()
 	ep.receiver = &queueReceiver{readQueue: &q}
@@ -114,6 +117,7 @@ func (e *connectionlessEndpoint) SendMsg(ctx context.Context, data [][]byte, c C
 	defer connected.Release(ctx)
 
 	e.Lock()
+	c.SecurityLabel = e.secLabel
 	n, notify, err := connected.Send(ctx, data, c, tcpip.FullAddress{Addr: tcpip.Address(e.path)})
 	e.Unlock()
 
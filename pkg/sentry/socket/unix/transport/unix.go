@@ -37,6 +37,24 @@ const (
 	maxBufferSize = 4 << 20 // 4 MiB 4 MiB (default in linux for net.core.wmem_max)
 )
 
+// contextID is the transport package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxSecurityLabel is a Context.Value key for the security label of the
+	// caller, which is assigned to the sockets it creates.
+	CtxSecurityLabel contextID = iota
+)
+
+// SecurityLabelFromContext returns the security label of the caller, or an
+// empty string if ctx doesn't carry one.
+func SecurityLabelFromContext(ctx context.Context) string {
+	if v := ctx.Value(CtxSecurityLabel); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
 // A RightsControlMessage is a control message containing FDs.
 //
 // +stateify savable
@@ -63,10 +81,16 @@ type ControlMessages struct {
 
 	// Credentials is a control message containing Unix credentials.
 	Credentials CredentialsControlMessage
+
+	// SecurityLabel is the security label of the sending socket. It is
+	// delivered as an SCM_SECURITY control message if the receiving socket
+	// has SO_PASSSEC enabled.
+	SecurityLabel string
 }
 
 // Empty returns true iff the ControlMessages does not contain either
-// credentials or rights.
+// credentials or rights. The security label is not considered, since it is
+// only delivered on request.
 func (c *ControlMessages) Empty() bool {
 	return c.Rights == nil && c.Credentials == nil
 }
@@ -78,6 +102,7 @@ func (c *ControlMessages) Clone() ControlMessages {
 		cm.Rights = c.Rights.Clone()
 	}
 	cm.Credentials = c.Credentials
+	cm.SecurityLabel = c.SecurityLabel
 	return cm
 }
 
@@ -183,6 +208,11 @@ type Endpoint interface {
 	// connected.
 	GetRemoteAddress() (tcpip.FullAddress, tcpip.Error)
 
+	// PeerSecurityLabel returns the security label of the connected peer
+	// socket. ok is false if the endpoint isn't connected to a sentry
+	// socket.
+	PeerSecurityLabel() (label string, ok bool)
+
 	// SetSockOpt sets a socket option.
 	SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error
 
@@ -545,6 +575,12 @@ func (q *streamQueueReceiver) Recv(ctx context.Context, data [][]byte, wantCreds
 				// Both messages have credentials, but they don't match.
 				break
 			}
+
+			if q.control.SecurityLabel != c.SecurityLabel {
+				// Never glue messages from sockets with different
+				// security labels.
+				break
+			}
 		}
 
 		if numRights != 0 && c.Rights != nil && q.control.Rights != nil {
@@ -656,6 +692,9 @@ type connectedEndpoint struct {
 
 		// Type implements Endpoint.Type.
 		Type() linux.SockType
+
+		// SecurityLabel returns the security label of the endpoint.
+		SecurityLabel() string
 	}
 
 	writeQueue *queue
@@ -771,6 +810,12 @@ type baseEndpoint struct {
 
 	// ops is used to get socket level options.
 	ops tcpip.SocketOptions
+
+	// secLabel is the security label of the socket, as reported to its peer
+	// by SO_PEERSEC and SCM_SECURITY. It is assigned when the socket is
+	// created and never changes, so that peers see a consistent label even
+	// if the creating task later execs.
+	secLabel string
 }
 
 // EventRegister implements waiter.Waitable.EventRegister.
@@ -807,6 +852,21 @@ func (e *baseEndpoint) ConnectedPasscred() bool {
 	return e.connected != nil && e.connected.Passcred()
 }
 
+// SecurityLabel returns the security label of the endpoint.
+func (e *baseEndpoint) SecurityLabel() string {
+	return e.secLabel
+}
+
+// PeerSecurityLabel implements Endpoint.PeerSecurityLabel.
+func (e *baseEndpoint) PeerSecurityLabel() (string, bool) {
+	e.Lock()
+	defer e.Unlock()
+	if c, ok := e.connected.(*connectedEndpoint); ok {
+		return c.endpoint.SecurityLabel(), true
+	}
+	return "", false
+}
+
 // Connected implements ConnectingEndpoint.Connected.
 func (e *baseEndpoint) Connected() bool {
 	return e.receiver != nil && e.connected != nil
@@ -835,6 +895,10 @@ func (e *baseEndpoint) RecvMsg(ctx context.Context, data [][]byte, creds bool, n
 	if addr != nil {
 		*addr = a
 	}
+	if !e.ops.GetPassSec() {
+		// SCM_SECURITY was not requested.
+		cms.SecurityLabel = ""
+	}
 	return recvLen, msgLen, cms, cmt, nil
 }
 
@@ -852,6 +916,7 @@ func (e *baseEndpoint) SendMsg(ctx context.Context, data [][]byte, c ControlMess
 	}
 
 	connected := e.connected
+	c.SecurityLabel = e.secLabel
 	n, notify, err := connected.Send(ctx, data, c, tcpip.FullAddress{Addr: tcpip.Address(e.path)})
 	e.Unlock()
 
@@ -27,6 +27,8 @@ import (
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/refsvfs2"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/fsutil"
@@ -163,6 +165,36 @@ func extractPath(sockaddr []byte) (string, *syserr.Error) {
 	return p, nil
 }
 
+// isAutobind returns true if sockaddr holds nothing but the AF_UNIX address
+// family, in which case bind(2) assigns an abstract name to the socket. See
+// net/unix/af_unix.c:unix_bind.
+func isAutobind(sockaddr []byte) bool {
+	return len(sockaddr) == 2 && hostarch.ByteOrder.Uint16(sockaddr) == linux.AF_UNIX
+}
+
+// autobind binds s to a kernel-assigned name in the abstract socket namespace
+// of t. socket is the socket that owns s.
+func (s *socketOpsCommon) autobind(t *kernel.Task, bep transport.BoundEndpoint, socket refsvfs2.TryRefCounter) *syserr.Error {
+	asn := t.AbstractSockets()
+	name, err := asn.Autobind(t, bep, socket)
+	if err != nil {
+		return syserr.FromError(err)
+	}
+	if err := s.ep.Bind(tcpip.FullAddress{Addr: tcpip.Address("\x00" + name)}, func() *syserr.Error {
+		s.abstractName = name
+		s.abstractNamespace = asn
+		return nil
+	}); err != nil {
+		asn.Remove(name, socket)
+		if err == syserr.ErrAlreadyBound {
+			// Like Linux, autobinding a bound socket is a no-op.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // GetPeerName implements the linux syscall getpeername(2) for sockets backed by
 // a transport.Endpoint.
 func (s *socketOpsCommon) GetPeerName(t *kernel.Task) (linux.SockAddr, uint32, *syserr.Error) {
@@ -195,6 +227,9 @@ func (s *SocketOperations) Ioctl(ctx context.Context, _ *fs.File, io usermem.IO,
 // GetSockOpt implements the linux syscall getsockopt(2) for sockets backed by
 // a transport.Endpoint.
 func (s *SocketOperations) GetSockOpt(t *kernel.Task, level, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if level == linux.SOL_SOCKET && name == linux.SO_PEERSEC {
+		return s.getSockOptPeerSec(outLen)
+	}
 	return netstack.GetSockOpt(t, s, s.ep, linux.AF_UNIX, s.ep.Type(), level, name, outPtr, outLen)
 }
 
@@ -227,21 +262,21 @@ func (s *SocketOperations) blockingAccept(t *kernel.Task, peerAddr *tcpip.FullAd
 
 // Accept implements the linux syscall accept(2) for sockets backed by
 // a transport.Endpoint.
-func (s *SocketOperations) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+func (s *SocketOperations) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	var peerAddr *tcpip.FullAddress
-	if peerRequested {
+	if writePeer != nil {
 		peerAddr = &tcpip.FullAddress{}
 	}
 	ep, err := s.ep.Accept(peerAddr)
 	if err != nil {
 		if err != syserr.ErrWouldBlock || !blocking {
-			return 0, nil, 0, err
+			return 0, err
 		}
 
 		var err *syserr.Error
 		ep, err = s.blockingAccept(t, peerAddr)
 		if err != nil {
-			return 0, nil, 0, err
+			return 0, err
 		}
 	}
 
@@ -254,43 +289,44 @@ func (s *SocketOperations) Accept(t *kernel.Task, peerRequested bool, flags int,
 		ns.SetFlags(flags.Settable())
 	}
 
-	var addr linux.SockAddr
-	var addrLen uint32
-	if peerAddr != nil {
-		addr, addrLen = socket.ConvertAddress(linux.AF_UNIX, *peerAddr)
+	if writePeer != nil {
+		if err := writePeer(socket.ConvertAddress(linux.AF_UNIX, *peerAddr)); err != nil {
+			return 0, syserr.FromError(err)
+		}
 	}
 
 	fd, e := t.NewFDFrom(0, ns, kernel.FDFlags{
 		CloseOnExec: flags&linux.SOCK_CLOEXEC != 0,
 	})
 	if e != nil {
-		return 0, nil, 0, syserr.FromError(e)
+		return 0, syserr.FromError(e)
 	}
 
 	t.Kernel().RecordSocket(ns)
 
-	return fd, addr, addrLen, nil
+	return fd, nil
 }
 
 // Bind implements the linux syscall bind(2) for unix sockets.
 func (s *SocketOperations) Bind(t *kernel.Task, sockaddr []byte) *syserr.Error {
-	p, e := extractPath(sockaddr)
-	if e != nil {
-		return e
-	}
-
 	bep, ok := s.ep.(transport.BoundEndpoint)
 	if !ok {
 		// This socket can't be bound.
 		return syserr.ErrInvalidArgument
 	}
 
+	if isAutobind(sockaddr) {
+		return s.autobind(t, bep, s)
+	}
+
+	p, e := extractPath(sockaddr)
+	if e != nil {
+		return e
+	}
+
 	return s.ep.Bind(tcpip.FullAddress{Addr: tcpip.Address(p)}, func() *syserr.Error {
 		// Is it abstract?
 		if p[0] == 0 {
-			if t.IsNetworkNamespaced() {
-				return syserr.ErrInvalidEndpointState
-			}
 			asn := t.AbstractSockets()
 			name := p[1:]
 			if err := asn.Bind(t, name, bep, s); err != nil {
@@ -335,11 +371,13 @@ func (s *SocketOperations) Bind(t *kernel.Task, sockaddr []byte) *syserr.Error {
 
 			// Create the socket.
 			//
-			// Note that the file permissions here are not set correctly (see
+			// Note that the file permissions here may not be set correctly (see
 			// gvisor.dev/issue/2324). There is no convenient way to get permissions
-			// on the socket referred to by s, so we will leave this discrepancy
-			// unresolved until VFS2 replaces this code.
-			childDir, err := d.Bind(t, t.FSContext().RootDirectory(), name, bep, fs.FilePermissions{User: fs.PermMask{Read: true}})
+			// on the socket referred to by s, so assume that they are the
+			// default (0777, as in net/socket.c:sock_alloc), to which the umask
+			// applies as in net/unix/af_unix.c:unix_bind.
+			perms := fs.FilePermsFromMode(0777 &^ linux.FileMode(t.FSContext().Umask()))
+			childDir, err := d.Bind(t, t.FSContext().RootDirectory(), name, bep, perms)
 			if err != nil {
 				return syserr.ErrPortInUse
 			}
@@ -361,10 +399,6 @@ func extractEndpoint(t *kernel.Task, sockaddr []byte) (transport.BoundEndpoint,
 
 	// Is it abstract?
 	if path[0] == 0 {
-		if t.IsNetworkNamespaced() {
-			return nil, syserr.ErrInvalidArgument
-		}
-
 		ep := t.AbstractSockets().BoundEndpoint(path[1:])
 		if ep == nil {
 			// No socket found.
@@ -529,6 +563,20 @@ func (s *socketOpsCommon) SendMsg(t *kernel.Task, src usermem.IOSequence, to []b
 	return int(total), syserr.FromError(err)
 }
 
+// getSockOptPeerSec implements getsockopt(SOL_SOCKET, SO_PEERSEC).
+func (s *socketOpsCommon) getSockOptPeerSec(outLen int) (marshal.Marshallable, *syserr.Error) {
+	label, ok := s.ep.PeerSecurityLabel()
+	if !ok || label == "" {
+		return nil, syserr.ErrProtocolNotAvailable
+	}
+	// Like AppArmor, don't include the terminating NUL.
+	if outLen < len(label) {
+		return nil, syserr.ErrRange
+	}
+	v := primitive.ByteSlice(label)
+	return &v, nil
+}
+
 // Passcred implements transport.Credentialer.Passcred.
 func (s *socketOpsCommon) Passcred() bool {
 	return s.ep.Passcred()
@@ -633,11 +681,12 @@ func (s *socketOpsCommon) RecvMsg(t *kernel.Task, dst usermem.IOSequence, flags
 		return dst.CopyOutFrom(t, &r)
 	}
 
-	// If MSG_TRUNC is set with a zero byte destination then we still need
-	// to read the message and discard it, or in the case where MSG_PEEK is
-	// set, leave it be. In both cases the full message length must be
-	// returned.
-	if trunc && dst.Addrs.NumBytes() == 0 {
+	// A zero byte destination can't be read into, but a message must still
+	// be read and discarded, or in the case where MSG_PEEK is set, left be.
+	// This applies to every message-based socket, and to stream sockets when
+	// MSG_TRUNC is set. In all cases, with MSG_TRUNC the full message length
+	// must be returned.
+	if (trunc || isPacket) && dst.Addrs.NumBytes() == 0 {
 		doRead = func() (int64, error) {
 			err := r.Truncate()
 			// Always return zero for bytes read since the destination size is
@@ -114,6 +114,9 @@ func (s *SocketVFS2) Release(ctx context.Context) {
 // GetSockOpt implements the linux syscall getsockopt(2) for sockets backed by
 // a transport.Endpoint.
 func (s *SocketVFS2) GetSockOpt(t *kernel.Task, level, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if level == linux.SOL_SOCKET && name == linux.SO_PEERSEC {
+		return s.getSockOptPeerSec(outLen)
+	}
 	return netstack.GetSockOpt(t, s, s.ep, linux.AF_UNIX, s.ep.Type(), level, name, outPtr, outLen)
 }
 
@@ -140,27 +143,27 @@ func (s *SocketVFS2) blockingAccept(t *kernel.Task, peerAddr *tcpip.FullAddress)
 
 // Accept implements the linux syscall accept(2) for sockets backed by
 // a transport.Endpoint.
-func (s *SocketVFS2) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
+func (s *SocketVFS2) Accept(t *kernel.Task, flags int, blocking bool, writePeer func(linux.SockAddr, uint32) error) (int32, *syserr.Error) {
 	var peerAddr *tcpip.FullAddress
-	if peerRequested {
+	if writePeer != nil {
 		peerAddr = &tcpip.FullAddress{}
 	}
 	ep, err := s.ep.Accept(peerAddr)
 	if err != nil {
 		if err != syserr.ErrWouldBlock || !blocking {
-			return 0, nil, 0, err
+			return 0, err
 		}
 
 		var err *syserr.Error
 		ep, err = s.blockingAccept(t, peerAddr)
 		if err != nil {
-			return 0, nil, 0, err
+			return 0, err
 		}
 	}
 
 	ns, err := NewSockfsFile(t, ep, s.stype)
 	if err != nil {
-		return 0, nil, 0, err
+		return 0, err
 	}
 	defer ns.DecRef(t)
 
@@ -168,42 +171,43 @@ func (s *SocketVFS2) Accept(t *kernel.Task, peerRequested bool, flags int, block
 		ns.SetStatusFlags(t, t.Credentials(), linux.SOCK_NONBLOCK)
 	}
 
-	var addr linux.SockAddr
-	var addrLen uint32
-	if peerAddr != nil {
-		addr, addrLen = socket.ConvertAddress(linux.AF_UNIX, *peerAddr)
+	if writePeer != nil {
+		if err := writePeer(socket.ConvertAddress(linux.AF_UNIX, *peerAddr)); err != nil {
+			return 0, syserr.FromError(err)
+		}
 	}
 
 	fd, e := t.NewFDFromVFS2(0, ns, kernel.FDFlags{
 		CloseOnExec: flags&linux.SOCK_CLOEXEC != 0,
 	})
 	if e != nil {
-		return 0, nil, 0, syserr.FromError(e)
+		return 0, syserr.FromError(e)
 	}
 
 	t.Kernel().RecordSocketVFS2(ns)
-	return fd, addr, addrLen, nil
+	return fd, nil
 }
 
 // Bind implements the linux syscall bind(2) for unix sockets.
 func (s *SocketVFS2) Bind(t *kernel.Task, sockaddr []byte) *syserr.Error {
-	p, e := extractPath(sockaddr)
-	if e != nil {
-		return e
-	}
-
 	bep, ok := s.ep.(transport.BoundEndpoint)
 	if !ok {
 		// This socket can't be bound.
 		return syserr.ErrInvalidArgument
 	}
 
+	if isAutobind(sockaddr) {
+		return s.autobind(t, bep, s)
+	}
+
+	p, e := extractPath(sockaddr)
+	if e != nil {
+		return e
+	}
+
 	return s.ep.Bind(tcpip.FullAddress{Addr: tcpip.Address(p)}, func() *syserr.Error {
 		// Is it abstract?
 		if p[0] == 0 {
-			if t.IsNetworkNamespaced() {
-				return syserr.ErrInvalidEndpointState
-			}
 			asn := t.AbstractSockets()
 			name := p[1:]
 			if err := asn.Bind(t, name, bep, s); err != nil {
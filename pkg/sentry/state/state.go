@@ -107,6 +107,11 @@ type LoadOpts struct {
 
 	// Key is used for state integrity check.
 	Key []byte
+
+	// CheckMetadata, if not nil, is called with the statefile's metadata
+	// before any state is loaded. If it returns an error, Load fails with
+	// that error.
+	CheckMetadata func(metadata map[string]string) error
 }
 
 // Load loads the given kernel, setting the provided platform and stack.
@@ -117,6 +122,12 @@ func (opts LoadOpts) Load(ctx context.Context, k *kernel.Kernel, timeReady chan
 		return ErrStateFile{err}
 	}
 
+	if opts.CheckMetadata != nil {
+		if err := opts.CheckMetadata(m); err != nil {
+			return err
+		}
+	}
+
 	previousMetadata = m
 
 	// Restore the Kernel object graph.
@@ -16,9 +16,21 @@ package linux
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 )
 
+// checkOpenAccessMode returns EINVAL if flags has an access mode of
+// O_ACCMODE without O_PATH. Linux opens such files for neither reading nor
+// writing (used by some drivers for ioctl-only access); we don't support
+// that, and must not treat the access mode as O_RDWR instead.
+func checkOpenAccessMode(flags uint) error {
+	if flags&linux.O_PATH == 0 && flags&linux.O_ACCMODE == linux.O_ACCMODE {
+		return linuxerr.EINVAL
+	}
+	return nil
+}
+
 // flagsToPermissions returns a Permissions object from Linux flags.
 // This includes truncate permission if O_TRUNC is set in the mask.
 func flagsToPermissions(mask uint) (p fs.PermMask) {
@@ -61,7 +61,7 @@ var AMD64 = &kernel.SyscallTable{
 		6:   syscalls.Supported("lstat", Lstat),
 		7:   syscalls.Supported("poll", Poll),
 		8:   syscalls.Supported("lseek", Lseek),
-		9:   syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
+		9:   syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_SYNC, MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
 		10:  syscalls.Supported("mprotect", Mprotect),
 		11:  syscalls.Supported("munmap", Munmap),
 		12:  syscalls.Supported("brk", Brk),
@@ -404,7 +404,12 @@ var AMD64 = &kernel.SyscallTable{
 		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
 		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
 		435: syscalls.ErrorWithEvent("clone3", linuxerr.ENOSYS, "", nil),
+		439: syscalls.Supported("faccessat2", Faccessat2),
+		440: syscalls.ErrorWithEvent("process_madvise", linuxerr.ENOSYS, "", nil),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		452: syscalls.Supported("fchmodat2", Fchmodat2),
+		457: syscalls.ErrorWithEvent("statmount", linuxerr.ENOSYS, "", nil),
+		458: syscalls.ErrorWithEvent("listmount", linuxerr.ENOSYS, "", nil),
 	},
 	Emulate: map[hostarch.Addr]uintptr{
 		0xffffffffff600000: 96,  // vsyscall gettimeofday(2)
@@ -651,7 +656,7 @@ var ARM64 = &kernel.SyscallTable{
 		219: syscalls.Error("keyctl", linuxerr.EACCES, "Not available to user.", nil),
 		220: syscalls.PartiallySupported("clone", Clone, "Mount namespace (CLONE_NEWNS) not supported. Options CLONE_PARENT, CLONE_SYSVSEM not supported.", nil),
 		221: syscalls.Supported("execve", Execve),
-		222: syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
+		222: syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_SYNC, MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
 		223: syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil),
 		224: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
 		225: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
@@ -723,7 +728,12 @@ var ARM64 = &kernel.SyscallTable{
 		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
 		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
 		435: syscalls.ErrorWithEvent("clone3", linuxerr.ENOSYS, "", nil),
+		439: syscalls.Supported("faccessat2", Faccessat2),
+		440: syscalls.ErrorWithEvent("process_madvise", linuxerr.ENOSYS, "", nil),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		452: syscalls.Supported("fchmodat2", Fchmodat2),
+		457: syscalls.ErrorWithEvent("statmount", linuxerr.ENOSYS, "", nil),
+		458: syscalls.ErrorWithEvent("listmount", linuxerr.ENOSYS, "", nil),
 	},
 	Emulate: map[hostarch.Addr]uintptr{},
 	Missing: func(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
@@ -40,7 +40,10 @@ func fileOpAt(t *kernel.Task, dirFD int32, path string, fn func(root *fs.Dirent,
 	if dir == "/" {
 		// Common case: we are accessing a file in the root.
 		root := t.FSContext().RootDirectory()
-		err := fn(root, root, name, linux.MaxSymlinkTraversals)
+		err := checkBeneath(t, root, name)
+		if err == nil {
+			err = fn(root, root, name, linux.MaxSymlinkTraversals)
+		}
 		root.DecRef(t)
 		return err
 	} else if dir == "." && dirFD == linux.AT_FDCWD {
@@ -48,19 +51,49 @@ func fileOpAt(t *kernel.Task, dirFD int32, path string, fn func(root *fs.Dirent,
 		// working directory; skip the look-up.
 		wd := t.FSContext().WorkingDirectory()
 		root := t.FSContext().RootDirectory()
-		err := fn(root, wd, name, linux.MaxSymlinkTraversals)
+		err := checkBeneath(t, wd, name)
+		if err == nil {
+			err = fn(root, wd, name, linux.MaxSymlinkTraversals)
+		}
 		wd.DecRef(t)
 		root.DecRef(t)
 		return err
 	}
 
 	return fileOpOn(t, dirFD, dir, true /* resolve */, func(root *fs.Dirent, d *fs.Dirent, remainingTraversals uint) error {
+		if err := checkBeneath(t, d, name); err != nil {
+			return err
+		}
 		return fn(root, d, name, remainingTraversals)
 	})
 }
 
+// checkBeneath enforces the FSContext's resolve-beneath policy, if any, on an
+// operation on the entry name in directory d. It returns EXDEV if d lies
+// outside of the confinement, or if name is ".." and d is its top.
+//
+// The remainder of the resolution is confined by the mount namespace, which
+// observes the policy through t.
+func checkBeneath(t *kernel.Task, d *fs.Dirent, name string) error {
+	beneath := t.FSContext().ResolveBeneath()
+	if beneath == nil {
+		return nil
+	}
+	defer beneath.DecRef(t)
+	if !d.IsDescendantOf(beneath) || (name == ".." && d == beneath) {
+		return linuxerr.EXDEV
+	}
+	return nil
+}
+
 // fileOpOn performs an operation on the last entry of the path.
 func fileOpOn(t *kernel.Task, dirFD int32, path string, resolve bool, fn func(root *fs.Dirent, d *fs.Dirent, remainingTraversals uint) error) error {
+	return fileOpOnWithContext(t, t, dirFD, path, resolve, fn)
+}
+
+// fileOpOnWithContext is like fileOpOn, but looks up the path with ctx, and
+// thus with the credentials of ctx rather than those of t.
+func fileOpOnWithContext(ctx context.Context, t *kernel.Task, dirFD int32, path string, resolve bool, fn func(root *fs.Dirent, d *fs.Dirent, remainingTraversals uint) error) error {
 	var (
 		d   *fs.Dirent // The file.
 		wd  *fs.Dirent // The working directory (if required.)
@@ -95,9 +128,9 @@ func fileOpOn(t *kernel.Task, dirFD int32, path string, resolve bool, fn func(ro
 	// Lookup the node.
 	remainingTraversals := uint(linux.MaxSymlinkTraversals)
 	if resolve {
-		d, err = t.MountNamespace().FindInode(t, root, rel, path, &remainingTraversals)
+		d, err = t.MountNamespace().FindInode(ctx, root, rel, path, &remainingTraversals)
 	} else {
-		d, err = t.MountNamespace().FindLink(t, root, rel, path, &remainingTraversals)
+		d, err = t.MountNamespace().FindLink(ctx, root, rel, path, &remainingTraversals)
 	}
 	root.DecRef(t)
 	if wd != nil {
@@ -110,6 +143,13 @@ func fileOpOn(t *kernel.Task, dirFD int32, path string, resolve bool, fn func(ro
 		return err
 	}
 
+	// The walk itself rejects escapes, but don't hand out anything that is
+	// not beneath the confinement regardless.
+	if err := checkBeneath(t, d, ""); err != nil {
+		d.DecRef(t)
+		return err
+	}
+
 	err = fn(root, d, remainingTraversals)
 	d.DecRef(t)
 	return err
@@ -117,13 +157,16 @@ func fileOpOn(t *kernel.Task, dirFD int32, path string, resolve bool, fn func(ro
 
 // copyInPath copies a path in.
 func copyInPath(t *kernel.Task, addr hostarch.Addr, allowEmpty bool) (path string, dirPath bool, err error) {
-	path, err = t.CopyInString(addr, linux.PATH_MAX)
+	path, err = t.CopyInString(addr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return "", false, err
 	}
 	if path == "" && !allowEmpty {
 		return "", false, linuxerr.ENOENT
 	}
+	if err := checkNameLengths(path); err != nil {
+		return "", false, err
+	}
 
 	// If the path ends with a /, then checks must be enforced in various
 	// ways in the different callers. We pass this back to the caller.
@@ -132,16 +175,66 @@ func copyInPath(t *kernel.Task, addr hostarch.Addr, allowEmpty bool) (path strin
 	return path, dirPath, nil
 }
 
+// checkNameLengths returns ENAMETOOLONG if any component of path is longer
+// than NAME_MAX.
+func checkNameLengths(path string) error {
+	n := 0
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			n = 0
+			continue
+		}
+		n++
+		if n > linux.NAME_MAX {
+			return linuxerr.ENAMETOOLONG
+		}
+	}
+	return nil
+}
+
+// truncateForOpen truncates d as for open(O_TRUNC). If this changes the size
+// of d, it generates IN_MODIFY, which precedes IN_OPEN as in Linux.
+func truncateForOpen(t *kernel.Task, d *fs.Dirent) error {
+	uattr, err := d.Inode.UnstableAttr(t)
+	if err != nil {
+		return err
+	}
+	if err := d.Inode.Truncate(t, d, 0); err != nil {
+		return err
+	}
+	if uattr.Size != 0 {
+		d.InotifyEvent(linux.IN_MODIFY, 0)
+	}
+	return nil
+}
+
 // LINT.IfChange
 
 func openAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint) (fd uintptr, err error) {
+	if err := checkOpenAccessMode(flags); err != nil {
+		return 0, err
+	}
 	path, dirPath, err := copyInPath(t, addr, false /* allowEmpty */)
 	if err != nil {
 		return 0, err
 	}
 
-	resolve := flags&linux.O_NOFOLLOW == 0
+	// O_NOFOLLOW only applies to the final path component, and a trailing
+	// slash forces it to be followed anyway.
+	resolve := dirPath || flags&linux.O_NOFOLLOW == 0
 	err = fileOpOn(t, dirFD, path, resolve, func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+		// A final symlink that wasn't resolved can't be opened.
+		if fs.IsSymlink(d.Inode.StableAttr) && !resolve {
+			return linuxerr.ELOOP
+		}
+
+		// A trailing slash or O_DIRECTORY requires the file, after following
+		// any final symlink, to be a directory. As in Linux, this is checked
+		// during lookup, before permissions.
+		if !fs.IsDir(d.Inode.StableAttr) && (dirPath || flags&linux.O_DIRECTORY != 0) {
+			return linuxerr.ENOTDIR
+		}
+
 		// First check a few things about the filesystem before trying to get the file
 		// reference.
 		//
@@ -152,27 +245,12 @@ func openAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint) (fd uin
 			return err
 		}
 
-		if fs.IsSymlink(d.Inode.StableAttr) && !resolve {
-			return linuxerr.ELOOP
-		}
-
 		fileFlags := linuxToFlags(flags)
 		// Linux always adds the O_LARGEFILE flag when running in 64-bit mode.
 		fileFlags.LargeFile = true
-		if fs.IsDir(d.Inode.StableAttr) {
-			// Don't allow directories to be opened writable.
-			if fileFlags.Write {
-				return linuxerr.EISDIR
-			}
-		} else {
-			// If O_DIRECTORY is set, but the file is not a directory, then fail.
-			if fileFlags.Directory {
-				return linuxerr.ENOTDIR
-			}
-			// If it's a directory, then make sure.
-			if dirPath {
-				return linuxerr.ENOTDIR
-			}
+		// Don't allow directories to be opened writable.
+		if fs.IsDir(d.Inode.StableAttr) && fileFlags.Write {
+			return linuxerr.EISDIR
 		}
 
 		file, err := d.Inode.GetFile(t, d, fileFlags)
@@ -185,7 +263,7 @@ func openAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint) (fd uin
 		// existing Dirent. Behavior is delegated to the entry's Truncate
 		// implementation.
 		if flags&linux.O_TRUNC != 0 {
-			if err := d.Inode.Truncate(t, d, 0); err != nil {
+			if err := truncateForOpen(t, d); err != nil {
 				return err
 			}
 		}
@@ -209,6 +287,21 @@ func openAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint) (fd uin
 	return fd, err // Use result in frame.
 }
 
+// checkCreatePermission checks that t may create a new entry in parent: parent
+// must not be on a read-only mount, and t needs write and execute permission
+// on it.
+//
+// Linux checks for a read-only mount in fs/namei.c:filename_create() before
+// checking permissions in may_create(), so EROFS takes precedence over EACCES.
+// may_create() additionally checks for an immutable parent; VFS1 does not
+// support inode flags.
+func checkCreatePermission(t *kernel.Task, parent *fs.Dirent) error {
+	if parent.Inode.MountSource.Flags.ReadOnly {
+		return linuxerr.EROFS
+	}
+	return parent.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true})
+}
+
 func mknodAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMode) error {
 	path, dirPath, err := copyInPath(t, addr, false /* allowEmpty */)
 	if err != nil {
@@ -224,7 +317,7 @@ func mknodAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMod
 		}
 
 		// Do we have the appropriate permissions on the parent?
-		if err := d.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+		if err := checkCreatePermission(t, d); err != nil {
 			return err
 		}
 
@@ -251,6 +344,12 @@ func mknodAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMod
 			return d.CreateFifo(t, root, name, perms)
 
 		case linux.ModeSocket:
+			// Linux checks for an existing file before considering whether
+			// the node can be created.
+			if err := checkMknodExists(t, root, d, name); err != nil {
+				return err
+			}
+
 			// While it is possible create a unix domain socket file on linux
 			// using mknod(2), in practice this is pretty useless from an
 			// application. Linux internally uses mknod() to create the socket
@@ -266,6 +365,10 @@ func mknodAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMod
 		case linux.ModeCharacterDevice:
 			fallthrough
 		case linux.ModeBlockDevice:
+			if err := checkMknodExists(t, root, d, name); err != nil {
+				return err
+			}
+
 			// TODO(b/72101894): We don't support creating block or character
 			// devices at the moment.
 			//
@@ -281,6 +384,16 @@ func mknodAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMod
 	})
 }
 
+// checkMknodExists returns EEXIST if name exists in directory d.
+func checkMknodExists(t *kernel.Task, root, d *fs.Dirent, name string) error {
+	child, err := d.Walk(t, root, name)
+	if err != nil {
+		return nil
+	}
+	child.DecRef(t)
+	return linuxerr.EEXIST
+}
+
 // Mknod implements the linux syscall mknod(2).
 func Mknod(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	path := args[0].Pointer()
@@ -303,6 +416,9 @@ func Mknodat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 }
 
 func createAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint, mode linux.FileMode) (fd uintptr, err error) {
+	if err := checkOpenAccessMode(flags); err != nil {
+		return 0, err
+	}
 	path, dirPath, err := copyInPath(t, addr, false /* allowEmpty */)
 	if err != nil {
 		return 0, err
@@ -408,7 +524,7 @@ func createAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint, mode
 			// existing Dirent. Behavior is delegated to the entry's Truncate
 			// implementation.
 			if flags&linux.O_TRUNC != 0 {
-				if err := found.Inode.Truncate(t, found, 0); err != nil {
+				if err := truncateForOpen(t, found); err != nil {
 					return err
 				}
 			}
@@ -423,7 +539,7 @@ func createAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, flags uint, mode
 			// File does not exist. Proceed with creation.
 
 			// Do we have write permissions on the parent?
-			if err := parent.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+			if err := checkCreatePermission(t, parent); err != nil {
 				return err
 			}
 
@@ -516,12 +632,12 @@ func (ac accessContext) Value(key interface{}) interface{} {
 	}
 }
 
-func accessAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode uint) error {
+func accessAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode uint, flags int32) error {
 	const rOK = 4
 	const wOK = 2
 	const xOK = 1
 
-	path, _, err := copyInPath(t, addr, false /* allowEmpty */)
+	path, dirPath, err := copyInPath(t, addr, flags&linux.AT_EMPTY_PATH != 0)
 	if err != nil {
 		return err
 	}
@@ -531,7 +647,8 @@ func accessAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode uint) error
 		return linuxerr.EINVAL
 	}
 
-	return fileOpOn(t, dirFD, path, true /* resolve */, func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+	creds := t.Credentials()
+	if flags&linux.AT_EACCESS == 0 {
 		// access(2) and faccessat(2) check permissions using real
 		// UID/GID, not effective UID/GID.
 		//
@@ -539,7 +656,7 @@ func accessAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode uint) error
 		// uid/gid. We do this by temporarily clearing all FS-related
 		// capabilities and switching the fsuid/fsgid around to the
 		// real ones." -fs/open.c:faccessat
-		creds := t.Credentials().Fork()
+		creds = creds.Fork()
 		creds.EffectiveKUID = creds.RealKUID
 		creds.EffectiveKGID = creds.RealKGID
 		if creds.EffectiveKUID.In(creds.UserNamespace) == auth.RootUID {
@@ -547,17 +664,43 @@ func accessAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode uint) error
 		} else {
 			creds.EffectiveCaps = 0
 		}
+	}
 
-		ctx := &accessContext{
-			Context: t,
-			creds:   creds,
-		}
+	// The path is also looked up with creds, like Linux does.
+	ctx := &accessContext{
+		Context: t,
+		creds:   creds,
+	}
 
+	check := func(d *fs.Dirent) error {
 		return d.Inode.CheckPermission(ctx, fs.PermMask{
 			Read:    mode&rOK != 0,
 			Write:   mode&wOK != 0,
 			Execute: mode&xOK != 0,
 		})
+	}
+
+	if path == "" {
+		// AT_EMPTY_PATH: check the file referred to by dirFD, which may be
+		// the working directory.
+		if dirFD == linux.AT_FDCWD {
+			wd := t.FSContext().WorkingDirectory()
+			defer wd.DecRef(t)
+			return check(wd)
+		}
+		file := t.GetFile(dirFD)
+		if file == nil {
+			return linuxerr.EBADF
+		}
+		defer file.DecRef(t)
+		return check(file.Dirent)
+	}
+
+	// If AT_SYMLINK_NOFOLLOW is set, a final symlink is checked rather than
+	// its target, unless the path ends in a slash.
+	resolve := dirPath || flags&linux.AT_SYMLINK_NOFOLLOW == 0
+	return fileOpOnWithContext(ctx, t, dirFD, path, resolve, func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+		return check(d)
 	})
 }
 
@@ -566,7 +709,7 @@ func Access(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscal
 	addr := args[0].Pointer()
 	mode := args[1].ModeT()
 
-	return 0, nil, accessAt(t, linux.AT_FDCWD, addr, mode)
+	return 0, nil, accessAt(t, linux.AT_FDCWD, addr, mode, 0 /* flags */)
 }
 
 // Faccessat implements linux syscall faccessat(2).
@@ -582,7 +725,21 @@ func Faccessat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 	addr := args[1].Pointer()
 	mode := args[2].ModeT()
 
-	return 0, nil, accessAt(t, dirFD, addr, mode)
+	return 0, nil, accessAt(t, dirFD, addr, mode, 0 /* flags */)
+}
+
+// Faccessat2 implements linux syscall faccessat2(2).
+func Faccessat2(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirFD := args[0].Int()
+	addr := args[1].Pointer()
+	mode := args[2].ModeT()
+	flags := args[3].Int()
+
+	if flags&^(linux.AT_EACCESS|linux.AT_SYMLINK_NOFOLLOW|linux.AT_EMPTY_PATH) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	return 0, nil, accessAt(t, dirFD, addr, mode, flags)
 }
 
 // LINT.ThenChange(vfs2/filesystem.go)
@@ -866,27 +1023,7 @@ func fGetOwnEx(t *kernel.Task, file *fs.File) linux.FOwnerEx {
 	if ma == nil {
 		return linux.FOwnerEx{}
 	}
-	a := ma.(*fasync.FileAsync)
-	ot, otg, opg := a.Owner()
-	switch {
-	case ot != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_TID,
-			PID:  int32(t.PIDNamespace().IDOfTask(ot)),
-		}
-	case otg != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_PID,
-			PID:  int32(t.PIDNamespace().IDOfThreadGroup(otg)),
-		}
-	case opg != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_PGRP,
-			PID:  int32(t.PIDNamespace().IDOfProcessGroup(opg)),
-		}
-	default:
-		return linux.FOwnerEx{}
-	}
+	return ma.(*fasync.FileAsync).OwnerEx(t.PIDNamespace())
 }
 
 func fGetOwn(t *kernel.Task, file *fs.File) int32 {
@@ -1189,7 +1326,7 @@ func mkdirAt(t *kernel.Task, dirFD int32, addr hostarch.Addr, mode linux.FileMod
 			return err
 		default:
 			// Do we have write permissions on the parent?
-			if err := d.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+			if err := checkCreatePermission(t, d); err != nil {
 				return err
 			}
 
@@ -1268,7 +1405,7 @@ func symlinkAt(t *kernel.Task, dirFD int32, newAddr hostarch.Addr, oldAddr hosta
 
 	// The oldPath is copied in verbatim. This is because the symlink
 	// will include all details, including trailing slashes.
-	oldPath, err := t.CopyInString(oldAddr, linux.PATH_MAX)
+	oldPath, err := t.CopyInString(oldAddr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return err
 	}
@@ -1282,7 +1419,7 @@ func symlinkAt(t *kernel.Task, dirFD int32, newAddr hostarch.Addr, oldAddr hosta
 		}
 
 		// Make sure we have write permissions on the parent directory.
-		if err := d.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+		if err := checkCreatePermission(t, d); err != nil {
 			return err
 		}
 		return d.CreateLink(t, root, oldPath, name)
@@ -1372,7 +1509,7 @@ func linkAt(t *kernel.Task, oldDirFD int32, oldAddr hostarch.Addr, newDirFD int3
 			}
 
 			// Make sure we have write permissions on the parent directory.
-			if err := newParent.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+			if err := checkCreatePermission(t, newParent); err != nil {
 				return err
 			}
 			return newParent.CreateHardLink(t, root, target.Dirent, newName)
@@ -1393,7 +1530,7 @@ func linkAt(t *kernel.Task, oldDirFD int32, oldAddr hostarch.Addr, newDirFD int3
 			}
 
 			// Make sure we have write permissions on the parent directory.
-			if err := newParent.Inode.CheckPermission(t, fs.PermMask{Write: true, Execute: true}); err != nil {
+			if err := checkCreatePermission(t, newParent); err != nil {
 				return err
 			}
 			return newParent.CreateHardLink(t, root, target, newName)
@@ -1678,7 +1815,18 @@ func chown(t *kernel.Task, d *fs.Dirent, uid auth.UID, gid auth.GID) error {
 	c := t.Credentials()
 	hasCap := d.Inode.CheckCapability(t, linux.CAP_CHOWN)
 	isOwner := uattr.Owner.UID == c.EffectiveKUID
-	var clearPrivilege bool
+
+	// As in Linux's fs/open.c:chown_common(), the setuid and setgid bits of
+	// non-directories are cleared by chown, even if neither the owner nor the
+	// group changes. Doing so is a mode change, which requires ownership
+	// (fs/attr.c:setattr_prepare()).
+	newPerms := uattr.Perms
+	newPerms.DropSetUIDAndMaybeGID()
+	clearPrivilege := newPerms != uattr.Perms && !fs.IsDir(d.Inode.StableAttr)
+	if clearPrivilege && !isOwner && !d.Inode.CheckCapability(t, linux.CAP_FOWNER) {
+		return linuxerr.EPERM
+	}
+
 	if uid.Ok() {
 		kuid := c.UserNamespace.MapToKUID(uid)
 		// Valid UID must be supplied if UID is to be changed.
@@ -1696,11 +1844,6 @@ func chown(t *kernel.Task, d *fs.Dirent, uid auth.UID, gid auth.GID) error {
 			return linuxerr.EPERM
 		}
 
-		// The setuid and setgid bits are cleared during a chown.
-		if uattr.Owner.UID != kuid {
-			clearPrivilege = true
-		}
-
 		owner.UID = kuid
 	}
 	if gid.Ok() {
@@ -1719,11 +1862,6 @@ func chown(t *kernel.Task, d *fs.Dirent, uid auth.UID, gid auth.GID) error {
 			return linuxerr.EPERM
 		}
 
-		// The setuid and setgid bits are cleared during a chown.
-		if uattr.Owner.GID != kgid {
-			clearPrivilege = true
-		}
-
 		owner.GID = kgid
 	}
 
@@ -1734,10 +1872,9 @@ func chown(t *kernel.Task, d *fs.Dirent, uid auth.UID, gid auth.GID) error {
 	if err := d.Inode.SetOwner(t, d, owner); err != nil {
 		return err
 	}
-	// Clear privilege bits if needed and they are set.
-	if clearPrivilege && uattr.Perms.HasSetUIDOrGID() && !fs.IsDir(d.Inode.StableAttr) {
-		uattr.Perms.DropSetUIDAndMaybeGID()
-		if !d.Inode.SetPermissions(t, d, uattr.Perms) {
+	// Clear privilege bits if needed.
+	if clearPrivilege {
+		if !d.Inode.SetPermissions(t, d, newPerms) {
 			return linuxerr.EPERM
 		}
 	}
@@ -1752,7 +1889,15 @@ func chownAt(t *kernel.Task, fd int32, addr hostarch.Addr, resolve, allowEmpty b
 	}
 
 	if path == "" {
-		// Annoying. What's wrong with fchown?
+		// Annoying. What's wrong with fchown? As there, no capability is
+		// needed beyond what the ownership change itself requires; in
+		// particular, unlike linkat, AT_EMPTY_PATH doesn't require
+		// CAP_DAC_READ_SEARCH.
+		if fd == linux.AT_FDCWD {
+			wd := t.FSContext().WorkingDirectory()
+			defer wd.DecRef(t)
+			return chown(t, wd, uid, gid)
+		}
 		file := t.GetFile(fd)
 		if file == nil {
 			return linuxerr.EBADF
@@ -1832,14 +1977,38 @@ func chmod(t *kernel.Task, d *fs.Dirent, mode linux.FileMode) error {
 	return nil
 }
 
-func chmodAt(t *kernel.Task, fd int32, addr hostarch.Addr, mode linux.FileMode) error {
-	path, _, err := copyInPath(t, addr, false /* allowEmpty */)
+func chmodAt(t *kernel.Task, fd int32, addr hostarch.Addr, mode linux.FileMode, flags int32) error {
+	if flags&^(linux.AT_EMPTY_PATH|linux.AT_SYMLINK_NOFOLLOW) != 0 {
+		return linuxerr.EINVAL
+	}
+
+	path, _, err := copyInPath(t, addr, flags&linux.AT_EMPTY_PATH != 0)
 	if err != nil {
 		return err
 	}
 
-	return fileOpOn(t, fd, path, true /* resolve */, func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+	// Symlink modes can't be changed, so fail if the file is one; this is
+	// consistent with Linux's fs/attr.c:notify_change().
+	chmodFile := func(d *fs.Dirent) error {
+		if fs.IsSymlink(d.Inode.StableAttr) {
+			return linuxerr.EOPNOTSUPP
+		}
 		return chmod(t, d, mode)
+	}
+
+	if path == "" {
+		file := t.GetFile(fd)
+		if file == nil {
+			return linuxerr.EBADF
+		}
+		defer file.DecRef(t)
+
+		return chmodFile(file.Dirent)
+	}
+
+	resolve := flags&linux.AT_SYMLINK_NOFOLLOW == 0
+	return fileOpOn(t, fd, path, resolve, func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+		return chmodFile(d)
 	})
 }
 
@@ -1848,7 +2017,7 @@ func Chmod(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 	addr := args[0].Pointer()
 	mode := linux.FileMode(args[1].ModeT())
 
-	return 0, nil, chmodAt(t, linux.AT_FDCWD, addr, mode)
+	return 0, nil, chmodAt(t, linux.AT_FDCWD, addr, mode, 0 /* flags */)
 }
 
 // Fchmod implements linux syscall fchmod(2).
@@ -1871,7 +2040,17 @@ func Fchmodat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysc
 	addr := args[1].Pointer()
 	mode := linux.FileMode(args[2].ModeT())
 
-	return 0, nil, chmodAt(t, fd, addr, mode)
+	return 0, nil, chmodAt(t, fd, addr, mode, 0 /* flags */)
+}
+
+// Fchmodat2 implements linux syscall fchmodat2(2).
+func Fchmodat2(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fd := args[0].Int()
+	addr := args[1].Pointer()
+	mode := linux.FileMode(args[2].ModeT())
+	flags := args[3].Int()
+
+	return 0, nil, chmodAt(t, fd, addr, mode, flags)
 }
 
 // defaultSetToSystemTimeSpec returns a TimeSpec that will set ATime and MTime
@@ -1885,6 +2064,12 @@ func defaultSetToSystemTimeSpec() fs.TimeSpec {
 
 func utimes(t *kernel.Task, dirFD int32, addr hostarch.Addr, ts fs.TimeSpec, resolve bool) error {
 	setTimestamp := func(root *fs.Dirent, d *fs.Dirent, _ uint) error {
+		// As in Linux's fs/utimes.c, timestamps can't be changed on a
+		// read-only mount; this precedes ownership and permission checks.
+		if d.Inode.MountSource.Flags.ReadOnly {
+			return linuxerr.EROFS
+		}
+
 		// Does the task own the file?
 		if !d.Inode.CheckOwnership(t) {
 			// Trying to set a specific time? Must be owner.
@@ -2240,7 +2425,11 @@ func MemfdCreate(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.S
 	if len(name) > memfdMaxNameLen {
 		return 0, nil, linuxerr.EINVAL
 	}
-	name = memfdPrefix + name
+	// Per Linux, mm/shmem.c:__shmem_file_setup() =>
+	// fs/file_table.c:alloc_file_pseudo() creates a dentry whose pathname, as
+	// shown in /proc/[pid]/fd and /proc/[pid]/maps, is its name prefixed with
+	// "/" and suffixed with " (deleted)"; see fs/d_path.c:simple_dname().
+	name = memfdPrefix + name + " (deleted)"
 
 	inode := tmpfs.NewMemfdInode(t, allowSeals)
 	dirent := fs.NewDirent(t, inode, name)
@@ -58,6 +58,7 @@ func futexWaitAbsolute(t *kernel.Task, clockRealtime bool, ts linux.Timespec, fo
 	if err != nil {
 		return 0, err
 	}
+	t.SetWaitReason("futex_wait")
 
 	if forever {
 		err = t.Block(w.C)
@@ -94,6 +95,7 @@ func futexWaitDuration(t *kernel.Task, duration time.Duration, forever bool, add
 	if err != nil {
 		return 0, err
 	}
+	t.SetWaitReason("futex_wait")
 
 	remaining, err := t.BlockWithTimeout(w.C, !forever, duration)
 	t.Futex().WaitComplete(w, t)
@@ -135,6 +137,7 @@ func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.
 		// Futex acquired, we're done!
 		return nil
 	}
+	t.SetWaitReason("futex_lock_pi")
 
 	if forever {
 		err = t.Block(w.C)
@@ -43,14 +43,26 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 	prot := args[2].Int()
 	flags := args[3].Int()
 	fd := args[4].Int()
-	fixed := flags&linux.MAP_FIXED != 0
-	private := flags&linux.MAP_PRIVATE != 0
-	shared := flags&linux.MAP_SHARED != 0
+	// MAP_FIXED_NOREPLACE implies MAP_FIXED, but takes precedence over it.
+	noReplace := flags&linux.MAP_FIXED_NOREPLACE != 0
+	fixed := flags&linux.MAP_FIXED != 0 || noReplace
 	anon := flags&linux.MAP_ANONYMOUS != 0
 	map32bit := flags&linux.MAP_32BIT != 0
 
-	// Require exactly one of MAP_PRIVATE and MAP_SHARED.
-	if private == shared {
+	var private, shared, validate bool
+	switch flags & linux.MAP_TYPE {
+	case linux.MAP_PRIVATE:
+		private = true
+	case linux.MAP_SHARED:
+		shared = true
+	case linux.MAP_SHARED_VALIDATE:
+		// As in Linux, anonymous mappings don't accept MAP_SHARED_VALIDATE.
+		if anon {
+			return 0, nil, linuxerr.EINVAL
+		}
+		shared = true
+		validate = true
+	default:
 		return 0, nil, linuxerr.EINVAL
 	}
 
@@ -59,7 +71,7 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 		Offset:   args[5].Uint64(),
 		Addr:     args[0].Pointer(),
 		Fixed:    fixed,
-		Unmap:    fixed,
+		Unmap:    fixed && !noReplace,
 		Map32Bit: map32bit,
 		Private:  private,
 		Perms: hostarch.AccessType{
@@ -88,6 +100,12 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 		}
 		defer file.DecRef(t)
 
+		// MAP_SHARED_VALIDATE rejects flags that MAP_SHARED silently ignores.
+		// No file supports MAP_SYNC, so only legacy flags are accepted.
+		if validate && uint32(flags)&^linux.LEGACY_MAP_MASK != 0 {
+			return 0, nil, linuxerr.EOPNOTSUPP
+		}
+
 		flags := file.Flags()
 		// mmap unconditionally requires that the FD is readable.
 		if !flags.Read {
@@ -161,7 +179,7 @@ func Mremap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscal
 func Mprotect(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	length := args[1].Uint64()
 	prot := args[2].Int()
-	err := t.MemoryManager().MProtect(args[0].Pointer(), length, hostarch.AccessType{
+	err := t.MemoryManager().MProtect(t, args[0].Pointer(), length, hostarch.AccessType{
 		Read:    linux.PROT_READ&prot != 0,
 		Write:   linux.PROT_WRITE&prot != 0,
 		Execute: linux.PROT_EXEC&prot != 0,
@@ -175,28 +193,36 @@ func Madvise(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 	length := uint64(args[1].SizeT())
 	adv := args[2].Int()
 
+	return 0, nil, MadviseMM(t.MemoryManager(), addr, length, adv)
+}
+
+// MadviseMM applies advice adv to the given range of m, with the semantics of
+// madvise(2). It is shared by madvise(2) and process_madvise(2).
+func MadviseMM(m *mm.MemoryManager, addr hostarch.Addr, length uint64, adv int32) error {
 	// "The Linux implementation requires that the address addr be
 	// page-aligned, and allows length to be zero." - madvise(2)
 	if addr.RoundDown() != addr {
-		return 0, nil, linuxerr.EINVAL
+		return linuxerr.EINVAL
 	}
 	if length == 0 {
-		return 0, nil, nil
+		return nil
 	}
 	// Not explicitly stated: length need not be page-aligned.
 	lenAddr, ok := hostarch.Addr(length).RoundUp()
 	if !ok {
-		return 0, nil, linuxerr.EINVAL
+		return linuxerr.EINVAL
 	}
 	length = uint64(lenAddr)
 
 	switch adv {
 	case linux.MADV_DONTNEED:
-		return 0, nil, t.MemoryManager().Decommit(addr, length)
+		return m.Decommit(addr, length)
 	case linux.MADV_DOFORK:
-		return 0, nil, t.MemoryManager().SetDontFork(addr, length, false)
+		return m.SetDontFork(addr, length, false)
 	case linux.MADV_DONTFORK:
-		return 0, nil, t.MemoryManager().SetDontFork(addr, length, true)
+		return m.SetDontFork(addr, length, true)
+	case linux.MADV_COLD, linux.MADV_PAGEOUT:
+		return m.MarkCold(addr, length)
 	case linux.MADV_HUGEPAGE, linux.MADV_NOHUGEPAGE:
 		fallthrough
 	case linux.MADV_MERGEABLE, linux.MADV_UNMERGEABLE:
@@ -207,17 +233,17 @@ func Madvise(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 		fallthrough
 	case linux.MADV_NORMAL, linux.MADV_RANDOM, linux.MADV_SEQUENTIAL, linux.MADV_WILLNEED:
 		// Do nothing, we totally ignore the suggestions above.
-		return 0, nil, nil
+		return nil
 	case linux.MADV_REMOVE:
 		// These "suggestions" have application-visible side effects, so we
 		// have to indicate that we don't support them.
-		return 0, nil, linuxerr.ENOSYS
+		return linuxerr.ENOSYS
 	case linux.MADV_HWPOISON:
 		// Only privileged processes are allowed to poison pages.
-		return 0, nil, linuxerr.EPERM
+		return linuxerr.EPERM
 	default:
 		// If adv is not a valid value tell the caller.
-		return 0, nil, linuxerr.EINVAL
+		return linuxerr.EINVAL
 	}
 }
 
@@ -271,7 +297,7 @@ func Msync(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 	if sync && flags&linux.MS_ASYNC != 0 {
 		return 0, nil, linuxerr.EINVAL
 	}
-	err := t.MemoryManager().MSync(t, addr, uint64(length), mm.MSyncOpts{
+	err := t.MemoryManager().MSync(t, addr, uint64(length), memmap.MSyncOpts{
 		Sync:       sync,
 		Invalidate: flags&linux.MS_INVALIDATE != 0,
 	})
@@ -394,22 +394,40 @@ func copyOutTimevalRemaining(t *kernel.Task, startNs ktime.Time, timeout time.Du
 type pollRestartBlock struct {
 	pfdAddr hostarch.Addr
 	nfds    uint
-	timeout time.Duration
+
+	// If forever is false, end is the CLOCK_MONOTONIC time at which the
+	// interrupted poll times out. As in Linux's restart_block, the deadline
+	// is absolute, so time spent between the interruption and the restart
+	// (e.g. running a signal handler) is charged against the timeout.
+	forever bool
+	end     ktime.Time
 }
 
 // Restart implements kernel.SyscallRestartBlock.Restart.
 func (p *pollRestartBlock) Restart(t *kernel.Task) (uintptr, error) {
-	return poll(t, p.pfdAddr, p.nfds, p.timeout)
+	timeout := time.Duration(-1)
+	if !p.forever {
+		timeout = p.end.Sub(t.Kernel().MonotonicClock().Now())
+		if timeout < 0 {
+			timeout = 0
+		}
+	}
+	return poll(t, p.pfdAddr, p.nfds, timeout)
 }
 
 func poll(t *kernel.Task, pfdAddr hostarch.Addr, nfds uint, timeout time.Duration) (uintptr, error) {
-	remainingTimeout, n, err := doPoll(t, pfdAddr, nfds, timeout)
+	var startNs ktime.Time
+	if timeout > 0 {
+		startNs = t.Kernel().MonotonicClock().Now()
+	}
+	_, n, err := doPoll(t, pfdAddr, nfds, timeout)
 	// On an interrupt poll(2) is restarted with the remaining timeout.
 	if linuxerr.Equals(linuxerr.EINTR, err) {
 		t.SetSyscallRestartBlock(&pollRestartBlock{
 			pfdAddr: pfdAddr,
 			nfds:    nfds,
-			timeout: remainingTimeout,
+			forever: timeout < 0,
+			end:     startNs.Add(timeout),
 		})
 		return 0, syserror.ERESTART_RESTARTBLOCK
 	}
@@ -443,15 +461,8 @@ func Ppoll(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 		startNs = t.Kernel().MonotonicClock().Now()
 	}
 
-	if maskAddr != 0 {
-		mask, err := CopyInSigSet(t, maskAddr, maskSize)
-		if err != nil {
-			return 0, nil, err
-		}
-
-		oldmask := t.SignalMask()
-		t.SetSignalMask(mask)
-		t.SetSavedSignalMask(oldmask)
+	if err := setTempSignalSet(t, maskAddr, maskSize); err != nil {
+		return 0, nil, err
 	}
 
 	_, n, err := doPoll(t, pfdAddr, nfds, timeout)
@@ -525,14 +536,8 @@ func Pselect(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 			return 0, nil, err
 		}
 
-		if maskAddr != 0 {
-			mask, err := CopyInSigSet(t, maskAddr, size)
-			if err != nil {
-				return 0, nil, err
-			}
-			oldmask := t.SignalMask()
-			t.SetSignalMask(mask)
-			t.SetSavedSignalMask(oldmask)
+		if err := setTempSignalSet(t, maskAddr, size); err != nil {
+			return 0, nil, err
 		}
 	}
 
@@ -544,3 +549,26 @@ func Pselect(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 	}
 	return n, nil, err
 }
+
+// setTempSignalSet installs the signal mask at maskAddr for the duration of
+// the current syscall, as for ppoll(2) and pselect(2). The original mask is
+// stashed in the task's saved signal mask and reinstated when the task
+// returns to the application (or delivers a signal to a handler, in which
+// case it is restored by sigreturn).
+//
+// Both masks are task state, so a checkpoint that interrupts the wait saves
+// them together; the interrupted syscall returns ERESTARTNOHAND, which
+// reinstates the original mask before the syscall is restarted on restore.
+func setTempSignalSet(t *kernel.Task, maskAddr hostarch.Addr, maskSize uint) error {
+	if maskAddr == 0 {
+		return nil
+	}
+	mask, err := CopyInSigSet(t, maskAddr, maskSize)
+	if err != nil {
+		return err
+	}
+	oldmask := t.SignalMask()
+	t.SetSignalMask(mask)
+	t.SetSavedSignalMask(oldmask)
+	return nil
+}
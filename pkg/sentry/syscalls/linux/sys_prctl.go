@@ -16,6 +16,7 @@ package linux
 
 import (
 	"fmt"
+	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -222,17 +223,37 @@ func Prctl(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 	case linux.PR_SET_CHILD_SUBREAPER:
 		// "If arg2 is nonzero, set the "child subreaper" attribute of
 		// the calling process; if arg2 is zero, unset the attribute."
-		//
-		// TODO(gvisor.dev/issues/2323): We only support setting, and
-		// only if the task is already TID 1 in the PID namespace,
-		// because it already acts as a subreaper in that case.
-		isPid1 := t.PIDNamespace().IDOfTask(t) == kernel.InitTID
-		if args[1].Int() != 0 && isPid1 {
-			return 0, nil, nil
+		t.ThreadGroup().SetChildSubreaper(args[1].Int() != 0)
+
+	case linux.PR_GET_CHILD_SUBREAPER:
+		var isSubreaper int32
+		if t.ThreadGroup().IsChildSubreaper() {
+			isSubreaper = 1
 		}
+		_, err := primitive.CopyInt32Out(t, args[1].Pointer(), isSubreaper)
+		return 0, nil, err
 
-		t.Kernel().EmitUnimplementedEvent(t)
-		return 0, nil, linuxerr.EINVAL
+	case linux.PR_SET_VMA:
+		if args[1].Int() != linux.PR_SET_VMA_ANON_NAME {
+			return 0, nil, linuxerr.EINVAL
+		}
+		addr := args[2].Pointer()
+		length := args[3].Uint64()
+		var name string
+		if nameAddr := args[4].Pointer(); nameAddr != 0 {
+			var err error
+			name, err = t.CopyInString(nameAddr, linux.ANON_VMA_NAME_MAX_LEN)
+			if linuxerr.Equals(linuxerr.ENAMETOOLONG, err) {
+				return 0, nil, linuxerr.EINVAL
+			}
+			if err != nil {
+				return 0, nil, err
+			}
+			if !validAnonVMAName(name) {
+				return 0, nil, linuxerr.EINVAL
+			}
+		}
+		return 0, nil, t.MemoryManager().SetVMAAnonName(addr, length, name)
 
 	case linux.PR_GET_TIMING,
 		linux.PR_SET_TIMING,
@@ -245,7 +266,6 @@ func Prctl(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 		linux.PR_MCE_KILL,
 		linux.PR_MCE_KILL_GET,
 		linux.PR_GET_TID_ADDRESS,
-		linux.PR_GET_CHILD_SUBREAPER,
 		linux.PR_GET_THP_DISABLE,
 		linux.PR_SET_THP_DISABLE,
 		linux.PR_MPX_ENABLE_MANAGEMENT,
@@ -259,3 +279,17 @@ func Prctl(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 
 	return 0, nil, nil
 }
+
+// validAnonVMAName returns true if name may be set by
+// prctl(PR_SET_VMA_ANON_NAME): it must consist of printable ASCII characters,
+// excluding those that could be confused with /proc/[pid]/maps syntax or
+// shell metacharacters. Compare Linux's kernel/sys.c:is_valid_name_char().
+func validAnonVMAName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= 0x1f || c >= 0x7f || strings.IndexByte("\\`$[]", c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
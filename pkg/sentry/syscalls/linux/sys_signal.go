@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/signalfd"
 	"gvisor.dev/gvisor/pkg/syserror"
 )
@@ -85,12 +86,7 @@ func Kill(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 			if !mayKill(t, target, sig) {
 				return 0, nil, linuxerr.EPERM
 			}
-			info := &linux.SignalInfo{
-				Signo: int32(sig),
-				Code:  linux.SI_USER,
-			}
-			info.SetPID(int32(target.PIDNamespace().IDOfTask(t)))
-			info.SetUID(int32(t.Credentials().RealKUID.In(target.UserNamespace()).OrOverflow()))
+			info := killSigInfo(t, target, sig)
 			if err := target.SendGroupSignal(info); !linuxerr.Equals(linuxerr.ESRCH, err) {
 				return 0, nil, err
 			}
@@ -124,12 +120,7 @@ func Kill(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 			// depend on the iteration order. We at least implement the
 			// semantics documented by the man page: "On success (at least
 			// one signal was sent), zero is returned."
-			info := &linux.SignalInfo{
-				Signo: int32(sig),
-				Code:  linux.SI_USER,
-			}
-			info.SetPID(int32(tg.PIDNamespace().IDOfTask(t)))
-			info.SetUID(int32(t.Credentials().RealKUID.In(tg.Leader().UserNamespace()).OrOverflow()))
+			info := killSigInfo(t, tg.Leader(), sig)
 			err := tg.SendSignal(info)
 			if linuxerr.Equals(linuxerr.ESRCH, err) {
 				// ESRCH is ignored because it means the task
@@ -168,12 +159,7 @@ func Kill(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 					continue
 				}
 
-				info := &linux.SignalInfo{
-					Signo: int32(sig),
-					Code:  linux.SI_USER,
-				}
-				info.SetPID(int32(tg.PIDNamespace().IDOfTask(t)))
-				info.SetUID(int32(t.Credentials().RealKUID.In(tg.Leader().UserNamespace()).OrOverflow()))
+				info := killSigInfo(t, tg.Leader(), sig)
 				// See note above regarding ESRCH race above.
 				if err := tg.SendSignal(info); !linuxerr.Equals(linuxerr.ESRCH, err) {
 					lastErr = err
@@ -185,6 +171,19 @@ func Kill(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 	}
 }
 
+// killSigInfo returns the siginfo for a kill(2) of receiver by sender. As in
+// Linux's kernel/signal.c:prepare_kill_siginfo, si_pid is the sender's thread
+// group ID, as seen from the receiver's PID namespace.
+func killSigInfo(sender, receiver *kernel.Task, sig linux.Signal) *linux.SignalInfo {
+	info := &linux.SignalInfo{
+		Signo: int32(sig),
+		Code:  linux.SI_USER,
+	}
+	info.SetPID(int32(receiver.PIDNamespace().IDOfThreadGroup(sender.ThreadGroup())))
+	info.SetUID(int32(sender.Credentials().RealKUID.In(receiver.UserNamespace()).OrOverflow()))
+	return info
+}
+
 func tkillSigInfo(sender, receiver *kernel.Task, sig linux.Signal) *linux.SignalInfo {
 	info := &linux.SignalInfo{
 		Signo: int32(sig),
@@ -434,7 +433,9 @@ func RtSigqueueinfo(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kerne
 			return 0, nil, linuxerr.EPERM
 		}
 
-		if err := target.SendGroupSignal(&info); !linuxerr.Equals(linuxerr.ESRCH, err) {
+		infoCopy := info
+		translateQueuedSigInfo(t, target, &infoCopy)
+		if err := target.SendGroupSignal(&infoCopy); !linuxerr.Equals(linuxerr.ESRCH, err) {
 			return 0, nil, err
 		}
 	}
@@ -476,9 +477,32 @@ func RtTgsigqueueinfo(t *kernel.Task, args arch.SyscallArguments) (uintptr, *ker
 	if !mayKill(t, target, sig) {
 		return 0, nil, linuxerr.EPERM
 	}
+	translateQueuedSigInfo(t, target, &info)
 	return 0, nil, target.SendSignal(&info)
 }
 
+// translateQueuedSigInfo translates the si_pid and si_uid fields of a siginfo
+// supplied by sender through rt_sigqueueinfo or rt_tgsigqueueinfo into the
+// namespaces of receiver. si_uid is interpreted in sender's user namespace,
+// and si_pid is cleared if sender is not visible in receiver's PID namespace.
+// This is equivalent to the has_si_pid_and_uid case of Linux's
+// kernel/signal.c:send_signal.
+func translateQueuedSigInfo(sender, receiver *kernel.Task, info *linux.SignalInfo) {
+	// Only a task signalling itself may use si_codes >= 0, in which case
+	// there is nothing to translate. SI_TIMER and SI_SIGIO have no si_pid or
+	// si_uid.
+	if info.Code >= 0 || info.Code == linux.SI_TIMER || info.Code == linux.SI_SIGIO {
+		return
+	}
+	if senderNS, receiverNS := sender.UserNamespace(), receiver.UserNamespace(); senderNS != receiverNS {
+		kuid := senderNS.MapToKUID(auth.UID(info.UID()))
+		info.SetUID(int32(kuid.In(receiverNS).OrOverflow()))
+	}
+	if receiver.PIDNamespace().IDOfThreadGroup(sender.ThreadGroup()) == 0 {
+		info.SetPID(0)
+	}
+}
+
 // RtSigsuspend implements linux syscall rt_sigsuspend(2).
 func RtSigsuspend(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	sigset := args[0].Pointer()
@@ -294,22 +294,22 @@ func accept(t *kernel.Task, fd int32, addr hostarch.Addr, addrLen hostarch.Addr,
 		return 0, linuxerr.ENOTSOCK
 	}
 
-	// Call the syscall implementation for this socket, then copy the
-	// output address if one is specified.
+	// Call the syscall implementation for this socket, which copies out the
+	// peer address if one is requested.
 	blocking := !file.Flags().NonBlocking
 
-	peerRequested := addrLen != 0
-	nfd, peer, peerLen, e := s.Accept(t, peerRequested, flags, blocking)
+	// Like Linux, if the peer address can't be written back out, the
+	// accepted connection is dropped before it is installed in the FD table.
+	var writePeer func(linux.SockAddr, uint32) error
+	if addrLen != 0 {
+		writePeer = func(peer linux.SockAddr, peerLen uint32) error {
+			return writeAddress(t, peer, peerLen, addr, addrLen)
+		}
+	}
+	nfd, e := s.Accept(t, flags, blocking, writePeer)
 	if e != nil {
 		return 0, syserror.ConvertIntr(e.ToError(), syserror.ERESTARTSYS)
 	}
-	if peerRequested {
-		// NOTE(magi): Linux does not give you an error if it can't
-		// write the data back out so neither do we.
-		if err := writeAddress(t, peer, peerLen, addr, addrLen); linuxerr.Equals(linuxerr.EINVAL, err) {
-			return 0, err
-		}
-	}
 	return uintptr(nfd), nil
 }
 
@@ -397,7 +397,11 @@ func Listen(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscal
 	// backlog and not >=.
 	backlog++
 
-	return 0, nil, s.Listen(t, int(backlog)).ToError()
+	if err := s.Listen(t, int(backlog)); err != nil {
+		return 0, nil, err.ToError()
+	}
+	socket.EmitListenEvent(t, fd, s, int(args[1].Int()))
+	return 0, nil, nil
 }
 
 // Shutdown implements the linux syscall shutdown(2).
@@ -765,7 +769,8 @@ func recvSingleMsg(t *kernel.Task, s socket.Socket, msgPtr hostarch.Addr, flags
 		if err != nil {
 			return 0, syserror.ConvertIntr(err.ToError(), syserror.ERESTARTSYS)
 		}
-		if !cms.Unix.Empty() {
+		if !cms.Unix.Empty() || control.CmsgsSpace(t, cms) != 0 {
+			// There is no space for any control messages.
 			mflags |= linux.MSG_CTRUNC
 			cms.Release(t)
 		}
@@ -790,7 +795,11 @@ func recvSingleMsg(t *kernel.Task, s socket.Socket, msgPtr hostarch.Addr, flags
 	defer cms.Release(t)
 
 	controlData := make([]byte, 0, msg.ControlLen)
-	controlData = control.PackControlMessages(t, cms, controlData)
+	controlData, mflags = control.PackControlMessages(t, cms, controlData, mflags)
+
+	if cms.Unix.SecurityLabel != "" {
+		controlData, mflags = control.PackSecurityLabel(t, cms.Unix.SecurityLabel, controlData, mflags)
+	}
 
 	if cr, ok := s.(transport.Credentialer); ok && cr.Passcred() {
 		creds, _ := cms.Unix.Credentials.(control.SCMCredentials)
@@ -19,6 +19,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
+	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/syserror"
 )
@@ -27,8 +28,12 @@ import (
 
 // Sync implements linux system call sync(2).
 func Sync(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
-	t.MountNamespace().SyncAll(t)
-	// Sync is always successful.
+	fsmetric.Syncs.Increment()
+	if err := t.MountNamespace().SyncAll(t); err != nil {
+		// Everything that could be synced has been; report that something
+		// failed to be written back, as Linux does for writeback errors.
+		return 0, nil, linuxerr.EIO
+	}
 	return 0, nil, nil
 }
 
@@ -85,7 +85,7 @@ func Execveat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysc
 }
 
 func execveat(t *kernel.Task, dirFD int32, pathnameAddr, argvAddr, envvAddr hostarch.Addr, flags int32) (uintptr, *kernel.SyscallControl, error) {
-	pathname, err := t.CopyInString(pathnameAddr, linux.PATH_MAX)
+	pathname, err := t.CopyInString(pathnameAddr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return 0, nil, err
 	}
@@ -177,6 +177,9 @@ func execveat(t *kernel.Task, dirFD int32, pathnameAddr, argvAddr, envvAddr host
 	}
 
 	ctrl, err := t.Execve(image)
+	if err == nil {
+		t.EmitExecveEvent(pathname, argv)
+	}
 	return 0, ctrl, err
 }
 
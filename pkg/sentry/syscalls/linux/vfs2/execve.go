@@ -50,7 +50,7 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 		return 0, nil, linuxerr.EINVAL
 	}
 
-	pathname, err := t.CopyInString(pathnameAddr, linux.PATH_MAX)
+	pathname, err := t.CopyInString(pathnameAddr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return 0, nil, err
 	}
@@ -132,5 +132,8 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 	}
 
 	ctrl, err := t.Execve(image)
+	if err == nil {
+		t.EmitExecveEvent(pathname, argv)
+	}
 	return 0, ctrl, err
 }
@@ -0,0 +1,154 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs2
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/fanotify"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+const (
+	fanotifyInitFlags = linux.FAN_CLOEXEC | linux.FAN_NONBLOCK | linux.FAN_ALL_CLASS_BITS |
+		linux.FAN_UNLIMITED_QUEUE | linux.FAN_UNLIMITED_MARKS
+
+	fanotifyEventFlags = linux.O_ACCMODE | linux.O_LARGEFILE | linux.O_CLOEXEC | linux.O_APPEND |
+		linux.O_DSYNC | linux.O_NOATIME | linux.O_NONBLOCK | linux.O_SYNC
+
+	fanotifyMarkFlags = linux.FAN_MARK_ADD | linux.FAN_MARK_REMOVE | linux.FAN_MARK_DONT_FOLLOW |
+		linux.FAN_MARK_ONLYDIR | linux.FAN_MARK_MOUNT | linux.FAN_MARK_IGNORED_MASK |
+		linux.FAN_MARK_IGNORED_SURV_MODIFY | linux.FAN_MARK_FLUSH | linux.FAN_MARK_FILESYSTEM
+)
+
+// FanotifyInit implements Linux syscall fanotify_init(2).
+func FanotifyInit(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	flags := args[0].Uint()
+	eventFlags := args[1].Uint()
+
+	if !t.HasCapability(linux.CAP_SYS_ADMIN) {
+		return 0, nil, linuxerr.EPERM
+	}
+	if flags&^fanotifyInitFlags != 0 || flags&linux.FAN_ALL_CLASS_BITS == linux.FAN_ALL_CLASS_BITS {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if eventFlags&^fanotifyEventFlags != 0 || eventFlags&linux.O_ACCMODE == linux.O_ACCMODE {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	file, err := fanotify.New(t, t.Kernel().VFS(), flags, eventFlags)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFromVFS2(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.FAN_CLOEXEC != 0,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// FanotifyMark implements Linux syscall fanotify_mark(2).
+func FanotifyMark(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fanotifyFD := args[0].Int()
+	flags := args[1].Uint()
+	mask := args[2].Uint64()
+	dirfd := args[3].Int()
+	addr := args[4].Pointer()
+
+	if flags&^fanotifyMarkFlags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	markType := flags & (linux.FAN_MARK_MOUNT | linux.FAN_MARK_FILESYSTEM)
+	if markType == linux.FAN_MARK_MOUNT|linux.FAN_MARK_FILESYSTEM {
+		return 0, nil, linuxerr.EINVAL
+	}
+	switch flags & (linux.FAN_MARK_ADD | linux.FAN_MARK_REMOVE | linux.FAN_MARK_FLUSH) {
+	case linux.FAN_MARK_ADD, linux.FAN_MARK_REMOVE:
+		if mask == 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	case linux.FAN_MARK_FLUSH:
+		if flags&^(linux.FAN_MARK_MOUNT|linux.FAN_MARK_FILESYSTEM|linux.FAN_MARK_FLUSH) != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+	default:
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	f := t.GetFileVFS2(fanotifyFD)
+	if f == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer f.DecRef(t)
+	g, ok := f.Impl().(*fanotify.Group)
+	if !ok {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	if flags&linux.FAN_MARK_FLUSH != 0 {
+		g.Flush(t, markType)
+		return 0, nil, nil
+	}
+
+	// Unlike most syscalls, fanotify_mark(2) with a NULL pathname marks the
+	// file referred to by dirfd, which can't be AT_FDCWD.
+	var path fspath.Path
+	if addr == 0 {
+		if dirfd == linux.AT_FDCWD {
+			return 0, nil, linuxerr.EBADF
+		}
+	} else {
+		var err error
+		path, err = copyInPath(t, addr)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if flags&linux.FAN_MARK_ONLYDIR != 0 {
+		path.Dir = true
+	}
+	follow := followFinalSymlink
+	if flags&linux.FAN_MARK_DONT_FOLLOW != 0 {
+		follow = nofollowFinalSymlink
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, shouldAllowEmptyPath(addr == 0), follow)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+	vd, err := t.Kernel().VFS().GetDentryAt(t, t.Credentials(), &tpop.pop, &vfs.GetDentryOptions{})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer vd.DecRef(t)
+
+	var obj interface{}
+	switch markType {
+	case linux.FAN_MARK_INODE:
+		obj = vd.Dentry()
+	case linux.FAN_MARK_MOUNT:
+		obj = vd.Mount()
+	case linux.FAN_MARK_FILESYSTEM:
+		obj = vd.Mount().Filesystem()
+	}
+	return 0, nil, g.Mark(t, obj, flags, mask)
+}
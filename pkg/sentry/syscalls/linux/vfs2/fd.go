@@ -15,6 +15,8 @@
 package vfs2
 
 import (
+	"math"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
@@ -242,26 +244,7 @@ func getAsyncOwner(t *kernel.Task, fd *vfs.FileDescription) (ownerEx linux.FOwne
 		return linux.FOwnerEx{}, false
 	}
 
-	ot, otg, opg := a.(*fasync.FileAsync).Owner()
-	switch {
-	case ot != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_TID,
-			PID:  int32(t.PIDNamespace().IDOfTask(ot)),
-		}, true
-	case otg != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_PID,
-			PID:  int32(t.PIDNamespace().IDOfThreadGroup(otg)),
-		}, true
-	case opg != nil:
-		return linux.FOwnerEx{
-			Type: linux.F_OWNER_PGRP,
-			PID:  int32(t.PIDNamespace().IDOfProcessGroup(opg)),
-		}, true
-	default:
-		return linux.FOwnerEx{}, true
-	}
+	return a.(*fasync.FileAsync).OwnerEx(t.PIDNamespace()), true
 }
 
 func setAsyncOwner(t *kernel.Task, fd int, file *vfs.FileDescription, ownerType, pid int32) error {
@@ -390,6 +373,7 @@ func posixLock(t *kernel.Task, args arch.SyscallArguments, file *vfs.FileDescrip
 // This implementation currently ignores the provided advice.
 func Fadvise64(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	fd := args[0].Int()
+	offset := args[1].Int64()
 	length := args[2].Int64()
 	advice := args[3].Int()
 
@@ -398,6 +382,13 @@ func Fadvise64(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 		return 0, nil, linuxerr.EINVAL
 	}
 
+	// As in Linux, a length of 0 (or one that overflows) means "to the end
+	// of the file", including any future growth.
+	end := offset + length
+	if length == 0 || end < offset {
+		end = math.MaxInt64
+	}
+
 	file := t.GetFileVFS2(fd)
 	if file == nil {
 		return 0, nil, linuxerr.EBADF
@@ -424,6 +415,5 @@ func Fadvise64(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 		return 0, nil, linuxerr.EINVAL
 	}
 
-	// Sure, whatever.
-	return 0, nil, nil
+	return 0, nil, file.Fadvise(t, offset, end, advice)
 }
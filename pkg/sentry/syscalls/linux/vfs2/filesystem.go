@@ -218,6 +218,14 @@ func Renameat2(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 }
 
 func renameat(t *kernel.Task, olddirfd int32, oldpathAddr hostarch.Addr, newdirfd int32, newpathAddr hostarch.Addr, flags uint32) error {
+	if flags&^(linux.RENAME_NOREPLACE|linux.RENAME_EXCHANGE|linux.RENAME_WHITEOUT) != 0 {
+		return linuxerr.EINVAL
+	}
+	// RENAME_EXCHANGE requires both files to exist, so it can't be combined
+	// with flags that create or preserve a file at newpath.
+	if flags&linux.RENAME_EXCHANGE != 0 && flags&(linux.RENAME_NOREPLACE|linux.RENAME_WHITEOUT) != 0 {
+		return linuxerr.EINVAL
+	}
 	oldpath, err := copyInPath(t, oldpathAddr)
 	if err != nil {
 		return err
@@ -314,7 +322,11 @@ func Symlinkat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 }
 
 func symlinkat(t *kernel.Task, targetAddr hostarch.Addr, newdirfd int32, linkpathAddr hostarch.Addr) error {
-	target, err := t.CopyInString(targetAddr, linux.PATH_MAX)
+	// As in Linux, the target may be at most PATH_MAX bytes long including
+	// the terminating NUL; longer targets fail with ENAMETOOLONG. Filesystems
+	// may impose stricter limits, e.g. the gofer forwards the remote
+	// filesystem's.
+	target, err := t.CopyInString(targetAddr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return err
 	}
@@ -36,7 +36,11 @@ func Ioctl(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 		return 0, nil, linuxerr.EBADF
 	}
 
-	// Handle ioctls that apply to all FDs.
+	// Handle ioctls that apply to all FDs. As in Linux's
+	// fs/ioctl.c:do_vfs_ioctl(), these copy their argument in or out before
+	// doing anything else, so a bad argument pointer fails with EFAULT.
+	// Requests that aren't handled here are passed to the file, which returns
+	// ENOTTY for requests it doesn't recognize without touching the argument.
 	switch args[1].Int() {
 	case linux.FIONCLEX:
 		t.FDTable().SetFlagsVFS2(t, fd, kernel.FDFlags{
@@ -74,8 +78,7 @@ func Ioctl(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscall
 		} else {
 			flags &^= linux.O_ASYNC
 		}
-		file.SetStatusFlags(t, t.Credentials(), flags)
-		return 0, nil, nil
+		return 0, nil, file.SetStatusFlags(t, t.Credentials(), flags)
 
 	case linux.FIOGETOWN, linux.SIOCGPGRP:
 		var who int32
@@ -29,14 +29,26 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 	prot := args[2].Int()
 	flags := args[3].Int()
 	fd := args[4].Int()
-	fixed := flags&linux.MAP_FIXED != 0
-	private := flags&linux.MAP_PRIVATE != 0
-	shared := flags&linux.MAP_SHARED != 0
+	// MAP_FIXED_NOREPLACE implies MAP_FIXED, but takes precedence over it.
+	noReplace := flags&linux.MAP_FIXED_NOREPLACE != 0
+	fixed := flags&linux.MAP_FIXED != 0 || noReplace
 	anon := flags&linux.MAP_ANONYMOUS != 0
 	map32bit := flags&linux.MAP_32BIT != 0
 
-	// Require exactly one of MAP_PRIVATE and MAP_SHARED.
-	if private == shared {
+	var private, shared, validate bool
+	switch flags & linux.MAP_TYPE {
+	case linux.MAP_PRIVATE:
+		private = true
+	case linux.MAP_SHARED:
+		shared = true
+	case linux.MAP_SHARED_VALIDATE:
+		// As in Linux, anonymous mappings don't accept MAP_SHARED_VALIDATE.
+		if anon {
+			return 0, nil, linuxerr.EINVAL
+		}
+		shared = true
+		validate = true
+	default:
 		return 0, nil, linuxerr.EINVAL
 	}
 
@@ -45,7 +57,7 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 		Offset:   args[5].Uint64(),
 		Addr:     args[0].Pointer(),
 		Fixed:    fixed,
-		Unmap:    fixed,
+		Unmap:    fixed && !noReplace,
 		Map32Bit: map32bit,
 		Private:  private,
 		Perms: hostarch.AccessType{
@@ -74,6 +86,11 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 		}
 		defer file.DecRef(t)
 
+		// MAP_SHARED_VALIDATE rejects flags that MAP_SHARED silently ignores.
+		// No file supports MAP_SYNC, so only legacy flags are accepted.
+		if validate && uint32(flags)&^linux.LEGACY_MAP_MASK != 0 {
+			return 0, nil, linuxerr.EOPNOTSUPP
+		}
 		// mmap unconditionally requires that the FD is readable.
 		if !file.IsReadable() {
 			return 0, nil, linuxerr.EACCES
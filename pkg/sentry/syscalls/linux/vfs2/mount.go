@@ -15,9 +15,12 @@
 package vfs2
 
 import (
+	"math"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -148,3 +151,142 @@ func Umount2(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysca
 
 	return 0, nil, t.Kernel().VFS().UmountAt(t, creds, &tpop.pop, &opts)
 }
+
+// copyInMntIDReq copies in the struct mnt_id_req at addr. Compare Linux's
+// fs/namespace.c:copy_mnt_id_req().
+func copyInMntIDReq(t *kernel.Task, addr hostarch.Addr) (linux.MntIDReq, error) {
+	var req linux.MntIDReq
+	var size primitive.Uint32
+	if _, err := size.CopyIn(t, addr); err != nil {
+		return req, err
+	}
+	if size > hostarch.PageSize {
+		return req, linuxerr.E2BIG
+	}
+	if size < linux.MNT_ID_REQ_SIZE_VER0 {
+		return req, linuxerr.EINVAL
+	}
+	// As for copy_struct_from_user(), a larger struct from a newer userspace
+	// is accepted only if the fields we don't know about are zero.
+	if known := uint32(req.SizeBytes()); uint32(size) > known {
+		extra := t.CopyScratchBuffer(int(uint32(size) - known))
+		if _, err := t.CopyInBytes(addr+hostarch.Addr(known), extra); err != nil {
+			return req, err
+		}
+		for _, b := range extra {
+			if b != 0 {
+				return req, linuxerr.E2BIG
+			}
+		}
+	}
+	if _, err := req.CopyIn(t, addr); err != nil {
+		return req, err
+	}
+	if req.Spare != 0 {
+		return req, linuxerr.EINVAL
+	}
+	return req, nil
+}
+
+// Statmount implements Linux syscall statmount(2).
+func Statmount(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	reqAddr := args[0].Pointer()
+	bufAddr := args[1].Pointer()
+	bufSize := args[2].SizeT()
+	flags := args[3].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	req, err := copyInMntIDReq(t, reqAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	root := t.FSContext().RootDirectoryVFS2()
+	defer root.DecRef(t)
+	ms, err := t.Kernel().VFS().StatMount(t, t.Credentials(), root, req.MntID, req.Param)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Each requested string is NUL-terminated and appended to the string
+	// table following struct statmount, in the order below, and its offset
+	// within the table is stored in the corresponding field. The fixed-size
+	// part and all strings (excluding the last NUL) must fit in bufSize. See
+	// Linux's fs/namespace.c:statmount_string().
+	var strs []byte
+	for _, s := range []struct {
+		bit uint64
+		val string
+		off *uint32
+	}{
+		{linux.STATMOUNT_FS_TYPE, ms.FSType, &ms.Statmount.FSType},
+		{linux.STATMOUNT_MNT_ROOT, ms.MntRoot, &ms.Statmount.MntRoot},
+		{linux.STATMOUNT_MNT_POINT, ms.MntPoint, &ms.Statmount.MntPoint},
+	} {
+		if ms.Mask&s.bit == 0 {
+			continue
+		}
+		*s.off = uint32(len(strs))
+		strs = append(strs, s.val...)
+		if uint64(linux.SizeOfStatmount+len(strs)) >= uint64(bufSize) {
+			return 0, nil, linuxerr.EOVERFLOW
+		}
+		strs = append(strs, 0)
+	}
+
+	// If the buffer is smaller than struct statmount (e.g. from an older
+	// userspace), only the fields that fit are copied out.
+	copySize := linux.SizeOfStatmount
+	if uint64(bufSize) < uint64(copySize) {
+		copySize = int(bufSize)
+	}
+	ms.Size = uint32(copySize + len(strs))
+	if len(strs) != 0 {
+		if _, err := t.CopyOutBytes(bufAddr+hostarch.Addr(linux.SizeOfStatmount), strs); err != nil {
+			return 0, nil, err
+		}
+	}
+	buf := t.CopyScratchBuffer(linux.SizeOfStatmount)
+	ms.Statmount.MarshalBytes(buf)
+	if _, err := t.CopyOutBytes(bufAddr, buf[:copySize]); err != nil {
+		return 0, nil, err
+	}
+	return 0, nil, nil
+}
+
+// Listmount implements Linux syscall listmount(2).
+func Listmount(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	reqAddr := args[0].Pointer()
+	idsAddr := args[1].Pointer()
+	nrIDs := args[2].SizeT()
+	flags := args[3].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	// Compare Linux's fs/namespace.c:listmount().
+	if uint64(nrIDs) > math.MaxUint64>>3 {
+		return 0, nil, linuxerr.EOVERFLOW
+	}
+	req, err := copyInMntIDReq(t, reqAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	root := t.FSContext().RootDirectoryVFS2()
+	defer root.DecRef(t)
+	// req.Param is the last mount ID returned by a previous call, allowing
+	// the mounts to be listed in batches.
+	ids, err := t.Kernel().VFS().ListMounts(t, t.Credentials(), root, req.MntID, req.Param, int(nrIDs))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(ids) != 0 {
+		if _, err := primitive.CopyUint64SliceOut(t, idsAddr, ids); err != nil {
+			return 0, nil, err
+		}
+	}
+	return uintptr(len(ids)), nil, nil
+}
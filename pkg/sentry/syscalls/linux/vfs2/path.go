@@ -24,11 +24,17 @@ import (
 )
 
 func copyInPath(t *kernel.Task, addr hostarch.Addr) (fspath.Path, error) {
-	pathname, err := t.CopyInString(addr, linux.PATH_MAX)
+	pathname, err := t.CopyInString(addr, t.Kernel().MaxPathLen())
 	if err != nil {
 		return fspath.Path{}, err
 	}
-	return fspath.Parse(pathname), nil
+	path := fspath.Parse(pathname)
+	for it := path.Begin; it.Ok(); it = it.Next() {
+		if len(it.String()) > linux.NAME_MAX {
+			return fspath.Path{}, linuxerr.ENAMETOOLONG
+		}
+	}
+	return path, nil
 }
 
 type taskPathOperation struct {
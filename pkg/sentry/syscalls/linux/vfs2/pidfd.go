@@ -0,0 +1,137 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs2
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pidfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	slinux "gvisor.dev/gvisor/pkg/sentry/syscalls/linux"
+)
+
+// PidfdOpen implements Linux syscall pidfd_open(2).
+func PidfdOpen(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	pid := kernel.ThreadID(args[0].Int())
+	flags := args[1].Uint()
+
+	if flags&^linux.PIDFD_NONBLOCK != 0 || pid <= 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	target := t.PIDNamespace().TaskWithID(pid)
+	if target == nil {
+		return 0, nil, linuxerr.ESRCH
+	}
+	// pidfds can only refer to thread group leaders.
+	tg := target.ThreadGroup()
+	if tg.Leader() != target {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	file, err := pidfd.New(t, t.Kernel().VFS(), tg, linux.O_RDWR|flags)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFromVFS2(0, file, kernel.FDFlags{
+		CloseOnExec: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
+
+// ProcessMadvise implements Linux syscall process_madvise(2).
+func ProcessMadvise(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fd := args[0].Int()
+	addr := args[1].Pointer()
+	iovcnt := args[2].SizeT()
+	adv := args[3].Int()
+	flags := args[4].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if iovcnt > linux.UIO_MAXIOV {
+		return 0, nil, linuxerr.EINVAL
+	}
+	ars, err := t.CopyInIovecs(addr, int(iovcnt))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	file := t.GetFileVFS2(fd)
+	if file == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	pfd, ok := file.Impl().(*pidfd.PIDFileDescription)
+	if !ok {
+		return 0, nil, linuxerr.EBADF
+	}
+	target := pfd.ThreadGroup().Leader()
+	if target == nil || target.ExitState() == kernel.TaskExitDead {
+		return 0, nil, linuxerr.ESRCH
+	}
+
+	// Compare Linux's mm/madvise.c:process_madvise_behavior_valid().
+	switch adv {
+	case linux.MADV_COLD, linux.MADV_PAGEOUT, linux.MADV_WILLNEED:
+	default:
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// Compare Linux's kernel/fork.c:mm_access(), which requires
+	// PTRACE_MODE_READ access unless the target shares our address space.
+	var tmm *mm.MemoryManager
+	target.WithMuLocked(func(target *kernel.Task) {
+		tmm = target.MemoryManager()
+	})
+	if tmm == nil || !tmm.IncUsers() {
+		return 0, nil, linuxerr.ESRCH
+	}
+	defer tmm.DecUsers(t)
+	if tmm != t.MemoryManager() && !t.CanTrace(target, false /* attach */) {
+		return 0, nil, linuxerr.EACCES
+	}
+
+	// "Require CAP_SYS_NICE for influencing process performance." -
+	// mm/madvise.c
+	if !t.HasCapabilityIn(linux.CAP_SYS_NICE, t.Kernel().RootUserNamespace()) {
+		return 0, nil, linuxerr.EPERM
+	}
+
+	// As in Linux, stop at the first range that fails, and report the number
+	// of bytes advised so far if any.
+	var total int64
+	for ; !ars.IsEmpty(); ars = ars.Tail() {
+		ar := ars.Head()
+		if ar.Length() == 0 {
+			continue
+		}
+		if err := slinux.MadviseMM(tmm, ar.Start, uint64(ar.Length()), adv); err != nil {
+			if total == 0 {
+				return 0, nil, err
+			}
+			break
+		}
+		total += int64(ar.Length())
+	}
+	return uintptr(total), nil, nil
+}
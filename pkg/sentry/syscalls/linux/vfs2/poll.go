@@ -400,22 +400,40 @@ func copyOutTimevalRemaining(t *kernel.Task, startNs ktime.Time, timeout time.Du
 type pollRestartBlock struct {
 	pfdAddr hostarch.Addr
 	nfds    uint
-	timeout time.Duration
+
+	// If forever is false, end is the CLOCK_MONOTONIC time at which the
+	// interrupted poll times out. As in Linux's restart_block, the deadline
+	// is absolute, so time spent between the interruption and the restart
+	// (e.g. running a signal handler) is charged against the timeout.
+	forever bool
+	end     ktime.Time
 }
 
 // Restart implements kernel.SyscallRestartBlock.Restart.
 func (p *pollRestartBlock) Restart(t *kernel.Task) (uintptr, error) {
-	return poll(t, p.pfdAddr, p.nfds, p.timeout)
+	timeout := time.Duration(-1)
+	if !p.forever {
+		timeout = p.end.Sub(t.Kernel().MonotonicClock().Now())
+		if timeout < 0 {
+			timeout = 0
+		}
+	}
+	return poll(t, p.pfdAddr, p.nfds, timeout)
 }
 
 func poll(t *kernel.Task, pfdAddr hostarch.Addr, nfds uint, timeout time.Duration) (uintptr, error) {
-	remainingTimeout, n, err := doPoll(t, pfdAddr, nfds, timeout)
+	var startNs ktime.Time
+	if timeout > 0 {
+		startNs = t.Kernel().MonotonicClock().Now()
+	}
+	_, n, err := doPoll(t, pfdAddr, nfds, timeout)
 	// On an interrupt poll(2) is restarted with the remaining timeout.
 	if linuxerr.Equals(linuxerr.EINTR, err) {
 		t.SetSyscallRestartBlock(&pollRestartBlock{
 			pfdAddr: pfdAddr,
 			nfds:    nfds,
-			timeout: remainingTimeout,
+			forever: timeout < 0,
+			end:     startNs.Add(timeout),
 		})
 		return 0, syserror.ERESTART_RESTARTBLOCK
 	}
@@ -569,6 +587,10 @@ func copyTimespecInToDuration(t *kernel.Task, timespecAddr hostarch.Addr) (time.
 	return timeout, nil
 }
 
+// setTempSignalSet installs the signal mask at maskAddr for the duration of
+// the current syscall. The original mask is stashed in the task's saved
+// signal mask, which is task state and thus saved along with the wait if a
+// checkpoint interrupts it.
 func setTempSignalSet(t *kernel.Task, maskAddr hostarch.Addr, maskSize uint) error {
 	if maskAddr == 0 {
 		return nil
@@ -33,7 +33,7 @@ const chmodMask = 0777 | linux.S_ISUID | linux.S_ISGID | linux.S_ISVTX
 func Chmod(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	pathAddr := args[0].Pointer()
 	mode := args[1].ModeT()
-	return 0, nil, fchmodat(t, linux.AT_FDCWD, pathAddr, mode)
+	return 0, nil, fchmodat(t, linux.AT_FDCWD, pathAddr, mode, 0 /* flags */)
 }
 
 // Fchmodat implements Linux syscall fchmodat(2).
@@ -41,16 +41,33 @@ func Fchmodat(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysc
 	dirfd := args[0].Int()
 	pathAddr := args[1].Pointer()
 	mode := args[2].ModeT()
-	return 0, nil, fchmodat(t, dirfd, pathAddr, mode)
+	return 0, nil, fchmodat(t, dirfd, pathAddr, mode, 0 /* flags */)
 }
 
-func fchmodat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, mode uint) error {
+// Fchmodat2 implements Linux syscall fchmodat2(2).
+func Fchmodat2(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	pathAddr := args[1].Pointer()
+	mode := args[2].ModeT()
+	flags := args[3].Int()
+	return 0, nil, fchmodat(t, dirfd, pathAddr, mode, flags)
+}
+
+func fchmodat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, mode uint, flags int32) error {
+	if flags&^(linux.AT_EMPTY_PATH|linux.AT_SYMLINK_NOFOLLOW) != 0 {
+		return linuxerr.EINVAL
+	}
+
 	path, err := copyInPath(t, pathAddr)
 	if err != nil {
 		return err
 	}
+	allowEmpty := shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0)
 
-	return setstatat(t, dirfd, path, disallowEmptyPath, followFinalSymlink, &vfs.SetStatOptions{
+	// Symlink modes can't be changed. Filesystems reject this in SetStat (see
+	// vfs.CheckSetStat), where the file type is checked on the file that is
+	// actually changed.
+	return setstatat(t, dirfd, path, allowEmpty, shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0), &vfs.SetStatOptions{
 		Stat: linux.Statx{
 			Mask: linux.STATX_MODE,
 			Mode: uint16(mode & chmodMask),
@@ -118,7 +135,22 @@ func fchownat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, owner, group,
 		return err
 	}
 
-	return setstatat(t, dirfd, path, shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0), &opts)
+	allowEmpty := shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0)
+	follow := shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_NOFOLLOW == 0)
+	tpop, err := getTaskPathOperation(t, dirfd, path, allowEmpty, follow)
+	if err != nil {
+		return err
+	}
+	stat, err := t.Kernel().VFS().StatAt(t, t.Credentials(), &tpop.pop, &vfs.StatOptions{
+		Mask: linux.STATX_TYPE | linux.STATX_MODE,
+	})
+	tpop.Release(t)
+	if err != nil {
+		return err
+	}
+	populateSetStatOptionsForChownPrivilege(&stat, &opts)
+
+	return setstatat(t, dirfd, path, allowEmpty, follow, &opts)
 }
 
 func populateSetStatOptionsForChown(t *kernel.Task, owner, group int32, opts *vfs.SetStatOptions) error {
@@ -142,6 +174,28 @@ func populateSetStatOptionsForChown(t *kernel.Task, owner, group int32, opts *vf
 	return nil
 }
 
+// populateSetStatOptionsForChownPrivilege adds the mode change that chown(2)
+// makes to a file with the given stat to opts.
+//
+// As in Linux's fs/open.c:chown_common(), the setuid bit of non-directories
+// is cleared by chown, as is the setgid bit if group execution is permitted,
+// even if neither the owner nor the group changes. Since this is a mode
+// change, it requires ownership of the file. Note that this is racy, since
+// the file's mode may change after stat is obtained.
+func populateSetStatOptionsForChownPrivilege(stat *linux.Statx, opts *vfs.SetStatOptions) {
+	if stat.Mode&linux.S_IFMT == linux.S_IFDIR {
+		return
+	}
+	mode := stat.Mode &^ linux.S_ISUID
+	if mode&(linux.S_ISGID|linux.ModeGroupExec) == linux.S_ISGID|linux.ModeGroupExec {
+		mode &^= linux.S_ISGID
+	}
+	if mode != stat.Mode {
+		opts.Stat.Mask |= linux.STATX_MODE
+		opts.Stat.Mode = mode & chmodMask
+	}
+}
+
 // Fchown implements Linux syscall fchown(2).
 func Fchown(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	fd := args[0].Int()
@@ -158,6 +212,11 @@ func Fchown(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscal
 	if err := populateSetStatOptionsForChown(t, owner, group, &opts); err != nil {
 		return 0, nil, err
 	}
+	stat, err := file.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_MODE})
+	if err != nil {
+		return 0, nil, err
+	}
+	populateSetStatOptionsForChownPrivilege(&stat, &opts)
 	return 0, nil, file.SetStat(t, opts)
 }
 
@@ -226,14 +285,16 @@ func Fallocate(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 	}
 	defer file.DecRef(t)
 
-	if !file.IsWritable() {
-		return 0, nil, linuxerr.EBADF
+	if offset < 0 || length <= 0 {
+		return 0, nil, linuxerr.EINVAL
 	}
-	if mode != 0 {
+	switch mode {
+	case 0, linux.FALLOC_FL_KEEP_SIZE, linux.FALLOC_FL_UNSHARE_RANGE, linux.FALLOC_FL_UNSHARE_RANGE | linux.FALLOC_FL_KEEP_SIZE:
+	default:
 		return 0, nil, linuxerr.ENOTSUP
 	}
-	if offset < 0 || length <= 0 {
-		return 0, nil, linuxerr.EINVAL
+	if !file.IsWritable() {
+		return 0, nil, linuxerr.EBADF
 	}
 
 	size := offset + length
@@ -442,10 +503,12 @@ func setstatat(t *kernel.Task, dirfd int32, path fspath.Path, shouldAllowEmptyPa
 			if dirfile == nil {
 				return linuxerr.EBADF
 			}
-			if !path.HasComponents() {
+			if !path.HasComponents() && dirfile.StatusFlags()&linux.O_PATH == 0 {
 				// Use FileDescription.SetStat() instead of
 				// VirtualFilesystem.SetStatAt(), since the former may be able
-				// to use opened file state to expedite the SetStat.
+				// to use opened file state to expedite the SetStat. O_PATH
+				// file descriptions don't support SetStat, but the file they
+				// refer to can still be changed by path.
 				err := dirfile.SetStat(t, *opts)
 				dirfile.DecRef(t)
 				return err
@@ -19,6 +19,7 @@ import (
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
@@ -453,6 +454,174 @@ func Sendfile(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sysc
 	return uintptr(total), nil, slinux.HandleIOErrorVFS2(t, total != 0, err, syserror.ERESTARTSYS, "sendfile", inFile)
 }
 
+// copyFileRangeBufferSize is the maximum number of bytes that
+// copy_file_range(2) buffers in the sentry at once.
+const copyFileRangeBufferSize = 1 << 20 // 1 MiB
+
+// CopyFileRange implements Linux syscall copy_file_range(2).
+//
+// As in Linux 5.3 and later, the two files may be on different filesystems.
+// Data is always copied through the sentry.
+func CopyFileRange(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	inFD := args[0].Int()
+	inOffsetAddr := args[1].Pointer()
+	outFD := args[2].Int()
+	outOffsetAddr := args[3].Pointer()
+	count := int64(args[4].SizeT())
+	flags := args[5].Uint()
+
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	inFile := t.GetFileVFS2(inFD)
+	if inFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer inFile.DecRef(t)
+
+	outFile := t.GetFileVFS2(outFD)
+	if outFile == nil {
+		return 0, nil, linuxerr.EBADF
+	}
+	defer outFile.DecRef(t)
+
+	// Both files must be regular files. The same checks appear in Linux
+	// (fs/read_write.c:generic_file_rw_checks).
+	inStat, err := inFile.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_INO | linux.STATX_SIZE})
+	if err != nil {
+		return 0, nil, err
+	}
+	outStat, err := outFile.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_INO})
+	if err != nil {
+		return 0, nil, err
+	}
+	inType := inStat.Mode & linux.S_IFMT
+	outType := outStat.Mode & linux.S_IFMT
+	if inType == linux.S_IFDIR || outType == linux.S_IFDIR {
+		return 0, nil, linuxerr.EISDIR
+	}
+	if inType != linux.S_IFREG || outType != linux.S_IFREG {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if !inFile.IsReadable() || !outFile.IsWritable() {
+		return 0, nil, linuxerr.EBADF
+	}
+	if outFile.StatusFlags()&linux.O_APPEND != 0 {
+		return 0, nil, linuxerr.EBADF
+	}
+
+	// Get the offsets, either from the provided pointers or from the files.
+	inOffset, err := copyFileRangeOffset(t, inFile, inOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	outOffset, err := copyFileRangeOffset(t, outFile, outOffsetAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if inOffset+count < 0 || outOffset+count < 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// Don't copy past the end of the input file.
+	if inSize := int64(inStat.Size); inOffset >= inSize {
+		count = 0
+	} else if count > inSize-inOffset {
+		count = inSize - inOffset
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+	if count > int64(kernel.MAX_RW_COUNT) {
+		count = int64(kernel.MAX_RW_COUNT)
+	}
+
+	// Don't allow overlapping copies within the same file.
+	if inStat.DevMajor == outStat.DevMajor && inStat.DevMinor == outStat.DevMinor && inStat.Ino == outStat.Ino &&
+		outOffset+count > inOffset && outOffset < inOffset+count {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// Read inFile to buffer, then write the contents to outFile.
+	bufSize := count
+	if bufSize > copyFileRangeBufferSize {
+		bufSize = copyFileRangeBufferSize
+	}
+	buf := make([]byte, bufSize)
+	var total int64
+	for total < count {
+		n := count - total
+		if n > bufSize {
+			n = bufSize
+		}
+		var readN, writeN int64
+		readN, err = inFile.PRead(t, usermem.BytesIOSequence(buf[:n]), inOffset+total, vfs.ReadOptions{})
+		if readN > 0 {
+			var writeErr error
+			writeN, writeErr = outFile.PWrite(t, usermem.BytesIOSequence(buf[:readN]), outOffset+total, vfs.WriteOptions{})
+			if writeErr != nil {
+				err = writeErr
+			}
+		}
+		total += writeN
+		if err != nil || readN == 0 || writeN < readN {
+			break
+		}
+		if t.Interrupted() {
+			err = syserror.ErrInterrupted
+			break
+		}
+	}
+
+	// Update the offsets.
+	if err := updateCopyFileRangeOffset(t, inFile, inOffsetAddr, inOffset+total); err != nil {
+		return 0, nil, err
+	}
+	if err := updateCopyFileRangeOffset(t, outFile, outOffsetAddr, outOffset+total); err != nil {
+		return 0, nil, err
+	}
+
+	if total != 0 {
+		if err != nil && err != io.EOF {
+			// If a partial copy is completed, the error is dropped. Log it here.
+			log.Debugf("copy_file_range completed a partial copy with error: %v", err)
+			err = nil
+		}
+	}
+
+	return uintptr(total), nil, slinux.HandleIOErrorVFS2(t, total != 0, err, syserror.ERESTARTSYS, "copy_file_range", outFile)
+}
+
+// copyFileRangeOffset returns the offset at which copy_file_range(2) should
+// start copying for fd, which is read from offsetAddr if it is not nil, or
+// is fd's file offset otherwise.
+func copyFileRangeOffset(t *kernel.Task, fd *vfs.FileDescription, offsetAddr hostarch.Addr) (int64, error) {
+	if offsetAddr == 0 {
+		return fd.Seek(t, 0, linux.SEEK_CUR)
+	}
+	var offsetP primitive.Int64
+	if _, err := offsetP.CopyIn(t, offsetAddr); err != nil {
+		return 0, err
+	}
+	if offsetP < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	return int64(offsetP), nil
+}
+
+// updateCopyFileRangeOffset stores the offset following a copy_file_range(2)
+// to offsetAddr if it is not nil, or to fd's file offset otherwise.
+func updateCopyFileRangeOffset(t *kernel.Task, fd *vfs.FileDescription, offsetAddr hostarch.Addr, offset int64) error {
+	if offsetAddr == 0 {
+		_, err := fd.Seek(t, offset, linux.SEEK_SET)
+		return err
+	}
+	offsetP := primitive.Int64(offset)
+	_, err := offsetP.CopyOut(t, offsetAddr)
+	return err
+}
+
 // dualWaiter is used to wait on one or both vfs.FileDescriptions. It is not
 // thread-safe, and does not take a reference on the vfs.FileDescriptions.
 //
@@ -18,12 +18,14 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/syserror"
 )
 
 // Sync implements Linux syscall sync(2).
 func Sync(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	fsmetric.Syncs.Increment()
 	return 0, nil, t.Kernel().VFS().SyncAllFilesystems(t)
 }
 
@@ -41,6 +43,7 @@ func Syncfs(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Syscal
 		return 0, nil, linuxerr.EBADF
 	}
 
+	fsmetric.Syncs.Increment()
 	return 0, nil, file.SyncFS(t)
 }
 
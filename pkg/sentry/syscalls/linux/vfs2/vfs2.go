@@ -108,7 +108,7 @@ func Override() {
 	s.Table[209] = syscalls.PartiallySupported("io_submit", IoSubmit, "Generally supported with exceptions. User ring optimizations are not implemented.", []string{"gvisor.dev/issue/204"})
 	s.Table[213] = syscalls.Supported("epoll_create", EpollCreate)
 	s.Table[217] = syscalls.Supported("getdents64", Getdents64)
-	s.Table[221] = syscalls.PartiallySupported("fadvise64", Fadvise64, "The syscall is 'supported', but ignores all provided advice.", nil)
+	s.Table[221] = syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil)
 	s.Table[232] = syscalls.Supported("epoll_wait", EpollWait)
 	s.Table[233] = syscalls.Supported("epoll_ctl", EpollCtl)
 	s.Table[235] = syscalls.Supported("utimes", Utimes)
@@ -151,15 +151,24 @@ func Override() {
 	s.Table[295] = syscalls.Supported("preadv", Preadv)
 	s.Table[296] = syscalls.Supported("pwritev", Pwritev)
 	s.Table[299] = syscalls.Supported("recvmmsg", RecvMMsg)
+	s.Table[300] = syscalls.PartiallySupported("fanotify_init", FanotifyInit, "Reporting by file handle and FAN_EVENT_ON_CHILD are not supported.", nil)
+	s.Table[301] = syscalls.PartiallySupported("fanotify_mark", FanotifyMark, "Reporting by file handle and FAN_EVENT_ON_CHILD are not supported.", nil)
 	s.Table[306] = syscalls.Supported("syncfs", Syncfs)
 	s.Table[307] = syscalls.Supported("sendmmsg", SendMMsg)
 	s.Table[316] = syscalls.Supported("renameat2", Renameat2)
 	s.Table[319] = syscalls.Supported("memfd_create", MemfdCreate)
 	s.Table[322] = syscalls.Supported("execveat", Execveat)
+	s.Table[326] = syscalls.Supported("copy_file_range", CopyFileRange)
 	s.Table[327] = syscalls.Supported("preadv2", Preadv2)
 	s.Table[328] = syscalls.Supported("pwritev2", Pwritev2)
 	s.Table[332] = syscalls.Supported("statx", Statx)
+	s.Table[434] = syscalls.PartiallySupported("pidfd_open", PidfdOpen, "Blocking poll(2) for process exit is not supported.", nil)
+	s.Table[439] = syscalls.Supported("faccessat2", Faccessat2)
+	s.Table[440] = syscalls.Supported("process_madvise", ProcessMadvise)
 	s.Table[441] = syscalls.Supported("epoll_pwait2", EpollPwait2)
+	s.Table[452] = syscalls.Supported("fchmodat2", Fchmodat2)
+	s.Table[457] = syscalls.PartiallySupported("statmount", Statmount, "Mount propagation is not supported, so all mounts are reported as private.", nil)
+	s.Table[458] = syscalls.Supported("listmount", Listmount)
 	s.Init()
 
 	// Override ARM64.
@@ -262,15 +271,24 @@ func Override() {
 	s.Table[223] = syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil)
 	s.Table[242] = syscalls.Supported("accept4", Accept4)
 	s.Table[243] = syscalls.Supported("recvmmsg", RecvMMsg)
+	s.Table[262] = syscalls.PartiallySupported("fanotify_init", FanotifyInit, "Reporting by file handle and FAN_EVENT_ON_CHILD are not supported.", nil)
+	s.Table[263] = syscalls.PartiallySupported("fanotify_mark", FanotifyMark, "Reporting by file handle and FAN_EVENT_ON_CHILD are not supported.", nil)
 	s.Table[267] = syscalls.Supported("syncfs", Syncfs)
 	s.Table[269] = syscalls.Supported("sendmmsg", SendMMsg)
 	s.Table[276] = syscalls.Supported("renameat2", Renameat2)
 	s.Table[279] = syscalls.Supported("memfd_create", MemfdCreate)
 	s.Table[281] = syscalls.Supported("execveat", Execveat)
+	s.Table[285] = syscalls.Supported("copy_file_range", CopyFileRange)
 	s.Table[286] = syscalls.Supported("preadv2", Preadv2)
 	s.Table[287] = syscalls.Supported("pwritev2", Pwritev2)
 	s.Table[291] = syscalls.Supported("statx", Statx)
+	s.Table[434] = syscalls.PartiallySupported("pidfd_open", PidfdOpen, "Blocking poll(2) for process exit is not supported.", nil)
+	s.Table[439] = syscalls.Supported("faccessat2", Faccessat2)
+	s.Table[440] = syscalls.Supported("process_madvise", ProcessMadvise)
 	s.Table[441] = syscalls.Supported("epoll_pwait2", EpollPwait2)
+	s.Table[452] = syscalls.Supported("fchmodat2", Fchmodat2)
+	s.Table[457] = syscalls.PartiallySupported("statmount", Statmount, "Mount propagation is not supported, so all mounts are reported as private.", nil)
+	s.Table[458] = syscalls.Supported("listmount", Listmount)
 
 	s.Init()
 }
@@ -78,6 +78,15 @@ func (i *IO) AccountWriteIO(bytes int64) {
 	}
 }
 
+// AccountWriteCancelledIO does the accounting for bytes that were written
+// into the file system's cache but discarded before being written back, e.g.
+// due to truncation.
+func (i *IO) AccountWriteCancelledIO(bytes int64) {
+	if bytes > 0 {
+		atomic.AddUint64(&i.BytesWriteCancelled, uint64(bytes))
+	}
+}
+
 // Accumulate adds up io usages.
 func (i *IO) Accumulate(io *IO) {
 	atomic.AddUint64(&i.CharsRead, atomic.LoadUint64(&io.CharsRead))
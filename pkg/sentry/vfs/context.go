@@ -27,6 +27,9 @@ const (
 
 	// CtxRoot is a Context.Value key for a VFS root.
 	CtxRoot
+
+	// CtxReleaseBatch is a Context.Value key for a *ReleaseBatch.
+	CtxReleaseBatch
 )
 
 // MountNamespaceFromContext returns the MountNamespace used by ctx. If ctx is
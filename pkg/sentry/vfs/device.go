@@ -95,6 +95,9 @@ func (vfs *VirtualFilesystem) RegisterDevice(kind DeviceKind, major, minor uint3
 // OpenDeviceSpecialFile returns a FileDescription representing the given
 // device.
 func (vfs *VirtualFilesystem) OpenDeviceSpecialFile(ctx context.Context, mnt *Mount, d *Dentry, kind DeviceKind, major, minor uint32, opts *OpenOptions) (*FileDescription, error) {
+	if err := CheckOpenDevice(mnt); err != nil {
+		return nil, err
+	}
 	tup := devTuple{kind, major, minor}
 	vfs.devicesMu.RLock()
 	defer vfs.devicesMu.RUnlock()
@@ -105,6 +108,16 @@ func (vfs *VirtualFilesystem) OpenDeviceSpecialFile(ctx context.Context, mnt *Mo
 	return rd.dev.Open(ctx, mnt, d, *opts)
 }
 
+// CheckOpenDevice returns EACCES if device special files on mnt may not be
+// opened because it is mounted with MS_NODEV, as per Linux's
+// fs/namei.c:may_open_dev().
+func CheckOpenDevice(mnt *Mount) error {
+	if mnt.Flags.NoDev {
+		return linuxerr.EACCES
+	}
+	return nil
+}
+
 // GetAnonBlockDevMinor allocates and returns an unused minor device number for
 // an "anonymous" block device with major number UNNAMED_MAJOR.
 func (vfs *VirtualFilesystem) GetAnonBlockDevMinor() (uint32, error) {
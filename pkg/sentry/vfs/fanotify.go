@@ -0,0 +1,109 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+)
+
+// A FanotifyGroup is a fanotify group created by fanotify_init(2).
+//
+// FanotifyGroups are implemented outside of vfs, since reporting an event
+// installs a file descriptor in the listener's file descriptor table. VFS only
+// tells every registered FanotifyGroup about file operations; each group
+// decides whether its marks match the file.
+type FanotifyGroup interface {
+	// FanotifyID returns an identifier that is unique among all
+	// FanotifyGroups.
+	FanotifyID() uint64
+
+	// CheckPermission reports the permission event ev (one of
+	// linux.FAN_PERM_EVENTS) on fd to the group. If the group has a mark
+	// matching ev and fd, CheckPermission blocks until the group's listener
+	// responds. It returns EPERM if the listener denies the operation.
+	CheckPermission(ctx context.Context, fd *FileDescription, ev uint64) error
+
+	// Notify reports the notification events in mask on fd to the group.
+	Notify(ctx context.Context, fd *FileDescription, mask uint64)
+}
+
+// RegisterFanotifyGroup causes g to be told about file operations until
+// UnregisterFanotifyGroup(g) is called.
+func (vfs *VirtualFilesystem) RegisterFanotifyGroup(g FanotifyGroup) {
+	vfs.fanotifyMu.Lock()
+	defer vfs.fanotifyMu.Unlock()
+	if vfs.fanotifyGroups == nil {
+		vfs.fanotifyGroups = make(map[uint64]FanotifyGroup)
+	}
+	vfs.fanotifyGroups[g.FanotifyID()] = g
+	atomic.StoreInt32(&vfs.numFanotifyGroups, int32(len(vfs.fanotifyGroups)))
+}
+
+// UnregisterFanotifyGroup undoes the effect of RegisterFanotifyGroup(g).
+func (vfs *VirtualFilesystem) UnregisterFanotifyGroup(g FanotifyGroup) {
+	vfs.fanotifyMu.Lock()
+	defer vfs.fanotifyMu.Unlock()
+	delete(vfs.fanotifyGroups, g.FanotifyID())
+	atomic.StoreInt32(&vfs.numFanotifyGroups, int32(len(vfs.fanotifyGroups)))
+}
+
+// fanotifyGroupsForFD returns the FanotifyGroups that must be told about
+// operations on fd. Since CheckPermission may block indefinitely, callers must
+// not hold vfs.fanotifyMu while using the groups.
+func (vfs *VirtualFilesystem) fanotifyGroupsForFD(fd *FileDescription) []FanotifyGroup {
+	if fd.noFanotify || atomic.LoadInt32(&vfs.numFanotifyGroups) == 0 {
+		return nil
+	}
+	vfs.fanotifyMu.Lock()
+	defer vfs.fanotifyMu.Unlock()
+	gs := make([]FanotifyGroup, 0, len(vfs.fanotifyGroups))
+	for _, g := range vfs.fanotifyGroups {
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+// fanotifyPermission asks all fanotify groups whether the operation described
+// by the permission event ev on fd may proceed. Compare Linux's
+// fs/notify/fsnotify.c:fsnotify_perm().
+func (fd *FileDescription) fanotifyPermission(ctx context.Context, ev uint64) error {
+	for _, g := range fd.vd.mount.vfs.fanotifyGroupsForFD(fd) {
+		if err := g.CheckPermission(ctx, fd, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fanotifyNotify reports the notification events in mask on fd to all fanotify
+// groups.
+func (fd *FileDescription) fanotifyNotify(ctx context.Context, mask uint64) {
+	for _, g := range fd.vd.mount.vfs.fanotifyGroupsForFD(fd) {
+		g.Notify(ctx, fd, mask)
+	}
+}
+
+// fanotifyOpen generates the fanotify events for opening fd. If a listener
+// denies the open, fanotifyOpen returns EPERM and the caller must release fd.
+func (fd *FileDescription) fanotifyOpen(ctx context.Context) error {
+	if err := fd.fanotifyPermission(ctx, linux.FAN_OPEN_PERM); err != nil {
+		return err
+	}
+	fd.fanotifyNotify(ctx, linux.FAN_OPEN)
+	return nil
+}
@@ -91,6 +91,13 @@ type FileDescription struct {
 
 	usedLockBSD uint32
 
+	// noFanotify is true if operations on this FileDescription do not
+	// generate fanotify events. noFanotify is immutable after
+	// VirtualFilesystem.OpenAt() returns.
+	//
+	// noFanotify is analogous to Linux's FMODE_NONOTIFY.
+	noFanotify bool
+
 	// impl is the FileDescriptionImpl associated with this Filesystem. impl is
 	// immutable. This should be the last field in FileDescription.
 	impl FileDescriptionImpl
@@ -167,6 +174,11 @@ func (fd *FileDescription) DecRef(ctx context.Context) {
 			ev = linux.IN_CLOSE_WRITE
 		}
 		fd.Dentry().InotifyWithParent(ctx, ev, 0, PathEvent)
+		fanEv := uint64(linux.FAN_CLOSE_NOWRITE)
+		if fd.IsWritable() {
+			fanEv = linux.FAN_CLOSE_WRITE
+		}
+		fd.fanotifyNotify(ctx, fanEv)
 
 		// Unregister fd from all epoll instances.
 		fd.epollMu.Lock()
@@ -607,10 +619,14 @@ func (fd *FileDescription) PRead(ctx context.Context, dst usermem.IOSequence, of
 	if !fd.readable {
 		return 0, linuxerr.EBADF
 	}
+	if err := fd.fanotifyPermission(ctx, linux.FAN_ACCESS_PERM); err != nil {
+		return 0, err
+	}
 	start := fsmetric.StartReadWait()
 	n, err := fd.impl.PRead(ctx, dst, offset, opts)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_ACCESS, 0, PathEvent)
+		fd.fanotifyNotify(ctx, linux.FAN_ACCESS)
 	}
 	fsmetric.Reads.Increment()
 	fsmetric.FinishReadWait(fsmetric.ReadWait, start)
@@ -622,10 +638,14 @@ func (fd *FileDescription) Read(ctx context.Context, dst usermem.IOSequence, opt
 	if !fd.readable {
 		return 0, linuxerr.EBADF
 	}
+	if err := fd.fanotifyPermission(ctx, linux.FAN_ACCESS_PERM); err != nil {
+		return 0, err
+	}
 	start := fsmetric.StartReadWait()
 	n, err := fd.impl.Read(ctx, dst, opts)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_ACCESS, 0, PathEvent)
+		fd.fanotifyNotify(ctx, linux.FAN_ACCESS)
 	}
 	fsmetric.Reads.Increment()
 	fsmetric.FinishReadWait(fsmetric.ReadWait, start)
@@ -645,6 +665,7 @@ func (fd *FileDescription) PWrite(ctx context.Context, src usermem.IOSequence, o
 	n, err := fd.impl.PWrite(ctx, src, offset, opts)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_MODIFY, 0, PathEvent)
+		fd.fanotifyNotify(ctx, linux.FAN_MODIFY)
 	}
 	return n, err
 }
@@ -657,6 +678,7 @@ func (fd *FileDescription) Write(ctx context.Context, src usermem.IOSequence, op
 	n, err := fd.impl.Write(ctx, src, opts)
 	if n > 0 {
 		fd.Dentry().InotifyWithParent(ctx, linux.IN_MODIFY, 0, PathEvent)
+		fd.fanotifyNotify(ctx, linux.FAN_MODIFY)
 	}
 	return n, err
 }
@@ -772,9 +794,13 @@ func (fd *FileDescription) RemoveXattr(ctx context.Context, name string) error {
 }
 
 // SyncFS instructs the filesystem containing fd to execute the semantics of
-// syncfs(2).
+// syncfs(2). As in Linux, any failure to write back data is reported as EIO.
 func (fd *FileDescription) SyncFS(ctx context.Context) error {
-	return fd.vd.mount.fs.impl.Sync(ctx)
+	if err := fd.vd.mount.fs.impl.Sync(ctx); err != nil {
+		ctx.Infof("vfs.FileDescription.SyncFS: %T.Sync failed: %v", fd.vd.mount.fs.impl, err)
+		return linuxerr.EIO
+	}
+	return nil
 }
 
 // MappedName implements memmap.MappingIdentity.MappedName.
@@ -816,8 +842,23 @@ func (fd *FileDescription) InodeID() uint64 {
 	return stat.Ino
 }
 
+// FileMsyncer may be implemented by FileDescriptionImpls that cache shared
+// mappings' data, so that msync(2) can act on only the synced range. For other
+// files, MS_SYNC syncs the whole file and MS_ASYNC and MS_INVALIDATE are
+// no-ops.
+type FileMsyncer interface {
+	// Msync implements memmap.MappingIdentity.Msync.
+	Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error
+}
+
 // Msync implements memmap.MappingIdentity.Msync.
-func (fd *FileDescription) Msync(ctx context.Context, mr memmap.MappableRange) error {
+func (fd *FileDescription) Msync(ctx context.Context, mr memmap.MappableRange, opts memmap.MSyncOpts) error {
+	if fm, ok := fd.impl.(FileMsyncer); ok {
+		return fm.Msync(ctx, mr, opts)
+	}
+	if !opts.Sync {
+		return nil
+	}
 	return fd.Sync(ctx)
 }
 
@@ -879,6 +920,24 @@ func (fd *FileDescription) ComputeLockRange(ctx context.Context, start uint64, l
 	return lock.ComputeRange(int64(start), int64(length), off)
 }
 
+// FileAdvisor may be implemented by FileDescriptionImpls that act on
+// fadvise(2) advice. Advice given to other files is accepted and ignored.
+type FileAdvisor interface {
+	// Fadvise applies advice to the byte range [start, end) of the file. end
+	// may exceed the file's size, in which case the range extends to EOF
+	// (including any future growth); start may also exceed the file's size.
+	Fadvise(ctx context.Context, start, end int64, advice int32) error
+}
+
+// Fadvise applies advice to the byte range [start, end) of the file
+// represented by fd.
+func (fd *FileDescription) Fadvise(ctx context.Context, start, end int64, advice int32) error {
+	if fa, ok := fd.impl.(FileAdvisor); ok {
+		return fa.Fadvise(ctx, start, end, advice)
+	}
+	return nil
+}
+
 // A FileAsync sends signals to its owner when w is ready for IO. This is only
 // implemented by pkg/sentry/fasync:FileAsync, but we unfortunately need this
 // interface to avoid circular dependencies.
@@ -513,6 +513,16 @@ type FilesystemImpl interface {
 	MountOptions() string
 }
 
+// MountStatsImpl is an optional interface that may be implemented by
+// FilesystemImpls that maintain statistics worth exposing to users, e.g. to
+// tune caching policies.
+type MountStatsImpl interface {
+	// MountStats returns filesystem-specific statistics, formatted as a
+	// space-separated list of key=value pairs, for inclusion in
+	// /proc/[pid]/mountstats.
+	MountStats() string
+}
+
 // PrependPathAtVFSRootError is returned by implementations of
 // FilesystemImpl.PrependPath() when they encounter the contextual VFS root.
 //
@@ -758,8 +758,10 @@ func InotifyRemoveChild(ctx context.Context, self, parent *Watches, name string)
 }
 
 // InotifyRename sends the appriopriate notifications to the watch sets of the
-// file being renamed and its old/new parents.
-func InotifyRename(ctx context.Context, renamed, oldParent, newParent *Watches, oldName, newName string, isDir bool) {
+// file being renamed, its old/new parents, and the file it replaces, if any.
+// The replaced file's IN_DELETE_SELF event is sent separately, when its last
+// link and reference are dropped.
+func InotifyRename(ctx context.Context, renamed, replaced, oldParent, newParent *Watches, oldName, newName string, isDir bool) {
 	var dirEv uint32
 	if isDir {
 		dirEv = linux.IN_ISDIR
@@ -771,6 +773,10 @@ func InotifyRename(ctx context.Context, renamed, oldParent, newParent *Watches,
 	if newParent != nil {
 		newParent.Notify(ctx, newName, dirEv|linux.IN_MOVED_TO, cookie, InodeEvent, false /* unlinked */)
 	}
+	// As with unlink, the replaced file's link count changes.
+	if replaced != nil {
+		replaced.Notify(ctx, "", linux.IN_ATTRIB, 0, InodeEvent, true /* unlinked */)
+	}
 	// Somewhat surprisingly, self move events do not have a cookie.
 	if renamed != nil {
 		renamed.Notify(ctx, "", linux.IN_MOVE_SELF, 0, InodeEvent, false /* unlinked */)
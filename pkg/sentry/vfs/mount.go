@@ -150,6 +150,9 @@ type MountNamespace struct {
 	// VFS.PrepareDeleteDentry() and VFS.PrepareRemoveDentry() operate
 	// correctly on unreferenced MountNamespaces.
 	mountpoints map[*Dentry]uint32
+
+	// inode is the namespace's inode number. inode is immutable.
+	inode uint64
 }
 
 // NewMountNamespace returns a new mount namespace with a root filesystem
@@ -168,12 +171,71 @@ func (vfs *VirtualFilesystem) NewMountNamespace(ctx context.Context, creds *auth
 	mntns := &MountNamespace{
 		Owner:       creds.UserNamespace,
 		mountpoints: make(map[*Dentry]uint32),
+		inode:       auth.NewNamespaceInode(),
 	}
 	mntns.InitRefs()
 	mntns.root = newMount(vfs, fs, root, mntns, opts)
 	return mntns, nil
 }
 
+// CloneMountNamespace returns a new mount namespace, owned by userns, that
+// contains a copy of the mount tree of mntns. A reference is taken on the
+// returned MountNamespace.
+//
+// CloneMountNamespace is analogous to Linux's fs/namespace.c:copy_mnt_ns().
+func (vfs *VirtualFilesystem) CloneMountNamespace(ctx context.Context, userns *auth.UserNamespace, mntns *MountNamespace) *MountNamespace {
+	newns := &MountNamespace{
+		Owner:       userns,
+		mountpoints: make(map[*Dentry]uint32),
+		inode:       auth.NewNamespaceInode(),
+	}
+	newns.InitRefs()
+	vfs.mountMu.Lock()
+	vfs.mounts.seq.BeginWrite()
+	var mountsToDecRef []*Mount
+	newns.root, mountsToDecRef = vfs.cloneMountTreeLocked(mntns.root, newns, mountsToDecRef)
+	vfs.mounts.seq.EndWrite()
+	vfs.mountMu.Unlock()
+	for _, mnt := range mountsToDecRef {
+		mnt.DecRef(ctx)
+	}
+	return newns
+}
+
+// cloneMountTreeLocked returns a copy of mnt, connected to copies of all of
+// mnt's mounted descendants, in mntns. References on the copies of mnt's
+// descendants that must be dropped once vfs.mountMu is unlocked are appended
+// to mountsToDecRef.
+//
+// cloneMountTreeLocked is analogous to Linux's fs/namespace.c:copy_tree().
+//
+// Preconditions:
+// * vfs.mountMu must be locked.
+// * vfs.mounts.seq must be in a writer critical section.
+func (vfs *VirtualFilesystem) cloneMountTreeLocked(mnt *Mount, mntns *MountNamespace, mountsToDecRef []*Mount) (*Mount, []*Mount) {
+	mnt.fs.IncRef()
+	mnt.root.IncRef()
+	clone := newMount(vfs, mnt.fs, mnt.root, mntns, &MountOptions{
+		Flags:    mnt.Flags,
+		ReadOnly: mnt.ReadOnly(),
+	})
+	for child := range mnt.children {
+		if child.umounted {
+			continue
+		}
+		var childClone *Mount
+		childClone, mountsToDecRef = vfs.cloneMountTreeLocked(child, mntns, mountsToDecRef)
+		point := child.getKey().dentry
+		clone.IncRef()
+		point.IncRef()
+		point.mu.Lock()
+		vfs.connectLocked(childClone, VirtualDentry{mount: clone, dentry: point}, mntns)
+		point.mu.Unlock()
+		mountsToDecRef = append(mountsToDecRef, childClone)
+	}
+	return clone, mountsToDecRef
+}
+
 // NewDisconnectedMount returns a Mount representing fs with the given root
 // (which may be nil). The new Mount is not associated with any MountNamespace
 // and is not connected to any other Mounts. References are taken on fs and
@@ -549,6 +611,16 @@ func (mnt *Mount) LogRefs() bool {
 	return false
 }
 
+// Inode returns the inode number of mntns.
+func (mntns *MountNamespace) Inode() uint64 {
+	return mntns.inode
+}
+
+// afterLoad is invoked by stateify.
+func (mntns *MountNamespace) afterLoad() {
+	auth.ObserveNamespaceInode(mntns.inode)
+}
+
 // DecRef decrements mntns' reference count.
 func (mntns *MountNamespace) DecRef(ctx context.Context) {
 	vfs := mntns.root.fs.VirtualFilesystem()
@@ -820,12 +892,7 @@ func (vfs *VirtualFilesystem) GenerateProcMounts(ctx context.Context, taskRootDi
 		if mnt.ReadOnly() {
 			opts = "ro"
 		}
-		if mnt.Flags.NoATime {
-			opts = ",noatime"
-		}
-		if mnt.Flags.NoExec {
-			opts += ",noexec"
-		}
+		opts += mnt.Flags.String()
 		if mopts := mnt.fs.Impl().MountOptions(); mopts != "" {
 			opts += "," + mopts
 		}
@@ -929,12 +996,7 @@ func (vfs *VirtualFilesystem) GenerateProcMountInfo(ctx context.Context, taskRoo
 		if mnt.ReadOnly() {
 			opts = "ro"
 		}
-		if mnt.Flags.NoATime {
-			opts = ",noatime"
-		}
-		if mnt.Flags.NoExec {
-			opts += ",noexec"
-		}
+		opts += mnt.Flags.String()
 		fmt.Fprintf(buf, "%s ", opts)
 
 		// (7) Optional fields: zero or more fields of the form "tag[:value]".
@@ -952,6 +1014,59 @@ func (vfs *VirtualFilesystem) GenerateProcMountInfo(ctx context.Context, taskRoo
 	}
 }
 
+// GenerateProcMountStats emits the contents of /proc/[pid]/mountstats for vfs
+// to buf.
+//
+// Preconditions: taskRootDir.Ok().
+func (vfs *VirtualFilesystem) GenerateProcMountStats(ctx context.Context, taskRootDir VirtualDentry, buf *bytes.Buffer) {
+	rootMnt := taskRootDir.mount
+
+	vfs.mountMu.Lock()
+	mounts := rootMnt.submountsLocked()
+	// Take a reference on mounts since we need to drop vfs.mountMu before
+	// calling vfs.PathnameReachable() (=> FilesystemImpl.PrependPath()).
+	for _, mnt := range mounts {
+		mnt.IncRef()
+	}
+	vfs.mountMu.Unlock()
+	defer func() {
+		for _, mnt := range mounts {
+			mnt.DecRef(ctx)
+		}
+	}()
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].ID < mounts[j].ID })
+
+	for _, mnt := range mounts {
+		// Get the path to this mount relative to task root.
+		mntRootVD := VirtualDentry{
+			mount:  mnt,
+			dentry: mnt.root,
+		}
+		path, err := vfs.PathnameReachable(ctx, taskRootDir, mntRootVD)
+		if err != nil {
+			// For some reason we didn't get a path. Log a warning
+			// and run with empty path.
+			ctx.Warningf("VFS.GenerateProcMountStats: error getting pathname for mount root %+v: %v", mnt.root, err)
+			path = ""
+		}
+		if path == "" {
+			// Either an error occurred, or path is not reachable
+			// from root.
+			break
+		}
+
+		// Format (see Linux fs/proc_namespace.c:show_vfsstat):
+		// device <source> mounted on <mount point> with fstype <type>[ <stats>]
+		fmt.Fprintf(buf, "device none mounted on %s with fstype %s", manglePath(path), mnt.fs.FilesystemType().Name())
+		if impl, ok := mnt.fs.Impl().(MountStatsImpl); ok {
+			if stats := impl.MountStats(); stats != "" {
+				fmt.Fprintf(buf, " %s", stats)
+			}
+		}
+		buf.WriteByte('\n')
+	}
+}
+
 // manglePath replaces ' ', '\t', '\n', and '\\' with their octal equivalents.
 // See Linux fs/seq_file.c:mangle_path.
 func manglePath(p string) string {
@@ -992,3 +1107,216 @@ func superBlockOpts(mountPath string, mnt *Mount) string {
 
 	return opts
 }
+
+// MountStat contains information about a mount, as returned by
+// statmount(2).
+type MountStat struct {
+	// Statmount contains the fixed-size fields of struct statmount.
+	// Statmount.Mask indicates which fields were filled. String field offsets
+	// and Statmount.Size are not set.
+	linux.Statmount
+
+	// FSType, MntRoot and MntPoint are the string fields of struct
+	// statmount. Each is valid only if the corresponding bit is set in
+	// Statmount.Mask.
+	FSType   string
+	MntRoot  string
+	MntPoint string
+}
+
+// findMountLocked returns the Mount with the given ID in mntns, or nil if no
+// such Mount exists.
+//
+// Preconditions: vfs.mountMu must be locked.
+func (vfs *VirtualFilesystem) findMountLocked(mntns *MountNamespace, id uint64) *Mount {
+	for _, mnt := range mntns.root.submountsLocked() {
+		if mnt.ID == id {
+			return mnt
+		}
+	}
+	return nil
+}
+
+// mountPathForStat returns the path to the root of mnt relative to root, or
+// "" if mnt is not reachable from root. If mnt is not reachable and creds
+// does not have CAP_SYS_ADMIN in the root user namespace, mountPathForStat
+// returns EPERM, as for Linux's fs/namespace.c:do_statmount() and
+// do_listmount().
+func (vfs *VirtualFilesystem) mountPathForStat(ctx context.Context, creds *auth.Credentials, root VirtualDentry, mnt *Mount) (string, error) {
+	path, err := vfs.PathnameReachable(ctx, root, VirtualDentry{mount: mnt, dentry: mnt.root})
+	if err != nil {
+		return "", err
+	}
+	if path == "" && !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
+		return "", linuxerr.EPERM
+	}
+	return path, nil
+}
+
+// StatMount returns the fields selected by mask (a bitmask of STATMOUNT_*
+// values) for the Mount with the given ID in root's mount namespace, as for
+// statmount(2).
+//
+// Preconditions: root.Ok().
+func (vfs *VirtualFilesystem) StatMount(ctx context.Context, creds *auth.Credentials, root VirtualDentry, id, mask uint64) (*MountStat, error) {
+	vfs.mountMu.Lock()
+	mnt := vfs.findMountLocked(root.mount.ns, id)
+	if mnt == nil {
+		vfs.mountMu.Unlock()
+		return nil, linuxerr.ENOENT
+	}
+	// Take a reference on mnt since we need to drop vfs.mountMu before
+	// calling vfs.PathnameReachable() and vfs.StatAt().
+	mnt.IncRef()
+	vfs.mountMu.Unlock()
+	defer mnt.DecRef(ctx)
+
+	path, err := vfs.mountPathForStat(ctx, creds, root, mnt)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &MountStat{}
+	mntRootVD := VirtualDentry{
+		mount:  mnt,
+		dentry: mnt.root,
+	}
+	if mask&linux.STATMOUNT_SB_BASIC != 0 {
+		pop := &PathOperation{
+			Root:  mntRootVD,
+			Start: mntRootVD,
+		}
+		// As in GenerateProcMountInfo, we don't have a superblock, so we
+		// use the root inode device number.
+		statx, err := vfs.StatAt(ctx, creds, pop, &StatOptions{})
+		if err != nil {
+			return nil, err
+		}
+		statfs, err := vfs.StatFSAt(ctx, creds, pop)
+		if err != nil {
+			return nil, err
+		}
+		ms.SbDevMajor = statx.DevMajor
+		ms.SbDevMinor = statx.DevMinor
+		ms.SbMagic = statfs.Type
+		// Superblock options are reported with the mount's read-only state,
+		// consistent with superBlockOpts().
+		if mnt.ReadOnly() {
+			ms.SbFlags |= linux.SB_RDONLY
+		}
+		ms.Mask |= linux.STATMOUNT_SB_BASIC
+	}
+	if mask&linux.STATMOUNT_MNT_BASIC != 0 {
+		// Mount IDs are never reused, so the "old" IDs reported by
+		// /proc/[pid]/mountinfo are the same as the unique IDs.
+		ms.MntID = mnt.ID
+		ms.MntParentID = mnt.ID
+		if p := mnt.parent(); p != nil {
+			ms.MntParentID = p.ID
+		}
+		ms.MntIDOld = uint32(ms.MntID)
+		ms.MntParentIDOld = uint32(ms.MntParentID)
+		ms.MntAttr = mnt.attrFlags()
+		// Mount propagation is not supported, so all mounts are private.
+		ms.MntPropagation = linux.MS_PRIVATE
+		ms.Mask |= linux.STATMOUNT_MNT_BASIC
+	}
+	if mask&linux.STATMOUNT_PROPAGATE_FROM != 0 {
+		// Without propagation, there is no peer group to receive from, so
+		// PropagateFrom is 0.
+		ms.Mask |= linux.STATMOUNT_PROPAGATE_FROM
+	}
+	if mask&linux.STATMOUNT_FS_TYPE != 0 {
+		ms.FSType = mnt.fs.FilesystemType().Name()
+		ms.Mask |= linux.STATMOUNT_FS_TYPE
+	}
+	if mask&linux.STATMOUNT_MNT_ROOT != 0 {
+		// NOTE(b/78135857): As in GenerateProcMountInfo, this will always be
+		// "/" until we implement bind mounts.
+		ms.MntRoot = "/"
+		ms.Mask |= linux.STATMOUNT_MNT_ROOT
+	}
+	if mask&linux.STATMOUNT_MNT_POINT != 0 {
+		ms.MntPoint = path
+		ms.Mask |= linux.STATMOUNT_MNT_POINT
+	}
+	return ms, nil
+}
+
+// attrFlags returns mnt's MOUNT_ATTR_* flags.
+func (mnt *Mount) attrFlags() uint64 {
+	var attr uint64
+	if mnt.ReadOnly() {
+		attr |= linux.MOUNT_ATTR_RDONLY
+	}
+	if mnt.Flags.NoSUID {
+		attr |= linux.MOUNT_ATTR_NOSUID
+	}
+	if mnt.Flags.NoDev {
+		attr |= linux.MOUNT_ATTR_NODEV
+	}
+	if mnt.Flags.NoExec {
+		attr |= linux.MOUNT_ATTR_NOEXEC
+	}
+	if mnt.Flags.NoATime {
+		attr |= linux.MOUNT_ATTR_NOATIME
+	}
+	return attr
+}
+
+// ListMounts returns the IDs of all Mounts below the Mount with the given ID
+// in root's mount namespace, not including that Mount itself, as for
+// listmount(2). If id is linux.LSMT_ROOT, root's Mount is used. Only IDs
+// greater than lastID are returned, in increasing order and at most max of
+// them; mounts that are not reachable from root are skipped.
+//
+// Preconditions: root.Ok().
+func (vfs *VirtualFilesystem) ListMounts(ctx context.Context, creds *auth.Credentials, root VirtualDentry, id, lastID uint64, max int) ([]uint64, error) {
+	vfs.mountMu.Lock()
+	parent := root.mount
+	if id != linux.LSMT_ROOT {
+		if parent = vfs.findMountLocked(root.mount.ns, id); parent == nil {
+			vfs.mountMu.Unlock()
+			return nil, linuxerr.ENOENT
+		}
+	}
+	var mounts []*Mount
+	for _, mnt := range parent.submountsLocked() {
+		if mnt != parent && mnt.ID > lastID {
+			mounts = append(mounts, mnt)
+		}
+	}
+	// Take a reference on parent and mounts since we need to drop
+	// vfs.mountMu before calling vfs.PathnameReachable().
+	parent.IncRef()
+	for _, mnt := range mounts {
+		mnt.IncRef()
+	}
+	vfs.mountMu.Unlock()
+	defer func() {
+		parent.DecRef(ctx)
+		for _, mnt := range mounts {
+			mnt.DecRef(ctx)
+		}
+	}()
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].ID < mounts[j].ID })
+
+	if _, err := vfs.mountPathForStat(ctx, creds, root, parent); err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	for _, mnt := range mounts {
+		if len(ids) >= max {
+			break
+		}
+		path, err := vfs.PathnameReachable(ctx, root, VirtualDentry{mount: mnt, dentry: mnt.root})
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			continue
+		}
+		ids = append(ids, mnt.ID)
+	}
+	return ids, nil
+}
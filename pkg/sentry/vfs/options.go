@@ -90,8 +90,6 @@ type MountFlags struct {
 
 	// NoDev is equivalent to MS_NODEV and indicates that the
 	// filesystem should not allow access to devices (special files).
-	// TODO(gVisor.dev/issue/3186): respect this flag in non FUSE
-	// filesystems.
 	NoDev bool
 
 	// NoSUID is equivalent to MS_NOSUID and indicates that the
@@ -100,6 +98,25 @@ type MountFlags struct {
 	NoSUID bool
 }
 
+// String returns the flags as mount options, each preceded by a comma, in the
+// order used by Linux's fs/proc_namespace.c:show_mnt_opts().
+func (f MountFlags) String() string {
+	var opts string
+	if f.NoSUID {
+		opts += ",nosuid"
+	}
+	if f.NoDev {
+		opts += ",nodev"
+	}
+	if f.NoExec {
+		opts += ",noexec"
+	}
+	if f.NoATime {
+		opts += ",noatime"
+	}
+	return opts
+}
+
 // MountOptions contains options to VirtualFilesystem.MountAt().
 //
 // +stateify savable
@@ -144,6 +161,13 @@ type OpenOptions struct {
 	// on the file, that the file is a regular file, and that the mount doesn't
 	// have MS_NOEXEC set.
 	FileExec bool
+
+	// If NoFanotify is true, neither the open nor later operations on the
+	// opened FileDescription generate fanotify events. This is analogous to
+	// Linux's FMODE_NONOTIFY, and is used for files passed to fanotify
+	// listeners, so that listeners can read them without deadlocking on their
+	// own permission events.
+	NoFanotify bool
 }
 
 // ReadOptions contains options to FileDescription.PRead(),
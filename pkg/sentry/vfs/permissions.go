@@ -199,6 +199,11 @@ func CheckSetStat(ctx context.Context, creds *auth.Credentials, opts *SetStatOpt
 		}
 	}
 	if stat.Mask&linux.STATX_MODE != 0 {
+		// Symlink modes can't be changed; compare Linux's
+		// fs/attr.c:notify_change().
+		if mode.FileType() == linux.ModeSymlink {
+			return linuxerr.EOPNOTSUPP
+		}
 		if !CanActAsOwner(creds, kuid) {
 			return linuxerr.EPERM
 		}
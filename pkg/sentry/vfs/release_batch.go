@@ -0,0 +1,102 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+)
+
+// A ReleaseBatcher accumulates cleanup work that a filesystem implementation
+// has deferred while a ReleaseBatch was in effect.
+type ReleaseBatcher interface {
+	// FlushRelease performs all work accumulated by the ReleaseBatcher.
+	FlushRelease(ctx context.Context)
+}
+
+// ReleaseBatch allows filesystem implementations to defer cleanup work done
+// when many FileDescriptions are released at once, such as when an FDTable
+// is torn down, so that it can be done in bulk. For example, the gofer client
+// closes all FIDs released by the batch with as few round trips to the remote
+// filesystem as possible.
+//
+// A ReleaseBatch is only used by the goroutine that owns it, and so requires
+// no synchronization. The zero value of ReleaseBatch is ready for use.
+type ReleaseBatch struct {
+	// batchers maps keys chosen by filesystem implementations to their
+	// ReleaseBatchers. order is the set of values in batchers, in the order in
+	// which they were added.
+	batchers map[interface{}]ReleaseBatcher
+	order    []ReleaseBatcher
+}
+
+// Batcher returns the ReleaseBatcher in b for the given key. If no such
+// ReleaseBatcher exists, Batcher creates it by calling newBatcher.
+func (b *ReleaseBatch) Batcher(key interface{}, newBatcher func() ReleaseBatcher) ReleaseBatcher {
+	if rb, ok := b.batchers[key]; ok {
+		return rb
+	}
+	if b.batchers == nil {
+		b.batchers = make(map[interface{}]ReleaseBatcher)
+	}
+	rb := newBatcher()
+	b.batchers[key] = rb
+	b.order = append(b.order, rb)
+	return rb
+}
+
+// Flush performs all work deferred to b, and then resets b.
+//
+// ctx must not carry b.
+func (b *ReleaseBatch) Flush(ctx context.Context) {
+	for _, rb := range b.order {
+		rb.FlushRelease(ctx)
+	}
+	b.batchers = nil
+	b.order = nil
+}
+
+// ReleaseBatchFromContext returns the ReleaseBatch in effect for ctx. If ctx
+// does not carry a ReleaseBatch, ReleaseBatchFromContext returns nil.
+func ReleaseBatchFromContext(ctx context.Context) *ReleaseBatch {
+	if v := ctx.Value(CtxReleaseBatch); v != nil {
+		return v.(*ReleaseBatch)
+	}
+	return nil
+}
+
+type releaseBatchContext struct {
+	context.Context
+	batch *ReleaseBatch
+}
+
+// WithReleaseBatch returns a copy of ctx with the given ReleaseBatch. Work
+// deferred to the batch by FileDescriptionImpl.Release, and by anything it
+// triggers, is not done until the caller calls batch.Flush.
+func WithReleaseBatch(ctx context.Context, batch *ReleaseBatch) context.Context {
+	return &releaseBatchContext{
+		Context: ctx,
+		batch:   batch,
+	}
+}
+
+// Value implements Context.Value.
+func (bc releaseBatchContext) Value(key interface{}) interface{} {
+	switch key {
+	case CtxReleaseBatch:
+		return bc.batch
+	default:
+		return bc.Context.Value(key)
+	}
+}
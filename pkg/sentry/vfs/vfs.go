@@ -30,6 +30,7 @@
 //         Watches.mu
 //           Inotify.evMu
 // VirtualFilesystem.fsTypesMu
+// VirtualFilesystem.fanotifyMu
 //
 // Locking Dentry.mu in multiple Dentries requires holding
 // VirtualFilesystem.mountMu. Locking EpollInstance.interestMu in multiple
@@ -123,6 +124,15 @@ type VirtualFilesystem struct {
 	// filesystemsMu.
 	filesystemsMu sync.Mutex `state:"nosave"`
 	filesystems   map[*Filesystem]struct{}
+
+	// fanotifyGroups contains all FanotifyGroups, keyed by
+	// FanotifyGroup.FanotifyID(). fanotifyGroups is protected by fanotifyMu.
+	// numFanotifyGroups is len(fanotifyGroups); it is accessed using atomic
+	// memory operations so that file operations can skip fanotify when there
+	// are no groups.
+	fanotifyMu        sync.Mutex `state:"nosave"`
+	fanotifyGroups    map[uint64]FanotifyGroup
+	numFanotifyGroups int32
 }
 
 // Init initializes a new VirtualFilesystem with no mounts or FilesystemTypes.
@@ -415,6 +425,12 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 	if opts.Flags&linux.O_PATH != 0 {
 		opts.Flags &= linux.O_DIRECTORY | linux.O_NOFOLLOW | linux.O_PATH
 	}
+	// Linux opens files with an access mode of O_ACCMODE (without O_PATH)
+	// for neither reading nor writing, which we don't support; reject them
+	// rather than granting both.
+	if opts.Flags&linux.O_ACCMODE == linux.O_ACCMODE {
+		return nil, linuxerr.EINVAL
+	}
 	// "On Linux, the following bits are also honored in mode: [S_ISUID,
 	// S_ISGID, S_ISVTX]" - open(2)
 	opts.Mode &= 0777 | linux.S_ISUID | linux.S_ISGID | linux.S_ISVTX
@@ -422,6 +438,12 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 	if opts.Flags&linux.O_NOFOLLOW != 0 {
 		pop.FollowFinalSymlink = false
 	}
+	// As in Linux's build_open_flags(), O_CREAT|O_EXCL implies O_NOFOLLOW for
+	// the final path component: an existing symlink, dangling or not, must
+	// fail with EEXIST rather than be followed.
+	if opts.Flags&(linux.O_CREAT|linux.O_EXCL) == linux.O_CREAT|linux.O_EXCL {
+		pop.FollowFinalSymlink = false
+	}
 	rp := vfs.getResolvingPath(creds, pop)
 	if opts.Flags&linux.O_DIRECTORY != 0 {
 		rp.mustBeDir = true
@@ -461,6 +483,13 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 				}
 			}
 
+			if opts.NoFanotify {
+				fd.noFanotify = true
+			} else if err := fd.fanotifyOpen(ctx); err != nil {
+				fd.DecRef(ctx)
+				return nil, err
+			}
+
 			fd.Dentry().InotifyWithParent(ctx, linux.IN_OPEN, 0, PathEvent)
 			return fd, nil
 		}
@@ -794,16 +823,21 @@ func (vfs *VirtualFilesystem) RemoveXattrAt(ctx context.Context, creds *auth.Cre
 	}
 }
 
-// SyncAllFilesystems has the semantics of Linux's sync(2).
+// SyncAllFilesystems has the semantics of Linux's sync(2). It syncs every
+// filesystem even if some fail, and returns EIO if any did.
 func (vfs *VirtualFilesystem) SyncAllFilesystems(ctx context.Context) error {
-	var retErr error
+	failed := false
 	for fs := range vfs.getFilesystems() {
-		if err := fs.impl.Sync(ctx); err != nil && retErr == nil {
-			retErr = err
+		if err := fs.impl.Sync(ctx); err != nil {
+			ctx.Infof("vfs.VirtualFilesystem.SyncAllFilesystems: %T.Sync failed: %v", fs.impl, err)
+			failed = true
 		}
 		fs.DecRef(ctx)
 	}
-	return retErr
+	if failed {
+		return linuxerr.EIO
+	}
+	return nil
 }
 
 func (vfs *VirtualFilesystem) getFilesystems() map[*Filesystem]struct{} {
@@ -41,6 +41,9 @@ const (
 	// https://www.iana.org/assignments/arp-parameters/arp-parameters.xhtml#arp-parameters-2
 	ARPHardwareEther    ARPHardwareType = 1
 	ARPHardwareLoopback ARPHardwareType = 2
+	ARPHardwareTunnel   ARPHardwareType = 3
+	ARPHardwareIPGRE    ARPHardwareType = 4
+	ARPHardwareIP6GRE   ARPHardwareType = 5
 )
 
 // ARPOp is an ARP opcode.
@@ -0,0 +1,192 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+const (
+	greFlagsAndVersion = 0
+	greProtocolType    = 2
+	greOptions         = 4
+)
+
+// GREFlags is the set of flags in the first two bytes of a GRE header, as
+// defined in RFC 2890.
+type GREFlags uint16
+
+const (
+	// GREFlagChecksum indicates that the checksum field is present.
+	GREFlagChecksum GREFlags = 0x8000
+
+	// GREFlagKey indicates that the key field is present.
+	GREFlagKey GREFlags = 0x2000
+
+	// GREFlagSequence indicates that the sequence number field is present.
+	GREFlagSequence GREFlags = 0x1000
+
+	// greVersionMask masks the version bits, which must be zero.
+	greVersionMask GREFlags = 0x0007
+
+	// greReservedMask masks the reserved bits, which must be zero when
+	// receiving a packet as per RFC 2784 section 2.3.
+	greReservedMask GREFlags = 0x4ff8
+)
+
+const (
+	// GREMinimumSize is the size of a GRE header without any optional
+	// fields.
+	GREMinimumSize = 4
+
+	// GREMaximumSize is the size of a GRE header with all optional fields.
+	GREMaximumSize = 16
+
+	// GREProtocolNumber is GRE's transport protocol number.
+	GREProtocolNumber tcpip.TransportProtocolNumber = 47
+
+	// IPIPProtocolNumber is the transport protocol number of IPv4 packets
+	// encapsulated in IPv4, as defined in RFC 2003.
+	IPIPProtocolNumber tcpip.TransportProtocolNumber = 4
+
+	// GREProtocolTransparentEthernetBridging is the GRE protocol type of
+	// encapsulated ethernet frames.
+	GREProtocolTransparentEthernetBridging tcpip.NetworkProtocolNumber = 0x6558
+)
+
+// GREFields contains the fields of a GRE header. It is used to describe the
+// fields of a header that needs to be encoded.
+type GREFields struct {
+	// Flags indicates which of the optional fields are present.
+	Flags GREFlags
+
+	// ProtocolType is the ethertype of the encapsulated packet.
+	ProtocolType tcpip.NetworkProtocolNumber
+
+	// Key is the "key" field, present if Flags has GREFlagKey.
+	Key uint32
+
+	// Sequence is the "sequence number" field, present if Flags has
+	// GREFlagSequence.
+	Sequence uint32
+}
+
+// GRE represents a GRE header stored in a byte array.
+type GRE []byte
+
+// GREHeaderSize returns the size of a GRE header with the given flags.
+func GREHeaderSize(flags GREFlags) int {
+	size := GREMinimumSize
+	if flags&GREFlagChecksum != 0 {
+		size += 4
+	}
+	if flags&GREFlagKey != 0 {
+		size += 4
+	}
+	if flags&GREFlagSequence != 0 {
+		size += 4
+	}
+	return size
+}
+
+// Flags returns the flags of the GRE header.
+func (b GRE) Flags() GREFlags {
+	return GREFlags(binary.BigEndian.Uint16(b[greFlagsAndVersion:])) &^ greVersionMask
+}
+
+// Version returns the version of the GRE header.
+func (b GRE) Version() uint8 {
+	return uint8(GREFlags(binary.BigEndian.Uint16(b[greFlagsAndVersion:])) & greVersionMask)
+}
+
+// ProtocolType returns the "protocol type" field of the GRE header.
+func (b GRE) ProtocolType() tcpip.NetworkProtocolNumber {
+	return tcpip.NetworkProtocolNumber(binary.BigEndian.Uint16(b[greProtocolType:]))
+}
+
+// HeaderLength returns the length of the GRE header, including the optional
+// fields indicated by its flags.
+func (b GRE) HeaderLength() int {
+	return GREHeaderSize(b.Flags())
+}
+
+// IsValid performs basic validation on the header. It returns false if b is
+// too short for the fields its flags indicate, if the version is not zero or
+// if any reserved bit is set.
+func (b GRE) IsValid() bool {
+	if len(b) < GREMinimumSize {
+		return false
+	}
+	if b.Version() != 0 || b.Flags()&greReservedMask != 0 {
+		return false
+	}
+	return len(b) >= b.HeaderLength()
+}
+
+// Checksum returns the "checksum" field of the GRE header. The header must
+// have GREFlagChecksum set.
+func (b GRE) Checksum() uint16 {
+	return binary.BigEndian.Uint16(b[greOptions:])
+}
+
+// SetChecksum sets the "checksum" field of the GRE header. The header must
+// have GREFlagChecksum set.
+func (b GRE) SetChecksum(checksum uint16) {
+	binary.BigEndian.PutUint16(b[greOptions:], checksum)
+}
+
+// Key returns the "key" field of the GRE header. The header must have
+// GREFlagKey set.
+func (b GRE) Key() uint32 {
+	return binary.BigEndian.Uint32(b[b.keyOffset():])
+}
+
+// Sequence returns the "sequence number" field of the GRE header. The header
+// must have GREFlagSequence set.
+func (b GRE) Sequence() uint32 {
+	off := b.keyOffset()
+	if b.Flags()&GREFlagKey != 0 {
+		off += 4
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
+
+func (b GRE) keyOffset() int {
+	if b.Flags()&GREFlagChecksum != 0 {
+		return greOptions + 4
+	}
+	return greOptions
+}
+
+// Encode encodes all the fields of the GRE header. The checksum field, if
+// present, is set to zero.
+func (b GRE) Encode(g *GREFields) {
+	binary.BigEndian.PutUint16(b[greFlagsAndVersion:], uint16(g.Flags))
+	binary.BigEndian.PutUint16(b[greProtocolType:], uint16(g.ProtocolType))
+	off := greOptions
+	if g.Flags&GREFlagChecksum != 0 {
+		binary.BigEndian.PutUint32(b[off:], 0)
+		off += 4
+	}
+	if g.Flags&GREFlagKey != 0 {
+		binary.BigEndian.PutUint32(b[off:], g.Key)
+		off += 4
+	}
+	if g.Flags&GREFlagSequence != 0 {
+		binary.BigEndian.PutUint32(b[off:], g.Sequence)
+	}
+}
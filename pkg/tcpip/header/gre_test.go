@@ -0,0 +1,146 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header_test
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+func TestGREEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  header.GREFields
+		wantLen int
+	}{
+		{
+			name:    "no options",
+			fields:  header.GREFields{ProtocolType: header.IPv4ProtocolNumber},
+			wantLen: header.GREMinimumSize,
+		},
+		{
+			name: "key",
+			fields: header.GREFields{
+				Flags:        header.GREFlagKey,
+				ProtocolType: header.IPv6ProtocolNumber,
+				Key:          0x01020304,
+			},
+			wantLen: header.GREMinimumSize + 4,
+		},
+		{
+			name: "sequence",
+			fields: header.GREFields{
+				Flags:        header.GREFlagSequence,
+				ProtocolType: header.IPv4ProtocolNumber,
+				Sequence:     0x05060708,
+			},
+			wantLen: header.GREMinimumSize + 4,
+		},
+		{
+			name: "all options",
+			fields: header.GREFields{
+				Flags:        header.GREFlagChecksum | header.GREFlagKey | header.GREFlagSequence,
+				ProtocolType: header.GREProtocolTransparentEthernetBridging,
+				Key:          0x01020304,
+				Sequence:     0x05060708,
+			},
+			wantLen: header.GREMaximumSize,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := header.GREHeaderSize(test.fields.Flags); got != test.wantLen {
+				t.Fatalf("got header.GREHeaderSize(%#x) = %d, want = %d", test.fields.Flags, got, test.wantLen)
+			}
+
+			gre := header.GRE(make([]byte, test.wantLen))
+			gre.Encode(&test.fields)
+			if !gre.IsValid() {
+				t.Fatalf("got gre.IsValid() = false, want = true")
+			}
+			if got := gre.HeaderLength(); got != test.wantLen {
+				t.Errorf("got gre.HeaderLength() = %d, want = %d", got, test.wantLen)
+			}
+			if got := gre.Flags(); got != test.fields.Flags {
+				t.Errorf("got gre.Flags() = %#x, want = %#x", got, test.fields.Flags)
+			}
+			if got := gre.Version(); got != 0 {
+				t.Errorf("got gre.Version() = %d, want = 0", got)
+			}
+			if got := gre.ProtocolType(); got != test.fields.ProtocolType {
+				t.Errorf("got gre.ProtocolType() = %#x, want = %#x", got, test.fields.ProtocolType)
+			}
+			if test.fields.Flags&header.GREFlagChecksum != 0 {
+				if got := gre.Checksum(); got != 0 {
+					t.Errorf("got gre.Checksum() = %#x, want = 0", got)
+				}
+			}
+			if test.fields.Flags&header.GREFlagKey != 0 {
+				if got := gre.Key(); got != test.fields.Key {
+					t.Errorf("got gre.Key() = %#x, want = %#x", got, test.fields.Key)
+				}
+			}
+			if test.fields.Flags&header.GREFlagSequence != 0 {
+				if got := gre.Sequence(); got != test.fields.Sequence {
+					t.Errorf("got gre.Sequence() = %#x, want = %#x", got, test.fields.Sequence)
+				}
+			}
+		})
+	}
+}
+
+func TestGREIsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		gre  header.GRE
+		want bool
+	}{
+		{
+			name: "too short",
+			gre:  header.GRE{0, 0, 0x08},
+			want: false,
+		},
+		{
+			name: "valid",
+			gre:  header.GRE{0, 0, 0x08, 0},
+			want: true,
+		},
+		{
+			name: "truncated key",
+			gre:  header.GRE{0x20, 0, 0x08, 0, 1, 2},
+			want: false,
+		},
+		{
+			name: "bad version",
+			gre:  header.GRE{0, 1, 0x08, 0},
+			want: false,
+		},
+		{
+			name: "routing present",
+			gre:  header.GRE{0x40, 0, 0x08, 0},
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.gre.IsValid(); got != test.want {
+				t.Errorf("got IsValid() = %t, want = %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,583 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bond provides the implementation of a bonding link endpoint, which
+// aggregates several ethernet link endpoints (its slaves) into one.
+//
+// Slaves are usually the link endpoints of existing NICs, which the bond takes
+// over while they are enslaved; see stack.Stack.NICLinkEndpoint. Packets
+// written to the bond are sent through one of its slaves, using the bond's
+// link address as their source, and packets received by the slaves are
+// delivered to the bond's NIC.
+package bond
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// defaultMTU is the MTU of a bond without slaves.
+const defaultMTU = 1500
+
+// Mode is the bonding policy of a bond. The values match Linux's bonding
+// modes.
+type Mode int
+
+const (
+	// ModeBalanceRR sends packets through each slave whose link is up in
+	// turn, and accepts packets received by any slave.
+	ModeBalanceRR Mode = 0
+
+	// ModeActiveBackup sends and accepts packets through a single slave, the
+	// active slave. Another slave becomes active when the link of the active
+	// slave goes down.
+	ModeActiveBackup Mode = 1
+)
+
+// String implements fmt.Stringer.
+func (m Mode) String() string {
+	switch m {
+	case ModeBalanceRR:
+		return "balance-rr"
+	case ModeActiveBackup:
+		return "active-backup"
+	default:
+		return "unknown"
+	}
+}
+
+// SlaveState is the state of a slave in a bond. The values match Linux's
+// BOND_STATE_* constants.
+type SlaveState int
+
+const (
+	// SlaveStateActive indicates that a slave sends and receives packets.
+	SlaveStateActive SlaveState = 0
+
+	// SlaveStateBackup indicates that a slave is only used if the active
+	// slave fails.
+	SlaveStateBackup SlaveState = 1
+)
+
+// LinkStatus is implemented by slave link endpoints that can tell whether
+// their link is up. The link of a slave that does not implement LinkStatus is
+// assumed to always be up.
+type LinkStatus interface {
+	// LinkUp returns true if the endpoint's link is up.
+	LinkUp() bool
+}
+
+// Options specify the details of a bond.
+type Options struct {
+	// Mode is the bonding policy.
+	Mode Mode
+
+	// MIIMon is the interval at which the links of the slaves are monitored.
+	// If zero, links are not monitored and the active slave only changes when
+	// it is released.
+	//
+	// In active-backup mode, the active slave is also considered to have
+	// failed if it received nothing during an interval in which another
+	// slave did.
+	MIIMon time.Duration
+
+	// LinkAddress is the link address of the bond. If empty, the bond takes
+	// the link address of its first slave.
+	LinkAddress tcpip.LinkAddress
+
+	// Clock is used to schedule link monitoring.
+	Clock tcpip.Clock
+}
+
+// SlaveInfo holds the state of a slave.
+type SlaveInfo struct {
+	// ID identifies the slave, as passed to Enslave.
+	ID tcpip.NICID
+
+	// State is the state of the slave in the bond.
+	State SlaveState
+
+	// LinkUp indicates whether the link of the slave was up when last
+	// monitored.
+	LinkUp bool
+
+	// LinkFailures is the number of times the link of the slave went down
+	// while it was enslaved.
+	LinkFailures uint32
+}
+
+// Info holds the state of a bond.
+type Info struct {
+	// Mode is the bonding policy.
+	Mode Mode
+
+	// MIIMon is the link monitoring interval.
+	MIIMon time.Duration
+
+	// ActiveSlave identifies the active slave in active-backup mode. It is
+	// zero if there is none.
+	ActiveSlave tcpip.NICID
+
+	// Failovers is the number of times the active slave changed because the
+	// previous one failed or was released.
+	Failovers uint32
+
+	// Slaves holds the state of each slave, in the order they were enslaved.
+	Slaves []SlaveInfo
+}
+
+// slave is a link endpoint enslaved to a bond. It is the dispatcher of the
+// endpoint while it is enslaved.
+type slave struct {
+	bond *Endpoint
+	id   tcpip.NICID
+	ep   stack.LinkEndpoint
+
+	// restore is the dispatcher the endpoint is attached to when it is
+	// released.
+	restore stack.NetworkDispatcher
+
+	// rxPackets is the number of packets received by the slave. It is
+	// accessed atomically.
+	rxPackets uint64
+
+	// The fields below are protected by bond.mu.
+
+	// lastRxPackets is the value of rxPackets when the slave was last
+	// monitored.
+	lastRxPackets uint64
+
+	// up indicates whether the link of the slave was up when last monitored.
+	up bool
+
+	// linkFailures is the number of times the link went down.
+	linkFailures uint32
+}
+
+var _ stack.NetworkDispatcher = (*slave)(nil)
+
+// DeliverNetworkPacket implements stack.NetworkDispatcher.
+func (s *slave) DeliverNetworkPacket(remote, local tcpip.LinkAddress, protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	atomic.AddUint64(&s.rxPackets, 1)
+
+	b := s.bond
+	b.mu.RLock()
+	d := b.mu.dispatcher
+	drop := b.mode == ModeActiveBackup && b.mu.active != s
+	b.mu.RUnlock()
+
+	// Packets received by backup slaves are dropped so that the bond's NIC
+	// does not receive duplicates.
+	if d == nil || drop {
+		return
+	}
+	d.DeliverNetworkPacket(remote, local, protocol, pkt)
+}
+
+// DeliverOutboundPacket implements stack.NetworkDispatcher.
+func (s *slave) DeliverOutboundPacket(remote, local tcpip.LinkAddress, protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	b := s.bond
+	b.mu.RLock()
+	d := b.mu.dispatcher
+	b.mu.RUnlock()
+	if d != nil {
+		d.DeliverOutboundPacket(remote, local, protocol, pkt)
+	}
+}
+
+// linkUp returns true if the link of the slave is up.
+func (s *slave) linkUp() bool {
+	if ls, ok := s.ep.(LinkStatus); ok {
+		return ls.LinkUp()
+	}
+	return true
+}
+
+// Endpoint is a bonding link endpoint.
+type Endpoint struct {
+	mode   Mode
+	miimon time.Duration
+	clock  tcpip.Clock
+
+	// next is the number of packets sent in balance-rr mode, used to pick the
+	// next slave. It is accessed atomically.
+	next uint32
+
+	mu struct {
+		sync.RWMutex
+
+		// dispatcher is the dispatcher of the bond's NIC.
+		dispatcher stack.NetworkDispatcher
+
+		// linkAddr is the link address of the bond.
+		linkAddr tcpip.LinkAddress
+
+		// slaves holds the slaves in the order they were enslaved.
+		slaves []*slave
+
+		// active is the active slave in active-backup mode.
+		active *slave
+
+		// failovers is the number of times the active slave changed.
+		failovers uint32
+
+		// monitor runs the link monitor while the bond has slaves.
+		monitor tcpip.Timer
+
+		// monitorGen is incremented each time the link monitor is started,
+		// so that a stopped monitor does not run again.
+		monitorGen uint32
+	}
+}
+
+var _ stack.LinkEndpoint = (*Endpoint)(nil)
+
+// New returns a bond without slaves.
+func New(opts Options) (*Endpoint, tcpip.Error) {
+	switch opts.Mode {
+	case ModeBalanceRR, ModeActiveBackup:
+	default:
+		return nil, &tcpip.ErrNotSupported{}
+	}
+	if opts.MIIMon < 0 {
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+
+	e := &Endpoint{
+		mode:   opts.Mode,
+		miimon: opts.MIIMon,
+		clock:  opts.Clock,
+	}
+	e.mu.linkAddr = opts.LinkAddress
+	return e, nil
+}
+
+// Enslave adds ep as a slave of the bond, identified by id. The bond attaches
+// itself to ep until the slave is released, at which point ep is attached to
+// restore.
+//
+// ep must be an ethernet link endpoint which sets the source address of the
+// packets it sends to the route's local link address.
+func (e *Endpoint) Enslave(id tcpip.NICID, ep stack.LinkEndpoint, restore stack.NetworkDispatcher) tcpip.Error {
+	if ep.ARPHardwareType() != header.ARPHardwareEther {
+		return &tcpip.ErrNotSupported{}
+	}
+	if _, ok := ep.(*Endpoint); ok {
+		return &tcpip.ErrNotSupported{}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.mu.slaves {
+		if s.id == id {
+			return &tcpip.ErrDuplicateNICID{}
+		}
+	}
+
+	s := &slave{
+		bond:    e,
+		id:      id,
+		ep:      ep,
+		restore: restore,
+	}
+	s.up = s.linkUp()
+	if len(e.mu.slaves) == 0 {
+		if e.mu.linkAddr == "" {
+			e.mu.linkAddr = ep.LinkAddress()
+		}
+		if e.miimon != 0 {
+			e.mu.monitorGen++
+			gen := e.mu.monitorGen
+			e.mu.monitor = e.clock.AfterFunc(e.miimon, func() { e.monitor(gen) })
+		}
+	}
+	e.mu.slaves = append(e.mu.slaves, s)
+	if e.mode == ModeActiveBackup && e.mu.active == nil && s.up {
+		e.mu.active = s
+	}
+	ep.Attach(s)
+	return nil
+}
+
+// Release removes the slave identified by id from the bond and attaches its
+// link endpoint back to the dispatcher passed to Enslave.
+func (e *Endpoint) Release(id tcpip.NICID) tcpip.Error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, s := range e.mu.slaves {
+		if s.id == id {
+			e.mu.slaves = append(e.mu.slaves[:i], e.mu.slaves[i+1:]...)
+			e.releaseLocked(s)
+			return nil
+		}
+	}
+	return &tcpip.ErrUnknownNICID{}
+}
+
+// releaseLocked gives the link endpoint of s back and picks another active
+// slave if s was active.
+//
+// Precondition: e.mu must be locked and s must have been removed from
+// e.mu.slaves.
+func (e *Endpoint) releaseLocked(s *slave) {
+	s.ep.Attach(s.restore)
+	if e.mu.active == s {
+		e.mu.active = nil
+		e.selectActiveLocked(s)
+	}
+	if len(e.mu.slaves) == 0 && e.mu.monitor != nil {
+		e.mu.monitor.Stop()
+		e.mu.monitor = nil
+	}
+}
+
+// selectActiveLocked makes the first slave whose link is up active if there is
+// no active slave. prev is the slave that was active before, if any.
+//
+// Precondition: e.mu must be locked.
+func (e *Endpoint) selectActiveLocked(prev *slave) {
+	if e.mode != ModeActiveBackup || e.mu.active != nil {
+		return
+	}
+	for _, s := range e.mu.slaves {
+		if s.up {
+			e.mu.active = s
+			if prev != nil && prev != s {
+				e.mu.failovers++
+			}
+			return
+		}
+	}
+}
+
+// monitor checks the links of the slaves and fails over to a backup slave if
+// the link of the active slave is down.
+func (e *Endpoint) monitor(gen uint32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.mu.monitor == nil || e.mu.monitorGen != gen {
+		// The monitor was stopped when all the slaves were released.
+		return
+	}
+
+	otherReceived := false
+	activeReceived := false
+	for _, s := range e.mu.slaves {
+		rx := atomic.LoadUint64(&s.rxPackets)
+		received := rx != s.lastRxPackets
+		s.lastRxPackets = rx
+		if s == e.mu.active {
+			activeReceived = received
+		} else if received {
+			otherReceived = true
+		}
+	}
+
+	for _, s := range e.mu.slaves {
+		up := s.linkUp()
+		if up && s == e.mu.active && e.mode == ModeActiveBackup && !activeReceived && otherReceived {
+			// The active slave stopped receiving while a backup slave did
+			// not, so it most likely lost connectivity.
+			up = false
+		}
+		if s.up && !up {
+			s.linkFailures++
+		}
+		s.up = up
+	}
+
+	if prev := e.mu.active; prev != nil && !prev.up {
+		e.mu.active = nil
+		e.selectActiveLocked(prev)
+	} else if prev == nil {
+		e.selectActiveLocked(nil)
+	}
+
+	e.mu.monitor.Reset(e.miimon)
+}
+
+// Info returns the state of the bond.
+func (e *Endpoint) Info() Info {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	info := Info{
+		Mode:      e.mode,
+		MIIMon:    e.miimon,
+		Failovers: e.mu.failovers,
+		Slaves:    make([]SlaveInfo, 0, len(e.mu.slaves)),
+	}
+	if e.mode == ModeActiveBackup && e.mu.active != nil {
+		info.ActiveSlave = e.mu.active.id
+	}
+	for _, s := range e.mu.slaves {
+		state := SlaveStateActive
+		if e.mode == ModeActiveBackup && s != e.mu.active {
+			state = SlaveStateBackup
+		}
+		info.Slaves = append(info.Slaves, SlaveInfo{
+			ID:           s.id,
+			State:        state,
+			LinkUp:       s.up,
+			LinkFailures: s.linkFailures,
+		})
+	}
+	return info
+}
+
+// txSlave returns the slave to send the next packet through, or nil if there
+// is none.
+func (e *Endpoint) txSlave() *slave {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.mode == ModeActiveBackup {
+		return e.mu.active
+	}
+
+	var up int
+	for _, s := range e.mu.slaves {
+		if s.up {
+			up++
+		}
+	}
+	if up == 0 {
+		return nil
+	}
+	n := int(atomic.AddUint32(&e.next, 1)-1) % up
+	for _, s := range e.mu.slaves {
+		if !s.up {
+			continue
+		}
+		if n == 0 {
+			return s
+		}
+		n--
+	}
+	panic("unreachable")
+}
+
+// WritePacket implements stack.LinkEndpoint.
+func (e *Endpoint) WritePacket(r stack.RouteInfo, protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) tcpip.Error {
+	s := e.txSlave()
+	if s == nil {
+		return &tcpip.ErrClosedForSend{}
+	}
+	return s.ep.WritePacket(e.route(r), protocol, pkt)
+}
+
+// WritePackets implements stack.LinkEndpoint.
+func (e *Endpoint) WritePackets(r stack.RouteInfo, pkts stack.PacketBufferList, protocol tcpip.NetworkProtocolNumber) (int, tcpip.Error) {
+	s := e.txSlave()
+	if s == nil {
+		return 0, &tcpip.ErrClosedForSend{}
+	}
+	return s.ep.WritePackets(e.route(r), pkts, protocol)
+}
+
+// route returns r with the bond's link address as its local link address, so
+// that the slave sending a packet takes over the bond's link address.
+func (e *Endpoint) route(r stack.RouteInfo) stack.RouteInfo {
+	r.LocalLinkAddress = e.LinkAddress()
+	return r
+}
+
+// Attach implements stack.LinkEndpoint.
+//
+// Detaching the bond releases all its slaves.
+func (e *Endpoint) Attach(dispatcher stack.NetworkDispatcher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mu.dispatcher = dispatcher
+	if dispatcher != nil {
+		return
+	}
+	slaves := e.mu.slaves
+	e.mu.slaves = nil
+	for _, s := range slaves {
+		e.releaseLocked(s)
+	}
+}
+
+// IsAttached implements stack.LinkEndpoint.
+func (e *Endpoint) IsAttached() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mu.dispatcher != nil
+}
+
+// MTU implements stack.LinkEndpoint. It is the smallest MTU of the slaves.
+func (e *Endpoint) MTU() uint32 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if len(e.mu.slaves) == 0 {
+		return defaultMTU
+	}
+	mtu := ^uint32(0)
+	for _, s := range e.mu.slaves {
+		if m := s.ep.MTU(); m < mtu {
+			mtu = m
+		}
+	}
+	return mtu
+}
+
+// MaxHeaderLength implements stack.LinkEndpoint. It is the largest header
+// length of the slaves.
+func (e *Endpoint) MaxHeaderLength() uint16 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	l := uint16(header.EthernetMinimumSize)
+	for _, s := range e.mu.slaves {
+		if h := s.ep.MaxHeaderLength(); h > l {
+			l = h
+		}
+	}
+	return l
+}
+
+// LinkAddress implements stack.LinkEndpoint.
+func (e *Endpoint) LinkAddress() tcpip.LinkAddress {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mu.linkAddr
+}
+
+// Capabilities implements stack.LinkEndpoint.
+func (*Endpoint) Capabilities() stack.LinkEndpointCapabilities {
+	return stack.CapabilityResolutionRequired
+}
+
+// Wait implements stack.LinkEndpoint.
+func (*Endpoint) Wait() {}
+
+// ARPHardwareType implements stack.LinkEndpoint.
+func (*Endpoint) ARPHardwareType() header.ARPHardwareType {
+	return header.ARPHardwareEther
+}
+
+// AddHeader implements stack.LinkEndpoint.
+func (e *Endpoint) AddHeader(local, remote tcpip.LinkAddress, protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	eth := header.Ethernet(pkt.LinkHeader().Push(header.EthernetMinimumSize))
+	eth.Encode(&header.EthernetFields{
+		SrcAddr: local,
+		DstAddr: remote,
+		Type:    protocol,
+	})
+}
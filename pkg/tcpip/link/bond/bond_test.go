@@ -0,0 +1,380 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bond_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/bond"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const (
+	bondNICID   = 1
+	slave1NICID = 2
+	slave2NICID = 3
+
+	slave1LinkAddr = tcpip.LinkAddress("\x02\x00\x00\x00\x00\x01")
+	slave2LinkAddr = tcpip.LinkAddress("\x02\x00\x00\x00\x00\x02")
+	remoteLinkAddr = tcpip.LinkAddress("\x02\x00\x00\x00\x00\x03")
+
+	miimon = 100 * time.Millisecond
+
+	// testProto is the network protocol of test packets. The test stacks have
+	// no network protocols, so NICs count the packets and drop them.
+	testProto tcpip.NetworkProtocolNumber = 0x88b5
+)
+
+// slaveEndpoint is an ethernet endpoint whose link can be brought down.
+type slaveEndpoint struct {
+	*ethernet.Endpoint
+
+	// down is accessed atomically.
+	down uint32
+}
+
+var _ bond.LinkStatus = (*slaveEndpoint)(nil)
+
+// LinkUp implements bond.LinkStatus.
+func (e *slaveEndpoint) LinkUp() bool {
+	return atomic.LoadUint32(&e.down) == 0
+}
+
+func (e *slaveEndpoint) setLinkUp(up bool) {
+	v := uint32(1)
+	if up {
+		v = 0
+	}
+	atomic.StoreUint32(&e.down, v)
+}
+
+type testContext struct {
+	t      *testing.T
+	clock  *faketime.ManualClock
+	s      *stack.Stack
+	bond   *bond.Endpoint
+	links  [2]*channel.Endpoint
+	slaves [2]*slaveEndpoint
+}
+
+func newTestContext(t *testing.T, mode bond.Mode) *testContext {
+	clock := faketime.NewManualClock()
+	c := &testContext{
+		t:     t,
+		clock: clock,
+		s:     stack.New(stack.Options{Clock: clock}),
+	}
+	t.Cleanup(func() {
+		c.s.Close()
+		c.s.Wait()
+	})
+
+	for i, linkAddr := range []tcpip.LinkAddress{slave1LinkAddr, slave2LinkAddr} {
+		c.links[i] = channel.New(4, header.EthernetMinimumSize+1500, linkAddr)
+		c.slaves[i] = &slaveEndpoint{Endpoint: ethernet.New(c.links[i])}
+		if err := c.s.CreateNIC(tcpip.NICID(slave1NICID+i), c.slaves[i]); err != nil {
+			t.Fatalf("CreateNIC(%d, _): %s", slave1NICID+i, err)
+		}
+	}
+
+	b, err := bond.New(bond.Options{
+		Mode:   mode,
+		MIIMon: miimon,
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatalf("bond.New(_): %s", err)
+	}
+	c.bond = b
+	if err := c.s.CreateNIC(bondNICID, b); err != nil {
+		t.Fatalf("CreateNIC(%d, _): %s", bondNICID, err)
+	}
+	for _, id := range []tcpip.NICID{slave1NICID, slave2NICID} {
+		c.enslave(id)
+	}
+	return c
+}
+
+func (c *testContext) enslave(id tcpip.NICID) {
+	c.t.Helper()
+	ep, d, err := c.s.NICLinkEndpoint(id)
+	if err != nil {
+		c.t.Fatalf("NICLinkEndpoint(%d): %s", id, err)
+	}
+	if err := c.bond.Enslave(id, ep, d); err != nil {
+		c.t.Fatalf("Enslave(%d, _, _): %s", id, err)
+	}
+}
+
+// write writes a packet through the bond and returns the index of the slave it
+// was sent through.
+func (c *testContext) write() int {
+	c.t.Helper()
+	payload := buffer.NewViewFromBytes([]byte{1, 2, 3, 4})
+	if err := c.s.WritePacketToRemote(bondNICID, remoteLinkAddr, testProto, payload.ToVectorisedView()); err != nil {
+		c.t.Fatalf("WritePacketToRemote(...): %s", err)
+	}
+
+	sent := -1
+	for i, link := range c.links {
+		p, ok := link.Read()
+		if !ok {
+			continue
+		}
+		if sent != -1 {
+			c.t.Fatalf("packet sent through slaves %d and %d", sent, i)
+		}
+		sent = i
+
+		// The slave sending the packet takes over the bond's link address.
+		eth := header.Ethernet(p.Pkt.LinkHeader().View())
+		if got, want := eth.SourceAddress(), c.bond.LinkAddress(); got != want {
+			c.t.Errorf("got eth.SourceAddress() = %s, want = %s", got, want)
+		}
+		if got := eth.DestinationAddress(); got != remoteLinkAddr {
+			c.t.Errorf("got eth.DestinationAddress() = %s, want = %s", got, remoteLinkAddr)
+		}
+	}
+	if sent == -1 {
+		c.t.Fatal("no packet sent")
+	}
+	return sent
+}
+
+// receive injects a packet into the link of slave i.
+func (c *testContext) receive(i int) {
+	v := buffer.NewView(header.EthernetMinimumSize + 4)
+	header.Ethernet(v).Encode(&header.EthernetFields{
+		SrcAddr: remoteLinkAddr,
+		DstAddr: c.bond.LinkAddress(),
+		Type:    testProto,
+	})
+	c.links[i].InjectInbound(testProto, stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Data: v.ToVectorisedView(),
+	}))
+}
+
+// rxPackets returns the number of packets received by NIC id.
+func (c *testContext) rxPackets(id tcpip.NICID) uint64 {
+	return c.s.NICInfo()[id].Stats.Rx.Packets.Value()
+}
+
+func TestActiveBackupLinkDownFailover(t *testing.T) {
+	c := newTestContext(t, bond.ModeActiveBackup)
+
+	if got := c.bond.LinkAddress(); got != slave1LinkAddr {
+		t.Fatalf("got c.bond.LinkAddress() = %s, want = %s", got, slave1LinkAddr)
+	}
+	if got := c.write(); got != 0 {
+		t.Fatalf("packet sent through slave %d, want 0", got)
+	}
+
+	// Packets received by the backup slave are dropped.
+	c.receive(0)
+	c.receive(1)
+	if got := c.rxPackets(bondNICID); got != 1 {
+		t.Errorf("got bond rx packets = %d, want = 1", got)
+	}
+
+	c.slaves[0].setLinkUp(false)
+	c.clock.Advance(miimon)
+
+	want := bond.Info{
+		Mode:        bond.ModeActiveBackup,
+		MIIMon:      miimon,
+		ActiveSlave: slave2NICID,
+		Failovers:   1,
+		Slaves: []bond.SlaveInfo{
+			{ID: slave1NICID, State: bond.SlaveStateBackup, LinkUp: false, LinkFailures: 1},
+			{ID: slave2NICID, State: bond.SlaveStateActive, LinkUp: true},
+		},
+	}
+	if diff := cmp.Diff(want, c.bond.Info()); diff != "" {
+		t.Errorf("bond info mismatch (-want +got):\n%s", diff)
+	}
+	if got := c.write(); got != 1 {
+		t.Fatalf("packet sent through slave %d, want 1", got)
+	}
+	c.receive(0)
+	c.receive(1)
+	if got := c.rxPackets(bondNICID); got != 2 {
+		t.Errorf("got bond rx packets = %d, want = 2", got)
+	}
+
+	// The active slave does not change when the link comes back up.
+	c.slaves[0].setLinkUp(true)
+	c.clock.Advance(miimon)
+	if got := c.bond.Info().ActiveSlave; got != slave2NICID {
+		t.Errorf("got c.bond.Info().ActiveSlave = %d, want = %d", got, slave2NICID)
+	}
+	if got := c.write(); got != 1 {
+		t.Fatalf("packet sent through slave %d, want 1", got)
+	}
+}
+
+func TestActiveBackupRxFailover(t *testing.T) {
+	c := newTestContext(t, bond.ModeActiveBackup)
+
+	// The active slave keeps being used while nothing is received.
+	c.clock.Advance(miimon)
+	if got := c.bond.Info().ActiveSlave; got != slave1NICID {
+		t.Fatalf("got c.bond.Info().ActiveSlave = %d, want = %d", got, slave1NICID)
+	}
+
+	// The active slave keeps being used while it receives packets.
+	c.receive(0)
+	c.receive(1)
+	c.clock.Advance(miimon)
+	if got := c.bond.Info().ActiveSlave; got != slave1NICID {
+		t.Fatalf("got c.bond.Info().ActiveSlave = %d, want = %d", got, slave1NICID)
+	}
+
+	// The active slave fails if only the backup slave receives packets.
+	c.receive(1)
+	c.clock.Advance(miimon)
+	info := c.bond.Info()
+	if info.ActiveSlave != slave2NICID {
+		t.Fatalf("got info.ActiveSlave = %d, want = %d", info.ActiveSlave, slave2NICID)
+	}
+	if info.Failovers != 1 {
+		t.Errorf("got info.Failovers = %d, want = 1", info.Failovers)
+	}
+	if got := c.write(); got != 1 {
+		t.Fatalf("packet sent through slave %d, want 1", got)
+	}
+}
+
+func TestActiveBackupRelease(t *testing.T) {
+	c := newTestContext(t, bond.ModeActiveBackup)
+
+	if err := c.bond.Release(slave1NICID); err != nil {
+		t.Fatalf("c.bond.Release(%d): %s", slave1NICID, err)
+	}
+	if err := c.bond.Release(slave1NICID); err == nil {
+		t.Errorf("c.bond.Release(%d) succeeded for a released slave", slave1NICID)
+	} else if _, ok := err.(*tcpip.ErrUnknownNICID); !ok {
+		t.Errorf("got c.bond.Release(%d) = %s, want = %s", slave1NICID, err, &tcpip.ErrUnknownNICID{})
+	}
+	info := c.bond.Info()
+	if info.ActiveSlave != slave2NICID || info.Failovers != 1 {
+		t.Errorf("got (info.ActiveSlave, info.Failovers) = (%d, %d), want = (%d, 1)", info.ActiveSlave, info.Failovers, slave2NICID)
+	}
+
+	// The released slave's NIC receives packets again.
+	c.receive(0)
+	if got := c.rxPackets(slave1NICID); got != 1 {
+		t.Errorf("got slave 1 rx packets = %d, want = 1", got)
+	}
+	c.receive(1)
+	if got := c.rxPackets(slave2NICID); got != 0 {
+		t.Errorf("got slave 2 rx packets = %d, want = 0", got)
+	}
+	if got := c.rxPackets(bondNICID); got != 1 {
+		t.Errorf("got bond rx packets = %d, want = 1", got)
+	}
+
+	// Removing the bond releases its slaves.
+	if err := c.s.RemoveNIC(bondNICID); err != nil {
+		t.Fatalf("RemoveNIC(%d): %s", bondNICID, err)
+	}
+	c.receive(1)
+	if got := c.rxPackets(slave2NICID); got != 1 {
+		t.Errorf("got slave 2 rx packets = %d, want = 1", got)
+	}
+}
+
+func TestActiveBackupAllLinksDown(t *testing.T) {
+	c := newTestContext(t, bond.ModeActiveBackup)
+
+	c.slaves[0].setLinkUp(false)
+	c.slaves[1].setLinkUp(false)
+	c.clock.Advance(miimon)
+	if got := c.bond.Info().ActiveSlave; got != 0 {
+		t.Fatalf("got c.bond.Info().ActiveSlave = %d, want = 0", got)
+	}
+	payload := buffer.NewView(4)
+	if err := c.s.WritePacketToRemote(bondNICID, remoteLinkAddr, testProto, payload.ToVectorisedView()); err == nil {
+		t.Fatal("WritePacketToRemote(...) succeeded with all links down")
+	}
+
+	c.slaves[1].setLinkUp(true)
+	c.clock.Advance(miimon)
+	if got := c.write(); got != 1 {
+		t.Fatalf("packet sent through slave %d, want 1", got)
+	}
+}
+
+func TestBalanceRR(t *testing.T) {
+	c := newTestContext(t, bond.ModeBalanceRR)
+
+	for i := 0; i < 4; i++ {
+		if got, want := c.write(), i%2; got != want {
+			t.Fatalf("packet %d sent through slave %d, want %d", i, got, want)
+		}
+	}
+
+	// Packets received by any slave are accepted.
+	c.receive(0)
+	c.receive(1)
+	if got := c.rxPackets(bondNICID); got != 2 {
+		t.Errorf("got bond rx packets = %d, want = 2", got)
+	}
+
+	// Slaves whose link is down are skipped.
+	c.slaves[0].setLinkUp(false)
+	c.clock.Advance(miimon)
+	for i := 0; i < 2; i++ {
+		if got := c.write(); got != 1 {
+			t.Fatalf("packet %d sent through slave %d, want 1", i, got)
+		}
+	}
+	if got := c.bond.Info().Slaves[0].LinkFailures; got != 1 {
+		t.Errorf("got slave 1 link failures = %d, want = 1", got)
+	}
+}
+
+func TestEnslaveErrors(t *testing.T) {
+	c := newTestContext(t, bond.ModeActiveBackup)
+
+	ep, d, err := c.s.NICLinkEndpoint(slave1NICID)
+	if err != nil {
+		t.Fatalf("NICLinkEndpoint(%d): %s", slave1NICID, err)
+	}
+	if err := c.bond.Enslave(slave1NICID, ep, d); err == nil {
+		t.Errorf("Enslave(%d, _, _) succeeded for an enslaved NIC", slave1NICID)
+	} else if _, ok := err.(*tcpip.ErrDuplicateNICID); !ok {
+		t.Errorf("got Enslave(%d, _, _) = %s, want = %s", slave1NICID, err, &tcpip.ErrDuplicateNICID{})
+	}
+
+	// Only ethernet endpoints can be enslaved.
+	if err := c.bond.Enslave(4, channel.New(1, 1500, ""), nil); err == nil {
+		t.Error("Enslave succeeded for a non-ethernet endpoint")
+	} else if _, ok := err.(*tcpip.ErrNotSupported); !ok {
+		t.Errorf("got Enslave(...) = %s, want = %s", err, &tcpip.ErrNotSupported{})
+	}
+
+	if _, _, err := c.s.NICLinkEndpoint(4); err == nil {
+		t.Error("NICLinkEndpoint succeeded for an unknown NIC")
+	}
+}
@@ -62,13 +62,13 @@ func (e *Endpoint) Capabilities() stack.LinkEndpointCapabilities {
 
 // WritePacket implements stack.LinkEndpoint.
 func (e *Endpoint) WritePacket(r stack.RouteInfo, proto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) tcpip.Error {
-	e.AddHeader(e.Endpoint.LinkAddress(), r.RemoteLinkAddress, proto, pkt)
+	e.AddHeader(e.localLinkAddress(r), r.RemoteLinkAddress, proto, pkt)
 	return e.Endpoint.WritePacket(r, proto, pkt)
 }
 
 // WritePackets implements stack.LinkEndpoint.
 func (e *Endpoint) WritePackets(r stack.RouteInfo, pkts stack.PacketBufferList, proto tcpip.NetworkProtocolNumber) (int, tcpip.Error) {
-	linkAddr := e.Endpoint.LinkAddress()
+	linkAddr := e.localLinkAddress(r)
 
 	for pkt := pkts.Front(); pkt != nil; pkt = pkt.Next() {
 		e.AddHeader(linkAddr, r.RemoteLinkAddress, proto, pkt)
@@ -77,6 +77,15 @@ func (e *Endpoint) WritePackets(r stack.RouteInfo, pkts stack.PacketBufferList,
 	return e.Endpoint.WritePackets(r, pkts, proto)
 }
 
+// localLinkAddress returns the source address of packets sent on r, which is
+// the route's local link address if it has one.
+func (e *Endpoint) localLinkAddress(r stack.RouteInfo) tcpip.LinkAddress {
+	if r.LocalLinkAddress != "" {
+		return r.LocalLinkAddress
+	}
+	return e.Endpoint.LinkAddress()
+}
+
 // MaxHeaderLength implements stack.LinkEndpoint.
 func (e *Endpoint) MaxHeaderLength() uint16 {
 	return header.EthernetMinimumSize + e.Endpoint.MaxHeaderLength()
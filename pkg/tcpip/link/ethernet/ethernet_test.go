@@ -69,3 +69,56 @@ func TestDeliverNetworkPacket(t *testing.T) {
 		t.Fatalf("got networkDispatcher.networkPackets = %d, want = 1", networkDispatcher.networkPackets)
 	}
 }
+
+func TestWritePacketSourceAddress(t *testing.T) {
+	const (
+		linkAddr      = tcpip.LinkAddress("\x02\x02\x03\x04\x05\x06")
+		routeLinkAddr = tcpip.LinkAddress("\x02\x02\x03\x04\x05\x07")
+		remoteAddr    = tcpip.LinkAddress("\x02\x02\x03\x04\x05\x08")
+	)
+
+	tests := []struct {
+		name          string
+		localLinkAddr tcpip.LinkAddress
+		wantSrc       tcpip.LinkAddress
+	}{
+		{
+			name:    "Endpoint address",
+			wantSrc: linkAddr,
+		},
+		{
+			name:          "Route address",
+			localLinkAddr: routeLinkAddr,
+			wantSrc:       routeLinkAddr,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := channel.New(1, header.EthernetMinimumSize, linkAddr)
+			e := ethernet.New(c)
+
+			var r stack.RouteInfo
+			r.LocalLinkAddress = test.localLinkAddr
+			r.RemoteLinkAddress = remoteAddr
+			pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+				ReserveHeaderBytes: int(e.MaxHeaderLength()),
+			})
+			if err := e.WritePacket(r, header.IPv4ProtocolNumber, pkt); err != nil {
+				t.Fatalf("e.WritePacket(...): %s", err)
+			}
+
+			p, ok := c.Read()
+			if !ok {
+				t.Fatal("expected a packet to be written")
+			}
+			eth := header.Ethernet(p.Pkt.LinkHeader().View())
+			if got := eth.SourceAddress(); got != test.wantSrc {
+				t.Errorf("got eth.SourceAddress() = %s, want = %s", got, test.wantSrc)
+			}
+			if got := eth.DestinationAddress(); got != remoteAddr {
+				t.Errorf("got eth.DestinationAddress() = %s, want = %s", got, remoteAddr)
+			}
+		})
+	}
+}
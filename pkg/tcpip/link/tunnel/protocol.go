@@ -0,0 +1,192 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// tunnelID identifies a tunnel from the perspective of received packets.
+type tunnelID struct {
+	local  tcpip.Address
+	remote tcpip.Address
+	keyed  bool
+	key    uint32
+}
+
+// protocol implements stack.TransportProtocol for the protocols carrying
+// tunneled packets. It decapsulates received packets and hands them to the
+// tunnel endpoint they belong to.
+type protocol struct {
+	stack  *stack.Stack
+	number tcpip.TransportProtocolNumber
+
+	mu struct {
+		sync.RWMutex
+
+		// tunnels holds the tunnels created on top of this protocol.
+		tunnels map[tunnelID]*Endpoint
+	}
+}
+
+// Number implements stack.TransportProtocol.Number.
+func (p *protocol) Number() tcpip.TransportProtocolNumber {
+	return p.number
+}
+
+// NewEndpoint implements stack.TransportProtocol.NewEndpoint.
+func (*protocol) NewEndpoint(tcpip.NetworkProtocolNumber, *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return nil, &tcpip.ErrUnknownProtocol{}
+}
+
+// NewRawEndpoint implements stack.TransportProtocol.NewRawEndpoint.
+func (p *protocol) NewRawEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
+	return raw.NewEndpoint(p.stack, netProto, p.number, waiterQueue)
+}
+
+// MinimumPacketSize implements stack.TransportProtocol.MinimumPacketSize.
+func (p *protocol) MinimumPacketSize() int {
+	if p.number == header.GREProtocolNumber {
+		return header.GREMinimumSize
+	}
+	return header.IPv4MinimumSize
+}
+
+// ParsePorts implements stack.TransportProtocol.ParsePorts. Tunneling
+// protocols have no ports.
+func (*protocol) ParsePorts(buffer.View) (src, dst uint16, err tcpip.Error) {
+	return 0, 0, nil
+}
+
+// HandleUnknownDestinationPacket implements
+// stack.TransportProtocol.HandleUnknownDestinationPacket.
+//
+// Tunneled packets never match transport endpoints, so this is where they are
+// handed to the tunnel they belong to. Packets that do not belong to any
+// tunnel are left unhandled so that the sender is notified.
+func (p *protocol) HandleUnknownDestinationPacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) stack.UnknownDestinationPacketDisposition {
+	tid := tunnelID{
+		local:  id.LocalAddress,
+		remote: id.RemoteAddress,
+	}
+	var gre header.GRE
+	if p.number == header.GREProtocolNumber {
+		gre = header.GRE(pkt.TransportHeader().View())
+		if gre.Flags()&header.GREFlagKey != 0 {
+			tid.keyed = true
+			tid.key = gre.Key()
+		}
+	}
+
+	e := p.lookup(tid)
+	if e == nil {
+		return stack.UnknownDestinationPacketUnhandled
+	}
+	e.handlePacket(gre, pkt)
+	return stack.UnknownDestinationPacketHandled
+}
+
+// lookup returns the tunnel that packets identified by id belong to, or nil if
+// there is none. Tunnels bound to a local address take precedence over
+// tunnels accepting packets on any local address.
+func (p *protocol) lookup(id tunnelID) *Endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if e, ok := p.mu.tunnels[id]; ok {
+		return e
+	}
+	id.local = ""
+	return p.mu.tunnels[id]
+}
+
+// register adds e to the set of tunnels receiving packets identified by id.
+func (p *protocol) register(id tunnelID, e *Endpoint) tcpip.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.mu.tunnels[id]; ok {
+		return &tcpip.ErrDuplicateAddress{}
+	}
+	p.mu.tunnels[id] = e
+	return nil
+}
+
+// unregister removes the tunnel identified by id if it is e.
+func (p *protocol) unregister(id tunnelID, e *Endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mu.tunnels[id] == e {
+		delete(p.mu.tunnels, id)
+	}
+}
+
+// SetOption implements stack.TransportProtocol.SetOption.
+func (*protocol) SetOption(tcpip.SettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Option implements stack.TransportProtocol.Option.
+func (*protocol) Option(tcpip.GettableTransportProtocolOption) tcpip.Error {
+	return &tcpip.ErrUnknownProtocolOption{}
+}
+
+// Close implements stack.TransportProtocol.Close.
+func (*protocol) Close() {}
+
+// Wait implements stack.TransportProtocol.Wait.
+func (*protocol) Wait() {}
+
+// Parse implements stack.TransportProtocol.Parse.
+//
+// The GRE header, including its optional fields, is consumed as the transport
+// header. IP-in-IP packets have no header of their own.
+func (p *protocol) Parse(pkt *stack.PacketBuffer) bool {
+	if p.number != header.GREProtocolNumber {
+		return true
+	}
+	h, ok := pkt.Data().PullUp(header.GREMinimumSize)
+	if !ok {
+		return false
+	}
+	hdrLen := header.GRE(h).HeaderLength()
+	if h, ok = pkt.Data().PullUp(hdrLen); !ok || !header.GRE(h).IsValid() {
+		return false
+	}
+	_, ok = pkt.TransportHeader().Consume(hdrLen)
+	return ok
+}
+
+// NewGREProtocol returns a GRE transport protocol. It decapsulates packets
+// received by GRE tunnels over IPv4 and IPv6, and must be registered with a
+// stack before GRE tunnels can be created on it.
+func NewGREProtocol(s *stack.Stack) stack.TransportProtocol {
+	p := &protocol{stack: s, number: header.GREProtocolNumber}
+	p.mu.tunnels = make(map[tunnelID]*Endpoint)
+	return p
+}
+
+// NewIPIPProtocol returns an IP-in-IP transport protocol. It decapsulates
+// packets received by IP-in-IP tunnels, and must be registered with a stack
+// before IP-in-IP tunnels can be created on it.
+func NewIPIPProtocol(s *stack.Stack) stack.TransportProtocol {
+	p := &protocol{stack: s, number: header.IPIPProtocolNumber}
+	p.mu.tunnels = make(map[tunnelID]*Endpoint)
+	return p
+}
@@ -0,0 +1,559 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tunnel provides the implementation of GRE (RFC 2784, RFC 2890) and
+// IP-in-IP (RFC 2003) tunnel link endpoints.
+//
+// A tunnel endpoint encapsulates the packets written to it and sends them to
+// the remote end of the tunnel through the stack it was created on. Received
+// packets are decapsulated by the transport protocols returned by
+// NewGREProtocol and NewIPIPProtocol, which must be registered with that
+// stack, and delivered to the NIC the tunnel endpoint is attached to.
+package tunnel
+
+import (
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// defaultLinkMTU is the MTU assumed for the link towards the remote end of a
+// tunnel when there is no route to it.
+const defaultLinkMTU = 1500
+
+// keepaliveTTL is the TTL of the packet carried by GRE keepalives, which is
+// sent back by the remote end of the tunnel.
+const keepaliveTTL = 255
+
+// Mode is the kind of a tunnel.
+type Mode int
+
+const (
+	// ModeGRE encapsulates IP packets in GRE. The tunnel is an ip6gre tunnel
+	// if its addresses are IPv6 addresses.
+	ModeGRE Mode = iota
+
+	// ModeGRETap encapsulates ethernet frames in GRE. The endpoint must be
+	// wrapped by an ethernet endpoint (see link/ethernet) before a NIC is
+	// created for it.
+	ModeGRETap
+
+	// ModeIPIP encapsulates IPv4 packets in IPv4.
+	ModeIPIP
+)
+
+// greOptionFlags is the set of GRE flags a tunnel may be configured with.
+const greOptionFlags = header.GREFlagChecksum | header.GREFlagKey | header.GREFlagSequence
+
+// Options specify the details of a tunnel endpoint.
+type Options struct {
+	// Mode is the kind of the tunnel.
+	Mode Mode
+
+	// Local is the source address of encapsulated packets. If empty, the
+	// source address is picked by the stack and packets received on any local
+	// address are accepted.
+	Local tcpip.Address
+
+	// Remote is the address of the remote end of the tunnel.
+	Remote tcpip.Address
+
+	// TTL is the TTL or hop limit of encapsulated packets. If zero, it is
+	// inherited from the packet being encapsulated.
+	TTL uint8
+
+	// IFlags and OFlags are the GRE options that received and sent packets
+	// carry. Only GREFlagChecksum, GREFlagKey and GREFlagSequence are valid.
+	IFlags header.GREFlags
+	OFlags header.GREFlags
+
+	// IKey and OKey are the keys of received and sent packets. They are only
+	// used if GREFlagKey is set in IFlags and OFlags respectively.
+	IKey uint32
+	OKey uint32
+
+	// MTU is the MTU of the tunnel. If zero, it is derived from the MTU of
+	// the path to the remote end of the tunnel, so that it follows the path
+	// MTU learned for it.
+	MTU uint32
+
+	// LinkAddress is the link address of a ModeGRETap tunnel.
+	LinkAddress tcpip.LinkAddress
+}
+
+// Stats holds the statistics of a tunnel endpoint.
+type Stats struct {
+	// KeepalivesSent is the number of GRE keepalives sent.
+	KeepalivesSent tcpip.StatCounter
+
+	// KeepalivesReceived is the number of GRE keepalives received back from
+	// the remote end of the tunnel.
+	KeepalivesReceived tcpip.StatCounter
+
+	// KeepalivesReflected is the number of GRE keepalives sent back to the
+	// remote end of the tunnel.
+	KeepalivesReflected tcpip.StatCounter
+
+	// InvalidPacketsReceived is the number of packets dropped because they do
+	// not carry the GRE options the tunnel is configured with, or because
+	// their checksum or sequence number is invalid.
+	InvalidPacketsReceived tcpip.StatCounter
+}
+
+var _ stack.LinkEndpoint = (*Endpoint)(nil)
+
+// Endpoint is a tunnel link endpoint.
+type Endpoint struct {
+	// The following fields are immutable.
+	stack    *stack.Stack
+	opts     Options
+	netProto tcpip.NetworkProtocolNumber
+	proto    *protocol
+	id       tunnelID
+
+	// oseq is the sequence number of the next packet sent. It is accessed
+	// atomically.
+	oseq uint32
+
+	stats Stats
+
+	mu struct {
+		sync.RWMutex
+
+		dispatcher stack.NetworkDispatcher
+
+		// iseq is the sequence number expected from the next packet received.
+		iseq uint32
+	}
+}
+
+// New creates a tunnel endpoint on top of s.
+//
+// The tunnel receives packets as soon as it is created. It stops receiving
+// them once the NIC it is attached to is removed, after which the endpoint
+// cannot be reused.
+func New(s *stack.Stack, opts Options) (*Endpoint, tcpip.Error) {
+	e := &Endpoint{
+		stack: s,
+		opts:  opts,
+	}
+
+	switch len(opts.Remote) {
+	case header.IPv4AddressSize:
+		e.netProto = header.IPv4ProtocolNumber
+	case header.IPv6AddressSize:
+		e.netProto = header.IPv6ProtocolNumber
+	default:
+		return nil, &tcpip.ErrBadAddress{}
+	}
+	if len(opts.Local) != 0 && len(opts.Local) != len(opts.Remote) {
+		return nil, &tcpip.ErrBadAddress{}
+	}
+
+	transProto := header.GREProtocolNumber
+	switch opts.Mode {
+	case ModeGRE, ModeGRETap:
+		if opts.IFlags&^greOptionFlags != 0 || opts.OFlags&^greOptionFlags != 0 {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+	case ModeIPIP:
+		if e.netProto != header.IPv4ProtocolNumber {
+			return nil, &tcpip.ErrBadAddress{}
+		}
+		if opts.IFlags != 0 || opts.OFlags != 0 {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		transProto = header.IPIPProtocolNumber
+	default:
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+
+	p, ok := s.TransportProtocolInstance(transProto).(*protocol)
+	if !ok {
+		return nil, &tcpip.ErrUnknownProtocol{}
+	}
+	e.proto = p
+	e.id = tunnelID{
+		local:  opts.Local,
+		remote: opts.Remote,
+		keyed:  opts.IFlags&header.GREFlagKey != 0,
+	}
+	if e.id.keyed {
+		e.id.key = opts.IKey
+	}
+	if err := p.register(e.id, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Stats returns the statistics of the tunnel.
+func (e *Endpoint) Stats() *Stats {
+	return &e.stats
+}
+
+// overhead returns the number of bytes added to packets by the tunnel, not
+// counting the outer network header.
+func (e *Endpoint) overhead() uint32 {
+	var n uint32
+	switch e.opts.Mode {
+	case ModeGRE:
+		n = uint32(header.GREHeaderSize(e.opts.OFlags))
+	case ModeGRETap:
+		n = uint32(header.GREHeaderSize(e.opts.OFlags)) + header.EthernetMinimumSize
+	}
+	return n
+}
+
+// route returns a route to the remote end of the tunnel.
+func (e *Endpoint) route() (*stack.Route, tcpip.Error) {
+	return e.stack.FindRoute(0 /* any NIC */, e.opts.Local, e.opts.Remote, e.netProto, false /* multicastLoop */)
+}
+
+// MTU implements stack.LinkEndpoint.
+func (e *Endpoint) MTU() uint32 {
+	if e.opts.MTU != 0 {
+		return e.opts.MTU
+	}
+
+	var mtu uint32
+	if r, err := e.route(); err == nil {
+		mtu = r.MTU()
+		r.Release()
+	} else if e.netProto == header.IPv4ProtocolNumber {
+		mtu = defaultLinkMTU - header.IPv4MinimumSize
+	} else {
+		mtu = defaultLinkMTU - header.IPv6MinimumSize
+	}
+	if mtu < e.overhead() {
+		return 0
+	}
+	return mtu - e.overhead()
+}
+
+// Capabilities implements stack.LinkEndpoint.
+func (*Endpoint) Capabilities() stack.LinkEndpointCapabilities {
+	return 0
+}
+
+// MaxHeaderLength implements stack.LinkEndpoint.
+//
+// Encapsulated packets are copied into new packets, so there is no need to
+// reserve space for the outer headers.
+func (*Endpoint) MaxHeaderLength() uint16 {
+	return 0
+}
+
+// LinkAddress implements stack.LinkEndpoint.
+func (e *Endpoint) LinkAddress() tcpip.LinkAddress {
+	return e.opts.LinkAddress
+}
+
+// ARPHardwareType implements stack.LinkEndpoint.
+func (e *Endpoint) ARPHardwareType() header.ARPHardwareType {
+	switch e.opts.Mode {
+	case ModeGRETap:
+		return header.ARPHardwareEther
+	case ModeIPIP:
+		return header.ARPHardwareTunnel
+	}
+	if e.netProto == header.IPv6ProtocolNumber {
+		return header.ARPHardwareIP6GRE
+	}
+	return header.ARPHardwareIPGRE
+}
+
+// AddHeader implements stack.LinkEndpoint.
+func (*Endpoint) AddHeader(_, _ tcpip.LinkAddress, _ tcpip.NetworkProtocolNumber, _ *stack.PacketBuffer) {
+}
+
+// Attach implements stack.LinkEndpoint.
+func (e *Endpoint) Attach(dispatcher stack.NetworkDispatcher) {
+	e.mu.Lock()
+	e.mu.dispatcher = dispatcher
+	e.mu.Unlock()
+	if dispatcher == nil {
+		e.proto.unregister(e.id, e)
+	}
+}
+
+// IsAttached implements stack.LinkEndpoint.
+func (e *Endpoint) IsAttached() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mu.dispatcher != nil
+}
+
+// Wait implements stack.LinkEndpoint.
+func (*Endpoint) Wait() {}
+
+// WritePacket implements stack.LinkEndpoint.
+func (e *Endpoint) WritePacket(_ stack.RouteInfo, proto tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) tcpip.Error {
+	ttl := e.opts.TTL
+	df := false
+	switch proto {
+	case header.IPv4ProtocolNumber:
+		if h := header.IPv4(pkt.NetworkHeader().View()); len(h) >= header.IPv4MinimumSize {
+			if e.isOwnPacket(h.SourceAddress(), h.DestinationAddress(), h.TransportProtocol()) {
+				return &tcpip.ErrNoRoute{}
+			}
+			if ttl == 0 {
+				ttl = h.TTL()
+			}
+			df = h.Flags()&header.IPv4FlagDontFragment != 0
+		}
+	case header.IPv6ProtocolNumber:
+		if h := header.IPv6(pkt.NetworkHeader().View()); len(h) >= header.IPv6MinimumSize {
+			if e.isOwnPacket(h.SourceAddress(), h.DestinationAddress(), h.TransportProtocol()) {
+				return &tcpip.ErrNoRoute{}
+			}
+			if ttl == 0 {
+				ttl = h.HopLimit()
+			}
+		}
+		// IPv6 packets are never fragmented on their way, so neither are the
+		// packets carrying them.
+		df = true
+	}
+
+	if e.opts.Mode == ModeGRETap {
+		proto = header.GREProtocolTransparentEthernetBridging
+	} else if e.opts.Mode == ModeIPIP && proto != header.IPv4ProtocolNumber {
+		return &tcpip.ErrNotSupported{}
+	}
+	return e.writeEncapsulated(proto, buffer.NewVectorisedView(pkt.Size(), pkt.Views()), ttl, df)
+}
+
+// WritePackets implements stack.LinkEndpoint.
+func (e *Endpoint) WritePackets(r stack.RouteInfo, pkts stack.PacketBufferList, proto tcpip.NetworkProtocolNumber) (int, tcpip.Error) {
+	n := 0
+	for pkt := pkts.Front(); pkt != nil; pkt = pkt.Next() {
+		if err := e.WritePacket(r, proto, pkt); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// isOwnPacket returns true if a packet with the given addresses and transport
+// protocol is a packet encapsulated by e. Such a packet is only written to e
+// if the route to the remote end of the tunnel goes through the tunnel itself.
+func (e *Endpoint) isOwnPacket(src, dst tcpip.Address, transProto tcpip.TransportProtocolNumber) bool {
+	return transProto == e.proto.number && dst == e.opts.Remote && (len(e.opts.Local) == 0 || src == e.opts.Local)
+}
+
+// writeEncapsulated sends data, a packet of protocol proto, to the remote end
+// of the tunnel.
+func (e *Endpoint) writeEncapsulated(proto tcpip.NetworkProtocolNumber, data buffer.VectorisedView, ttl uint8, df bool) tcpip.Error {
+	r, err := e.route()
+	if err != nil {
+		return err
+	}
+	defer r.Release()
+
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(r.MaxHeaderLength()) + header.GREMaximumSize,
+		Data:               data,
+	})
+	if e.proto.number == header.GREProtocolNumber {
+		fields := header.GREFields{
+			Flags:        e.opts.OFlags,
+			ProtocolType: proto,
+			Key:          e.opts.OKey,
+		}
+		if fields.Flags&header.GREFlagSequence != 0 {
+			fields.Sequence = atomic.AddUint32(&e.oseq, 1) - 1
+		}
+		encodeGRE(pkt, &fields)
+	}
+
+	if ttl == 0 {
+		ttl = r.DefaultTTL()
+	}
+	return r.WritePacket(stack.NetworkHeaderParams{
+		Protocol: e.proto.number,
+		TTL:      ttl,
+		DF:       df,
+	}, pkt)
+}
+
+// encodeGRE pushes a GRE header with the given fields as the transport header
+// of pkt, computing its checksum if it has one.
+func encodeGRE(pkt *stack.PacketBuffer, fields *header.GREFields) {
+	gre := header.GRE(pkt.TransportHeader().Push(header.GREHeaderSize(fields.Flags)))
+	gre.Encode(fields)
+	if fields.Flags&header.GREFlagChecksum != 0 {
+		gre.SetChecksum(^header.ChecksumCombine(header.Checksum(gre, 0), pkt.Data().AsRange().Checksum()))
+	}
+	pkt.TransportProtocolNumber = header.GREProtocolNumber
+}
+
+// handlePacket handles pkt, a packet received by the tunnel. gre is the GRE
+// header of pkt, or nil for IP-in-IP packets.
+func (e *Endpoint) handlePacket(gre header.GRE, pkt *stack.PacketBuffer) {
+	proto := header.IPv4ProtocolNumber
+	if gre != nil {
+		flags := gre.Flags()
+		if flags&header.GREFlagChecksum != e.opts.IFlags&header.GREFlagChecksum {
+			e.stats.InvalidPacketsReceived.Increment()
+			return
+		}
+		if flags&header.GREFlagChecksum != 0 && header.ChecksumCombine(header.Checksum(gre, 0), pkt.Data().AsRange().Checksum()) != 0xffff {
+			e.stats.InvalidPacketsReceived.Increment()
+			return
+		}
+
+		proto = gre.ProtocolType()
+		if proto == 0 {
+			// Keepalives reflected by the remote end of the tunnel are the only
+			// packets carrying nothing.
+			e.stats.KeepalivesReceived.Increment()
+			return
+		}
+		if !e.checkSequence(gre) {
+			e.stats.InvalidPacketsReceived.Increment()
+			return
+		}
+	}
+
+	data := pkt.Data().ExtractVV()
+	if e.maybeReflectKeepalive(proto, data) {
+		return
+	}
+
+	switch e.opts.Mode {
+	case ModeGRETap:
+		if proto != header.GREProtocolTransparentEthernetBridging {
+			return
+		}
+		// The ethernet endpoint wrapping the tunnel determines the protocol.
+		proto = 0
+	default:
+		if proto == header.GREProtocolTransparentEthernetBridging {
+			return
+		}
+	}
+
+	e.mu.RLock()
+	d := e.mu.dispatcher
+	e.mu.RUnlock()
+	if d == nil {
+		return
+	}
+	d.DeliverNetworkPacket("" /* remote */, "" /* local */, proto, stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Data: data,
+	}))
+}
+
+// checkSequence returns false if the tunnel expects sequence numbers and gre
+// has none, or is out of order.
+func (e *Endpoint) checkSequence(gre header.GRE) bool {
+	if e.opts.IFlags&header.GREFlagSequence == 0 {
+		return true
+	}
+	if gre.Flags()&header.GREFlagSequence == 0 {
+		return false
+	}
+
+	seq := gre.Sequence()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.mu.iseq != 0 && int32(seq-e.mu.iseq) < 0 {
+		return false
+	}
+	e.mu.iseq = seq + 1
+	return true
+}
+
+// maybeReflectKeepalive sends data, a packet received by the tunnel, back to
+// the remote end of the tunnel if it is a GRE keepalive and returns true.
+//
+// A GRE keepalive is an IPv4 packet carrying GRE, addressed from the local end
+// of a tunnel to its remote end, that the remote end encapsulated in GRE. Its
+// receiver sends it as is to the sender, which receives it as a GRE packet
+// carrying nothing.
+func (e *Endpoint) maybeReflectKeepalive(proto tcpip.NetworkProtocolNumber, data buffer.VectorisedView) bool {
+	if e.opts.Mode != ModeGRE || e.netProto != header.IPv4ProtocolNumber || proto != header.IPv4ProtocolNumber {
+		return false
+	}
+	h, ok := data.PullUp(header.IPv4MinimumSize)
+	if !ok {
+		return false
+	}
+	ip := header.IPv4(h)
+	if ip.TransportProtocol() != header.GREProtocolNumber || ip.DestinationAddress() != e.opts.Remote || (len(e.opts.Local) != 0 && ip.SourceAddress() != e.opts.Local) {
+		return false
+	}
+
+	r, err := e.route()
+	if err != nil {
+		return true
+	}
+	defer r.Release()
+	if err := r.WriteHeaderIncludedPacket(stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(r.MaxHeaderLength()),
+		Data:               data,
+	})); err == nil {
+		e.stats.KeepalivesReflected.Increment()
+	}
+	return true
+}
+
+// SendKeepalive sends a GRE keepalive to the remote end of the tunnel. Only
+// GRE tunnels over IPv4 support keepalives.
+//
+// The remote end sends the keepalive back, which is counted in
+// Stats.KeepalivesReceived.
+func (e *Endpoint) SendKeepalive() tcpip.Error {
+	if e.opts.Mode != ModeGRE || e.netProto != header.IPv4ProtocolNumber {
+		return &tcpip.ErrNotSupported{}
+	}
+
+	r, err := e.route()
+	if err != nil {
+		return err
+	}
+	local := r.LocalAddress()
+	r.Release()
+
+	// The keepalive is addressed to us and carries the options we expect, so
+	// that it is recognized as ours once the remote end sends it back.
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: header.IPv4MinimumSize + header.GREMaximumSize,
+	})
+	encodeGRE(pkt, &header.GREFields{
+		Flags: e.opts.IFlags &^ header.GREFlagSequence,
+		Key:   e.opts.IKey,
+	})
+	ip := header.IPv4(pkt.NetworkHeader().Push(header.IPv4MinimumSize))
+	ip.Encode(&header.IPv4Fields{
+		TotalLength: uint16(pkt.Size()),
+		TTL:         keepaliveTTL,
+		Protocol:    uint8(header.GREProtocolNumber),
+		SrcAddr:     e.opts.Remote,
+		DstAddr:     local,
+	})
+	ip.SetChecksum(^ip.CalculateChecksum())
+
+	if err := e.writeEncapsulated(header.IPv4ProtocolNumber, buffer.NewVectorisedView(pkt.Size(), pkt.Views()), 0 /* ttl */, false /* df */); err != nil {
+		return err
+	}
+	e.stats.KeepalivesSent.Increment()
+	return nil
+}
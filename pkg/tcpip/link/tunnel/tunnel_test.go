@@ -0,0 +1,524 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/checker"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/ethernet"
+	"gvisor.dev/gvisor/pkg/tcpip/link/pipe"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/arp"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	linkNICID   = 1
+	tunnelNICID = 2
+
+	port = 1234
+
+	// readTimeout is the time to wait for packets that are expected to be
+	// received.
+	readTimeout = 5 * time.Second
+)
+
+var (
+	outerAddrs4 = [2]tcpip.AddressWithPrefix{
+		{Address: tcpip.Address("\xc0\xa8\x00\x01"), PrefixLen: 24},
+		{Address: tcpip.Address("\xc0\xa8\x00\x02"), PrefixLen: 24},
+	}
+	outerAddrs6 = [2]tcpip.AddressWithPrefix{
+		{Address: tcpip.Address("\xfd\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"), PrefixLen: 64},
+		{Address: tcpip.Address("\xfd\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02"), PrefixLen: 64},
+	}
+	innerAddrs = [2]tcpip.AddressWithPrefix{
+		{Address: tcpip.Address("\x0a\x00\x00\x01"), PrefixLen: 24},
+		{Address: tcpip.Address("\x0a\x00\x00\x02"), PrefixLen: 24},
+	}
+	linkAddrs = [2]tcpip.LinkAddress{
+		tcpip.LinkAddress("\x02\x00\x00\x00\x00\x01"),
+		tcpip.LinkAddress("\x02\x00\x00\x00\x00\x02"),
+	}
+	tapLinkAddrs = [2]tcpip.LinkAddress{
+		tcpip.LinkAddress("\x02\x00\x00\x00\x01\x01"),
+		tcpip.LinkAddress("\x02\x00\x00\x00\x01\x02"),
+	}
+)
+
+// host is one end of a tunnel.
+type host struct {
+	stack  *stack.Stack
+	tunnel *tunnel.Endpoint
+}
+
+// setup creates two stacks connected by a pipe and a tunnel between them.
+// opts[i] configures the tunnel of the i-th host; its addresses are filled in
+// according to outer.
+func setup(t *testing.T, outer [2]tcpip.AddressWithPrefix, opts [2]tunnel.Options) [2]host {
+	t.Helper()
+
+	links := [2]*pipe.Endpoint{}
+	links[0], links[1] = pipe.New(linkAddrs[0], linkAddrs[1])
+
+	var hosts [2]host
+	for i := range hosts {
+		s := stack.New(stack.Options{
+			NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol, arp.NewProtocol},
+			TransportProtocols: []stack.TransportProtocolFactory{udp.NewProtocol, tunnel.NewGREProtocol, tunnel.NewIPIPProtocol},
+			RawFactory:         raw.EndpointFactory{},
+		})
+		t.Cleanup(s.Close)
+
+		if err := s.CreateNIC(linkNICID, links[i]); err != nil {
+			t.Fatalf("s.CreateNIC(%d, _): %s", linkNICID, err)
+		}
+		outerProto := header.IPv4ProtocolNumber
+		if len(outer[i].Address) == header.IPv6AddressSize {
+			outerProto = header.IPv6ProtocolNumber
+		}
+		protocolAddr := tcpip.ProtocolAddress{Protocol: outerProto, AddressWithPrefix: outer[i]}
+		if err := s.AddProtocolAddress(linkNICID, protocolAddr); err != nil {
+			t.Fatalf("s.AddProtocolAddress(%d, %#v): %s", linkNICID, protocolAddr, err)
+		}
+
+		o := opts[i]
+		o.Local = outer[i].Address
+		o.Remote = outer[1-i].Address
+		ep, err := tunnel.New(s, o)
+		if err != nil {
+			t.Fatalf("tunnel.New(_, %#v): %s", o, err)
+		}
+		var linkEP stack.LinkEndpoint = ep
+		if o.Mode == tunnel.ModeGRETap {
+			linkEP = ethernet.New(ep)
+		}
+		if err := s.CreateNIC(tunnelNICID, linkEP); err != nil {
+			t.Fatalf("s.CreateNIC(%d, _): %s", tunnelNICID, err)
+		}
+		protocolAddr = tcpip.ProtocolAddress{Protocol: header.IPv4ProtocolNumber, AddressWithPrefix: innerAddrs[i]}
+		if err := s.AddProtocolAddress(tunnelNICID, protocolAddr); err != nil {
+			t.Fatalf("s.AddProtocolAddress(%d, %#v): %s", tunnelNICID, protocolAddr, err)
+		}
+
+		s.SetRouteTable([]tcpip.Route{
+			{Destination: outer[i].Subnet(), NIC: linkNICID},
+			{Destination: innerAddrs[i].Subnet(), NIC: tunnelNICID},
+		})
+		hosts[i] = host{stack: s, tunnel: ep}
+	}
+	return hosts
+}
+
+// udpEndpoint is a UDP endpoint bound to an inner address.
+type udpEndpoint struct {
+	tcpip.Endpoint
+	readableCH chan struct{}
+}
+
+func newUDPEndpoint(t *testing.T, s *stack.Stack, addr tcpip.Address) udpEndpoint {
+	t.Helper()
+
+	var wq waiter.Queue
+	we, ch := waiter.NewChannelEntry(nil)
+	wq.EventRegister(&we, waiter.ReadableEvents)
+	ep, err := s.NewEndpoint(udp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		t.Fatalf("s.NewEndpoint(%d, %d, _): %s", udp.ProtocolNumber, ipv4.ProtocolNumber, err)
+	}
+	t.Cleanup(ep.Close)
+	bindAddr := tcpip.FullAddress{Addr: addr, Port: port}
+	if err := ep.Bind(bindAddr); err != nil {
+		t.Fatalf("ep.Bind(%#v): %s", bindAddr, err)
+	}
+	return udpEndpoint{Endpoint: ep, readableCH: ch}
+}
+
+func write(ep tcpip.Endpoint, to tcpip.Address, data []byte) tcpip.Error {
+	var r bytes.Reader
+	r.Reset(data)
+	_, err := ep.Write(&r, tcpip.WriteOptions{To: &tcpip.FullAddress{Addr: to, Port: port}})
+	return err
+}
+
+// read reads a packet from ep. It waits for one to be received for up to
+// timeout, and returns false if none was.
+func read(t *testing.T, ep udpEndpoint, timeout time.Duration) ([]byte, tcpip.Address, bool) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	res, err := ep.Read(&buf, tcpip.ReadOptions{NeedRemoteAddr: true})
+	if _, ok := err.(*tcpip.ErrWouldBlock); ok {
+		select {
+		case <-ep.readableCH:
+		case <-time.After(timeout):
+			return nil, "", false
+		}
+		res, err = ep.Read(&buf, tcpip.ReadOptions{NeedRemoteAddr: true})
+	}
+	if err != nil {
+		t.Fatalf("ep.Read(_, _): %s", err)
+	}
+	return buf.Bytes(), res.RemoteAddr.Addr, true
+}
+
+func TestTunnelRoundTrip(t *testing.T) {
+	allFlags := header.GREFlagChecksum | header.GREFlagKey | header.GREFlagSequence
+
+	tests := []struct {
+		name  string
+		outer [2]tcpip.AddressWithPrefix
+		opts  [2]tunnel.Options
+	}{
+		{
+			name:  "gre",
+			outer: outerAddrs4,
+		},
+		{
+			name:  "gre with options",
+			outer: outerAddrs4,
+			opts: [2]tunnel.Options{
+				{IFlags: allFlags, OFlags: allFlags, IKey: 1, OKey: 2},
+				{IFlags: allFlags, OFlags: allFlags, IKey: 2, OKey: 1},
+			},
+		},
+		{
+			name:  "gre with TTL",
+			outer: outerAddrs4,
+			opts:  [2]tunnel.Options{{TTL: 10}, {TTL: 10}},
+		},
+		{
+			name:  "ip6gre",
+			outer: outerAddrs6,
+		},
+		{
+			name:  "gretap",
+			outer: outerAddrs4,
+			opts: [2]tunnel.Options{
+				{Mode: tunnel.ModeGRETap, LinkAddress: tapLinkAddrs[0]},
+				{Mode: tunnel.ModeGRETap, LinkAddress: tapLinkAddrs[1]},
+			},
+		},
+		{
+			name:  "ipip",
+			outer: outerAddrs4,
+			opts:  [2]tunnel.Options{{Mode: tunnel.ModeIPIP}, {Mode: tunnel.ModeIPIP}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hosts := setup(t, test.outer, test.opts)
+			eps := [2]udpEndpoint{
+				newUDPEndpoint(t, hosts[0].stack, innerAddrs[0].Address),
+				newUDPEndpoint(t, hosts[1].stack, innerAddrs[1].Address),
+			}
+
+			for i := 0; i < 2; i++ {
+				for from := range eps {
+					to := 1 - from
+					data := []byte{byte(i), byte(from), 3, 4}
+					if err := write(eps[from], innerAddrs[to].Address, data); err != nil {
+						t.Fatalf("write(_, %s, _) from host %d: %s", innerAddrs[to].Address, from, err)
+					}
+					got, src, ok := read(t, eps[to], readTimeout)
+					if !ok {
+						t.Fatalf("host %d did not receive a packet from host %d", to, from)
+					}
+					if !bytes.Equal(got, data) {
+						t.Errorf("got data = %x, want = %x", got, data)
+					}
+					if src != innerAddrs[from].Address {
+						t.Errorf("got source address = %s, want = %s", src, innerAddrs[from].Address)
+					}
+				}
+			}
+
+			for i, h := range hosts {
+				if got := h.tunnel.Stats().InvalidPacketsReceived.Value(); got != 0 {
+					t.Errorf("got hosts[%d].tunnel.Stats().InvalidPacketsReceived.Value() = %d, want = 0", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTunnelMismatchedOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        [2]tunnel.Options
+		wantInvalid uint64
+	}{
+		{
+			name: "key mismatch",
+			opts: [2]tunnel.Options{
+				{OFlags: header.GREFlagKey, OKey: 1},
+				{IFlags: header.GREFlagKey, IKey: 2},
+			},
+		},
+		{
+			name: "unexpected key",
+			opts: [2]tunnel.Options{{OFlags: header.GREFlagKey, OKey: 1}, {}},
+		},
+		{
+			name:        "missing checksum",
+			opts:        [2]tunnel.Options{{}, {IFlags: header.GREFlagChecksum}},
+			wantInvalid: 1,
+		},
+		{
+			name:        "unexpected checksum",
+			opts:        [2]tunnel.Options{{OFlags: header.GREFlagChecksum}, {}},
+			wantInvalid: 1,
+		},
+		{
+			name:        "missing sequence number",
+			opts:        [2]tunnel.Options{{}, {IFlags: header.GREFlagSequence}},
+			wantInvalid: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hosts := setup(t, outerAddrs4, test.opts)
+			sender := newUDPEndpoint(t, hosts[0].stack, innerAddrs[0].Address)
+			receiver := newUDPEndpoint(t, hosts[1].stack, innerAddrs[1].Address)
+
+			if err := write(sender, innerAddrs[1].Address, []byte{1, 2, 3, 4}); err != nil {
+				t.Fatalf("write(_, %s, _): %s", innerAddrs[1].Address, err)
+			}
+			if got, _, ok := read(t, receiver, 0); ok {
+				t.Errorf("unexpectedly received %x", got)
+			}
+			if got := hosts[1].tunnel.Stats().InvalidPacketsReceived.Value(); got != test.wantInvalid {
+				t.Errorf("got InvalidPacketsReceived.Value() = %d, want = %d", got, test.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestTunnelCorruptChecksum(t *testing.T) {
+	hosts := setup(t, outerAddrs4, [2]tunnel.Options{{}, {IFlags: header.GREFlagChecksum}})
+
+	// Send a GRE packet whose checksum does not cover its payload.
+	r, err := hosts[0].stack.FindRoute(linkNICID, outerAddrs4[0].Address, outerAddrs4[1].Address, header.IPv4ProtocolNumber, false /* multicastLoop */)
+	if err != nil {
+		t.Fatalf("FindRoute(...): %s", err)
+	}
+	defer r.Release()
+	gre := header.GRE(make([]byte, header.GREHeaderSize(header.GREFlagChecksum)))
+	gre.Encode(&header.GREFields{Flags: header.GREFlagChecksum, ProtocolType: header.IPv4ProtocolNumber})
+	gre.SetChecksum(0x1234)
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(r.MaxHeaderLength()),
+		Data:               buffer.NewVectorisedView(len(gre)+4, []buffer.View{buffer.View(gre), {1, 2, 3, 4}}),
+	})
+	if err := r.WritePacket(stack.NetworkHeaderParams{Protocol: header.GREProtocolNumber, TTL: 64}, pkt); err != nil {
+		t.Fatalf("r.WritePacket(...): %s", err)
+	}
+
+	if got := hosts[1].tunnel.Stats().InvalidPacketsReceived.Value(); got != 1 {
+		t.Errorf("got InvalidPacketsReceived.Value() = %d, want = 1", got)
+	}
+}
+
+func TestTunnelKeepalive(t *testing.T) {
+	tests := []struct {
+		name string
+		opts [2]tunnel.Options
+	}{
+		{
+			name: "gre",
+		},
+		{
+			name: "gre with options",
+			opts: [2]tunnel.Options{
+				{IFlags: header.GREFlagKey | header.GREFlagChecksum | header.GREFlagSequence, OFlags: header.GREFlagKey, IKey: 1, OKey: 2},
+				{IFlags: header.GREFlagKey, OFlags: header.GREFlagKey | header.GREFlagChecksum | header.GREFlagSequence, IKey: 2, OKey: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hosts := setup(t, outerAddrs4, test.opts)
+
+			for i := 1; i <= 2; i++ {
+				if err := hosts[0].tunnel.SendKeepalive(); err != nil {
+					t.Fatalf("SendKeepalive(): %s", err)
+				}
+				if got := hosts[0].tunnel.Stats().KeepalivesSent.Value(); got != uint64(i) {
+					t.Errorf("got KeepalivesSent.Value() = %d, want = %d", got, i)
+				}
+				if got := hosts[1].tunnel.Stats().KeepalivesReflected.Value(); got != uint64(i) {
+					t.Errorf("got KeepalivesReflected.Value() = %d, want = %d", got, i)
+				}
+				if got := hosts[0].tunnel.Stats().KeepalivesReceived.Value(); got != uint64(i) {
+					t.Errorf("got KeepalivesReceived.Value() = %d, want = %d", got, i)
+				}
+			}
+		})
+	}
+}
+
+func TestTunnelKeepaliveNotSupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer [2]tcpip.AddressWithPrefix
+		opts  [2]tunnel.Options
+	}{
+		{
+			name:  "ip6gre",
+			outer: outerAddrs6,
+		},
+		{
+			name:  "ipip",
+			outer: outerAddrs4,
+			opts:  [2]tunnel.Options{{Mode: tunnel.ModeIPIP}, {Mode: tunnel.ModeIPIP}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hosts := setup(t, test.outer, test.opts)
+			err := hosts[0].tunnel.SendKeepalive()
+			if _, ok := err.(*tcpip.ErrNotSupported); !ok {
+				t.Errorf("got SendKeepalive() = %v, want = %s", err, &tcpip.ErrNotSupported{})
+			}
+		})
+	}
+}
+
+// sendFragmentationNeeded makes from send an ICMP Fragmentation Needed error
+// for a GRE packet sent to it by to.
+func sendFragmentationNeeded(t *testing.T, from *stack.Stack, fromAddr, toAddr tcpip.Address, mtu uint16) {
+	t.Helper()
+
+	r, err := from.FindRoute(linkNICID, fromAddr, toAddr, header.IPv4ProtocolNumber, false /* multicastLoop */)
+	if err != nil {
+		t.Fatalf("FindRoute(...): %s", err)
+	}
+	defer r.Release()
+
+	icmp := header.ICMPv4(make([]byte, header.ICMPv4MinimumSize+header.IPv4MinimumSize+8))
+	icmp.SetType(header.ICMPv4DstUnreachable)
+	icmp.SetCode(header.ICMPv4FragmentationNeeded)
+	icmp.SetMTU(mtu)
+	header.IPv4(icmp[header.ICMPv4MinimumSize:]).Encode(&header.IPv4Fields{
+		TotalLength: uint16(mtu) + 1,
+		Flags:       header.IPv4FlagDontFragment,
+		TTL:         64,
+		Protocol:    uint8(header.GREProtocolNumber),
+		SrcAddr:     toAddr,
+		DstAddr:     fromAddr,
+	})
+	icmp.SetChecksum(^header.Checksum(icmp, 0))
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(r.MaxHeaderLength()),
+		Data:               buffer.View(icmp).ToVectorisedView(),
+	})
+	if err := r.WritePacket(stack.NetworkHeaderParams{Protocol: header.ICMPv4ProtocolNumber, TTL: 64}, pkt); err != nil {
+		t.Fatalf("r.WritePacket(...): %s", err)
+	}
+}
+
+func TestTunnelPathMTU(t *testing.T) {
+	const pathMTU = 1000
+
+	hosts := setup(t, outerAddrs4, [2]tunnel.Options{})
+	sender := newUDPEndpoint(t, hosts[0].stack, innerAddrs[0].Address)
+	receiver := newUDPEndpoint(t, hosts[1].stack, innerAddrs[1].Address)
+
+	// Capture the packets carrying the tunneled packets to check their DF bit.
+	var wq waiter.Queue
+	rawEP, err := hosts[1].stack.NewRawEndpoint(header.GREProtocolNumber, header.IPv4ProtocolNumber, &wq, true /* associated */)
+	if err != nil {
+		t.Fatalf("NewRawEndpoint(%d, %d, _, true): %s", header.GREProtocolNumber, header.IPv4ProtocolNumber, err)
+	}
+	defer rawEP.Close()
+	checkOuterFlags := func(flags uint8) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if _, err := rawEP.Read(&buf, tcpip.ReadOptions{}); err != nil {
+			t.Fatalf("rawEP.Read(_, _): %s", err)
+		}
+		checker.IPv4(t, buf.Bytes(),
+			checker.SrcAddr(outerAddrs4[0].Address),
+			checker.DstAddr(outerAddrs4[1].Address),
+			checker.FragmentFlags(flags),
+		)
+	}
+
+	checkMTU := func(want uint32) {
+		t.Helper()
+
+		if got := hosts[0].stack.NICInfo()[tunnelNICID].MTU; got != want {
+			t.Errorf("got tunnel MTU = %d, want = %d", got, want)
+		}
+	}
+	checkMTU(header.IPv6MinimumMTU - header.IPv4MinimumSize - header.GREMinimumSize)
+
+	sendFragmentationNeeded(t, hosts[1].stack, outerAddrs4[1].Address, outerAddrs4[0].Address, pathMTU)
+	tunnelMTU := uint32(pathMTU - header.IPv4MinimumSize - header.GREMinimumSize)
+	checkMTU(tunnelMTU)
+
+	maxPayload := int(tunnelMTU) - header.IPv4MinimumSize - header.UDPMinimumSize
+
+	// With path MTU discovery, tunneled packets larger than the tunnel MTU are
+	// rejected, and the DF bit of the tunneled packets is carried over to the
+	// packets carrying them.
+	if err := sender.SetSockOptInt(tcpip.MTUDiscoverOption, tcpip.PMTUDiscoveryDo); err != nil {
+		t.Fatalf("SetSockOptInt(MTUDiscoverOption, PMTUDiscoveryDo): %s", err)
+	}
+	err = write(sender, innerAddrs[1].Address, make([]byte, maxPayload+1))
+	if _, ok := err.(*tcpip.ErrMessageTooLong); !ok {
+		t.Errorf("got write(_, _, %d bytes) = %v, want = %s", maxPayload+1, err, &tcpip.ErrMessageTooLong{})
+	}
+	if err := write(sender, innerAddrs[1].Address, make([]byte, maxPayload)); err != nil {
+		t.Fatalf("write(_, _, %d bytes): %s", maxPayload, err)
+	}
+	if got, _, ok := read(t, receiver, readTimeout); !ok || len(got) != maxPayload {
+		t.Errorf("got read(...) = (%d bytes, _, %t), want = (%d bytes, _, true)", len(got), ok, maxPayload)
+	}
+	checkOuterFlags(header.IPv4FlagDontFragment)
+
+	// Without it, tunneled packets are fragmented to fit the tunnel, and the
+	// packets carrying them may be fragmented on their way.
+	if err := sender.SetSockOptInt(tcpip.MTUDiscoverOption, tcpip.PMTUDiscoveryDont); err != nil {
+		t.Fatalf("SetSockOptInt(MTUDiscoverOption, PMTUDiscoveryDont): %s", err)
+	}
+	data := make([]byte, pathMTU)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := write(sender, innerAddrs[1].Address, data); err != nil {
+		t.Fatalf("write(_, _, %d bytes): %s", len(data), err)
+	}
+	if got, _, ok := read(t, receiver, readTimeout); !ok || !bytes.Equal(got, data) {
+		t.Errorf("got read(...) = (%d bytes, _, %t), want = (%x, _, true)", len(got), ok, data)
+	}
+	checkOuterFlags(0)
+}
@@ -44,6 +44,28 @@ func (*icmpv4DestinationUnreachableSockError) Info() uint32 {
 	return 0
 }
 
+var _ stack.TransportError = (*icmpv4DestinationNetworkUnreachableSockError)(nil)
+
+// icmpv4DestinationNetworkUnreachableSockError is an ICMPv4 Destination
+// Network Unreachable error.
+//
+// It indicates that the destination network is unreachable.
+//
+// +stateify savable
+type icmpv4DestinationNetworkUnreachableSockError struct {
+	icmpv4DestinationUnreachableSockError
+}
+
+// Code implements tcpip.SockErrorCause.
+func (*icmpv4DestinationNetworkUnreachableSockError) Code() uint8 {
+	return uint8(header.ICMPv4NetUnreachable)
+}
+
+// Kind implements stack.TransportError.
+func (*icmpv4DestinationNetworkUnreachableSockError) Kind() stack.TransportErrorKind {
+	return stack.DestinationNetworkUnreachableTransportError
+}
+
 var _ stack.TransportError = (*icmpv4DestinationHostUnreachableSockError)(nil)
 
 // icmpv4DestinationHostUnreachableSockError is an ICMPv4 Destination Host
@@ -340,6 +362,8 @@ func (e *endpoint) handleICMP(pkt *stack.PacketBuffer) {
 		code := h.Code()
 		pkt.Data().DeleteFront(header.ICMPv4MinimumSize)
 		switch code {
+		case header.ICMPv4NetUnreachable:
+			e.handleControl(&icmpv4DestinationNetworkUnreachableSockError{}, pkt)
 		case header.ICMPv4HostUnreachable:
 			e.handleControl(&icmpv4DestinationHostUnreachableSockError{}, pkt)
 		case header.ICMPv4PortUnreachable:
@@ -369,12 +369,17 @@ func (e *endpoint) addIPHeader(srcAddr, dstAddr tcpip.Address, pkt *stack.Packet
 		return &tcpip.ErrMessageTooLong{}
 	}
 	// RFC 6864 section 4.3 mandates uniqueness of ID values for non-atomic
-	// datagrams. Since the DF bit is never being set here, all datagrams
-	// are non-atomic and need an ID.
+	// datagrams. Atomic datagrams (those with the DF bit set) are given an ID
+	// as well, as Linux does.
 	id := atomic.AddUint32(&e.protocol.ids[hashRoute(srcAddr, dstAddr, params.Protocol, e.protocol.hashIV)%buckets], 1)
+	var flags uint8
+	if params.DF {
+		flags = header.IPv4FlagDontFragment
+	}
 	ipH.Encode(&header.IPv4Fields{
 		TotalLength: uint16(length),
 		ID:          uint16(id),
+		Flags:       flags,
 		TTL:         params.TTL,
 		TOS:         params.TOS,
 		Protocol:    uint8(params.Protocol),
@@ -387,6 +392,17 @@ func (e *endpoint) addIPHeader(srcAddr, dstAddr tcpip.Address, pkt *stack.Packet
 	return nil
 }
 
+// pathMTU returns the MTU, including the IP header, of the path to the
+// destination of r. This is the MTU of the NIC unless a smaller path MTU was
+// learned from an ICMP Fragmentation Needed error.
+func (e *endpoint) pathMTU(r *stack.Route) uint32 {
+	mtu := e.nic.MTU()
+	if pmtu, ok := e.protocol.stack.PathMTU(ProtocolNumber, r.RemoteAddress()); ok && pmtu+header.IPv4MinimumSize < mtu {
+		return pmtu + header.IPv4MinimumSize
+	}
+	return mtu
+}
+
 // handleFragments fragments pkt and calls the handler function on each
 // fragment. It returns the number of fragments handled and the number of
 // fragments left to be processed. The IP header must already be present in the
@@ -467,17 +483,26 @@ func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer, headerIn
 
 	stats := e.stats.ip
 
-	networkMTU, err := calculateNetworkMTU(e.nic.MTU(), uint32(pkt.NetworkHeader().View().Size()))
+	h := header.IPv4(pkt.NetworkHeader().View())
+	mtu := e.nic.MTU()
+	if !headerIncluded && h.Flags()&header.IPv4FlagDontFragment == 0 {
+		// Packets with DF set were sized against the path MTU by the
+		// transport layer, which may also choose to ignore it, so they are
+		// only checked against the link MTU. Other packets are fragmented to
+		// fit the path MTU, unless their header was written by the caller.
+		mtu = e.pathMTU(r)
+	}
+	networkMTU, err := calculateNetworkMTU(mtu, uint32(len(h)))
 	if err != nil {
 		stats.OutgoingPacketErrors.Increment()
 		return err
 	}
 
 	if packetMustBeFragmented(pkt, networkMTU) {
-		h := header.IPv4(pkt.NetworkHeader().View())
-		if h.Flags()&header.IPv4FlagDontFragment != 0 && pkt.NetworkPacketInfo.IsForwardedPacket {
-			// TODO(gvisor.dev/issue/5919): Handle error condition in which DontFragment
-			// is set but the packet must be fragmented for the non-forwarding case.
+		if h.Flags()&header.IPv4FlagDontFragment != 0 {
+			if !pkt.NetworkPacketInfo.IsForwardedPacket {
+				stats.OutgoingPacketErrors.Increment()
+			}
 			return &tcpip.ErrMessageTooLong{}
 		}
 		sent, remain, err := e.handleFragments(r, networkMTU, pkt, func(fragPkt *stack.PacketBuffer) tcpip.Error {
@@ -516,13 +541,21 @@ func (e *endpoint) WritePackets(r *stack.Route, pkts stack.PacketBufferList, par
 			return 0, err
 		}
 
-		networkMTU, err := calculateNetworkMTU(e.nic.MTU(), uint32(pkt.NetworkHeader().View().Size()))
+		mtu := e.nic.MTU()
+		if !params.DF {
+			mtu = e.pathMTU(r)
+		}
+		networkMTU, err := calculateNetworkMTU(mtu, uint32(pkt.NetworkHeader().View().Size()))
 		if err != nil {
 			stats.OutgoingPacketErrors.IncrementBy(uint64(pkts.Len()))
 			return 0, err
 		}
 
 		if packetMustBeFragmented(pkt, networkMTU) {
+			if params.DF {
+				stats.OutgoingPacketErrors.IncrementBy(uint64(pkts.Len()))
+				return 0, &tcpip.ErrMessageTooLong{}
+			}
 			// Keep track of the packet that is about to be fragmented so it can be
 			// removed once the fragmentation is done.
 			originalPkt := pkt
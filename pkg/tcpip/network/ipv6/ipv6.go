@@ -691,6 +691,17 @@ func addIPHeader(srcAddr, dstAddr tcpip.Address, pkt *stack.PacketBuffer, params
 	return nil
 }
 
+// pathMTU returns the MTU, including the IP header, of the path to the
+// destination of r. This is the MTU of the NIC unless a smaller path MTU was
+// learned from an ICMPv6 Packet Too Big error.
+func (e *endpoint) pathMTU(r *stack.Route) uint32 {
+	mtu := e.nic.MTU()
+	if pmtu, ok := e.protocol.stack.PathMTU(ProtocolNumber, r.RemoteAddress()); ok && pmtu+header.IPv6MinimumSize < mtu {
+		return pmtu + header.IPv6MinimumSize
+	}
+	return mtu
+}
+
 func packetMustBeFragmented(pkt *stack.PacketBuffer, networkMTU uint32) bool {
 	payload := pkt.TransportHeader().View().Size() + pkt.Data().Size()
 	return pkt.GSOOptions.Type == stack.GSONone && uint32(payload) > networkMTU
@@ -771,10 +782,13 @@ func (e *endpoint) WritePacket(r *stack.Route, params stack.NetworkHeaderParams,
 		}
 	}
 
-	return e.writePacket(r, pkt, params.Protocol, false /* headerIncluded */)
+	return e.writePacket(r, pkt, params.Protocol, false /* headerIncluded */, params.DF)
 }
 
-func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer, protocol tcpip.TransportProtocolNumber, headerIncluded bool) tcpip.Error {
+// writePacket writes pkt, fragmenting it if needed. If dontFragment is true,
+// a packet that does not fit in the NIC's MTU is rejected with
+// ErrMessageTooLong instead.
+func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer, protocol tcpip.TransportProtocolNumber, headerIncluded, dontFragment bool) tcpip.Error {
 	if r.Loop()&stack.PacketLoop != 0 {
 		// If the packet was generated by the stack (not a raw/packet endpoint
 		// where a packet may be written with the header included), then we can
@@ -795,13 +809,26 @@ func (e *endpoint) writePacket(r *stack.Route, pkt *stack.PacketBuffer, protocol
 	}
 
 	stats := e.stats.ip
-	networkMTU, err := calculateNetworkMTU(e.nic.MTU(), uint32(pkt.NetworkHeader().View().Size()))
+	mtu := e.nic.MTU()
+	if !headerIncluded && !dontFragment {
+		// Packets that must not be fragmented were sized against the path
+		// MTU by the transport layer, which may also choose to ignore it, so
+		// they are only checked against the link MTU. Other packets are
+		// fragmented to fit the path MTU, unless their header was written by
+		// the caller.
+		mtu = e.pathMTU(r)
+	}
+	networkMTU, err := calculateNetworkMTU(mtu, uint32(pkt.NetworkHeader().View().Size()))
 	if err != nil {
 		stats.OutgoingPacketErrors.Increment()
 		return err
 	}
 
 	if packetMustBeFragmented(pkt, networkMTU) {
+		if dontFragment {
+			stats.OutgoingPacketErrors.Increment()
+			return &tcpip.ErrMessageTooLong{}
+		}
 		if pkt.NetworkPacketInfo.IsForwardedPacket {
 			// As per RFC 2460, section 4.5:
 			//   Unlike IPv4, fragmentation in IPv6 is performed only by source nodes,
@@ -839,18 +866,25 @@ func (e *endpoint) WritePackets(r *stack.Route, pkts stack.PacketBufferList, par
 	}
 
 	stats := e.stats.ip
-	linkMTU := e.nic.MTU()
+	mtu := e.nic.MTU()
+	if !params.DF {
+		mtu = e.pathMTU(r)
+	}
 	for pb := pkts.Front(); pb != nil; pb = pb.Next() {
 		if err := addIPHeader(r.LocalAddress(), r.RemoteAddress(), pb, params, nil /* extensionHeaders */); err != nil {
 			return 0, err
 		}
 
-		networkMTU, err := calculateNetworkMTU(linkMTU, uint32(pb.NetworkHeader().View().Size()))
+		networkMTU, err := calculateNetworkMTU(mtu, uint32(pb.NetworkHeader().View().Size()))
 		if err != nil {
 			stats.OutgoingPacketErrors.IncrementBy(uint64(pkts.Len()))
 			return 0, err
 		}
 		if packetMustBeFragmented(pb, networkMTU) {
+			if params.DF {
+				stats.OutgoingPacketErrors.IncrementBy(uint64(pkts.Len()))
+				return 0, &tcpip.ErrMessageTooLong{}
+			}
 			// Keep track of the packet that is about to be fragmented so it can be
 			// removed once the fragmentation is done.
 			originalPkt := pb
@@ -942,7 +976,7 @@ func (e *endpoint) WriteHeaderIncludedPacket(r *stack.Route, pkt *stack.PacketBu
 		return &tcpip.ErrMalformedHeader{}
 	}
 
-	return e.writePacket(r, pkt, proto, true /* headerIncluded */)
+	return e.writePacket(r, pkt, proto, true /* headerIncluded */, false /* dontFragment */)
 }
 
 // forwardPacket attempts to forward a packet to its final destination.
@@ -62,6 +62,15 @@ type SocketOptionsHandler interface {
 	// changed. The handler notifies the writers if the send buffer size is
 	// increased with setsockopt(2) for TCP endpoints.
 	WakeupWriters()
+
+	// OnSetRcvlowat is invoked when SO_RCVLOWAT is set for an endpoint. The
+	// handler is invoked with the requested value and returns the value to
+	// set.
+	OnSetRcvlowat(v int32) (newV int32)
+
+	// WakeupReaders is invoked when SO_RCVLOWAT is set for an endpoint. The
+	// handler notifies the readers if the endpoint has become readable.
+	WakeupReaders()
 }
 
 // DefaultSocketOptionsHandler is an embeddable type that implements no-op
@@ -111,6 +120,14 @@ func (*DefaultSocketOptionsHandler) OnSetReceiveBufferSize(v, oldSz int64) (newS
 	return v
 }
 
+// OnSetRcvlowat implements SocketOptionsHandler.OnSetRcvlowat.
+func (*DefaultSocketOptionsHandler) OnSetRcvlowat(v int32) (newV int32) {
+	return v
+}
+
+// WakeupReaders implements SocketOptionsHandler.WakeupReaders.
+func (*DefaultSocketOptionsHandler) WakeupReaders() {}
+
 // StackHandler holds methods to access the stack options. These must be
 // implemented by the stack.
 type StackHandler interface {
@@ -142,6 +159,10 @@ type SocketOptions struct {
 	// messages are enabled.
 	passCredEnabled uint32
 
+	// passSecEnabled determines whether SCM_SECURITY socket control messages
+	// are enabled.
+	passSecEnabled uint32
+
 	// noChecksumEnabled determines whether UDP checksum is disabled while
 	// transmitting for this socket.
 	noChecksumEnabled uint32
@@ -227,6 +248,11 @@ type SocketOptions struct {
 	// receiveBufferSize determines the receive buffer size for this socket.
 	receiveBufferSize atomicbitops.AlignedAtomicInt64
 
+	// rcvlowat is the minimum number of bytes that must be available before
+	// a stream socket is reported as readable, or 0 if SO_RCVLOWAT has never
+	// been set (which is equivalent to 1). It is accessed atomically.
+	rcvlowat int32
+
 	// mu protects the access to the below fields.
 	mu sync.Mutex `state:"nosave"`
 
@@ -277,6 +303,16 @@ func (so *SocketOptions) SetPassCred(v bool) {
 	storeAtomicBool(&so.passCredEnabled, v)
 }
 
+// GetPassSec gets value for SO_PASSSEC option.
+func (so *SocketOptions) GetPassSec() bool {
+	return atomic.LoadUint32(&so.passSecEnabled) != 0
+}
+
+// SetPassSec sets value for SO_PASSSEC option.
+func (so *SocketOptions) SetPassSec(v bool) {
+	storeAtomicBool(&so.passSecEnabled, v)
+}
+
 // GetNoChecksum gets value for SO_NO_CHECK option.
 func (so *SocketOptions) GetNoChecksum() bool {
 	return atomic.LoadUint32(&so.noChecksumEnabled) != 0
@@ -660,3 +696,22 @@ func (so *SocketOptions) SetReceiveBufferSize(receiveBufferSize int64, notify bo
 	}
 	so.receiveBufferSize.Store(receiveBufferSize)
 }
+
+// GetRcvlowat gets the value of the SO_RCVLOWAT option.
+func (so *SocketOptions) GetRcvlowat() int32 {
+	if v := atomic.LoadInt32(&so.rcvlowat); v != 0 {
+		return v
+	}
+	return 1
+}
+
+// SetRcvlowat sets the value of the SO_RCVLOWAT option. As in Linux, values
+// less than 1 are treated as 1.
+func (so *SocketOptions) SetRcvlowat(rcvlowat int32) {
+	rcvlowat = so.handler.OnSetRcvlowat(rcvlowat)
+	if rcvlowat < 1 {
+		rcvlowat = 1
+	}
+	atomic.StoreInt32(&so.rcvlowat, rcvlowat)
+	so.handler.WakeupReaders()
+}
@@ -30,6 +30,7 @@ const (
 	NATID TableID = iota
 	MangleID
 	FilterID
+	RawID
 	NumTables
 )
 
@@ -108,6 +109,27 @@ func DefaultTables(seed uint32) *IPTables {
 					Postrouting: HookUnset,
 				},
 			},
+			RawID: {
+				Rules: []Rule{
+					{Target: &AcceptTarget{NetworkProtocol: header.IPv4ProtocolNumber}},
+					{Target: &AcceptTarget{NetworkProtocol: header.IPv4ProtocolNumber}},
+					{Target: &ErrorTarget{NetworkProtocol: header.IPv4ProtocolNumber}},
+				},
+				BuiltinChains: [NumHooks]int{
+					Prerouting:  0,
+					Input:       HookUnset,
+					Forward:     HookUnset,
+					Output:      1,
+					Postrouting: HookUnset,
+				},
+				Underflows: [NumHooks]int{
+					Prerouting:  0,
+					Input:       HookUnset,
+					Forward:     HookUnset,
+					Output:      1,
+					Postrouting: HookUnset,
+				},
+			},
 		},
 		v6Tables: [NumTables]Table{
 			NATID: {
@@ -173,12 +195,33 @@ func DefaultTables(seed uint32) *IPTables {
 					Postrouting: HookUnset,
 				},
 			},
+			RawID: {
+				Rules: []Rule{
+					{Target: &AcceptTarget{NetworkProtocol: header.IPv6ProtocolNumber}},
+					{Target: &AcceptTarget{NetworkProtocol: header.IPv6ProtocolNumber}},
+					{Target: &ErrorTarget{NetworkProtocol: header.IPv6ProtocolNumber}},
+				},
+				BuiltinChains: [NumHooks]int{
+					Prerouting:  0,
+					Input:       HookUnset,
+					Forward:     HookUnset,
+					Output:      1,
+					Postrouting: HookUnset,
+				},
+				Underflows: [NumHooks]int{
+					Prerouting:  0,
+					Input:       HookUnset,
+					Forward:     HookUnset,
+					Output:      1,
+					Postrouting: HookUnset,
+				},
+			},
 		},
 		priorities: [NumHooks][]TableID{
-			Prerouting:  {MangleID, NATID},
+			Prerouting:  {RawID, MangleID, NATID},
 			Input:       {NATID, FilterID},
 			Forward:     {FilterID},
-			Output:      {MangleID, NATID, FilterID},
+			Output:      {RawID, MangleID, NATID, FilterID},
 			Postrouting: {MangleID, NATID},
 		},
 		connections: ConnTrack{
@@ -218,6 +261,24 @@ func EmptyNATTable() Table {
 	}
 }
 
+// EmptyRawTable returns a Table with no rules and the chains that the raw
+// table does not use mapped to HookUnset.
+func EmptyRawTable() Table {
+	return Table{
+		Rules: []Rule{},
+		BuiltinChains: [NumHooks]int{
+			Input:       HookUnset,
+			Forward:     HookUnset,
+			Postrouting: HookUnset,
+		},
+		Underflows: [NumHooks]int{
+			Input:       HookUnset,
+			Forward:     HookUnset,
+			Postrouting: HookUnset,
+		},
+	}
+}
+
 // GetTable returns a table with the given id and IP version. It panics when an
 // invalid id is provided.
 func (it *IPTables) GetTable(id TableID, ipv6 bool) Table {
@@ -281,49 +342,32 @@ func (it *IPTables) Check(hook Hook, pkt *PacketBuffer, r *Route, preroutingAddr
 		return true
 	}
 
+	// The raw table is traversed before connection tracking so that its
+	// rules can exempt packets from being tracked.
+	priorities := it.priorities[hook]
+	if len(priorities) > 0 && priorities[0] == RawID {
+		if !it.checkTable(hook, pkt, RawID, r, preroutingAddr, inNicName, outNicName) {
+			return false
+		}
+		priorities = priorities[1:]
+	}
+
 	// Packets are manipulated only if connection and matching
 	// NAT rule exists.
-	shouldTrack := it.connections.handlePacket(pkt, hook, r)
+	shouldTrack := false
+	if !pkt.Untracked {
+		shouldTrack = it.connections.handlePacket(pkt, hook, r)
+	}
 
-	// Go through each table containing the hook.
-	priorities := it.priorities[hook]
+	// Go through each remaining table containing the hook.
 	for _, tableID := range priorities {
 		// If handlePacket already NATed the packet, we don't need to
-		// check the NAT table.
-		if tableID == NATID && pkt.NatDone {
+		// check the NAT table. Untracked packets are never NATed.
+		if tableID == NATID && (pkt.NatDone || pkt.Untracked) {
 			continue
 		}
-		var table Table
-		if pkt.NetworkProtocolNumber == header.IPv6ProtocolNumber {
-			table = it.v6Tables[tableID]
-		} else {
-			table = it.v4Tables[tableID]
-		}
-		ruleIdx := table.BuiltinChains[hook]
-		switch verdict := it.checkChain(hook, pkt, table, ruleIdx, r, preroutingAddr, inNicName, outNicName); verdict {
-		// If the table returns Accept, move on to the next table.
-		case chainAccept:
-			continue
-		// The Drop verdict is final.
-		case chainDrop:
+		if !it.checkTable(hook, pkt, tableID, r, preroutingAddr, inNicName, outNicName) {
 			return false
-		case chainReturn:
-			// Any Return from a built-in chain means we have to
-			// call the underflow.
-			underflow := table.Rules[table.Underflows[hook]]
-			switch v, _ := underflow.Target.Action(pkt, &it.connections, hook, r, preroutingAddr); v {
-			case RuleAccept:
-				continue
-			case RuleDrop:
-				return false
-			case RuleJump, RuleReturn:
-				panic("Underflows should only return RuleAccept or RuleDrop.")
-			default:
-				panic(fmt.Sprintf("Unknown verdict: %d", v))
-			}
-
-		default:
-			panic(fmt.Sprintf("Unknown verdict %v.", verdict))
 		}
 	}
 
@@ -345,6 +389,45 @@ func (it *IPTables) Check(hook Hook, pkt *PacketBuffer, r *Route, preroutingAddr
 	return true
 }
 
+// checkTable runs pkt through the rules for hook in the table identified by
+// tableID. It returns true when the packet should continue on to the next
+// table and false when it should be dropped.
+//
+// Precondition: it.mu must be locked for reading.
+func (it *IPTables) checkTable(hook Hook, pkt *PacketBuffer, tableID TableID, r *Route, preroutingAddr tcpip.Address, inNicName, outNicName string) bool {
+	var table Table
+	if pkt.NetworkProtocolNumber == header.IPv6ProtocolNumber {
+		table = it.v6Tables[tableID]
+	} else {
+		table = it.v4Tables[tableID]
+	}
+	ruleIdx := table.BuiltinChains[hook]
+	switch verdict := it.checkChain(hook, pkt, table, ruleIdx, r, preroutingAddr, inNicName, outNicName); verdict {
+	// If the table returns Accept, move on to the next table.
+	case chainAccept:
+		return true
+	// The Drop verdict is final.
+	case chainDrop:
+		return false
+	case chainReturn:
+		// Any Return from a built-in chain means we have to
+		// call the underflow.
+		underflow := table.Rules[table.Underflows[hook]]
+		switch v, _ := underflow.Target.Action(pkt, &it.connections, hook, r, preroutingAddr); v {
+		case RuleAccept:
+			return true
+		case RuleDrop:
+			return false
+		case RuleJump, RuleReturn, RuleContinue:
+			panic("Underflows should only return RuleAccept or RuleDrop.")
+		default:
+			panic(fmt.Sprintf("Unknown verdict: %d", v))
+		}
+	default:
+		panic(fmt.Sprintf("Unknown verdict %v.", verdict))
+	}
+}
+
 // beforeSave is invoked by stateify.
 func (it *IPTables) beforeSave() {
 	// Ensure the reaper exits cleanly.
@@ -421,6 +504,12 @@ func (it *IPTables) checkChain(hook Hook, pkt *PacketBuffer, table Table, ruleId
 		case RuleReturn:
 			return chainReturn
 
+		case RuleContinue:
+			// The target acted on the packet without issuing a
+			// verdict, so move on to the next rule.
+			ruleIdx++
+			continue
+
 		case RuleJump:
 			// "Jumping" to the next rule just means we're
 			// continuing on down the list.
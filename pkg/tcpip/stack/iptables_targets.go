@@ -83,6 +83,19 @@ func (*ReturnTarget) Action(*PacketBuffer, *ConnTrack, Hook, *Route, tcpip.Addre
 	return RuleReturn, 0
 }
 
+// NoTrackTarget exempts packets from connection tracking. It is only valid in
+// the raw table, which is traversed before packets are tracked.
+type NoTrackTarget struct {
+	// NetworkProtocol is the network protocol the target is used with.
+	NetworkProtocol tcpip.NetworkProtocolNumber
+}
+
+// Action implements Target.Action.
+func (*NoTrackTarget) Action(pkt *PacketBuffer, _ *ConnTrack, _ Hook, _ *Route, _ tcpip.Address) (RuleVerdict, int) {
+	pkt.Untracked = true
+	return RuleContinue, 0
+}
+
 // RedirectTarget redirects the packet to this machine by modifying the
 // destination port/IP. Outgoing packets are redirected to the loopback device,
 // and incoming packets are redirected to the incoming interface (rather than
@@ -75,6 +75,10 @@ const (
 
 	// RuleReturn indicates the packet should return to the previous chain.
 	RuleReturn
+
+	// RuleContinue indicates the packet should continue on to the next rule
+	// in the current chain.
+	RuleContinue
 )
 
 // IPTables holds all the tables for a netstack.
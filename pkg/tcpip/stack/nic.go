@@ -853,6 +853,12 @@ func (n *nic) DeliverTransportError(local, remote tcpip.Address, net tcpip.Netwo
 
 	transProto := state.proto
 
+	// Remember the path MTU so that subsequent packets to the same destination
+	// are sized correctly, even if no endpoint is interested in the error.
+	if transErr != nil && transErr.Kind() == PacketTooBigTransportError && transErr.Info() != 0 {
+		n.stack.pathMTUs.update(net, remote, transErr.Info())
+	}
+
 	// ICMPv4 only guarantees that 8 bytes of the transport protocol will
 	// be present in the payload. We know that the ports are within the
 	// first 8 bytes for all known transport protocols.
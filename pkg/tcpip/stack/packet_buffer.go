@@ -130,6 +130,10 @@ type PacketBuffer struct {
 	// iptables rule.
 	NatDone bool
 
+	// Untracked indicates that the packet was exempted from connection
+	// tracking by an iptables rule in the raw table.
+	Untracked bool
+
 	// PktType indicates the SockAddrLink.PacketType of the packet as defined in
 	// https://www.man7.org/linux/man-pages/man7/packet.7.html.
 	PktType tcpip.PacketType
@@ -297,6 +301,7 @@ func (pk *PacketBuffer) Clone() *PacketBuffer {
 		GSOOptions:                   pk.GSOOptions,
 		NetworkProtocolNumber:        pk.NetworkProtocolNumber,
 		NatDone:                      pk.NatDone,
+		Untracked:                    pk.Untracked,
 		TransportProtocolNumber:      pk.TransportProtocolNumber,
 		PktType:                      pk.PktType,
 		NICID:                        pk.NICID,
@@ -338,6 +343,9 @@ func (pk *PacketBuffer) CloneToInbound() *PacketBuffer {
 	if pk.NatDone {
 		newPk.NatDone = true
 	}
+	// Packets looped back to the stack stay exempt from connection tracking,
+	// as their outbound counterparts were.
+	newPk.Untracked = pk.Untracked
 	return newPk
 }
 
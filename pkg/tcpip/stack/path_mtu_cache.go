@@ -0,0 +1,170 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// PathMTUExpiration is the duration for which a path MTU learned from an
+// ICMP Fragmentation Needed or Packet Too Big message is used before it is
+// discarded and the link MTU is used again.
+//
+// This matches Linux's default net.ipv4.route.mtu_expires.
+const PathMTUExpiration = 10 * time.Minute
+
+// minIPv4PathMTU is the smallest IPv4 path MTU, including the IPv4 header, that
+// is learned. Smaller MTUs are raised to it so that forged ICMP messages
+// cannot force excessive fragmentation.
+//
+// This matches Linux's default net.ipv4.route.min_pmtu.
+const minIPv4PathMTU = 552
+
+// maxPathMTUEntries is the maximum number of path MTUs that are remembered.
+// Path MTUs are learned from ICMP messages, which may be forged, so the cache
+// must be bounded. When it is full, the entry that expires first is evicted.
+const maxPathMTUEntries = 4096
+
+// pathMTUKey identifies a destination in the path MTU cache.
+type pathMTUKey struct {
+	netProto tcpip.NetworkProtocolNumber
+	addr     tcpip.Address
+}
+
+// pathMTUEntry is a learned path MTU.
+type pathMTUEntry struct {
+	key     pathMTUKey
+	mtu     uint32
+	expires tcpip.MonotonicTime
+}
+
+// pathMTUCache holds path MTUs learned from ICMP errors, keyed by
+// destination.
+type pathMTUCache struct {
+	clock tcpip.Clock
+
+	// size is the number of entries in the cache. It lets lookups, which
+	// happen for every outgoing packet, skip locking while the cache is empty.
+	//
+	// size is accessed using atomic memory operations, and is only mutated
+	// while holding mu.
+	size int32
+
+	mu struct {
+		sync.RWMutex
+
+		// entries maps each destination to its element in expiry.
+		entries map[pathMTUKey]*list.Element
+
+		// expiry holds the *pathMTUEntry values in the order in which they
+		// expire. Every entry lives for PathMTUExpiration, so this is also the
+		// order in which they were added.
+		expiry list.List
+	}
+}
+
+func (c *pathMTUCache) init(clock tcpip.Clock) {
+	c.clock = clock
+	c.mu.entries = make(map[pathMTUKey]*list.Element)
+}
+
+// update records mtu as the path MTU towards addr. mtu is the maximum size of
+// the network layer payload, excluding the network header. It is raised to
+// the protocol's minimum if needed.
+//
+// A path MTU is only ever lowered by an update; a larger value is ignored
+// until the current entry expires.
+func (c *pathMTUCache) update(netProto tcpip.NetworkProtocolNumber, addr tcpip.Address, mtu uint32) {
+	switch netProto {
+	case header.IPv4ProtocolNumber:
+		if min := uint32(minIPv4PathMTU - header.IPv4MinimumSize); mtu < min {
+			mtu = min
+		}
+	case header.IPv6ProtocolNumber:
+		if min := uint32(header.IPv6MinimumMTU - header.IPv6MinimumSize); mtu < min {
+			mtu = min
+		}
+	}
+
+	now := c.clock.NowMonotonic()
+	key := pathMTUKey{netProto: netProto, addr: addr}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.mu.entries[key]; ok {
+		if e := el.Value.(*pathMTUEntry); e.expires.After(now) && e.mtu <= mtu {
+			return
+		}
+		c.removeLocked(el)
+	}
+
+	// Drop expired entries, then make room for the new one if needed.
+	for el := c.mu.expiry.Front(); el != nil; el = c.mu.expiry.Front() {
+		if len(c.mu.entries) < maxPathMTUEntries && el.Value.(*pathMTUEntry).expires.After(now) {
+			break
+		}
+		c.removeLocked(el)
+	}
+
+	c.mu.entries[key] = c.mu.expiry.PushBack(&pathMTUEntry{
+		key:     key,
+		mtu:     mtu,
+		expires: now.Add(PathMTUExpiration),
+	})
+	atomic.StoreInt32(&c.size, int32(len(c.mu.entries)))
+}
+
+// removeLocked removes el from the cache.
+//
+// Preconditions: c.mu must be locked for writing.
+func (c *pathMTUCache) removeLocked(el *list.Element) {
+	delete(c.mu.entries, c.mu.expiry.Remove(el).(*pathMTUEntry).key)
+	atomic.StoreInt32(&c.size, int32(len(c.mu.entries)))
+}
+
+// get returns the path MTU towards addr, if one has been learned and has not
+// yet expired.
+func (c *pathMTUCache) get(netProto tcpip.NetworkProtocolNumber, addr tcpip.Address) (uint32, bool) {
+	if atomic.LoadInt32(&c.size) == 0 {
+		return 0, false
+	}
+	key := pathMTUKey{netProto: netProto, addr: addr}
+	now := c.clock.NowMonotonic()
+
+	// Expired entries are removed by update.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	el, ok := c.mu.entries[key]
+	if !ok {
+		return 0, false
+	}
+	e := el.Value.(*pathMTUEntry)
+	if !e.expires.After(now) {
+		return 0, false
+	}
+	return e.mtu, true
+}
+
+// PathMTU returns the path MTU learned for addr, excluding the network header.
+// It returns false if no path MTU has been learned or if it has expired.
+func (s *Stack) PathMTU(netProto tcpip.NetworkProtocolNumber, addr tcpip.Address) (uint32, bool) {
+	return s.pathMTUs.get(netProto, addr)
+}
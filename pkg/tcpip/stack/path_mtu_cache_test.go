@@ -0,0 +1,114 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/faketime"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+func TestPathMTUCache(t *testing.T) {
+	const (
+		addr1 = tcpip.Address("\x0a\x00\x00\x01")
+		addr2 = tcpip.Address("\x0a\x00\x00\x02")
+	)
+
+	clock := faketime.NewManualClock()
+	var c pathMTUCache
+	c.init(clock)
+
+	get := func(addr tcpip.Address, wantMTU uint32, wantOK bool) {
+		t.Helper()
+		if mtu, ok := c.get(header.IPv4ProtocolNumber, addr); mtu != wantMTU || ok != wantOK {
+			t.Errorf("got c.get(%d, %s) = (%d, %t), want = (%d, %t)", header.IPv4ProtocolNumber, addr, mtu, ok, wantMTU, wantOK)
+		}
+	}
+
+	get(addr1, 0, false)
+
+	c.update(header.IPv4ProtocolNumber, addr1, 1400)
+	get(addr1, 1400, true)
+	get(addr2, 0, false)
+	if mtu, ok := c.get(header.IPv6ProtocolNumber, addr1); ok {
+		t.Errorf("got c.get(%d, %s) = (%d, true), want = (_, false)", header.IPv6ProtocolNumber, addr1, mtu)
+	}
+
+	// A larger MTU does not replace a smaller one that has not expired.
+	clock.Advance(PathMTUExpiration / 2)
+	c.update(header.IPv4ProtocolNumber, addr1, 1480)
+	get(addr1, 1400, true)
+
+	// A smaller MTU does, and restarts the expiration timer.
+	c.update(header.IPv4ProtocolNumber, addr1, 1200)
+	clock.Advance(PathMTUExpiration - time.Nanosecond)
+	get(addr1, 1200, true)
+
+	clock.Advance(time.Nanosecond)
+	get(addr1, 0, false)
+
+	// Once expired, any MTU may be learned.
+	c.update(header.IPv4ProtocolNumber, addr1, 1480)
+	get(addr1, 1480, true)
+
+	// MTUs below the protocol minimum are raised to it.
+	c.update(header.IPv4ProtocolNumber, addr2, 100)
+	get(addr2, minIPv4PathMTU-header.IPv4MinimumSize, true)
+	c.update(header.IPv6ProtocolNumber, addr2, 1000)
+	if mtu, ok := c.get(header.IPv6ProtocolNumber, addr2); !ok || mtu != header.IPv6MinimumMTU-header.IPv6MinimumSize {
+		t.Errorf("got c.get(%d, %s) = (%d, %t), want = (%d, true)", header.IPv6ProtocolNumber, addr2, mtu, ok, header.IPv6MinimumMTU-header.IPv6MinimumSize)
+	}
+}
+
+func TestPathMTUCacheLimit(t *testing.T) {
+	clock := faketime.NewManualClock()
+	var c pathMTUCache
+	c.init(clock)
+
+	addr := func(i int) tcpip.Address {
+		return tcpip.Address([]byte{10, byte(i >> 16), byte(i >> 8), byte(i)})
+	}
+
+	// Expired entries are dropped when a new one is added.
+	c.update(header.IPv4ProtocolNumber, addr(0), 1400)
+	clock.Advance(PathMTUExpiration)
+	c.update(header.IPv4ProtocolNumber, addr(1), 1400)
+	if got := len(c.mu.entries); got != 1 {
+		t.Errorf("got %d entries after the first expired, want 1", got)
+	}
+	if got := c.mu.expiry.Len(); got != 1 {
+		t.Errorf("got expiry.Len() = %d after the first expired, want 1", got)
+	}
+
+	// The cache doesn't grow beyond its limit; the oldest entries are evicted.
+	for i := 2; i <= maxPathMTUEntries+1; i++ {
+		clock.Advance(time.Nanosecond)
+		c.update(header.IPv4ProtocolNumber, addr(i), 1400)
+	}
+	if got := len(c.mu.entries); got != maxPathMTUEntries {
+		t.Errorf("got %d entries, want %d", got, maxPathMTUEntries)
+	}
+	if _, ok := c.get(header.IPv4ProtocolNumber, addr(1)); ok {
+		t.Errorf("got c.get(_, %s) = (_, true) for the oldest entry, want = (_, false)", addr(1))
+	}
+	for _, i := range []int{2, maxPathMTUEntries + 1} {
+		if mtu, ok := c.get(header.IPv4ProtocolNumber, addr(i)); !ok || mtu != 1400 {
+			t.Errorf("got c.get(_, %s) = (%d, %t), want = (1400, true)", addr(i), mtu, ok)
+		}
+	}
+}
@@ -106,9 +106,11 @@ type TransportEndpoint interface {
 	HandlePacket(TransportEndpointID, *PacketBuffer)
 
 	// HandleError is called when the transport endpoint receives an error.
+	// The TransportEndpointID is that of the packet that caused the error, as
+	// reported in the error message.
 	//
 	// HandleError takes ownership of the packet buffer.
-	HandleError(TransportError, *PacketBuffer)
+	HandleError(TransportEndpointID, TransportError, *PacketBuffer)
 
 	// Abort initiates an expedited endpoint teardown. It puts the endpoint
 	// in a closed state and frees all resources associated with it. This
@@ -295,6 +297,11 @@ type NetworkHeaderParams struct {
 
 	// TOS refers to TypeOfService or TrafficClass field of the IP-header.
 	TOS uint8
+
+	// DF indicates that the Don't Fragment flag should be set in the IPv4
+	// header. Such packets are rejected with ErrMessageTooLong rather than
+	// fragmented if they do not fit in the MTU of the outgoing interface.
+	DF bool
 }
 
 // GroupAddressableEndpoint is an endpoint that supports group addressing.
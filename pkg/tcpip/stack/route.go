@@ -487,11 +487,69 @@ func (r *Route) DefaultTTL() uint8 {
 	return r.outgoingNIC.getNetworkEndpoint(r.NetProto()).DefaultTTL()
 }
 
-// MTU returns the MTU of the underlying network endpoint.
+// MTU returns the MTU of the route, which is the MTU of the underlying network
+// endpoint or the path MTU learned for the remote address, whichever is lower.
 func (r *Route) MTU() uint32 {
+	mtu := r.LinkMTU()
+	if pmtu, ok := r.outgoingNIC.stack.pathMTUs.get(r.NetProto(), r.RemoteAddress()); ok && pmtu < mtu {
+		return pmtu
+	}
+	return mtu
+}
+
+// LinkMTU returns the MTU of the underlying network endpoint, ignoring any path
+// MTU learned for the remote address.
+func (r *Route) LinkMTU() uint32 {
 	return r.outgoingNIC.getNetworkEndpoint(r.NetProto()).MTU()
 }
 
+// PathMTU returns the MTU of the route including the network header, as
+// reported by the IP_MTU and IPV6_MTU socket options.
+func (r *Route) PathMTU() uint32 {
+	return r.MTU() + r.networkHeaderSize()
+}
+
+func (r *Route) networkHeaderSize() uint32 {
+	switch r.NetProto() {
+	case header.IPv4ProtocolNumber:
+		return header.IPv4MinimumSize
+	case header.IPv6ProtocolNumber:
+		return header.IPv6MinimumSize
+	default:
+		return 0
+	}
+}
+
+// DontFragment reports whether a packet carrying size bytes of network payload
+// should be sent with fragmentation disallowed, according to pmtud, a
+// tcpip.MTUDiscoverOption setting.
+//
+// If fragmentation is disallowed but the packet does not fit, it returns
+// ErrMessageTooLong along with the MTU that was exceeded, including the
+// network header.
+func (r *Route) DontFragment(pmtud int, size int) (bool, uint32, tcpip.Error) {
+	switch pmtud {
+	case tcpip.PMTUDiscoveryWant:
+		// Let the packet be fragmented locally if it is larger than the path
+		// MTU, otherwise forbid fragmentation along the path so that we learn
+		// about smaller MTUs.
+		return uint32(size) <= r.MTU(), 0, nil
+	case tcpip.PMTUDiscoveryDo:
+		if mtu := r.MTU(); uint32(size) > mtu {
+			return true, mtu + r.networkHeaderSize(), &tcpip.ErrMessageTooLong{}
+		}
+		return true, 0, nil
+	case tcpip.PMTUDiscoveryProbe:
+		// Ignore the path MTU, but never send more than the interface can.
+		if mtu := r.LinkMTU(); uint32(size) > mtu {
+			return true, mtu + r.networkHeaderSize(), &tcpip.ErrMessageTooLong{}
+		}
+		return true, 0, nil
+	default:
+		return false, 0, nil
+	}
+}
+
 // Release decrements the reference counter of the resources associated with the
 // route.
 func (r *Route) Release() {
@@ -119,6 +119,9 @@ type Stack struct {
 	// by the stack.
 	icmpRateLimiter *ICMPRateLimiter
 
+	// pathMTUs holds the path MTUs learned from ICMP errors.
+	pathMTUs pathMTUCache
+
 	// seed is a one-time random value initialized at stack startup
 	// and is used to seed the TCP port picking on active connections
 	//
@@ -386,6 +389,8 @@ func New(opts Options) *Stack {
 		tcpInvalidRateLimit: defaultTCPInvalidRateLimit,
 	}
 
+	s.pathMTUs.init(clock)
+
 	// Add specified network protocols.
 	for _, netProtoFactory := range opts.NetworkProtocols {
 		netProto := netProtoFactory(s)
@@ -723,6 +728,22 @@ func (s *Stack) GetLinkEndpointByName(name string) LinkEndpoint {
 	return nil
 }
 
+// NICLinkEndpoint returns the link endpoint of the NIC with the given ID, along
+// with the dispatcher the endpoint must be attached to for the NIC to receive
+// packets.
+//
+// This lets link endpoints that aggregate NICs, such as bonds, take over the
+// link endpoint of a NIC and later give it back.
+func (s *Stack) NICLinkEndpoint(id tcpip.NICID) (LinkEndpoint, NetworkDispatcher, tcpip.Error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nic, ok := s.nics[id]
+	if !ok {
+		return nil, nil, &tcpip.ErrUnknownNICID{}
+	}
+	return nic.LinkEndpoint, nic, nil
+}
+
 // EnableNIC enables the given NIC so that the link-layer endpoint can start
 // delivering packets to it.
 func (s *Stack) EnableNIC(id tcpip.NICID) tcpip.Error {
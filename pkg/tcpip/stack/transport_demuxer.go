@@ -200,7 +200,7 @@ func (epsByNIC *endpointsByNIC) handleError(n *nic, id TransportEndpointID, tran
 	// broadcast like we are doing with handlePacket above?
 
 	// multiPortEndpoints are guaranteed to have at least one element.
-	selectEndpoint(id, mpep, epsByNIC.seed).HandleError(transErr, pkt)
+	selectEndpoint(id, mpep, epsByNIC.seed).HandleError(id, transErr, pkt)
 }
 
 // registerEndpoint returns true if it succeeds. It fails and returns
@@ -708,11 +708,14 @@ const (
 
 	// MTUDiscoverOption is used to set/get the path MTU discovery setting.
 	//
-	// NOTE: Setting this option to any other value than PMTUDiscoveryDont
-	// is not supported and will fail as such, and getting this option will
-	// always return PMTUDiscoveryDont.
+	// NOTE: TCP endpoints accept all settings but never set the Don't
+	// Fragment flag on outgoing segments.
 	MTUDiscoverOption
 
+	// MTUOption is used by GetSockOptInt to get the path MTU, including the
+	// network header, of a connected endpoint.
+	MTUOption
+
 	// MulticastTTLOption is used by SetSockOptInt/GetSockOptInt to control
 	// the default TTL value for multicast messages. The default is 1.
 	MulticastTTLOption
@@ -945,6 +948,14 @@ type TCPInfoOption struct {
 
 	// ReorderSeen indicates if reordering is seen in the endpoint.
 	ReorderSeen bool
+
+	// AcceptQueueLen is the number of established connections waiting to
+	// be accepted. It is only set for listening endpoints.
+	AcceptQueueLen uint32
+
+	// AcceptQueueCap is the capacity of the accept queue. It is only set
+	// for listening endpoints.
+	AcceptQueueCap uint32
 }
 
 func (*TCPInfoOption) isGettableSocketOption() {}
@@ -15,6 +15,8 @@
 package iptables_test
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/tcpip"
@@ -22,12 +24,14 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/checker"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/tcpip/tests/utils"
 	"gvisor.dev/gvisor/pkg/tcpip/testutil"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 type inputIfNameMatcher struct {
@@ -1132,3 +1136,178 @@ func TestInputHookWithLocalForwarding(t *testing.T) {
 		})
 	}
 }
+
+// setupRawTable replaces the raw table with one that runs target on every
+// packet at the Prerouting and Output hooks before accepting it.
+func setupRawTable(tb testing.TB, s *stack.Stack, target stack.Target, ipv6 bool) {
+	tb.Helper()
+	netProto := header.IPv4ProtocolNumber
+	if ipv6 {
+		netProto = header.IPv6ProtocolNumber
+	}
+	raw := stack.EmptyRawTable()
+	raw.Rules = []stack.Rule{
+		{Target: target},
+		{Target: &stack.AcceptTarget{NetworkProtocol: netProto}},
+		{Target: target},
+		{Target: &stack.AcceptTarget{NetworkProtocol: netProto}},
+		{Target: &stack.ErrorTarget{NetworkProtocol: netProto}},
+	}
+	raw.BuiltinChains[stack.Prerouting] = 0
+	raw.Underflows[stack.Prerouting] = 1
+	raw.BuiltinChains[stack.Output] = 2
+	raw.Underflows[stack.Output] = 3
+	if err := s.IPTables().ReplaceTable(stack.RawID, raw, ipv6); err != nil {
+		tb.Fatalf("ReplaceTable(%d, _, %t): %s", stack.RawID, ipv6, err)
+	}
+}
+
+func TestRawTableNoTrack(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupStack    func(*testing.T) (*stack.Stack, *channel.Endpoint)
+		genPacket     func() *stack.PacketBuffer
+		proto         tcpip.NetworkProtocolNumber
+		notrack       bool
+		expectDropped uint64
+	}{
+		{
+			name:          "IPv4 tracked",
+			setupStack:    genStackV4,
+			genPacket:     genPacketV4,
+			proto:         header.IPv4ProtocolNumber,
+			notrack:       false,
+			expectDropped: 1,
+		},
+		{
+			name:          "IPv4 untracked",
+			setupStack:    genStackV4,
+			genPacket:     genPacketV4,
+			proto:         header.IPv4ProtocolNumber,
+			notrack:       true,
+			expectDropped: 0,
+		},
+		{
+			name:          "IPv6 tracked",
+			setupStack:    genStackV6,
+			genPacket:     genPacketV6,
+			proto:         header.IPv6ProtocolNumber,
+			notrack:       false,
+			expectDropped: 1,
+		},
+		{
+			name:          "IPv6 untracked",
+			setupStack:    genStackV6,
+			genPacket:     genPacketV6,
+			proto:         header.IPv6ProtocolNumber,
+			notrack:       true,
+			expectDropped: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, e := test.setupStack(t)
+			ipv6 := test.proto == header.IPv6ProtocolNumber
+
+			// Drop everything that reaches the NAT table, which untracked
+			// packets must skip.
+			ipt := s.IPTables()
+			nat := ipt.GetTable(stack.NATID, ipv6)
+			nat.Rules[nat.BuiltinChains[stack.Prerouting]].Target = &stack.DropTarget{NetworkProtocol: test.proto}
+			if err := ipt.ReplaceTable(stack.NATID, nat, ipv6); err != nil {
+				t.Fatalf("ipt.ReplaceTable(%d, _, %t): %s", stack.NATID, ipv6, err)
+			}
+
+			var target stack.Target = &stack.AcceptTarget{NetworkProtocol: test.proto}
+			if test.notrack {
+				target = &stack.NoTrackTarget{NetworkProtocol: test.proto}
+			}
+			setupRawTable(t, s, target, ipv6)
+
+			e.InjectInbound(test.proto, test.genPacket())
+
+			if got := s.Stats().IP.IPTablesPreroutingDropped.Value(); got != test.expectDropped {
+				t.Errorf("got IPTablesPreroutingDropped = %d, want = %d", got, test.expectDropped)
+			}
+		})
+	}
+}
+
+// BenchmarkUDPEchoConntrack measures the cost of echoing a UDP datagram over
+// loopback with and without the datagrams being exempted from connection
+// tracking by the raw table.
+func BenchmarkUDPEchoConntrack(b *testing.B) {
+	const localPort = 80
+	data := []byte{1, 2, 3, 4}
+
+	for _, notrack := range []bool{false, true} {
+		b.Run(fmt.Sprintf("notrack=%t", notrack), func(b *testing.B) {
+			s := stack.New(stack.Options{
+				NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+				TransportProtocols: []stack.TransportProtocolFactory{udp.NewProtocol},
+			})
+			defer s.Close()
+			if err := s.CreateNIC(nicID, loopback.New()); err != nil {
+				b.Fatalf("CreateNIC(%d, _): %s", nicID, err)
+			}
+			if err := s.AddAddress(nicID, header.IPv4ProtocolNumber, utils.Ipv4Addr.Address); err != nil {
+				b.Fatalf("AddAddress(%d, %d, %s): %s", nicID, header.IPv4ProtocolNumber, utils.Ipv4Addr.Address, err)
+			}
+			s.SetRouteTable([]tcpip.Route{
+				{
+					Destination: header.IPv4EmptySubnet,
+					NIC:         nicID,
+				},
+			})
+
+			// Enable iptables in both cases so that only the NOTRACK rule
+			// differs.
+			var target stack.Target = &stack.AcceptTarget{NetworkProtocol: header.IPv4ProtocolNumber}
+			if notrack {
+				target = &stack.NoTrackTarget{NetworkProtocol: header.IPv4ProtocolNumber}
+			}
+			setupRawTable(b, s, target, false /* ipv6 */)
+
+			var wq waiter.Queue
+			server, err := s.NewEndpoint(udp.ProtocolNumber, header.IPv4ProtocolNumber, &wq)
+			if err != nil {
+				b.Fatalf("NewEndpoint(%d, %d, _): %s", udp.ProtocolNumber, header.IPv4ProtocolNumber, err)
+			}
+			defer server.Close()
+			serverAddr := tcpip.FullAddress{Addr: utils.Ipv4Addr.Address, Port: localPort}
+			if err := server.Bind(serverAddr); err != nil {
+				b.Fatalf("server.Bind(%+v): %s", serverAddr, err)
+			}
+
+			client, err := s.NewEndpoint(udp.ProtocolNumber, header.IPv4ProtocolNumber, &wq)
+			if err != nil {
+				b.Fatalf("NewEndpoint(%d, %d, _): %s", udp.ProtocolNumber, header.IPv4ProtocolNumber, err)
+			}
+			defer client.Close()
+
+			var r bytes.Reader
+			var buf bytes.Buffer
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Reset(data)
+				if _, err := client.Write(&r, tcpip.WriteOptions{To: &serverAddr}); err != nil {
+					b.Fatalf("client.Write(_, _): %s", err)
+				}
+				buf.Reset()
+				res, err := server.Read(&buf, tcpip.ReadOptions{NeedRemoteAddr: true})
+				if err != nil {
+					b.Fatalf("server.Read(_, _): %s", err)
+				}
+				r.Reset(buf.Bytes())
+				if _, err := server.Write(&r, tcpip.WriteOptions{To: &res.RemoteAddr}); err != nil {
+					b.Fatalf("server.Write(_, _): %s", err)
+				}
+				buf.Reset()
+				if _, err := client.Read(&buf, tcpip.ReadOptions{}); err != nil {
+					b.Fatalf("client.Read(_, _): %s", err)
+				}
+			}
+		})
+	}
+}
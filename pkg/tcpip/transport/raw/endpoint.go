@@ -82,6 +82,10 @@ type endpoint struct {
 	// Connect(), and is valid only when conneted is true.
 	route *stack.Route                 `state:"manual"`
 	stats tcpip.TransportEndpointStats `state:"nosave"`
+	// pmtud is the path MTU discovery setting, one of the
+	// tcpip.PMTUDiscovery* values. It does not apply to packets written
+	// with the IP header included.
+	pmtud int
 	// owner is used to get uid and gid of the packet.
 	owner tcpip.PacketOwner
 
@@ -111,6 +115,7 @@ func newEndpoint(s *stack.Stack, netProto tcpip.NetworkProtocolNumber, transProt
 		},
 		waiterQueue: waiterQueue,
 		associated:  associated,
+		pmtud:       tcpip.PMTUDiscoveryDont,
 	}
 	e.ops.InitHandler(e, e.stack, tcpip.GetStackSendBufferLimits, tcpip.GetStackReceiveBufferLimits)
 	e.ops.SetHeaderIncluded(!associated)
@@ -329,6 +334,13 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 			return 0, err
 		}
 	} else {
+		e.mu.RLock()
+		pmtud := e.pmtud
+		e.mu.RUnlock()
+		df, _, err := route.DontFragment(pmtud, len(payloadBytes))
+		if err != nil {
+			return 0, err
+		}
 		pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
 			ReserveHeaderBytes: int(route.MaxHeaderLength()),
 			Data:               buffer.View(payloadBytes).ToVectorisedView(),
@@ -338,6 +350,7 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 			Protocol: e.TransProto,
 			TTL:      route.DefaultTTL(),
 			TOS:      stack.DefaultTOS,
+			DF:       df,
 		}, pkt); err != nil {
 			return 0, err
 		}
@@ -506,8 +519,23 @@ func (e *endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 	}
 }
 
-func (*endpoint) SetSockOptInt(tcpip.SockOptInt, int) tcpip.Error {
-	return &tcpip.ErrUnknownProtocolOption{}
+// SetSockOptInt implements tcpip.Endpoint.SetSockOptInt.
+func (e *endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
+	switch opt {
+	case tcpip.MTUDiscoverOption:
+		switch v {
+		case tcpip.PMTUDiscoveryWant, tcpip.PMTUDiscoveryDont, tcpip.PMTUDiscoveryDo, tcpip.PMTUDiscoveryProbe:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		e.mu.Lock()
+		e.pmtud = v
+		e.mu.Unlock()
+		return nil
+
+	default:
+		return &tcpip.ErrUnknownProtocolOption{}
+	}
 }
 
 // GetSockOpt implements tcpip.Endpoint.GetSockOpt.
@@ -528,6 +556,20 @@ func (e *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 		e.rcvMu.Unlock()
 		return v, nil
 
+	case tcpip.MTUDiscoverOption:
+		e.mu.RLock()
+		v := e.pmtud
+		e.mu.RUnlock()
+		return v, nil
+
+	case tcpip.MTUOption:
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		if !e.connected {
+			return -1, &tcpip.ErrNotConnected{}
+		}
+		return int(e.route.PathMTU()), nil
+
 	default:
 		return -1, &tcpip.ErrUnknownProtocolOption{}
 	}
@@ -103,6 +103,11 @@ type handshake struct {
 	// original SYN-ACK when deferAccept is enabled.
 	acked bool
 
+	// deferAcceptExpired is true if a SYN-ACK has been retransmitted after
+	// the deferAccept duration ended, giving the peer a last chance to
+	// complete the handshake.
+	deferAcceptExpired bool
+
 	// sendSYNOpts is the cached values for the SYN options to be sent.
 	sendSYNOpts header.TCPSynOptions
 }
@@ -522,7 +527,7 @@ func (h *handshake) complete() tcpip.Error {
 	defer s.Done()
 
 	// Initialize the resend timer.
-	timer, err := newBackoffTimer(h.ep.stack.Clock(), time.Second, MaxRTO, resendWaker.Assert)
+	timer, err := newBackoffTimer(h.ep.stack.Clock(), initialSynRTO, MaxRTO, resendWaker.Assert)
 	if err != nil {
 		return err
 	}
@@ -537,7 +542,15 @@ func (h *handshake) complete() tcpip.Error {
 
 		case wakerForResend:
 			if err := timer.reset(); err != nil {
-				return err
+				// As in Linux, a passive handshake that dropped a bare ACK
+				// because of deferAccept doesn't expire with the SYN-ACK
+				// retransmissions. It lives until the SYN-ACK sent at the end
+				// of the deferAccept duration goes unanswered; see
+				// net/ipv4/inet_connection_sock.c:syn_ack_recalc().
+				if !h.acked || h.deferAccept == 0 || h.deferAcceptExpired {
+					return err
+				}
+				timer.resetMax()
 			}
 			// Resend the SYN/SYN-ACK only if the following conditions hold.
 			//  - It's an active handshake (deferAccept does not apply)
@@ -547,7 +560,10 @@ func (h *handshake) complete() tcpip.Error {
 			// The last is required to provide a way for the peer to complete
 			// the connection with another ACK or data (as ACKs are never
 			// retransmitted on their own).
-			if h.active || !h.acked || h.deferAccept != 0 && h.ep.stack.Clock().NowMonotonic().Sub(h.startTime) > h.deferAccept {
+			if h.acked && h.deferAccept != 0 && h.ep.stack.Clock().NowMonotonic().Sub(h.startTime) >= h.deferAccept {
+				h.deferAcceptExpired = true
+			}
+			if h.active || !h.acked || h.deferAcceptExpired {
 				h.ep.sendSynTCP(h.ep.route, tcpFields{
 					id:     h.ep.TransportEndpointInfo.ID,
 					ttl:    h.ep.ttl,
@@ -624,6 +640,12 @@ func (bt *backoffTimer) reset() tcpip.Error {
 	return nil
 }
 
+// resetMax rearms the timer with the maximum timeout.
+func (bt *backoffTimer) resetMax() {
+	bt.timeout = bt.maxTimeout
+	bt.t.Reset(bt.maxTimeout)
+}
+
 func (bt *backoffTimer) stop() {
 	bt.t.Stop()
 }
@@ -1237,13 +1259,13 @@ func (e *endpoint) keepaliveTimerExpired() tcpip.Error {
 	if userTimeout != 0 && e.stack.Clock().NowMonotonic().Sub(e.rcv.lastRcvdAckTime) >= userTimeout && e.keepalive.unacked > 0 {
 		e.keepalive.Unlock()
 		e.stack.Stats().TCP.EstablishedTimedout.Increment()
-		return &tcpip.ErrTimeout{}
+		return e.timeoutError()
 	}
 
 	if e.keepalive.unacked >= e.keepalive.count {
 		e.keepalive.Unlock()
 		e.stack.Stats().TCP.EstablishedTimedout.Increment()
-		return &tcpip.ErrTimeout{}
+		return e.timeoutError()
 	}
 
 	// RFC1122 4.2.3.6: TCP keepalive is a dataless ACK with
@@ -1397,7 +1419,7 @@ func (e *endpoint) protocolMainLoop(handshake bool, wakerInitDone chan<- struct{
 			f: func() tcpip.Error {
 				if !e.snd.retransmitTimerExpired() {
 					e.stack.Stats().TCP.EstablishedTimedout.Increment()
-					return &tcpip.ErrTimeout{}
+					return e.timeoutError()
 				}
 				return nil
 			},
@@ -1455,6 +1477,9 @@ func (e *endpoint) protocolMainLoop(handshake bool, wakerInitDone chan<- struct{
 						if closeTimer == nil {
 							closeTimer = e.stack.Clock().AfterFunc(e.tcpLingerTimeout, closeWaker.Assert)
 						}
+						// All sent data has been acknowledged, so wake up
+						// a close blocked on SO_LINGER.
+						e.waiterQueue.Notify(waiter.EventHUp)
 					}
 				}
 
@@ -391,6 +391,12 @@ type endpoint struct {
 	lastErrorMu sync.Mutex `state:"nosave"`
 	lastError   tcpip.Error
 
+	// softError is the last ICMP error received on a synchronized
+	// connection that was not reported to the application, as required by
+	// RFC 1122 section 4.2.3.9. It is reported in place of ETIMEDOUT if the
+	// connection later times out. softError is protected by lastErrorMu.
+	softError tcpip.Error
+
 	// rcvReadMu synchronizes calls to Read.
 	//
 	// mu and rcvQueueMu are temporarily released during data copying. rcvReadMu
@@ -441,6 +447,12 @@ type endpoint struct {
 	ttl               uint8
 	isConnectNotified bool
 
+	// pmtud is the path MTU discovery setting, one of the
+	// tcpip.PMTUDiscovery* values. It is only reported back to the user;
+	// segments are always sent without the Don't Fragment flag. The MSS does
+	// however take the path MTU learned by the stack into account.
+	pmtud int
+
 	// h stores a reference to the current handshake state if the endpoint is in
 	// the SYN-SENT or SYN-RECV states, in which case endpoint == endpoint.h.ep.
 	// nil otherwise.
@@ -551,7 +563,8 @@ type endpoint struct {
 	// which the final ACK of a handshake will be dropped provided the
 	// ACK is a bare ACK and carries no data. If the timeout is crossed then
 	// the bare ACK is accepted and the connection is delivered to the
-	// listener.
+	// listener. It is always the end of a SYN-ACK retransmission period;
+	// see deferAcceptTimeout.
 	deferAccept time.Duration
 
 	// pendingAccepted tracks connections queued to be accepted. It is used to
@@ -827,6 +840,7 @@ func newEndpoint(s *stack.Stack, netProto tcpip.NetworkProtocolNumber, waiterQue
 		txHash:        s.Rand().Uint32(),
 		windowClamp:   DefaultReceiveBufferSize,
 		maxSynRetries: DefaultSynRetries,
+		pmtud:         tcpip.PMTUDiscoveryDont,
 	}
 	e.ops.InitHandler(e, e.stack, GetTCPSendBufferLimits, GetTCPReceiveBufferLimits)
 	e.ops.SetMulticastLoop(true)
@@ -923,7 +937,7 @@ func (e *endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
 		// Determine if the endpoint is readable if requested.
 		if (mask & waiter.ReadableEvents) != 0 {
 			e.rcvQueueInfo.rcvQueueMu.Lock()
-			if e.rcvQueueInfo.RcvBufUsed > 0 || e.rcvQueueInfo.RcvClosed {
+			if e.readableLocked() {
 				result |= waiter.ReadableEvents
 			}
 			e.rcvQueueInfo.rcvQueueMu.Unlock()
@@ -1274,6 +1288,18 @@ func (e *endpoint) lastErrorLocked() tcpip.Error {
 	return err
 }
 
+// timeoutError returns the error to report when the connection times out,
+// which is the last soft error if one was received.
+func (e *endpoint) timeoutError() tcpip.Error {
+	e.lastErrorMu.Lock()
+	defer e.lastErrorMu.Unlock()
+	if err := e.softError; err != nil {
+		e.softError = nil
+		return err
+	}
+	return &tcpip.ErrTimeout{}
+}
+
 // LastError implements tcpip.Endpoint.LastError.
 func (e *endpoint) LastError() tcpip.Error {
 	e.LockUser()
@@ -1717,6 +1743,53 @@ func (e *endpoint) OnSetReceiveBufferSize(rcvBufSz, oldSz int64) (newSz int64) {
 	return rcvBufSz
 }
 
+// OnSetRcvlowat implements tcpip.SocketOptionsHandler.OnSetRcvlowat.
+func (e *endpoint) OnSetRcvlowat(rcvlowat int32) int32 {
+	e.LockUser()
+	defer e.UnlockUser()
+	e.rcvQueueInfo.rcvQueueMu.Lock()
+	defer e.rcvQueueInfo.rcvQueueMu.Unlock()
+
+	// As in Linux, SO_RCVLOWAT is limited to half of the largest receive
+	// buffer the endpoint may have, and the receive buffer is grown to hold
+	// SO_RCVLOWAT bytes unless its size was set explicitly.
+	oldSz := int(e.ops.GetReceiveBufferSize())
+	if e.rcvQueueInfo.RcvAutoParams.Disabled {
+		if max := int32(oldSz >> 1); rcvlowat > max {
+			rcvlowat = max
+		}
+		return rcvlowat
+	}
+	maxSz := e.maxReceiveBufferSize()
+	if max := int32(maxSz >> 1); rcvlowat > max {
+		rcvlowat = max
+	}
+	if newSz := 2 * int(rcvlowat); newSz > oldSz {
+		availBefore := wndFromSpace(e.receiveBufferAvailableLocked(oldSz))
+		availAfter := wndFromSpace(e.receiveBufferAvailableLocked(newSz))
+		e.ops.SetReceiveBufferSize(int64(newSz), false /* notify */)
+		if crossed, above := e.windowCrossedACKThresholdLocked(availAfter-availBefore, newSz); crossed && above {
+			e.notifyProtocolGoroutine(notifyNonZeroReceiveWindow)
+		}
+	}
+	return rcvlowat
+}
+
+// WakeupReaders implements tcpip.SocketOptionsHandler.WakeupReaders.
+func (e *endpoint) WakeupReaders() {
+	e.LockUser()
+	defer e.UnlockUser()
+
+	// Data already queued may satisfy a lowered SO_RCVLOWAT.
+	e.rcvQueueInfo.rcvQueueMu.Lock()
+	notify := e.EndpointState().connected() && e.readableLocked()
+	e.rcvQueueInfo.rcvQueueMu.Unlock()
+
+	if notify {
+		e.waiterQueue.Notify(waiter.ReadableEvents)
+	}
+}
+
 // OnSetSendBufferSize implements tcpip.SocketOptionsHandler.OnSetSendBufferSize.
 func (e *endpoint) OnSetSendBufferSize(sz int64) int64 {
 	atomic.StoreUint32(&e.sndQueueInfo.TCPSndBufState.AutoTuneSndBufDisabled, 1)
@@ -1775,11 +1848,14 @@ func (e *endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
 		e.notifyProtocolGoroutine(notifyMSSChanged)
 
 	case tcpip.MTUDiscoverOption:
-		// Return not supported if attempting to set this option to
-		// anything other than path MTU discovery disabled.
-		if v != tcpip.PMTUDiscoveryDont {
-			return &tcpip.ErrNotSupported{}
+		switch v {
+		case tcpip.PMTUDiscoveryWant, tcpip.PMTUDiscoveryDont, tcpip.PMTUDiscoveryDo, tcpip.PMTUDiscoveryProbe:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
 		}
+		e.LockUser()
+		e.pmtud = v
+		e.UnlockUser()
 
 	case tcpip.TTLOption:
 		e.LockUser()
@@ -1898,10 +1974,7 @@ func (e *endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 
 	case *tcpip.TCPDeferAcceptOption:
 		e.LockUser()
-		if time.Duration(*v) > MaxRTO {
-			*v = tcpip.TCPDeferAcceptOption(MaxRTO)
-		}
-		e.deferAccept = time.Duration(*v)
+		e.deferAccept = deferAcceptTimeout(time.Duration(*v))
 		e.UnlockUser()
 
 	case *tcpip.SocketDetachFilterOption:
@@ -1929,6 +2002,28 @@ func (e *endpoint) readyReceiveSize() (int, tcpip.Error) {
 	return e.rcvQueueInfo.RcvBufUsed, nil
 }
 
+// deferAcceptTimeout returns the TCP_DEFER_ACCEPT period that is in effect
+// when d is requested. Linux stores the option as a number of SYN-ACK
+// retransmissions, so d is rounded up to the end of the retransmission
+// period in which it falls, and is capped at maxDeferAcceptRetransmits
+// retransmissions. See net/ipv4/tcp.c:secs_to_retrans() and
+// retrans_to_secs().
+func deferAcceptTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	timeout := initialSynRTO
+	period := timeout
+	for retransmits := 1; d > period && retransmits < maxDeferAcceptRetransmits; retransmits++ {
+		timeout *= 2
+		if timeout > MaxRTO {
+			timeout = MaxRTO
+		}
+		period += timeout
+	}
+	return period
+}
+
 // GetSockOptInt implements tcpip.Endpoint.GetSockOptInt.
 func (e *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 	switch opt {
@@ -1959,9 +2054,18 @@ func (e *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 		return v, nil
 
 	case tcpip.MTUDiscoverOption:
-		// Always return the path MTU discovery disabled setting since
-		// it's the only one supported.
-		return tcpip.PMTUDiscoveryDont, nil
+		e.LockUser()
+		v := e.pmtud
+		e.UnlockUser()
+		return v, nil
+
+	case tcpip.MTUOption:
+		e.LockUser()
+		defer e.UnlockUser()
+		if !e.EndpointState().connected() {
+			return -1, &tcpip.ErrNotConnected{}
+		}
+		return int(e.route.PathMTU()), nil
 
 	case tcpip.ReceiveQueueSizeOption:
 		return e.readyReceiveSize()
@@ -2000,6 +2104,16 @@ func (e *endpoint) getTCPInfo() tcpip.TCPInfoOption {
 	} else {
 		info.State = tcpip.EndpointState(state)
 	}
+	if info.State == tcpip.EndpointState(StateListen) {
+		// Connections still in the handshake, including those held back
+		// by deferAccept, are not counted.
+		e.acceptMu.Lock()
+		if e.accepted != (accepted{}) {
+			info.AcceptQueueLen = uint32(e.accepted.endpoints.Len())
+			info.AcceptQueueCap = uint32(e.accepted.cap)
+		}
+		e.acceptMu.Unlock()
+	}
 	snd := e.snd
 	if snd != nil {
 		// We do not calculate RTT before sending the data packets. If
@@ -2726,13 +2840,38 @@ func (e *endpoint) enqueueSegment(s *segment) bool {
 }
 
 func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, pkt *stack.PacketBuffer) {
-	// Update last error first.
+	// Errors received while the connection is being established abort the
+	// handshake. Once the connection is synchronized, errors are only
+	// reported to the application if IP_RECVERR is enabled; otherwise they
+	// are recorded as soft errors. See net/ipv4/tcp_ipv4.c:tcp_v4_err.
+	recvErr := e.SocketOptions().GetRecvError()
 	e.lastErrorMu.Lock()
-	e.lastError = err
+	if e.EndpointState().connecting() || recvErr {
+		e.lastError = err
+	} else {
+		e.softError = err
+		e.lastErrorMu.Unlock()
+		return
+	}
 	e.lastErrorMu.Unlock()
 
 	// Update the error queue if IP_RECVERR is enabled.
-	if e.SocketOptions().GetRecvError() {
+	if recvErr {
+		// The offender is the sender of the ICMP error. Errors generated
+		// locally (e.g. on link resolution failure) carry no network header,
+		// in which case the local endpoint is the offender.
+		offender := tcpip.FullAddress{
+			NIC:  pkt.NICID,
+			Addr: e.TransportEndpointInfo.ID.LocalAddress,
+			Port: e.TransportEndpointInfo.ID.LocalPort,
+		}
+		if len(pkt.NetworkHeader().View()) != 0 {
+			offender = tcpip.FullAddress{
+				NIC:  pkt.NICID,
+				Addr: pkt.Network().SourceAddress(),
+			}
+		}
+
 		e.SocketOptions().QueueErr(&tcpip.SockError{
 			Err:   err,
 			Cause: transErr,
@@ -2744,11 +2883,7 @@ func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, p
 				Addr: e.TransportEndpointInfo.ID.RemoteAddress,
 				Port: e.TransportEndpointInfo.ID.RemotePort,
 			},
-			Offender: tcpip.FullAddress{
-				NIC:  pkt.NICID,
-				Addr: e.TransportEndpointInfo.ID.LocalAddress,
-				Port: e.TransportEndpointInfo.ID.LocalPort,
-			},
+			Offender: offender,
 			NetProto: pkt.NetworkProtocolNumber,
 		})
 	}
@@ -2758,7 +2893,7 @@ func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, p
 }
 
 // HandleError implements stack.TransportEndpoint.
-func (e *endpoint) HandleError(transErr stack.TransportError, pkt *stack.PacketBuffer) {
+func (e *endpoint) HandleError(_ stack.TransportEndpointID, transErr stack.TransportError, pkt *stack.PacketBuffer) {
 	handlePacketTooBig := func(mtu uint32) {
 		e.sndQueueInfo.sndQueueMu.Lock()
 		e.sndQueueInfo.PacketTooBigCount++
@@ -2773,6 +2908,8 @@ func (e *endpoint) HandleError(transErr stack.TransportError, pkt *stack.PacketB
 	switch transErr.Kind() {
 	case stack.PacketTooBigTransportError:
 		handlePacketTooBig(transErr.Info())
+	case stack.DestinationPortUnreachableTransportError:
+		e.onICMPError(&tcpip.ErrConnectionRefused{}, transErr, pkt)
 	case stack.DestinationHostUnreachableTransportError:
 		e.onICMPError(&tcpip.ErrNoRoute{}, transErr, pkt)
 	case stack.DestinationNetworkUnreachableTransportError:
@@ -2817,8 +2954,33 @@ func (e *endpoint) readyToRead(s *segment) {
 	} else {
 		e.rcvQueueInfo.RcvClosed = true
 	}
+	readable := e.readableLocked()
 	e.rcvQueueInfo.rcvQueueMu.Unlock()
-	e.waiterQueue.Notify(waiter.ReadableEvents)
+	if readable {
+		e.waiterQueue.Notify(waiter.ReadableEvents)
+	}
+}
+
+// readableLocked returns true if the endpoint should be reported as readable,
+// i.e. if the receive queue holds at least SO_RCVLOWAT bytes or the endpoint
+// is closed for receiving.
+//
+// Precondition: e.rcvQueueInfo.rcvQueueMu must be held.
+func (e *endpoint) readableLocked() bool {
+	if e.rcvQueueInfo.RcvClosed {
+		return true
+	}
+	used := e.rcvQueueInfo.RcvBufUsed
+	if used == 0 {
+		return false
+	}
+	if used >= int(e.ops.GetRcvlowat()) {
+		return true
+	}
+	// As in Linux, if the receive window is too small for the peer to send
+	// another full-sized segment, SO_RCVLOWAT bytes may never be queued, so
+	// report the endpoint as readable anyway.
+	return wndFromSpace(e.receiveBufferAvailableLocked(int(e.ops.GetReceiveBufferSize()))) <= int(e.amss)
 }
 
 // receiveBufferAvailableLocked calculates how many bytes are still available
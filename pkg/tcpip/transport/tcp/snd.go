@@ -36,6 +36,15 @@ const (
 	// MaxRTO is the maximum allowed value for the retransmit timeout.
 	MaxRTO = 120 * time.Second
 
+	// initialSynRTO is the initial retransmit timeout for SYN and SYN-ACK
+	// segments.
+	initialSynRTO = time.Second
+
+	// maxDeferAcceptRetransmits is the maximum number of SYN-ACK
+	// retransmissions that TCP_DEFER_ACCEPT can span. It matches the
+	// largest value Linux can store for the option.
+	maxDeferAcceptRetransmits = 255
+
 	// InitialCwnd is the initial congestion window.
 	InitialCwnd = 10
 
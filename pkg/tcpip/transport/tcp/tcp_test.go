@@ -920,6 +920,98 @@ func TestSimpleReceive(t *testing.T) {
 	)
 }
 
+func TestRcvlowat(t *testing.T) {
+	c := context.New(t, defaultMTU)
+	defer c.Cleanup()
+
+	c.CreateConnected(context.TestInitialSequenceNumber, 30000, -1 /* epRcvBuf */)
+
+	we, ch := waiter.NewChannelEntry(nil)
+	c.WQ.EventRegister(&we, waiter.ReadableEvents)
+	defer c.WQ.EventUnregister(&we)
+
+	const rcvlowat = 10
+	c.EP.SocketOptions().SetRcvlowat(rcvlowat)
+	if got := c.EP.SocketOptions().GetRcvlowat(); got != rcvlowat {
+		t.Fatalf("got GetRcvlowat() = %d, want = %d", got, rcvlowat)
+	}
+
+	iss := seqnum.Value(context.TestInitialSequenceNumber).Add(1)
+	send := func(data []byte) {
+		t.Helper()
+		c.SendPacket(data, &context.Headers{
+			SrcPort: context.TestPort,
+			DstPort: c.Port,
+			Flags:   header.TCPFlagAck,
+			SeqNum:  iss,
+			AckNum:  c.IRS.Add(1),
+			RcvWnd:  30000,
+		})
+		iss = iss.Add(seqnum.Size(len(data)))
+		// Wait for the data to be acknowledged, which happens after it's
+		// queued for reading.
+		checker.IPv4(t, c.GetPacket(),
+			checker.TCP(
+				checker.DstPort(context.TestPort),
+				checker.TCPAckNum(uint32(iss)),
+				checker.TCPFlags(header.TCPFlagAck),
+			),
+		)
+	}
+	checkReadable := func(want bool) {
+		t.Helper()
+		if got := c.EP.Readiness(waiter.ReadableEvents)&waiter.ReadableEvents != 0; got != want {
+			t.Errorf("got readable = %t, want = %t", got, want)
+		}
+		select {
+		case <-ch:
+			if !want {
+				t.Errorf("got unexpected readable notification")
+			}
+		default:
+			if want {
+				t.Errorf("got no readable notification")
+			}
+		}
+	}
+
+	// Fewer than SO_RCVLOWAT bytes don't make the endpoint readable.
+	send(make([]byte, rcvlowat-1))
+	checkReadable(false)
+
+	// Reaching SO_RCVLOWAT does.
+	send([]byte{1})
+	checkReadable(true)
+	var buf bytes.Buffer
+	if _, err := c.EP.Read(&buf, tcpip.ReadOptions{}); err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	if got := buf.Len(); got != rcvlowat {
+		t.Fatalf("got buf.Len() = %d, want = %d", got, rcvlowat)
+	}
+
+	// Lowering SO_RCVLOWAT below the number of queued bytes makes the endpoint
+	// readable.
+	send([]byte{1, 2, 3})
+	checkReadable(false)
+	c.EP.SocketOptions().SetRcvlowat(3)
+	checkReadable(true)
+
+	// SO_RCVLOWAT is limited to half the maximum receive buffer size, and the
+	// receive buffer grows to accommodate it.
+	var rs tcpip.TCPReceiveBufferSizeRangeOption
+	if err := c.Stack().TransportProtocolOption(tcp.ProtocolNumber, &rs); err != nil {
+		t.Fatalf("TransportProtocolOption(%d, &%T): %s", tcp.ProtocolNumber, rs, err)
+	}
+	c.EP.SocketOptions().SetRcvlowat(math.MaxInt32)
+	if got, want := c.EP.SocketOptions().GetRcvlowat(), int32(rs.Max/2); got != want {
+		t.Errorf("got GetRcvlowat() = %d, want = %d", got, want)
+	}
+	if got, want := c.EP.SocketOptions().GetReceiveBufferSize(), int64(rs.Max/2*2); got != want {
+		t.Errorf("got GetReceiveBufferSize() = %d, want = %d", got, want)
+	}
+}
+
 // TestUserSuppliedMSSOnConnect tests that the user supplied MSS is used when
 // creating a new active TCP socket. It should be present in the sent TCP
 // SYN segment.
@@ -7513,6 +7605,52 @@ func TestTCPUserTimeout(t *testing.T) {
 	}
 }
 
+// TestTCPUserTimeoutReportsSoftError tests that ICMP errors received on an
+// established connection are not reported immediately, but are reported in
+// place of ETIMEDOUT when the connection times out.
+func TestTCPUserTimeoutReportsSoftError(t *testing.T) {
+	c := context.New(t, defaultMTU)
+	defer c.Cleanup()
+
+	c.CreateConnected(context.TestInitialSequenceNumber, 30000, -1 /* epRcvBuf */)
+
+	waitEntry, notifyCh := waiter.NewChannelEntry(nil)
+	c.WQ.EventRegister(&waitEntry, waiter.EventHUp)
+	defer c.WQ.EventUnregister(&waitEntry)
+
+	initRTO := 1 * time.Second
+	userTimeout := initRTO / 2
+	v := tcpip.TCPUserTimeoutOption(userTimeout)
+	if err := c.EP.SetSockOpt(&v); err != nil {
+		t.Fatalf("c.EP.SetSockOpt(&%T(%s): %s", v, userTimeout, err)
+	}
+
+	view := make([]byte, 3)
+	var r bytes.Reader
+	r.Reset(view)
+	if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	data := c.GetPacket()
+
+	c.SendICMPPacket(header.ICMPv4DstUnreachable, header.ICMPv4HostUnreachable, nil, data, defaultMTU)
+
+	// Without IP_RECVERR, the error is soft and must not be reported while the
+	// connection is alive.
+	if err := c.EP.LastError(); err != nil {
+		t.Errorf("got c.EP.LastError() = %s, want = nil", err)
+	}
+
+	select {
+	case <-notifyCh:
+	case <-time.After(2 * initRTO):
+		t.Fatalf("connection still alive after %s, should have been closed after %s", 2*initRTO, userTimeout)
+	}
+
+	ept := endpointTester{c.EP}
+	ept.CheckReadError(t, &tcpip.ErrNoRoute{})
+}
+
 func TestKeepaliveWithUserTimeout(t *testing.T) {
 	c := context.New(t, defaultMTU)
 	defer c.Cleanup()
@@ -7778,6 +7916,39 @@ func TestTCPDeferAccept(t *testing.T) {
 		checker.TCPAckNum(uint32(irs+5))))
 }
 
+func TestTCPDeferAcceptRounding(t *testing.T) {
+	c := context.New(t, defaultMTU)
+	defer c.Cleanup()
+
+	c.Create(-1)
+
+	for _, tc := range []struct {
+		set  time.Duration
+		want time.Duration
+	}{
+		{set: 0, want: 0},
+		{set: 1 * time.Second, want: 1 * time.Second},
+		{set: 3 * time.Second, want: 3 * time.Second},
+		{set: 5 * time.Second, want: 7 * time.Second},
+		{set: 127 * time.Second, want: 127 * time.Second},
+		{set: 128 * time.Second, want: 247 * time.Second},
+		// Capped at 255 SYN-ACK retransmissions.
+		{set: math.MaxInt32 * time.Second, want: 29887 * time.Second},
+	} {
+		opt := tcpip.TCPDeferAcceptOption(tc.set)
+		if err := c.EP.SetSockOpt(&opt); err != nil {
+			t.Fatalf("c.EP.SetSockOpt(&%T(%s)): %s", opt, tc.set, err)
+		}
+		var got tcpip.TCPDeferAcceptOption
+		if err := c.EP.GetSockOpt(&got); err != nil {
+			t.Fatalf("c.EP.GetSockOpt(&%T): %s", got, err)
+		}
+		if want := tcpip.TCPDeferAcceptOption(tc.want); got != want {
+			t.Errorf("got TCPDeferAcceptOption = %s after setting %s, want %s", time.Duration(got), tc.set, time.Duration(want))
+		}
+	}
+}
+
 func TestTCPDeferAcceptTimeout(t *testing.T) {
 	c := context.New(t, defaultMTU)
 	defer c.Cleanup()
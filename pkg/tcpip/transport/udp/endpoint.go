@@ -121,6 +121,10 @@ type endpoint struct {
 	// applied while sending packets. Defaults to 0 as on Linux.
 	sendTOS uint8
 
+	// pmtud is the path MTU discovery setting, one of the
+	// tcpip.PMTUDiscovery* values.
+	pmtud int
+
 	// shutdownFlags represent the current shutdown state of the endpoint.
 	shutdownFlags tcpip.ShutdownFlags
 
@@ -178,6 +182,7 @@ func newEndpoint(s *stack.Stack, netProto tcpip.NetworkProtocolNumber, waiterQue
 		// Linux defaults to TTL=1.
 		multicastTTL:         1,
 		multicastMemberships: make(map[multicastMembership]struct{}),
+		pmtud:                tcpip.PMTUDiscoveryDont,
 		state:                uint32(StateInitial),
 		uniqueID:             s.UniqueID(),
 	}
@@ -514,14 +519,13 @@ func (e *endpoint) buildUDPPacketInfo(p tcpip.Payloader, opts tcpip.WriteOptions
 	if _, err := io.ReadFull(p, v); err != nil {
 		return udpPacketInfo{}, &tcpip.ErrBadBuffer{}
 	}
-	if len(v) > header.UDPMaximumPacketSize {
-		// Payload can't possibly fit in a packet.
+	messageTooLong := func(info uint32) tcpip.Error {
 		so := e.SocketOptions()
 		if so.GetRecvError() {
 			so.QueueLocalErr(
 				&tcpip.ErrMessageTooLong{},
 				route.NetProto(),
-				header.UDPMaximumPacketSize,
+				info,
 				tcpip.FullAddress{
 					NIC:  route.NICID(),
 					Addr: route.RemoteAddress(),
@@ -530,7 +534,16 @@ func (e *endpoint) buildUDPPacketInfo(p tcpip.Payloader, opts tcpip.WriteOptions
 				v,
 			)
 		}
-		return udpPacketInfo{}, &tcpip.ErrMessageTooLong{}
+		return &tcpip.ErrMessageTooLong{}
+	}
+	if len(v) > header.UDPMaximumPacketSize {
+		// Payload can't possibly fit in a packet.
+		return udpPacketInfo{}, messageTooLong(header.UDPMaximumPacketSize)
+	}
+
+	df, mtu, err := route.DontFragment(e.pmtud, header.UDPMinimumSize+len(v))
+	if err != nil {
+		return udpPacketInfo{}, messageTooLong(mtu)
 	}
 
 	ttl := e.ttl
@@ -549,6 +562,7 @@ func (e *endpoint) buildUDPPacketInfo(p tcpip.Payloader, opts tcpip.WriteOptions
 		ttl:           ttl,
 		useDefaultTTL: useDefaultTTL,
 		tos:           e.sendTOS,
+		df:            df,
 		owner:         e.owner,
 		noChecksum:    e.SocketOptions().GetNoChecksum(),
 	}, nil
@@ -603,11 +617,14 @@ func (e *endpoint) OnReusePortSet(v bool) {
 func (e *endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
 	switch opt {
 	case tcpip.MTUDiscoverOption:
-		// Return not supported if the value is not disabling path
-		// MTU discovery.
-		if v != tcpip.PMTUDiscoveryDont {
-			return &tcpip.ErrNotSupported{}
+		switch v {
+		case tcpip.PMTUDiscoveryWant, tcpip.PMTUDiscoveryDont, tcpip.PMTUDiscoveryDo, tcpip.PMTUDiscoveryProbe:
+		default:
+			return &tcpip.ErrInvalidOptionValue{}
 		}
+		e.mu.Lock()
+		e.pmtud = v
+		e.mu.Unlock()
 
 	case tcpip.MulticastTTLOption:
 		e.mu.Lock()
@@ -772,8 +789,18 @@ func (e *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 		return v, nil
 
 	case tcpip.MTUDiscoverOption:
-		// The only supported setting is path MTU discovery disabled.
-		return tcpip.PMTUDiscoveryDont, nil
+		e.mu.RLock()
+		v := e.pmtud
+		e.mu.RUnlock()
+		return v, nil
+
+	case tcpip.MTUOption:
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		if e.EndpointState() != StateConnected {
+			return -1, &tcpip.ErrNotConnected{}
+		}
+		return int(e.route.PathMTU()), nil
 
 	case tcpip.MulticastTTLOption:
 		e.mu.Lock()
@@ -831,6 +858,7 @@ type udpPacketInfo struct {
 	ttl           uint8
 	useDefaultTTL bool
 	tos           uint8
+	df            bool
 	owner         tcpip.PacketOwner
 	noChecksum    bool
 }
@@ -875,6 +903,7 @@ func (u *udpPacketInfo) send() (int, tcpip.Error) {
 		Protocol: ProtocolNumber,
 		TTL:      u.ttl,
 		TOS:      u.tos,
+		DF:       u.df,
 	}, pkt); err != nil {
 		u.route.Stats().UDP.PacketSendErrors.Increment()
 		return 0, err
@@ -1361,14 +1390,21 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 	}
 }
 
-func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, pkt *stack.PacketBuffer) {
+func (e *endpoint) onICMPError(err tcpip.Error, id stack.TransportEndpointID, transErr stack.TransportError, pkt *stack.PacketBuffer) {
+	// Linux only reports errors to unconnected sockets if IP_RECVERR is
+	// enabled. See net/ipv4/udp.c:__udp4_lib_err.
+	recvErr := e.SocketOptions().GetRecvError()
+	if !recvErr && e.EndpointState() != StateConnected {
+		return
+	}
+
 	// Update last error first.
 	e.lastErrorMu.Lock()
 	e.lastError = err
 	e.lastErrorMu.Unlock()
 
 	// Update the error queue if IP_RECVERR is enabled.
-	if e.SocketOptions().GetRecvError() {
+	if recvErr {
 		// Linux passes the payload without the UDP header.
 		var payload []byte
 		udp := header.UDP(pkt.Data().AsRange().ToOwnedView())
@@ -1376,20 +1412,34 @@ func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, p
 			payload = udp.Payload()
 		}
 
+		// The offender is the sender of the ICMP error. Errors generated
+		// locally (e.g. on link resolution failure) carry no network header,
+		// in which case the local endpoint is the offender.
+		offender := tcpip.FullAddress{
+			NIC:  pkt.NICID,
+			Addr: id.LocalAddress,
+			Port: id.LocalPort,
+		}
+		if len(pkt.NetworkHeader().View()) != 0 {
+			offender = tcpip.FullAddress{
+				NIC:  pkt.NICID,
+				Addr: pkt.Network().SourceAddress(),
+			}
+		}
+
 		e.SocketOptions().QueueErr(&tcpip.SockError{
 			Err:     err,
 			Cause:   transErr,
 			Payload: payload,
+			// The destination is that of the packet that caused the error,
+			// which for unconnected sockets isn't the endpoint's remote
+			// address.
 			Dst: tcpip.FullAddress{
 				NIC:  pkt.NICID,
-				Addr: e.ID.RemoteAddress,
-				Port: e.ID.RemotePort,
-			},
-			Offender: tcpip.FullAddress{
-				NIC:  pkt.NICID,
-				Addr: e.ID.LocalAddress,
-				Port: e.ID.LocalPort,
+				Addr: id.RemoteAddress,
+				Port: id.RemotePort,
 			},
+			Offender: offender,
 			NetProto: pkt.NetworkProtocolNumber,
 		})
 	}
@@ -1399,13 +1449,15 @@ func (e *endpoint) onICMPError(err tcpip.Error, transErr stack.TransportError, p
 }
 
 // HandleError implements stack.TransportEndpoint.
-func (e *endpoint) HandleError(transErr stack.TransportError, pkt *stack.PacketBuffer) {
+func (e *endpoint) HandleError(id stack.TransportEndpointID, transErr stack.TransportError, pkt *stack.PacketBuffer) {
 	// TODO(gvisor.dev/issues/5270): Handle all transport errors.
 	switch transErr.Kind() {
 	case stack.DestinationPortUnreachableTransportError:
-		if e.EndpointState() == StateConnected {
-			e.onICMPError(&tcpip.ErrConnectionRefused{}, transErr, pkt)
-		}
+		e.onICMPError(&tcpip.ErrConnectionRefused{}, id, transErr, pkt)
+	case stack.DestinationHostUnreachableTransportError:
+		e.onICMPError(&tcpip.ErrNoRoute{}, id, transErr, pkt)
+	case stack.DestinationNetworkUnreachableTransportError:
+		e.onICMPError(&tcpip.ErrNetworkUnreachable{}, id, transErr, pkt)
 	}
 }
 
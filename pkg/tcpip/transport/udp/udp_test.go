@@ -2557,3 +2557,246 @@ func TestOutgoingSubnetBroadcast(t *testing.T) {
 		})
 	}
 }
+
+// injectICMPv4Error injects an ICMPv4 error sent by testAddr in response to
+// the packet orig. mtu is only meaningful for Fragmentation Needed errors.
+func (c *testContext) injectICMPv4Error(typ header.ICMPv4Type, code header.ICMPv4Code, mtu uint16, orig []byte) {
+	c.t.Helper()
+
+	buf := buffer.NewView(header.IPv4MinimumSize + header.ICMPv4PayloadOffset + len(orig))
+	ip := header.IPv4(buf)
+	ip.Encode(&header.IPv4Fields{
+		TotalLength: uint16(len(buf)),
+		TTL:         65,
+		Protocol:    uint8(header.ICMPv4ProtocolNumber),
+		SrcAddr:     testAddr,
+		DstAddr:     stackAddr,
+	})
+	ip.SetChecksum(^ip.CalculateChecksum())
+
+	icmp := header.ICMPv4(buf[header.IPv4MinimumSize:])
+	icmp.SetType(typ)
+	icmp.SetCode(code)
+	icmp.SetMTU(mtu)
+	copy(icmp[header.ICMPv4PayloadOffset:], orig)
+	icmp.SetChecksum(^header.Checksum(icmp, 0 /* initial */))
+
+	c.linkEP.InjectInbound(ipv4.ProtocolNumber, stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Data: buf.ToVectorisedView(),
+	}))
+}
+
+func TestICMPErrors(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		code      header.ICMPv4Code
+		connect   bool
+		recvErr   bool
+		wantErr   tcpip.Error
+		wantQueue bool
+	}{
+		{
+			name:    "connected port unreachable",
+			code:    header.ICMPv4PortUnreachable,
+			connect: true,
+			wantErr: &tcpip.ErrConnectionRefused{},
+		},
+		{
+			name:    "connected host unreachable",
+			code:    header.ICMPv4HostUnreachable,
+			connect: true,
+			wantErr: &tcpip.ErrNoRoute{},
+		},
+		{
+			name:    "connected network unreachable",
+			code:    header.ICMPv4NetUnreachable,
+			connect: true,
+			wantErr: &tcpip.ErrNetworkUnreachable{},
+		},
+		{
+			name: "unconnected without IP_RECVERR",
+			code: header.ICMPv4PortUnreachable,
+		},
+		{
+			name:      "unconnected with IP_RECVERR",
+			code:      header.ICMPv4PortUnreachable,
+			recvErr:   true,
+			wantErr:   &tcpip.ErrConnectionRefused{},
+			wantQueue: true,
+		},
+		{
+			name:      "connected with IP_RECVERR",
+			code:      header.ICMPv4HostUnreachable,
+			connect:   true,
+			recvErr:   true,
+			wantErr:   &tcpip.ErrNoRoute{},
+			wantQueue: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := newDualTestContext(t, defaultMTU)
+			defer c.cleanup()
+
+			c.createEndpointForFlow(unicastV4)
+			if err := c.ep.Bind(tcpip.FullAddress{Port: stackPort}); err != nil {
+				t.Fatalf("c.ep.Bind(...) failed: %s", err)
+			}
+			c.ep.SocketOptions().SetRecvError(test.recvErr)
+
+			to := tcpip.FullAddress{Addr: testAddr, Port: testPort}
+			var opts tcpip.WriteOptions
+			if test.connect {
+				if err := c.ep.Connect(to); err != nil {
+					t.Fatalf("c.ep.Connect(%#v) failed: %s", to, err)
+				}
+			} else {
+				opts.To = &to
+			}
+			var r bytes.Reader
+			r.Reset([]byte{1, 2, 3, 4})
+			if _, err := c.ep.Write(&r, opts); err != nil {
+				t.Fatalf("c.ep.Write(_, %#v) failed: %s", opts, err)
+			}
+			orig := c.getPacketAndVerify(unicastV4)
+
+			c.injectICMPv4Error(header.ICMPv4DstUnreachable, test.code, 0 /* mtu */, orig)
+
+			if diff := cmp.Diff(test.wantErr, c.ep.LastError()); diff != "" {
+				t.Errorf("c.ep.LastError() mismatch (-want +got):\n%s", diff)
+			}
+
+			sockErr := c.ep.SocketOptions().DequeueErr()
+			if !test.wantQueue {
+				if sockErr != nil {
+					t.Fatalf("got c.ep.SocketOptions().DequeueErr() = %#v, want = nil", sockErr)
+				}
+				return
+			}
+			if sockErr == nil {
+				t.Fatal("got c.ep.SocketOptions().DequeueErr() = nil, want non-nil")
+			}
+			if diff := cmp.Diff(test.wantErr, sockErr.Err); diff != "" {
+				t.Errorf("sockErr.Err mismatch (-want +got):\n%s", diff)
+			}
+			if got, want := sockErr.Offender.Addr, tcpip.Address(testAddr); got != want {
+				t.Errorf("got sockErr.Offender.Addr = %s, want = %s", got, want)
+			}
+			// The destination comes from the packet that caused the error, since
+			// unconnected endpoints have no remote address.
+			if got, want := sockErr.Dst.Addr, tcpip.Address(testAddr); got != want {
+				t.Errorf("got sockErr.Dst.Addr = %s, want = %s", got, want)
+			}
+			if got, want := sockErr.Dst.Port, uint16(testPort); got != want {
+				t.Errorf("got sockErr.Dst.Port = %d, want = %d", got, want)
+			}
+			if got, want := sockErr.Cause.Code(), uint8(test.code); got != want {
+				t.Errorf("got sockErr.Cause.Code() = %d, want = %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPathMTUDiscovery(t *testing.T) {
+	const (
+		linkMTU = 1500
+		pathMTU = 1280
+		// largePayload fits in the link MTU but not in the path MTU.
+		largePayload = 1400
+	)
+
+	for _, test := range []struct {
+		name  string
+		pmtud int
+		// smallDF is whether a packet that fits in the path MTU is sent with
+		// the DF flag set.
+		smallDF bool
+		// wantErr is the error returned when writing largePayload bytes after
+		// the path MTU was learned.
+		wantErr tcpip.Error
+		// wantFlags is the IPv4 flags of the first packet sent when writing
+		// largePayload bytes after the path MTU was learned.
+		wantFlags uint8
+	}{
+		{
+			name:      "dont",
+			pmtud:     tcpip.PMTUDiscoveryDont,
+			smallDF:   false,
+			wantFlags: header.IPv4FlagMoreFragments,
+		},
+		{
+			name:      "want",
+			pmtud:     tcpip.PMTUDiscoveryWant,
+			smallDF:   true,
+			wantFlags: header.IPv4FlagMoreFragments,
+		},
+		{
+			name:    "do",
+			pmtud:   tcpip.PMTUDiscoveryDo,
+			smallDF: true,
+			wantErr: &tcpip.ErrMessageTooLong{},
+		},
+		{
+			name:      "probe",
+			pmtud:     tcpip.PMTUDiscoveryProbe,
+			smallDF:   true,
+			wantFlags: header.IPv4FlagDontFragment,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := newDualTestContext(t, linkMTU)
+			defer c.cleanup()
+
+			c.createEndpointForFlow(unicastV4)
+			if err := c.ep.SetSockOptInt(tcpip.MTUDiscoverOption, test.pmtud); err != nil {
+				t.Fatalf("c.ep.SetSockOptInt(tcpip.MTUDiscoverOption, %d) failed: %s", test.pmtud, err)
+			}
+			if v, err := c.ep.GetSockOptInt(tcpip.MTUDiscoverOption); err != nil || v != test.pmtud {
+				t.Fatalf("got c.ep.GetSockOptInt(tcpip.MTUDiscoverOption) = (%d, %v), want = (%d, nil)", v, err, test.pmtud)
+			}
+
+			if _, err := c.ep.GetSockOptInt(tcpip.MTUOption); err == nil {
+				t.Fatal("got c.ep.GetSockOptInt(tcpip.MTUOption) = (_, nil) on an unconnected endpoint, want non-nil error")
+			}
+			to := tcpip.FullAddress{Addr: testAddr, Port: testPort}
+			if err := c.ep.Connect(to); err != nil {
+				t.Fatalf("c.ep.Connect(%#v) failed: %s", to, err)
+			}
+			if v, err := c.ep.GetSockOptInt(tcpip.MTUOption); err != nil || v != linkMTU {
+				t.Fatalf("got c.ep.GetSockOptInt(tcpip.MTUOption) = (%d, %v), want = (%d, nil)", v, err, linkMTU)
+			}
+
+			var r bytes.Reader
+			r.Reset(make([]byte, 100))
+			if _, err := c.ep.Write(&r, tcpip.WriteOptions{}); err != nil {
+				t.Fatalf("c.ep.Write(...) failed: %s", err)
+			}
+			var wantFlags uint8
+			if test.smallDF {
+				wantFlags = header.IPv4FlagDontFragment
+			}
+			orig := c.getPacketAndVerify(unicastV4, checker.FragmentFlags(wantFlags))
+
+			c.injectICMPv4Error(header.ICMPv4DstUnreachable, header.ICMPv4FragmentationNeeded, pathMTU, orig)
+
+			if v, err := c.ep.GetSockOptInt(tcpip.MTUOption); err != nil || v != pathMTU {
+				t.Fatalf("got c.ep.GetSockOptInt(tcpip.MTUOption) = (%d, %v), want = (%d, nil)", v, err, pathMTU)
+			}
+
+			r.Reset(make([]byte, largePayload))
+			if _, err := c.ep.Write(&r, tcpip.WriteOptions{}); err != nil {
+				if diff := cmp.Diff(test.wantErr, err); diff != "" {
+					t.Fatalf("c.ep.Write(...) error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if test.wantErr != nil {
+				t.Fatalf("got c.ep.Write(...) = (_, nil), want = (_, %s)", test.wantErr)
+			}
+			p, ok := c.linkEP.Read()
+			if !ok {
+				t.Fatal("packet wasn't written out")
+			}
+			checker.IPv4(t, stack.PayloadSince(p.Pkt.NetworkHeader()), checker.FragmentFlags(test.wantFlags))
+		})
+	}
+}
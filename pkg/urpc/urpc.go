@@ -27,6 +27,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/fd"
@@ -281,6 +282,18 @@ func (s *Server) Register(obj interface{}) {
 	}
 }
 
+// Methods returns the sorted names of all registered methods.
+func (s *Server) Methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // lookup looks up the given method.
 func (s *Server) lookup(method string) (registeredMethod, bool) {
 	s.mu.Lock()
@@ -17,6 +17,7 @@ package urpc
 import (
 	"errors"
 	"os"
+	"reflect"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/unet"
@@ -123,6 +124,15 @@ func TestUnknownMethod(t *testing.T) {
 	}
 }
 
+func TestMethods(t *testing.T) {
+	s := NewServer()
+	s.Register(test{})
+	want := []string{"test.Err", "test.FailNoFile", "test.Func", "test.SendFile", "test.TooManyFiles"}
+	if got := s.Methods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Methods() = %v, want %v", got, want)
+	}
+}
+
 func TestErr(t *testing.T) {
 	c, err := testClient()
 	if err != nil {
@@ -28,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/sentry/time"
@@ -57,6 +58,10 @@ const (
 	// ContMgrExecuteAsync executes a command in a container.
 	ContMgrExecuteAsync = "containerManager.ExecuteAsync"
 
+	// ContMgrMount mounts a directory served by a new gofer in a running
+	// container.
+	ContMgrMount = "containerManager.Mount"
+
 	// ContMgrProcesses lists processes running in a container.
 	ContMgrProcesses = "containerManager.Processes"
 
@@ -69,6 +74,16 @@ const (
 	// ContMgrStartSubcontainer starts a sub-container inside a running sandbox.
 	ContMgrStartSubcontainer = "containerManager.StartSubcontainer"
 
+	// ContMgrSyscallPolicyStats returns the counters of a container's syscall
+	// policy.
+	ContMgrSyscallPolicyStats = "containerManager.SyscallPolicyStats"
+
+	// ContMgrUnmount unmounts a directory previously mounted with ContMgrMount.
+	ContMgrUnmount = "containerManager.Unmount"
+
+	// ContMgrVersion returns the control API version of the sandbox.
+	ContMgrVersion = "containerManager.Version"
+
 	// ContMgrWait waits on the init process of the container and returns its
 	// ExitStatus.
 	ContMgrWait = "containerManager.Wait"
@@ -91,11 +106,13 @@ const (
 
 // Profiling related commands (see pprof.go for more details).
 const (
-	ProfileCPU   = "Profile.CPU"
-	ProfileHeap  = "Profile.Heap"
-	ProfileBlock = "Profile.Block"
-	ProfileMutex = "Profile.Mutex"
-	ProfileTrace = "Profile.Trace"
+	ProfileCPU                = "Profile.CPU"
+	ProfileHeap               = "Profile.Heap"
+	ProfileBlock              = "Profile.Block"
+	ProfileMutex              = "Profile.Mutex"
+	ProfileContention         = "Profile.Contention"
+	ProfileSetContentionRates = "Profile.SetContentionRates"
+	ProfileTrace              = "Profile.Trace"
 )
 
 // Logging related commands (see logging.go for more details).
@@ -114,6 +131,20 @@ const (
 	FsCat = "Fs.Cat"
 )
 
+// ControlAPIVersion is the version of the control API served by the sandbox.
+// It must be incremented whenever a control method is removed or its arguments
+// or results change incompatibly. Adding methods doesn't change the version,
+// since clients discover them with ContMgrVersion.
+//
+// The control API is only served over urpc on the socket named by
+// ControlSocketAddr. A versioned ttrpc or gRPC transport with protobuf message
+// definitions, server-streamed container events, and a CLI that is a thin
+// client of that transport are deliberately not implemented here: serving them
+// from the sandbox would pull an RPC stack and its syscalls under the seccomp
+// filters. They are expected to live in a host-side daemon that forwards to
+// these methods after negotiating ControlAPIVersion.
+const ControlAPIVersion = 1
+
 // ControlSocketAddr generates an abstract unix socket name for the given ID.
 func ControlSocketAddr(id string) string {
 	return fmt.Sprintf("\x00runsc-sandbox.%s", id)
@@ -204,6 +235,18 @@ func (cm *containerManager) Processes(cid *string, out *[]*control.Process) erro
 	return control.Processes(cm.l.k, *cid, out)
 }
 
+// SyscallPolicyStats returns the number of syscalls matched by each rule of a
+// container's syscall policy.
+func (cm *containerManager) SyscallPolicyStats(cid *string, out *[]syspolicy.RuleStats) error {
+	log.Debugf("containerManager.SyscallPolicyStats, cid: %s", *cid)
+	stats, ok := cm.l.k.SyscallPolicyStats(*cid)
+	if !ok {
+		return fmt.Errorf("container %q has no syscall policy", *cid)
+	}
+	*out = stats
+	return nil
+}
+
 // CreateArgs contains arguments to the Create method.
 type CreateArgs struct {
 	// CID is the ID of the container to start.
@@ -242,6 +285,10 @@ type StartArgs struct {
 	// CID is the ID of the container to start.
 	CID string
 
+	// SyscallPolicy is the container's syscall policy profile, or nil if it
+	// has none.
+	SyscallPolicy *syspolicy.Profile
+
 	// FilePayload contains, in order:
 	//   * stdin, stdout, and stderr (optional: if terminal is disabled).
 	//   * file descriptors to connect to gofer to serve the root filesystem.
@@ -302,7 +349,7 @@ func (cm *containerManager) StartSubcontainer(args *StartArgs, _ *struct{}) erro
 		}
 	}()
 
-	if err := cm.l.startSubcontainer(args.Spec, args.Conf, args.CID, stdios, goferFDs); err != nil {
+	if err := cm.l.startSubcontainer(args.Spec, args.Conf, args.CID, stdios, goferFDs, args.SyscallPolicy); err != nil {
 		log.Debugf("containerManager.StartSubcontainer failed, cid: %s, args: %+v, err: %v", args.CID, args, err)
 		return err
 	}
@@ -318,18 +365,60 @@ func (cm *containerManager) DestroySubcontainer(cid *string, _ *struct{}) error
 }
 
 // ExecuteAsync starts running a command on a created or running sandbox. It
-// returns the PID of the new process.
-func (cm *containerManager) ExecuteAsync(args *control.ExecArgs, pid *int32) error {
+// returns the PID of the new process and the inode numbers of its namespaces.
+func (cm *containerManager) ExecuteAsync(args *control.ExecArgs, result *control.ExecResult) error {
 	log.Debugf("containerManager.ExecuteAsync, cid: %s, args: %+v", args.ContainerID, args)
-	tgid, err := cm.l.executeAsync(args)
+	res, err := cm.l.executeAsync(args)
 	if err != nil {
 		log.Debugf("containerManager.ExecuteAsync failed, cid: %s, args: %+v, err: %v", args.ContainerID, args, err)
 		return err
 	}
-	*pid = int32(tgid)
+	*result = *res
 	return nil
 }
 
+// MountArgs contains arguments to the Mount method.
+type MountArgs struct {
+	// CID is the ID of the container in which to mount.
+	CID string
+
+	// Mount is the bind mount to add to the container. Mount.Options may
+	// contain "ro" and a propagation option.
+	Mount specs.Mount
+
+	// FilePayload contains the FD connected to the gofer serving Mount.Source.
+	urpc.FilePayload
+}
+
+// Mount mounts a directory, served by the gofer connected to the FD in args,
+// in the mount namespace of a running container.
+func (cm *containerManager) Mount(args *MountArgs, _ *struct{}) error {
+	log.Debugf("containerManager.Mount, cid: %s, mount: %+v", args.CID, args.Mount)
+	if len(args.Files) != 1 {
+		return fmt.Errorf("mount arguments must contain exactly one file for the gofer")
+	}
+	goferFD, err := fd.NewFromFile(args.Files[0])
+	if err != nil {
+		return fmt.Errorf("error dup'ing gofer file: %w", err)
+	}
+	return cm.l.mount(args.CID, &args.Mount, goferFD)
+}
+
+// UnmountArgs contains arguments to the Unmount method.
+type UnmountArgs struct {
+	// CID is the ID of the container in which to unmount.
+	CID string
+
+	// Destination is the path of the mount in the container.
+	Destination string
+}
+
+// Unmount lazily unmounts a directory previously mounted with Mount.
+func (cm *containerManager) Unmount(args *UnmountArgs, _ *struct{}) error {
+	log.Debugf("containerManager.Unmount, cid: %s, destination: %s", args.CID, args.Destination)
+	return cm.l.unmount(args.CID, args.Destination)
+}
+
 // Checkpoint pauses a sandbox and saves its state.
 func (cm *containerManager) Checkpoint(o *control.SaveOpts, _ *struct{}) error {
 	log.Debugf("containerManager.Checkpoint")
@@ -338,6 +427,13 @@ func (cm *containerManager) Checkpoint(o *control.SaveOpts, _ *struct{}) error {
 		return errors.New("checkpoint not supported when using hostinet")
 	}
 
+	if o.Metadata == nil {
+		o.Metadata = make(map[string]string)
+	}
+	if err := NewCheckpointManifest(cm.l.root.conf, cm.l.root.spec).AddToMetadata(o.Metadata); err != nil {
+		return err
+	}
+
 	state := control.State{
 		Kernel:   cm.l.k,
 		Watchdog: cm.l.watchdog,
@@ -391,7 +487,7 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	k := &kernel.Kernel{
 		Platform: p,
 	}
-	mf, err := createMemoryFile()
+	mf, err := createMemoryFile(cm.l.root.conf)
 	if err != nil {
 		return fmt.Errorf("creating memory file: %v", err)
 	}
@@ -439,11 +535,28 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	}
 
 	// Load the state.
-	loadOpts := state.LoadOpts{Source: specFile}
+	loadOpts := state.LoadOpts{
+		Source: specFile,
+		CheckMetadata: func(metadata map[string]string) error {
+			saved, err := ManifestFromMetadata(metadata)
+			if err != nil {
+				return err
+			}
+			if saved == nil {
+				log.Warningf("Checkpoint has no manifest, skipping compatibility checks")
+				return nil
+			}
+			return NewCheckpointManifest(cm.l.root.conf, cm.l.root.spec).Check(saved).Err(cm.l.root.conf.RestoreAllowMismatch)
+		},
+	}
 	if err := loadOpts.Load(ctx, k, nil, networkStack, time.NewCalibratedClocks(), &vfs.CompleteRestoreOptions{}); err != nil {
 		return err
 	}
 
+	// A checkpoint may be restored many times, so with --random-blocking
+	// /dev/random readers wait until entropy is credited again.
+	k.EntropyPool().SetSeeded(!cm.l.root.conf.RandomBlocking)
+
 	// Since we have a new kernel we also must make a new watchdog.
 	dogOpts := watchdog.DefaultOpts
 	dogOpts.TaskTimeoutAction = cm.l.root.conf.WatchdogAction
@@ -452,6 +565,7 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	// Change the loader fields to reflect the changes made when restoring.
 	cm.l.k = k
 	cm.l.watchdog = dog
+	cm.l.oomHandler = newOOMHandler(k, cm.l.root.conf)
 	cm.l.root.procArgs = kernel.CreateProcessArgs{}
 	cm.l.restore = true
 
@@ -476,6 +590,33 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	return nil
 }
 
+// VersionArgs are arguments to the Version method.
+type VersionArgs struct {
+	// ClientVersion is the control API version the client was built for.
+	ClientVersion uint32
+}
+
+// VersionResult is the result of the Version method.
+type VersionResult struct {
+	// APIVersion is the control API version served by the sandbox.
+	APIVersion uint32
+
+	// Methods are the control methods served by the sandbox.
+	Methods []string
+}
+
+// Version negotiates the control API version with a client. It fails if the
+// client was built for a different version than the sandbox serves.
+func (cm *containerManager) Version(args *VersionArgs, r *VersionResult) error {
+	log.Debugf("containerManager.Version, client version: %d", args.ClientVersion)
+	if args.ClientVersion != ControlAPIVersion {
+		return fmt.Errorf("control API version %d is not supported, sandbox serves version %d", args.ClientVersion, ControlAPIVersion)
+	}
+	r.APIVersion = ControlAPIVersion
+	r.Methods = cm.l.ctrl.srv.Methods()
+	return nil
+}
+
 // Wait waits for the init process in the given container.
 func (cm *containerManager) Wait(cid *string, waitStatus *uint32) error {
 	log.Debugf("containerManager.Wait, cid: %s", *cid)
@@ -449,6 +449,11 @@ func hostInetFilters() seccomp.SyscallRules {
 				seccomp.EqualTo(unix.SOL_TCP),
 				seccomp.EqualTo(linux.TCP_INQ),
 			},
+			{
+				seccomp.MatchAny{},
+				seccomp.EqualTo(linux.SOL_MPTCP),
+				seccomp.EqualTo(linux.MPTCP_INFO),
+			},
 		},
 		unix.SYS_IOCTL: []seccomp.Rule{
 			{
@@ -623,6 +628,16 @@ func hostInetFilters() seccomp.SyscallRules {
 				seccomp.EqualTo(unix.SOCK_DGRAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
 				seccomp.EqualTo(0),
 			},
+			{
+				seccomp.EqualTo(unix.AF_INET),
+				seccomp.EqualTo(unix.SOCK_STREAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+				seccomp.EqualTo(linux.IPPROTO_MPTCP),
+			},
+			{
+				seccomp.EqualTo(unix.AF_INET6),
+				seccomp.EqualTo(unix.SOCK_STREAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+				seccomp.EqualTo(linux.IPPROTO_MPTCP),
+			},
 		},
 		unix.SYS_WRITEV: {},
 	}
@@ -822,7 +822,11 @@ func (c *containerMounter) getMountNameAndOptions(conf *config.Config, m *specs.
 	case bind:
 		fd := c.fds.remove()
 		fsName = gofervfs2.Name
-		opts = p9MountData(fd, c.getMountAccessType(conf, m), conf.VFS2)
+		var err error
+		opts, err = c.goferMountData(fd, conf, m, conf.VFS2)
+		if err != nil {
+			return "", nil, false, err
+		}
 		// If configured, add overlay to all writable mounts.
 		useOverlay = conf.Overlay && !mountFlags(m.Options).ReadOnly
 	case cgroupfs.Name:
@@ -845,6 +849,47 @@ func (c *containerMounter) getMountAccessType(conf *config.Config, mount *specs.
 	return conf.FileAccessMounts
 }
 
+// goferMountData returns the mount data for a gofer mount of the given mount
+// over fd. Per-mount cache policy options, given either in the mount's options
+// or in its mount hint's options, take precedence over the access type derived
+// from the mount hint's share type and conf.
+func (c *containerMounter) goferMountData(fd int, conf *config.Config, mount *specs.Mount, vfs2 bool) ([]string, error) {
+	opts := mount.Options
+	if hint := c.hints.findMount(mount); hint != nil && !hasCacheMountOption(opts) {
+		opts = hint.mount.Options
+	}
+	policy, ttl, err := specutils.ParseCacheMountOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	switch policy {
+	case specutils.CachePolicyExclusive:
+		return p9MountData(fd, config.FileAccessExclusive, vfs2), nil
+	case specutils.CachePolicyShared:
+		data := p9MountData(fd, config.FileAccessShared, vfs2)
+		if ttl != 0 {
+			if !vfs2 {
+				return nil, fmt.Errorf("mount option %q is only supported with VFS2", specutils.CacheTTLMountOption)
+			}
+			data = append(data, "revalidate_ttl="+ttl.String())
+		}
+		return data, nil
+	case specutils.CachePolicyUncached:
+		return append(p9MountData(fd, config.FileAccessExclusive, vfs2), "cache=none"), nil
+	default:
+		return p9MountData(fd, c.getMountAccessType(conf, mount), vfs2), nil
+	}
+}
+
+func hasCacheMountOption(opts []string) bool {
+	for _, o := range opts {
+		if specutils.IsCacheMountOption(o) {
+			return true
+		}
+	}
+	return false
+}
+
 // mountSubmount mounts volumes inside the container's root. Because mounts may
 // be readonly, a lower ramfs overlay is added to create the mount point dir.
 // Another overlay is added with tmpfs on top if Config.Overlay is true.
@@ -250,3 +250,84 @@ func TestGetMountAccessType(t *testing.T) {
 		})
 	}
 }
+
+func TestGoferMountData(t *testing.T) {
+	const source = "foo"
+	for _, tst := range []struct {
+		name        string
+		annotations map[string]string
+		options     []string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name: "default",
+			want: []string{"trans=fd", "rfdno=1", "wfdno=1", "cache=remote_revalidating"},
+		},
+		{
+			name:    "exclusive",
+			options: []string{"gvisor.cache=exclusive"},
+			want:    []string{"trans=fd", "rfdno=1", "wfdno=1"},
+		},
+		{
+			name:    "shared+ttl",
+			options: []string{"gvisor.cache=shared", "gvisor.cache.ttl=5s"},
+			want:    []string{"trans=fd", "rfdno=1", "wfdno=1", "cache=remote_revalidating", "revalidate_ttl=5s"},
+		},
+		{
+			name:    "uncached",
+			options: []string{"gvisor.cache=uncached"},
+			want:    []string{"trans=fd", "rfdno=1", "wfdno=1", "cache=none"},
+		},
+		{
+			name:    "ttl without shared",
+			options: []string{"gvisor.cache=exclusive", "gvisor.cache.ttl=5s"},
+			wantErr: true,
+		},
+		{
+			name: "hint options",
+			annotations: map[string]string{
+				MountPrefix + "mount1.source":  source,
+				MountPrefix + "mount1.type":    "bind",
+				MountPrefix + "mount1.share":   "shared",
+				MountPrefix + "mount1.options": "gvisor.cache=exclusive",
+			},
+			want: []string{"trans=fd", "rfdno=1", "wfdno=1"},
+		},
+		{
+			name: "mount options override hint",
+			annotations: map[string]string{
+				MountPrefix + "mount1.source":  source,
+				MountPrefix + "mount1.type":    "bind",
+				MountPrefix + "mount1.share":   "shared",
+				MountPrefix + "mount1.options": "gvisor.cache=exclusive",
+			},
+			options: []string{"gvisor.cache=uncached"},
+			want:    []string{"trans=fd", "rfdno=1", "wfdno=1", "cache=none"},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			spec := &specs.Spec{Annotations: tst.annotations}
+			podHints, err := newPodMountHints(spec)
+			if err != nil {
+				t.Fatalf("newPodMountHints failed: %v", err)
+			}
+			mounter := containerMounter{hints: podHints}
+			conf := &config.Config{FileAccessMounts: config.FileAccessShared}
+			mount := &specs.Mount{Source: source, Options: tst.options}
+			got, err := mounter.goferMountData(1, conf, mount, true /* vfs2 */)
+			if tst.wantErr {
+				if err == nil {
+					t.Errorf("goferMountData() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("goferMountData() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tst.want) {
+				t.Errorf("goferMountData(), want: %v, got: %v", tst.want, got)
+			}
+		})
+	}
+}
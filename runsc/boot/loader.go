@@ -26,17 +26,20 @@ import (
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/bpf"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/coverage"
 	"gvisor.dev/gvisor/pkg/cpuid"
+	"gvisor.dev/gvisor/pkg/eventchannel"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/memutil"
 	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/refs"
 	"gvisor.dev/gvisor/pkg/refsvfs2"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/sentry/fdimport"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
@@ -46,9 +49,12 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/oom"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	"gvisor.dev/gvisor/pkg/sentry/loader"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/sighandling"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netfilter"
 	"gvisor.dev/gvisor/pkg/sentry/syscalls/linux/vfs2"
@@ -60,6 +66,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
 	"gvisor.dev/gvisor/pkg/tcpip/link/sniffer"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tunnel"
 	"gvisor.dev/gvisor/pkg/tcpip/network/arp"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
@@ -100,6 +107,10 @@ type containerInfo struct {
 
 	// goferFDs are the FDs that attach the sandbox to the gofers.
 	goferFDs []*fd.FD
+
+	// syscallPolicy is the container's syscall policy profile, or nil if it
+	// has none.
+	syscallPolicy *syspolicy.Profile
 }
 
 // Loader keeps state needed to start the kernel and run the container.
@@ -115,6 +126,10 @@ type Loader struct {
 
 	watchdog *watchdog.Watchdog
 
+	// oomHandler is the sentry's OOM handler, or nil if OOM handling is left
+	// to the host.
+	oomHandler *oom.Handler
+
 	// stopSignalForwarding disables forwarding of signals to the sandboxed
 	// container. It should be called when a sandbox is destroyed.
 	stopSignalForwarding func()
@@ -138,6 +153,20 @@ type Loader struct {
 	// mountHints provides extra information about mounts for containers that
 	// apply to the entire pod.
 	mountHints *podMountHints
+
+	// dynamicMounts maps mounts added to running containers by
+	// containerManager.Mount to their mount IDs. Entries may be stale if the
+	// container unmounted or moved the mount itself; see
+	// Loader.dynamicMountLocked.
+	//
+	// dynamicMounts is guarded by mu.
+	dynamicMounts map[dynamicMount]uint64
+}
+
+// dynamicMount identifies a mount added to a running container.
+type dynamicMount struct {
+	cid         string
+	destination string
 }
 
 // execID uniquely identifies a sentry process that is executed in a container.
@@ -198,6 +227,15 @@ type Args struct {
 	TotalMem uint64
 	// UserLogFD is the file descriptor to write user logs to.
 	UserLogFD int
+	// SyscallPolicy is the root container's syscall policy profile, or nil if
+	// it has none.
+	SyscallPolicy *syspolicy.Profile
+	// SecurityEvents is the file to write security events to, or nil if
+	// security events are disabled. The Loader takes ownership of it.
+	SecurityEvents *os.File
+	// HostCPUStat is the sandbox's host cgroup cpu.stat file, or nil if it is
+	// unavailable. The Loader takes ownership of it.
+	HostCPUStat *os.File
 }
 
 // make sure stdioFDs are always the same on initial start and on restore
@@ -263,7 +301,7 @@ func New(args Args) (*Loader, error) {
 	}
 
 	// Create memory file.
-	mf, err := createMemoryFile()
+	mf, err := createMemoryFile(args.Conf)
 	if err != nil {
 		return nil, fmt.Errorf("creating memory file: %w", err)
 	}
@@ -337,9 +375,15 @@ func New(args Args) (*Loader, error) {
 		RootIPCNamespace:            kernel.NewIPCNamespace(creds.UserNamespace),
 		RootAbstractSocketNamespace: kernel.NewAbstractSocketNamespace(),
 		PIDNamespace:                kernel.NewRootPIDNamespace(creds.UserNamespace),
+		MaxPathLen:                  args.Conf.MaxPathLen,
+		FanotifyPermTimeout:         gtime.Duration(args.Conf.FanotifyPermTimeout) * gtime.Second,
+		FanotifyPermDeny:            args.Conf.FanotifyPermDeny,
 	}); err != nil {
 		return nil, fmt.Errorf("initializing kernel: %w", err)
 	}
+	if args.HostCPUStat != nil {
+		k.SetHostCPUStat(args.HostCPUStat)
+	}
 
 	if kernel.VFS2Enabled {
 		if err := registerFilesystems(k); err != nil {
@@ -369,12 +413,17 @@ func New(args Args) (*Loader, error) {
 	if err != nil {
 		return nil, fmt.Errorf("creating init process for root container: %w", err)
 	}
+	procArgs.ResolveBeneath = args.Conf.ResolveBeneath
 	info.procArgs = procArgs
 
 	if err := initCompatLogs(args.UserLogFD); err != nil {
 		return nil, fmt.Errorf("initializing compat logs: %w", err)
 	}
 
+	if err := startSecurityEvents(args.Conf, args.SecurityEvents); err != nil {
+		return nil, fmt.Errorf("starting security events: %w", err)
+	}
+
 	mountHints, err := newPodMountHints(args.Spec)
 	if err != nil {
 		return nil, fmt.Errorf("creating pod mount hints: %w", err)
@@ -382,6 +431,7 @@ func New(args Args) (*Loader, error) {
 
 	info.conf = args.Conf
 	info.spec = args.Spec
+	info.syscallPolicy = args.SyscallPolicy
 
 	if kernel.VFS2Enabled {
 		// Set up host mount that will be used for imported fds.
@@ -401,6 +451,7 @@ func New(args Args) (*Loader, error) {
 	l := &Loader{
 		k:          k,
 		watchdog:   dog,
+		oomHandler: newOOMHandler(k, args.Conf),
 		sandboxID:  args.ID,
 		processes:  map[execID]*execProcess{eid: {}},
 		mountHints: mountHints,
@@ -478,6 +529,11 @@ func (l *Loader) Destroy() {
 		l.stopSignalForwarding()
 	}
 	l.watchdog.Stop()
+	if l.oomHandler != nil {
+		l.oomHandler.Stop()
+	}
+	// Flush queued security events.
+	seccheck.Global.Stop()
 
 	// Stop the control server. This will indirectly stop any
 	// long-running control operations that are in flight, e.g.
@@ -508,7 +564,7 @@ func createPlatform(conf *config.Config, deviceFile *os.File) (platform.Platform
 	return p.New(deviceFile)
 }
 
-func createMemoryFile() (*pgalloc.MemoryFile, error) {
+func createMemoryFile(conf *config.Config) (*pgalloc.MemoryFile, error) {
 	const memfileName = "runsc-memory"
 	memfd, err := memutil.CreateMemFD(memfileName, 0)
 	if err != nil {
@@ -518,7 +574,10 @@ func createMemoryFile() (*pgalloc.MemoryFile, error) {
 	// We can't enable pgalloc.MemoryFileOpts.UseHostMemcgPressure even if
 	// there are memory cgroups specified, because at this point we're already
 	// in a mount namespace in which the relevant cgroupfs is not visible.
-	mf, err := pgalloc.NewMemoryFile(memfile, pgalloc.MemoryFileOpts{})
+	mf, err := pgalloc.NewMemoryFile(memfile, pgalloc.MemoryFileOpts{
+		CompressionWatermark: conf.MemoryCompressionWatermark,
+		FakeSwap:             conf.FakeSwap,
+	})
 	if err != nil {
 		_ = memfile.Close()
 		return nil, fmt.Errorf("error creating pgalloc.MemoryFile: %w", err)
@@ -630,9 +689,45 @@ func (l *Loader) run() error {
 
 	log.Infof("Process should have started...")
 	l.watchdog.Start()
+	if l.oomHandler != nil {
+		l.oomHandler.Start()
+	}
 	return l.k.Start()
 }
 
+// newOOMHandler returns the sentry's OOM handler for k, or nil if conf leaves
+// OOM handling to the host.
+// startSecurityEvents starts writing the security events selected by conf to
+// f. It is a no-op if f is nil.
+func startSecurityEvents(conf *config.Config, f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	points, err := seccheck.ParsePointSet(conf.SecurityEventPoints)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	sink := eventchannel.FileEmitter(f)
+	if err := seccheck.Global.Start(seccheck.Config{
+		Points:  points,
+		Sink:    sink,
+		MaxRate: float64(conf.SecurityEventRate),
+		Burst:   conf.SecurityEventRate,
+	}); err != nil {
+		sink.Close()
+		return err
+	}
+	return nil
+}
+
+func newOOMHandler(k *kernel.Kernel, conf *config.Config) *oom.Handler {
+	if conf.OOMPolicy == oom.PolicyNone {
+		return nil
+	}
+	return oom.New(k, conf.OOMLimit, conf.OOMPolicy)
+}
+
 // createSubcontainer creates a new container inside the sandbox.
 func (l *Loader) createSubcontainer(cid string, tty *fd.FD) error {
 	l.mu.Lock()
@@ -649,7 +744,7 @@ func (l *Loader) createSubcontainer(cid string, tty *fd.FD) error {
 // startSubcontainer starts a child container. It returns the thread group ID of
 // the newly created process. Used FDs are either closed or released. It's safe
 // for the caller to close any remaining files upon return.
-func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid string, stdioFDs, goferFDs []*fd.FD) error {
+func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid string, stdioFDs, goferFDs []*fd.FD, syscallPolicy *syspolicy.Profile) error {
 	// Create capabilities.
 	caps, err := specutils.Capabilities(conf.EnableRaw, spec.Process.Capabilities)
 	if err != nil {
@@ -700,14 +795,16 @@ func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid st
 	}
 
 	info := &containerInfo{
-		conf:     conf,
-		spec:     spec,
-		goferFDs: goferFDs,
+		conf:          conf,
+		spec:          spec,
+		goferFDs:      goferFDs,
+		syscallPolicy: syscallPolicy,
 	}
 	info.procArgs, err = createProcessArgs(cid, spec, creds, l.k, pidns)
 	if err != nil {
 		return fmt.Errorf("creating new process: %w", err)
 	}
+	info.procArgs.ResolveBeneath = conf.ResolveBeneath
 
 	// Use stdios or TTY depending on the spec configuration.
 	if spec.Process.Terminal {
@@ -773,6 +870,12 @@ func (l *Loader) createContainerProcess(root bool, cid string, info *containerIn
 	}
 	info.procArgs.Envv = envv
 
+	// Install the container's syscall policy before its first task is
+	// created, so that every task in the container inherits it.
+	if err := l.installSyscallPolicy(cid, info.syscallPolicy); err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Create and start the new process.
 	tg, _, err := l.k.CreateProcess(info.procArgs)
 	if err != nil {
@@ -818,6 +921,27 @@ func (l *Loader) createContainerProcess(root bool, cid string, info *containerIn
 	return tg, ttyFile, ttyFileVFS2, nil
 }
 
+// installSyscallPolicy compiles the given profile and enforces it on tasks
+// subsequently created in the container. A nil profile removes the container's
+// policy.
+func (l *Loader) installSyscallPolicy(cid string, profile *syspolicy.Profile) error {
+	if profile == nil {
+		l.k.SetSyscallPolicy(cid, nil)
+		return nil
+	}
+	table, ok := kernel.LookupSyscallTable(abi.Linux, arch.Host)
+	if !ok {
+		return fmt.Errorf("no syscall table found for %v/%v", abi.Linux, arch.Host)
+	}
+	policy, err := syspolicy.Compile(profile, table.LookupNo)
+	if err != nil {
+		return err
+	}
+	log.Infof("Installing syscall policy for container %q: %d rule(s)", cid, len(profile.Rules))
+	l.k.SetSyscallPolicy(cid, policy)
+	return nil
+}
+
 // startGoferMonitor runs a goroutine to monitor gofer's health. It polls on
 // the gofer FD looking for disconnects, and kills the container processes if
 // the rootfs FD disconnects.
@@ -902,12 +1026,17 @@ func (l *Loader) destroySubcontainer(cid string) error {
 			delete(l.processes, key)
 		}
 	}
+	for key := range l.dynamicMounts {
+		if key.cid == cid {
+			delete(l.dynamicMounts, key)
+		}
+	}
 
 	log.Debugf("Container destroyed, cid: %s", cid)
 	return nil
 }
 
-func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
+func (l *Loader) executeAsync(args *control.ExecArgs) (*control.ExecResult, error) {
 	// Hold the lock for the entire operation to ensure that exec'd process is
 	// added to 'processes' in case it races with destroyContainer().
 	l.mu.Lock()
@@ -915,10 +1044,10 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 
 	tg, err := l.tryThreadGroupFromIDLocked(execID{cid: args.ContainerID})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	if tg == nil {
-		return 0, fmt.Errorf("container %q not started", args.ContainerID)
+		return nil, fmt.Errorf("container %q not started", args.ContainerID)
 	}
 
 	// Get the container MountNamespace from the Task. Try to acquire ref may fail
@@ -927,7 +1056,7 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 		// task.MountNamespaceVFS2() does not take a ref, so we must do so ourselves.
 		args.MountNamespaceVFS2 = tg.Leader().MountNamespaceVFS2()
 		if !args.MountNamespaceVFS2.TryIncRef() {
-			return 0, fmt.Errorf("container %q has stopped", args.ContainerID)
+			return nil, fmt.Errorf("container %q has stopped", args.ContainerID)
 		}
 	} else {
 		var reffed bool
@@ -937,14 +1066,15 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 			reffed = args.MountNamespace.TryIncRef()
 		})
 		if !reffed {
-			return 0, fmt.Errorf("container %q has stopped", args.ContainerID)
+			return nil, fmt.Errorf("container %q has stopped", args.ContainerID)
 		}
 	}
 
 	args.Envv, err = specutils.ResolveEnvs(args.Envv)
 	if err != nil {
-		return 0, fmt.Errorf("resolving env: %w", err)
+		return nil, fmt.Errorf("resolving env: %w", err)
 	}
+	args.ResolveBeneath = l.root.conf.ResolveBeneath
 
 	// Add the HOME environment variable if it is not already set.
 	if kernel.VFS2Enabled {
@@ -953,7 +1083,7 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 		defer args.MountNamespaceVFS2.DecRef(ctx)
 		envv, err := user.MaybeAddExecUserHomeVFS2(ctx, args.MountNamespaceVFS2, args.KUID, args.Envv)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 		args.Envv = envv
 	} else {
@@ -963,7 +1093,7 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 		defer root.DecRef(ctx)
 		envv, err := user.MaybeAddExecUserHome(ctx, args.MountNamespace, args.KUID, args.Envv)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 		args.Envv = envv
 	}
@@ -971,14 +1101,14 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 
 	args.Limits, err = createLimitSet(l.root.spec)
 	if err != nil {
-		return 0, fmt.Errorf("creating limits: %w", err)
+		return nil, fmt.Errorf("creating limits: %w", err)
 	}
 
 	// Start the process.
 	proc := control.Proc{Kernel: l.k}
-	newTG, tgid, ttyFile, ttyFileVFS2, err := control.ExecAsync(&proc, args)
+	newTG, tgid, namespaces, ttyFile, ttyFileVFS2, err := control.ExecAsync(&proc, args)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	eid := execID{cid: args.ContainerID, pid: tgid}
@@ -989,7 +1119,10 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 	}
 	log.Debugf("updated processes: %v", l.processes)
 
-	return tgid, nil
+	return &control.ExecResult{
+		PID:        int32(tgid),
+		Namespaces: namespaces,
+	}, nil
 }
 
 // waitContainer waits for the init process of a container to exit.
@@ -1111,8 +1244,11 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, uniqueID stack.UniqueID) (in
 		udp.NewProtocol,
 		icmp.NewProtocol4,
 		icmp.NewProtocol6,
+		tunnel.NewGREProtocol,
+		tunnel.NewIPIPProtocol,
 	}
-	s := netstack.Stack{Stack: stack.New(stack.Options{
+	s := &netstack.Stack{}
+	s.Stack = stack.New(stack.Options{
 		NetworkProtocols:   netProtos,
 		TransportProtocols: transProtos,
 		Clock:              clock,
@@ -1123,7 +1259,9 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, uniqueID stack.UniqueID) (in
 		RawFactory:      raw.EndpointFactory{},
 		UniqueID:        uniqueID,
 		DefaultIPTables: netfilter.DefaultLinuxTables,
-	})}
+		// Report neighbor table changes to rtnetlink.
+		NUDDisp: s,
+	})
 
 	// Enable SACK Recovery.
 	{
@@ -1152,7 +1290,7 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, uniqueID stack.UniqueID) (in
 		}
 	}
 
-	return &s, nil
+	return s, nil
 }
 
 // sandboxNetstackCreator implements kernel.NetworkStackCreator.
@@ -16,8 +16,10 @@ package boot
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path"
 	"reflect"
 	"testing"
 	"time"
@@ -516,6 +518,56 @@ func TestCreateMountNamespaceVFS2(t *testing.T) {
 	}
 }
 
+// TestPrefetchVFS2 checks that paths listed in --prefetch-paths are cached
+// when the container's filesystem is mounted, by resolving them again after
+// the gofer has stopped.
+func TestPrefetchVFS2(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(path.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatalf("MkdirAll(): %v", err)
+	}
+	if err := ioutil.WriteFile(path.Join(root, "a", "b", "file"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+
+	spec := testSpec()
+	spec.Root = &specs.Root{Path: root, Readonly: true}
+	l, loaderCleanup, err := createLoader(true /* VFS2 Enabled */, spec)
+	if err != nil {
+		t.Fatalf("failed to create loader: %v", err)
+	}
+	defer l.Destroy()
+	defer loaderCleanup()
+
+	l.root.conf.PrefetchPaths = "/a/b/file,/nonexistent"
+	mntr := newContainerMounter(&l.root, l.k, l.mountHints, true /* vfs2Enabled */)
+	mns, err := mntr.mountAll(l.root.conf, &l.root.procArgs)
+	if err != nil {
+		t.Fatalf("mountAll: %v", err)
+	}
+
+	// Any RPC to the gofer fails from now on.
+	loaderCleanup()
+
+	ctx := l.k.SupervisorContext()
+	vfsRoot := mns.Root()
+	vfsRoot.IncRef()
+	defer vfsRoot.DecRef(ctx)
+	for _, p := range []string{"/a", "/a/b", "/a/b/file"} {
+		target := &vfs.PathOperation{
+			Root:  vfsRoot,
+			Start: vfsRoot,
+			Path:  fspath.Parse(p),
+		}
+		d, err := l.k.VFS().GetDentryAt(ctx, l.root.procArgs.Credentials, target, &vfs.GetDentryOptions{})
+		if err != nil {
+			t.Errorf("resolving prefetched path %q: %v", p, err)
+			continue
+		}
+		d.DecRef(ctx)
+	}
+}
+
 // TestRestoreEnvironment tests that the correct mounts are collected from the spec and config
 // in order to build the environment for restoring.
 func TestRestoreEnvironment(t *testing.T) {
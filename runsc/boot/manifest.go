@@ -0,0 +1,268 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/runsc/config"
+)
+
+// Version is the runsc version recorded in checkpoint manifests. It is set
+// by cli.Main.
+var Version = "VERSION_MISSING"
+
+const (
+	// ManifestMetadataKey is the statefile metadata key under which the
+	// checkpoint manifest is stored.
+	ManifestMetadataKey = "runsc_manifest"
+
+	// manifestVersion is the version of the CheckpointManifest format. It must
+	// be incremented when fields are removed or their meaning changes.
+	manifestVersion = 1
+)
+
+// Names of mismatches that can be overridden using
+// --restore-allow-mismatch.
+const (
+	mismatchVersion  = "version"
+	mismatchPlatform = "platform"
+	mismatchDevices  = "devices"
+)
+
+// CheckpointManifest describes the configuration of the sandbox that produced
+// a checkpoint, such that a restore can be checked for compatibility before
+// loading any state.
+type CheckpointManifest struct {
+	// ManifestVersion is the version of the manifest format.
+	ManifestVersion int `json:"manifest_version"`
+
+	// RunscVersion is the version of runsc that produced the checkpoint.
+	RunscVersion string `json:"runsc_version"`
+
+	// VFS2 is true if the sandbox used VFS2.
+	VFS2 bool `json:"vfs2"`
+
+	// Network is the network stack used by the sandbox.
+	Network string `json:"network"`
+
+	// Platform is the platform used by the sandbox.
+	Platform string `json:"platform"`
+
+	// Mounts maps the destination of each mount in the root container's spec
+	// to its type.
+	Mounts map[string]string `json:"mounts"`
+
+	// Devices is the sorted list of device paths in the root container's
+	// spec.
+	Devices []string `json:"devices,omitempty"`
+}
+
+// NewCheckpointManifest returns the manifest for a sandbox running with the
+// given configuration and root container spec.
+func NewCheckpointManifest(conf *config.Config, spec *specs.Spec) *CheckpointManifest {
+	m := &CheckpointManifest{
+		ManifestVersion: manifestVersion,
+		RunscVersion:    Version,
+		VFS2:            conf.VFS2,
+		Network:         conf.Network.String(),
+		Platform:        conf.Platform,
+		Mounts:          make(map[string]string),
+	}
+	for _, mnt := range spec.Mounts {
+		m.Mounts[mnt.Destination] = mnt.Type
+	}
+	if spec.Linux != nil {
+		for _, dev := range spec.Linux.Devices {
+			m.Devices = append(m.Devices, dev.Path)
+		}
+		sort.Strings(m.Devices)
+	}
+	return m
+}
+
+// AddToMetadata stores m in the given statefile metadata.
+func (m *CheckpointManifest) AddToMetadata(metadata map[string]string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint manifest: %w", err)
+	}
+	metadata[ManifestMetadataKey] = string(b)
+	return nil
+}
+
+// ManifestFromMetadata returns the manifest stored in the given statefile
+// metadata. It returns nil if the checkpoint predates manifests.
+func ManifestFromMetadata(metadata map[string]string) (*CheckpointManifest, error) {
+	val, ok := metadata[ManifestMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	var m CheckpointManifest
+	if err := json.Unmarshal([]byte(val), &m); err != nil {
+		return nil, fmt.Errorf("decoding checkpoint manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// String returns a human-readable description of m.
+func (m *CheckpointManifest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Manifest version: %d\n", m.ManifestVersion)
+	fmt.Fprintf(&b, "runsc version:    %s\n", m.RunscVersion)
+	fmt.Fprintf(&b, "VFS2:             %t\n", m.VFS2)
+	fmt.Fprintf(&b, "Network:          %s\n", m.Network)
+	fmt.Fprintf(&b, "Platform:         %s\n", m.Platform)
+	fmt.Fprintf(&b, "Mounts:\n")
+	for _, dst := range sortedKeys(m.Mounts) {
+		fmt.Fprintf(&b, "  %s (%s)\n", dst, m.Mounts[dst])
+	}
+	fmt.Fprintf(&b, "Devices:\n")
+	for _, dev := range m.Devices {
+		fmt.Fprintf(&b, "  %s\n", dev)
+	}
+	return b.String()
+}
+
+// ManifestMismatch describes an item that differs between the configuration
+// that produced a checkpoint and the one restoring it.
+type ManifestMismatch struct {
+	// Item names the mismatched item.
+	Item string
+
+	// Saved and Current are the item's values in the checkpoint and in the
+	// restoring configuration respectively.
+	Saved   string
+	Current string
+
+	// Override is the value of --restore-allow-mismatch that permits the
+	// restore to proceed despite the mismatch. If Override is empty, the
+	// mismatch is fatal.
+	Override string
+}
+
+// Fatal returns true if the mismatch can't be overridden.
+func (mm ManifestMismatch) Fatal() bool {
+	return mm.Override == ""
+}
+
+// String implements fmt.Stringer.String.
+func (mm ManifestMismatch) String() string {
+	action := "fatal"
+	if !mm.Fatal() {
+		action = fmt.Sprintf("override with --restore-allow-mismatch=%s", mm.Override)
+	}
+	return fmt.Sprintf("%s: checkpointed with %q, restoring with %q (%s)", mm.Item, mm.Saved, mm.Current, action)
+}
+
+// CompatibilityReport lists the differences between the configuration that
+// produced a checkpoint and the one restoring it.
+type CompatibilityReport struct {
+	Mismatches []ManifestMismatch
+}
+
+// String implements fmt.Stringer.String.
+func (r *CompatibilityReport) String() string {
+	if len(r.Mismatches) == 0 {
+		return "no mismatches"
+	}
+	lines := make([]string, 0, len(r.Mismatches))
+	for _, mm := range r.Mismatches {
+		lines = append(lines, mm.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Err returns an error describing all mismatches in r that are not permitted
+// by allowed, a comma-separated list of overrides as for
+// --restore-allow-mismatch, or nil if there are none.
+func (r *CompatibilityReport) Err(allowed string) error {
+	allow := make(map[string]bool)
+	for _, o := range strings.Split(allowed, ",") {
+		allow[strings.TrimSpace(o)] = true
+	}
+	var lines []string
+	for _, mm := range r.Mismatches {
+		if mm.Fatal() || !allow[mm.Override] {
+			lines = append(lines, "  "+mm.String())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return fmt.Errorf("checkpoint is incompatible with the current configuration:\n%s", strings.Join(lines, "\n"))
+}
+
+// Check compares saved, the manifest of a checkpoint, to m, the manifest of
+// the configuration restoring it.
+func (m *CheckpointManifest) Check(saved *CheckpointManifest) *CompatibilityReport {
+	r := &CompatibilityReport{}
+	add := func(item, saved, current, override string) {
+		r.Mismatches = append(r.Mismatches, ManifestMismatch{
+			Item:     item,
+			Saved:    saved,
+			Current:  current,
+			Override: override,
+		})
+	}
+	if saved.ManifestVersion > m.ManifestVersion {
+		// Nothing else in the manifest can be trusted.
+		add("manifest version", fmt.Sprint(saved.ManifestVersion), fmt.Sprint(m.ManifestVersion), "")
+		return r
+	}
+	if saved.RunscVersion != m.RunscVersion {
+		add("runsc version", saved.RunscVersion, m.RunscVersion, mismatchVersion)
+	}
+	if saved.VFS2 != m.VFS2 {
+		add("vfs2", fmt.Sprint(saved.VFS2), fmt.Sprint(m.VFS2), "")
+	}
+	if saved.Network != m.Network {
+		add("network", saved.Network, m.Network, "")
+	}
+	if saved.Platform != m.Platform {
+		add("platform", saved.Platform, m.Platform, mismatchPlatform)
+	}
+	// Restored mounts are reattached by destination, so every checkpointed
+	// mount must exist with the same type.
+	for _, dst := range sortedKeys(saved.Mounts) {
+		if typ, ok := m.Mounts[dst]; !ok {
+			add("mount "+dst, saved.Mounts[dst], "<none>", "")
+		} else if typ != saved.Mounts[dst] {
+			add("mount "+dst, saved.Mounts[dst], typ, "")
+		}
+	}
+	for _, dst := range sortedKeys(m.Mounts) {
+		if _, ok := saved.Mounts[dst]; !ok {
+			add("mount "+dst, "<none>", m.Mounts[dst], "")
+		}
+	}
+	if savedDevs, curDevs := strings.Join(saved.Devices, ","), strings.Join(m.Devices, ","); savedDevs != curDevs {
+		add("devices", savedDevs, curDevs, mismatchDevices)
+	}
+	return r
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
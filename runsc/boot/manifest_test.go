@@ -0,0 +1,141 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/runsc/config"
+)
+
+func manifestTestSpec() *specs.Spec {
+	return &specs.Spec{
+		Mounts: []specs.Mount{
+			{Destination: "/proc", Type: "proc"},
+			{Destination: "/tmp", Type: "tmpfs"},
+		},
+		Linux: &specs.Linux{
+			Devices: []specs.LinuxDevice{{Path: "/dev/fuse"}},
+		},
+	}
+}
+
+func TestManifestMetadataRoundTrip(t *testing.T) {
+	m := NewCheckpointManifest(&config.Config{Platform: "ptrace", VFS2: true}, manifestTestSpec())
+	metadata := make(map[string]string)
+	if err := m.AddToMetadata(metadata); err != nil {
+		t.Fatalf("AddToMetadata failed: %v", err)
+	}
+	got, err := ManifestFromMetadata(metadata)
+	if err != nil {
+		t.Fatalf("ManifestFromMetadata failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("ManifestFromMetadata: got %+v, want %+v", got, m)
+	}
+
+	got, err = ManifestFromMetadata(map[string]string{})
+	if err != nil || got != nil {
+		t.Errorf("ManifestFromMetadata without manifest: got (%v, %v), want (nil, nil)", got, err)
+	}
+}
+
+func TestManifestCheck(t *testing.T) {
+	saved := NewCheckpointManifest(&config.Config{Platform: "ptrace", VFS2: true}, manifestTestSpec())
+
+	for _, test := range []struct {
+		name    string
+		modify  func(conf *config.Config, spec *specs.Spec)
+		items   []string
+		allowed string
+		wantErr bool
+	}{
+		{
+			name:   "identical",
+			modify: func(*config.Config, *specs.Spec) {},
+		},
+		{
+			name: "platform",
+			modify: func(conf *config.Config, _ *specs.Spec) {
+				conf.Platform = "kvm"
+			},
+			items:   []string{"platform"},
+			wantErr: true,
+		},
+		{
+			name: "platform allowed",
+			modify: func(conf *config.Config, _ *specs.Spec) {
+				conf.Platform = "kvm"
+			},
+			items:   []string{"platform"},
+			allowed: "version,platform",
+		},
+		{
+			name: "vfs2 can't be allowed",
+			modify: func(conf *config.Config, _ *specs.Spec) {
+				conf.VFS2 = false
+			},
+			items:   []string{"vfs2"},
+			allowed: "vfs2",
+			wantErr: true,
+		},
+		{
+			name: "mounts",
+			modify: func(_ *config.Config, spec *specs.Spec) {
+				spec.Mounts[1].Type = "bind"
+				spec.Mounts = append(spec.Mounts, specs.Mount{Destination: "/data", Type: "bind"})
+			},
+			items:   []string{"mount /tmp", "mount /data"},
+			wantErr: true,
+		},
+		{
+			name: "devices",
+			modify: func(_ *config.Config, spec *specs.Spec) {
+				spec.Linux.Devices = nil
+			},
+			items:   []string{"devices"},
+			allowed: "devices",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := &config.Config{Platform: "ptrace", VFS2: true}
+			spec := manifestTestSpec()
+			test.modify(conf, spec)
+			report := NewCheckpointManifest(conf, spec).Check(saved)
+			var items []string
+			for _, mm := range report.Mismatches {
+				items = append(items, mm.Item)
+			}
+			if !reflect.DeepEqual(items, test.items) {
+				t.Errorf("got mismatched items %q, want %q", items, test.items)
+			}
+			if err := report.Err(test.allowed); (err != nil) != test.wantErr {
+				t.Errorf("Err(%q): got %v, want error: %t", test.allowed, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestCheckNewerFormat(t *testing.T) {
+	saved := NewCheckpointManifest(&config.Config{}, &specs.Spec{})
+	saved.ManifestVersion++
+	saved.Platform = "kvm"
+	report := NewCheckpointManifest(&config.Config{}, &specs.Spec{}).Check(saved)
+	if len(report.Mismatches) != 1 || !report.Mismatches[0].Fatal() {
+		t.Errorf("got mismatches %v, want a single fatal manifest version mismatch", report.Mismatches)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/devices/memdev"
@@ -202,9 +203,31 @@ func (c *containerMounter) mountAll(conf *config.Config, procArgs *kernel.Create
 		return nil, fmt.Errorf("mounting submounts vfs2: %w", err)
 	}
 
+	c.prefetchVFS2(rootCtx, conf, root, rootCreds)
+
 	return mns, nil
 }
 
+// prefetchVFS2 resolves the paths listed in conf.PrefetchPaths, so that their
+// dentries and the gofer files backing them are cached before the container
+// starts. Paths that can't be resolved are skipped.
+func (c *containerMounter) prefetchVFS2(ctx context.Context, conf *config.Config, root vfs.VirtualDentry, creds *auth.Credentials) {
+	for _, p := range conf.PrefetchPathList() {
+		pop := vfs.PathOperation{
+			Root:               root,
+			Start:              root,
+			Path:               fspath.Parse(p),
+			FollowFinalSymlink: true,
+		}
+		vd, err := c.k.VFS().GetDentryAt(ctx, creds, &pop, &vfs.GetDentryOptions{})
+		if err != nil {
+			log.Infof("Prefetching %q: %v", p, err)
+			continue
+		}
+		vd.DecRef(ctx)
+	}
+}
+
 // createMountNamespaceVFS2 creates the container's root mount and namespace.
 func (c *containerMounter) createMountNamespaceVFS2(ctx context.Context, conf *config.Config, creds *auth.Credentials) (*vfs.MountNamespace, error) {
 	fd := c.fds.remove()
@@ -515,7 +538,14 @@ func (c *containerMounter) getMountNameAndOptionsVFS2(conf *config.Config, m *mo
 			// but unlikely to be correct in this context.
 			return "", nil, false, fmt.Errorf("9P mount requires a connection FD")
 		}
-		data = p9MountData(m.fd, c.getMountAccessType(conf, m.mount), true /* vfs2 */)
+		var err error
+		data, err = c.goferMountData(m.fd, conf, m.mount, true /* vfs2 */)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if specutils.ContainsStr(m.mount.Options, specutils.InodeFlagsMountOption) {
+			data = append(data, "inodeflags")
+		}
 		internalData = gofer.InternalFilesystemOptions{
 			UniqueID: m.mount.Destination,
 		}
@@ -553,9 +583,19 @@ func (c *containerMounter) getMountNameAndOptionsVFS2(conf *config.Config, m *mo
 			opts.Flags.NoATime = true
 		case "noexec":
 			opts.Flags.NoExec = true
+		case "nodev":
+			opts.Flags.NoDev = true
+		case "nosuid":
+			opts.Flags.NoSUID = true
 		case "bind", "rbind":
 			// These are the same as a mount with type="bind".
+		case specutils.InodeFlagsMountOption:
+			// Handled above for gofer mounts.
 		default:
+			if specutils.IsCacheMountOption(o) {
+				// Handled above for gofer mounts.
+				continue
+			}
 			log.Warningf("ignoring unknown mount option %q", o)
 		}
 	}
@@ -828,3 +868,153 @@ func (c *containerMounter) configureRestore(ctx context.Context) (context.Contex
 	}
 	return context.WithValue(ctx, gofer.CtxRestoreServerFDMap, fdmap), nil
 }
+
+// mount mounts the directory served by the gofer connected to goferFD at
+// m.Destination in the mount namespace of the running container cid.
+func (l *Loader) mount(cid string, m *specs.Mount, goferFD *fd.FD) error {
+	defer goferFD.Close()
+	if !kernel.VFS2Enabled {
+		return fmt.Errorf("adding mounts to a running container requires VFS2")
+	}
+
+	if err := specutils.ValidateMountOptions(m.Options); err != nil {
+		return err
+	}
+	// Propagation options only apply to the host mount served by the gofer.
+	// The sentry does not implement mount propagation, so the new mount is
+	// always private.
+	var opts []string
+	for _, o := range m.Options {
+		if !specutils.IsPropagationOption(o) {
+			opts = append(opts, o)
+		}
+	}
+	mount := *m
+	mount.Type = bind
+	mount.Options = opts
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	mntns, err := l.mountNamespaceLocked(cid)
+	if err != nil {
+		return err
+	}
+	ctx := l.k.SupervisorContext()
+	defer mntns.DecRef(ctx)
+	creds := auth.NewRootCredentials(l.k.RootUserNamespace())
+	root := mntns.Root()
+	root.IncRef()
+	defer root.DecRef(ctx)
+
+	key := dynamicMount{cid: cid, destination: path.Clean(m.Destination)}
+	if l.dynamicMountLocked(ctx, creds, root, key) {
+		return fmt.Errorf("%q is already mounted in container %q", m.Destination, cid)
+	}
+
+	// Refuse to cover an existing mount.
+	vd, err := l.k.VFS().GetDentryAt(ctx, creds, &vfs.PathOperation{
+		Root:  root,
+		Start: root,
+		Path:  fspath.Parse(m.Destination),
+	}, &vfs.GetDentryOptions{})
+	if err == nil {
+		isMountpoint := vd.Dentry() == vd.Mount().Root()
+		vd.DecRef(ctx)
+		if isMountpoint {
+			return fmt.Errorf("%q is already a mount point in container %q", m.Destination, cid)
+		}
+	}
+
+	mounter := &containerMounter{
+		k:     l.k,
+		hints: l.mountHints,
+	}
+	mnt, err := mounter.mountSubmountVFS2(ctx, l.root.conf, mntns, creds, &mountAndFD{
+		mount: &mount,
+		fd:    goferFD.Release(),
+	})
+	if err != nil {
+		return err
+	}
+	if l.dynamicMounts == nil {
+		l.dynamicMounts = make(map[dynamicMount]uint64)
+	}
+	l.dynamicMounts[key] = mnt.ID
+	return nil
+}
+
+// unmount lazily unmounts the mount at destination in the running container
+// cid, which must have been added by Loader.mount. The gofer serving the
+// mount exits once the last reference on the mount is dropped.
+func (l *Loader) unmount(cid, destination string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	mntns, err := l.mountNamespaceLocked(cid)
+	if err != nil {
+		return err
+	}
+	ctx := vfs.WithMountNamespace(l.k.SupervisorContext(), mntns)
+	defer mntns.DecRef(ctx)
+	creds := auth.NewRootCredentials(l.k.RootUserNamespace())
+	root := mntns.Root()
+	root.IncRef()
+	defer root.DecRef(ctx)
+
+	key := dynamicMount{cid: cid, destination: path.Clean(destination)}
+	if !l.dynamicMountLocked(ctx, creds, root, key) {
+		return fmt.Errorf("%q was not mounted in container %q by runsc", destination, cid)
+	}
+	if err := l.k.VFS().UmountAt(ctx, creds, &vfs.PathOperation{
+		Root:  root,
+		Start: root,
+		Path:  fspath.Parse(destination),
+	}, &vfs.UmountOptions{
+		Flags: linux.MNT_DETACH,
+	}); err != nil {
+		return fmt.Errorf("unmounting %q in container %q: %w", destination, cid, err)
+	}
+	delete(l.dynamicMounts, key)
+	return nil
+}
+
+// dynamicMountLocked returns true if the mount that Loader.mount added for key
+// is still mounted at key.destination in the mount namespace rooted at root.
+// The container may unmount or move the mount on its own, so l.dynamicMounts
+// is reconciled against the mount tree here and stale entries are removed.
+//
+// Preconditions: l.mu must be locked.
+func (l *Loader) dynamicMountLocked(ctx context.Context, creds *auth.Credentials, root vfs.VirtualDentry, key dynamicMount) bool {
+	id, ok := l.dynamicMounts[key]
+	if !ok {
+		return false
+	}
+	// Mount IDs are never reused, so the mount is ours if a mount with its
+	// ID is still connected at the same place.
+	ms, err := l.k.VFS().StatMount(ctx, creds, root, id, linux.STATMOUNT_MNT_POINT)
+	if err == nil && ms.MntPoint == key.destination {
+		return true
+	}
+	delete(l.dynamicMounts, key)
+	return false
+}
+
+// mountNamespaceLocked returns the mount namespace of the running container
+// cid. A reference is taken on the returned MountNamespace.
+//
+// Preconditions: l.mu must be locked.
+func (l *Loader) mountNamespaceLocked(cid string) (*vfs.MountNamespace, error) {
+	tg, err := l.tryThreadGroupFromIDLocked(execID{cid: cid})
+	if err != nil {
+		return nil, err
+	}
+	if tg == nil {
+		return nil, fmt.Errorf("container %q not started", cid)
+	}
+	mntns := tg.Leader().MountNamespaceVFS2()
+	if mntns == nil || !mntns.TryIncRef() {
+		return nil, fmt.Errorf("container %q has stopped", cid)
+	}
+	return mntns, nil
+}
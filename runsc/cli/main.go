@@ -32,6 +32,7 @@ import (
 	"gvisor.dev/gvisor/pkg/refs"
 	"gvisor.dev/gvisor/pkg/refsvfs2"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/cmd"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/flag"
@@ -57,6 +58,8 @@ var (
 
 // Main is the main entrypoint.
 func Main(version string) {
+	boot.Version = version
+
 	// Help and flags commands are generated automatically.
 	help := cmd.NewHelp(subcommands.DefaultCommander)
 	help.Register(new(cmd.Syscalls))
@@ -70,6 +73,7 @@ func Main(version string) {
 
 	// Register user-facing runsc commands.
 	subcommands.Register(new(cmd.Checkpoint), "")
+	subcommands.Register(new(cmd.CheckpointInspect), "")
 	subcommands.Register(new(cmd.Create), "")
 	subcommands.Register(new(cmd.Delete), "")
 	subcommands.Register(new(cmd.Do), "")
@@ -78,6 +82,7 @@ func Main(version string) {
 	subcommands.Register(new(cmd.Gofer), "")
 	subcommands.Register(new(cmd.Kill), "")
 	subcommands.Register(new(cmd.List), "")
+	subcommands.Register(new(cmd.Mount), "")
 	subcommands.Register(new(cmd.Pause), "")
 	subcommands.Register(new(cmd.PS), "")
 	subcommands.Register(new(cmd.Restore), "")
@@ -24,6 +24,7 @@ import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/config"
@@ -71,6 +72,17 @@ type Boot struct {
 	// userLogFD is the file descriptor to write user logs to.
 	userLogFD int
 
+	// syscallPolicyFD is the file descriptor to read the root container's
+	// syscall policy profile from.
+	syscallPolicyFD int
+
+	// securityEventsFD is the file descriptor to write security events to.
+	securityEventsFD int
+
+	// hostCPUStatFD is the file descriptor to read the sandbox's host cgroup
+	// cpu.stat from.
+	hostCPUStatFD int
+
 	// startSyncFD is the file descriptor to synchronize runsc and sandbox.
 	startSyncFD int
 
@@ -117,6 +129,9 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&b.cpuNum, "cpu-num", 0, "number of CPUs to create inside the sandbox")
 	f.Uint64Var(&b.totalMem, "total-memory", 0, "sets the initial amount of total memory to report back to the container")
 	f.IntVar(&b.userLogFD, "user-log-fd", 0, "file descriptor to write user logs to. 0 means no logging.")
+	f.IntVar(&b.syscallPolicyFD, "syscall-policy-fd", -1, "file descriptor to read the root container's syscall policy profile from.")
+	f.IntVar(&b.securityEventsFD, "security-events-fd", -1, "file descriptor to write security events to.")
+	f.IntVar(&b.hostCPUStatFD, "host-cpu-stat-fd", -1, "file descriptor to read the sandbox's host cgroup cpu.stat from.")
 	f.IntVar(&b.startSyncFD, "start-sync-fd", -1, "required FD to used to synchronize sandbox startup")
 	f.IntVar(&b.mountsFD, "mounts-fd", -1, "mountsFD is the file descriptor to read list of mounts after they have been resolved (direct paths, no symlinks).")
 	f.BoolVar(&b.attached, "attached", false, "if attached is true, kills the sandbox process when the parent process terminates")
@@ -211,18 +226,41 @@ func (b *Boot) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 	mountsFile.Close()
 	spec.Mounts = cleanMounts
 
+	var syscallPolicy *syspolicy.Profile
+	if b.syscallPolicyFD >= 0 {
+		policyFile := os.NewFile(uintptr(b.syscallPolicyFD), "syscall policy file")
+		syscallPolicy, err = syspolicy.ParseProfile(policyFile)
+		policyFile.Close()
+		if err != nil {
+			Fatalf("Error reading syscall policy: %v", err)
+		}
+	}
+
+	var securityEvents *os.File
+	if b.securityEventsFD >= 0 {
+		securityEvents = os.NewFile(uintptr(b.securityEventsFD), "security events file")
+	}
+
+	var hostCPUStat *os.File
+	if b.hostCPUStatFD >= 0 {
+		hostCPUStat = os.NewFile(uintptr(b.hostCPUStatFD), "host cpu.stat file")
+	}
+
 	// Create the loader.
 	bootArgs := boot.Args{
-		ID:           f.Arg(0),
-		Spec:         spec,
-		Conf:         conf,
-		ControllerFD: b.controllerFD,
-		Device:       os.NewFile(uintptr(b.deviceFD), "platform device"),
-		GoferFDs:     b.ioFDs.GetArray(),
-		StdioFDs:     b.stdioFDs.GetArray(),
-		NumCPU:       b.cpuNum,
-		TotalMem:     b.totalMem,
-		UserLogFD:    b.userLogFD,
+		ID:             f.Arg(0),
+		Spec:           spec,
+		Conf:           conf,
+		ControllerFD:   b.controllerFD,
+		Device:         os.NewFile(uintptr(b.deviceFD), "platform device"),
+		GoferFDs:       b.ioFDs.GetArray(),
+		StdioFDs:       b.stdioFDs.GetArray(),
+		NumCPU:         b.cpuNum,
+		TotalMem:       b.totalMem,
+		UserLogFD:      b.userLogFD,
+		SyscallPolicy:  syscallPolicy,
+		SecurityEvents: securityEvents,
+		HostCPUStat:    hostCPUStat,
 	}
 	l, err := boot.New(bootArgs)
 	if err != nil {
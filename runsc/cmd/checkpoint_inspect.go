@@ -0,0 +1,105 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/specutils"
+)
+
+// CheckpointInspect implements subcommands.Command for the
+// "checkpoint-inspect" command.
+type CheckpointInspect struct {
+	imagePath string
+	bundleDir string
+}
+
+// Name implements subcommands.Command.Name.
+func (*CheckpointInspect) Name() string {
+	return "checkpoint-inspect"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*CheckpointInspect) Synopsis() string {
+	return "print the manifest of a saved container image"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*CheckpointInspect) Usage() string {
+	return `checkpoint-inspect [flags] - print the configuration that produced a saved container image.
+
+If -bundle is given, also report how the image differs from a restore of that
+bundle with the current flags.
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (c *CheckpointInspect) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.imagePath, "image-path", "", "directory path to saved container image")
+	f.StringVar(&c.bundleDir, "bundle", "", "path to the root of a bundle directory to check the image against")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (c *CheckpointInspect) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 0 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	if c.imagePath == "" {
+		return Errorf("image-path flag must be provided")
+	}
+	conf := args[0].(*config.Config)
+
+	input, err := os.Open(filepath.Join(c.imagePath, checkpointFileName))
+	if err != nil {
+		return Errorf("opening image: %v", err)
+	}
+	defer input.Close()
+	metadata, err := statefile.MetadataUnsafe(input)
+	if err != nil {
+		return Errorf("reading metadata: %v", err)
+	}
+	saved, err := boot.ManifestFromMetadata(metadata)
+	if err != nil {
+		return Errorf("%v", err)
+	}
+	if saved == nil {
+		return Errorf("image has no manifest; it was produced by a runsc version without checkpoint manifests")
+	}
+	fmt.Print(saved)
+
+	if c.bundleDir == "" {
+		return subcommands.ExitSuccess
+	}
+	spec, err := specutils.ReadSpec(c.bundleDir, conf)
+	if err != nil {
+		return Errorf("reading spec: %v", err)
+	}
+	report := boot.NewCheckpointManifest(conf, spec).Check(saved)
+	fmt.Printf("Compatibility with %s:\n%s\n", c.bundleDir, report)
+	if err := report.Err(conf.RestoreAllowMismatch); err != nil {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/signal"
 	"strconv"
@@ -41,6 +42,9 @@ type Debug struct {
 	profileCPU   string
 	profileBlock string
 	profileMutex string
+	contention   string
+	blockRate    int
+	mutexFrac    int
 	trace        string
 	strace       string
 	logLevel     string
@@ -48,6 +52,8 @@ type Debug struct {
 	delay        time.Duration
 	duration     time.Duration
 	ps           bool
+	version      bool
+	policyStats  bool
 	cat          stringSlice
 }
 
@@ -74,14 +80,19 @@ func (d *Debug) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&d.profileCPU, "profile-cpu", "", "writes CPU profile to the given file.")
 	f.StringVar(&d.profileBlock, "profile-block", "", "writes block profile to the given file.")
 	f.StringVar(&d.profileMutex, "profile-mutex", "", "writes mutex profile to the given file.")
+	f.StringVar(&d.contention, "profile-contention", "", "writes block and mutex profiles collected over the same period to the given path with .block and .mutex suffixes.")
+	f.IntVar(&d.blockRate, "block-profile-rate", -1, "sets the sandbox's block profile rate outside of profile collection; 0 disables it.")
+	f.IntVar(&d.mutexFrac, "mutex-profile-fraction", -1, "sets the sandbox's mutex profile fraction outside of profile collection; 0 disables it.")
 	f.DurationVar(&d.delay, "delay", time.Hour, "amount of time to delay for collecting heap and goroutine profiles.")
-	f.DurationVar(&d.duration, "duration", time.Hour, "amount of time to wait for CPU and trace profiles.")
+	f.DurationVar(&d.duration, "duration", time.Hour, "amount of time to wait for CPU, block, mutex, contention and trace profiles.")
 	f.StringVar(&d.trace, "trace", "", "writes an execution trace to the given file.")
 	f.IntVar(&d.signal, "signal", -1, "sends signal to the sandbox")
 	f.StringVar(&d.strace, "strace", "", `A comma separated list of syscalls to trace. "all" enables all traces, "off" disables all.`)
 	f.StringVar(&d.logLevel, "log-level", "", "The log level to set: warning (0), info (1), or debug (2).")
 	f.StringVar(&d.logPackets, "log-packets", "", "A boolean value to enable or disable packet logging: true or false.")
 	f.BoolVar(&d.ps, "ps", false, "lists processes")
+	f.BoolVar(&d.version, "control-version", false, "negotiates the control API version with the sandbox and lists the control methods it serves")
+	f.BoolVar(&d.policyStats, "syscall-policy-stats", false, "prints the number of syscalls matched by each rule of the container's syscall policy")
 	f.Var(&d.cat, "cat", "reads files and print to standard output")
 }
 
@@ -205,6 +216,13 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		}
 		log.Infof("Logging options changed")
 	}
+	if d.blockRate >= 0 || d.mutexFrac >= 0 {
+		rates, err := c.Sandbox.SetContentionRates(d.blockRate, d.mutexFrac)
+		if err != nil {
+			return Errorf(err.Error())
+		}
+		log.Infof("Block profile rate %d, mutex profile fraction %d", rates.BlockRate, rates.MutexFraction)
+	}
 	if d.ps {
 		pList, err := c.Processes()
 		if err != nil {
@@ -216,6 +234,24 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		}
 		log.Infof(o)
 	}
+	if d.version {
+		v, err := c.Sandbox.ControlVersion()
+		if err != nil {
+			Fatalf("getting control API version: %v", err)
+		}
+		log.Infof("Control API version %d, methods: %s", v.APIVersion, strings.Join(v.Methods, ", "))
+	}
+	if d.policyStats {
+		stats, err := c.Sandbox.SyscallPolicyStats(c.ID)
+		if err != nil {
+			Fatalf("getting syscall policy stats: %v", err)
+		}
+		b, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			Fatalf("generating JSON: %v", err)
+		}
+		log.Infof("%s", b)
+	}
 
 	// Open profiling files.
 	var (
@@ -224,6 +260,9 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		traceFile *os.File
 		blockFile *os.File
 		mutexFile *os.File
+
+		contentionBlockFile *os.File
+		contentionMutexFile *os.File
 	)
 	if d.profileHeap != "" {
 		f, err := os.OpenFile(d.profileHeap, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -264,6 +303,20 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		defer f.Close()
 		mutexFile = f
 	}
+	if d.contention != "" {
+		f, err := os.OpenFile(d.contention+".block", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return Errorf("error opening contention block profile output: %v", err)
+		}
+		defer f.Close()
+		contentionBlockFile = f
+		f, err = os.OpenFile(d.contention+".mutex", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return Errorf("error opening contention mutex profile output: %v", err)
+		}
+		defer f.Close()
+		contentionMutexFile = f
+	}
 
 	// Collect profiles.
 	var (
@@ -273,6 +326,8 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		traceErr error
 		blockErr error
 		mutexErr error
+
+		contentionErr error
 	)
 	if heapFile != nil {
 		wg.Add(1)
@@ -309,6 +364,13 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 			mutexErr = c.Sandbox.MutexProfile(mutexFile, d.duration)
 		}()
 	}
+	if contentionBlockFile != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			contentionErr = c.Sandbox.ContentionProfile(contentionBlockFile, contentionMutexFile, d.duration)
+		}()
+	}
 
 	// Before sleeping, allow us to catch signals and try to exit
 	// gracefully before just exiting. If we can't wait for wg, then
@@ -364,6 +426,12 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		log.Infof("error collecting mutex profile: %v", mutexErr)
 		os.Remove(mutexFile.Name())
 	}
+	if contentionErr != nil {
+		errorCount++
+		log.Infof("error collecting contention profile: %v", contentionErr)
+		os.Remove(contentionBlockFile.Name())
+		os.Remove(contentionMutexFile.Name())
+	}
 
 	if errorCount > 0 {
 		return subcommands.ExitFailure
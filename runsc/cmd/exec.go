@@ -57,6 +57,14 @@ type Exec struct {
 	// file descriptor referencing the master end of the console's
 	// pseudoterminal.
 	consoleSocket string
+
+	// excludeNamespaces is the set of container namespaces that the new
+	// process does not join.
+	excludeNamespaces stringSlice
+
+	// usernsMap contains the UID and GID mappings of a new user namespace in
+	// which to run the new process.
+	usernsMap idMap
 }
 
 // Name implements subcommands.Command.Name.
@@ -100,6 +108,8 @@ func (ex *Exec) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&ex.pidFile, "pid-file", "", "filename that the container pid will be written to")
 	f.StringVar(&ex.internalPidFile, "internal-pid-file", "", "filename that the container-internal pid will be written to")
 	f.StringVar(&ex.consoleSocket, "console-socket", "", "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal")
+	f.Var(&ex.excludeNamespaces, "exclude-namespace", "container namespace that the process does not join, one of: ipc, mnt, pid, uts (e.g. '-exclude-namespace uts -exclude-namespace ipc')")
+	f.Var(&ex.usernsMap, "userns-map", "run the process in a new user namespace with the given UID and GID mapping (format: <id>:<parent-id>:<length>), may be repeated")
 }
 
 // Execute implements subcommands.Command.Execute. It starts a process in an
@@ -117,6 +127,10 @@ func (ex *Exec) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		Fatalf("loading sandbox: %v", err)
 	}
 
+	e.ExcludeNamespaces = ex.excludeNamespaces
+	e.UIDMap = ex.usernsMap
+	e.GIDMap = ex.usernsMap
+
 	log.Debugf("Exec arguments: %+v", e)
 	log.Debugf("Exec capabilities: %+v", e.Capabilities)
 
@@ -151,10 +165,12 @@ func (ex *Exec) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 
 func (ex *Exec) exec(conf *config.Config, c *container.Container, e *control.ExecArgs, waitStatus *unix.WaitStatus) subcommands.ExitStatus {
 	// Start the new process and get its pid.
-	pid, err := c.Execute(conf, e)
+	res, err := c.ExecuteWithResult(conf, e)
 	if err != nil {
 		return Errorf("executing processes for container: %v", err)
 	}
+	pid := res.PID
+	log.Infof("Exec'd process %d namespaces: %v", pid, res.Namespaces)
 
 	if e.StdioIsPty {
 		// Forward signals sent to this process to the foreground
@@ -454,3 +470,40 @@ func (u *user) Set(s string) error {
 	}
 	return nil
 }
+
+// idMap allows -userns-map to convey user namespace ID mappings, each
+// consisting of the first ID in the namespace, the first ID in the parent
+// namespace and the length of the range, separated by colons.
+type idMap []auth.IDMapEntry
+
+func (m *idMap) String() string {
+	return fmt.Sprintf("%+v", *m)
+}
+
+func (m *idMap) Get() interface{} {
+	return m
+}
+
+func (m *idMap) Set(s string) error {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("ID mapping must be of the form <id>:<parent-id>:<length>: %s", s)
+	}
+	var ids [3]uint32
+	for i, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return fmt.Errorf("couldn't parse ID mapping: %s", s)
+		}
+		ids[i] = uint32(id)
+	}
+	if ids[2] == 0 {
+		return fmt.Errorf("ID mapping length must be positive: %s", s)
+	}
+	*m = append(*m, auth.IDMapEntry{
+		FirstID:       ids[0],
+		FirstParentID: ids[1],
+		Length:        ids[2],
+	})
+	return nil
+}
@@ -58,6 +58,36 @@ func TestUser(t *testing.T) {
 	}
 }
 
+func TestIDMap(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    idMap
+		wantErr bool
+	}{
+		{input: "0:1000:1", want: idMap{{FirstID: 0, FirstParentID: 1000, Length: 1}}},
+		{input: "1:100000:65536", want: idMap{{FirstID: 1, FirstParentID: 100000, Length: 65536}}},
+		{input: "", wantErr: true},
+		{input: "0:1000", wantErr: true},
+		{input: "0:1000:0", wantErr: true},
+		{input: "0:-1:1", wantErr: true},
+		{input: "0:1000:1:2", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		var m idMap
+		if err := m.Set(tc.input); err != nil && tc.wantErr {
+			// We got an error and wanted one.
+			continue
+		} else if err == nil && tc.wantErr {
+			t.Errorf("idMap.Set(%s): got no error, but wanted one", tc.input)
+		} else if err != nil && !tc.wantErr {
+			t.Errorf("idMap.Set(%s): got error %v, but wanted none", tc.input, err)
+		} else if !cmp.Equal(m, tc.want) {
+			t.Errorf("idMap.Set(%s): got %+v, but wanted %+v", tc.input, m, tc.want)
+		}
+	}
+}
+
 func TestCLIArgs(t *testing.T) {
 	testCases := []struct {
 		ex       Exec
@@ -116,7 +116,13 @@ func (g *Gofer) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		// Note: minimal argument handling for the default case to keep it simple.
 		args := os.Args
 		args = append(args, "--apply-caps=false", "--setup-root=false")
-		Fatalf("setCapsAndCallSelf(%v, %v): %v", args, goferCaps, setCapsAndCallSelf(args, goferCaps))
+		caps := goferCaps
+		if inodeFlagsRequested(spec, conf) {
+			// Setting FS_IMMUTABLE_FL or FS_APPEND_FL requires
+			// CAP_LINUX_IMMUTABLE.
+			caps = withInodeFlagsCaps(goferCaps)
+		}
+		Fatalf("setCapsAndCallSelf(%v, %v): %v", args, caps, setCapsAndCallSelf(args, caps))
 		panic("unreachable")
 	}
 
@@ -179,6 +185,7 @@ func (g *Gofer) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 				ROMount:           isReadonlyMount(m.Options) || conf.Overlay,
 				HostUDS:           conf.FSGoferHostUDS,
 				EnableVerityXattr: conf.Verity,
+				EnableInodeFlags:  isInodeFlagsMount(m, conf),
 			}
 			ap, err := fsgofer.NewAttachPoint(m.Destination, cfg)
 			if err != nil {
@@ -205,6 +212,10 @@ func (g *Gofer) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		filter.InstallXattrFilters()
 	}
 
+	if inodeFlagsRequested(spec, conf) {
+		filter.InstallInodeFlagsFilters()
+	}
+
 	if err := filter.Install(); err != nil {
 		Fatalf("installing seccomp filters: %v", err)
 	}
@@ -262,6 +273,36 @@ func isReadonlyMount(opts []string) bool {
 	return false
 }
 
+// isInodeFlagsMount returns true if m is a gofer mount that allows getting
+// and setting inode flags.
+func isInodeFlagsMount(m specs.Mount, conf *config.Config) bool {
+	return specutils.Is9PMount(m, conf.VFS2) && specutils.ContainsStr(m.Options, specutils.InodeFlagsMountOption)
+}
+
+// inodeFlagsRequested returns true if any gofer mount in spec allows getting
+// and setting inode flags.
+func inodeFlagsRequested(spec *specs.Spec, conf *config.Config) bool {
+	for _, m := range spec.Mounts {
+		if isInodeFlagsMount(m, conf) {
+			return true
+		}
+	}
+	return false
+}
+
+// withInodeFlagsCaps returns a copy of caps that additionally includes
+// CAP_LINUX_IMMUTABLE.
+func withInodeFlagsCaps(caps *specs.LinuxCapabilities) *specs.LinuxCapabilities {
+	add := func(set []string) []string {
+		return append(append([]string(nil), set...), "CAP_LINUX_IMMUTABLE")
+	}
+	return &specs.LinuxCapabilities{
+		Bounding:  add(caps.Bounding),
+		Effective: add(caps.Effective),
+		Permitted: add(caps.Permitted),
+	}
+}
+
 func setupRootFS(spec *specs.Spec, conf *config.Config) error {
 	// Convert all shared mounts into slaves to be sure that nothing will be
 	// propagated outside of our namespace.
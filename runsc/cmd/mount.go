@@ -0,0 +1,110 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// Mount implements subcommands.Command for the "mount" command.
+type Mount struct {
+	readOnly    bool
+	propagation string
+}
+
+// Name implements subcommands.Command.Name.
+func (*Mount) Name() string {
+	return "mount"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*Mount) Synopsis() string {
+	return "add or remove bind mounts in a running container"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*Mount) Usage() string {
+	return `mount add [flags] <container id> <host path> <destination> - bind mount a host directory into the container.
+mount remove <container id> <destination> - lazily unmount a directory added with "mount add".
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (m *Mount) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&m.readOnly, "ro", false, "mount the directory read-only")
+	f.StringVar(&m.propagation, "propagation", "", "propagation of the host bind mount of the directory: private, rprivate, slave or rslave")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (m *Mount) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() < 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	conf := args[0].(*config.Config)
+
+	switch op := f.Arg(0); op {
+	case "add":
+		// Flags may follow the subcommand name.
+		if err := f.Parse(f.Args()[1:]); err != nil || f.NArg() != 3 {
+			f.Usage()
+			return subcommands.ExitUsageError
+		}
+		mnt := specs.Mount{
+			Source:      f.Arg(1),
+			Destination: f.Arg(2),
+			Type:        "bind",
+		}
+		if m.readOnly {
+			mnt.Options = append(mnt.Options, "ro")
+		}
+		if m.propagation != "" {
+			mnt.Options = append(mnt.Options, m.propagation)
+		}
+		cont := loadContainer(conf, f.Arg(0))
+		if err := cont.AddMount(conf, mnt); err != nil {
+			Fatalf("adding mount: %v", err)
+		}
+
+	case "remove":
+		if f.NArg() != 3 {
+			f.Usage()
+			return subcommands.ExitUsageError
+		}
+		cont := loadContainer(conf, f.Arg(1))
+		if err := cont.RemoveMount(f.Arg(2)); err != nil {
+			Fatalf("removing mount: %v", err)
+		}
+
+	default:
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	return subcommands.ExitSuccess
+}
+
+func loadContainer(conf *config.Config, id string) *container.Container {
+	cont, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		Fatalf("loading container: %v", err)
+	}
+	return cont
+}
@@ -19,8 +19,13 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/refs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/oom"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
 )
 
@@ -76,6 +81,11 @@ type Config struct {
 	// FSGoferHostUDS enables the gofer to mount a host UDS.
 	FSGoferHostUDS bool `flag:"fsgofer-host-uds"`
 
+	// PrefetchPaths is a comma-separated list of absolute paths inside the
+	// container that are resolved when the container starts, so that their
+	// first access doesn't need to wait on the gofer. VFS2 only.
+	PrefetchPaths string `flag:"prefetch-paths"`
+
 	// Network indicates what type of network to use.
 	Network NetworkType `flag:"network"`
 
@@ -124,16 +134,80 @@ type Config struct {
 	// WatchdogAction sets what action the watchdog takes when triggered.
 	WatchdogAction watchdog.Action `flag:"watchdog-action"`
 
+	// OOMPolicy sets what the sentry does when memory usage exceeds OOMLimit
+	// or an allocation fails. If it is oom.PolicyNone, OOM handling is left
+	// to the host.
+	OOMPolicy oom.Policy `flag:"oom-policy"`
+
+	// OOMLimit is the memory usage in bytes above which the sentry's OOM
+	// handler acts. 0 means no limit.
+	OOMLimit uint64 `flag:"oom-limit"`
+
+	// MemoryCompressionWatermark is the memory usage in bytes above which the
+	// sentry compresses cold application memory. 0 disables compression.
+	MemoryCompressionWatermark uint64 `flag:"memory-compression-watermark"`
+
+	// FakeSwap presents compressed memory to applications as swap in
+	// /proc/meminfo.
+	FakeSwap bool `flag:"fake-swap"`
+
+	// SyscallPolicy is the path to a JSON profile of syscalls that the sentry
+	// allows, denies or logs in every container of the sandbox. A container
+	// can use a different profile with the dev.gvisor.syscall-policy
+	// annotation.
+	SyscallPolicy string `flag:"syscall-policy"`
+
+	// MaxPathLen is the maximum length in bytes, including the terminating
+	// NUL, of paths passed to syscalls. It must not exceed PATH_MAX. 0 means
+	// PATH_MAX.
+	MaxPathLen int `flag:"max-path-len"`
+
+	// SecurityEvents is the path of a file to which security events are
+	// appended. If empty, security events are disabled.
+	SecurityEvents string `flag:"security-events"`
+
+	// SecurityEventPoints is a comma-separated list of the security events to
+	// report: execve, credentials, listen, syscall-policy-denial or all.
+	SecurityEventPoints string `flag:"security-event-points"`
+
+	// SecurityEventRate is the maximum number of security events reported
+	// per second. Events above the rate are dropped. 0 means no limit.
+	SecurityEventRate int `flag:"security-event-rate"`
+
+	// ResolveBeneath is a directory inside the container beneath which all
+	// path resolution by syscalls is confined. Resolutions that would leave
+	// it fail with EXDEV. Empty disables the confinement. VFS1 only.
+	ResolveBeneath string `flag:"resolve-beneath"`
+
+	// FanotifyPermTimeout is the number of seconds an operation waits for a
+	// fanotify listener to answer a permission event. 0 means wait
+	// indefinitely, as in Linux.
+	FanotifyPermTimeout int `flag:"fanotify-perm-timeout"`
+
+	// FanotifyPermDeny denies fanotify permission events that time out or
+	// whose listener exits without answering. By default they are allowed.
+	FanotifyPermDeny bool `flag:"fanotify-perm-deny"`
+
 	// PanicSignal registers signal handling that panics. Usually set to
 	// SIGUSR2(12) to troubleshoot hangs. -1 disables it.
 	PanicSignal int `flag:"panic-signal"`
 
+	// RandomBlocking makes reads from /dev/random block while the sentry's
+	// entropy pool is unseeded, which happens only after restore. Otherwise
+	// /dev/random never blocks, as in modern Linux.
+	RandomBlocking bool `flag:"random-blocking"`
+
 	// ProfileEnable is set to prepare the sandbox to be profiled.
 	ProfileEnable bool `flag:"profile"`
 
 	// RestoreFile is the path to the saved container image
 	RestoreFile string
 
+	// RestoreAllowMismatch is a comma-separated list of differences between
+	// the checkpointed and current configurations that are permitted on
+	// restore.
+	RestoreAllowMismatch string `flag:"restore-allow-mismatch"`
+
 	// NumNetworkChannels controls the number of AF_PACKET sockets that map
 	// to the same underlying network device. This allows netstack to better
 	// scale for high throughput use cases.
@@ -195,9 +269,51 @@ func (c *Config) validate() error {
 	if c.NumNetworkChannels <= 0 {
 		return fmt.Errorf("num_network_channels must be > 0, got: %d", c.NumNetworkChannels)
 	}
+	if c.OOMLimit != 0 && c.OOMPolicy == oom.PolicyNone {
+		return fmt.Errorf("oom-limit requires an oom-policy other than none")
+	}
+	if c.FakeSwap && c.MemoryCompressionWatermark == 0 {
+		return fmt.Errorf("fake-swap requires memory-compression-watermark")
+	}
+	if c.MaxPathLen < 0 || c.MaxPathLen > linux.PATH_MAX {
+		return fmt.Errorf("max-path-len must be between 0 and %d, got: %d", linux.PATH_MAX, c.MaxPathLen)
+	}
+	if c.ResolveBeneath != "" {
+		if !path.IsAbs(c.ResolveBeneath) {
+			return fmt.Errorf("resolve-beneath must be an absolute path, got: %q", c.ResolveBeneath)
+		}
+		if c.VFS2 {
+			return fmt.Errorf("resolve-beneath is not supported with VFS2")
+		}
+	}
+	if c.FanotifyPermTimeout < 0 {
+		return fmt.Errorf("fanotify-perm-timeout must be non-negative, got: %d", c.FanotifyPermTimeout)
+	}
+	if _, err := seccheck.ParsePointSet(c.SecurityEventPoints); err != nil {
+		return fmt.Errorf("security-event-points: %v", err)
+	}
+	if c.SecurityEventRate < 0 {
+		return fmt.Errorf("security-event-rate must be >= 0, got: %d", c.SecurityEventRate)
+	}
+	for _, p := range c.PrefetchPathList() {
+		if !path.IsAbs(p) {
+			return fmt.Errorf("prefetch-paths must be absolute, got: %q", p)
+		}
+	}
 	return nil
 }
 
+// PrefetchPathList returns the paths listed in PrefetchPaths.
+func (c *Config) PrefetchPathList() []string {
+	var paths []string
+	for _, p := range strings.Split(c.PrefetchPaths, ",") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
 // FileAccessType tells how the filesystem is accessed.
 type FileAccessType int
 
@@ -354,3 +470,7 @@ func leakModePtr(v refs.LeakMode) *refs.LeakMode {
 func watchdogActionPtr(v watchdog.Action) *watchdog.Action {
 	return &v
 }
+
+func oomPolicyPtr(v oom.Policy) *oom.Policy {
+	return &v
+}
@@ -154,6 +154,10 @@ func TestInvalidFlags(t *testing.T) {
 			name:  "ref-leak-mode",
 			error: "invalid ref leak mode",
 		},
+		{
+			name:  "oom-policy",
+			error: "invalid OOM policy",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			defer setDefault(tc.name)
@@ -185,6 +189,41 @@ func TestValidationFail(t *testing.T) {
 			},
 			error: "num_network_channels must be > 0",
 		},
+		{
+			name: "oom-limit-without-policy",
+			flags: map[string]string{
+				"oom-limit": "1048576",
+			},
+			error: "oom-limit requires an oom-policy",
+		},
+		{
+			name: "fake-swap-without-compression",
+			flags: map[string]string{
+				"fake-swap": "true",
+			},
+			error: "fake-swap requires memory-compression-watermark",
+		},
+		{
+			name: "max-path-len-too-long",
+			flags: map[string]string{
+				"max-path-len": "4097",
+			},
+			error: "max-path-len must be between 0 and 4096",
+		},
+		{
+			name: "security-event-points",
+			flags: map[string]string{
+				"security-event-points": "execve,bogus",
+			},
+			error: "invalid security event point",
+		},
+		{
+			name: "prefetch-paths-relative",
+			flags: map[string]string{
+				"prefetch-paths": "/bin/sh,lib/libc.so.6",
+			},
+			error: "prefetch-paths must be absolute",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			for name, val := range tc.flags {
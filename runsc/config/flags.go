@@ -22,6 +22,7 @@ import (
 	"strconv"
 
 	"gvisor.dev/gvisor/pkg/refs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/oom"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/runsc/flag"
@@ -60,7 +61,20 @@ func RegisterFlags() {
 		// Flags that control sandbox runtime behavior.
 		flag.String("platform", "ptrace", "specifies which platform to use: ptrace (default), kvm.")
 		flag.Var(watchdogActionPtr(watchdog.LogWarning), "watchdog-action", "sets what action the watchdog takes when triggered: log (default), panic.")
+		flag.Var(oomPolicyPtr(oom.PolicyNone), "oom-policy", "sets what the sentry does when memory usage exceeds --oom-limit or an allocation fails: none (default, leave it to the host), kill (kill the process with the highest oom_score), fail (fail allocations with ENOMEM).")
+		flag.Uint64("oom-limit", 0, "memory usage in bytes above which the sentry's OOM handler acts. 0 means no limit.")
+		flag.Uint64("memory-compression-watermark", 0, "memory usage in bytes above which the sentry compresses cold application memory. 0 disables compression.")
+		flag.Bool("fake-swap", false, "presents compressed memory as swap in /proc/meminfo. Requires --memory-compression-watermark.")
+		flag.Int("max-path-len", 0, "maximum length in bytes, including the terminating NUL, of paths passed to syscalls; longer paths fail with ENAMETOOLONG. 0 means PATH_MAX (4096).")
+		flag.String("syscall-policy", "", "path to a JSON profile of syscalls that the sentry allows, denies or logs in every container. The dev.gvisor.syscall-policy annotation overrides it per container.")
+		flag.String("security-events", "", "path of a file to which security events are appended. Empty disables security events.")
+		flag.String("security-event-points", "all", "comma-separated list of security events to report: execve, credentials, listen, syscall-policy-denial or all.")
+		flag.Int("security-event-rate", 0, "maximum number of security events reported per second. 0 means no limit.")
+		flag.String("resolve-beneath", "", "directory inside the container beneath which all path resolution is confined; escapes via \"..\", absolute symlinks or mounts fail with EXDEV. Empty disables it. VFS1 only.")
+		flag.Int("fanotify-perm-timeout", 0, "seconds an operation waits for a fanotify listener to answer a permission event. 0 means wait indefinitely.")
+		flag.Bool("fanotify-perm-deny", false, "deny fanotify permission events that time out or whose listener exits without answering. By default they are allowed.")
 		flag.Int("panic-signal", -1, "register signal handling that panics. Usually set to SIGUSR2(12) to troubleshoot hangs. -1 disables it.")
+		flag.Bool("random-blocking", false, "mark the entropy pool unseeded on restore, so that reads from /dev/random block until entropy is credited with RNDADDENTROPY. By default, /dev/random never blocks.")
 		flag.Bool("profile", false, "prepares the sandbox to use Golang profiler. Note that enabling profiler loosens the seccomp protection added to the sandbox (DO NOT USE IN PRODUCTION).")
 		flag.Bool("rootless", false, "it allows the sandbox to be started with a user that is not root. Sandbox and Gofer processes may run with same privileges as current user.")
 		flag.Var(leakModePtr(refs.NoLeakChecking), "ref-leak-mode", "sets reference leak check mode: disabled (default), log-names, log-traces.")
@@ -76,6 +90,7 @@ func RegisterFlags() {
 		flag.Bool("vfs2", false, "enables VFSv2. This uses the new VFS layer that is faster than the previous one.")
 		flag.Bool("fuse", false, "TEST ONLY; use while FUSE in VFSv2 is landing. This allows the use of the new experimental FUSE filesystem.")
 		flag.Bool("cgroupfs", false, "Automatically mount cgroupfs.")
+		flag.String("prefetch-paths", "", "comma-separated list of absolute paths inside the container to resolve at container start, warming the gofer dentry cache. VFS2 only.")
 
 		// Flags that control sandbox runtime behavior: network related.
 		flag.Var(networkTypePtr(NetworkSandbox), "network", "specifies which network to use: sandbox (default), host, none. Using network inside the sandbox is more secure because it's isolated from the host network.")
@@ -87,6 +102,9 @@ func RegisterFlags() {
 		flag.Var(queueingDisciplinePtr(QDiscFIFO), "qdisc", "specifies which queueing discipline to apply by default to the non loopback nics used by the sandbox.")
 		flag.Int("num-network-channels", 1, "number of underlying channels(FDs) to use for network link endpoints.")
 
+		// Flags that control checkpoint/restore.
+		flag.String("restore-allow-mismatch", "", "comma-separated list of differences from the checkpointed configuration to permit on restore: version, platform, devices.")
+
 		// Test flags, not to be used outside tests, ever.
 		flag.Bool("TESTONLY-unsafe-nonroot", false, "TEST ONLY; do not ever use! This skips many security measures that isolate the host from the sandbox.")
 		flag.String("TESTONLY-test-name-env", "", "TEST ONLY; do not ever use! Used for automated tests to improve logging.")
@@ -17,11 +17,13 @@ package container
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -481,14 +483,124 @@ func Run(conf *config.Config, args Args) (unix.WaitStatus, error) {
 // Execute runs the specified command in the container. It returns the PID of
 // the newly created process.
 func (c *Container) Execute(conf *config.Config, args *control.ExecArgs) (int32, error) {
+	res, err := c.ExecuteWithResult(conf, args)
+	if err != nil {
+		return 0, err
+	}
+	return res.PID, nil
+}
+
+// ExecuteWithResult runs the specified command in the container. It returns
+// the PID of the newly created process and the inode numbers of its
+// namespaces.
+func (c *Container) ExecuteWithResult(conf *config.Config, args *control.ExecArgs) (*control.ExecResult, error) {
 	log.Debugf("Execute in container, cid: %s, args: %+v", c.ID, args)
 	if err := c.requireStatus("execute in", Created, Running); err != nil {
-		return 0, err
+		return nil, err
 	}
 	args.ContainerID = c.ID
 	return c.Sandbox.Execute(conf, args)
 }
 
+// AddMount bind mounts the host directory m.Source at m.Destination in the
+// running container. A new gofer is started to serve m.Source; it exits once
+// the mount is removed and the sandbox drops its last reference on it.
+// m.Options may contain "ro" and a private or slave propagation option, which
+// applies to the host bind mount of m.Source.
+func (c *Container) AddMount(conf *config.Config, m specs.Mount) error {
+	log.Debugf("Add mount in container, cid: %s, mount: %+v", c.ID, m)
+	if err := c.requireStatus("add mount in", Created, Running); err != nil {
+		return err
+	}
+	if !conf.VFS2 {
+		return fmt.Errorf("adding mounts to a running container requires VFS2")
+	}
+	if !filepath.IsAbs(m.Destination) {
+		return fmt.Errorf("mount destination must be an absolute path: %q", m.Destination)
+	}
+	if err := specutils.ValidateMountOptions(m.Options); err != nil {
+		return err
+	}
+	src, err := filepath.Abs(m.Source)
+	if err != nil {
+		return fmt.Errorf("resolving mount source %q: %v", m.Source, err)
+	}
+	if fi, err := os.Stat(src); err != nil {
+		return fmt.Errorf("mount source: %v", err)
+	} else if !fi.IsDir() {
+		return fmt.Errorf("mount source %q is not a directory", src)
+	}
+	m.Source = src
+
+	// The gofer serves the root of a spec, so give it one whose root is the
+	// directory to be mounted.
+	goferSpec := &specs.Spec{
+		Version: c.Spec.Version,
+		Process: &specs.Process{
+			Args: c.Spec.Process.Args,
+			Env:  c.Spec.Process.Env,
+		},
+		Root: &specs.Root{
+			Path:     src,
+			Readonly: specutils.ContainsStr(m.Options, "ro"),
+		},
+		Linux: &specs.Linux{},
+	}
+	if c.Spec.Linux != nil {
+		goferSpec.Linux.Namespaces = specutils.FilterNS([]specs.LinuxNamespaceType{specs.UserNamespace}, c.Spec)
+		goferSpec.Linux.UIDMappings = c.Spec.Linux.UIDMappings
+		goferSpec.Linux.GIDMappings = c.Spec.Linux.GIDMappings
+	}
+	for _, o := range m.Options {
+		if specutils.IsPropagationOption(o) {
+			goferSpec.Linux.RootfsPropagation = o
+		}
+	}
+	if err := specutils.ValidateSpec(goferSpec); err != nil {
+		return err
+	}
+	bundleDir, err := ioutil.TempDir("", "runsc-mount-")
+	if err != nil {
+		return fmt.Errorf("creating gofer bundle: %v", err)
+	}
+	defer os.RemoveAll(bundleDir)
+	specBytes, err := json.Marshal(goferSpec)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(bundleDir, "config.json"), specBytes, 0600); err != nil {
+		return fmt.Errorf("writing gofer spec: %v", err)
+	}
+
+	goferFiles, mountsFile, pid, err := startGoferProcess(goferSpec, conf, bundleDir, false)
+	if err != nil {
+		return err
+	}
+	// The gofer serves no submounts, so the mount list isn't needed.
+	mountsFile.Close()
+	defer func() {
+		for _, f := range goferFiles {
+			_ = f.Close()
+		}
+	}()
+	if err := c.Sandbox.Mount(c.ID, m, goferFiles[0]); err != nil {
+		// Closing the sandbox end of the connection causes the gofer to exit.
+		return err
+	}
+	log.Infof("Mounted %q at %q in container %q, gofer PID: %d", m.Source, m.Destination, c.ID, pid)
+	return nil
+}
+
+// RemoveMount lazily unmounts a directory previously mounted with AddMount
+// from the running container.
+func (c *Container) RemoveMount(destination string) error {
+	log.Debugf("Remove mount in container, cid: %s, destination: %s", c.ID, destination)
+	if err := c.requireStatus("remove mount in", Created, Running); err != nil {
+		return err
+	}
+	return c.Sandbox.Unmount(c.ID, destination)
+}
+
 // Event returns events for the container.
 func (c *Container) Event() (*boot.EventOut, error) {
 	log.Debugf("Getting events for container, cid: %s", c.ID)
@@ -829,6 +941,20 @@ func (c *Container) waitForStopped() error {
 }
 
 func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bundleDir string, attached bool) ([]*os.File, *os.File, error) {
+	sandEnds, mountsSand, pid, err := startGoferProcess(spec, conf, bundleDir, attached)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.GoferPid = pid
+	c.goferIsChild = true
+	return sandEnds, mountsSand, nil
+}
+
+// startGoferProcess starts a gofer serving the root and bind mounts of spec.
+// It returns the sandbox ends of the gofer connections, the read end of the
+// pipe over which the gofer sends the resolved mount list, and the gofer's
+// PID.
+func startGoferProcess(spec *specs.Spec, conf *config.Config, bundleDir string, attached bool) ([]*os.File, *os.File, int, error) {
 	// Start with the general config flags.
 	args := conf.ToFlags()
 
@@ -841,7 +967,7 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	if conf.LogFilename != "" {
 		logFile, err := os.OpenFile(conf.LogFilename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			return nil, nil, fmt.Errorf("opening log file %q: %v", conf.LogFilename, err)
+			return nil, nil, 0, fmt.Errorf("opening log file %q: %v", conf.LogFilename, err)
 		}
 		defer logFile.Close()
 		goferEnds = append(goferEnds, logFile)
@@ -859,7 +985,7 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 		}
 		debugLogFile, err := specutils.DebugLogFile(conf.DebugLog, "gofer", test)
 		if err != nil {
-			return nil, nil, fmt.Errorf("opening debug log file in %q: %v", conf.DebugLog, err)
+			return nil, nil, 0, fmt.Errorf("opening debug log file in %q: %v", conf.DebugLog, err)
 		}
 		defer debugLogFile.Close()
 		goferEnds = append(goferEnds, debugLogFile)
@@ -872,7 +998,7 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	// Open the spec file to donate to the sandbox.
 	specFile, err := specutils.OpenSpec(bundleDir)
 	if err != nil {
-		return nil, nil, fmt.Errorf("opening spec file: %v", err)
+		return nil, nil, 0, fmt.Errorf("opening spec file: %v", err)
 	}
 	defer specFile.Close()
 	goferEnds = append(goferEnds, specFile)
@@ -883,7 +1009,7 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	// have been resolved.
 	mountsSand, mountsGofer, err := os.Pipe()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 	defer mountsGofer.Close()
 	goferEnds = append(goferEnds, mountsGofer)
@@ -902,7 +1028,7 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	for i := 0; i < mountCount; i++ {
 		fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, 0, err
 		}
 		sandEnds = append(sandEnds, os.NewFile(uintptr(fds[0]), "sandbox IO FD"))
 
@@ -954,12 +1080,10 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	// Start the gofer in the given namespace.
 	log.Debugf("Starting gofer: %s %v", binPath, args)
 	if err := specutils.StartInNS(cmd, nss); err != nil {
-		return nil, nil, fmt.Errorf("gofer: %v", err)
+		return nil, nil, 0, fmt.Errorf("gofer: %v", err)
 	}
 	log.Infof("Gofer started, PID: %d", cmd.Process.Pid)
-	c.GoferPid = cmd.Process.Pid
-	c.goferIsChild = true
-	return sandEnds, mountsSand, nil
+	return sandEnds, mountsSand, cmd.Process.Pid, nil
 }
 
 // changeStatus transitions from one status to another ensuring that the
@@ -2360,6 +2360,96 @@ func TestMountSymlink(t *testing.T) {
 	}
 }
 
+// TestAddRemoveMount checks that mounts can be added to and removed from a
+// running container, and that runsc notices when the container unmounts them
+// itself.
+func TestAddRemoveMount(t *testing.T) {
+	dir, err := ioutil.TempDir(testutil.TmpDir(), "add-mount")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := path.Join(dir, "source")
+	target := path.Join(dir, "target")
+	for _, path := range []string{source, target} {
+		if err := os.MkdirAll(path, 0777); err != nil {
+			t.Fatalf("os.MkdirAll(): %v", err)
+		}
+	}
+	f, err := os.Create(path.Join(source, "file"))
+	if err != nil {
+		t.Fatalf("os.Create(): %v", err)
+	}
+	f.Close()
+	file := path.Join(target, "file")
+
+	spec, conf := sleepSpecConf(t)
+	conf.VFS2 = true
+	_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+	if err != nil {
+		t.Fatalf("error setting up container: %v", err)
+	}
+	defer cleanup()
+
+	args := Args{
+		ID:        testutil.RandomContainerID(),
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	cont, err := New(conf, args)
+	if err != nil {
+		t.Fatalf("creating container: %v", err)
+	}
+	defer cont.Destroy()
+
+	if err := cont.Start(conf); err != nil {
+		t.Fatalf("starting container: %v", err)
+	}
+
+	m := specs.Mount{
+		Destination: target,
+		Source:      source,
+	}
+	if err := cont.AddMount(conf, m); err != nil {
+		t.Fatalf("AddMount(%+v): %v", m, err)
+	}
+	if ws, err := execute(conf, cont, "/usr/bin/test", "-f", file); err != nil || ws != 0 {
+		t.Fatalf("exec: test -f %q, ws: %v, err: %v", file, ws, err)
+	}
+	if err := cont.AddMount(conf, m); err == nil {
+		t.Errorf("AddMount(%+v) over an added mount succeeded", m)
+	}
+
+	if err := cont.RemoveMount(target); err != nil {
+		t.Fatalf("RemoveMount(%q): %v", target, err)
+	}
+	if ws, err := execute(conf, cont, "/usr/bin/test", "!", "-f", file); err != nil || ws != 0 {
+		t.Fatalf("exec: test ! -f %q, ws: %v, err: %v", file, ws, err)
+	}
+	if err := cont.RemoveMount(target); err == nil {
+		t.Errorf("RemoveMount(%q) of a removed mount succeeded", target)
+	}
+
+	// Unmount from inside the container. runsc must not consider the mount
+	// still present.
+	if err := cont.AddMount(conf, m); err != nil {
+		t.Fatalf("AddMount(%+v): %v", m, err)
+	}
+	if ws, err := execute(conf, cont, "/bin/umount", target); err != nil || ws != 0 {
+		t.Fatalf("exec: umount %q, ws: %v, err: %v", target, ws, err)
+	}
+	if err := cont.RemoveMount(target); err == nil {
+		t.Errorf("RemoveMount(%q) of a mount unmounted by the container succeeded", target)
+	}
+	if err := cont.AddMount(conf, m); err != nil {
+		t.Fatalf("AddMount(%+v) after the container unmounted it: %v", m, err)
+	}
+	if ws, err := execute(conf, cont, "/usr/bin/test", "-f", file); err != nil || ws != 0 {
+		t.Fatalf("exec: test -f %q, ws: %v, err: %v", file, ws, err)
+	}
+}
+
 // Check that --net-raw disables the CAP_NET_RAW capability.
 func TestNetRaw(t *testing.T) {
 	capNetRaw := strconv.FormatUint(bits.MaskOf64(int(linux.CAP_NET_RAW)), 10)
@@ -2655,3 +2745,78 @@ func TestCat(t *testing.T) {
 		t.Errorf("out got %s, want include %s", buf, want)
 	}
 }
+
+// TestMsyncDurable checks that data written through a shared file mapping
+// reaches the host once msync(MS_SYNC) returns, even if the sandbox is killed
+// immediately afterward.
+func TestMsyncDurable(t *testing.T) {
+	app, err := testutil.FindFile("test/cmd/test_app/test_app")
+	if err != nil {
+		t.Fatal("error finding test_app:", err)
+	}
+
+	for name, conf := range configs(t, noOverlay...) {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir(testutil.TmpDir(), "msync")
+			if err != nil {
+				t.Fatalf("ioutil.TempDir(): %v", err)
+			}
+			defer os.RemoveAll(dir)
+			file := path.Join(dir, "file")
+			done := path.Join(dir, "done")
+
+			spec := testutil.NewSpecWithArgs(app, "msync", "--file", file, "--done", done)
+			spec.Mounts = append(spec.Mounts, specs.Mount{
+				Destination: dir,
+				Source:      dir,
+				Type:        "bind",
+			})
+			_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+			if err != nil {
+				t.Fatalf("error setting up container: %v", err)
+			}
+			defer cleanup()
+
+			args := Args{
+				ID:        testutil.RandomContainerID(),
+				Spec:      spec,
+				BundleDir: bundleDir,
+			}
+			c, err := New(conf, args)
+			if err != nil {
+				t.Fatalf("error creating container: %v", err)
+			}
+			defer c.Destroy()
+			if err := c.Start(conf); err != nil {
+				t.Fatalf("error starting container: %v", err)
+			}
+			if err := waitForFileExist(done); err != nil {
+				t.Fatalf("error waiting for msync: %v", err)
+			}
+
+			// Kill the sandbox so that it can't write anything back.
+			sandboxProc, err := os.FindProcess(c.Sandbox.Pid)
+			if err != nil {
+				t.Fatalf("error finding sandbox process: %v", err)
+			}
+			if err := sandboxProc.Kill(); err != nil {
+				t.Fatalf("error killing sandbox process: %v", err)
+			}
+			if err := blockUntilWaitable(c.Sandbox.Pid); err != nil && err != unix.ECHILD {
+				t.Fatalf("error waiting for sandbox to exit: %v", err)
+			}
+
+			got, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf("ioutil.ReadFile(%q): %v", file, err)
+			}
+			want := make([]byte, 2*os.Getpagesize())
+			for i := range want {
+				want[i] = byte(i % 251)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("file contents after msync and kill differ from written data (got %d bytes, want %d)", len(got), len(want))
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ var (
 	Parse       = flag.Parse
 	String      = flag.String
 	Uint        = flag.Uint
+	Uint64      = flag.Uint64
 	Var         = flag.Var
 )
 
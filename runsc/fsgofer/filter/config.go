@@ -213,6 +213,7 @@ var allowedSyscalls = seccomp.SyscallRules{
 		},
 	},
 	unix.SYS_SYMLINKAT: {},
+	unix.SYS_SYNCFS:    {},
 	unix.SYS_TGKILL: []seccomp.Rule{
 		{
 			seccomp.EqualTo(uint64(os.Getpid())),
@@ -252,3 +253,16 @@ var xattrSyscalls = seccomp.SyscallRules{
 	unix.SYS_FGETXATTR: {},
 	unix.SYS_FSETXATTR: {},
 }
+
+var inodeFlagsSyscalls = seccomp.SyscallRules{
+	unix.SYS_IOCTL: []seccomp.Rule{
+		{
+			seccomp.MatchAny{},
+			seccomp.EqualTo(unix.FS_IOC_GETFLAGS),
+		},
+		{
+			seccomp.MatchAny{},
+			seccomp.EqualTo(unix.FS_IOC_SETFLAGS),
+		},
+	},
+}
@@ -42,3 +42,9 @@ func InstallUDSFilters() {
 func InstallXattrFilters() {
 	allowedSyscalls.Merge(xattrSyscalls)
 }
+
+// InstallInodeFlagsFilters extends the allowed syscalls to include the ioctls
+// necessary for getting and setting inode flags.
+func InstallInodeFlagsFilters() {
+	allowedSyscalls.Merge(inodeFlagsSyscalls)
+}
@@ -32,6 +32,7 @@ import (
 	"strconv"
 
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
@@ -76,6 +77,10 @@ type Config struct {
 	// EnableVerityXattr allows access to extended attributes used by the
 	// verity file system.
 	EnableVerityXattr bool
+
+	// EnableInodeFlags allows getting and setting inode flags
+	// (FS_IOC_GETFLAGS and FS_IOC_SETFLAGS) on files.
+	EnableInodeFlags bool
 }
 
 type attachPoint struct {
@@ -141,6 +146,13 @@ func (a *attachPoint) Attach() (p9.File, error) {
 }
 
 // makeQID returns a unique QID for the given stat buffer.
+//
+// Host device numbers are mapped to small integers in the order they are
+// first seen. Since Attach stats the attach point before any other file, the
+// attach point's device is always 0, so QIDs for files on that device depend
+// only on the host inode number and remain stable across gofer restarts
+// (e.g. checkpoint/restore) as long as the host inode numbers do. QIDs for
+// files on other devices are only stable within a single gofer process.
 func (a *attachPoint) makeQID(stat *unix.Stat_t) p9.QID {
 	a.deviceMu.Lock()
 	defer a.deviceMu.Unlock()
@@ -631,6 +643,25 @@ func (l *localFile) FSync() error {
 	return nil
 }
 
+// SyncFS implements p9.File.
+func (l *localFile) SyncFS() error {
+	if l.fileType != unix.S_IFREG && l.fileType != unix.S_IFDIR {
+		return unix.EINVAL
+	}
+	// syncfs(2) can't be used on O_PATH FDs.
+	f, err := l.nonPathFile()
+	if err != nil {
+		return err
+	}
+	if f != l.file {
+		defer f.Close()
+	}
+	if err := unix.Syncfs(f.FD()); err != nil {
+		return extractErrno(err)
+	}
+	return nil
+}
+
 // GetAttr implements p9.File.
 func (l *localFile) GetAttr(_ p9.AttrMask) (p9.QID, p9.AttrMask, p9.Attr, error) {
 	stat, err := fstat(l.file.FD())
@@ -849,6 +880,79 @@ func (l *localFile) Allocate(mode p9.AllocateMode, offset, length uint64) error
 	return nil
 }
 
+// GetFlags implements p9.File.
+func (l *localFile) GetFlags() (uint32, error) {
+	f, err := l.inodeFlagsFile()
+	if err != nil {
+		return 0, err
+	}
+	if f != l.file {
+		defer f.Close()
+	}
+	flags, err := getFlags(f.FD())
+	if err != nil {
+		return 0, err
+	}
+	// Other flags belong to the host filesystem, and are not exposed to the
+	// sandbox.
+	return flags & linux.SettableInodeFlags, nil
+}
+
+// SetFlags implements p9.File.
+func (l *localFile) SetFlags(flags uint32) error {
+	if flags&^linux.SettableInodeFlags != 0 {
+		return unix.EOPNOTSUPP
+	}
+	if err := l.checkROMount(); err != nil {
+		return err
+	}
+	f, err := l.inodeFlagsFile()
+	if err != nil {
+		return err
+	}
+	if f != l.file {
+		defer f.Close()
+	}
+	// Preserve the flags that the sandbox can't see, such as
+	// FS_EXTENTS_FL, which ext4 refuses to clear.
+	oldFlags, err := getFlags(f.FD())
+	if err != nil {
+		return err
+	}
+	return setFlags(f.FD(), oldFlags&^linux.SettableInodeFlags|flags)
+}
+
+// inodeFlagsFile returns a host FD on which FS_IOC_GETFLAGS and
+// FS_IOC_SETFLAGS can be used. If the returned FD is not l.file, the caller
+// must close it.
+func (l *localFile) inodeFlagsFile() (*fd.FD, error) {
+	if !l.attachPoint.conf.EnableInodeFlags {
+		return nil, unix.ENOTTY
+	}
+	// Linux only supports inode flags on regular files and directories.
+	if l.fileType != unix.S_IFREG && l.fileType != unix.S_IFDIR {
+		return nil, unix.ENOTTY
+	}
+	// The ioctls can't be used on O_PATH FDs.
+	return l.nonPathFile()
+}
+
+// nonPathFile returns a host FD for l that isn't an O_PATH FD. If the returned
+// FD is not l.file, the caller must close it.
+//
+// Precondition: l is a regular file or directory, since only these can be
+// reopened without side effects.
+func (l *localFile) nonPathFile() (*fd.FD, error) {
+	if l.controlReadable || l.isOpen() {
+		return l.file, nil
+	}
+	f, err := reopenProcFd(l.file, openFlags|unix.O_RDONLY|unix.O_NONBLOCK)
+	if err != nil {
+		return nil, extractErrno(err)
+	}
+	return f, nil
+}
+
 // Rename implements p9.File; this should never be called.
 func (*localFile) Rename(p9.File, string) error {
 	panic("rename called directly")
@@ -427,6 +427,23 @@ func TestUnopened(t *testing.T) {
 	})
 }
 
+func TestSyncFS(t *testing.T) {
+	runCustom(t, allTypes, allConfs, func(t *testing.T, s state) {
+		// SyncFS doesn't require the file to be open, but is only supported
+		// on files that can be reopened.
+		err := s.file.SyncFS()
+		if s.fileType == unix.S_IFLNK {
+			if err != unix.EINVAL {
+				t.Errorf("%v: SyncFS() should have failed, got: %v, expected: unix.EINVAL", s, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Errorf("%v: SyncFS() failed: %v", s, err)
+		}
+	})
+}
+
 // TestOpenOPath is a regression test to ensure that a file that cannot be open
 // for read is allowed to be open. This was happening because the control file
 // was open with O_PATH, but Open() was not checking for it and allowing the
@@ -80,3 +80,30 @@ func renameat(oldDirFD int, oldName string, newDirFD int, newName string) error
 	}
 	return nil
 }
+
+func getFlags(fd int) (uint32, error) {
+	// Despite the ioctl's encoded argument size, Linux copies an int.
+	var flags int32
+	if _, _, errno := unix.Syscall(
+		unix.SYS_IOCTL,
+		uintptr(fd),
+		unix.FS_IOC_GETFLAGS,
+		uintptr(unsafe.Pointer(&flags))); errno != 0 {
+
+		return 0, syserr.FromHost(errno).ToError()
+	}
+	return uint32(flags), nil
+}
+
+func setFlags(fd int, flags uint32) error {
+	val := int32(flags)
+	if _, _, errno := unix.Syscall(
+		unix.SYS_IOCTL,
+		uintptr(fd),
+		unix.FS_IOC_SETFLAGS,
+		uintptr(unsafe.Pointer(&val))); errno != 0 {
+
+		return syserr.FromHost(errno).ToError()
+	}
+	return nil
+}
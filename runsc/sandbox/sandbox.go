@@ -22,6 +22,7 @@ import (
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -37,6 +38,7 @@ import (
 	"gvisor.dev/gvisor/pkg/coverage"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/urpc"
@@ -251,12 +253,18 @@ func (s *Sandbox) StartSubcontainer(spec *specs.Spec, conf *config.Config, cid s
 	payload.Files = append(payload.Files, stdios...)
 	payload.Files = append(payload.Files, goferFiles...)
 
+	policy, err := specutils.ReadSyscallPolicy(spec, conf)
+	if err != nil {
+		return err
+	}
+
 	// Start running the container.
 	args := boot.StartArgs{
-		Spec:        spec,
-		Conf:        conf,
-		CID:         cid,
-		FilePayload: payload,
+		Spec:          spec,
+		Conf:          conf,
+		CID:           cid,
+		SyscallPolicy: policy,
+		FilePayload:   payload,
 	}
 	if err := sandboxConn.Call(boot.ContMgrStartSubcontainer, &args, nil); err != nil {
 		return fmt.Errorf("starting sub-container %v: %v", spec.Process.Args, err)
@@ -331,26 +339,26 @@ func (s *Sandbox) NewCGroup() (*cgroup.Cgroup, error) {
 }
 
 // Execute runs the specified command in the container. It returns the PID of
-// the newly created process.
-func (s *Sandbox) Execute(conf *config.Config, args *control.ExecArgs) (int32, error) {
+// the newly created process and the inode numbers of its namespaces.
+func (s *Sandbox) Execute(conf *config.Config, args *control.ExecArgs) (*control.ExecResult, error) {
 	log.Debugf("Executing new process in container %q in sandbox %q", args.ContainerID, s.ID)
 
 	if err := s.configureStdios(conf, args.Files); err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	conn, err := s.sandboxConnect()
 	if err != nil {
-		return 0, s.connError(err)
+		return nil, s.connError(err)
 	}
 	defer conn.Close()
 
 	// Send a message to the sandbox control server to start the container.
-	var pid int32
-	if err := conn.Call(boot.ContMgrExecuteAsync, args, &pid); err != nil {
-		return 0, fmt.Errorf("executing command %q in sandbox: %v", args, err)
+	var res control.ExecResult
+	if err := conn.Call(boot.ContMgrExecuteAsync, args, &res); err != nil {
+		return nil, fmt.Errorf("executing command %q in sandbox: %v", args, err)
 	}
-	return pid, nil
+	return &res, nil
 }
 
 // Event retrieves stats about the sandbox such as memory and CPU utilization.
@@ -737,6 +745,17 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 		if mem < 0x7ffffffffffff000 {
 			cmd.Args = append(cmd.Args, "--total-memory", strconv.FormatUint(mem, 10))
 		}
+
+		// Let the sandbox report throttling by the host to the application.
+		// The cpu controller may not be available, which isn't fatal.
+		if f, err := os.Open(filepath.Join(s.Cgroup.MakePath("cpu"), "cpu.stat")); err != nil {
+			log.Warningf("Failed to open host cpu.stat, throttling won't be reported: %v", err)
+		} else {
+			defer f.Close()
+			cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+			cmd.Args = append(cmd.Args, "--host-cpu-stat-fd", strconv.Itoa(nextFD))
+			nextFD++
+		}
 	}
 
 	if args.UserLog != "" {
@@ -751,6 +770,30 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 		nextFD++
 	}
 
+	if path := specutils.SyscallPolicyFile(args.Spec, conf); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening syscall policy: %v", err)
+		}
+		defer f.Close()
+
+		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+		cmd.Args = append(cmd.Args, "--syscall-policy-fd", strconv.Itoa(nextFD))
+		nextFD++
+	}
+
+	if conf.SecurityEvents != "" {
+		f, err := os.OpenFile(conf.SecurityEvents, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("opening security events file: %v", err)
+		}
+		defer f.Close()
+
+		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+		cmd.Args = append(cmd.Args, "--security-events-fd", strconv.Itoa(nextFD))
+		nextFD++
+	}
+
 	_ = nextFD // All FD assignment is finished.
 
 	if args.Attached {
@@ -987,6 +1030,81 @@ func (s *Sandbox) Pause(cid string) error {
 	return nil
 }
 
+// Mount mounts the directory served by the gofer connected to goferFile at
+// m.Destination in a running container in the sandbox.
+func (s *Sandbox) Mount(cid string, m specs.Mount, goferFile *os.File) error {
+	log.Debugf("Mount %q in container %q in sandbox %q", m.Destination, cid, s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	args := boot.MountArgs{
+		CID:         cid,
+		Mount:       m,
+		FilePayload: urpc.FilePayload{Files: []*os.File{goferFile}},
+	}
+	if err := conn.Call(boot.ContMgrMount, &args, nil); err != nil {
+		return fmt.Errorf("mounting %q in container %q: %v", m.Destination, cid, err)
+	}
+	return nil
+}
+
+// Unmount unmounts a directory previously mounted with Mount from a running
+// container in the sandbox.
+func (s *Sandbox) Unmount(cid, destination string) error {
+	log.Debugf("Unmount %q in container %q in sandbox %q", destination, cid, s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	args := boot.UnmountArgs{
+		CID:         cid,
+		Destination: destination,
+	}
+	if err := conn.Call(boot.ContMgrUnmount, &args, nil); err != nil {
+		return fmt.Errorf("unmounting %q in container %q: %v", destination, cid, err)
+	}
+	return nil
+}
+
+// ControlVersion negotiates the control API version with the sandbox and
+// returns the methods it serves.
+func (s *Sandbox) ControlVersion() (*boot.VersionResult, error) {
+	log.Debugf("Control API version of sandbox %q", s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	args := boot.VersionArgs{ClientVersion: boot.ControlAPIVersion}
+	var r boot.VersionResult
+	if err := conn.Call(boot.ContMgrVersion, &args, &r); err != nil {
+		return nil, fmt.Errorf("negotiating control API version with sandbox %q: %v", s.ID, err)
+	}
+	return &r, nil
+}
+
+// SyscallPolicyStats returns the counters of a container's syscall policy.
+func (s *Sandbox) SyscallPolicyStats(cid string) ([]syspolicy.RuleStats, error) {
+	log.Debugf("Getting syscall policy stats for container %q in sandbox %q", cid, s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var stats []syspolicy.RuleStats
+	if err := conn.Call(boot.ContMgrSyscallPolicyStats, &cid, &stats); err != nil {
+		return nil, fmt.Errorf("getting syscall policy stats for container %q: %v", cid, err)
+	}
+	return stats, nil
+}
+
 // Resume sends the resume call for a container in the sandbox.
 func (s *Sandbox) Resume(cid string) error {
 	log.Debugf("Resume sandbox %q", s.ID)
@@ -1112,6 +1230,45 @@ func (s *Sandbox) MutexProfile(f *os.File, duration time.Duration) error {
 	return conn.Call(boot.ProfileMutex, &opts, nil)
 }
 
+// ContentionProfile writes block and mutex profiles collected over the same
+// period to the given files.
+func (s *Sandbox) ContentionProfile(blockFile, mutexFile *os.File, duration time.Duration) error {
+	log.Debugf("Contention profile %q", s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	opts := control.ContentionProfileOpts{
+		FilePayload: urpc.FilePayload{Files: []*os.File{blockFile, mutexFile}},
+		Duration:    duration,
+	}
+	return conn.Call(boot.ProfileContention, &opts, nil)
+}
+
+// SetContentionRates sets the block profile rate and mutex profile fraction
+// used by the sandbox outside of profile collection. Negative values leave the
+// corresponding rate unchanged. It returns the resulting rates.
+func (s *Sandbox) SetContentionRates(blockRate, mutexFraction int) (control.ContentionRates, error) {
+	log.Debugf("Set contention rates %q: block %d, mutex %d", s.ID, blockRate, mutexFraction)
+	var rates control.ContentionRates
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return rates, err
+	}
+	defer conn.Close()
+
+	opts := control.ContentionRates{
+		BlockRate:     blockRate,
+		MutexFraction: mutexFraction,
+	}
+	if err := conn.Call(boot.ProfileSetContentionRates, &opts, &rates); err != nil {
+		return rates, fmt.Errorf("setting sandbox %q contention rates: %v", s.ID, err)
+	}
+	return rates, nil
+}
+
 // Trace collects an execution trace.
 func (s *Sandbox) Trace(f *os.File, duration time.Duration) error {
 	log.Debugf("Trace %q", s.ID)
@@ -19,6 +19,7 @@ import (
 	"math/bits"
 	"path"
 	"strings"
+	"time"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
@@ -71,6 +72,38 @@ var verityMountOptions = map[string]struct{}{
 	"verity.action":   {},
 }
 
+// InodeFlagsMountOption is a gVisor-specific bind mount option that allows the
+// sandbox to get and set inode flags (e.g. with chattr(1) and lsattr(1)) on
+// files in the mount. Inode flags are always supported on tmpfs.
+const InodeFlagsMountOption = "gvisor.inodeflags"
+
+// Per-mount cache policy options for bind mounts. They override the
+// sandbox-wide --file-access-mounts setting and any mount hint for the mount:
+//
+//   - gvisor.cache=exclusive: the sandbox is the only user of the mount, so
+//     file data and metadata are cached without revalidation.
+//
+//   - gvisor.cache=shared: the mount may be changed externally, so cached
+//     dentries and metadata are revalidated against the gofer before use.
+//
+//   - gvisor.cache=uncached: like shared, but regular file data is never
+//     cached in the sandbox.
+//
+//   - gvisor.cache.ttl=<duration>: with gvisor.cache=shared, skips
+//     revalidation of dentries that were revalidated less than <duration>
+//     (e.g. "500ms", "5s") ago.
+const (
+	CacheMountOption    = "gvisor.cache"
+	CacheTTLMountOption = "gvisor.cache.ttl"
+)
+
+// Valid values for CacheMountOption.
+const (
+	CachePolicyExclusive = "exclusive"
+	CachePolicyShared    = "shared"
+	CachePolicyUncached  = "uncached"
+)
+
 // propOptionsMap is similar to optionsMap, but it lists propagation options
 // that cannot be used together with other flags.
 var propOptionsMap = map[string]mapping{
@@ -132,6 +165,12 @@ func moptKey(opt string) string {
 	return strings.SplitN(opt, "=", 2)[0]
 }
 
+// IsPropagationOption returns true if opt is a mount propagation option.
+func IsPropagationOption(opt string) bool {
+	_, ok := propOptionsMap[opt]
+	return ok
+}
+
 // ValidateMountOptions validates that mount options are correct.
 func ValidateMountOptions(opts []string) error {
 	for _, o := range opts {
@@ -141,16 +180,61 @@ func ValidateMountOptions(opts []string) error {
 		_, ok1 := optionsMap[o]
 		_, ok2 := propOptionsMap[o]
 		_, ok3 := verityMountOptions[moptKey(o)]
-		if !ok1 && !ok2 && !ok3 {
+		ok4 := o == InodeFlagsMountOption
+		ok5 := IsCacheMountOption(o)
+		if !ok1 && !ok2 && !ok3 && !ok4 && !ok5 {
 			return fmt.Errorf("unknown mount option %q", o)
 		}
 		if err := validatePropagation(o); err != nil {
 			return err
 		}
 	}
+	if _, _, err := ParseCacheMountOptions(opts); err != nil {
+		return err
+	}
 	return nil
 }
 
+// IsCacheMountOption returns true if opt is one of the per-mount cache policy
+// options.
+func IsCacheMountOption(opt string) bool {
+	key := moptKey(opt)
+	return key == CacheMountOption || key == CacheTTLMountOption
+}
+
+// ParseCacheMountOptions returns the cache policy and revalidation TTL
+// requested by the per-mount cache policy options in opts. policy is empty if
+// no policy was requested, and ttl is 0 if no TTL was requested.
+func ParseCacheMountOptions(opts []string) (policy string, ttl time.Duration, err error) {
+	for _, o := range opts {
+		kv := strings.SplitN(o, "=", 2)
+		switch kv[0] {
+		case CacheMountOption:
+			if len(kv) != 2 {
+				return "", 0, fmt.Errorf("mount option %q requires a value", o)
+			}
+			switch kv[1] {
+			case CachePolicyExclusive, CachePolicyShared, CachePolicyUncached:
+				policy = kv[1]
+			default:
+				return "", 0, fmt.Errorf("invalid cache policy in mount option %q", o)
+			}
+		case CacheTTLMountOption:
+			if len(kv) != 2 {
+				return "", 0, fmt.Errorf("mount option %q requires a value", o)
+			}
+			ttl, err = time.ParseDuration(kv[1])
+			if err != nil || ttl < 0 {
+				return "", 0, fmt.Errorf("invalid duration in mount option %q", o)
+			}
+		}
+	}
+	if ttl != 0 && policy != CachePolicyShared {
+		return "", 0, fmt.Errorf("mount option %q requires %s=%s", CacheTTLMountOption, CacheMountOption, CachePolicyShared)
+	}
+	return policy, ttl, nil
+}
+
 // ValidateRootfsPropagation validates that rootfs propagation options are
 // correct.
 func validateRootfsPropagation(opt string) error {
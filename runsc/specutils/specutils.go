@@ -36,6 +36,7 @@ import (
 	"gvisor.dev/gvisor/pkg/bits"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/syspolicy"
 	"gvisor.dev/gvisor/runsc/config"
 )
 
@@ -585,3 +586,31 @@ func ResolveEnvs(envs ...[]string) ([]string, error) {
 func FaqErrorMsg(anchor, msg string) string {
 	return fmt.Sprintf("%s; see https://gvisor.dev/faq#%s for more details", msg, anchor)
 }
+
+// SyscallPolicyAnnotation is the annotation holding the path to a container's
+// syscall policy profile. It overrides the --syscall-policy flag.
+const SyscallPolicyAnnotation = "dev.gvisor.syscall-policy"
+
+// SyscallPolicyFile returns the path to the container's syscall policy
+// profile, or "" if the container has none.
+func SyscallPolicyFile(spec *specs.Spec, conf *config.Config) string {
+	if path, ok := spec.Annotations[SyscallPolicyAnnotation]; ok {
+		return path
+	}
+	return conf.SyscallPolicy
+}
+
+// ReadSyscallPolicy reads the container's syscall policy profile. It returns
+// nil if the container has none.
+func ReadSyscallPolicy(spec *specs.Spec, conf *config.Config) (*syspolicy.Profile, error) {
+	path := SyscallPolicyFile(spec, conf)
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening syscall policy: %v", err)
+	}
+	defer f.Close()
+	return syspolicy.ParseProfile(f)
+}
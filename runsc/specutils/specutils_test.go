@@ -16,12 +16,15 @@ package specutils
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/runsc/config"
 )
 
 func TestWaitForReadyHappy(t *testing.T) {
@@ -269,3 +272,61 @@ func TestSpecInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestReadSyscallPolicy(t *testing.T) {
+	dir := t.TempDir()
+	flagPolicy := filepath.Join(dir, "flag.json")
+	if err := ioutil.WriteFile(flagPolicy, []byte(`{"rules": [{"name": "flag", "syscalls": ["ptrace"], "action": "errno"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	annotationPolicy := filepath.Join(dir, "annotation.json")
+	if err := ioutil.WriteFile(annotationPolicy, []byte(`{"rules": [{"name": "annotation", "syscalls": ["mount"], "action": "kill"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	badPolicy := filepath.Join(dir, "bad.json")
+	if err := ioutil.WriteFile(badPolicy, []byte(`{"rules": [{"syscalls": ["mount"], "action": "explode"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		flag       string
+		annotation string
+		want       string
+		error      string
+	}{
+		{name: "none"},
+		{name: "flag", flag: flagPolicy, want: "flag"},
+		{name: "annotation", annotation: annotationPolicy, want: "annotation"},
+		{name: "annotation overrides flag", flag: flagPolicy, annotation: annotationPolicy, want: "annotation"},
+		{name: "missing", flag: filepath.Join(dir, "missing.json"), error: "opening syscall policy"},
+		{name: "invalid", flag: badPolicy, error: "invalid action"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := &config.Config{SyscallPolicy: test.flag}
+			spec := &specs.Spec{}
+			if test.annotation != "" {
+				spec.Annotations = map[string]string{SyscallPolicyAnnotation: test.annotation}
+			}
+			p, err := ReadSyscallPolicy(spec, conf)
+			if len(test.error) != 0 {
+				if err == nil || !strings.Contains(err.Error(), test.error) {
+					t.Fatalf("ReadSyscallPolicy() wrong error, got: %v, want: .*%s.*", err, test.error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadSyscallPolicy() failed: %v", err)
+			}
+			if test.want == "" {
+				if p != nil {
+					t.Fatalf("ReadSyscallPolicy() = %+v, want nil", p)
+				}
+				return
+			}
+			if p == nil || len(p.Rules) != 1 || p.Rules[0].Name != test.want {
+				t.Errorf("ReadSyscallPolicy() = %+v, want rule %q", p, test.want)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"strconv"
 	sys "syscall"
 	"time"
+	"unsafe"
 
 	"github.com/google/subcommands"
 	"github.com/kr/pty"
@@ -43,6 +44,7 @@ func main() {
 	subcommands.Register(new(fdReceiver), "")
 	subcommands.Register(new(fdSender), "")
 	subcommands.Register(new(forkBomb), "")
+	subcommands.Register(new(msync), "")
 	subcommands.Register(new(ptyRunner), "")
 	subcommands.Register(new(reaper), "")
 	subcommands.Register(new(syscall), "")
@@ -300,6 +302,65 @@ func (s *syscall) Execute(ctx context.Context, f *flag.FlagSet, args ...interfac
 	return subcommands.ExitSuccess
 }
 
+type msync struct {
+	file string
+	done string
+}
+
+// Name implements subcommands.Command.
+func (*msync) Name() string {
+	return "msync"
+}
+
+// Synopsis implements subcommands.Command.
+func (*msync) Synopsis() string {
+	return "writes to a shared file mapping, msyncs it and waits to be killed"
+}
+
+// Usage implements subcommands.Command.
+func (*msync) Usage() string {
+	return "msync --file=<path> --done=<path>"
+}
+
+// SetFlags implements subcommands.Command.
+func (c *msync) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.file, "file", "", "file to map and write")
+	f.StringVar(&c.done, "done", "", "file created once msync(MS_SYNC) returns")
+}
+
+// Execute implements subcommands.Command.
+func (c *msync) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	file, err := os.OpenFile(c.file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Fatalf("error opening %q: %v", c.file, err)
+	}
+	size := 2 * os.Getpagesize()
+	if err := file.Truncate(int64(size)); err != nil {
+		log.Fatalf("error truncating %q: %v", c.file, err)
+	}
+	m, err := sys.Mmap(int(file.Fd()), 0, size, sys.PROT_READ|sys.PROT_WRITE, sys.MAP_SHARED)
+	if err != nil {
+		log.Fatalf("error mapping %q: %v", c.file, err)
+	}
+	for i := range m {
+		m[i] = byte(i % 251)
+	}
+	if _, _, errno := sys.Syscall(sys.SYS_MSYNC, uintptr(unsafe.Pointer(&m[0])), uintptr(len(m)), sys.MS_SYNC); errno != 0 {
+		log.Fatalf("error syncing %q: %v", c.file, errno)
+	}
+	done, err := os.Create(c.done)
+	if err != nil {
+		log.Fatalf("error creating %q: %v", c.done, err)
+	}
+	done.Close()
+
+	// Keep the mapping and file open until the sandbox is killed, so that
+	// nothing else writes the data back.
+	for {
+		time.Sleep(time.Hour)
+	}
+}
+
 type capability struct {
 	enabled  uint64
 	disabled uint64
@@ -350,6 +350,7 @@ func runTestCaseRunsc(testBin string, tc gtest.TestCase, t *testing.T) {
 	// Test spec comes with pre-defined mounts that we don't want. Reset it.
 	spec.Mounts = nil
 	testTmpDir := "/tmp"
+	testUncachedDir := ""
 	if *useTmpfs {
 		// Forces '/tmp' to be mounted as tmpfs, otherwise test that rely on
 		// features only available in gVisor's internal tmpfs may fail.
@@ -388,6 +389,30 @@ func runTestCaseRunsc(testBin string, tc gtest.TestCase, t *testing.T) {
 			})
 			testTmpDir = "/tmp"
 		}
+
+		if *vfs2 {
+			// Also provide a gofer-backed directory that the sandbox
+			// doesn't cache, for tests of the uncached file paths.
+			uncachedDir, err := ioutil.TempDir(testutil.TmpDir(), "")
+			if err != nil {
+				t.Fatalf("could not create temp dir: %v", err)
+			}
+			defer os.RemoveAll(uncachedDir)
+
+			if err := os.Chmod(uncachedDir, 0777); err != nil {
+				t.Fatalf("could not chmod temp dir: %v", err)
+			}
+			testUncachedDir = filepath.Join(testTmpDir, "uncached")
+			if err := os.Mkdir(filepath.Join(tmpDir, "uncached"), 0777); err != nil {
+				t.Fatalf("could not create mount point: %v", err)
+			}
+			spec.Mounts = append(spec.Mounts, specs.Mount{
+				Type:        "bind",
+				Destination: testUncachedDir,
+				Source:      uncachedDir,
+				Options:     []string{specutils.CacheMountOption + "=" + specutils.CachePolicyUncached},
+			})
+		}
 	}
 
 	// Set environment variables that indicate we are running in gVisor with
@@ -416,6 +441,10 @@ func runTestCaseRunsc(testBin string, tc gtest.TestCase, t *testing.T) {
 	// be backed by tmpfs.
 	env = filterEnv(env, []string{"TEST_TMPDIR"})
 	env = append(env, fmt.Sprintf("TEST_TMPDIR=%s", testTmpDir))
+	env = filterEnv(env, []string{"TEST_UNCACHED_DIR"})
+	if testUncachedDir != "" {
+		env = append(env, fmt.Sprintf("TEST_UNCACHED_DIR=%s", testUncachedDir))
+	}
 
 	spec.Process.Env = env
 
@@ -16,14 +16,21 @@
 #include <fcntl.h>
 #include <stdlib.h>
 #include <sys/stat.h>
+#include <sys/syscall.h>
 #include <sys/types.h>
 #include <unistd.h>
 
 #include "gtest/gtest.h"
+#include "absl/flags/flag.h"
 #include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
 #include "test/util/fs_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
+
+ABSL_FLAG(int32_t, scratch_uid, 65534, "scratch UID");
+ABSL_FLAG(int32_t, scratch_gid, 65534, "scratch GID");
 
 using ::testing::Ge;
 
@@ -32,6 +39,19 @@ namespace testing {
 
 namespace {
 
+#ifndef SYS_faccessat2
+#define SYS_faccessat2 439
+#endif  // SYS_faccessat2
+
+int faccessat2(int dirfd, const char* pathname, int mode, int flags) {
+  return syscall(SYS_faccessat2, dirfd, pathname, mode, flags);
+}
+
+// SKIP_IF_FACCESSAT2_UNSUPPORTED skips the current test if the host does not
+// implement faccessat2(2).
+#define SKIP_IF_FACCESSAT2_UNSUPPORTED()                             \
+  SKIP_IF(faccessat2(AT_FDCWD, ".", F_OK, 0) < 0 && errno == ENOSYS)
+
 class AccessTest : public ::testing::Test {
  public:
   std::string CreateTempFile(int perm) {
@@ -164,6 +184,156 @@ TEST_F(AccessTest, UsrReadWriteExec) {
   EXPECT_THAT(unlink(filename.c_str()), SyscallSucceeds());
 }
 
+TEST_F(AccessTest, DanglingSymlink) {
+  const std::string link = NewTempAbsPath();
+  ASSERT_THAT(symlink(absnone_.c_str(), link.c_str()), SyscallSucceeds());
+
+  // access(2) follows the symlink to a target that does not exist.
+  EXPECT_THAT(access(link.c_str(), F_OK), SyscallFailsWithErrno(ENOENT));
+  EXPECT_THAT(faccessat(AT_FDCWD, link.c_str(), F_OK, 0),
+              SyscallFailsWithErrno(ENOENT));
+
+  EXPECT_THAT(unlink(link.c_str()), SyscallSucceeds());
+}
+
+TEST_F(AccessTest, Faccessat2DanglingSymlink) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+
+  const std::string link = NewTempAbsPath();
+  ASSERT_THAT(symlink(absnone_.c_str(), link.c_str()), SyscallSucceeds());
+
+  EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), F_OK, 0),
+              SyscallFailsWithErrno(ENOENT));
+
+  // With AT_SYMLINK_NOFOLLOW, the symlink itself is checked. Symlinks are
+  // always readable.
+  EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), F_OK, AT_SYMLINK_NOFOLLOW),
+              SyscallSucceeds());
+  EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), R_OK, AT_SYMLINK_NOFOLLOW),
+              SyscallSucceeds());
+
+  EXPECT_THAT(unlink(link.c_str()), SyscallSucceeds());
+}
+
+TEST_F(AccessTest, Faccessat2SymlinkNoFollowUnprivileged) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+
+  // Drop capabilities that allow us to override permissions. We must drop
+  // PERMITTED because access() checks those instead of EFFECTIVE.
+  ASSERT_NO_ERRNO(DropPermittedCapability(CAP_DAC_OVERRIDE));
+  ASSERT_NO_ERRNO(DropPermittedCapability(CAP_DAC_READ_SEARCH));
+
+  // The target is not readable, but the symlink is.
+  const std::string filename = CreateTempFile(0000);
+  const std::string link = NewTempAbsPath();
+  ASSERT_THAT(symlink(filename.c_str(), link.c_str()), SyscallSucceeds());
+
+  EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), R_OK, 0),
+              SyscallFailsWithErrno(EACCES));
+  EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), R_OK, AT_SYMLINK_NOFOLLOW),
+              SyscallSucceeds());
+
+  EXPECT_THAT(unlink(link.c_str()), SyscallSucceeds());
+  EXPECT_THAT(unlink(filename.c_str()), SyscallSucceeds());
+}
+
+// AT_EACCESS and AT_SYMLINK_NOFOLLOW can be combined: the symlink itself is
+// checked, and the path is resolved with the effective rather than the real
+// IDs.
+TEST_F(AccessTest, Faccessat2EffectiveIdsSymlinkNoFollow) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETUID)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_CHOWN)));
+
+  const int uid = absl::GetFlag(FLAGS_scratch_uid);
+  const int gid = absl::GetFlag(FLAGS_scratch_gid);
+
+  // The target is only readable by its owner, the real UID.
+  const std::string target = CreateTempFile(0600);
+
+  // The symlink is in a directory that only the effective UID can search.
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  ASSERT_THAT(chmod(dir.path().c_str(), 0700), SyscallSucceeds());
+  ASSERT_THAT(chown(dir.path().c_str(), uid, gid), SyscallSucceeds());
+  const std::string link = JoinPath(dir.path(), "link");
+  ASSERT_THAT(symlink(target.c_str(), link.c_str()), SyscallSucceeds());
+
+  // Change the effective IDs only in a child thread, or else this thread
+  // won't be able to clean up after the test.
+  ScopedThread([&] {
+    // Drop capabilities that allow the real UID to override permissions. We
+    // must drop PERMITTED because faccessat2(2) checks those instead of
+    // EFFECTIVE without AT_EACCESS.
+    EXPECT_NO_ERRNO(DropPermittedCapability(CAP_DAC_OVERRIDE));
+    EXPECT_NO_ERRNO(DropPermittedCapability(CAP_DAC_READ_SEARCH));
+
+    EXPECT_THAT(syscall(SYS_setresgid, -1, gid, -1), SyscallSucceeds());
+    EXPECT_THAT(syscall(SYS_setresuid, -1, uid, -1), SyscallSucceeds());
+
+    // The real UID can't search the directory containing the symlink.
+    EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), F_OK, AT_SYMLINK_NOFOLLOW),
+                SyscallFailsWithErrno(EACCES));
+
+    // The effective UID can search the directory, and the symlink itself is
+    // readable.
+    EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), R_OK,
+                           AT_EACCESS | AT_SYMLINK_NOFOLLOW),
+                SyscallSucceeds());
+
+    // Following the symlink checks the target, which the effective UID can't
+    // read.
+    EXPECT_THAT(faccessat2(AT_FDCWD, link.c_str(), R_OK, AT_EACCESS),
+                SyscallFailsWithErrno(EACCES));
+  });
+
+  EXPECT_THAT(unlink(target.c_str()), SyscallSucceeds());
+}
+
+TEST_F(AccessTest, Faccessat2EmptyPath) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+
+  const int fd = open(absfile_.c_str(), O_RDONLY);
+  ASSERT_THAT(fd, SyscallSucceeds());
+  EXPECT_THAT(faccessat2(fd, "", R_OK, 0), SyscallFailsWithErrno(ENOENT));
+  EXPECT_THAT(faccessat2(fd, "", R_OK, AT_EMPTY_PATH), SyscallSucceeds());
+  EXPECT_THAT(close(fd), SyscallSucceeds());
+}
+
+TEST_F(AccessTest, Faccessat2EmptyPathCwd) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+
+  const std::string cwd = ASSERT_NO_ERRNO_AND_VALUE(GetCWD());
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  ASSERT_THAT(chdir(dir.path().c_str()), SyscallSucceeds());
+  Cleanup restore_cwd(
+      [&] { EXPECT_THAT(chdir(cwd.c_str()), SyscallSucceeds()); });
+
+  // With AT_FDCWD, AT_EMPTY_PATH checks the working directory.
+  EXPECT_THAT(faccessat2(AT_FDCWD, "", R_OK | W_OK | X_OK, AT_EMPTY_PATH),
+              SyscallSucceeds());
+  EXPECT_THAT(faccessat2(AT_FDCWD, "", R_OK, 0),
+              SyscallFailsWithErrno(ENOENT));
+
+  ASSERT_THAT(chmod(dir.path().c_str(), 0500), SyscallSucceeds());
+  ScopedThread([&] {
+    // Drop capabilities that allow the real UID to override permissions.
+    EXPECT_NO_ERRNO(DropPermittedCapability(CAP_DAC_OVERRIDE));
+    EXPECT_NO_ERRNO(DropPermittedCapability(CAP_DAC_READ_SEARCH));
+
+    EXPECT_THAT(faccessat2(AT_FDCWD, "", R_OK | X_OK, AT_EMPTY_PATH),
+                SyscallSucceeds());
+    EXPECT_THAT(faccessat2(AT_FDCWD, "", W_OK, AT_EMPTY_PATH),
+                SyscallFailsWithErrno(EACCES));
+  });
+}
+
+TEST_F(AccessTest, Faccessat2InvalidFlags) {
+  SKIP_IF_FACCESSAT2_UNSUPPORTED();
+
+  EXPECT_THAT(faccessat2(AT_FDCWD, absfile_.c_str(), R_OK, 0x1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -19,6 +19,9 @@
 #include <sys/mount.h>
 #include <unistd.h>
 
+#include <string>
+#include <vector>
+
 #include "gtest/gtest.h"
 #include "absl/container/flat_hash_map.h"
 #include "absl/container/flat_hash_set.h"
@@ -26,6 +29,7 @@
 #include "test/util/capability_util.h"
 #include "test/util/cgroup_util.h"
 #include "test/util/cleanup.h"
+#include "test/util/fs_util.h"
 #include "test/util/mount_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -37,13 +41,16 @@ namespace {
 
 using ::testing::_;
 using ::testing::Contains;
+using ::testing::ContainsRegex;
 using ::testing::Ge;
 using ::testing::Gt;
+using ::testing::HasSubstr;
 using ::testing::Key;
+using ::testing::MatchesRegex;
 using ::testing::Not;
 
 std::vector<std::string> known_controllers = {
-    "cpu", "cpuset", "cpuacct", "job", "memory",
+    "cpu", "cpuset", "cpuacct", "io", "job", "memory",
 };
 
 bool CgroupsAvailable() {
@@ -367,6 +374,34 @@ TEST(CPUAcctCgroup, CPUAcctStat) {
   EXPECT_THAT(Atoi<int64_t>(sys_tokens[1]), IsPosixErrorOkAndHolds(Ge(0)));
 }
 
+TEST(IOCgroup, IOStat) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroupfs("io"));
+
+  // Generate some I/O. Whether it reaches a backing store depends on the
+  // filesystem backing the test's temporary directory, so the exact counts
+  // aren't checked.
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_NO_ERRNO(SetContents(file.path(), std::string(4096, 'a')));
+  std::string contents;
+  ASSERT_NO_ERRNO(GetContents(file.path(), &contents));
+
+  std::string stat = ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("io.stat"));
+
+  // We're expecting the contents of "io.stat" to look similar to this:
+  //
+  // 0:25 rbytes=4096 wbytes=4096 rios=1 wios=1 dbytes=0 dios=0
+  std::vector<absl::string_view> lines =
+      absl::StrSplit(stat, '\n', absl::SkipEmpty());
+  for (const auto& line : lines) {
+    EXPECT_THAT(std::string(line),
+                MatchesRegex("[0-9]+:[0-9]+ rbytes=[0-9]+ wbytes=[0-9]+ "
+                             "rios=[0-9]+ wios=[0-9]+ dbytes=0 dios=0"));
+  }
+}
+
 // WriteAndVerifyControlValue attempts to write val to a cgroup file at path,
 // and verify the value by reading it afterwards.
 PosixError WriteAndVerifyControlValue(const Cgroup& c, std::string_view path,
@@ -383,6 +418,91 @@ PosixError WriteAndVerifyControlValue(const Cgroup& c, std::string_view path,
   return NoError();
 }
 
+TEST(CPUCgroup, RootBandwidthIsReadOnly) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroupfs("cpu"));
+  EXPECT_THAT(c.WriteIntegerControlFile("cpu.cfs_quota_us", 50000),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(c.WriteIntegerControlFile("cpu.cfs_period_us", 50000),
+              PosixErrorIs(EINVAL, _));
+}
+
+TEST(CPUCgroup, ChildBandwidth) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroupfs("cpu"));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+
+  // Children start out unlimited, regardless of their parent.
+  EXPECT_THAT(child.ReadIntegerControlFile("cpu.cfs_quota_us"),
+              IsPosixErrorOkAndHolds(-1));
+  EXPECT_THAT(child.ReadIntegerControlFile("cpu.cfs_period_us"),
+              IsPosixErrorOkAndHolds(100000));
+
+  ASSERT_NO_ERRNO(
+      WriteAndVerifyControlValue(child, "cpu.cfs_period_us", 50000));
+  ASSERT_NO_ERRNO(WriteAndVerifyControlValue(child, "cpu.cfs_quota_us", 20000));
+
+  // Out of range values are rejected.
+  EXPECT_THAT(child.WriteIntegerControlFile("cpu.cfs_period_us", 999),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(child.WriteIntegerControlFile("cpu.cfs_period_us", 1000001),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(child.WriteIntegerControlFile("cpu.cfs_quota_us", 999),
+              PosixErrorIs(EINVAL, _));
+
+  // Any negative quota lifts the limit.
+  ASSERT_NO_ERRNO(child.WriteIntegerControlFile("cpu.cfs_quota_us", -2));
+  EXPECT_THAT(child.ReadIntegerControlFile("cpu.cfs_quota_us"),
+              IsPosixErrorOkAndHolds(-1));
+}
+
+TEST(CPUCgroup, ChildBurst) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroupfs("cpu"));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  // Burst support was added in Linux 5.14.
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(
+      Exists(child.Relpath("cpu.cfs_burst_us"))));
+
+  EXPECT_THAT(child.ReadIntegerControlFile("cpu.cfs_burst_us"),
+              IsPosixErrorOkAndHolds(0));
+  ASSERT_NO_ERRNO(WriteAndVerifyControlValue(child, "cpu.cfs_quota_us", 20000));
+  ASSERT_NO_ERRNO(WriteAndVerifyControlValue(child, "cpu.cfs_burst_us", 10000));
+
+  // The burst can't exceed the quota.
+  EXPECT_THAT(child.WriteIntegerControlFile("cpu.cfs_burst_us", 30000),
+              PosixErrorIs(EINVAL, _));
+  EXPECT_THAT(child.WriteIntegerControlFile("cpu.cfs_quota_us", 5000),
+              PosixErrorIs(EINVAL, _));
+}
+
+TEST(CPUCgroup, CPUStat) {
+  SKIP_IF(!CgroupsAvailable());
+
+  Mounter m(ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir()));
+  Cgroup c = ASSERT_NO_ERRNO_AND_VALUE(m.MountCgroupfs("cpu"));
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+
+  // A new cgroup hasn't been throttled.
+  std::string stat =
+      ASSERT_NO_ERRNO_AND_VALUE(child.ReadControlFile("cpu.stat"));
+  EXPECT_THAT(stat, HasSubstr("nr_periods 0\n"));
+  EXPECT_THAT(stat, HasSubstr("nr_throttled 0\n"));
+  EXPECT_THAT(stat, HasSubstr("throttled_time 0\n"));
+
+  // The root cgroup reports throttling of the whole sandbox.
+  stat = ASSERT_NO_ERRNO_AND_VALUE(c.ReadControlFile("cpu.stat"));
+  EXPECT_THAT(stat, ContainsRegex("nr_periods [0-9]+\n"));
+  EXPECT_THAT(stat, ContainsRegex("nr_throttled [0-9]+\n"));
+  EXPECT_THAT(stat, ContainsRegex("throttled_time [0-9]+\n"));
+}
+
 TEST(JobCgroup, ReadWriteRead) {
   SKIP_IF(!CgroupsAvailable());
 
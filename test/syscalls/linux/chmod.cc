@@ -15,6 +15,7 @@
 #include <fcntl.h>
 #include <sys/stat.h>
 #include <sys/types.h>
+#include <syscall.h>
 #include <unistd.h>
 
 #include <string>
@@ -294,6 +295,99 @@ TEST(ChmodTest, ChmodWritableWithOpenFD) {
   EXPECT_THAT(WriteFd(fd2.get(), &c, 1), SyscallSucceedsWithValue(1));
 }
 
+#ifndef SYS_fchmodat2
+#define SYS_fchmodat2 452
+#endif
+
+int fchmodat2(int dirfd, const char* path, mode_t mode, int flags) {
+  return syscall(SYS_fchmodat2, dirfd, path, mode, flags);
+}
+
+// Skips the current test if the host kernel doesn't support fchmodat2, which
+// was added in Linux 6.6. Invalid flags are used so that the probe has no
+// effect if it is supported.
+#define SKIP_IF_FCHMODAT2_UNSUPPORTED()          \
+  SKIP_IF(!IsRunningOnGvisor() &&                \
+          fchmodat2(AT_FDCWD, "", 0, -1) < 0 && errno == ENOSYS)
+
+TEST(ChmodTest, Fchmodat2Follow) {
+  SKIP_IF_FCHMODAT2_UNSUPPORTED();
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0644));
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), file.path()));
+
+  // Without AT_SYMLINK_NOFOLLOW, fchmodat2 behaves like fchmodat.
+  ASSERT_THAT(fchmodat2(AT_FDCWD, link.path().c_str(), 0600, 0),
+              SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(stat(file.path().c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 07777, 0600);
+}
+
+TEST(ChmodTest, Fchmodat2NoFollow) {
+  SKIP_IF_FCHMODAT2_UNSUPPORTED();
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0644));
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), file.path()));
+
+  // AT_SYMLINK_NOFOLLOW has no effect on non-symlinks.
+  ASSERT_THAT(fchmodat2(AT_FDCWD, file.path().c_str(), 0600,
+                        AT_SYMLINK_NOFOLLOW),
+              SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(stat(file.path().c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 07777, 0600);
+
+  // Symlink modes can't be changed.
+  EXPECT_THAT(fchmodat2(AT_FDCWD, link.path().c_str(), 0644,
+                        AT_SYMLINK_NOFOLLOW),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+  ASSERT_THAT(stat(file.path().c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 07777, 0600);
+}
+
+TEST(ChmodTest, Fchmodat2EmptyPath) {
+  SKIP_IF_FCHMODAT2_UNSUPPORTED();
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0644));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+
+  // An empty path requires AT_EMPTY_PATH.
+  EXPECT_THAT(fchmodat2(fd.get(), "", 0600, 0), SyscallFailsWithErrno(ENOENT));
+
+  ASSERT_THAT(fchmodat2(fd.get(), "", 0600, AT_EMPTY_PATH), SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 07777, 0600);
+}
+
+TEST(ChmodTest, Fchmodat2EmptyPathSymlink) {
+  SKIP_IF_FCHMODAT2_UNSUPPORTED();
+  SKIP_IF(IsRunningWithVFS1());
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0644));
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), file.path()));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(link.path(), O_PATH | O_NOFOLLOW));
+
+  EXPECT_THAT(fchmodat2(fd.get(), "", 0600, AT_EMPTY_PATH),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST(ChmodTest, Fchmodat2InvalidFlags) {
+  SKIP_IF_FCHMODAT2_UNSUPPORTED();
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0644));
+  EXPECT_THAT(fchmodat2(AT_FDCWD, file.path().c_str(), 0600, AT_REMOVEDIR),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fchmodat2(AT_FDCWD, file.path().c_str(), 0600, 0x80000000),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 
 }  // namespace testing
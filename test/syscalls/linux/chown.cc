@@ -14,9 +14,11 @@
 
 #include <fcntl.h>
 #include <grp.h>
+#include <sys/stat.h>
 #include <sys/types.h>
 #include <unistd.h>
 
+#include <ios>
 #include <vector>
 
 #include "gmock/gmock.h"
@@ -85,6 +87,50 @@ TEST(ChownTest, FchownatEmptyPath) {
   ASSERT_THAT(fchownat(fd.get(), "", 0, 0, 0), SyscallFailsWithErrno(ENOENT));
 }
 
+TEST(ChownTest, FchownatEmptyPathUnprivileged) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETUID)));
+
+  const auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  ASSERT_THAT(chmod(dir.path().c_str(), 0777), SyscallSucceeds());
+  const std::string path = JoinPath(dir.path(), "file");
+
+  // Drop privileges and change IDs only in child thread, or else this parent
+  // thread won't be able to open some log files after the test ends.
+  ScopedThread([&] {
+    // Changing the EUID from 0 clears all effective capabilities, including
+    // CAP_CHOWN and CAP_DAC_READ_SEARCH.
+    EXPECT_THAT(
+        syscall(SYS_setresuid, -1, absl::GetFlag(FLAGS_scratch_uid1), -1),
+        SyscallSucceeds());
+    EXPECT_FALSE(
+        ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_RDWR | O_CREAT, 0644));
+
+    // Like fchown, the owner may keep its UID and set its group to one it is
+    // a member of.
+    EXPECT_THAT(fchownat(fd.get(), "", geteuid(), getegid(), AT_EMPTY_PATH),
+                SyscallSucceeds());
+    EXPECT_THAT(fchownat(fd.get(), "", -1, -1, AT_EMPTY_PATH),
+                SyscallSucceeds());
+
+    // Giving the file away still requires CAP_CHOWN.
+    EXPECT_THAT(fchownat(fd.get(), "", absl::GetFlag(FLAGS_scratch_uid2), -1,
+                         AT_EMPTY_PATH),
+                SyscallFailsWithErrno(EPERM));
+
+    // AT_FDCWD refers to the working directory. Nothing changes, so no
+    // ownership is needed.
+    EXPECT_THAT(fchownat(AT_FDCWD, "", -1, -1, AT_EMPTY_PATH),
+                SyscallSucceeds());
+  });
+
+  struct stat s = {};
+  ASSERT_THAT(stat(path.c_str(), &s), SyscallSucceeds());
+  EXPECT_EQ(s.st_uid, static_cast<uid_t>(absl::GetFlag(FLAGS_scratch_uid1)));
+}
+
 using Chown =
     std::function<PosixError(const std::string&, uid_t owner, gid_t group)>;
 
@@ -203,6 +249,80 @@ PosixError errorFromReturn(const std::string& name, int ret) {
   return NoError();
 }
 
+// chown(-1, -1) doesn't change ownership, but as in Linux it still clears the
+// setuid bit, and the setgid bit if group execution is permitted.
+TEST_P(ChownParamTest, ChownNoopClearsSetuid) {
+  const auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+
+  struct {
+    mode_t before;
+    mode_t after;
+  } const kCases[] = {
+      {04755, 0755},
+      {02775, 0775},
+      {06775, 0775},
+      // Without group execution, setgid marks mandatory locking and is kept.
+      {02745, 02745},
+      {0755, 0755},
+  };
+  for (const auto& c : kCases) {
+    ASSERT_THAT(chmod(file.path().c_str(), c.before), SyscallSucceeds());
+    EXPECT_NO_ERRNO(GetParam()(file.path(), -1, -1));
+
+    struct stat s = {};
+    ASSERT_THAT(stat(file.path().c_str(), &s), SyscallSucceeds());
+    EXPECT_EQ(s.st_mode & 07777, c.after) << std::oct << c.before;
+    EXPECT_EQ(s.st_uid, geteuid());
+  }
+}
+
+TEST_P(ChownParamTest, ChownNoopDirectoryKeepsSetgid) {
+  const auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  ASSERT_THAT(chmod(dir.path().c_str(), 02775), SyscallSucceeds());
+
+  // Some variants open the file for writing, which directories don't allow;
+  // use fchownat(AT_EMPTY_PATH) on a directory FD instead.
+  const auto fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_DIRECTORY | O_RDONLY));
+  ASSERT_THAT(fchownat(fd.get(), "", -1, -1, AT_EMPTY_PATH),
+              SyscallSucceeds());
+
+  struct stat s = {};
+  ASSERT_THAT(stat(dir.path().c_str(), &s), SyscallSucceeds());
+  EXPECT_EQ(s.st_mode & 07777, 02775);
+}
+
+TEST_P(ChownParamTest, ChownNoopByNonOwner) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETUID)));
+
+  const auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0666));
+  const auto setuid_file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0666));
+  ASSERT_THAT(chmod(setuid_file.path().c_str(), 04777), SyscallSucceeds());
+  EXPECT_THAT(chmod(GetAbsoluteTestTmpdir().c_str(), 0777), SyscallSucceeds());
+
+  // Drop privileges and change IDs only in child thread, or else this parent
+  // thread won't be able to open some log files after the test ends.
+  ScopedThread([&] {
+    AutoCapability cap_chown(CAP_CHOWN, false);
+    AutoCapability cap_fowner(CAP_FOWNER, false);
+    EXPECT_THAT(
+        syscall(SYS_setresuid, -1, absl::GetFlag(FLAGS_scratch_uid1), -1),
+        SyscallSucceeds());
+
+    // Nothing changes, so no privilege is needed.
+    EXPECT_NO_ERRNO(GetParam()(file.path(), -1, -1));
+
+    // Clearing the setuid bit is a mode change, which requires ownership.
+    EXPECT_THAT(GetParam()(setuid_file.path(), -1, -1),
+                PosixErrorIs(EPERM, ::testing::ContainsRegex("chown")));
+  });
+
+  struct stat s = {};
+  ASSERT_THAT(stat(setuid_file.path().c_str(), &s), SyscallSucceeds());
+  EXPECT_EQ(s.st_mode & 07777, 04777);
+}
+
 INSTANTIATE_TEST_SUITE_P(
     ChownKinds, ChownParamTest,
     ::testing::Values(
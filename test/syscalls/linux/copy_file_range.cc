@@ -0,0 +1,195 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/string_view.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+constexpr char kData[] = "0123456789abcdefghijklmnopqrstuvwxyz";
+constexpr int kDataSize = sizeof(kData) - 1;
+
+ssize_t CopyFileRange(int fd_in, off64_t* off_in, int fd_out, off64_t* off_out,
+                      size_t len, unsigned int flags) {
+  return syscall(SYS_copy_file_range, fd_in, off_in, fd_out, off_out, len,
+                 flags);
+}
+
+class CopyFileRangeTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    // copy_file_range is only implemented in VFS2.
+    SKIP_IF(IsRunningWithVFS1());
+
+    in_file_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+        GetAbsoluteTestTmpdir(), kData, TempPath::kDefaultFileMode));
+    out_file_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+    in_fd_ = ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_RDONLY));
+    out_fd_ = ASSERT_NO_ERRNO_AND_VALUE(Open(out_file_.path(), O_RDWR));
+  }
+
+  TempPath in_file_;
+  TempPath out_file_;
+  FileDescriptor in_fd_;
+  FileDescriptor out_fd_;
+};
+
+TEST_F(CopyFileRangeTest, CopyWithOffsets) {
+  off64_t in_off = 10;
+  off64_t out_off = 5;
+  ASSERT_THAT(CopyFileRange(in_fd_.get(), &in_off, out_fd_.get(), &out_off,
+                            10, 0),
+              SyscallSucceedsWithValue(10));
+  EXPECT_EQ(in_off, 20);
+  EXPECT_EQ(out_off, 15);
+
+  // The file offsets are unchanged.
+  EXPECT_THAT(lseek(in_fd_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+  EXPECT_THAT(lseek(out_fd_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path()));
+  EXPECT_EQ(contents, std::string(5, '\0') + std::string(kData + 10, 10));
+}
+
+TEST_F(CopyFileRangeTest, CopyWithFileOffsets) {
+  ASSERT_THAT(lseek(in_fd_.get(), 3, SEEK_SET), SyscallSucceedsWithValue(3));
+  ASSERT_THAT(CopyFileRange(in_fd_.get(), nullptr, out_fd_.get(), nullptr, 7,
+                            0),
+              SyscallSucceedsWithValue(7));
+  EXPECT_THAT(lseek(in_fd_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(10));
+  EXPECT_THAT(lseek(out_fd_.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(7));
+
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(out_file_.path()));
+  EXPECT_EQ(contents, std::string(kData + 3, 7));
+}
+
+TEST_F(CopyFileRangeTest, StopsAtEOF) {
+  off64_t in_off = kDataSize - 4;
+  ASSERT_THAT(CopyFileRange(in_fd_.get(), &in_off, out_fd_.get(), nullptr,
+                            100, 0),
+              SyscallSucceedsWithValue(4));
+  EXPECT_EQ(in_off, kDataSize);
+
+  ASSERT_THAT(CopyFileRange(in_fd_.get(), &in_off, out_fd_.get(), nullptr,
+                            100, 0),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST_F(CopyFileRangeTest, InvalidFlags) {
+  EXPECT_THAT(CopyFileRange(in_fd_.get(), nullptr, out_fd_.get(), nullptr, 1,
+                            1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(CopyFileRangeTest, NegativeOffset) {
+  off64_t in_off = -1;
+  EXPECT_THAT(CopyFileRange(in_fd_.get(), &in_off, out_fd_.get(), nullptr, 1,
+                            0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(CopyFileRangeTest, BadFileModes) {
+  // The input must be readable.
+  const FileDescriptor wronly =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_WRONLY));
+  EXPECT_THAT(CopyFileRange(wronly.get(), nullptr, out_fd_.get(), nullptr, 1,
+                            0),
+              SyscallFailsWithErrno(EBADF));
+
+  // The output must be writable.
+  EXPECT_THAT(CopyFileRange(in_fd_.get(), nullptr, in_fd_.get(), nullptr, 1,
+                            0),
+              SyscallFailsWithErrno(EBADF));
+
+  // The output must not be opened with O_APPEND.
+  const FileDescriptor append =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(out_file_.path(), O_WRONLY | O_APPEND));
+  EXPECT_THAT(CopyFileRange(in_fd_.get(), nullptr, append.get(), nullptr, 1,
+                            0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(CopyFileRangeTest, Directory) {
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dir_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(CopyFileRange(dir_fd.get(), nullptr, out_fd_.get(), nullptr, 1,
+                            0),
+              SyscallFailsWithErrno(EISDIR));
+}
+
+TEST_F(CopyFileRangeTest, OverlappingRangesInSameFile) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file_.path(), O_RDWR));
+  off64_t in_off = 0;
+  off64_t out_off = 5;
+  EXPECT_THAT(CopyFileRange(fd.get(), &in_off, fd.get(), &out_off, 10, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Non-overlapping ranges within the same file are fine.
+  out_off = 20;
+  ASSERT_THAT(CopyFileRange(fd.get(), &in_off, fd.get(), &out_off, 10, 0),
+              SyscallSucceedsWithValue(10));
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(in_file_.path()));
+  EXPECT_EQ(contents.substr(20, 10), std::string(kData, 10));
+}
+
+TEST_F(CopyFileRangeTest, CrossFilesystem) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const auto mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", 0, "mode=0700", 0));
+  const TempPath tmpfs_file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(dir.path()));
+  const FileDescriptor tmpfs_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(tmpfs_file.path(), O_WRONLY));
+
+  off64_t in_off = 2;
+  int ret = CopyFileRange(in_fd_.get(), &in_off, tmpfs_fd.get(), nullptr,
+                          kDataSize, 0);
+  if (!IsRunningOnGvisor() && ret < 0 && errno == EXDEV) {
+    // Linux 5.19 and later only copy across filesystems of the same type.
+    GTEST_SKIP() << "Host does not support cross-filesystem copies";
+  }
+  ASSERT_THAT(ret, SyscallSucceedsWithValue(kDataSize - 2));
+  EXPECT_EQ(in_off, kDataSize);
+
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(tmpfs_file.path()));
+  EXPECT_EQ(contents, absl::string_view(kData + 2, kDataSize - 2));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -13,15 +13,23 @@
 // limitations under the License.
 
 #include <fcntl.h>
+#include <linux/random.h>
+#include <poll.h>
+#include <sys/ioctl.h>
 #include <sys/stat.h>
 #include <sys/types.h>
 #include <unistd.h>
 
+#include <string>
 #include <vector>
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
 #include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -174,6 +182,122 @@ TEST(DevTest, ReadDevFuseWithoutMount) {
               SyscallFailsWithErrno(EPERM));
 }
 
+PosixErrorOr<int> ReadProcRandomInt(const std::string& name) {
+  ASSIGN_OR_RETURN_ERRNO(
+      std::string contents,
+      GetContents(absl::StrCat("/proc/sys/kernel/random/", name)));
+  int value;
+  if (!absl::SimpleAtoi(contents, &value)) {
+    return PosixError(EINVAL, absl::StrCat("invalid value: ", contents));
+  }
+  return value;
+}
+
+TEST(DevTest, RandomGetEntropyCount) {
+  const int poolsize = ASSERT_NO_ERRNO_AND_VALUE(ReadProcRandomInt("poolsize"));
+  const int avail =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadProcRandomInt("entropy_avail"));
+  EXPECT_GE(avail, 0);
+  EXPECT_LE(avail, poolsize);
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/random", O_RDONLY));
+  int count = -1;
+  ASSERT_THAT(ioctl(fd.get(), RNDGETENTCNT, &count), SyscallSucceeds());
+  EXPECT_GE(count, 0);
+  EXPECT_LE(count, poolsize);
+}
+
+TEST(DevTest, RandomReadableWhenSeeded) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/random", O_RDONLY | O_NONBLOCK));
+  struct pollfd pfd = {.fd = fd.get(), .events = POLLIN};
+  ASSERT_THAT(RetryEINTR(poll)(&pfd, 1, 0), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(pfd.revents & POLLIN, POLLIN);
+
+  char buf[16];
+  EXPECT_THAT(read(fd.get(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+}
+
+TEST(DevTest, RandomAddEntropy) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/random", O_WRONLY));
+
+  struct {
+    struct rand_pool_info info;
+    char buf[8];
+  } req = {};
+  req.info.entropy_count = 64;
+  req.info.buf_size = sizeof(req.buf);
+
+  // Modern Linux no longer zaps the entropy count, and reports the pool as
+  // full once it is initialized, so only gVisor's count is predictable.
+  ASSERT_THAT(ioctl(fd.get(), RNDZAPENTCNT), SyscallSucceeds());
+  ASSERT_THAT(ioctl(fd.get(), RNDADDENTROPY, &req), SyscallSucceeds());
+  if (IsRunningOnGvisor()) {
+    int count = -1;
+    ASSERT_THAT(ioctl(fd.get(), RNDGETENTCNT, &count), SyscallSucceeds());
+    EXPECT_EQ(count, 64);
+    EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadProcRandomInt("entropy_avail")),
+              64);
+  }
+
+  // Credits saturate at the pool size.
+  int bits = 1024;
+  ASSERT_THAT(ioctl(fd.get(), RNDADDTOENTCNT, &bits), SyscallSucceeds());
+  if (IsRunningOnGvisor()) {
+    const int poolsize =
+        ASSERT_NO_ERRNO_AND_VALUE(ReadProcRandomInt("poolsize"));
+    int count = -1;
+    ASSERT_THAT(ioctl(fd.get(), RNDGETENTCNT, &count), SyscallSucceeds());
+    EXPECT_EQ(count, poolsize);
+  }
+}
+
+TEST(DevTest, RandomAddEntropyInvalid) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/random", O_WRONLY));
+
+  struct rand_pool_info info = {};
+  info.entropy_count = -1;
+  EXPECT_THAT(ioctl(fd.get(), RNDADDENTROPY, &info),
+              SyscallFailsWithErrno(EINVAL));
+
+  info.entropy_count = 8;
+  info.buf_size = -1;
+  EXPECT_THAT(ioctl(fd.get(), RNDADDENTROPY, &info),
+              SyscallFailsWithErrno(EINVAL));
+
+  int bits = -1;
+  EXPECT_THAT(ioctl(fd.get(), RNDADDTOENTCNT, &bits),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(DevTest, RandomIoctlsRequireCapSysAdmin) {
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/urandom", O_WRONLY));
+
+  struct rand_pool_info info = {};
+  EXPECT_THAT(ioctl(fd.get(), RNDADDENTROPY, &info),
+              SyscallFailsWithErrno(EPERM));
+  int bits = 8;
+  EXPECT_THAT(ioctl(fd.get(), RNDADDTOENTCNT, &bits),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(ioctl(fd.get(), RNDZAPENTCNT), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(ioctl(fd.get(), RNDCLEARPOOL), SyscallFailsWithErrno(EPERM));
+
+  // Reading the entropy count is unprivileged.
+  int count = -1;
+  EXPECT_THAT(ioctl(fd.get(), RNDGETENTCNT, &count), SyscallSucceeds());
+}
+
 }  // namespace
 }  // namespace testing
 
@@ -54,6 +54,8 @@ constexpr char kProcExeWorkload[] =
     "test/syscalls/linux/exec_proc_exe_workload";
 constexpr char kAssertClosedWorkload[] =
     "test/syscalls/linux/exec_assert_closed_workload";
+constexpr char kListFDsWorkload[] =
+    "test/syscalls/linux/exec_list_fds_workload";
 constexpr char kPriorityWorkload[] = "test/syscalls/linux/priority_execve";
 
 constexpr char kExit42[] = "--exec_exit_42";
@@ -524,6 +526,30 @@ TEST(ExecTest, CloexecEventfd) {
             W_EXITCODE(0, 0), "");
 }
 
+// Tests that exec closes every fd with FD_CLOEXEC set, however it was set,
+// and no others.
+TEST(ExecTest, CloexecClosesOnlyCloexecFDs) {
+  const TempPath tempFile = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor cloexec1 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(tempFile.path(), O_RDONLY | O_CLOEXEC));
+  const FileDescriptor kept1 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(tempFile.path(), O_RDONLY));
+  const FileDescriptor cloexec2 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(tempFile.path(), O_WRONLY));
+  ASSERT_THAT(fcntl(cloexec2.get(), F_SETFD, FD_CLOEXEC), SyscallSucceeds());
+  const FileDescriptor kept2 = ASSERT_NO_ERRNO_AND_VALUE(cloexec1.Dup());
+  int efd;
+  ASSERT_THAT(efd = eventfd(0, EFD_CLOEXEC), SyscallSucceeds());
+  const FileDescriptor cloexec3(efd);
+
+  CheckExec(RunfilePath(kListFDsWorkload),
+            {RunfilePath(kListFDsWorkload), absl::StrCat("+", kept1.get()),
+             absl::StrCat("+", kept2.get()), absl::StrCat("-", cloexec1.get()),
+             absl::StrCat("-", cloexec2.get()),
+             absl::StrCat("-", cloexec3.get())},
+            {}, W_EXITCODE(0, 0), absl::StrCat("open fd: ", kept1.get(), "\n"));
+}
+
 constexpr int kLinuxMaxSymlinks = 40;
 
 TEST(ExecTest, SymlinkLimitExceeded) {
@@ -0,0 +1,63 @@
+// Copyright 2021 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <dirent.h>
+#include <errno.h>
+#include <stdlib.h>
+
+#include <iostream>
+#include <set>
+
+#include "absl/strings/numbers.h"
+
+// Lists the fds open in this process on stderr, then checks them against the
+// arguments: "+N" asserts that fd N is open, and "-N" that it is closed.
+// Exits with code 2 if any assertion fails.
+int main(int argc, char** argv) {
+  DIR* dir = opendir("/proc/self/fd");
+  if (dir == nullptr) {
+    std::cerr << "opendir /proc/self/fd failed: " << errno << std::endl;
+    exit(1);
+  }
+  std::set<int> open_fds;
+  for (struct dirent* de = readdir(dir); de != nullptr; de = readdir(dir)) {
+    int fd;
+    if (!absl::SimpleAtoi(de->d_name, &fd) || fd == dirfd(dir)) {
+      continue;
+    }
+    open_fds.insert(fd);
+  }
+  closedir(dir);
+
+  for (int fd : open_fds) {
+    std::cerr << "open fd: " << fd << std::endl;
+  }
+
+  int ret = 0;
+  for (int i = 1; i < argc; i++) {
+    const char* arg = argv[i];
+    int fd;
+    if ((arg[0] != '+' && arg[0] != '-') || !absl::SimpleAtoi(arg + 1, &fd)) {
+      std::cerr << "argument " << arg << " could not be parsed" << std::endl;
+      exit(1);
+    }
+    const bool want_open = arg[0] == '+';
+    if ((open_fds.count(fd) != 0) != want_open) {
+      std::cerr << "fd " << fd << (want_open ? " should" : " should not")
+                << " be open" << std::endl;
+      ret = 2;
+    }
+  }
+  return ret;
+}
@@ -13,9 +13,13 @@
 // limitations under the License.
 
 #include <errno.h>
+#include <fcntl.h>
 #include <syscall.h>
 #include <unistd.h>
 
+#include <string>
+#include <vector>
+
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
 #include "test/util/file_descriptor.h"
@@ -30,7 +34,7 @@ TEST(FAdvise64Test, Basic) {
   auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
   const auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
 
-  // fadvise64 is noop in gVisor, so just test that it succeeds.
+  // Most advice has no observable effect, so just test that it succeeds.
   ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 10, POSIX_FADV_NORMAL),
               SyscallSucceeds());
   ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 10, POSIX_FADV_RANDOM),
@@ -45,6 +49,115 @@ TEST(FAdvise64Test, Basic) {
               SyscallSucceeds());
 }
 
+TEST(FAdvise64Test, ZeroLengthDropsWholeFile) {
+  constexpr char kData[] = "fadvise64 test data";
+  constexpr int kSize = 3 * 4096;
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  // Write data throughout the file, including a partial last page.
+  std::string contents(kSize + sizeof(kData), 'a');
+  contents.replace(kSize, sizeof(kData), kData, sizeof(kData));
+  ASSERT_THAT(WriteFd(fd.get(), contents.data(), contents.size()),
+              SyscallSucceedsWithValue(contents.size()));
+
+  // A length of 0 means the range extends to EOF; dropping the file's
+  // cached pages must not lose any data.
+  ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 0, POSIX_FADV_DONTNEED),
+              SyscallSucceeds());
+  std::vector<char> buf(contents.size());
+  ASSERT_THAT(pread(fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), contents);
+
+  // Likewise starting partway through the file.
+  ASSERT_THAT(
+      syscall(__NR_fadvise64, fd.get(), 4096 + 1, 0, POSIX_FADV_DONTNEED),
+      SyscallSucceeds());
+  ASSERT_THAT(pread(fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), contents);
+}
+
+TEST(FAdvise64Test, WillNeedPreservesContents) {
+  constexpr int kSize = 16 * 4096 + 123;
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  std::string contents(kSize, '\0');
+  for (int i = 0; i < kSize; i++) {
+    contents[i] = static_cast<char>(i % 251);
+  }
+  ASSERT_THAT(WriteFd(fd.get(), contents.data(), contents.size()),
+              SyscallSucceedsWithValue(contents.size()));
+
+  // Drop the file's cached pages so that WILLNEED has something to read, then
+  // read the file while the prefetch may still be in progress.
+  ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 0, POSIX_FADV_DONTNEED),
+              SyscallSucceeds());
+  ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 4096 + 1, 0,
+                      POSIX_FADV_WILLNEED),
+              SyscallSucceeds());
+  std::vector<char> buf(contents.size());
+  ASSERT_THAT(pread(fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), contents);
+
+  // Writes after WILLNEED must not be overwritten by prefetched data.
+  ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 0, POSIX_FADV_DONTNEED),
+              SyscallSucceeds());
+  ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 0, POSIX_FADV_WILLNEED),
+              SyscallSucceeds());
+  ASSERT_THAT(pwrite(fd.get(), "b", 1, kSize - 1), SyscallSucceedsWithValue(1));
+  contents[kSize - 1] = 'b';
+  ASSERT_THAT(pread(fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), contents);
+}
+
+TEST(FAdvise64Test, WillNeedThenClose) {
+  constexpr int kSize = 64 * 4096;
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), std::string(kSize, 'a'),
+      TempPath::kDefaultFileMode));
+
+  // Closing the file immediately abandons any prefetch in progress.
+  for (int i = 0; i < 10; i++) {
+    auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+    ASSERT_THAT(syscall(__NR_fadvise64, fd.get(), 0, 0, POSIX_FADV_WILLNEED),
+                SyscallSucceeds());
+  }
+
+  const auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  std::vector<char> buf(kSize);
+  ASSERT_THAT(pread(fd.get(), buf.data(), buf.size(), 0),
+              SyscallSucceedsWithValue(buf.size()));
+  EXPECT_EQ(std::string(buf.data(), buf.size()), std::string(kSize, 'a'));
+}
+
+TEST(FAdvise64Test, OffsetBeyondEOF) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+  ASSERT_THAT(WriteFd(fd.get(), "a", 1), SyscallSucceedsWithValue(1));
+
+  for (int advice : {POSIX_FADV_NORMAL, POSIX_FADV_WILLNEED,
+                     POSIX_FADV_DONTNEED, POSIX_FADV_NOREUSE}) {
+    EXPECT_THAT(syscall(__NR_fadvise64, fd.get(), 1 << 20, 0, advice),
+                SyscallSucceeds());
+    EXPECT_THAT(syscall(__NR_fadvise64, fd.get(), 1 << 20, 4096, advice),
+                SyscallSucceeds());
+  }
+
+  // Offset and length overflowing also means "to EOF".
+  EXPECT_THAT(syscall(__NR_fadvise64, fd.get(), 1, INT64_MAX,
+                      POSIX_FADV_DONTNEED),
+              SyscallSucceeds());
+
+  char c;
+  ASSERT_THAT(pread(fd.get(), &c, 1, 0), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(c, 'a');
+}
+
 TEST(FAdvise64Test, FAdvise64WithOpath) {
   SKIP_IF(IsRunningWithVFS1());
   auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
@@ -14,7 +14,10 @@
 
 #include <errno.h>
 #include <fcntl.h>
+#include <linux/falloc.h>
 #include <signal.h>
+#include <stdlib.h>
+#include <string.h>
 #include <sys/eventfd.h>
 #include <sys/resource.h>
 #include <sys/signalfd.h>
@@ -34,6 +37,7 @@
 #include "test/util/cleanup.h"
 #include "test/util/eventfd_util.h"
 #include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/socket_util.h"
 #include "test/util/temp_path.h"
@@ -89,6 +93,79 @@ TEST_F(AllocateTest, Fallocate) {
   EXPECT_EQ(buf.st_size, 40);
 }
 
+TEST_F(AllocateTest, FallocateGrowUpdatesTimestamps) {
+  struct stat before;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &before), SyscallSucceeds());
+
+  // Ensure that any timestamp update is observable.
+  absl::SleepFor(absl::Milliseconds(10));
+
+  ASSERT_THAT(fallocate(test_file_fd_.get(), 0, 0, 10), SyscallSucceeds());
+  struct stat after;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &after), SyscallSucceeds());
+  EXPECT_EQ(after.st_size, 10);
+  EXPECT_GT(absl::TimeFromTimespec(after.st_ctim),
+            absl::TimeFromTimespec(before.st_ctim));
+
+  // Linux's tmpfs only updates ctime on fallocate.
+  if (IsRunningOnGvisor() ||
+      !ASSERT_NO_ERRNO_AND_VALUE(IsTmpfs(test_file_name_))) {
+    EXPECT_GT(absl::TimeFromTimespec(after.st_mtim),
+              absl::TimeFromTimespec(before.st_mtim));
+  }
+}
+
+TEST_F(AllocateTest, FallocateKeepSizeUpdatesCtime) {
+  // VFS1 only supports mode 0.
+  SKIP_IF(IsRunningWithVFS1());
+
+  ASSERT_THAT(fallocate(test_file_fd_.get(), 0, 0, 10), SyscallSucceeds());
+  struct stat before;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &before), SyscallSucceeds());
+
+  // Ensure that any timestamp update is observable.
+  absl::SleepFor(absl::Milliseconds(10));
+
+  ASSERT_THAT(
+      fallocate(test_file_fd_.get(), FALLOC_FL_KEEP_SIZE, 0, getpagesize()),
+      SyscallSucceeds());
+  struct stat after;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &after), SyscallSucceeds());
+  EXPECT_EQ(after.st_size, 10);
+  EXPECT_GT(absl::TimeFromTimespec(after.st_ctim),
+            absl::TimeFromTimespec(before.st_ctim));
+}
+
+TEST_F(AllocateTest, FallocateAllocatedRangeIsNoop) {
+  constexpr char kData[] = "abcdefgh";
+  ASSERT_THAT(
+      pwrite(test_file_fd_.get(), kData, sizeof(kData), getpagesize() - 4),
+      SyscallSucceedsWithValue(sizeof(kData)));
+
+  const off_t len = 2 * getpagesize();
+  ASSERT_THAT(fallocate(test_file_fd_.get(), 0, 0, len), SyscallSucceeds());
+  struct stat before;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &before), SyscallSucceeds());
+  EXPECT_EQ(before.st_size, len);
+
+  // Ensure that any timestamp update is observable.
+  absl::SleepFor(absl::Milliseconds(10));
+
+  // Allocating the same range again must succeed without changing the file.
+  ASSERT_THAT(fallocate(test_file_fd_.get(), 0, 0, len), SyscallSucceeds());
+  struct stat after;
+  ASSERT_THAT(fstat(test_file_fd_.get(), &after), SyscallSucceeds());
+  EXPECT_EQ(after.st_size, len);
+  EXPECT_EQ(after.st_blocks, before.st_blocks);
+  EXPECT_EQ(absl::TimeFromTimespec(after.st_mtim),
+            absl::TimeFromTimespec(before.st_mtim));
+
+  char buf[sizeof(kData)];
+  ASSERT_THAT(pread(test_file_fd_.get(), buf, sizeof(buf), getpagesize() - 4),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(memcmp(buf, kData, sizeof(kData)), 0);
+}
+
 TEST_F(AllocateTest, FallocateInvalid) {
   // Invalid FD
   EXPECT_THAT(fallocate(-1, 0, 0, 10), SyscallFailsWithErrno(EBADF));
@@ -108,6 +185,26 @@ TEST_F(AllocateTest, FallocateReadonly) {
   EXPECT_THAT(fallocate(fd.get(), 0, 0, 10), SyscallFailsWithErrno(EBADF));
 }
 
+TEST_F(AllocateTest, FallocateDirectory) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+
+  // Offset and length are validated before anything else, so invalid
+  // arguments take precedence over the file type.
+  EXPECT_THAT(fallocate(fd.get(), 0, 0, -1), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fallocate(fd.get(), 0, -1, 10), SyscallFailsWithErrno(EINVAL));
+
+  // Directories can't be opened for writing, so EISDIR is never reached.
+  EXPECT_THAT(fallocate(fd.get(), 0, 0, 10), SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(AllocateTest, FallocateReadonlyInvalid) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(fallocate(fd.get(), 0, 0, -1), SyscallFailsWithErrno(EINVAL));
+}
+
 TEST_F(AllocateTest, FallocateWithOpath) {
   SKIP_IF(IsRunningWithVFS1());
   auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
@@ -194,6 +291,87 @@ TEST_F(AllocateTest, FallocateOtherFDs) {
   close(pipefds[1]);
 }
 
+TEST(FallocateUnshareTest, Tmpfs) {
+  // Linux's tmpfs doesn't support FALLOC_FL_UNSHARE_RANGE.
+  SKIP_IF(!IsRunningOnGvisor());
+
+  int fd;
+  ASSERT_THAT(fd = syscall(__NR_memfd_create, "fallocate", 0),
+              SyscallSucceeds());
+  FileDescriptor memfd(fd);
+  const int page_size = getpagesize();
+  constexpr char kData[] = "unshare";
+  ASSERT_THAT(PwriteFd(memfd.get(), kData, sizeof(kData), 0),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  // tmpfs files never share pages, so unsharing is a no-op that preserves
+  // file contents and, with FALLOC_FL_KEEP_SIZE, the file size.
+  ASSERT_THAT(fallocate(memfd.get(),
+                        FALLOC_FL_UNSHARE_RANGE | FALLOC_FL_KEEP_SIZE, 0,
+                        page_size),
+              SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(fstat(memfd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, sizeof(kData));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(PreadFd(memfd.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+
+  // Without FALLOC_FL_KEEP_SIZE, the file grows to cover the range.
+  ASSERT_THAT(
+      fallocate(memfd.get(), FALLOC_FL_UNSHARE_RANGE, page_size, page_size),
+      SyscallSucceeds());
+  ASSERT_THAT(fstat(memfd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, 2 * page_size);
+
+  // Writes to the range still succeed.
+  ASSERT_THAT(PwriteFd(memfd.get(), kData, sizeof(kData), page_size),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  // The range must be block-aligned.
+  EXPECT_THAT(fallocate(memfd.get(), FALLOC_FL_UNSHARE_RANGE, 1, page_size),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fallocate(memfd.get(), FALLOC_FL_UNSHARE_RANGE, 0, 1),
+              SyscallFailsWithErrno(EINVAL));
+
+  // FALLOC_FL_UNSHARE_RANGE can't be combined with other modes.
+  EXPECT_THAT(fallocate(memfd.get(),
+                        FALLOC_FL_UNSHARE_RANGE | FALLOC_FL_PUNCH_HOLE, 0,
+                        page_size),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST(FallocateUnshareTest, Readonly) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  auto fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(fallocate(fd.get(), FALLOC_FL_UNSHARE_RANGE, 0, getpagesize()),
+              SyscallFailsWithErrno(EBADF));
+}
+
+// Test that FALLOC_FL_KEEP_SIZE doesn't change the size of a file whose data
+// the sandbox doesn't cache. TEST_UNCACHED_DIR is only set by the gVisor test
+// runner.
+TEST(FallocateUncachedTest, KeepSize) {
+  const char* dir = getenv("TEST_UNCACHED_DIR");
+  SKIP_IF(dir == nullptr);
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(dir, "0123456789", 0666));
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  ASSERT_THAT(fallocate(fd.get(), FALLOC_FL_KEEP_SIZE, 0, getpagesize()),
+              SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, 10);
+
+  // Without FALLOC_FL_KEEP_SIZE, the file grows to cover the range.
+  ASSERT_THAT(fallocate(fd.get(), 0, 0, getpagesize()), SyscallSucceeds());
+  ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, getpagesize());
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
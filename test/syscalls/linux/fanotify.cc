@@ -0,0 +1,269 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <fcntl.h>
+#include <sys/fanotify.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+PosixErrorOr<FileDescriptor> FanotifyInit(unsigned int flags,
+                                          unsigned int event_f_flags) {
+  int fd = fanotify_init(flags, event_f_flags);
+  MaybeSave();
+  if (fd < 0) {
+    return PosixError(errno, "fanotify_init() failed");
+  }
+  return FileDescriptor(fd);
+}
+
+PosixError FanotifyMark(int fd, unsigned int flags, uint64_t mask,
+                        const std::string& path) {
+  int ret = fanotify_mark(fd, flags, mask, AT_FDCWD, path.c_str());
+  MaybeSave();
+  if (ret < 0) {
+    return PosixError(errno, "fanotify_mark() failed");
+  }
+  return NoError();
+}
+
+// ReadEvent reads a single event from fd.
+PosixErrorOr<struct fanotify_event_metadata> ReadEvent(int fd) {
+  struct fanotify_event_metadata event;
+  int n = read(fd, &event, sizeof(event));
+  if (n < 0) {
+    return PosixError(errno, "read() failed");
+  }
+  if (n != sizeof(event) || event.event_len != sizeof(event) ||
+      event.vers != FANOTIFY_METADATA_VERSION) {
+    return PosixError(EINVAL, "unexpected event");
+  }
+  return event;
+}
+
+// ExpectSameFile checks that fd refers to the file at path.
+void ExpectSameFile(int fd, const std::string& path) {
+  struct stat fd_stat, path_stat;
+  ASSERT_THAT(fstat(fd, &fd_stat), SyscallSucceeds());
+  ASSERT_THAT(stat(path.c_str(), &path_stat), SyscallSucceeds());
+  EXPECT_EQ(fd_stat.st_dev, path_stat.st_dev);
+  EXPECT_EQ(fd_stat.st_ino, path_stat.st_ino);
+}
+
+TEST(FanotifyTest, InitInvalidFlags) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  EXPECT_THAT(fanotify_init(FAN_CLASS_CONTENT | FAN_CLASS_PRE_CONTENT, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(fanotify_init(FAN_CLASS_NOTIF, O_ACCMODE),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(FanotifyTest, InitRequiresCapSysAdmin) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+
+  // Linux 5.13+ allows unprivileged groups without permission events.
+  EXPECT_THAT(fanotify_init(FAN_CLASS_CONTENT, O_RDONLY),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST(FanotifyTest, PermissionEventsRequireContentClass) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan =
+      ASSERT_NO_ERRNO_AND_VALUE(FanotifyInit(FAN_CLASS_NOTIF, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  EXPECT_THAT(fanotify_mark(fan.get(), FAN_MARK_ADD, FAN_OPEN_PERM, AT_FDCWD,
+                            file.path().c_str()),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(FanotifyTest, OpenAndCloseEvents) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan = ASSERT_NO_ERRNO_AND_VALUE(
+      FanotifyInit(FAN_CLASS_NOTIF | FAN_NONBLOCK, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_NO_ERRNO(FanotifyMark(fan.get(), FAN_MARK_ADD,
+                               FAN_OPEN | FAN_CLOSE_NOWRITE, file.path()));
+
+  ASSERT_NO_ERRNO(Open(file.path(), O_RDONLY));
+
+  const struct fanotify_event_metadata event =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadEvent(fan.get()));
+  EXPECT_EQ(event.mask, FAN_OPEN | FAN_CLOSE_NOWRITE);
+  EXPECT_EQ(event.pid, getpid());
+  const FileDescriptor event_fd(event.fd);
+  ExpectSameFile(event_fd.get(), file.path());
+
+  // Reading through the event's fd must not generate more events.
+  char buf;
+  ASSERT_THAT(read(event_fd.get(), &buf, 1), SyscallSucceeds());
+  EXPECT_THAT(ReadEvent(fan.get()), PosixErrorIs(EAGAIN, ::testing::_));
+}
+
+TEST(FanotifyTest, RemoveMark) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan = ASSERT_NO_ERRNO_AND_VALUE(
+      FanotifyInit(FAN_CLASS_NOTIF | FAN_NONBLOCK, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  EXPECT_THAT(fanotify_mark(fan.get(), FAN_MARK_REMOVE, FAN_OPEN, AT_FDCWD,
+                            file.path().c_str()),
+              SyscallFailsWithErrno(ENOENT));
+
+  ASSERT_NO_ERRNO(FanotifyMark(fan.get(), FAN_MARK_ADD, FAN_OPEN, file.path()));
+  ASSERT_NO_ERRNO(
+      FanotifyMark(fan.get(), FAN_MARK_REMOVE, FAN_OPEN, file.path()));
+  ASSERT_NO_ERRNO(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(ReadEvent(fan.get()), PosixErrorIs(EAGAIN, ::testing::_));
+}
+
+TEST(FanotifyTest, ResponseToUnknownFD) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan =
+      ASSERT_NO_ERRNO_AND_VALUE(FanotifyInit(FAN_CLASS_CONTENT, O_RDONLY));
+  struct fanotify_response response = {};
+  response.fd = fan.get();
+  response.response = FAN_ALLOW;
+  EXPECT_THAT(write(fan.get(), &response, sizeof(response)),
+              SyscallFailsWithErrno(ENOENT));
+  response.response = 0;
+  EXPECT_THAT(write(fan.get(), &response, sizeof(response)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// OpenInChild opens path in a child process, which waits for the listener's
+// response, and returns the child's pid. The child exits with 0 if open
+// returned the expected errno (or succeeded if expected_errno is 0).
+pid_t OpenInChild(const std::string& path, int expected_errno) {
+  pid_t child = fork();
+  if (child == 0) {
+    int fd = open(path.c_str(), O_RDONLY);
+    if (expected_errno == 0) {
+      TEST_CHECK(fd >= 0);
+    } else {
+      TEST_CHECK(fd < 0 && errno == expected_errno);
+    }
+    _exit(0);
+  }
+  return child;
+}
+
+void RespondToOpen(int fan, const std::string& path, pid_t child,
+                   uint32_t response) {
+  const struct fanotify_event_metadata event =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadEvent(fan));
+  EXPECT_EQ(event.mask, FAN_OPEN_PERM);
+  EXPECT_EQ(event.pid, child);
+  const FileDescriptor event_fd(event.fd);
+  ExpectSameFile(event_fd.get(), path);
+
+  struct fanotify_response resp = {};
+  resp.fd = event.fd;
+  resp.response = response;
+  ASSERT_THAT(write(fan, &resp, sizeof(resp)),
+              SyscallSucceedsWithValue(sizeof(resp)));
+}
+
+TEST(FanotifyTest, OpenPermDeny) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan =
+      ASSERT_NO_ERRNO_AND_VALUE(FanotifyInit(FAN_CLASS_CONTENT, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_NO_ERRNO(
+      FanotifyMark(fan.get(), FAN_MARK_ADD, FAN_OPEN_PERM, file.path()));
+
+  pid_t child = OpenInChild(file.path(), EPERM);
+  ASSERT_THAT(child, SyscallSucceeds());
+  RespondToOpen(fan.get(), file.path(), child, FAN_DENY);
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0) << status;
+}
+
+TEST(FanotifyTest, OpenPermAllow) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fan =
+      ASSERT_NO_ERRNO_AND_VALUE(FanotifyInit(FAN_CLASS_CONTENT, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_NO_ERRNO(
+      FanotifyMark(fan.get(), FAN_MARK_ADD, FAN_OPEN_PERM, file.path()));
+
+  pid_t child = OpenInChild(file.path(), 0);
+  ASSERT_THAT(child, SyscallSucceeds());
+  RespondToOpen(fan.get(), file.path(), child, FAN_ALLOW);
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0) << status;
+}
+
+TEST(FanotifyTest, PendingPermissionEventsAllowedOnClose) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  FileDescriptor fan =
+      ASSERT_NO_ERRNO_AND_VALUE(FanotifyInit(FAN_CLASS_CONTENT, O_RDONLY));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_NO_ERRNO(
+      FanotifyMark(fan.get(), FAN_MARK_ADD, FAN_OPEN_PERM, file.path()));
+
+  pid_t child = fork();
+  if (child == 0) {
+    // Drop the inherited reference on the group, so that the parent closing
+    // its fd releases it.
+    fan.reset();
+    TEST_CHECK(open(file.path().c_str(), O_RDONLY) >= 0);
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  // Wait until the child's open is queued, then close the group without
+  // answering.
+  const struct fanotify_event_metadata event =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadEvent(fan.get()));
+  ASSERT_THAT(close(event.fd), SyscallSucceeds());
+  fan.reset();
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0) << status;
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -13,10 +13,14 @@
 // limitations under the License.
 
 #include <fcntl.h>
+#include <netinet/in.h>
 #include <signal.h>
 #include <sys/epoll.h>
 #include <sys/mman.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
 #include <sys/types.h>
+#include <sys/wait.h>
 #include <syscall.h>
 #include <unistd.h>
 
@@ -33,6 +37,7 @@
 #include "absl/flags/flag.h"
 #include "absl/memory/memory.h"
 #include "absl/strings/str_cat.h"
+#include "absl/strings/string_view.h"
 #include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/util/capability_util.h"
@@ -297,6 +302,18 @@ TEST(FcntlTest, ClearCloExec) {
   ASSERT_THAT(fcntl(fd.get(), F_GETFD), SyscallSucceedsWithValue(0));
 }
 
+TEST(FcntlTest, SetFdIgnoresUnknownBits) {
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(NewEventFD(0, 0));
+
+  // Bits other than FD_CLOEXEC are silently ignored, and never reported by
+  // F_GETFD.
+  ASSERT_THAT(fcntl(fd.get(), F_SETFD, ~FD_CLOEXEC), SyscallSucceeds());
+  EXPECT_THAT(fcntl(fd.get(), F_GETFD), SyscallSucceedsWithValue(0));
+
+  ASSERT_THAT(fcntl(fd.get(), F_SETFD, -1), SyscallSucceeds());
+  EXPECT_THAT(fcntl(fd.get(), F_GETFD), SyscallSucceedsWithValue(FD_CLOEXEC));
+}
+
 TEST(FcntlTest, IndependentDescriptorFlags) {
   // Open an eventfd file descriptor with FD_CLOEXEC descriptor flag not set.
   FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(NewEventFD(0, 0));
@@ -869,6 +886,68 @@ TEST_F(FcntlLockTest, SetLockAcrossRename) {
       << "Exited with code: " << status;
 }
 
+// Open files, their flags and offsets, and the locks held on them must
+// survive save/restore.
+TEST_F(FcntlLockTest, FileStateSurvivesSave) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileDescriptor locked_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR | O_CLOEXEC, 0666));
+  FileDescriptor append_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_WRONLY | O_APPEND, 0666));
+
+  struct flock fl;
+  fl.l_type = F_WRLCK;
+  fl.l_whence = SEEK_SET;
+  fl.l_start = 0;
+  fl.l_len = 0;
+  ASSERT_THAT(fcntl(locked_fd.get(), F_SETLK, &fl), SyscallSucceeds());
+
+  constexpr char kData[] = "0123456789";
+  ASSERT_THAT(WriteFd(locked_fd.get(), kData, sizeof(kData) - 1),
+              SyscallSucceedsWithValue(sizeof(kData) - 1));
+  ASSERT_THAT(lseek(locked_fd.get(), 4, SEEK_SET), SyscallSucceedsWithValue(4));
+
+  // Use the raw syscall because the glibc wrapper may convert F_{GET,SET}OWN
+  // into F_{GET,SET}OWN_EX.
+  ASSERT_THAT(syscall(__NR_fcntl, locked_fd.get(), F_SETOWN, getpid()),
+              SyscallSucceeds());
+
+  MaybeSave();
+
+  EXPECT_THAT(lseek(locked_fd.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(4));
+  char buf[3] = {};
+  ASSERT_THAT(ReadFd(locked_fd.get(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(absl::string_view(buf, sizeof(buf)), "456");
+
+  EXPECT_THAT(fcntl(locked_fd.get(), F_GETFD),
+              SyscallSucceedsWithValue(FD_CLOEXEC));
+  EXPECT_THAT(fcntl(append_fd.get(), F_GETFD), SyscallSucceedsWithValue(0));
+  int flags;
+  ASSERT_THAT(flags = fcntl(append_fd.get(), F_GETFL), SyscallSucceeds());
+  EXPECT_EQ(flags & (O_ACCMODE | O_APPEND), O_WRONLY | O_APPEND);
+  EXPECT_THAT(syscall(__NR_fcntl, locked_fd.get(), F_GETOWN),
+              SyscallSucceedsWithValue(getpid()));
+
+  // Writes through the append fd must still go to the end of the file.
+  ASSERT_THAT(WriteFd(append_fd.get(), "ab", 2), SyscallSucceedsWithValue(2));
+  struct stat st;
+  ASSERT_THAT(fstat(locked_fd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, static_cast<off_t>(sizeof(kData) - 1 + 2));
+
+  // Another process should still fail to take a conflicting lock.
+  pid_t child_pid = 0;
+  auto cleanup = ASSERT_NO_ERRNO_AND_VALUE(
+      SubprocessLock(file.path(), false /* write lock */,
+                     false /* nonblocking */, false /* no eintr retry */,
+                     -1 /* no socket fd */, fl.l_start, fl.l_len, &child_pid));
+
+  int status = 0;
+  ASSERT_THAT(RetryEINTR(waitpid)(child_pid, &status, 0), SyscallSucceeds());
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == EAGAIN)
+      << "Exited with code: " << status;
+}
+
 // NOTE: The blocking tests below aren't perfect. It's hard to assert exactly
 // what the kernel did while handling a syscall. These tests are timing based
 // because there really isn't any other reasonable way to assert that correct
@@ -1189,6 +1268,38 @@ TEST(FcntlTest, SetOwnPid) {
               SyscallSucceedsWithValue(pid));
 }
 
+TEST(FcntlTest, GetOwnAfterOwnerExits) {
+  FileDescriptor s = ASSERT_NO_ERRNO_AND_VALUE(
+      Socket(AF_UNIX, SOCK_SEQPACKET | SOCK_NONBLOCK | SOCK_CLOEXEC, 0));
+
+  pid_t child = fork();
+  if (child == 0) {
+    pause();
+    _exit(1);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  ASSERT_THAT(syscall(__NR_fcntl, s.get(), F_SETOWN, child),
+              SyscallSucceedsWithValue(0));
+
+  // Kill and reap the owner. The owner's PID is still reported until it is
+  // changed.
+  ASSERT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFSIGNALED(status) && WTERMSIG(status) == SIGKILL)
+      << "status = " << status;
+
+  EXPECT_THAT(syscall(__NR_fcntl, s.get(), F_GETOWN),
+              SyscallSucceedsWithValue(child));
+
+  f_owner_ex got_owner = {};
+  ASSERT_THAT(syscall(__NR_fcntl, s.get(), F_GETOWN_EX, &got_owner),
+              SyscallSucceedsWithValue(0));
+  EXPECT_EQ(got_owner.type, F_OWNER_PID);
+  EXPECT_EQ(got_owner.pid, child);
+}
+
 TEST(FcntlTest, SetOwnPgrp) {
   FileDescriptor s = ASSERT_NO_ERRNO_AND_VALUE(
       Socket(AF_UNIX, SOCK_SEQPACKET | SOCK_NONBLOCK | SOCK_CLOEXEC, 0));
@@ -1545,6 +1656,90 @@ TEST_F(FcntlSignalTest, SetSigWithSigioStillGetsSiginfo) {
   EXPECT_EQ(sig.info.si_band, EPOLLIN | EPOLLRDNORM);
 }
 
+TEST_F(FcntlSignalTest, SetSigDefaultIsSentByKernel) {
+  const auto signal_cleanup =
+      ASSERT_NO_ERRNO_AND_VALUE(RegisterSignalHandler(SIGIO));
+  RegisterFD(pipe_read_fd_, 0);
+  GenerateIOEvent();
+  WaitForSignalDelivery(absl::Seconds(1));
+  ASSERT_EQ(num_signals_received_, 1);
+  SignalDelivery sig = signals_received_.front();
+  EXPECT_EQ(sig.info.si_signo, SIGIO);
+  EXPECT_EQ(sig.info.si_code, SI_KERNEL);
+}
+
+TEST_F(FcntlSignalTest, SetSigCustomReportsPollIn) {
+  const auto signal_cleanup =
+      ASSERT_NO_ERRNO_AND_VALUE(RegisterSignalHandler(SIGUSR1));
+  RegisterFD(pipe_read_fd_, SIGUSR1);
+  GenerateIOEvent();
+  WaitForSignalDelivery(absl::Seconds(1));
+  ASSERT_EQ(num_signals_received_, 1);
+  SignalDelivery sig = signals_received_.front();
+  EXPECT_EQ(sig.info.si_signo, SIGUSR1);
+  EXPECT_EQ(sig.info.si_code, POLL_IN);
+  EXPECT_EQ(sig.info.si_fd, pipe_read_fd_);
+  EXPECT_EQ(sig.info.si_band, EPOLLIN | EPOLLRDNORM);
+}
+
+TEST_F(FcntlSignalTest, SetSigWriteEndReportsPollOut) {
+  const auto signal_cleanup =
+      ASSERT_NO_ERRNO_AND_VALUE(RegisterSignalHandler(SIGUSR1));
+  RegisterFD(pipe_write_fd_, SIGUSR1);
+
+  // Fill the pipe. This makes the read end readable, which must not be
+  // reported on the write end.
+  std::vector<char> buf(kPageSize);
+  int ret;
+  do {
+    ret = write(pipe_write_fd_, buf.data(), buf.size());
+  } while (ret > 0);
+  ASSERT_THAT(ret, SyscallFailsWithErrno(EAGAIN));
+  EXPECT_EQ(num_signals_received_, 0);
+
+  // Draining a page makes the write end writable again.
+  ASSERT_THAT(read(pipe_read_fd_, buf.data(), buf.size()),
+              SyscallSucceedsWithValue(buf.size()));
+  WaitForSignalDelivery(absl::Seconds(1));
+  ASSERT_GE(num_signals_received_, 1);
+  SignalDelivery sig = signals_received_.front();
+  EXPECT_EQ(sig.info.si_signo, SIGUSR1);
+  EXPECT_EQ(sig.info.si_code, POLL_OUT);
+  EXPECT_EQ(sig.info.si_fd, pipe_write_fd_);
+  EXPECT_EQ(sig.info.si_band, EPOLLOUT | EPOLLWRNORM | EPOLLWRBAND);
+}
+
+TEST_F(FcntlSignalTest, SetSigUDPSocket) {
+  const auto signal_cleanup =
+      ASSERT_NO_ERRNO_AND_VALUE(RegisterSignalHandler(SIGUSR1));
+  FileDescriptor rfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  FileDescriptor wfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  struct sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(bind(rfd.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                   addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(getsockname(rfd.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                          &addrlen),
+              SyscallSucceeds());
+  RegisterFD(rfd.get(), SIGUSR1);
+
+  ASSERT_THAT(sendto(wfd.get(), "test", 4, 0,
+                     reinterpret_cast<struct sockaddr*>(&addr), addrlen),
+              SyscallSucceedsWithValue(4));
+  WaitForSignalDelivery(absl::Seconds(1));
+  ASSERT_GE(num_signals_received_, 1);
+  SignalDelivery sig = signals_received_.front();
+  EXPECT_EQ(sig.info.si_signo, SIGUSR1);
+  EXPECT_EQ(sig.info.si_code, POLL_IN);
+  EXPECT_EQ(sig.info.si_fd, rfd.get());
+  EXPECT_EQ(sig.info.si_band, EPOLLIN | EPOLLRDNORM);
+}
+
 TEST_F(FcntlSignalTest, SetSigDupThenCloseOld) {
   const auto sigusr1_cleanup =
       ASSERT_NO_ERRNO_AND_VALUE(RegisterSignalHandler(SIGUSR1));
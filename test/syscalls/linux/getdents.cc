@@ -15,20 +15,24 @@
 #include <dirent.h>
 #include <errno.h>
 #include <fcntl.h>
+#include <linux/magic.h>
 #include <stddef.h>
 #include <stdint.h>
 #include <stdio.h>
 #include <string.h>
 #include <sys/mman.h>
+#include <sys/statfs.h>
 #include <sys/types.h>
 #include <syscall.h>
 #include <unistd.h>
 
+#include <atomic>
 #include <map>
 #include <string>
 #include <unordered_map>
 #include <unordered_set>
 #include <utility>
+#include <vector>
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
@@ -42,6 +46,7 @@
 #include "test/util/posix_error.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
 
 using ::testing::Contains;
 using ::testing::IsEmpty;
@@ -188,6 +193,21 @@ class GetdentsTest : public ::testing::Test {
     return NoError();
   }
 
+  // Seek to offset, which must have been returned by getdents.
+  PosixError SeekTo(off_t offset) {
+    off_t got = lseek(fd_.get(), offset, SEEK_SET);
+    if (got < 0) {
+      return PosixError(errno, absl::StrCat("error seeking to ", offset));
+    }
+    if (got != offset) {
+      return PosixError(EINVAL, absl::StrCat("tried to seek to ", offset,
+                                             " but got ", got));
+    }
+    return NoError();
+  }
+
+  std::string DirPath() const { return dir_.path(); }
+
   // Call getdents multiple times, reading all dirents and calling f on each.
   // f has the type signature PosixError f(T*).
   // If f returns a non-OK error, so does ReadDirents.
@@ -490,6 +510,101 @@ TYPED_TEST(GetdentsTest, Issue128ProcSeekEnd) {
 }
 
 // Some tests using the glibc readdir interface.
+// Tests that entries present for the whole iteration are returned exactly
+// once, even while other entries are concurrently created and removed.
+TYPED_TEST(GetdentsTest, ConcurrentModification) {
+  constexpr int kStableFiles = 20;
+  std::vector<std::string> stable;
+  for (int i = 0; i < kStableFiles; i++) {
+    stable.push_back(absl::StrCat("stable", i));
+  }
+  this->FillDirectoryWithFiles(stable);
+
+  std::atomic<bool> done(false);
+  const std::string dir = this->DirPath();
+  ScopedThread churn([&] {
+    for (int i = 0; !done.load(); i++) {
+      const std::string name = JoinPath(dir, absl::StrCat("churn", i % 10));
+      int fd = open(name.c_str(), O_CREAT | O_WRONLY, 0644);
+      if (fd >= 0) {
+        close(fd);
+      }
+      unlink(JoinPath(dir, absl::StrCat("churn", (i + 5) % 10)).c_str());
+    }
+  });
+
+  // A small buffer forces many getdents calls per iteration.
+  typename TestFixture::DirentBufferType dirents(256);
+  for (int iter = 0; iter < 50; iter++) {
+    ASSERT_NO_ERRNO(this->SeekStart());
+    std::map<std::string, int> seen;
+    ASSERT_NO_ERRNO(this->ReadDirents(
+        &dirents, [&](typename TestFixture::LinuxDirentType* d) {
+          seen[d->d_name]++;
+          return NoError();
+        }));
+    for (const auto& name : stable) {
+      EXPECT_EQ(seen[name], 1) << "iteration " << iter << ": " << name;
+    }
+  }
+
+  done.store(true);
+  churn.Join();
+  for (int i = 0; i < 10; i++) {
+    unlink(JoinPath(dir, absl::StrCat("churn", i)).c_str());
+  }
+}
+
+// Tests that seeking to an offset returned by getdents resumes iteration after
+// the corresponding entry, even if entries have since been added or removed.
+TYPED_TEST(GetdentsTest, SeekToReturnedOffsetAfterModification) {
+  // Linux only guarantees this for filesystems with stable directory offsets,
+  // which excludes tmpfs before Linux 6.6.
+  SKIP_IF(!IsRunningOnGvisor());
+  struct statfs st;
+  ASSERT_THAT(statfs(this->DirPath().c_str(), &st), SyscallSucceeds());
+  SKIP_IF(st.f_type != TMPFS_MAGIC);
+
+  this->FillDirectory(10);
+
+  // Record every entry and the offset following it.
+  std::vector<std::pair<std::string, off_t>> entries;
+  typename TestFixture::DirentBufferType dirents(1024);
+  ASSERT_NO_ERRNO(this->ReadDirents(
+      &dirents, [&](typename TestFixture::LinuxDirentType* d) {
+        entries.emplace_back(d->d_name, d->d_off);
+        return NoError();
+      }));
+  ASSERT_GE(entries.size(), 8);
+
+  // Remove an entry before the seek point, and one after it, and add one.
+  const size_t seek_idx = entries.size() / 2;
+  const std::string removed_before = entries[seek_idx - 1].first;
+  const std::string removed_after = entries[seek_idx + 2].first;
+  ASSERT_THAT(unlink(JoinPath(this->DirPath(), removed_before).c_str()),
+              SyscallSucceeds());
+  ASSERT_THAT(unlink(JoinPath(this->DirPath(), removed_after).c_str()),
+              SyscallSucceeds());
+  ASSERT_NO_ERRNO(CreateWithContents(JoinPath(this->DirPath(), "added"), ""));
+
+  ASSERT_NO_ERRNO(this->SeekTo(entries[seek_idx].second));
+  std::vector<std::string> got;
+  ASSERT_NO_ERRNO(this->ReadDirents(
+      &dirents, [&](typename TestFixture::LinuxDirentType* d) {
+        got.push_back(d->d_name);
+        return NoError();
+      }));
+
+  std::vector<std::string> want;
+  for (size_t i = seek_idx + 1; i < entries.size(); i++) {
+    if (entries[i].first != removed_after) {
+      want.push_back(entries[i].first);
+    }
+  }
+  want.push_back("added");
+  EXPECT_EQ(got, want);
+}
+
 TEST(ReaddirTest, OpenDir) {
   DIR* dev;
   ASSERT_THAT(dev = opendir("/dev"), NotNull());
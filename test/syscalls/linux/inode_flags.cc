@@ -0,0 +1,199 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <stdio.h>
+#include <sys/ioctl.h>
+#include <sys/mount.h>
+#include <sys/stat.h>
+#include <unistd.h>
+
+#include <string>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef FS_IOC_GETFLAGS
+#define FS_IOC_GETFLAGS 2148034049
+#endif  // FS_IOC_GETFLAGS
+
+#ifndef FS_IOC_SETFLAGS
+#define FS_IOC_SETFLAGS 1074292226
+#endif  // FS_IOC_SETFLAGS
+
+#ifndef FS_COMPR_FL
+#define FS_COMPR_FL 0x00000004
+#endif  // FS_COMPR_FL
+
+#ifndef FS_IMMUTABLE_FL
+#define FS_IMMUTABLE_FL 0x00000010
+#endif  // FS_IMMUTABLE_FL
+
+#ifndef FS_APPEND_FL
+#define FS_APPEND_FL 0x00000020
+#endif  // FS_APPEND_FL
+
+// InodeFlagsTest runs tests on a tmpfs mount, since TEST_TMPDIR may not
+// support inode flags.
+class InodeFlagsTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_LINUX_IMMUTABLE)));
+
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    mount_ = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("", dir_.path(), "tmpfs", 0, "", MNT_DETACH));
+    path_ = JoinPath(dir_.path(), "file");
+    fd_ = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR | O_CREAT, 0644));
+    ASSERT_THAT(WriteFd(fd_.get(), "a", 1), SyscallSucceedsWithValue(1));
+
+    // Linux's tmpfs only supports inode flags since 6.0.
+    int flags = 0;
+    SKIP_IF(!IsRunningOnGvisor() &&
+            ioctl(fd_.get(), FS_IOC_GETFLAGS, &flags) < 0 && errno == ENOTTY);
+  }
+
+  void SetFlags(int flags) {
+    ASSERT_THAT(ioctl(fd_.get(), FS_IOC_SETFLAGS, &flags), SyscallSucceeds());
+    int got = 0;
+    ASSERT_THAT(ioctl(fd_.get(), FS_IOC_GETFLAGS, &got), SyscallSucceeds());
+    EXPECT_EQ(got & (FS_IMMUTABLE_FL | FS_APPEND_FL), flags);
+  }
+
+  TempPath dir_;
+  Cleanup mount_;
+  std::string path_;
+  FileDescriptor fd_;
+};
+
+TEST_F(InodeFlagsTest, Immutable) {
+  ASSERT_NO_FATAL_FAILURE(SetFlags(FS_IMMUTABLE_FL));
+
+  EXPECT_THAT(open(path_.c_str(), O_WRONLY), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(open(path_.c_str(), O_RDONLY | O_TRUNC),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(truncate(path_.c_str(), 0), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(ftruncate(fd_.get(), 0), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(chmod(path_.c_str(), 0600), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(unlink(path_.c_str()), SyscallFailsWithErrno(EPERM));
+  const std::string other = JoinPath(dir_.path(), "other");
+  EXPECT_THAT(rename(path_.c_str(), other.c_str()),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(link(path_.c_str(), other.c_str()),
+              SyscallFailsWithErrno(EPERM));
+
+  // Reading is still allowed.
+  char buf;
+  EXPECT_THAT(pread(fd_.get(), &buf, 1, 0), SyscallSucceedsWithValue(1));
+}
+
+TEST_F(InodeFlagsTest, ImmutableWriteThroughExistingFD) {
+  // Linux only checks immutability when the file is opened.
+  SKIP_IF(!IsRunningOnGvisor());
+  ASSERT_NO_FATAL_FAILURE(SetFlags(FS_IMMUTABLE_FL));
+  EXPECT_THAT(pwrite(fd_.get(), "b", 1, 0), SyscallFailsWithErrno(EPERM));
+}
+
+TEST_F(InodeFlagsTest, AppendOnly) {
+  ASSERT_NO_FATAL_FAILURE(SetFlags(FS_APPEND_FL));
+
+  // Only appending writes are permitted.
+  EXPECT_THAT(open(path_.c_str(), O_WRONLY), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(open(path_.c_str(), O_WRONLY | O_APPEND | O_TRUNC),
+              SyscallFailsWithErrno(EPERM));
+  const FileDescriptor afd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_WRONLY | O_APPEND));
+  EXPECT_THAT(WriteFd(afd.get(), "b", 1), SyscallSucceedsWithValue(1));
+  struct stat st;
+  ASSERT_THAT(fstat(afd.get(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_size, 2);
+
+  // O_APPEND can't be cleared.
+  EXPECT_THAT(fcntl(afd.get(), F_SETFL, 0), SyscallFailsWithErrno(EPERM));
+
+  EXPECT_THAT(truncate(path_.c_str(), 0), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(ftruncate(afd.get(), 0), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(unlink(path_.c_str()), SyscallFailsWithErrno(EPERM));
+  const std::string other = JoinPath(dir_.path(), "other");
+  EXPECT_THAT(rename(path_.c_str(), other.c_str()),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST_F(InodeFlagsTest, AppendOnlyWriteThroughExistingFD) {
+  // Linux only checks append-only when the file is opened.
+  SKIP_IF(!IsRunningOnGvisor());
+  ASSERT_NO_FATAL_FAILURE(SetFlags(FS_APPEND_FL));
+  EXPECT_THAT(pwrite(fd_.get(), "b", 1, 0), SyscallFailsWithErrno(EPERM));
+}
+
+TEST_F(InodeFlagsTest, AppendOnlyDirectory) {
+  const std::string sub = JoinPath(dir_.path(), "sub");
+  ASSERT_THAT(mkdir(sub.c_str(), 0755), SyscallSucceeds());
+  const std::string child = JoinPath(sub, "child");
+  ASSERT_NO_ERRNO(Open(child, O_RDONLY | O_CREAT, 0644));
+
+  const FileDescriptor dfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(sub, O_RDONLY | O_DIRECTORY));
+  int flags = FS_APPEND_FL;
+  ASSERT_THAT(ioctl(dfd.get(), FS_IOC_SETFLAGS, &flags), SyscallSucceeds());
+
+  // New entries may be created, but existing ones can't be removed.
+  const std::string child2 = JoinPath(sub, "child2");
+  EXPECT_NO_ERRNO(Open(child2, O_RDONLY | O_CREAT, 0644));
+  EXPECT_THAT(unlink(child.c_str()), SyscallFailsWithErrno(EPERM));
+
+  flags = 0;
+  ASSERT_THAT(ioctl(dfd.get(), FS_IOC_SETFLAGS, &flags), SyscallSucceeds());
+  EXPECT_THAT(unlink(child.c_str()), SyscallSucceeds());
+  EXPECT_THAT(unlink(child2.c_str()), SyscallSucceeds());
+}
+
+TEST_F(InodeFlagsTest, UnsupportedFlags) {
+  int flags = FS_COMPR_FL;
+  EXPECT_THAT(ioctl(fd_.get(), FS_IOC_SETFLAGS, &flags),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST_F(InodeFlagsTest, SetFlagsRequiresCapLinuxImmutable) {
+  AutoCapability cap(CAP_LINUX_IMMUTABLE, false);
+  int flags = FS_IMMUTABLE_FL;
+  EXPECT_THAT(ioctl(fd_.get(), FS_IOC_SETFLAGS, &flags),
+              SyscallFailsWithErrno(EPERM));
+  flags = FS_APPEND_FL;
+  EXPECT_THAT(ioctl(fd_.get(), FS_IOC_SETFLAGS, &flags),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST_F(InodeFlagsTest, GetFlagsBadAddress) {
+  EXPECT_THAT(ioctl(fd_.get(), FS_IOC_GETFLAGS, nullptr),
+              SyscallFailsWithErrno(EFAULT));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
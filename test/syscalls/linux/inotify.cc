@@ -782,6 +782,65 @@ TEST(Inotify, MoveWatchedTargetGeneratesEvents) {
   EXPECT_EQ(events[0].cookie, events[1].cookie);
 }
 
+TEST(Inotify, MoveWatchedDirectoryGeneratesMoveSelf) {
+  const TempPath root = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(InotifyInit1(IN_NONBLOCK));
+
+  TempPath dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(root.path()));
+
+  const int dir1_wd = ASSERT_NO_ERRNO_AND_VALUE(
+      InotifyAddWatch(fd.get(), dir1.path(), IN_ALL_EVENTS));
+
+  const std::string newpath = NewTempAbsPathInDir(root.path());
+  const std::string oldpath = dir1.release();
+  EXPECT_THAT(rename(oldpath.c_str(), newpath.c_str()), SyscallSucceeds());
+  dir1.reset(newpath);
+  const std::vector<Event> events =
+      ASSERT_NO_ERRNO_AND_VALUE(DrainEvents(fd.get()));
+  // Some versions of Linux also set IN_ISDIR on self events for directories.
+  ASSERT_EQ(events.size(), 1u);
+  EXPECT_EQ(events[0].wd, dir1_wd);
+  EXPECT_EQ(events[0].mask & ~IN_ISDIR, IN_MOVE_SELF);
+  EXPECT_EQ(events[0].cookie, 0);
+
+  // The watch follows the directory to its new name.
+  const TempPath child =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir1.path()));
+  ASSERT_THAT(
+      ASSERT_NO_ERRNO_AND_VALUE(DrainEvents(fd.get())),
+      Are({Event(IN_CREATE | IN_ISDIR, dir1_wd, Basename(child.path()))}));
+}
+
+TEST(Inotify, RenameOverWatchedTargetGeneratesDeleteSelf) {
+  // VFS1 doesn't notify the replaced file.
+  SKIP_IF(IsRunningWithVFS1());
+
+  const TempPath root = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(InotifyInit1(IN_NONBLOCK));
+
+  TempPath file1 =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(root.path()));
+  TempPath file2 =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(root.path()));
+
+  const int file1_wd = ASSERT_NO_ERRNO_AND_VALUE(
+      InotifyAddWatch(fd.get(), file1.path(), IN_ALL_EVENTS));
+  const int file2_wd = ASSERT_NO_ERRNO_AND_VALUE(
+      InotifyAddWatch(fd.get(), file2.path(), IN_ALL_EVENTS));
+
+  const std::string oldpath = file1.release();
+  EXPECT_THAT(rename(oldpath.c_str(), file2.path().c_str()), SyscallSucceeds());
+  file1.reset(file2.release());
+  const std::vector<Event> events =
+      ASSERT_NO_ERRNO_AND_VALUE(DrainEvents(fd.get()));
+  ASSERT_THAT(events, Are({Event(IN_ATTRIB, file2_wd),
+                           Event(IN_MOVE_SELF, file1_wd),
+                           Event(IN_DELETE_SELF, file2_wd),
+                           Event(IN_IGNORED, file2_wd)}));
+}
+
 // Tests that close events are only emitted when a file description drops its
 // last reference.
 TEST(Inotify, DupFD) {
@@ -1240,6 +1299,42 @@ TEST(Inotify, TruncateGeneratesModifyEvent) {
   ASSERT_THAT(events, Are({}));
 }
 
+TEST(Inotify, OpenWithTruncGeneratesModifyThenOpenEvent) {
+  const TempPath root = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath file1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      root.path(), "some content", TempPath::kDefaultFileMode));
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(InotifyInit1(IN_NONBLOCK));
+  const int root_wd = ASSERT_NO_ERRNO_AND_VALUE(
+      InotifyAddWatch(fd.get(), root.path(), IN_ALL_EVENTS));
+  const int file1_wd = ASSERT_NO_ERRNO_AND_VALUE(
+      InotifyAddWatch(fd.get(), file1.path(), IN_ALL_EVENTS));
+  const std::string name = std::string(Basename(file1.path()));
+
+  auto verify_trunc_events = [&]() {
+    const std::vector<Event> events =
+        ASSERT_NO_ERRNO_AND_VALUE(DrainEvents(fd.get()));
+    ASSERT_THAT(events, Are({Event(IN_MODIFY, root_wd, name),
+                             Event(IN_MODIFY, file1_wd),
+                             Event(IN_OPEN, root_wd, name),
+                             Event(IN_OPEN, file1_wd)}));
+  };
+
+  {
+    const FileDescriptor file1_fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(file1.path(), O_WRONLY | O_TRUNC));
+    verify_trunc_events();
+    ASSERT_THAT(WriteFd(file1_fd.get(), "x", 1), SyscallSucceedsWithValue(1));
+  }
+  ASSERT_NO_ERRNO(DrainEvents(fd.get()));
+
+  // O_CREAT on an existing file behaves the same way.
+  const FileDescriptor file1_fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(file1.path(), O_WRONLY | O_CREAT | O_TRUNC, 0644));
+  verify_trunc_events();
+}
+
 TEST(Inotify, GetdentsGeneratesAccessEvent) {
   const TempPath root = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
   const TempPath file1 =
@@ -21,6 +21,7 @@
 #include <sys/ioctl.h>
 #include <sys/socket.h>
 #include <sys/types.h>
+#include <termios.h>
 #include <unistd.h>
 
 #include "gmock/gmock.h"
@@ -28,8 +29,10 @@
 #include "test/syscalls/linux/ip_socket_test_util.h"
 #include "test/syscalls/linux/unix_domain_socket_test_util.h"
 #include "test/util/file_descriptor.h"
+#include "test/util/pty_util.h"
 #include "test/util/signal_util.h"
 #include "test/util/socket_util.h"
+#include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -76,6 +79,50 @@ TEST_F(IoctlTest, InvalidControlNumber) {
   EXPECT_THAT(ioctl(STDOUT_FILENO, 0), SyscallFailsWithErrno(ENOTTY));
 }
 
+// Terminal ioctls on files that aren't terminals fail with ENOTTY, which is
+// how isatty(3) detects them.
+TEST_F(IoctlTest, TCGETSOnNonTerminalFails) {
+  struct termios t;
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor regular =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(ioctl(regular.get(), TCGETS, &t), SyscallFailsWithErrno(ENOTTY));
+  EXPECT_THAT(ioctl(regular.get(), TCSETS, &t), SyscallFailsWithErrno(ENOTTY));
+  struct winsize ws;
+  EXPECT_THAT(ioctl(regular.get(), TIOCGWINSZ, &ws),
+              SyscallFailsWithErrno(ENOTTY));
+  EXPECT_FALSE(isatty(regular.get()));
+  EXPECT_EQ(errno, ENOTTY);
+
+  const FileDescriptor dir =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(GetAbsoluteTestTmpdir(), O_RDONLY));
+  EXPECT_THAT(ioctl(dir.get(), TCGETS, &t), SyscallFailsWithErrno(ENOTTY));
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  const FileDescriptor rfd(pipe_fds[0]);
+  const FileDescriptor wfd(pipe_fds[1]);
+  EXPECT_THAT(ioctl(rfd.get(), TCGETS, &t), SyscallFailsWithErrno(ENOTTY));
+
+  const FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  EXPECT_THAT(ioctl(sock.get(), TCGETS, &t), SyscallFailsWithErrno(ENOTTY));
+}
+
+TEST_F(IoctlTest, TCGETSOnPtySucceeds) {
+  const FileDescriptor master =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/ptmx", O_RDWR | O_NONBLOCK));
+  const FileDescriptor replica = ASSERT_NO_ERRNO_AND_VALUE(OpenReplica(master));
+
+  struct termios t;
+  EXPECT_THAT(ioctl(replica.get(), TCGETS, &t), SyscallSucceeds());
+  EXPECT_THAT(ioctl(replica.get(), TCSETS, &t), SyscallSucceeds());
+  EXPECT_THAT(ioctl(master.get(), TCGETS, &t), SyscallSucceeds());
+  EXPECT_TRUE(isatty(replica.get()));
+  EXPECT_TRUE(isatty(master.get()));
+}
+
 TEST_F(IoctlTest, IoctlWithOpath) {
   SKIP_IF(IsRunningWithVFS1());
   const FileDescriptor fd =
@@ -103,6 +150,25 @@ TEST_F(IoctlTest, FIONBIOFails) {
   EXPECT_THAT(ioctl(fd(), FIONBIO, nullptr), SyscallFailsWithErrno(EFAULT));
 }
 
+// EBADF takes precedence over a bad argument pointer.
+TEST_F(IoctlTest, FIONBIOBadFileDescriptor) {
+  EXPECT_THAT(ioctl(-1 /* fd */, FIONBIO, nullptr),
+              SyscallFailsWithErrno(EBADF));
+}
+
+// Unknown requests fail with ENOTTY before the argument is dereferenced.
+TEST_F(IoctlTest, InvalidControlNumberBadArgument) {
+  EXPECT_THAT(ioctl(fd(), 0, nullptr), SyscallFailsWithErrno(ENOTTY));
+  EXPECT_THAT(ioctl(fd(), 0, reinterpret_cast<void*>(-1)),
+              SyscallFailsWithErrno(ENOTTY));
+
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const FileDescriptor regular =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  EXPECT_THAT(ioctl(regular.get(), TCGETS, nullptr),
+              SyscallFailsWithErrno(ENOTTY));
+}
+
 TEST_F(IoctlTest, FIONCLEXSucceeds) {
   EXPECT_THAT(ioctl(fd(), FIONCLEX), SyscallSucceeds());
   EXPECT_FALSE(CheckCloExec(fd()));
@@ -406,6 +472,14 @@ TEST_F(IoctlTest, FIOGETOWNSucceeds) {
   EXPECT_EQ(get, 0);
 }
 
+TEST_F(IoctlTest, FIOGETOWNFails) {
+  EXPECT_THAT(ioctl(fd(), FIOGETOWN, nullptr), SyscallFailsWithErrno(EFAULT));
+}
+
+TEST_F(IoctlTest, FIOSETOWNFails) {
+  EXPECT_THAT(ioctl(fd(), FIOSETOWN, nullptr), SyscallFailsWithErrno(EFAULT));
+}
+
 TEST_F(IoctlTest, SIOCGPGRPSucceeds) {
   const FileDescriptor s = ASSERT_NO_ERRNO_AND_VALUE(
       Socket(AF_UNIX, SOCK_SEQPACKET | SOCK_NONBLOCK | SOCK_CLOEXEC, 0));
@@ -76,6 +76,47 @@ TEST(LinkTest, CanCreateLinkFile) {
               IsPosixErrorOkAndHolds(initial_link_count));
 }
 
+TEST(LinkTest, LinksShareLinkCount) {
+  auto oldfile = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const std::string newname = NewTempAbsPath();
+  const std::string replaced = NewTempAbsPath();
+
+  EXPECT_THAT(link(oldfile.path().c_str(), newname.c_str()), SyscallSucceeds());
+
+  FileDescriptor oldfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(oldfile.path(), O_RDONLY));
+  FileDescriptor newfd = ASSERT_NO_ERRNO_AND_VALUE(Open(newname, O_RDONLY));
+
+  // Both names refer to the same inode, and report the same link count.
+  struct stat oldst, newst;
+  ASSERT_THAT(fstat(oldfd.get(), &oldst), SyscallSucceeds());
+  ASSERT_THAT(fstat(newfd.get(), &newst), SyscallSucceeds());
+  EXPECT_EQ(oldst.st_ino, newst.st_ino);
+  EXPECT_EQ(oldst.st_nlink, 2);
+  EXPECT_EQ(newst.st_nlink, 2);
+
+  // Renaming over one of the links drops the link count as seen through
+  // either file.
+  ASSERT_THAT(link(oldfile.path().c_str(), replaced.c_str()),
+              SyscallSucceeds());
+  ASSERT_THAT(fstat(newfd.get(), &newst), SyscallSucceeds());
+  EXPECT_EQ(newst.st_nlink, 3);
+  auto other = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  ASSERT_THAT(rename(other.release().c_str(), replaced.c_str()),
+              SyscallSucceeds());
+  ASSERT_THAT(fstat(oldfd.get(), &oldst), SyscallSucceeds());
+  EXPECT_EQ(oldst.st_nlink, 2);
+
+  // Unlinking one name is visible through the other.
+  ASSERT_THAT(unlink(newname.c_str()), SyscallSucceeds());
+  ASSERT_THAT(fstat(oldfd.get(), &oldst), SyscallSucceeds());
+  ASSERT_THAT(fstat(newfd.get(), &newst), SyscallSucceeds());
+  EXPECT_EQ(oldst.st_nlink, 1);
+  EXPECT_EQ(newst.st_nlink, 1);
+
+  EXPECT_THAT(unlink(replaced.c_str()), SyscallSucceeds());
+}
+
 TEST(LinkTest, PermissionDenied) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_FOWNER)));
 
@@ -125,6 +166,30 @@ TEST(LinkTest, CannotLinkDirectory) {
   EXPECT_THAT(rmdir(olddir.path().c_str()), SyscallSucceeds());
 }
 
+TEST(LinkTest, CannotLinkDirectoryWithSymlinkFollow) {
+  auto olddir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto oldsymlink = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), olddir.path()));
+  const std::string newdir = NewTempAbsPath();
+
+  EXPECT_THAT(linkat(AT_FDCWD, oldsymlink.path().c_str(), AT_FDCWD,
+                     newdir.c_str(), AT_SYMLINK_FOLLOW),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST(LinkTest, CannotLinkDirectoryWithEmptyPath) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+  auto olddir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(olddir.path(), O_RDONLY | O_DIRECTORY));
+  const std::string newdir = NewTempAbsPath();
+
+  EXPECT_THAT(
+      linkat(dirfd.get(), "", AT_FDCWD, newdir.c_str(), AT_EMPTY_PATH),
+      SyscallFailsWithErrno(EPERM));
+}
+
 TEST(LinkTest, CannotLinkWithSlash) {
   auto oldfile = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
   // Put a final "/" on newname.
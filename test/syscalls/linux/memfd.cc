@@ -27,6 +27,7 @@
 #include "test/util/fs_util.h"
 #include "test/util/memory_util.h"
 #include "test/util/multiprocess_util.h"
+#include "test/util/proc_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
@@ -80,6 +81,28 @@ TEST(MemfdTest, Name) {
   EXPECT_THAT(proc_name, StartsWith("/memfd:" + kMemfdName));
 }
 
+// Mappings of memfds are named after the memfd in /proc/[pid]/maps.
+TEST(MemfdTest, MapsName) {
+  const FileDescriptor memfd =
+      ASSERT_NO_ERRNO_AND_VALUE(MemfdCreate(kMemfdName, 0));
+  ASSERT_THAT(ftruncate(memfd.get(), kPageSize), SyscallSucceeds());
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(Mmap(
+      nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED, memfd.get(), 0));
+
+  const std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/maps"));
+  const auto entries = ASSERT_NO_ERRNO_AND_VALUE(ParseProcMaps(contents));
+  bool found = false;
+  for (const auto& entry : entries) {
+    if (entry.start == m.addr()) {
+      EXPECT_EQ(entry.end, m.endaddr());
+      EXPECT_EQ(entry.filename, "/memfd:" + kMemfdName + " (deleted)");
+      found = true;
+    }
+  }
+  EXPECT_TRUE(found) << contents;
+}
+
 // Memfds support read/write syscalls.
 TEST(MemfdTest, WriteRead) {
   const FileDescriptor memfd =
@@ -393,6 +416,32 @@ TEST(MemfdTest, SealWriteWithOutstandingWritbleMapping) {
   }
 }
 
+// F_SEAL_WRITE can be added once the outstanding writable mapping that
+// prevented it is unmapped.
+TEST(MemfdTest, SealWriteAfterUnmappingWritableMapping) {
+  const FileDescriptor memfd =
+      ASSERT_NO_ERRNO_AND_VALUE(MemfdCreate(kMemfdName, MFD_ALLOW_SEALING));
+  const std::vector<char> buf(kPageSize);
+  ASSERT_THAT(write(memfd.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(kPageSize));
+
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(Mmap(
+      nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED, memfd.get(), 0));
+  EXPECT_THAT(fcntl(memfd.get(), F_ADD_SEALS, F_SEAL_WRITE),
+              SyscallFailsWithErrno(EBUSY));
+
+  m.reset();
+  ASSERT_THAT(fcntl(memfd.get(), F_ADD_SEALS, F_SEAL_WRITE), SyscallSucceeds());
+  EXPECT_THAT(fcntl(memfd.get(), F_GET_SEALS),
+              SyscallSucceedsWithValue(F_SEAL_WRITE));
+
+  // New writable shared mappings are now rejected.
+  void* ret = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED,
+                   memfd.get(), 0);
+  EXPECT_EQ(ret, MAP_FAILED);
+  EXPECT_EQ(errno, EPERM);
+}
+
 // When applying F_SEAL_WRITE fails due to outstanding writable mappings, any
 // additional seals passed to the same add seal call are also rejected.
 TEST(MemfdTest, NoPartialSealApplicationWhenWriteSealRejected) {
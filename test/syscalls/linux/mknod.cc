@@ -25,6 +25,7 @@
 #include "gtest/gtest.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/temp_path.h"
+#include "test/util/temp_umask.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
 
@@ -62,6 +63,18 @@ TEST(MknodTest, RegularFilePermissions) {
   ASSERT_EQ(st.st_mode & S_IFMT, S_IFREG);
 }
 
+TEST(MknodTest, FIFOPermissionsHonorUmask) {
+  const std::string node = NewTempAbsPath();
+  TempUmask mask(0027);
+
+  EXPECT_THAT(mknod(node.c_str(), S_IFIFO | 0777, 0), SyscallSucceeds());
+
+  struct stat st;
+  ASSERT_THAT(stat(node.c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_mode & 07777, 0750);
+  EXPECT_TRUE(S_ISFIFO(st.st_mode));
+}
+
 TEST(MknodTest, MknodAtFIFO) {
   const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
   const std::string fifo_relpath = NewTempRelPath();
@@ -92,6 +105,38 @@ TEST(MknodTest, MknodOnExistingPathFails) {
               SyscallFailsWithErrno(EEXIST));
 }
 
+TEST(MknodTest, MknodTwiceFails) {
+  const std::string fifo = NewTempAbsPath();
+  ASSERT_THAT(mknod(fifo.c_str(), S_IFIFO | S_IRUSR | S_IWUSR, 0),
+              SyscallSucceeds());
+  EXPECT_THAT(mknod(fifo.c_str(), S_IFIFO | S_IRUSR | S_IWUSR, 0),
+              SyscallFailsWithErrno(EEXIST));
+
+  const std::string file = NewTempAbsPath();
+  ASSERT_THAT(mknod(file.c_str(), S_IFREG | S_IRUSR | S_IWUSR, 0),
+              SyscallSucceeds());
+  EXPECT_THAT(mknod(file.c_str(), S_IFREG | S_IRUSR | S_IWUSR, 0),
+              SyscallFailsWithErrno(EEXIST));
+
+  ASSERT_THAT(unlink(fifo.c_str()), SyscallSucceeds());
+  ASSERT_THAT(unlink(file.c_str()), SyscallSucceeds());
+}
+
+TEST(MknodTest, ExistingPathFailsForAllTypes) {
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+
+  // The existence check precedes any check of whether the node type can be
+  // created, including the CAP_MKNOD check for device nodes.
+  for (const std::string& path : {file.path(), dir.path()}) {
+    for (mode_t type : {S_IFREG, S_IFIFO, S_IFSOCK, S_IFCHR, S_IFBLK}) {
+      EXPECT_THAT(mknod(path.c_str(), type | S_IRUSR | S_IWUSR, 0),
+                  SyscallFailsWithErrno(EEXIST))
+          << "path: " << path << ", type: " << std::oct << type;
+    }
+  }
+}
+
 TEST(MknodTest, UnimplementedTypesReturnError) {
   // TODO(gvisor.dev/issue/1624): These file types are supported by some
   // filesystems in VFS2, so this test should be deleted along with VFS1.
@@ -33,8 +33,10 @@
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
+#include "absl/strings/ascii.h"
 #include "absl/strings/escaping.h"
 #include "absl/strings/str_split.h"
+#include "absl/strings/strip.h"
 #include "test/util/cleanup.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/fs_util.h"
@@ -46,12 +48,28 @@
 using ::testing::AnyOf;
 using ::testing::Eq;
 using ::testing::Gt;
+using ::testing::Ne;
+
+#ifndef MAP_SHARED_VALIDATE
+#define MAP_SHARED_VALIDATE 0x03
+#endif
+
+#ifndef MAP_SYNC
+#define MAP_SYNC 0x80000
+#endif
+
+#ifndef MAP_FIXED_NOREPLACE
+#define MAP_FIXED_NOREPLACE 0x100000
+#endif
 
 namespace gvisor {
 namespace testing {
 
 namespace {
 
+// kUnknownMapFlag is an mmap(2) flag that Linux does not define.
+constexpr int kUnknownMapFlag = 0x200000;
+
 PosixErrorOr<int64_t> VirtualMemorySize() {
   ASSIGN_OR_RETURN_ERRNO(auto contents, GetContents("/proc/self/statm"));
   std::vector<std::string> parts = absl::StrSplit(contents, ' ');
@@ -62,6 +80,40 @@ PosixErrorOr<int64_t> VirtualMemorySize() {
   return pages * getpagesize();
 }
 
+// VirtualDataSize returns the size of the calling process' private data
+// mappings, which are subject to RLIMIT_DATA.
+PosixErrorOr<int64_t> VirtualDataSize() {
+  ASSIGN_OR_RETURN_ERRNO(auto contents, GetContents("/proc/self/status"));
+  for (absl::string_view line : absl::StrSplit(contents, '\n')) {
+    if (absl::ConsumePrefix(&line, "VmData:")) {
+      line = absl::StripAsciiWhitespace(line);
+      if (!absl::ConsumeSuffix(&line, " kB")) {
+        break;
+      }
+      ASSIGN_OR_RETURN_ERRNO(auto kb, Atoi<int64_t>(line));
+      return kb << 10;
+    }
+  }
+  return PosixError(EINVAL, "Unable to parse VmData from /proc/self/status");
+}
+
+// SetLimit sets the soft limit of resource to cur, and returns a Cleanup that
+// restores it.
+PosixErrorOr<Cleanup> SetLimit(int resource, rlim_t cur) {
+  struct rlimit old;
+  if (getrlimit(resource, &old) < 0) {
+    return PosixError(errno, "getrlimit");
+  }
+  struct rlimit lim = old;
+  lim.rlim_cur = cur;
+  if (setrlimit(resource, &lim) < 0) {
+    return PosixError(errno, "setrlimit");
+  }
+  return Cleanup([resource, old] {
+    EXPECT_THAT(setrlimit(resource, &old), SyscallSucceeds());
+  });
+}
+
 class MMapTest : public ::testing::Test {
  protected:
   // Unmap mapping, if one was made.
@@ -394,13 +446,88 @@ TEST_F(MMapTest, NotPrivateOrShared) {
               SyscallFailsWithErrno(EINVAL));
 }
 
-// Only one of MAP_PRIVATE or MAP_SHARED may be passed
+// MAP_PRIVATE | MAP_SHARED is MAP_SHARED_VALIDATE, which anonymous mappings
+// don't accept.
 TEST_F(MMapTest, PrivateAndShared) {
   EXPECT_THAT(Map(0, kPageSize, PROT_NONE,
                   MAP_PRIVATE | MAP_SHARED | MAP_ANONYMOUS, -1, 0),
               SyscallFailsWithErrno(EINVAL));
 }
 
+// Undefined mapping types are rejected.
+TEST_F(MMapTest, InvalidMapType) {
+  EXPECT_THAT(Map(0, kPageSize, PROT_NONE, 0x04 | MAP_ANONYMOUS, -1, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// Unknown flags are ignored without MAP_SHARED_VALIDATE.
+TEST_F(MMapTest, UnknownFlagsIgnored) {
+  EXPECT_THAT(Map(0, kPageSize, PROT_NONE,
+                  MAP_PRIVATE | MAP_ANONYMOUS | kUnknownMapFlag, -1, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(Unmap(), SyscallSucceeds());
+  EXPECT_THAT(Map(0, kPageSize, PROT_NONE,
+                  MAP_SHARED | MAP_ANONYMOUS | MAP_SYNC, -1, 0),
+              SyscallSucceeds());
+}
+
+// MAP_FIXED_NOREPLACE gives us exactly the requested address if it is free.
+TEST_F(MMapTest, MapFixedNoReplace) {
+  // Find a free range.
+  uintptr_t addr;
+  ASSERT_THAT(addr = Map(0, 2 * kPageSize, PROT_NONE,
+                         MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(Unmap(), SyscallSucceeds());
+
+  EXPECT_THAT(Map(addr, 2 * kPageSize, PROT_NONE,
+                  MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED_NOREPLACE, -1, 0),
+              SyscallSucceedsWithValue(addr));
+}
+
+// MAP_FIXED_NOREPLACE fails with EEXIST on any overlap with an existing
+// mapping, even in combination with MAP_FIXED.
+TEST_F(MMapTest, MapFixedNoReplaceExisting) {
+  uintptr_t addr;
+  ASSERT_THAT(addr = Map(0, 2 * kPageSize, PROT_NONE,
+                         MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(munmap(reinterpret_cast<void*>(addr + kPageSize), kPageSize),
+              SyscallSucceeds());
+
+  // Exact overlap.
+  EXPECT_THAT(mmap(reinterpret_cast<void*>(addr), kPageSize, PROT_NONE,
+                   MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED_NOREPLACE, -1, 0),
+              SyscallFailsWithErrno(EEXIST));
+  // Partial overlap.
+  EXPECT_THAT(mmap(reinterpret_cast<void*>(addr), 2 * kPageSize, PROT_NONE,
+                   MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED_NOREPLACE, -1, 0),
+              SyscallFailsWithErrno(EEXIST));
+  EXPECT_THAT(
+      mmap(reinterpret_cast<void*>(addr), kPageSize, PROT_NONE,
+           MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED | MAP_FIXED_NOREPLACE, -1,
+           0),
+      SyscallFailsWithErrno(EEXIST));
+
+  // The freed page can still be mapped.
+  void* page;
+  EXPECT_THAT(
+      page = mmap(reinterpret_cast<void*>(addr + kPageSize), kPageSize,
+                  PROT_NONE, MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED_NOREPLACE,
+                  -1, 0),
+      SyscallSucceedsWithValue(addr + kPageSize));
+  if (page != MAP_FAILED) {
+    EXPECT_THAT(munmap(page, kPageSize), SyscallSucceeds());
+  }
+}
+
+// MAP_FIXED_NOREPLACE, like MAP_FIXED, requires a page-aligned address.
+TEST_F(MMapTest, MapFixedNoReplaceAlignment) {
+  EXPECT_THAT(Map(0x30000001, kPageSize, PROT_NONE,
+                  MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED_NOREPLACE, -1, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 TEST_F(MMapTest, FixedAlignment) {
   // Addr must be page aligned (MAP_FIXED)
   EXPECT_THAT(Map(0x30000001, kPageSize, PROT_NONE,
@@ -739,6 +866,123 @@ TEST_F(MMapTest, ExceedLimitAS) {
       SyscallFailsWithErrno(ENOMEM));
 }
 
+TEST_F(MMapTest, ExceedLimitASMremap) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+  constexpr uint64_t kExtraASBytes = 20 << 20;
+
+  void* addr = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                    MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+  ASSERT_THAT(addr, Ne(MAP_FAILED));
+  auto cleanup = Cleanup([addr] { munmap(addr, kPageSize); });
+
+  auto vss = ASSERT_NO_ERRNO_AND_VALUE(VirtualMemorySize());
+  auto restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_AS, vss + kExtraASBytes));
+  EXPECT_THAT(reinterpret_cast<intptr_t>(
+                  mremap(addr, kPageSize, kAllocBytes, MREMAP_MAYMOVE)),
+              SyscallFailsWithErrno(ENOMEM));
+}
+
+TEST_F(MMapTest, ExceedLimitASBrk) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+  constexpr uint64_t kExtraASBytes = 20 << 20;
+
+  void* prevbrk = sbrk(0);
+  ASSERT_NE(-1, reinterpret_cast<intptr_t>(prevbrk));
+
+  auto vss = ASSERT_NO_ERRNO_AND_VALUE(VirtualMemorySize());
+  auto restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_AS, vss + kExtraASBytes));
+  EXPECT_THAT(brk(reinterpret_cast<char*>(prevbrk) + kAllocBytes),
+              SyscallFailsWithErrno(ENOMEM));
+  EXPECT_EQ(sbrk(0), prevbrk);
+}
+
+TEST_F(MMapTest, ExceedLimitDataMmap) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+  constexpr uint64_t kExtraDataBytes = 20 << 20;
+
+  auto data = ASSERT_NO_ERRNO_AND_VALUE(VirtualDataSize());
+  auto restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_DATA, data + kExtraDataBytes));
+
+  // Private writable mappings are data.
+  EXPECT_THAT(Map(0, kAllocBytes, PROT_READ | PROT_WRITE,
+                  MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+              SyscallFailsWithErrno(ENOMEM));
+
+  // Shared and read-only mappings aren't.
+  void* addr = mmap(nullptr, kAllocBytes, PROT_READ | PROT_WRITE,
+                    MAP_SHARED | MAP_ANONYMOUS, -1, 0);
+  EXPECT_THAT(addr, Ne(MAP_FAILED));
+  if (addr != MAP_FAILED) {
+    EXPECT_THAT(munmap(addr, kAllocBytes), SyscallSucceeds());
+  }
+  EXPECT_THAT(
+      Map(0, kAllocBytes, PROT_READ, MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+      SyscallSucceedsWithValue(Gt(0)));
+}
+
+TEST_F(MMapTest, ExceedLimitDataMprotect) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+  constexpr uint64_t kExtraDataBytes = 20 << 20;
+
+  uintptr_t addr;
+  ASSERT_THAT(addr = Map(0, kAllocBytes, PROT_READ,
+                         MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+              SyscallSucceedsWithValue(Gt(0)));
+
+  auto data = ASSERT_NO_ERRNO_AND_VALUE(VirtualDataSize());
+  auto restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_DATA, data + kExtraDataBytes));
+
+  // Making the mapping writable turns it into data.
+  EXPECT_THAT(Protect(addr, kAllocBytes, PROT_READ | PROT_WRITE),
+              SyscallFailsWithErrno(ENOMEM));
+  EXPECT_THAT(Protect(addr, kPageSize, PROT_READ | PROT_WRITE),
+              SyscallSucceeds());
+}
+
+TEST_F(MMapTest, ExceedLimitDataMremap) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+  constexpr uint64_t kExtraDataBytes = 20 << 20;
+
+  void* addr = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                    MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+  ASSERT_THAT(addr, Ne(MAP_FAILED));
+  auto cleanup = Cleanup([addr] { munmap(addr, kPageSize); });
+
+  auto data = ASSERT_NO_ERRNO_AND_VALUE(VirtualDataSize());
+  auto restore =
+      ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_DATA, data + kExtraDataBytes));
+  EXPECT_THAT(reinterpret_cast<intptr_t>(
+                  mremap(addr, kPageSize, kAllocBytes, MREMAP_MAYMOVE)),
+              SyscallFailsWithErrno(ENOMEM));
+}
+
+TEST_F(MMapTest, LowerLimitDataAffectsOnlyNewMappings) {
+  constexpr uint64_t kAllocBytes = 200 << 20;
+
+  uintptr_t addr;
+  ASSERT_THAT(addr = Map(0, kAllocBytes, PROT_READ | PROT_WRITE,
+                         MAP_PRIVATE | MAP_ANONYMOUS, -1, 0),
+              SyscallSucceedsWithValue(Gt(0)));
+
+  // Lower the limit below current usage.
+  auto restore = ASSERT_NO_ERRNO_AND_VALUE(SetLimit(RLIMIT_DATA, kPageSize));
+
+  // The existing mapping remains usable.
+  memset(reinterpret_cast<void*>(addr), 1, kPageSize);
+  memset(reinterpret_cast<void*>(addr + kAllocBytes - kPageSize), 1,
+         kPageSize);
+
+  // New data mappings fail.
+  void* other = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                     MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+  EXPECT_EQ(other, MAP_FAILED);
+  EXPECT_EQ(errno, ENOMEM);
+}
+
 // Tests that setting an anonymous mmap to PROT_NONE doesn't free the memory.
 TEST_F(MMapTest, SettingProtNoneDoesntFreeMemory) {
   uintptr_t addr;
@@ -951,6 +1195,48 @@ TEST_F(MMapFileTest, WriteSharedOnReadOnlyFd) {
       SyscallFailsWithErrno(EACCES));
 }
 
+// MAP_SHARED_VALIDATE behaves like MAP_SHARED for legacy flags.
+TEST_F(MMapFileTest, SharedValidate) {
+  SKIP_IF(!FSSupportsMap());
+  uintptr_t addr;
+  ASSERT_THAT(addr = Map(0, kPageSize, PROT_READ | PROT_WRITE,
+                         MAP_SHARED_VALIDATE | MAP_POPULATE, fd_.get(), 0),
+              SyscallSucceeds());
+
+  // Writes through the mapping are shared with the file.
+  *reinterpret_cast<char*>(addr) = 'a';
+  char c;
+  ASSERT_THAT(pread(fd_.get(), &c, 1, 0), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(c, 'a');
+}
+
+// MAP_SHARED_VALIDATE rejects MAP_SYNC on files that don't support it, which
+// is the only way for applications to detect support.
+TEST_F(MMapFileTest, SharedValidateSync) {
+  SKIP_IF(!FSSupportsMap());
+  EXPECT_THAT(Map(0, kPageSize, PROT_READ | PROT_WRITE,
+                  MAP_SHARED_VALIDATE | MAP_SYNC, fd_.get(), 0),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+TEST_F(MMapFileTest, SharedValidateUnknownFlag) {
+  SKIP_IF(!FSSupportsMap());
+  EXPECT_THAT(Map(0, kPageSize, PROT_READ,
+                  MAP_SHARED_VALIDATE | kUnknownMapFlag, fd_.get(), 0),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
+// MAP_SYNC is silently ignored without MAP_SHARED_VALIDATE.
+TEST_F(MMapFileTest, SyncIgnored) {
+  SKIP_IF(!FSSupportsMap());
+  EXPECT_THAT(Map(0, kPageSize, PROT_READ, MAP_SHARED | MAP_SYNC, fd_.get(), 0),
+              SyscallSucceeds());
+  ASSERT_THAT(Unmap(), SyscallSucceeds());
+  EXPECT_THAT(
+      Map(0, kPageSize, PROT_READ, MAP_PRIVATE | MAP_SYNC, fd_.get(), 0),
+      SyscallSucceeds());
+}
+
 // Mmap not allowed on O_PATH FDs.
 TEST_F(MMapFileTest, MmapFileWithOpath) {
   SKIP_IF(!FSSupportsMap());
@@ -17,6 +17,9 @@
 #include <stdio.h>
 #include <sys/mount.h>
 #include <sys/stat.h>
+#include <sys/sysmacros.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
 #include <functional>
@@ -26,6 +29,8 @@
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
 #include "absl/strings/string_view.h"
 #include "absl/time/time.h"
@@ -279,6 +284,29 @@ TEST(MountTest, MountReadonly) {
               SyscallFailsWithErrno(EROFS));
 }
 
+TEST(MountTest, MountReadonlyCreate) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const target = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", MS_RDONLY, "mode=0777", 0));
+
+  std::string const filename = JoinPath(dir.path(), "foo");
+  EXPECT_THAT(open(filename.c_str(), O_RDWR | O_CREAT, 0777),
+              SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(mknod(filename.c_str(), S_IFREG | 0777, 0),
+              SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(mknod(filename.c_str(), S_IFIFO | 0777, 0),
+              SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(mkdir(filename.c_str(), 0777), SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(symlink(target.path().c_str(), filename.c_str()),
+              SyscallFailsWithErrno(EROFS));
+  // The read-only mount is reported before the cross-mount link.
+  EXPECT_THAT(link(target.path().c_str(), filename.c_str()),
+              SyscallFailsWithErrno(EROFS));
+}
+
 PosixErrorOr<absl::Time> ATime(absl::string_view file) {
   struct stat s = {};
   if (stat(std::string(file).c_str(), &s) == -1) {
@@ -331,6 +359,104 @@ TEST(MountTest, MountNoExec) {
   EXPECT_EQ(execve_errno, EACCES);
 }
 
+TEST(MountTest, MountNoDev) {
+  // VFS1 doesn't support MS_NODEV.
+  SKIP_IF(IsRunningWithVFS1());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_MKNOD)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", MS_NODEV, "mode=0777", 0));
+
+  // Device nodes can still be created, but not opened.
+  const std::string path = JoinPath(dir.path(), "null");
+  ASSERT_THAT(mknod(path.c_str(), S_IFCHR | 0666, makedev(1, 3)),
+              SyscallSucceeds());
+  EXPECT_THAT(open(path.c_str(), O_RDWR), SyscallFailsWithErrno(EACCES));
+
+  // Other files are unaffected.
+  auto const file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(dir.path()));
+  EXPECT_NO_ERRNO(Open(file.path(), O_RDWR));
+}
+
+TEST(MountTest, MountDev) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_MKNOD)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+
+  const std::string path = JoinPath(dir.path(), "null");
+  ASSERT_THAT(mknod(path.c_str(), S_IFCHR | 0666, makedev(1, 3)),
+              SyscallSucceeds());
+  EXPECT_NO_ERRNO(Open(path, O_RDWR));
+}
+
+TEST(MountTest, MountFlagsInMountInfo) {
+  SKIP_IF(IsRunningWithVFS1());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs",
+            MS_NOSUID | MS_NODEV | MS_NOEXEC | MS_NOATIME, "", 0));
+
+  bool found = false;
+  for (auto const& e :
+       ASSERT_NO_ERRNO_AND_VALUE(ProcSelfMountInfoEntries())) {
+    if (e.mount_point == dir.path()) {
+      found = true;
+      auto mopts = ParseMountOptions(e.mount_opts);
+      EXPECT_THAT(mopts, Contains(Pair("rw", "")));
+      EXPECT_THAT(mopts, Contains(Pair("nosuid", "")));
+      EXPECT_THAT(mopts, Contains(Pair("nodev", "")));
+      EXPECT_THAT(mopts, Contains(Pair("noexec", "")));
+      EXPECT_THAT(mopts, Contains(Pair("noatime", "")));
+    }
+  }
+  EXPECT_TRUE(found);
+}
+
+// A set-user-ID binary executed from a nosuid mount runs with the caller's
+// effective UID.
+TEST(MountTest, NoSuid) {
+  SKIP_IF(IsRunningWithVFS1());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETUID)));
+  // The copied binary must be owned by a UID other than nobody.
+  SKIP_IF(getuid() != 0);
+  constexpr int kNobody = 65534;
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", MS_NOSUID, "mode=0755", 0));
+
+  const std::string contents = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(RunfilePath("test/syscalls/linux/exec_euid_workload")));
+  const std::string path = JoinPath(dir.path(), "euid");
+  ASSERT_NO_ERRNO(CreateWithContents(path, contents, 0755));
+  ASSERT_THAT(chmod(path.c_str(), S_ISUID | 0755), SyscallSucceeds());
+
+  pid_t child = fork();
+  if (child == 0) {
+    TEST_PCHECK(syscall(SYS_setresuid, kNobody, kNobody, kNobody) == 0);
+    const ExecveArray argv = {path};
+    const ExecveArray envv;
+    execve(path.c_str(), argv.get(), envv.get());
+    _exit(errno);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status " << status;
+}
+
 TEST(MountTest, RenameRemoveMountPoint) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
 
@@ -348,6 +474,23 @@ TEST(MountTest, RenameRemoveMountPoint) {
   ASSERT_THAT(rmdir(dir.path().c_str()), SyscallFailsWithErrno(EBUSY));
 }
 
+TEST(MountTest, RenameOverMountPoint) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir_parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const dir =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir_parent.path()));
+  auto const src_dir =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir_parent.path()));
+
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+
+  ASSERT_THAT(rename(src_dir.path().c_str(), dir.path().c_str()),
+              SyscallFailsWithErrno(EBUSY));
+  EXPECT_THAT(Exists(src_dir.path()), IsPosixErrorOkAndHolds(true));
+}
+
 TEST(MountTest, MountInfo) {
   SKIP_IF(IsRunningWithVFS1());
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
@@ -379,6 +522,22 @@ TEST(MountTest, MountInfo) {
   }
 }
 
+TEST(MountTest, MountStats) {
+  SKIP_IF(IsRunningWithVFS1());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("none", dir.path(), "tmpfs", 0, "", 0));
+
+  std::string const contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/mountstats"));
+  EXPECT_TRUE(absl::StrContains(
+      contents, absl::StrCat("device none mounted on ", dir.path(),
+                             " with fstype tmpfs\n")))
+      << contents;
+}
+
 }  // namespace
 
 }  // namespace testing
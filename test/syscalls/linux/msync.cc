@@ -12,6 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <fcntl.h>
+#include <string.h>
 #include <sys/mman.h>
 #include <unistd.h>
 
@@ -20,6 +22,8 @@
 #include <utility>
 #include <vector>
 
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/memory_util.h"
 #include "test/util/posix_error.h"
@@ -145,6 +149,62 @@ INSTANTIATE_TEST_SUITE_P(
     ::testing::Combine(::testing::ValuesIn(kMsyncFlags),
                        ::testing::ValuesIn(SyncableMappings())));
 
+// msync of a range spanning shared mappings of two files, and of part of a
+// mapping, must write back each file's data without losing any of it, including
+// when cached pages are invalidated.
+TEST(MsyncTest, SharedMappingsOfTwoFiles) {
+  for (int const flags : {MS_SYNC, MS_ASYNC, 0}) {
+    for (int const invalidate : {0, MS_INVALIDATE}) {
+      SCOPED_TRACE(absl::StrCat("flags: ", flags | invalidate));
+
+      auto const file1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+      auto const file2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+      auto const fd1 = ASSERT_NO_ERRNO_AND_VALUE(Open(file1.path(), O_RDWR));
+      auto const fd2 = ASSERT_NO_ERRNO_AND_VALUE(Open(file2.path(), O_RDWR));
+      ASSERT_THAT(ftruncate(fd1.get(), 2 * kPageSize), SyscallSucceeds());
+      ASSERT_THAT(ftruncate(fd2.get(), kPageSize), SyscallSucceeds());
+
+      // Map both pages of file1 followed by file2 in a contiguous range.
+      Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+          MmapAnon(3 * kPageSize, PROT_NONE, MAP_PRIVATE));
+      ASSERT_THAT(mmap(m.ptr(), 2 * kPageSize, PROT_READ | PROT_WRITE,
+                       MAP_SHARED | MAP_FIXED, fd1.get(), 0),
+                  SyscallSucceeds());
+      ASSERT_THAT(
+          mmap(reinterpret_cast<void*>(m.addr() + 2 * kPageSize), kPageSize,
+               PROT_READ | PROT_WRITE, MAP_SHARED | MAP_FIXED, fd2.get(), 0),
+          SyscallSucceeds());
+
+      char* const p = reinterpret_cast<char*>(m.ptr());
+      memset(p, 'a', 2 * kPageSize);
+      memset(p + 2 * kPageSize, 'b', kPageSize);
+
+      // First sync only the second page of file1, then the range spanning the
+      // end of file1 and file2.
+      ASSERT_THAT(msync(p + kPageSize, kPageSize, flags | invalidate),
+                  SyscallSucceeds());
+      ASSERT_THAT(msync(p + kPageSize, 2 * kPageSize, flags | invalidate),
+                  SyscallSucceeds());
+      ASSERT_THAT(msync(p, 3 * kPageSize, flags | invalidate),
+                  SyscallSucceeds());
+
+      std::vector<char> buf(2 * kPageSize);
+      ASSERT_THAT(pread(fd1.get(), buf.data(), buf.size(), 0),
+                  SyscallSucceedsWithValue(buf.size()));
+      EXPECT_EQ(buf, std::vector<char>(2 * kPageSize, 'a'));
+      buf.resize(kPageSize);
+      ASSERT_THAT(pread(fd2.get(), buf.data(), buf.size(), 0),
+                  SyscallSucceedsWithValue(buf.size()));
+      EXPECT_EQ(buf, std::vector<char>(kPageSize, 'b'));
+
+      // The mappings still observe the written data.
+      EXPECT_EQ(p[0], 'a');
+      EXPECT_EQ(p[2 * kPageSize - 1], 'a');
+      EXPECT_EQ(p[2 * kPageSize], 'b');
+    }
+  }
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -14,7 +14,9 @@
 
 #include <errno.h>
 #include <fcntl.h>
+#include <limits.h>
 #include <linux/capability.h>
+#include <sys/mount.h>
 #include <sys/stat.h>
 #include <sys/types.h>
 #include <unistd.h>
@@ -27,6 +29,7 @@
 #include "test/util/cleanup.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -175,6 +178,34 @@ TEST_F(OpenTest, ReadWrite) {
   EXPECT_THAT(write(rw_file.get(), &buf, 1), SyscallSucceedsWithValue(1));
 }
 
+TEST_F(OpenTest, InvalidAccessMode) {
+  if (!IsRunningOnGvisor()) {
+    // Linux opens the file for neither reading nor writing.
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(test_file_name_, O_ACCMODE));
+    char buf;
+    EXPECT_THAT(read(fd.get(), &buf, 1), SyscallFailsWithErrno(EBADF));
+    EXPECT_THAT(write(fd.get(), &buf, 1), SyscallFailsWithErrno(EBADF));
+    return;
+  }
+
+  // gVisor doesn't support such files, and rejects the access mode.
+  EXPECT_THAT(open(test_file_name_.c_str(), O_ACCMODE),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(open(test_file_name_.c_str(), O_ACCMODE | O_CREAT, 0644),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(OpenTest, PathIgnoresAccessMode) {
+  // VFS1 doesn't support O_PATH.
+  SKIP_IF(IsRunningWithVFS1());
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(test_file_name_, O_PATH | O_ACCMODE));
+  char buf;
+  EXPECT_THAT(read(fd.get(), &buf, 1), SyscallFailsWithErrno(EBADF));
+}
+
 TEST_F(OpenTest, RelPath) {
   auto name = std::string(Basename(test_file_name_));
 
@@ -202,6 +233,25 @@ TEST_F(OpenTest, AtAbsPath) {
       ASSERT_NO_ERRNO_AND_VALUE(OpenAt(dirfd.get(), test_file_name_, O_RDONLY));
 }
 
+TEST_F(OpenTest, AtDeletedDirectory) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  ASSERT_THAT(rmdir(dir.path().c_str()), SyscallSucceeds());
+
+  // The directory has no children and none can be created in it.
+  EXPECT_THAT(openat(dirfd.get(), "foo", O_RDONLY),
+              SyscallFailsWithErrno(ENOENT));
+  EXPECT_THAT(openat(dirfd.get(), "foo", O_RDWR | O_CREAT, 0666),
+              SyscallFailsWithErrno(ENOENT));
+  EXPECT_THAT(mkdirat(dirfd.get(), "bar", 0777),
+              SyscallFailsWithErrno(ENOENT));
+
+  // The directory itself can still be opened.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(OpenAt(dirfd.get(), ".", O_RDONLY));
+}
+
 TEST_F(OpenTest, OpenNoFollowSymlink) {
   auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
   const std::string link_path = JoinPath(dir.path().c_str(), "link");
@@ -236,6 +286,45 @@ TEST_F(OpenTest, OpenNoFollowStillFollowsLinksInPath) {
       ASSERT_NO_ERRNO_AND_VALUE(Open(path_via_symlink, O_RDONLY | O_NOFOLLOW));
 }
 
+TEST_F(OpenTest, OpenNoFollowRegularFile) {
+  // O_NOFOLLOW has no effect if the final component isn't a symlink.
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(test_file_name_, O_RDONLY | O_NOFOLLOW));
+}
+
+TEST_F(OpenTest, OpenNoFollowSymlinkWithTrailingSlash) {
+  // A trailing slash forces the final symlink to be followed, so O_NOFOLLOW
+  // has no effect.
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), dir.path()));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(link.path() + "/", O_RDONLY | O_DIRECTORY | O_NOFOLLOW));
+  ASSERT_THAT(open(link.path().c_str(), O_RDONLY | O_NOFOLLOW),
+              SyscallFailsWithErrno(ELOOP));
+}
+
+TEST_F(OpenTest, OpenNoFollowIntermediateSymlinksRegularFile) {
+  // tmp_folder/real_folder/file
+  // tmp_folder/sym1 -> tmp_folder/sym2
+  // tmp_folder/sym2 -> tmp_folder/real_folder
+  //
+  // Opening tmp_folder/sym1/file with O_NOFOLLOW succeeds since only
+  // intermediate components are symlinks, while opening tmp_folder/sym1 with
+  // O_NOFOLLOW fails.
+  auto real_dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto sym2 = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), real_dir.path()));
+  auto sym1 = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), sym2.path()));
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(real_dir.path()));
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(
+      JoinPath(sym1.path(), Basename(file.path())), O_RDONLY | O_NOFOLLOW));
+  ASSERT_THAT(open(sym1.path().c_str(), O_RDONLY | O_NOFOLLOW),
+              SyscallFailsWithErrno(ELOOP));
+}
+
 // Test that open(2) can follow symlinks that point back to the same tree.
 // Test sets up files as follows:
 //   root/child/symlink => redirects to ../..
@@ -386,6 +475,34 @@ TEST_F(OpenTest, NameTooLong) {
   EXPECT_THAT(open(buf, O_RDONLY), SyscallFailsWithErrno(ENAMETOOLONG));
 }
 
+TEST_F(OpenTest, ComponentTooLong) {
+  const std::string long_name(NAME_MAX + 1, 'a');
+
+  // Looking up a component longer than NAME_MAX fails even though the whole
+  // path is shorter than PATH_MAX, whether it is the final component or not.
+  const std::string final_path = JoinPath(GetAbsoluteTestTmpdir(), long_name);
+  EXPECT_THAT(open(final_path.c_str(), O_RDONLY),
+              SyscallFailsWithErrno(ENAMETOOLONG));
+  EXPECT_THAT(open(final_path.c_str(), O_RDWR | O_CREAT, 0666),
+              SyscallFailsWithErrno(ENAMETOOLONG));
+
+  const std::string middle_path = JoinPath(final_path, "file");
+  EXPECT_THAT(open(middle_path.c_str(), O_RDONLY),
+              SyscallFailsWithErrno(ENAMETOOLONG));
+
+  struct stat st;
+  EXPECT_THAT(stat(middle_path.c_str(), &st),
+              SyscallFailsWithErrno(ENAMETOOLONG));
+}
+
+TEST_F(OpenTest, ComponentOfNameMaxSucceeds) {
+  const std::string name(NAME_MAX, 'a');
+  const std::string path = JoinPath(GetAbsoluteTestTmpdir(), name);
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(path, O_RDWR | O_CREAT | O_EXCL, 0666));
+  EXPECT_THAT(unlink(path.c_str()), SyscallSucceeds());
+}
+
 TEST_F(OpenTest, DotsFromRoot) {
   const FileDescriptor rootfd =
       ASSERT_NO_ERRNO_AND_VALUE(Open("/", O_RDONLY | O_DIRECTORY));
@@ -429,7 +546,7 @@ TEST_F(OpenTest, CanTruncateReadOnly) {
   EXPECT_EQ(stat.st_size, 0);
 }
 
-// If we don't have read permission on the file, opening with
+// If we don't have write permission on the file, opening with
 // O_TRUNC should fail.
 TEST_F(OpenTest, CanTruncateReadOnlyNoWritePermission) {
   // Drop capabilities that allow us to override file permissions.
@@ -450,6 +567,34 @@ TEST_F(OpenTest, CanTruncateReadOnlyNoWritePermission) {
   EXPECT_EQ(stat.st_size, test_data_.size());
 }
 
+// Opening an existing file with O_CREAT follows the same rules: O_TRUNC
+// truncates the file even if it is opened read only.
+TEST_F(OpenTest, CanTruncateReadOnlyCreate) {
+  const FileDescriptor fd1 = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(test_file_name_, O_CREAT | O_RDONLY | O_TRUNC, 0644));
+
+  struct stat stat;
+  EXPECT_THAT(fstat(fd1.get(), &stat), SyscallSucceeds());
+  EXPECT_EQ(stat.st_size, 0);
+}
+
+// ... and requires write permission on the file to do so.
+TEST_F(OpenTest, CanTruncateReadOnlyCreateNoWritePermission) {
+  // Drop capabilities that allow us to override file permissions.
+  AutoCapability cap(CAP_DAC_OVERRIDE, false);
+
+  const DisableSave ds;  // Permissions are dropped.
+  ASSERT_THAT(chmod(test_file_name_.c_str(), S_IRUSR | S_IRGRP),
+              SyscallSucceeds());
+
+  ASSERT_THAT(open(test_file_name_.c_str(), O_CREAT | O_RDONLY | O_TRUNC, 0644),
+              SyscallFailsWithErrno(EACCES));
+
+  struct stat stat;
+  EXPECT_THAT(::stat(test_file_name_.c_str(), &stat), SyscallSucceeds());
+  EXPECT_EQ(stat.st_size, test_data_.size());
+}
+
 // If we don't have read permission but have write permission, opening O_WRONLY
 // and O_TRUNC should succeed.
 TEST_F(OpenTest, CanTruncateWriteOnlyNoReadPermission) {
@@ -496,6 +641,70 @@ TEST_F(OpenTest, OpenNonDirectoryWithTrailingSlash) {
   EXPECT_THAT(open(bad_path.c_str(), O_RDONLY), SyscallFailsWithErrno(ENOTDIR));
 }
 
+TEST_F(OpenTest, OpenSymlinkToFileWithTrailingSlash) {
+  // The symlink is followed, and the trailing slash then requires its target
+  // to be a directory.
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), test_file_name_));
+  const std::string bad_path = link.path() + "/";
+  EXPECT_THAT(open(bad_path.c_str(), O_RDONLY), SyscallFailsWithErrno(ENOTDIR));
+  EXPECT_THAT(open(bad_path.c_str(), O_RDONLY | O_NOFOLLOW),
+              SyscallFailsWithErrno(ENOTDIR));
+}
+
+TEST_F(OpenTest, OpenSymlinkToUnreadableFileWithTrailingSlash) {
+  // Drop capabilities that allow us to override file permissions.
+  AutoCapability cap1(CAP_DAC_OVERRIDE, false);
+  AutoCapability cap2(CAP_DAC_READ_SEARCH, false);
+
+  // ENOTDIR is reported before the permission check on the target.
+  const TempPath file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileMode(0000));
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), file.path()));
+  const std::string bad_path = link.path() + "/";
+  EXPECT_THAT(open(bad_path.c_str(), O_RDONLY), SyscallFailsWithErrno(ENOTDIR));
+}
+
+TEST_F(OpenTest, OpenSymlinkToDirectoryWithTrailingSlash) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateSymlinkTo(GetAbsoluteTestTmpdir(), dir.path()));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(link.path() + "/", O_RDONLY));
+  struct stat st;
+  ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+  EXPECT_TRUE(S_ISDIR(st.st_mode));
+}
+
+// A read-only mount is reported even when file permissions would allow the
+// open.
+TEST_F(OpenTest, OpenForWriteOnReadOnlyMount) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "proc", MS_RDONLY, "", 0));
+  const std::string path = JoinPath(dir.path(), "self/oom_score_adj");
+
+  EXPECT_THAT(open(path.c_str(), O_RDWR), SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(open(path.c_str(), O_WRONLY), SyscallFailsWithErrno(EROFS));
+  ASSERT_NO_ERRNO(Open(path, O_RDONLY));
+}
+
+// On a writable mount, a file that is read-only for the caller fails with
+// EACCES instead.
+TEST_F(OpenTest, OpenForWriteReadOnlyFile) {
+  AutoCapability cap(CAP_DAC_OVERRIDE, false);
+  const DisableSave ds;  // Permissions are dropped.
+  ASSERT_THAT(chmod(test_file_name_.c_str(), S_IRUSR | S_IRGRP),
+              SyscallSucceeds());
+
+  EXPECT_THAT(open(test_file_name_.c_str(), O_RDWR),
+              SyscallFailsWithErrno(EACCES));
+  ASSERT_NO_ERRNO(Open(test_file_name_, O_RDONLY));
+}
+
 TEST_F(OpenTest, OpenWithStrangeFlags) {
   // VFS1 incorrectly allows read/write operations on such file descriptors.
   SKIP_IF(IsRunningWithVFS1());
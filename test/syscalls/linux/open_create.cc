@@ -18,6 +18,8 @@
 #include <sys/types.h>
 #include <unistd.h>
 
+#include <string>
+
 #include "gtest/gtest.h"
 #include "test/util/capability_util.h"
 #include "test/util/file_descriptor.h"
@@ -26,6 +28,7 @@
 #include "test/util/temp_path.h"
 #include "test/util/temp_umask.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
 
 namespace gvisor {
 namespace testing {
@@ -63,6 +66,19 @@ TEST(CreateTest, HonorsUmask) {
   EXPECT_EQ(0444, statbuf.st_mode & 0777);
 }
 
+TEST(CreateTest, CreatHonorsUmask) {
+  const DisableSave ds;  // file cannot be re-opened as writable.
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  TempUmask mask(0027);
+  const std::string path = JoinPath(dir.path(), "UmaskedFile");
+  int fd;
+  ASSERT_THAT(fd = creat(path.c_str(), 0777), SyscallSucceeds());
+  FileDescriptor fd_closer(fd);
+  struct stat statbuf;
+  ASSERT_THAT(fstat(fd, &statbuf), SyscallSucceeds());
+  EXPECT_EQ(0750, statbuf.st_mode & 0777);
+}
+
 TEST(CreateTest, CreateExclusively) {
   auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
   auto path = JoinPath(dir.path(), "foo");
@@ -230,6 +246,62 @@ TEST(CreateTest, CreateWithWriteFlagNotAllowedByMode) {
   EXPECT_EQ(c, 'x');
 }
 
+TEST(CreateTest, CreateInRemovedDirectory) {
+  // A removed directory can't be restored.
+  const DisableSave ds;
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  ASSERT_THAT(rmdir(dir.path().c_str()), SyscallSucceeds());
+
+  EXPECT_THAT(openat(dirfd.get(), "file", O_RDWR | O_CREAT, 0666),
+              SyscallFailsWithErrno(ENOENT));
+  EXPECT_THAT(mkdirat(dirfd.get(), "dir", 0777),
+              SyscallFailsWithErrno(ENOENT));
+}
+
+// Creating a file races with removing its parent directory. Either the
+// creation wins and the removal fails with ENOTEMPTY, or the removal wins and
+// the creation fails with ENOENT; the file must never be created in a removed
+// directory.
+TEST(CreateTest, CreateRacesWithRmdirOfParent) {
+  // Files created in removed directories can't be restored.
+  const DisableSave ds;
+  auto base = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string dir = JoinPath(base.path(), "dir");
+  const std::string file = JoinPath(dir, "file");
+
+  constexpr int kIterations = 1000;
+  for (int i = 0; i < kIterations; i++) {
+    ASSERT_THAT(mkdir(dir.c_str(), 0777), SyscallSucceeds());
+
+    int open_ret = -1;
+    int open_errno = 0;
+    ScopedThread creator([&] {
+      open_ret = open(file.c_str(), O_RDWR | O_CREAT, 0666);
+      open_errno = errno;
+    });
+    int rmdir_ret = rmdir(dir.c_str());
+    int rmdir_errno = errno;
+    creator.Join();
+
+    if (open_ret >= 0) {
+      const FileDescriptor fd(open_ret);
+      ASSERT_NE(rmdir_ret, 0) << "created file in removed directory";
+      ASSERT_EQ(rmdir_errno, ENOTEMPTY);
+      // The file must be linked into its parent.
+      struct stat st;
+      ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+      EXPECT_EQ(st.st_nlink, 1);
+      ASSERT_THAT(unlink(file.c_str()), SyscallSucceeds());
+      ASSERT_THAT(rmdir(dir.c_str()), SyscallSucceeds());
+    } else {
+      ASSERT_EQ(open_errno, ENOENT);
+      ASSERT_EQ(rmdir_ret, 0) << "rmdir failed with errno " << rmdir_errno;
+    }
+  }
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -64,6 +64,21 @@ TEST_F(PollTest, NegativeTimeout) {
   EXPECT_TRUE(TimerFired());
 }
 
+// Verify that a poll interrupted by a save/restore is transparently restarted
+// with the time remaining.
+TEST_F(PollTest, TimeoutSurvivesSave) {
+  absl::Duration duration = absl::Seconds(2);
+  ScopedThread saver([] {
+    absl::SleepFor(absl::Milliseconds(250));
+    MaybeSave();
+  });
+
+  absl::Time start = absl::Now();
+  EXPECT_THAT(poll(nullptr, 0, absl::ToInt64Milliseconds(duration)),
+              SyscallSucceedsWithValue(0));
+  EXPECT_GE(absl::Now() - start, duration);
+}
+
 void NonBlockingReadableTest(int16_t mask) {
   // Create a pipe.
   int fds[2];
@@ -19,10 +19,12 @@
 #include <unistd.h>
 
 #include "gtest/gtest.h"
+#include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/syscalls/linux/base_poll_test.h"
 #include "test/util/signal_util.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
 
 namespace gvisor {
 namespace testing {
@@ -150,6 +152,35 @@ TEST_F(PpollTest, SignalMaskAllowsSignal) {
   EXPECT_GT(absl::DurationFromTimespec(timeout), absl::Duration());
 }
 
+// Verify that a save/restore while ppoll is blocked preserves both the
+// temporary signal mask and the remaining timeout.
+TEST_F(PpollTest, SignalMaskAndTimeoutSurviveSave) {
+  absl::Duration duration = absl::Seconds(2);
+  struct timespec timeout = absl::ToTimespec(duration);
+
+  sigset_t mask;
+  ASSERT_THAT(sigprocmask(0, nullptr, &mask), SyscallSucceeds());
+  ASSERT_EQ(sigismember(&mask, SIGALRM), 0);
+  TEST_PCHECK(sigaddset(&mask, SIGALRM) == 0);
+
+  SetTimer(absl::Milliseconds(500));
+  ScopedThread saver([] {
+    absl::SleepFor(absl::Milliseconds(250));
+    MaybeSave();
+  });
+
+  absl::Time start = absl::Now();
+  ASSERT_THAT(syscallPpoll(nullptr, 0, &timeout, &mask, kSigsetSize),
+              SyscallSucceedsWithValue(0));
+  EXPECT_GE(absl::Now() - start, duration);
+  EXPECT_EQ(absl::DurationFromTimespec(timeout), absl::Duration());
+  EXPECT_TRUE(TimerFired());
+
+  sigset_t after;
+  ASSERT_THAT(sigprocmask(0, nullptr, &after), SyscallSucceeds());
+  EXPECT_EQ(sigismember(&after, SIGALRM), 0);
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
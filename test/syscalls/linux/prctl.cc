@@ -12,6 +12,9 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <fcntl.h>
+#include <sched.h>
+#include <sys/mman.h>
 #include <sys/prctl.h>
 #include <sys/ptrace.h>
 #include <sys/types.h>
@@ -22,10 +25,16 @@
 
 #include "gtest/gtest.h"
 #include "absl/flags/flag.h"
+#include "absl/strings/str_cat.h"
 #include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/memory_util.h"
 #include "test/util/multiprocess_util.h"
 #include "test/util/posix_error.h"
+#include "test/util/proc_util.h"
+#include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
 
@@ -48,6 +57,13 @@ namespace {
 #define SUID_DUMP_ROOT 2
 #endif /* SUID_DUMP_ROOT */
 
+#ifndef PR_SET_VMA
+#define PR_SET_VMA 0x53564d41
+#endif /* PR_SET_VMA */
+#ifndef PR_SET_VMA_ANON_NAME
+#define PR_SET_VMA_ANON_NAME 0
+#endif /* PR_SET_VMA_ANON_NAME */
+
 TEST(PrctlTest, NameInitialized) {
   const size_t name_length = 20;
   char name[name_length] = {};
@@ -215,9 +231,259 @@ TEST(PrctlTest, RootDumpability) {
 }
 
 TEST(PrctlTest, SetGetSubreaper) {
-  // Setting subreaper on PID 1 works vacuously because PID 1 is always a
-  // subreaper.
-  EXPECT_THAT(prctl(PR_SET_CHILD_SUBREAPER, 1), SyscallSucceeds());
+  const auto rest = [] {
+    int is_subreaper = -1;
+    TEST_PCHECK(prctl(PR_GET_CHILD_SUBREAPER, &is_subreaper) == 0);
+    TEST_CHECK(is_subreaper == 0);
+
+    TEST_PCHECK(prctl(PR_SET_CHILD_SUBREAPER, 1) == 0);
+    TEST_PCHECK(prctl(PR_GET_CHILD_SUBREAPER, &is_subreaper) == 0);
+    TEST_CHECK(is_subreaper == 1);
+
+    TEST_PCHECK(prctl(PR_SET_CHILD_SUBREAPER, 0) == 0);
+    TEST_PCHECK(prctl(PR_GET_CHILD_SUBREAPER, &is_subreaper) == 0);
+    TEST_CHECK(is_subreaper == 0);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+// Tests that a process orphaned by its parent's exit is reparented to the
+// nearest subreaper ancestor, which can then reap it.
+TEST(PrctlTest, SubreaperAdoptsOrphan) {
+  const auto rest = [] {
+    TEST_PCHECK(prctl(PR_SET_CHILD_SUBREAPER, 1) == 0);
+    const pid_t subreaper = getpid();
+
+    int fds[2];
+    TEST_PCHECK(pipe(fds) == 0);
+
+    const pid_t child = fork();
+    if (child == 0) {
+      const pid_t grandchild = fork();
+      if (grandchild == 0) {
+        // Wait to be reparented to the subreaper.
+        while (getppid() != subreaper) {
+          sched_yield();
+        }
+        _exit(0);
+      }
+      TEST_PCHECK(WriteFd(fds[1], &grandchild, sizeof(grandchild)) ==
+                  sizeof(grandchild));
+      _exit(0);
+    }
+    TEST_PCHECK(child > 0);
+
+    pid_t grandchild;
+    TEST_PCHECK(ReadFd(fds[0], &grandchild, sizeof(grandchild)) ==
+                sizeof(grandchild));
+
+    int status;
+    TEST_PCHECK(RetryEINTR(waitpid)(child, &status, 0) == child);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+    TEST_PCHECK(RetryEINTR(waitpid)(grandchild, &status, 0) == grandchild);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+// Tests that orphans in a nested PID namespace are reparented to that
+// namespace's init rather than to a subreaper outside it, and that the
+// subreaper adopts the namespace's init when its parent exits.
+TEST(PrctlTest, SubreaperAcrossPIDNamespaces) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const auto rest = [] {
+    TEST_PCHECK(prctl(PR_SET_CHILD_SUBREAPER, 1) == 0);
+
+    int fds[2];
+    TEST_PCHECK(pipe(fds) == 0);
+
+    const pid_t middle = fork();
+    if (middle == 0) {
+      // The next child will be init of a new PID namespace.
+      TEST_PCHECK(unshare(CLONE_NEWPID) == 0);
+      const pid_t init = fork();
+      if (init == 0) {
+        TEST_CHECK(getpid() == 1);
+        const pid_t parent = fork();
+        if (parent == 0) {
+          const pid_t orphan = fork();
+          if (orphan == 0) {
+            // Wait to be reparented to the namespace's init.
+            while (getppid() != 1) {
+              sched_yield();
+            }
+            _exit(0);
+          }
+          _exit(0);
+        }
+        // Reap both the child and the orphaned grandchild.
+        for (int i = 0; i < 2; i++) {
+          int status;
+          TEST_PCHECK(RetryEINTR(wait)(&status) > 0);
+          TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+        }
+        _exit(0);
+      }
+      TEST_PCHECK(WriteFd(fds[1], &init, sizeof(init)) == sizeof(init));
+      _exit(0);
+    }
+    TEST_PCHECK(middle > 0);
+
+    pid_t init;
+    TEST_PCHECK(ReadFd(fds[0], &init, sizeof(init)) == sizeof(init));
+
+    int status;
+    TEST_PCHECK(RetryEINTR(waitpid)(middle, &status, 0) == middle);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+    // The namespace's init was orphaned by middle's exit, and is now ours.
+    TEST_PCHECK(RetryEINTR(waitpid)(init, &status, 0) == init);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+int SetVMAAnonName(uintptr_t addr, size_t len, const char* name) {
+  return prctl(PR_SET_VMA, PR_SET_VMA_ANON_NAME, addr, len, name);
+}
+
+// AnonVMANameSupported returns false if the kernel was built without
+// CONFIG_ANON_VMA_NAME.
+PosixErrorOr<bool> AnonVMANameSupported() {
+  ASSIGN_OR_RETURN_ERRNO(Mapping m,
+                         MmapAnon(kPageSize, PROT_READ, MAP_PRIVATE));
+  if (SetVMAAnonName(m.addr(), m.len(), "probe") < 0) {
+    if (errno == EINVAL) {
+      return false;
+    }
+    return PosixError(errno, "prctl(PR_SET_VMA_ANON_NAME)");
+  }
+  return true;
+}
+
+// VMAContaining returns the /proc/self/maps entry containing addr.
+PosixErrorOr<ProcMapsEntry> VMAContaining(uintptr_t addr) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents,
+                         GetContents("/proc/self/maps"));
+  ASSIGN_OR_RETURN_ERRNO(auto entries, ParseProcMaps(contents));
+  for (const auto& entry : entries) {
+    if (entry.start <= addr && addr < entry.end) {
+      return entry;
+    }
+  }
+  return PosixError(ENOENT, absl::StrCat("no vma contains ", addr));
+}
+
+TEST(PrctlTest, SetVMAAnonName) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(AnonVMANameSupported()));
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(3 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  // Naming part of a mapping splits it.
+  ASSERT_THAT(SetVMAAnonName(m.addr() + kPageSize, kPageSize, "test:name"),
+              SyscallSucceeds());
+  ProcMapsEntry entry =
+      ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr() + kPageSize));
+  EXPECT_EQ(entry.start, m.addr() + kPageSize);
+  EXPECT_EQ(entry.end, m.addr() + 2 * kPageSize);
+  EXPECT_EQ(entry.filename, "[anon:test:name]");
+  entry = ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr()));
+  EXPECT_EQ(entry.filename, "");
+
+  std::string smaps =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/smaps"));
+  EXPECT_NE(smaps.find("[anon:test:name]\n"), std::string::npos);
+
+  // vmas with the same name merge.
+  ASSERT_THAT(SetVMAAnonName(m.addr(), m.len(), "test:name"),
+              SyscallSucceeds());
+  entry = ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr()));
+  EXPECT_EQ(entry.start, m.addr());
+  EXPECT_EQ(entry.end, m.endaddr());
+  EXPECT_EQ(entry.filename, "[anon:test:name]");
+
+  // A NULL name clears it.
+  ASSERT_THAT(SetVMAAnonName(m.addr(), m.len(), nullptr), SyscallSucceeds());
+  entry = ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr()));
+  EXPECT_EQ(entry.filename, "");
+}
+
+TEST(PrctlTest, SetVMAAnonNameInvalid) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(AnonVMANameSupported()));
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  for (const char* name : {"[x", "x]", "$x", "`x", "\\x", "x\ny", "\x7f"}) {
+    EXPECT_THAT(SetVMAAnonName(m.addr(), m.len(), name),
+                SyscallFailsWithErrno(EINVAL))
+        << name;
+  }
+
+  // The name, including the terminating NUL, may be at most 80 bytes long.
+  EXPECT_THAT(SetVMAAnonName(m.addr(), m.len(), std::string(79, 'x').c_str()),
+              SyscallSucceeds());
+  EXPECT_THAT(SetVMAAnonName(m.addr(), m.len(), std::string(80, 'x').c_str()),
+              SyscallFailsWithErrno(EINVAL));
+
+  EXPECT_THAT(SetVMAAnonName(m.addr() + 1, m.len(), "x"),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(prctl(PR_SET_VMA, PR_SET_VMA_ANON_NAME + 1, m.addr(), m.len(),
+                    "x"),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(PrctlTest, SetVMAAnonNameUnmapped) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(AnonVMANameSupported()));
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(2 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_THAT(munmap(reinterpret_cast<void*>(m.addr() + kPageSize), kPageSize),
+              SyscallSucceeds());
+
+  // The mapped part is still named.
+  EXPECT_THAT(SetVMAAnonName(m.addr(), m.len(), "x"),
+              SyscallFailsWithErrno(ENOMEM));
+  const ProcMapsEntry entry =
+      ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr()));
+  EXPECT_EQ(entry.filename, "[anon:x]");
+}
+
+TEST(PrctlTest, SetVMAAnonNameFileMapping) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(AnonVMANameSupported()));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), std::string(kPageSize, 'a'), 0644));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      Mmap(nullptr, kPageSize, PROT_READ, MAP_PRIVATE, fd.get(), 0));
+
+  EXPECT_THAT(SetVMAAnonName(m.addr(), m.len(), "x"),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST(PrctlTest, SetVMAAnonNamePreservedAcrossForkAndMremap) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(AnonVMANameSupported()));
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_THAT(SetVMAAnonName(m.addr(), m.len(), "x"), SyscallSucceeds());
+
+  const auto rest = [&] {
+    const auto entry = VMAContaining(m.addr());
+    TEST_CHECK(entry.ok());
+    TEST_CHECK(entry.ValueOrDie().filename == "[anon:x]");
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  // Growing the mapping, whether in place or by moving it, names the whole
+  // new range.
+  void* addr = ASSERT_NO_ERRNO_AND_VALUE(
+      Mremap(m.ptr(), m.len(), 2 * kPageSize, MREMAP_MAYMOVE, nullptr));
+  m.release();
+  m.reset(addr, 2 * kPageSize);
+  const ProcMapsEntry entry =
+      ASSERT_NO_ERRNO_AND_VALUE(VMAContaining(m.addr()));
+  EXPECT_EQ(entry.start, m.addr());
+  EXPECT_GE(entry.end, m.endaddr());
+  EXPECT_EQ(entry.filename, "[anon:x]");
 }
 
 }  // namespace
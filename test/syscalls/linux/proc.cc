@@ -16,6 +16,7 @@
 #include <errno.h>
 #include <fcntl.h>
 #include <limits.h>
+#include <linux/futex.h>
 #include <linux/magic.h>
 #include <linux/sem.h>
 #include <sched.h>
@@ -72,6 +73,7 @@
 #include "test/util/posix_error.h"
 #include "test/util/proc_util.h"
 #include "test/util/temp_path.h"
+#include "test/util/temp_umask.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
 #include "test/util/time_util.h"
@@ -86,6 +88,7 @@ using ::testing::ContainerEq;
 using ::testing::Contains;
 using ::testing::ContainsRegex;
 using ::testing::Eq;
+using ::testing::Ge;
 using ::testing::Gt;
 using ::testing::HasSubstr;
 using ::testing::IsSupersetOf;
@@ -1663,6 +1666,19 @@ TEST(ProcPidStatusTest, HasBasicFields) {
   });
 }
 
+TEST(ProcPidStatusTest, Umask) {
+  TempUmask mask(0027);
+  std::string status_str =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/status"));
+  const auto status = ASSERT_NO_ERRNO_AND_VALUE(ParseProcStatus(status_str));
+  EXPECT_THAT(status, Contains(Pair("Umask", "0027")));
+
+  umask(0);
+  status_str = ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/status"));
+  const auto status2 = ASSERT_NO_ERRNO_AND_VALUE(ParseProcStatus(status_str));
+  EXPECT_THAT(status2, Contains(Pair("Umask", "0000")));
+}
+
 TEST(ProcPidStatusTest, StateRunning) {
   // Task must be running when reading the file.
   const pid_t tid = syscall(SYS_gettid);
@@ -2738,6 +2754,52 @@ TEST(Proc, PidTidIOAccounting) {
   noop.Join();
 }
 
+// Returns the value of field in /proc/self/io.
+PosixErrorOr<uint64_t> ProcSelfIOField(absl::string_view field) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents, GetContents("/proc/self/io"));
+  for (absl::string_view line :
+       absl::StrSplit(contents, '\n', absl::SkipEmpty())) {
+    std::vector<absl::string_view> kv =
+        absl::StrSplit(line, absl::MaxSplits(':', 1));
+    if (kv.size() != 2 || kv[0] != field) {
+      continue;
+    }
+    uint64_t val;
+    if (!absl::SimpleAtoi(absl::StripAsciiWhitespace(kv[1]), &val)) {
+      return PosixError(EINVAL, absl::StrCat("malformed line: ", line));
+    }
+    return val;
+  }
+  return PosixError(ENOENT, absl::StrCat("no field ", field));
+}
+
+// Checks that the I/O of a reaped child is accounted to its parent.
+TEST(Proc, ReapedChildIOAccounting) {
+  constexpr int kNumWrites = 10;
+  const uint64_t before =
+      ASSERT_NO_ERRNO_AND_VALUE(ProcSelfIOField("syscw"));
+
+  const pid_t child = fork();
+  if (child == 0) {
+    int fd = open("/dev/null", O_WRONLY);
+    TEST_PCHECK(fd >= 0);
+    for (int i = 0; i < kNumWrites; i++) {
+      TEST_PCHECK(write(fd, "a", 1) == 1);
+    }
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  ASSERT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+
+  EXPECT_THAT(ProcSelfIOField("syscw"),
+              IsPosixErrorOkAndHolds(Ge(before + kNumWrites)));
+}
+
 TEST(Proc, Statfs) {
   struct statfs st;
   EXPECT_THAT(statfs("/proc", &st), SyscallSucceeds());
@@ -2750,6 +2812,67 @@ TEST(Proc, Statfs) {
   EXPECT_EQ(st.f_namelen, NAME_MAX);
 }
 
+TEST(ProcPidStack, RequiresCapSysAdmin) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/stack", O_RDONLY));
+  char buf[1];
+  EXPECT_THAT(read(fd.get(), buf, sizeof(buf)), SyscallFailsWithErrno(EACCES));
+}
+
+// Tests that /proc/[pid]/stack reports a task blocked in FUTEX_WAIT.
+TEST(ProcPidStack, FutexWait) {
+  // Linux reports real kernel stacks, whose contents vary.
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  std::atomic<uint32_t> futex_word(0);
+  std::atomic<pid_t> tid(0);
+  ScopedThread t([&] {
+    tid.store(syscall(SYS_gettid));
+    while (futex_word.load() == 0) {
+      syscall(SYS_futex, &futex_word, FUTEX_WAIT_PRIVATE, 0, nullptr);
+    }
+  });
+
+  std::string stack;
+  for (int i = 0; i < 1000; i++) {
+    if (tid.load() != 0) {
+      stack = ASSERT_NO_ERRNO_AND_VALUE(GetContents(
+          absl::StrCat("/proc/self/task/", tid.load(), "/stack")));
+      if (absl::StrContains(stack, "futex_wait")) {
+        break;
+      }
+    }
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+  EXPECT_THAT(stack, HasSubstr("[<0>] futex_wait\n"));
+  EXPECT_THAT(stack, HasSubstr("[<0>] sys_futex\n"));
+
+  futex_word.store(1);
+  syscall(SYS_futex, &futex_word, FUTEX_WAKE_PRIVATE, 1);
+}
+
+TEST(ProcSysrqTrigger, DumpTasks) {
+  // Writing to the real /proc/sysrq-trigger affects the host.
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  EXPECT_NO_ERRNO(SetContents("/proc/sysrq-trigger", "l"));
+  EXPECT_NO_ERRNO(SetContents("/proc/sysrq-trigger", "t\n"));
+}
+
+TEST(ProcSysrqTrigger, UnsupportedKeyFails) {
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/sysrq-trigger", O_WRONLY));
+  EXPECT_THAT(WriteFd(fd.get(), "b", 1), SyscallFailsWithErrno(EPERM));
+}
+
 // Tests that /proc/[pid]/fd/[num] can resolve to a path inside /proc.
 TEST(Proc, ResolveSymlinkToProc) {
   const auto proc = ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/cmdline", 0));
@@ -13,11 +13,21 @@
 // limitations under the License.
 
 #include <errno.h>
+#include <signal.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <sys/sysinfo.h>
+#include <sys/wait.h>
+#include <unistd.h>
 
+#include <algorithm>
 #include <exception>
 #include <iostream>
 #include <string>
 
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
 #include "test/util/fs_util.h"
 #include "test/util/test_util.h"
 
@@ -66,6 +76,151 @@ TEST(ProcPidOomscoreAdjTest, BasicWrite) {
   EXPECT_EQ(oom_score, test_value);
 }
 
+TEST(ProcPidOomAdjTest, BasicRead) {
+  // VFS1 doesn't implement oom_adj.
+  SKIP_IF(IsRunningWithVFS1());
+
+  auto const oom_adj =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_adj"));
+  EXPECT_EQ(oom_adj, 0);
+}
+
+TEST(ProcPidOomAdjTest, WriteScalesScoreAdj) {
+  // VFS1 doesn't implement oom_adj.
+  SKIP_IF(IsRunningWithVFS1());
+  // Lowering the adjustment below its starting value requires
+  // CAP_SYS_RESOURCE on Linux.
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_RESOURCE)));
+
+  const struct {
+    int oom_adj;
+    int oom_score_adj;
+    // oom_adj is reported by scaling oom_score_adj back, which may round.
+    int read_oom_adj;
+  } kCases[] = {
+      {-17, -1000, -17},  // OOM_DISABLE
+      {-16, -941, -15},   // OOM_ADJUST_MIN
+      {15, 1000, 15},     // OOM_ADJUST_MAX
+      {0, 0, 0},
+  };
+  for (const auto& c : kCases) {
+    FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/oom_adj", O_WRONLY));
+    const std::string value = std::to_string(c.oom_adj);
+    ASSERT_THAT(RetryEINTR(write)(fd.get(), value.c_str(), value.size()),
+                SyscallSucceeds());
+    EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_adj")),
+              c.read_oom_adj);
+    EXPECT_EQ(
+        ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_score_adj")),
+        c.oom_score_adj);
+  }
+}
+
+TEST(ProcPidOomAdjTest, WriteOutOfRange) {
+  // VFS1 doesn't implement oom_adj.
+  SKIP_IF(IsRunningWithVFS1());
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/oom_adj", O_WRONLY));
+  EXPECT_THAT(RetryEINTR(write)(fd.get(), "16", 2),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(RetryEINTR(write)(fd.get(), "-18", 3),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(ProcPidOomscoreTest, ScoreAdjRaisesScore) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_RESOURCE)));
+
+  auto write_score_adj = [](int adj) {
+    FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/self/oom_score_adj", O_WRONLY));
+    const std::string value = std::to_string(adj);
+    ASSERT_THAT(RetryEINTR(write)(fd.get(), value.c_str(), value.size()),
+                SyscallSucceeds());
+  };
+
+  write_score_adj(0);
+  auto const base =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_score"));
+  write_score_adj(1000);
+  auto const raised =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_score"));
+  write_score_adj(-1000);
+  auto const disabled =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_score"));
+  write_score_adj(0);
+
+  EXPECT_GT(raised, base);
+  EXPECT_EQ(disabled, 0);
+}
+
+// StartChild forks a child that touches size bytes of anonymous memory and
+// then waits to be killed. It returns the child's PID once the memory is
+// resident.
+PosixErrorOr<pid_t> StartChild(size_t size) {
+  int fds[2];
+  if (pipe(fds) < 0) {
+    return PosixError(errno, "pipe");
+  }
+  const pid_t pid = fork();
+  if (pid == 0) {
+    close(fds[0]);
+    if (size > 0) {
+      void* addr = mmap(nullptr, size, PROT_READ | PROT_WRITE,
+                        MAP_PRIVATE | MAP_ANONYMOUS, -1, 0);
+      TEST_PCHECK(addr != MAP_FAILED);
+      memset(addr, 1, size);
+    }
+    char c = 0;
+    TEST_PCHECK(WriteFd(fds[1], &c, 1) == 1);
+    while (true) {
+      pause();
+    }
+  }
+  close(fds[1]);
+  if (pid < 0) {
+    close(fds[0]);
+    return PosixError(errno, "fork");
+  }
+  char c;
+  const int ret = ReadFd(fds[0], &c, 1);
+  close(fds[0]);
+  if (ret != 1) {
+    kill(pid, SIGKILL);
+    waitpid(pid, nullptr, 0);
+    return PosixError(EIO, "child failed to allocate memory");
+  }
+  return pid;
+}
+
+TEST(ProcPidOomscoreTest, ScoreReflectsMemoryUsage) {
+  struct sysinfo info;
+  ASSERT_THAT(sysinfo(&info), SyscallSucceeds());
+  const uint64_t total = static_cast<uint64_t>(info.totalram) * info.mem_unit;
+  // Use 2% of memory, which raises the score by about 13, but don't allocate
+  // an unreasonable amount on large hosts.
+  constexpr uint64_t kMaxSize = 1 << 30;
+  const size_t size = std::min(total / 50, kMaxSize);
+
+  const pid_t idle = ASSERT_NO_ERRNO_AND_VALUE(StartChild(0));
+  auto kill_idle = Cleanup([idle] {
+    kill(idle, SIGKILL);
+    waitpid(idle, nullptr, 0);
+  });
+  const pid_t busy = ASSERT_NO_ERRNO_AND_VALUE(StartChild(size));
+  auto kill_busy = Cleanup([busy] {
+    kill(busy, SIGKILL);
+    waitpid(busy, nullptr, 0);
+  });
+
+  auto const idle_score = ASSERT_NO_ERRNO_AND_VALUE(
+      ReadProcNumber(absl::StrCat("/proc/", idle, "/oom_score")));
+  auto const busy_score = ASSERT_NO_ERRNO_AND_VALUE(
+      ReadProcNumber(absl::StrCat("/proc/", busy, "/oom_score")));
+  EXPECT_GT(busy_score, idle_score);
+}
+
 }  // namespace
 
 }  // namespace testing
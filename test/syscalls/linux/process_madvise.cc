@@ -0,0 +1,320 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <limits.h>
+#include <sched.h>
+#include <signal.h>
+#include <sys/mman.h>
+#include <sys/syscall.h>
+#include <sys/uio.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#include <atomic>
+#include <cstring>
+#include <functional>
+#include <utility>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+#ifndef SYS_pidfd_open
+#define SYS_pidfd_open 434
+#endif
+
+#ifndef SYS_process_madvise
+#define SYS_process_madvise 440
+#endif
+
+#ifndef MADV_COLD
+#define MADV_COLD 20
+#endif
+
+#ifndef MADV_PAGEOUT
+#define MADV_PAGEOUT 21
+#endif
+
+int PidfdOpen(pid_t pid, unsigned int flags) {
+  return syscall(SYS_pidfd_open, pid, flags);
+}
+
+int ProcessMadvise(int pidfd, const struct iovec* iov, size_t vlen, int advice,
+                   unsigned int flags) {
+  return syscall(SYS_process_madvise, pidfd, iov, vlen, advice, flags);
+}
+
+// ForkIdleChild forks a child that waits until the returned release FD is
+// closed, then exits with status 0 if check, run in the child, returns true.
+PosixErrorOr<std::pair<pid_t, FileDescriptor>> ForkIdleChild(
+    const std::function<bool()>& check) {
+  int fds[2];
+  if (pipe(fds) < 0) {
+    return PosixError(errno, "pipe");
+  }
+  FileDescriptor rfd(fds[0]);
+  FileDescriptor wfd(fds[1]);
+
+  pid_t child = fork();
+  if (child == 0) {
+    wfd.reset();
+    char c;
+    TEST_PCHECK(read(rfd.get(), &c, 1) == 0);
+    _exit(check() ? 0 : 1);
+  }
+  if (child < 0) {
+    return PosixError(errno, "fork");
+  }
+  return std::make_pair(child, std::move(wfd));
+}
+
+// ReleaseAndWait releases a child forked by ForkIdleChild and checks that it
+// exits successfully.
+void ReleaseAndWait(pid_t child, FileDescriptor release) {
+  release.reset();
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status " << status;
+}
+
+class ProcessMadviseTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    // pidfds are only supported by VFS2.
+    SKIP_IF(IsRunningWithVFS1());
+    // process_madvise(2) was added in Linux 5.10.
+    SKIP_IF(!IsRunningOnGvisor() &&
+            ProcessMadvise(-1, nullptr, 0, MADV_COLD, 0) < 0 &&
+            errno == ENOSYS);
+  }
+};
+
+TEST_F(ProcessMadviseTest, PidfdOpenInvalid) {
+  EXPECT_THAT(PidfdOpen(getpid(), ~O_NONBLOCK), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(PidfdOpen(0, 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(PidfdOpen(-1, 0), SyscallFailsWithErrno(EINVAL));
+
+  // Reaped processes can't be opened.
+  pid_t child = fork();
+  if (child == 0) {
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_THAT(PidfdOpen(child, 0), SyscallFailsWithErrno(ESRCH));
+}
+
+TEST_F(ProcessMadviseTest, PidfdIsCloseOnExec) {
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(getpid(), 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+  EXPECT_THAT(fcntl(fd.get(), F_GETFD), SyscallSucceedsWithValue(FD_CLOEXEC));
+}
+
+TEST_F(ProcessMadviseTest, InvalidArguments) {
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  struct iovec iov = {m.ptr(), m.len()};
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(getpid(), 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_COLD, 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, IOV_MAX + 1, MADV_COLD, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Only advice that doesn't change the target's memory is allowed.
+  for (int advice : {MADV_DONTNEED, MADV_DONTFORK, MADV_NORMAL, 12345}) {
+    EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, advice, 0),
+                SyscallFailsWithErrno(EINVAL))
+        << "advice " << advice;
+  }
+
+  // Only pidfds are accepted.
+  const FileDescriptor devnull =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_RDONLY));
+  EXPECT_THAT(ProcessMadvise(devnull.get(), &iov, 1, MADV_COLD, 0),
+              SyscallFailsWithErrno(EBADF));
+  EXPECT_THAT(ProcessMadvise(-1, &iov, 1, MADV_COLD, 0),
+              SyscallFailsWithErrno(EBADF));
+}
+
+TEST_F(ProcessMadviseTest, NotSharedMemory) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_NICE)));
+
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(2 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  memset(m.ptr(), 'a', m.len());
+
+  // The child's copy of the mapping must be intact after it is paged out.
+  char* const addr = static_cast<char*>(m.ptr());
+  const size_t len = m.len();
+  auto [child, release] = ASSERT_NO_ERRNO_AND_VALUE(ForkIdleChild([=] {
+    for (size_t i = 0; i < len; i++) {
+      if (addr[i] != 'a') {
+        return false;
+      }
+    }
+    return true;
+  }));
+  memset(m.ptr(), 'b', m.len());
+
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(child, 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+  struct iovec iov = {m.ptr(), m.len()};
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_COLD, 0),
+              SyscallSucceedsWithValue(m.len()));
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_PAGEOUT, 0),
+              SyscallSucceedsWithValue(m.len()));
+
+  ReleaseAndWait(child, std::move(release));
+}
+
+TEST_F(ProcessMadviseTest, SharedMemory) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_NICE)));
+
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  memset(m.ptr(), 'a', m.len());
+  const Mapping stack = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(16 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+
+  // The child shares our address space, but is a separate process.
+  std::atomic<bool> done(false);
+  pid_t child = clone(
+      +[](void* arg) {
+        auto done = static_cast<std::atomic<bool>*>(arg);
+        while (!done->load()) {
+          sched_yield();
+        }
+        return 0;
+      },
+      reinterpret_cast<char*>(stack.ptr()) + stack.len(), CLONE_VM | SIGCHLD,
+      &done);
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int pidfd = PidfdOpen(child, 0);
+  EXPECT_THAT(pidfd, SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+  struct iovec iov = {m.ptr(), m.len()};
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_PAGEOUT, 0),
+              SyscallSucceedsWithValue(m.len()));
+
+  done.store(true);
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status " << status;
+
+  // Our data is unaffected.
+  const char* p = static_cast<const char*>(m.ptr());
+  for (size_t i = 0; i < m.len(); i++) {
+    ASSERT_EQ(p[i], 'a') << "offset " << i;
+  }
+}
+
+TEST_F(ProcessMadviseTest, PartialSuccess) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_NICE)));
+
+  // Create a hole in the middle of a mapping, which the child inherits.
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(3 * kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  char* const addr = static_cast<char*>(m.ptr());
+  ASSERT_THAT(munmap(addr + kPageSize, kPageSize), SyscallSucceeds());
+
+  auto [child, release] =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkIdleChild([] { return true; }));
+
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(child, 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+
+  // Advice stops at the first failing range; bytes advised before it are
+  // reported.
+  struct iovec iov[] = {
+      {addr, kPageSize},
+      {addr + kPageSize, 2 * kPageSize},
+      {addr + 2 * kPageSize, kPageSize},
+  };
+  EXPECT_THAT(ProcessMadvise(fd.get(), iov, 3, MADV_COLD, 0),
+              SyscallSucceedsWithValue(kPageSize));
+
+  // If the first range fails, its error is returned.
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov[1], 2, MADV_COLD, 0),
+              SyscallFailsWithErrno(ENOMEM));
+  struct iovec unaligned = {addr + 1, kPageSize};
+  EXPECT_THAT(ProcessMadvise(fd.get(), &unaligned, 1, MADV_COLD, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  ReleaseAndWait(child, std::move(release));
+}
+
+TEST_F(ProcessMadviseTest, RequiresCapSysNice) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_NICE)));
+
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  auto [child, release] =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkIdleChild([] { return true; }));
+
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(child, 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+  struct iovec iov = {m.ptr(), m.len()};
+  {
+    AutoCapability cap(CAP_SYS_NICE, false);
+    EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_COLD, 0),
+                SyscallFailsWithErrno(EPERM));
+  }
+
+  ReleaseAndWait(child, std::move(release));
+}
+
+TEST_F(ProcessMadviseTest, ExitedProcess) {
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  auto [child, release] =
+      ASSERT_NO_ERRNO_AND_VALUE(ForkIdleChild([] { return true; }));
+
+  int pidfd;
+  ASSERT_THAT(pidfd = PidfdOpen(child, 0), SyscallSucceeds());
+  const FileDescriptor fd(pidfd);
+  ASSERT_NO_FATAL_FAILURE(ReleaseAndWait(child, std::move(release)));
+
+  struct iovec iov = {m.ptr(), m.len()};
+  EXPECT_THAT(ProcessMadvise(fd.get(), &iov, 1, MADV_COLD, 0),
+              SyscallFailsWithErrno(ESRCH));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
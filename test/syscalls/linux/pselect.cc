@@ -16,10 +16,12 @@
 #include <sys/select.h>
 
 #include "gtest/gtest.h"
+#include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/syscalls/linux/base_poll_test.h"
 #include "test/util/signal_util.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
 
 namespace gvisor {
 namespace testing {
@@ -185,6 +187,42 @@ TEST_F(PselectTest, SignalMaskAllowsSignal) {
   EXPECT_GT(absl::DurationFromTimespec(timeout), absl::Duration());
 }
 
+// Verify that a save/restore while pselect is blocked preserves both the
+// temporary signal mask and the remaining timeout: the signal blocked by the
+// pselect mask must not interrupt it after restore, pselect must not return
+// before the original timeout has elapsed, and the original mask must be
+// reinstated on return.
+TEST_F(PselectTest, SignalMaskAndTimeoutSurviveSave) {
+  absl::Duration duration = absl::Seconds(2);
+  struct timespec timeout = absl::ToTimespec(duration);
+
+  sigset_t mask;
+  ASSERT_THAT(sigprocmask(0, nullptr, &mask), SyscallSucceeds());
+  ASSERT_EQ(sigismember(&mask, SIGALRM), 0);
+  ASSERT_THAT(sigaddset(&mask, SIGALRM), SyscallSucceeds());
+  MaskWithSize mask_with_size = {&mask, kSigsetSize};
+
+  // The timer fires after the save, while SIGALRM is blocked only by the
+  // pselect mask.
+  SetTimer(absl::Milliseconds(500));
+  ScopedThread saver([] {
+    absl::SleepFor(absl::Milliseconds(250));
+    MaybeSave();
+  });
+
+  absl::Time start = absl::Now();
+  ASSERT_THAT(
+      syscallPselect6(1, nullptr, nullptr, nullptr, &timeout, &mask_with_size),
+      SyscallSucceedsWithValue(0));
+  EXPECT_GE(absl::Now() - start, duration);
+  EXPECT_EQ(absl::DurationFromTimespec(timeout), absl::Duration());
+  EXPECT_TRUE(TimerFired());
+
+  sigset_t after;
+  ASSERT_THAT(sigprocmask(0, nullptr, &after), SyscallSucceeds());
+  EXPECT_EQ(sigismember(&after, SIGALRM), 0);
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
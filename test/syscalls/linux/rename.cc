@@ -14,6 +14,8 @@
 
 #include <fcntl.h>
 #include <stdio.h>
+#include <sys/stat.h>
+#include <unistd.h>
 
 #include <string>
 
@@ -454,6 +456,10 @@ TEST(RenameTest, SysfsDirectoryToSelf) {
 #define RENAME_NOREPLACE (1 << 0)
 #endif  // RENAME_NOREPLACE
 
+#ifndef RENAME_EXCHANGE
+#define RENAME_EXCHANGE (1 << 1)
+#endif  // RENAME_EXCHANGE
+
 int renameat2(int olddirfd, const char* oldpath, int newdirfd,
               const char* newpath, unsigned int flags) {
   return syscall(SYS_renameat2, olddirfd, oldpath, newdirfd, newpath, flags);
@@ -494,6 +500,114 @@ TEST(Renameat2Test, NoReplaceDot) {
       SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL, EEXIST)));
 }
 
+TEST(Renameat2Test, ExchangeWithNoReplaceFails) {
+  auto f1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  auto f2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  EXPECT_THAT(renameat2(AT_FDCWD, f1.path().c_str(), AT_FDCWD,
+                        f2.path().c_str(), RENAME_EXCHANGE | RENAME_NOREPLACE),
+              SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL)));
+}
+
+TEST(Renameat2Test, ExchangeFileAndDirectoryAcrossDirectories) {
+  // Directory link counts are synthetic on overlay filesystems.
+  SKIP_IF(ASSERT_NO_ERRNO_AND_VALUE(IsOverlayfs(GetAbsoluteTestTmpdir())));
+
+  auto parent1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto parent2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(parent1.path(), "contents", 0644));
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent2.path()));
+  const std::string child = JoinPath(dir.path(), "child");
+  ASSERT_NO_ERRNO(Open(child, O_CREAT | O_WRONLY, 0644));
+
+  EXPECT_THAT(Links(parent1.path()), IsPosixErrorOkAndHolds(2));
+  EXPECT_THAT(Links(parent2.path()), IsPosixErrorOkAndHolds(3));
+
+  // renameat2 may fail with ENOSYS (if the syscall is unsupported) or EINVAL
+  // (if RENAME_EXCHANGE is unsupported by the filesystem).
+  int ret = renameat2(AT_FDCWD, file.path().c_str(), AT_FDCWD,
+                      dir.path().c_str(), RENAME_EXCHANGE);
+  if (ret < 0 && (errno == ENOSYS || errno == EINVAL)) {
+    GTEST_SKIP() << "RENAME_EXCHANGE is not supported";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  // The directory, with its contents, is now at the file's old path, and vice
+  // versa.
+  struct stat st = ASSERT_NO_ERRNO_AND_VALUE(Stat(file.path()));
+  EXPECT_TRUE(S_ISDIR(st.st_mode));
+  EXPECT_NO_ERRNO(Stat(JoinPath(file.path(), "child")));
+  st = ASSERT_NO_ERRNO_AND_VALUE(Stat(dir.path()));
+  EXPECT_TRUE(S_ISREG(st.st_mode));
+  std::string contents;
+  ASSERT_NO_ERRNO(GetContents(dir.path(), &contents));
+  EXPECT_EQ("contents", contents);
+
+  // The directory's ".." link moved from parent2 to parent1.
+  EXPECT_THAT(Links(parent1.path()), IsPosixErrorOkAndHolds(3));
+  EXPECT_THAT(Links(parent2.path()), IsPosixErrorOkAndHolds(2));
+  struct stat dotdot =
+      ASSERT_NO_ERRNO_AND_VALUE(Stat(JoinPath(file.path(), "..")));
+  struct stat p1 = ASSERT_NO_ERRNO_AND_VALUE(Stat(parent1.path()));
+  EXPECT_EQ(dotdot.st_ino, p1.st_ino);
+}
+
+TEST(Renameat2Test, ExchangeFileAndSymlink) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(dir.path(), "contents", 0644));
+  const std::string link = JoinPath(dir.path(), "link");
+  ASSERT_THAT(symlink("target", link.c_str()), SyscallSucceeds());
+
+  int ret = renameat2(AT_FDCWD, file.path().c_str(), AT_FDCWD, link.c_str(),
+                      RENAME_EXCHANGE);
+  if (ret < 0 && (errno == ENOSYS || errno == EINVAL)) {
+    GTEST_SKIP() << "RENAME_EXCHANGE is not supported";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+
+  EXPECT_THAT(ReadLink(file.path()), IsPosixErrorOkAndHolds("target"));
+  std::string contents;
+  ASSERT_NO_ERRNO(GetContents(link, &contents));
+  EXPECT_EQ("contents", contents);
+}
+
+TEST(Renameat2Test, ExchangeWithNonemptyDirectory) {
+  // Exchange doesn't replace either file, so non-empty directories are fine.
+  auto dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto dir2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string child = JoinPath(dir2.path(), "child");
+  ASSERT_NO_ERRNO(Open(child, O_CREAT | O_WRONLY, 0644));
+
+  int ret = renameat2(AT_FDCWD, dir1.path().c_str(), AT_FDCWD,
+                      dir2.path().c_str(), RENAME_EXCHANGE);
+  if (ret < 0 && (errno == ENOSYS || errno == EINVAL)) {
+    GTEST_SKIP() << "RENAME_EXCHANGE is not supported";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+  EXPECT_NO_ERRNO(Stat(JoinPath(dir1.path(), "child")));
+}
+
+TEST(Renameat2Test, ExchangeWithAncestorFails) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto subdir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir.path()));
+
+  EXPECT_THAT(renameat2(AT_FDCWD, dir.path().c_str(), AT_FDCWD,
+                        subdir.path().c_str(), RENAME_EXCHANGE),
+              SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL)));
+  EXPECT_THAT(renameat2(AT_FDCWD, subdir.path().c_str(), AT_FDCWD,
+                        dir.path().c_str(), RENAME_EXCHANGE),
+              SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL)));
+}
+
+TEST(Renameat2Test, ExchangeNonexistentFails) {
+  auto f = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  std::string const newpath = NewTempAbsPath();
+  EXPECT_THAT(renameat2(AT_FDCWD, f.path().c_str(), AT_FDCWD, newpath.c_str(),
+                        RENAME_EXCHANGE),
+              SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL, ENOENT)));
+}
+
 }  // namespace
 
 }  // namespace testing
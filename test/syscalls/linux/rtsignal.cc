@@ -12,19 +12,24 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <sched.h>
 #include <sys/syscall.h>
 #include <sys/types.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
+#include <atomic>
 #include <cerrno>
 #include <csignal>
 
 #include "gtest/gtest.h"
+#include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
 #include "test/util/logging.h"
 #include "test/util/posix_error.h"
 #include "test/util/signal_util.h"
 #include "test/util/test_util.h"
+#include "test/util/thread_util.h"
 
 namespace gvisor {
 namespace testing {
@@ -121,6 +126,99 @@ TEST_F(RtSignalTest, InvalidCodes) {
   EXPECT_THAT(waitpid(child, nullptr, 0), SyscallSucceeds());
 }
 
+TEST_F(RtSignalTest, InvalidCodesSiblingThread) {
+  // Sending to another thread in the same thread group is not sending to
+  // self, so the code checks apply.
+  std::atomic<pid_t> tid(0);
+  std::atomic<bool> done(false);
+  ScopedThread thread([&] {
+    tid.store(syscall(SYS_gettid));
+    while (!done.load()) {
+      sched_yield();
+    }
+  });
+  while (tid.load() == 0) {
+    sched_yield();
+  }
+
+  siginfo_t uinfo = {};
+  uinfo.si_code = 0x80;  // SI_KERNEL.
+  uinfo.si_pid = 1;
+  EXPECT_THAT(syscall(SYS_rt_tgsigqueueinfo, getpid(), tid.load(), SIGUSR1,
+                      &uinfo),
+              SyscallFailsWithErrno(EPERM));
+  uinfo.si_code = 0;  // SI_USER.
+  EXPECT_THAT(syscall(SYS_rt_tgsigqueueinfo, getpid(), tid.load(), SIGUSR1,
+                      &uinfo),
+              SyscallFailsWithErrno(EPERM));
+  uinfo.si_code = -6;  // SI_TKILL.
+  EXPECT_THAT(syscall(SYS_rt_tgsigqueueinfo, getpid(), tid.load(), SIGUSR1,
+                      &uinfo),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_FALSE(has_saved_info);
+
+  done.store(true);
+}
+
+TEST_F(RtSignalTest, KillFromThreadReportsThreadGroupID) {
+  // si_pid for kill(2) is the sender's thread group ID, even when sent from a
+  // thread other than the leader.
+  ScopedThread thread([] { TEST_PCHECK(kill(getpid(), SIGUSR2) == 0); });
+  thread.Join();
+
+  sigset_t set;
+  sigemptyset(&set);
+  sigaddset(&set, SIGUSR2);
+  siginfo_t info;
+  struct timespec timeout = {10, 0};
+  ASSERT_THAT(sigtimedwait(&set, &info, &timeout),
+              SyscallSucceedsWithValue(SIGUSR2));
+  EXPECT_EQ(info.si_code, SI_USER);
+  EXPECT_EQ(info.si_pid, getpid());
+}
+
+TEST(RtSignalNamespaceTest, SenderInAncestorPIDNamespace) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  // SIGUSR2 is blocked (see main), so the grandchild can wait for it below.
+  pid_t child = fork();
+  if (child == 0) {
+    TEST_PCHECK(unshare(CLONE_NEWPID) == 0);
+    pid_t grandchild = fork();
+    if (grandchild == 0) {
+      sigset_t set;
+      sigemptyset(&set);
+      sigaddset(&set, SIGUSR2);
+      siginfo_t info;
+      struct timespec timeout = {10, 0};
+      TEST_PCHECK(sigtimedwait(&set, &info, &timeout) == SIGUSR2);
+      TEST_CHECK(info.si_code == SI_QUEUE);
+      // The sender is not visible in this PID namespace, so the si_pid it
+      // supplied must not be passed through.
+      TEST_CHECK(info.si_pid == 0);
+      _exit(0);
+    }
+    TEST_PCHECK(grandchild > 0);
+
+    siginfo_t uinfo = {};
+    uinfo.si_code = SI_QUEUE;
+    uinfo.si_pid = getpid();
+    uinfo.si_uid = getuid();
+    TEST_PCHECK(rt_sigqueueinfo(grandchild, SIGUSR2, &uinfo) == 0);
+
+    int status;
+    TEST_PCHECK(waitpid(grandchild, &status, 0) == grandchild);
+    TEST_CHECK(WIFEXITED(status) && WEXITSTATUS(status) == 0);
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+}
+
 TEST_F(RtSignalTest, ValueDelivered) {
   siginfo_t uinfo;
   uinfo.si_code = -1;  // SI_QUEUE (allowed).
@@ -0,0 +1,702 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <arpa/inet.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <linux/netlink.h>
+#include <netinet/in.h>
+#include <stddef.h>
+#include <sys/mman.h>
+#include <sys/socket.h>
+#include <sys/un.h>
+#include <unistd.h>
+
+#include <cstring>
+#include <functional>
+#include <string>
+#include <utility>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/socket_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+// Tests for the address and address length copied out by accept(2),
+// accept4(2), getsockname(2), getpeername(2) and recvfrom(2).
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// kPoison is used to fill address buffers, to detect bytes written beyond the
+// buffer length passed by the caller.
+constexpr unsigned char kPoison = 0xa5;
+
+// AddressCall performs a syscall that copies a socket address out to addr and
+// its length to *addrlen, like getsockname(2). It returns -1 and sets errno on
+// failure.
+using AddressCall = std::function<int(sockaddr*, socklen_t*)>;
+
+// CheckAddressLength calls call with buffers of various sizes, and verifies
+// that the full address length want_len is always reported, and that the
+// address is truncated to the size of the buffer without writing past it.
+//
+// The full address is returned in *full.
+void CheckAddressLength(AddressCall const& call, socklen_t want_len,
+                        sockaddr_storage* full) {
+  memset(full, kPoison, sizeof(*full));
+  socklen_t len = sizeof(*full);
+  ASSERT_THAT(call(AsSockAddr(full), &len), SyscallSucceeds());
+  ASSERT_EQ(len, want_len);
+
+  for (socklen_t size : {socklen_t{0}, socklen_t{1}, want_len - 1, want_len}) {
+    if (size > want_len) {
+      // want_len is 0.
+      continue;
+    }
+    SCOPED_TRACE(absl::StrCat("size = ", size));
+
+    sockaddr_storage buf;
+    memset(&buf, kPoison, sizeof(buf));
+    len = size;
+    ASSERT_THAT(call(AsSockAddr(&buf), &len), SyscallSucceeds());
+    EXPECT_EQ(len, want_len);
+
+    // Only size bytes of the address may be copied out.
+    EXPECT_EQ(memcmp(&buf, full, size), 0);
+    auto const* bytes = reinterpret_cast<unsigned char const*>(&buf);
+    for (size_t i = size; i < sizeof(buf); i++) {
+      ASSERT_EQ(bytes[i], kPoison) << "byte " << i << " was overwritten";
+    }
+  }
+}
+
+// GetSockNameCall returns an AddressCall for getsockname(2) on fd.
+AddressCall GetSockNameCall(int fd) {
+  return [fd](sockaddr* addr, socklen_t* addrlen) {
+    return getsockname(fd, addr, addrlen);
+  };
+}
+
+// GetPeerNameCall returns an AddressCall for getpeername(2) on fd.
+AddressCall GetPeerNameCall(int fd) {
+  return [fd](sockaddr* addr, socklen_t* addrlen) {
+    return getpeername(fd, addr, addrlen);
+  };
+}
+
+// AcceptCall returns an AddressCall that connects a new socket created by
+// make_client to the listening socket listener, and then accepts the
+// connection with accept(2), or accept4(2) if use_accept4 is true.
+AddressCall AcceptCall(int listener, std::function<int()> make_client,
+                       sockaddr const* listener_addr, socklen_t listener_len,
+                       bool use_accept4) {
+  return [=](sockaddr* addr, socklen_t* addrlen) {
+    int client = make_client();
+    if (client < 0) {
+      return -1;
+    }
+    auto close_client = Cleanup([client] { close(client); });
+    if (connect(client, listener_addr, listener_len) < 0) {
+      return -1;
+    }
+    int fd = use_accept4 ? accept4(listener, addr, addrlen, SOCK_CLOEXEC)
+                         : accept(listener, addr, addrlen);
+    if (fd < 0) {
+      return -1;
+    }
+    close(fd);
+    return 0;
+  };
+}
+
+// RecvFromCall returns an AddressCall that sends a datagram from sender to
+// the socket bound to receiver_addr, and then receives it on receiver with
+// recvfrom(2).
+AddressCall RecvFromCall(int receiver, int sender,
+                         sockaddr const* receiver_addr,
+                         socklen_t receiver_len) {
+  return [=](sockaddr* addr, socklen_t* addrlen) {
+    char c = 'x';
+    if (sendto(sender, &c, sizeof(c), 0, receiver_addr, receiver_len) < 0) {
+      return -1;
+    }
+    if (RetryEINTR(recvfrom)(receiver, &c, sizeof(c), 0, addr, addrlen) < 0) {
+      return -1;
+    }
+    return 0;
+  };
+}
+
+// UnixAddress is an AF_UNIX address and the length to bind it with.
+struct UnixAddress {
+  sockaddr_un addr;
+  socklen_t len;
+};
+
+// UnixAddressGenerator generates distinct filesystem or abstract AF_UNIX
+// addresses that all have the same length. Filesystem addresses are unlinked
+// when the generator is destroyed.
+class UnixAddressGenerator {
+ public:
+  explicit UnixAddressGenerator(bool abstract)
+      : abstract_(abstract), base_(NewTempAbsPathInDir("/tmp")) {}
+
+  ~UnixAddressGenerator() {
+    if (!abstract_) {
+      for (int i = 0; i < next_; i++) {
+        unlink(Path(i).c_str());
+      }
+    }
+  }
+
+  // Len returns the length of all generated addresses, including the
+  // terminating NUL of filesystem addresses.
+  socklen_t Len() const {
+    return offsetof(sockaddr_un, sun_path) + Path(0).size() + !abstract_;
+  }
+
+  UnixAddress Next() {
+    std::string const path = Path(next_++);
+    UnixAddress ua = {};
+    ua.addr.sun_family = AF_UNIX;
+    memcpy(ua.addr.sun_path, path.data(), path.size());
+    if (abstract_) {
+      ua.addr.sun_path[0] = 0;
+    }
+    ua.len = Len();
+    return ua;
+  }
+
+ private:
+  std::string Path(int i) const {
+    return absl::StrCat(base_, "_", absl::Dec(i, absl::kZeroPad4));
+  }
+
+  bool const abstract_;
+  std::string const base_;
+  int next_ = 0;
+};
+
+// UnixListener returns a listening AF_UNIX stream socket bound to addr.
+PosixErrorOr<FileDescriptor> UnixListener(UnixAddress const& addr) {
+  ASSIGN_OR_RETURN_ERRNO(FileDescriptor fd, Socket(AF_UNIX, SOCK_STREAM, 0));
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      bind(fd.get(), reinterpret_cast<sockaddr const*>(&addr.addr), addr.len));
+  RETURN_ERROR_IF_SYSCALL_FAIL(listen(fd.get(), 5));
+  return std::move(fd);
+}
+
+// BoundUnixSocket returns a function that creates AF_UNIX sockets of the given
+// type bound to addresses generated by gen.
+std::function<int()> BoundUnixSocket(UnixAddressGenerator* gen, int type) {
+  return [gen, type]() {
+    UnixAddress addr = gen->Next();
+    int fd = socket(AF_UNIX, type, 0);
+    if (fd >= 0 && bind(fd, AsSockAddr(&addr.addr), addr.len) < 0) {
+      int saved_errno = errno;
+      close(fd);
+      errno = saved_errno;
+      return -1;
+    }
+    return fd;
+  };
+}
+
+int UnixStreamSocket() { return socket(AF_UNIX, SOCK_STREAM, 0); }
+
+void CheckUnixNamed(bool abstract) {
+  UnixAddressGenerator listener_gen(abstract);
+  UnixAddress listener_addr = listener_gen.Next();
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(UnixListener(listener_addr));
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(listener.get()),
+                                             listener_addr.len, &full));
+  EXPECT_EQ(memcmp(&full, &listener_addr.addr, listener_addr.len), 0);
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&listener_addr.addr),
+                      listener_addr.len),
+              SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(client.get()),
+                                             listener_addr.len, &full));
+  EXPECT_EQ(memcmp(&full, &listener_addr.addr, listener_addr.len), 0);
+
+  // Accepted connections report the address the client is bound to.
+  UnixAddressGenerator client_gen(abstract);
+  for (bool use_accept4 : {false, true}) {
+    SCOPED_TRACE(absl::StrCat("use_accept4 = ", use_accept4));
+    ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+        AcceptCall(listener.get(), BoundUnixSocket(&client_gen, SOCK_STREAM),
+                   AsSockAddr(&listener_addr.addr), listener_addr.len,
+                   use_accept4),
+        client_gen.Len(), &full));
+    auto const* peer = reinterpret_cast<sockaddr_un*>(&full);
+    EXPECT_EQ(peer->sun_family, AF_UNIX);
+    if (abstract) {
+      // Abstract addresses keep their leading NUL.
+      EXPECT_EQ(peer->sun_path[0], 0);
+    } else {
+      EXPECT_EQ(peer->sun_path[client_gen.Len() -
+                               offsetof(sockaddr_un, sun_path) - 1],
+                0);
+    }
+  }
+
+  // The accepted socket is named after the listener, and its peer after the
+  // client.
+  UnixAddress client_addr = client_gen.Next();
+  FileDescriptor bound_client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(
+      bind(bound_client.get(), AsSockAddr(&client_addr.addr), client_addr.len),
+      SyscallSucceeds());
+  ASSERT_THAT(connect(bound_client.get(), AsSockAddr(&listener_addr.addr),
+                      listener_addr.len),
+              SyscallSucceeds());
+  FileDescriptor accepted =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listener.get(), nullptr, nullptr));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(accepted.get()),
+                                             listener_addr.len, &full));
+  EXPECT_EQ(memcmp(&full, &listener_addr.addr, listener_addr.len), 0);
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(accepted.get()),
+                                             client_addr.len, &full));
+  EXPECT_EQ(memcmp(&full, &client_addr.addr, client_addr.len), 0);
+
+  // So do datagrams.
+  UnixAddressGenerator receiver_gen(abstract);
+  UnixAddress receiver_addr = receiver_gen.Next();
+  FileDescriptor receiver =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_DGRAM, 0));
+  ASSERT_THAT(bind(receiver.get(), AsSockAddr(&receiver_addr.addr),
+                   receiver_addr.len),
+              SyscallSucceeds());
+  UnixAddressGenerator sender_gen(abstract);
+  UnixAddress sender_addr = sender_gen.Next();
+  FileDescriptor sender =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_DGRAM, 0));
+  ASSERT_THAT(
+      bind(sender.get(), AsSockAddr(&sender_addr.addr), sender_addr.len),
+      SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      RecvFromCall(receiver.get(), sender.get(),
+                   AsSockAddr(&receiver_addr.addr), receiver_addr.len),
+      sender_addr.len, &full));
+  EXPECT_EQ(memcmp(&full, &sender_addr.addr, sender_addr.len), 0);
+}
+
+TEST(SocketAddressLengthTest, UnixFilesystem) {
+  CheckUnixNamed(false /* abstract */);
+}
+
+// Abstract addresses keep their leading NUL, and aren't NUL-terminated.
+TEST(SocketAddressLengthTest, UnixAbstract) {
+  CheckUnixNamed(true /* abstract */);
+}
+
+// A filesystem path that fills sun_path is reported with the length it would
+// have with a terminating NUL, i.e. longer than struct sockaddr_un.
+TEST(SocketAddressLengthTest, UnixFilesystemMaxLength) {
+  sockaddr_un addr = {};
+  addr.sun_family = AF_UNIX;
+  std::string path = NewTempAbsPathInDir("/tmp");
+  ASSERT_LT(path.size(), sizeof(addr.sun_path));
+  path.resize(sizeof(addr.sun_path), 'x');
+  memcpy(addr.sun_path, path.data(), path.size());
+
+  FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(bind(sock.get(), AsSockAddr(&addr), sizeof(addr)),
+              SyscallSucceeds());
+  auto unlink_path = Cleanup([&path] { unlink(path.c_str()); });
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      GetSockNameCall(sock.get()), sizeof(addr) + 1, &full));
+  EXPECT_EQ(memcmp(&full, &addr, sizeof(addr)), 0);
+}
+
+// Abstract names are arbitrary bytes, so one that fills sun_path is reported
+// with exactly the length of struct sockaddr_un, and embedded NULs are kept.
+TEST(SocketAddressLengthTest, UnixAbstractMaxLength) {
+  sockaddr_un addr = {};
+  addr.sun_family = AF_UNIX;
+  std::string name = NewTempAbsPathInDir("/tmp");
+  ASSERT_LT(name.size(), sizeof(addr.sun_path));
+  name.resize(sizeof(addr.sun_path), '\0');
+  name[0] = '\0';
+  name.back() = 'x';
+  memcpy(addr.sun_path, name.data(), name.size());
+
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&addr), sizeof(addr)),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(listener.get()),
+                                             sizeof(addr), &full));
+  EXPECT_EQ(memcmp(&full, &addr, sizeof(addr)), 0);
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&addr), sizeof(addr)),
+              SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(client.get()),
+                                             sizeof(addr), &full));
+  EXPECT_EQ(memcmp(&full, &addr, sizeof(addr)), 0);
+}
+
+TEST(SocketAddressLengthTest, UnixUnnamed) {
+  int fds[2];
+  ASSERT_THAT(socketpair(AF_UNIX, SOCK_STREAM, 0, fds), SyscallSucceeds());
+  FileDescriptor first(fds[0]);
+  FileDescriptor second(fds[1]);
+
+  // Unnamed addresses consist of only the address family.
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(first.get()),
+                                             sizeof(sa_family_t), &full));
+  EXPECT_EQ(full.ss_family, AF_UNIX);
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(first.get()),
+                                             sizeof(sa_family_t), &full));
+  EXPECT_EQ(full.ss_family, AF_UNIX);
+
+  UnixAddressGenerator gen(true /* abstract */);
+  UnixAddress listener_addr = gen.Next();
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(UnixListener(listener_addr));
+  for (bool use_accept4 : {false, true}) {
+    SCOPED_TRACE(absl::StrCat("use_accept4 = ", use_accept4));
+    ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+        AcceptCall(listener.get(), UnixStreamSocket,
+                   AsSockAddr(&listener_addr.addr), listener_addr.len,
+                   use_accept4),
+        sizeof(sa_family_t), &full));
+    EXPECT_EQ(full.ss_family, AF_UNIX);
+  }
+
+  // Datagrams from unnamed sockets have no address at all.
+  UnixAddress receiver_addr = gen.Next();
+  FileDescriptor receiver =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_DGRAM, 0));
+  ASSERT_THAT(bind(receiver.get(), AsSockAddr(&receiver_addr.addr),
+                   receiver_addr.len),
+              SyscallSucceeds());
+  FileDescriptor sender =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_DGRAM, 0));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      RecvFromCall(receiver.get(), sender.get(),
+                   AsSockAddr(&receiver_addr.addr), receiver_addr.len),
+      0, &full));
+}
+
+TEST(SocketAddressLengthTest, Inet) {
+  sockaddr_in listener_addr = {};
+  listener_addr.sin_family = AF_INET;
+  listener_addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  socklen_t listener_len = sizeof(listener_addr);
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&listener_addr), listener_len),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(listener.get()),
+                                             sizeof(sockaddr_in), &full));
+  EXPECT_EQ(full.ss_family, AF_INET);
+  memcpy(&listener_addr, &full, sizeof(listener_addr));
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&listener_addr), listener_len),
+              SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(client.get()),
+                                             sizeof(sockaddr_in), &full));
+  EXPECT_EQ(memcmp(&full, &listener_addr, listener_len), 0);
+
+  for (bool use_accept4 : {false, true}) {
+    SCOPED_TRACE(absl::StrCat("use_accept4 = ", use_accept4));
+    ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+        AcceptCall(
+            listener.get(), [] { return socket(AF_INET, SOCK_STREAM, 0); },
+            AsSockAddr(&listener_addr), listener_len, use_accept4),
+        sizeof(sockaddr_in), &full));
+    EXPECT_EQ(full.ss_family, AF_INET);
+  }
+
+  FileDescriptor receiver =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  sockaddr_in receiver_addr = {};
+  receiver_addr.sin_family = AF_INET;
+  receiver_addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  socklen_t receiver_len = sizeof(receiver_addr);
+  ASSERT_THAT(bind(receiver.get(), AsSockAddr(&receiver_addr), receiver_len),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(receiver.get(), AsSockAddr(&receiver_addr), &receiver_len),
+      SyscallSucceeds());
+  FileDescriptor sender =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      RecvFromCall(receiver.get(), sender.get(), AsSockAddr(&receiver_addr),
+                   receiver_len),
+      sizeof(sockaddr_in), &full));
+  EXPECT_EQ(full.ss_family, AF_INET);
+}
+
+TEST(SocketAddressLengthTest, Inet6) {
+  sockaddr_in6 listener_addr = {};
+  listener_addr.sin6_family = AF_INET6;
+  listener_addr.sin6_addr = in6addr_loopback;
+  socklen_t listener_len = sizeof(listener_addr);
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_STREAM, 0));
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&listener_addr), listener_len),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(listener.get()),
+                                             sizeof(sockaddr_in6), &full));
+  EXPECT_EQ(full.ss_family, AF_INET6);
+  memcpy(&listener_addr, &full, sizeof(listener_addr));
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&listener_addr), listener_len),
+              SyscallSucceeds());
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(client.get()),
+                                             sizeof(sockaddr_in6), &full));
+  EXPECT_EQ(memcmp(&full, &listener_addr, listener_len), 0);
+
+  for (bool use_accept4 : {false, true}) {
+    SCOPED_TRACE(absl::StrCat("use_accept4 = ", use_accept4));
+    ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+        AcceptCall(
+            listener.get(), [] { return socket(AF_INET6, SOCK_STREAM, 0); },
+            AsSockAddr(&listener_addr), listener_len, use_accept4),
+        sizeof(sockaddr_in6), &full));
+    auto const* peer = reinterpret_cast<sockaddr_in6*>(&full);
+    EXPECT_EQ(peer->sin6_family, AF_INET6);
+    EXPECT_TRUE(IN6_IS_ADDR_LOOPBACK(&peer->sin6_addr));
+  }
+
+  FileDescriptor receiver =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_DGRAM, 0));
+  sockaddr_in6 receiver_addr = {};
+  receiver_addr.sin6_family = AF_INET6;
+  receiver_addr.sin6_addr = in6addr_loopback;
+  socklen_t receiver_len = sizeof(receiver_addr);
+  ASSERT_THAT(bind(receiver.get(), AsSockAddr(&receiver_addr), receiver_len),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(receiver.get(), AsSockAddr(&receiver_addr), &receiver_len),
+      SyscallSucceeds());
+  FileDescriptor sender =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_DGRAM, 0));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      RecvFromCall(receiver.get(), sender.get(), AsSockAddr(&receiver_addr),
+                   receiver_len),
+      sizeof(sockaddr_in6), &full));
+  auto const* from = reinterpret_cast<sockaddr_in6*>(&full);
+  EXPECT_EQ(from->sin6_family, AF_INET6);
+  EXPECT_TRUE(IN6_IS_ADDR_LOOPBACK(&from->sin6_addr));
+}
+
+// IPv4 peers of dual-stack IPv6 sockets are reported as v4-mapped IPv6
+// addresses.
+TEST(SocketAddressLengthTest, Inet6V4Mapped) {
+  sockaddr_in6 listener_addr = {};
+  listener_addr.sin6_family = AF_INET6;
+  listener_addr.sin6_addr = in6addr_any;
+  socklen_t listener_len = sizeof(listener_addr);
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_STREAM, 0));
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&listener_addr), listener_len),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), 5), SyscallSucceeds());
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(listener.get()),
+                                             sizeof(sockaddr_in6), &full));
+  EXPECT_EQ(full.ss_family, AF_INET6);
+
+  sockaddr_in connect_addr = {};
+  connect_addr.sin_family = AF_INET;
+  connect_addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  connect_addr.sin_port = reinterpret_cast<sockaddr_in6*>(&full)->sin6_port;
+
+  for (bool use_accept4 : {false, true}) {
+    SCOPED_TRACE(absl::StrCat("use_accept4 = ", use_accept4));
+    ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+        AcceptCall(
+            listener.get(), [] { return socket(AF_INET, SOCK_STREAM, 0); },
+            AsSockAddr(&connect_addr), sizeof(connect_addr), use_accept4),
+        sizeof(sockaddr_in6), &full));
+    auto const* peer = reinterpret_cast<sockaddr_in6*>(&full);
+    EXPECT_EQ(peer->sin6_family, AF_INET6);
+    EXPECT_TRUE(IN6_IS_ADDR_V4MAPPED(&peer->sin6_addr));
+    EXPECT_EQ(memcmp(&peer->sin6_addr.s6_addr[12], &connect_addr.sin_addr,
+                     sizeof(connect_addr.sin_addr)),
+              0);
+    EXPECT_EQ(peer->sin6_flowinfo, 0u);
+    EXPECT_EQ(peer->sin6_scope_id, 0u);
+  }
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&connect_addr),
+                      sizeof(connect_addr)),
+              SyscallSucceeds());
+  sockaddr_in client_addr = {};
+  socklen_t client_len = sizeof(client_addr);
+  ASSERT_THAT(getsockname(client.get(), AsSockAddr(&client_addr), &client_len),
+              SyscallSucceeds());
+
+  // accept(2) reports the same v4-mapped address as getpeername(2).
+  sockaddr_storage accept_addr;
+  socklen_t accept_len = sizeof(accept_addr);
+  FileDescriptor accepted = ASSERT_NO_ERRNO_AND_VALUE(
+      Accept(listener.get(), AsSockAddr(&accept_addr), &accept_len));
+  ASSERT_EQ(accept_len, sizeof(sockaddr_in6));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(accepted.get()),
+                                             sizeof(sockaddr_in6), &full));
+  EXPECT_EQ(memcmp(&full, &accept_addr, sizeof(sockaddr_in6)), 0);
+  auto const* peer = reinterpret_cast<sockaddr_in6*>(&full);
+  EXPECT_TRUE(IN6_IS_ADDR_V4MAPPED(&peer->sin6_addr));
+  EXPECT_EQ(memcmp(&peer->sin6_addr.s6_addr[12], &client_addr.sin_addr,
+                   sizeof(client_addr.sin_addr)),
+            0);
+  EXPECT_EQ(peer->sin6_port, client_addr.sin_port);
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(accepted.get()),
+                                             sizeof(sockaddr_in6), &full));
+  EXPECT_TRUE(IN6_IS_ADDR_V4MAPPED(
+      &reinterpret_cast<sockaddr_in6*>(&full)->sin6_addr));
+
+  FileDescriptor receiver =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET6, SOCK_DGRAM, 0));
+  sockaddr_in6 receiver_addr = {};
+  receiver_addr.sin6_family = AF_INET6;
+  receiver_addr.sin6_addr = in6addr_any;
+  socklen_t receiver_len = sizeof(receiver_addr);
+  ASSERT_THAT(bind(receiver.get(), AsSockAddr(&receiver_addr), receiver_len),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockname(receiver.get(), AsSockAddr(&receiver_addr), &receiver_len),
+      SyscallSucceeds());
+  sockaddr_in send_addr = {};
+  send_addr.sin_family = AF_INET;
+  send_addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  send_addr.sin_port = receiver_addr.sin6_port;
+  FileDescriptor sender =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(
+      RecvFromCall(receiver.get(), sender.get(), AsSockAddr(&send_addr),
+                   sizeof(send_addr)),
+      sizeof(sockaddr_in6), &full));
+  auto const* from = reinterpret_cast<sockaddr_in6*>(&full);
+  EXPECT_EQ(from->sin6_family, AF_INET6);
+  EXPECT_TRUE(IN6_IS_ADDR_V4MAPPED(&from->sin6_addr));
+}
+
+TEST(SocketAddressLengthTest, Netlink) {
+  FileDescriptor sock =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_NETLINK, SOCK_RAW, NETLINK_ROUTE));
+  sockaddr_nl addr = {};
+  addr.nl_family = AF_NETLINK;
+  ASSERT_THAT(bind(sock.get(), reinterpret_cast<sockaddr*>(&addr),
+                   sizeof(addr)),
+              SyscallSucceeds());
+
+  sockaddr_storage full;
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetSockNameCall(sock.get()),
+                                             sizeof(sockaddr_nl), &full));
+  EXPECT_EQ(full.ss_family, AF_NETLINK);
+  ASSERT_NO_FATAL_FAILURE(CheckAddressLength(GetPeerNameCall(sock.get()),
+                                             sizeof(sockaddr_nl), &full));
+  EXPECT_EQ(full.ss_family, AF_NETLINK);
+}
+
+// CheckAcceptDropsConnection verifies that accept(2) with the given address
+// buffer fails with want_errno, and that the connection is dropped rather than
+// leaked in the file descriptor table.
+void CheckAcceptDropsConnection(sockaddr* addr, socklen_t addrlen,
+                                int want_errno) {
+  UnixAddressGenerator gen(true /* abstract */);
+  UnixAddress listener_addr = gen.Next();
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(UnixListener(listener_addr));
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(client.get(), AsSockAddr(&listener_addr.addr),
+                      listener_addr.len),
+              SyscallSucceeds());
+
+  // The next file descriptor to be allocated.
+  int next_fd = dup(client.get());
+  ASSERT_THAT(next_fd, SyscallSucceeds());
+  ASSERT_THAT(close(next_fd), SyscallSucceeds());
+
+  ASSERT_THAT(accept(listener.get(), addr, &addrlen),
+              SyscallFailsWithErrno(want_errno));
+  EXPECT_THAT(fcntl(next_fd, F_GETFD), SyscallFailsWithErrno(EBADF));
+
+  // The client sees the connection closed.
+  char c;
+  EXPECT_THAT(RetryEINTR(read)(client.get(), &c, sizeof(c)),
+              SyscallSucceedsWithValue(0));
+
+  // The listener is still usable.
+  FileDescriptor second =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_UNIX, SOCK_STREAM, 0));
+  ASSERT_THAT(connect(second.get(), AsSockAddr(&listener_addr.addr),
+                      listener_addr.len),
+              SyscallSucceeds());
+  sockaddr_storage peer;
+  socklen_t peer_len = sizeof(peer);
+  EXPECT_NO_ERRNO(Accept(listener.get(), AsSockAddr(&peer), &peer_len));
+}
+
+// A negative address length fails with EINVAL.
+TEST(SocketAddressLengthTest, AcceptNegativeAddrLenDropsConnection) {
+  sockaddr_storage addr;
+  ASSERT_NO_FATAL_FAILURE(
+      CheckAcceptDropsConnection(AsSockAddr(&addr), -1, EINVAL));
+}
+
+// An address buffer that can't be written fails with EFAULT.
+TEST(SocketAddressLengthTest, AcceptBadAddrDropsConnection) {
+  Mapping const m =
+      ASSERT_NO_ERRNO_AND_VALUE(MmapAnon(kPageSize, PROT_NONE, MAP_PRIVATE));
+  ASSERT_NO_FATAL_FAILURE(CheckAcceptDropsConnection(
+      reinterpret_cast<sockaddr*>(m.ptr()), sizeof(sockaddr_storage), EFAULT));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -1363,6 +1363,74 @@ TEST_P(SocketInetLoopbackTest, TCPDeferAcceptTimeout) {
       ASSERT_NO_ERRNO_AND_VALUE(Accept(listen_fd.get(), nullptr, nullptr));
 }
 
+// Test that connections held back by TCP_DEFER_ACCEPT are not counted in the
+// listener's accept queue.
+TEST_P(SocketInetLoopbackTest, TCPDeferAcceptListenQueue) {
+  SocketInetTestParam const& param = GetParam();
+  TestAddress const& listener = param.listener;
+  TestAddress const& connector = param.connector;
+
+  // Create the listening socket.
+  const FileDescriptor listen_fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Socket(listener.family(), SOCK_STREAM, IPPROTO_TCP));
+  sockaddr_storage listen_addr = listener.addr;
+  ASSERT_THAT(
+      bind(listen_fd.get(), AsSockAddr(&listen_addr), listener.addr_len),
+      SyscallSucceeds());
+  constexpr int kBacklog = 5;
+  ASSERT_THAT(listen(listen_fd.get(), kBacklog), SyscallSucceeds());
+
+  // Get the port bound by the listening socket.
+  socklen_t addrlen = listener.addr_len;
+  ASSERT_THAT(getsockname(listen_fd.get(), AsSockAddr(&listen_addr), &addrlen),
+              SyscallSucceeds());
+
+  const uint16_t port =
+      ASSERT_NO_ERRNO_AND_VALUE(AddrPort(listener.family(), listen_addr));
+
+  // Set the TCP_DEFER_ACCEPT on the listening socket.
+  constexpr int kTCPDeferAccept = 3;
+  ASSERT_THAT(setsockopt(listen_fd.get(), IPPROTO_TCP, TCP_DEFER_ACCEPT,
+                         &kTCPDeferAccept, sizeof(kTCPDeferAccept)),
+              SyscallSucceeds());
+
+  // For listening sockets, tcpi_unacked is the number of connections waiting
+  // to be accepted and tcpi_sacked is the backlog.
+  auto queue = [](int fd) -> std::pair<int, int> {
+    struct tcp_info opt = {};
+    socklen_t optLen = sizeof(opt);
+    EXPECT_THAT(getsockopt(fd, SOL_TCP, TCP_INFO, &opt, &optLen),
+                SyscallSucceeds());
+    return {opt.tcpi_unacked, opt.tcpi_sacked};
+  };
+
+  // Connect to the listening socket without sending any data.
+  FileDescriptor conn_fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Socket(connector.family(), SOCK_STREAM, IPPROTO_TCP));
+  sockaddr_storage conn_addr = connector.addr;
+  ASSERT_NO_ERRNO(SetAddrPort(connector.family(), &conn_addr, port));
+  ASSERT_THAT(RetryEINTR(connect)(conn_fd.get(), AsSockAddr(&conn_addr),
+                                  connector.addr_len),
+              SyscallSucceeds());
+
+  // The connection is not ready to be accepted yet.
+  absl::SleepFor(absl::Milliseconds(100));
+  EXPECT_EQ(queue(listen_fd.get()), std::make_pair(0, kBacklog));
+
+  // Data makes the connection ready.
+  int data = 0;
+  ASSERT_THAT(RetryEINTR(write)(conn_fd.get(), &data, sizeof(data)),
+              SyscallSucceedsWithValue(sizeof(data)));
+  struct pollfd pfd = {listen_fd.get(), POLLIN, 0};
+  ASSERT_THAT(RetryEINTR(poll)(&pfd, 1, /*timeout=*/1000),
+              SyscallSucceedsWithValue(1));
+  EXPECT_EQ(queue(listen_fd.get()), std::make_pair(1, kBacklog));
+
+  auto accepted =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listen_fd.get(), nullptr, nullptr));
+  EXPECT_EQ(queue(listen_fd.get()), std::make_pair(0, kBacklog));
+}
+
 INSTANTIATE_TEST_SUITE_P(All, SocketInetLoopbackTest,
                          SocketInetLoopbackTestValues(),
                          DescribeSocketInetTestParam);
@@ -24,6 +24,8 @@
 #include <sys/types.h>
 #include <sys/un.h>
 
+#include <vector>
+
 #include "gtest/gtest.h"
 #include "absl/memory/memory.h"
 #include "absl/time/clock.h"
@@ -1304,5 +1306,63 @@ TEST_P(TCPSocketPairTest, CloseWithLingerOption) {
   ASSERT_THAT(RetryEINTR(write)(dupFd.get(), buf, sizeof(buf)),
               SyscallFailsWithErrno(EBADF));
 }
+
+// Test that a lingering close returns as soon as all data has been
+// acknowledged, without waiting for the peer to close its end.
+TEST_P(TCPSocketPairTest, CloseWithLingerReturnsOnceAcked) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  constexpr int kLingerTimeout = 5;  // Seconds.
+  struct linger sl;
+  sl.l_onoff = 1;
+  sl.l_linger = kLingerTimeout;
+  ASSERT_THAT(
+      setsockopt(sockets->first_fd(), SOL_SOCKET, SO_LINGER, &sl, sizeof(sl)),
+      SyscallSucceeds());
+
+  char buf[10] = {};
+  ASSERT_THAT(RetryEINTR(write)(sockets->first_fd(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+
+  absl::Time start = absl::Now();
+  ASSERT_THAT(close(sockets->release_first_fd()), SyscallSucceeds());
+  EXPECT_LT(absl::Now() - start, absl::Seconds(kLingerTimeout));
+
+  // The peer still receives the data followed by EOF.
+  ASSERT_THAT(RetryEINTR(read)(sockets->second_fd(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_THAT(RetryEINTR(read)(sockets->second_fd(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(0));
+}
+
+// Test that a lingering close blocks until the linger timeout elapses if sent
+// data can't be acknowledged, even if the socket is non-blocking.
+TEST_P(TCPSocketPairTest, CloseWithLingerBlocksOnUnackedData) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  // Fill the peer's receive buffer and our send buffer.
+  int opts;
+  ASSERT_THAT(opts = fcntl(sockets->first_fd(), F_GETFL), SyscallSucceeds());
+  ASSERT_THAT(fcntl(sockets->first_fd(), F_SETFL, opts | O_NONBLOCK),
+              SyscallSucceeds());
+  std::vector<char> buf(1 << 16);
+  int ret;
+  while ((ret = RetryEINTR(write)(sockets->first_fd(), buf.data(),
+                                  buf.size())) > 0) {
+  }
+  ASSERT_THAT(ret, SyscallFailsWithErrno(EWOULDBLOCK));
+
+  constexpr int kLingerTimeout = 1;  // Seconds.
+  struct linger sl;
+  sl.l_onoff = 1;
+  sl.l_linger = kLingerTimeout;
+  ASSERT_THAT(
+      setsockopt(sockets->first_fd(), SOL_SOCKET, SO_LINGER, &sl, sizeof(sl)),
+      SyscallSucceeds());
+
+  absl::Time start = absl::Now();
+  ASSERT_THAT(close(sockets->release_first_fd()), SyscallSucceeds());
+  EXPECT_GE(absl::Now() - start, absl::Seconds(kLingerTimeout));
+}
 }  // namespace testing
 }  // namespace gvisor
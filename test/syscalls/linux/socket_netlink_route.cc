@@ -16,12 +16,17 @@
 #include <fcntl.h>
 #include <ifaddrs.h>
 #include <linux/if.h>
+#include <linux/if_arp.h>
+#include <linux/if_tunnel.h>
+#include <linux/neighbour.h>
 #include <linux/netlink.h>
 #include <linux/rtnetlink.h>
+#include <poll.h>
 #include <sys/socket.h>
 #include <sys/types.h>
 #include <unistd.h>
 
+#include <cstddef>
 #include <iostream>
 #include <vector>
 
@@ -382,6 +387,212 @@ TEST(NetlinkRouteTest, RemoveLinkByNameNotFound) {
               PosixErrorIs(ENODEV, _));
 }
 
+// AddAndRemoveGRELink tests creating a GRE tunnel link with RTM_NEWLINK and
+// removing it with RTM_DELLINK.
+TEST(NetlinkRouteTest, AddAndRemoveGRELink) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // Don't do cooperative save/restore because netstack state is not restored.
+  // TODO(gvisor.dev/issue/4595): enable cooperative save tests.
+  const DisableSave ds;
+
+  const std::string name = "gretest0";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct ifinfomsg ifm;
+    char attrs[256];
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_type = RTM_NEWLINK;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_CREATE | NLM_F_EXCL | NLM_F_ACK;
+  req.hdr.nlmsg_seq = kSeq;
+  req.hdr.nlmsg_len = NLMSG_LENGTH(sizeof(req.ifm));
+  req.ifm.ifi_family = AF_UNSPEC;
+
+  auto add_attr = [&req](int type, const void* data,
+                         int len) -> struct rtattr* {
+    struct rtattr* rta = reinterpret_cast<struct rtattr*>(
+        reinterpret_cast<char*>(&req) + NLMSG_ALIGN(req.hdr.nlmsg_len));
+    rta->rta_type = type;
+    rta->rta_len = RTA_LENGTH(len);
+    if (len > 0) {
+      memcpy(RTA_DATA(rta), data, len);
+    }
+    req.hdr.nlmsg_len =
+        NLMSG_ALIGN(req.hdr.nlmsg_len) + RTA_ALIGN(rta->rta_len);
+    return rta;
+  };
+  auto end_nested = [&req](struct rtattr* rta) {
+    rta->rta_len = reinterpret_cast<char*>(&req) + req.hdr.nlmsg_len -
+                   reinterpret_cast<char*>(rta);
+  };
+
+  struct in_addr local, remote;
+  ASSERT_EQ(inet_pton(AF_INET, "192.0.2.1", &local), 1);
+  ASSERT_EQ(inet_pton(AF_INET, "192.0.2.2", &remote), 1);
+  const uint16_t flags = GRE_KEY;
+  const uint32_t key = htonl(42);
+  const uint8_t ttl = 64;
+
+  add_attr(IFLA_IFNAME, name.c_str(), name.size() + 1);
+  struct rtattr* linkinfo = add_attr(IFLA_LINKINFO, nullptr, 0);
+  add_attr(IFLA_INFO_KIND, "gre", 3);
+  struct rtattr* data = add_attr(IFLA_INFO_DATA, nullptr, 0);
+  add_attr(IFLA_GRE_LOCAL, &local, sizeof(local));
+  add_attr(IFLA_GRE_REMOTE, &remote, sizeof(remote));
+  add_attr(IFLA_GRE_TTL, &ttl, sizeof(ttl));
+  add_attr(IFLA_GRE_IFLAGS, &flags, sizeof(flags));
+  add_attr(IFLA_GRE_OFLAGS, &flags, sizeof(flags));
+  add_attr(IFLA_GRE_IKEY, &key, sizeof(key));
+  add_attr(IFLA_GRE_OKEY, &key, sizeof(key));
+  end_nested(data);
+  end_nested(linkinfo);
+
+  PosixError err = NetlinkRequestAckOrError(fd, kSeq, &req, req.hdr.nlmsg_len);
+  // Linux may not have GRE support.
+  SKIP_IF(err.errno_value() == EOPNOTSUPP);
+  ASSERT_NO_ERRNO(err);
+
+  // Creating the same link again fails.
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, req.hdr.nlmsg_len),
+              PosixErrorIs(EEXIST, _));
+
+  bool found = false;
+  for (const Link& link : ASSERT_NO_ERRNO_AND_VALUE(DumpLinks())) {
+    if (link.name == name) {
+      EXPECT_EQ(link.type, ARPHRD_IPGRE);
+      found = true;
+    }
+  }
+  EXPECT_TRUE(found) << "link " << name << " not found";
+
+  struct {
+    struct nlmsghdr hdr;
+    struct ifinfomsg ifm;
+    struct rtattr rtattr;
+    char ifname[IFNAMSIZ];
+    char pad[NLMSG_ALIGNTO + RTA_ALIGNTO];
+  } del_req = {};
+  del_req.hdr.nlmsg_type = RTM_DELLINK;
+  del_req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_ACK;
+  del_req.hdr.nlmsg_seq = kSeq;
+  del_req.ifm.ifi_family = AF_UNSPEC;
+  del_req.rtattr.rta_type = IFLA_IFNAME;
+  del_req.rtattr.rta_len = RTA_LENGTH(name.size() + 1);
+  strncpy(del_req.ifname, name.c_str(), sizeof(del_req.ifname));
+  del_req.hdr.nlmsg_len =
+      NLMSG_LENGTH(sizeof(del_req.ifm)) + NLMSG_ALIGN(del_req.rtattr.rta_len);
+  ASSERT_NO_ERRNO(
+      NetlinkRequestAckOrError(fd, kSeq, &del_req, sizeof(del_req)));
+
+  for (const Link& link : ASSERT_NO_ERRNO_AND_VALUE(DumpLinks())) {
+    EXPECT_NE(link.name, name);
+  }
+}
+
+// AddAndRemoveBondLink tests creating an active-backup bond link with
+// RTM_NEWLINK and removing it with RTM_DELLINK.
+TEST(NetlinkRouteTest, AddAndRemoveBondLink) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // Don't do cooperative save/restore because netstack state is not restored.
+  // TODO(gvisor.dev/issue/4595): enable cooperative save tests.
+  const DisableSave ds;
+
+  const std::string name = "bondtest0";
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct ifinfomsg ifm;
+    char attrs[256];
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_type = RTM_NEWLINK;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_CREATE | NLM_F_EXCL | NLM_F_ACK;
+  req.hdr.nlmsg_seq = kSeq;
+  req.hdr.nlmsg_len = NLMSG_LENGTH(sizeof(req.ifm));
+  req.ifm.ifi_family = AF_UNSPEC;
+
+  auto add_attr = [&req](int type, const void* data,
+                         int len) -> struct rtattr* {
+    struct rtattr* rta = reinterpret_cast<struct rtattr*>(
+        reinterpret_cast<char*>(&req) + NLMSG_ALIGN(req.hdr.nlmsg_len));
+    rta->rta_type = type;
+    rta->rta_len = RTA_LENGTH(len);
+    if (len > 0) {
+      memcpy(RTA_DATA(rta), data, len);
+    }
+    req.hdr.nlmsg_len =
+        NLMSG_ALIGN(req.hdr.nlmsg_len) + RTA_ALIGN(rta->rta_len);
+    return rta;
+  };
+  auto end_nested = [&req](struct rtattr* rta) {
+    rta->rta_len = reinterpret_cast<char*>(&req) + req.hdr.nlmsg_len -
+                   reinterpret_cast<char*>(rta);
+  };
+
+  // Active-backup mode.
+  const uint8_t mode = 1;
+  const uint32_t miimon = 100;
+
+  add_attr(IFLA_IFNAME, name.c_str(), name.size() + 1);
+  struct rtattr* linkinfo = add_attr(IFLA_LINKINFO, nullptr, 0);
+  add_attr(IFLA_INFO_KIND, "bond", 4);
+  struct rtattr* data = add_attr(IFLA_INFO_DATA, nullptr, 0);
+  add_attr(IFLA_BOND_MODE, &mode, sizeof(mode));
+  add_attr(IFLA_BOND_MIIMON, &miimon, sizeof(miimon));
+  end_nested(data);
+  end_nested(linkinfo);
+
+  PosixError err = NetlinkRequestAckOrError(fd, kSeq, &req, req.hdr.nlmsg_len);
+  // Linux may not have bonding support.
+  SKIP_IF(err.errno_value() == EOPNOTSUPP);
+  ASSERT_NO_ERRNO(err);
+
+  // Creating the same link again fails.
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, req.hdr.nlmsg_len),
+              PosixErrorIs(EEXIST, _));
+
+  bool found = false;
+  for (const Link& link : ASSERT_NO_ERRNO_AND_VALUE(DumpLinks())) {
+    if (link.name == name) {
+      EXPECT_EQ(link.type, ARPHRD_ETHER);
+      found = true;
+    }
+  }
+  EXPECT_TRUE(found) << "link " << name << " not found";
+
+  struct {
+    struct nlmsghdr hdr;
+    struct ifinfomsg ifm;
+    struct rtattr rtattr;
+    char ifname[IFNAMSIZ];
+    char pad[NLMSG_ALIGNTO + RTA_ALIGNTO];
+  } del_req = {};
+  del_req.hdr.nlmsg_type = RTM_DELLINK;
+  del_req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_ACK;
+  del_req.hdr.nlmsg_seq = kSeq;
+  del_req.ifm.ifi_family = AF_UNSPEC;
+  del_req.rtattr.rta_type = IFLA_IFNAME;
+  del_req.rtattr.rta_len = RTA_LENGTH(name.size() + 1);
+  strncpy(del_req.ifname, name.c_str(), sizeof(del_req.ifname));
+  del_req.hdr.nlmsg_len =
+      NLMSG_LENGTH(sizeof(del_req.ifm)) + NLMSG_ALIGN(del_req.rtattr.rta_len);
+  ASSERT_NO_ERRNO(
+      NetlinkRequestAckOrError(fd, kSeq, &del_req, sizeof(del_req)));
+
+  for (const Link& link : ASSERT_NO_ERRNO_AND_VALUE(DumpLinks())) {
+    EXPECT_NE(link.name, name);
+  }
+}
+
 TEST(NetlinkRouteTest, MsgHdrMsgUnsuppType) {
   FileDescriptor fd =
       ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
@@ -1071,6 +1282,338 @@ TEST(NetlinkRouteTest, PasscredCreds) {
   EXPECT_THAT(creds.gid, AnyOf(Eq(0), Eq(65534)));
 }
 
+// GetNeighDump tests a RTM_GETNEIGH + NLM_F_DUMP request.
+TEST(NetlinkRouteTest, GetNeighDump) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+  uint32_t port = ASSERT_NO_ERRNO_AND_VALUE(NetlinkPortID(fd.get()));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct ndmsg ndm;
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = RTM_GETNEIGH;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_DUMP;
+  req.hdr.nlmsg_seq = kSeq;
+  req.ndm.ndm_family = AF_UNSPEC;
+
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        EXPECT_THAT(hdr->nlmsg_type, Eq(RTM_NEWNEIGH));
+        EXPECT_TRUE((hdr->nlmsg_flags & NLM_F_MULTI) == NLM_F_MULTI)
+            << std::hex << hdr->nlmsg_flags;
+        EXPECT_EQ(hdr->nlmsg_seq, kSeq);
+        EXPECT_EQ(hdr->nlmsg_pid, port);
+
+        const struct ndmsg* ndm =
+            reinterpret_cast<const struct ndmsg*>(NLMSG_DATA(hdr));
+        ASSERT_GE(hdr->nlmsg_len, NLMSG_SPACE(sizeof(*ndm)));
+        EXPECT_THAT(ndm->ndm_family, AnyOf(Eq(AF_INET), Eq(AF_INET6)));
+        EXPECT_GT(ndm->ndm_ifindex, 0);
+
+        const struct rtattr* rta = FindRtAttr(hdr, ndm, NDA_DST);
+        ASSERT_NE(rta, nullptr);
+        const size_t addrlen = ndm->ndm_family == AF_INET
+                                   ? sizeof(struct in_addr)
+                                   : sizeof(struct in6_addr);
+        EXPECT_EQ(RTA_PAYLOAD(rta), addrlen);
+      },
+      false));
+}
+
+// EthernetLink returns a link whose neighbors are resolved with ARP and NDP.
+PosixErrorOr<Link> EthernetLink() {
+  ASSIGN_OR_RETURN_ERRNO(auto links, DumpLinks());
+  for (const Link& link : links) {
+    if (link.type == ARPHRD_ETHER) {
+      return link;
+    }
+  }
+  return PosixError(ENOENT, "no ethernet link found");
+}
+
+// NeighRequest sends a request of the given type and flags for the IPv4
+// neighbor addr of the link index, with the link address lladdr unless it is
+// null, and waits for the acknowledgement.
+PosixError NeighRequest(const FileDescriptor& fd, uint16_t type,
+                        uint16_t flags, int index, const struct in_addr& addr,
+                        const uint8_t* lladdr) {
+  struct request {
+    struct nlmsghdr hdr;
+    struct ndmsg ndm;
+    struct rtattr dst_attr;
+    struct in_addr dst;
+    struct rtattr lladdr_attr;
+    uint8_t lladdr[6];
+    char pad[2];
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_type = type;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_ACK | flags;
+  req.hdr.nlmsg_seq = kSeq;
+  req.ndm.ndm_family = AF_INET;
+  req.ndm.ndm_ifindex = index;
+  req.ndm.ndm_state = NUD_PERMANENT;
+  req.dst_attr.rta_type = NDA_DST;
+  req.dst_attr.rta_len = RTA_LENGTH(sizeof(req.dst));
+  req.dst = addr;
+  req.hdr.nlmsg_len = offsetof(struct request, lladdr_attr);
+  if (lladdr != nullptr) {
+    req.lladdr_attr.rta_type = NDA_LLADDR;
+    req.lladdr_attr.rta_len = RTA_LENGTH(sizeof(req.lladdr));
+    memcpy(req.lladdr, lladdr, sizeof(req.lladdr));
+    req.hdr.nlmsg_len = sizeof(req);
+  }
+  return NetlinkRequestAckOrError(fd, kSeq, &req, req.hdr.nlmsg_len);
+}
+
+// FindNeigh returns the state and link address of the IPv4 neighbor addr of
+// the link index, as reported by a RTM_GETNEIGH dump.
+PosixErrorOr<std::pair<uint16_t, std::vector<uint8_t>>> FindNeigh(
+    int index, const struct in_addr& addr) {
+  FileDescriptor fd;
+  ASSIGN_OR_RETURN_ERRNO(fd, NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct ndmsg ndm;
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = RTM_GETNEIGH;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_DUMP;
+  req.hdr.nlmsg_seq = kSeq;
+  req.ndm.ndm_family = AF_INET;
+
+  bool found = false;
+  std::pair<uint16_t, std::vector<uint8_t>> neigh;
+  RETURN_IF_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        const struct ndmsg* ndm =
+            reinterpret_cast<const struct ndmsg*>(NLMSG_DATA(hdr));
+        if (hdr->nlmsg_type != RTM_NEWNEIGH || ndm->ndm_ifindex != index) {
+          return;
+        }
+        const struct rtattr* dst = FindRtAttr(hdr, ndm, NDA_DST);
+        if (dst == nullptr || RTA_PAYLOAD(dst) != sizeof(addr) ||
+            memcmp(RTA_DATA(dst), &addr, sizeof(addr)) != 0) {
+          return;
+        }
+        found = true;
+        neigh.first = ndm->ndm_state;
+        const struct rtattr* lladdr = FindRtAttr(hdr, ndm, NDA_LLADDR);
+        if (lladdr != nullptr) {
+          const uint8_t* data =
+              reinterpret_cast<const uint8_t*>(RTA_DATA(lladdr));
+          neigh.second.assign(data, data + RTA_PAYLOAD(lladdr));
+        }
+      },
+      false));
+  if (!found) {
+    return PosixError(ENOENT, "neighbor not found");
+  }
+  return neigh;
+}
+
+// AddReplaceAndRemoveNeigh tests adding, replacing and removing a permanent
+// neighbor entry with RTM_NEWNEIGH and RTM_DELNEIGH.
+TEST(NetlinkRouteTest, AddReplaceAndRemoveNeigh) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // Don't do cooperative save/restore because netstack state is not restored.
+  // TODO(gvisor.dev/issue/4595): enable cooperative save tests.
+  const DisableSave ds;
+
+  auto link_or = EthernetLink();
+  SKIP_IF(!link_or.ok());
+  const Link link = link_or.ValueOrDie();
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct in_addr addr;
+  ASSERT_EQ(inet_pton(AF_INET, "192.0.2.10", &addr), 1);
+  const uint8_t lladdr1[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x01};
+  const uint8_t lladdr2[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x02};
+
+  ASSERT_NO_ERRNO(NeighRequest(fd, RTM_NEWNEIGH, NLM_F_CREATE | NLM_F_EXCL,
+                               link.index, addr, lladdr1));
+  Cleanup remove_neigh([&] {
+    NeighRequest(fd, RTM_DELNEIGH, 0, link.index, addr, nullptr).IgnoreError();
+  });
+
+  // Adding the same entry again fails.
+  EXPECT_THAT(NeighRequest(fd, RTM_NEWNEIGH, NLM_F_CREATE | NLM_F_EXCL,
+                           link.index, addr, lladdr1),
+              PosixErrorIs(EEXIST, _));
+
+  auto neigh = ASSERT_NO_ERRNO_AND_VALUE(FindNeigh(link.index, addr));
+  EXPECT_EQ(neigh.first, NUD_PERMANENT);
+  EXPECT_EQ(neigh.second, std::vector<uint8_t>(lladdr1, lladdr1 + 6));
+
+  // Replace the link address of the entry.
+  ASSERT_NO_ERRNO(NeighRequest(fd, RTM_NEWNEIGH, NLM_F_CREATE | NLM_F_REPLACE,
+                               link.index, addr, lladdr2));
+  neigh = ASSERT_NO_ERRNO_AND_VALUE(FindNeigh(link.index, addr));
+  EXPECT_EQ(neigh.first, NUD_PERMANENT);
+  EXPECT_EQ(neigh.second, std::vector<uint8_t>(lladdr2, lladdr2 + 6));
+
+  ASSERT_NO_ERRNO(
+      NeighRequest(fd, RTM_DELNEIGH, 0, link.index, addr, nullptr));
+  EXPECT_THAT(FindNeigh(link.index, addr), PosixErrorIs(ENOENT, _));
+
+  // Removing the entry again fails.
+  EXPECT_THAT(NeighRequest(fd, RTM_DELNEIGH, 0, link.index, addr, nullptr),
+              PosixErrorIs(ENOENT, _));
+}
+
+// WaitNeighNotification waits for a notification of the given type about the
+// IPv4 neighbor addr on fd, a socket that is a member of RTNLGRP_NEIGH.
+PosixError WaitNeighNotification(const FileDescriptor& fd, uint16_t type,
+                                 const struct in_addr& addr) {
+  // Other neighbors may change state concurrently, so skip unrelated
+  // notifications.
+  for (int i = 0; i < 100; i++) {
+    struct pollfd pfd = {fd.get(), POLLIN, 0};
+    int ret;
+    RETURN_ERROR_IF_SYSCALL_FAIL(ret = RetryEINTR(poll)(&pfd, 1, 5000));
+    if (ret == 0) {
+      return PosixError(ETIMEDOUT, "no neighbor notification received");
+    }
+
+    bool found = false;
+    RETURN_IF_ERRNO(NetlinkResponse(
+        fd,
+        [&](const struct nlmsghdr* hdr) {
+          const struct ndmsg* ndm =
+              reinterpret_cast<const struct ndmsg*>(NLMSG_DATA(hdr));
+          if (hdr->nlmsg_type != type || ndm->ndm_family != AF_INET) {
+            return;
+          }
+          const struct rtattr* dst = FindRtAttr(hdr, ndm, NDA_DST);
+          if (dst != nullptr && RTA_PAYLOAD(dst) == sizeof(addr) &&
+              memcmp(RTA_DATA(dst), &addr, sizeof(addr)) == 0) {
+            // Notifications are not sent in response to a request.
+            EXPECT_EQ(hdr->nlmsg_pid, 0u);
+            found = true;
+          }
+        },
+        false));
+    if (found) {
+      return NoError();
+    }
+  }
+  return PosixError(ETIMEDOUT, "no matching neighbor notification received");
+}
+
+// NeighNotifications tests that members of RTNLGRP_NEIGH are notified of
+// changes to the neighbor table.
+TEST(NetlinkRouteTest, NeighNotifications) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  // Don't do cooperative save/restore because netstack state is not restored.
+  // TODO(gvisor.dev/issue/4595): enable cooperative save tests.
+  const DisableSave ds;
+
+  auto link_or = EthernetLink();
+  SKIP_IF(!link_or.ok());
+  const Link link = link_or.ValueOrDie();
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  // Join the group with the legacy bind(2) interface.
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_NETLINK, SOCK_RAW, NETLINK_ROUTE));
+  struct sockaddr_nl addr = {};
+  addr.nl_family = AF_NETLINK;
+  addr.nl_groups = RTMGRP_NEIGH;
+  ASSERT_THAT(bind(listener.get(), reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(addr)),
+              SyscallSucceeds());
+
+  struct in_addr neigh;
+  ASSERT_EQ(inet_pton(AF_INET, "192.0.2.11", &neigh), 1);
+  const uint8_t lladdr[6] = {0x02, 0x00, 0x00, 0x00, 0x00, 0x03};
+
+  ASSERT_NO_ERRNO(NeighRequest(fd, RTM_NEWNEIGH, NLM_F_CREATE | NLM_F_EXCL,
+                               link.index, neigh, lladdr));
+  EXPECT_NO_ERRNO(WaitNeighNotification(listener, RTM_NEWNEIGH, neigh));
+
+  ASSERT_NO_ERRNO(
+      NeighRequest(fd, RTM_DELNEIGH, 0, link.index, neigh, nullptr));
+  EXPECT_NO_ERRNO(WaitNeighNotification(listener, RTM_DELNEIGH, neigh));
+}
+
+// AddAndDropMembership tests joining and leaving multicast groups with
+// setsockopt(2).
+TEST(NetlinkRouteTest, AddAndDropMembership) {
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  const int group = RTNLGRP_NEIGH;
+  ASSERT_THAT(setsockopt(fd.get(), SOL_NETLINK, NETLINK_ADD_MEMBERSHIP,
+                         &group, sizeof(group)),
+              SyscallSucceeds());
+
+  uint32_t groups[2] = {};
+  socklen_t len = sizeof(groups);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_NETLINK, NETLINK_LIST_MEMBERSHIPS,
+                         groups, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(groups[0], 1u << (RTNLGRP_NEIGH - 1));
+
+  ASSERT_THAT(setsockopt(fd.get(), SOL_NETLINK, NETLINK_DROP_MEMBERSHIP,
+                         &group, sizeof(group)),
+              SyscallSucceeds());
+
+  groups[0] = 0xffffffff;
+  len = sizeof(groups);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_NETLINK, NETLINK_LIST_MEMBERSHIPS,
+                         groups, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(groups[0], 0u);
+
+  // Group 0 doesn't exist.
+  const int invalid = 0;
+  EXPECT_THAT(setsockopt(fd.get(), SOL_NETLINK, NETLINK_ADD_MEMBERSHIP,
+                         &invalid, sizeof(invalid)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// Groups for which gVisor never sends notifications can't be joined, as the
+// members would wait for them forever.
+TEST(NetlinkRouteTest, UnsupportedGroup) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_NETLINK, SOCK_RAW, NETLINK_ROUTE));
+  struct sockaddr_nl addr = {};
+  addr.nl_family = AF_NETLINK;
+  addr.nl_groups = RTMGRP_LINK | RTMGRP_NEIGH;
+  EXPECT_THAT(
+      bind(fd.get(), reinterpret_cast<struct sockaddr*>(&addr), sizeof(addr)),
+      SyscallFailsWithErrno(EPERM));
+
+  const int group = RTNLGRP_LINK;
+  EXPECT_THAT(setsockopt(fd.get(), SOL_NETLINK, NETLINK_ADD_MEMBERSHIP, &group,
+                         sizeof(group)),
+              SyscallFailsWithErrno(EPERM));
+
+  // Neither call joined anything.
+  uint32_t groups[2] = {0xffffffff};
+  socklen_t len = sizeof(groups);
+  ASSERT_THAT(getsockopt(fd.get(), SOL_NETLINK, NETLINK_LIST_MEMBERSHIPS,
+                         groups, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(groups[0], 0u);
+}
+
 }  // namespace
 
 }  // namespace testing
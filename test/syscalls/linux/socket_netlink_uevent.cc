@@ -77,6 +77,25 @@ TEST(NetlinkUeventTest, AttachFilter) {
       SyscallSucceeds());
 }
 
+// No multicast groups can be joined, since gVisor never sends any events.
+TEST(NetlinkUeventTest, JoinGroup) {
+  SKIP_IF(!IsRunningOnGvisor());
+
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Socket(AF_NETLINK, SOCK_RAW, NETLINK_KOBJECT_UEVENT));
+  struct sockaddr_nl addr = {};
+  addr.nl_family = AF_NETLINK;
+  addr.nl_groups = 1;
+  EXPECT_THAT(
+      bind(fd.get(), reinterpret_cast<struct sockaddr*>(&addr), sizeof(addr)),
+      SyscallFailsWithErrno(EPERM));
+
+  const int group = 1;
+  EXPECT_THAT(setsockopt(fd.get(), SOL_NETLINK, NETLINK_ADD_MEMBERSHIP, &group,
+                         sizeof(group)),
+              SyscallFailsWithErrno(EPERM));
+}
+
 }  // namespace
 
 }  // namespace testing
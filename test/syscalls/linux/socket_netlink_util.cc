@@ -180,12 +180,16 @@ PosixError NetlinkRequestAckOrError(const FileDescriptor& fd, uint32_t seq,
   return PosixError(err);
 }
 
-const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
-                                const struct ifinfomsg* msg, int16_t attr) {
-  const int ifi_space = NLMSG_SPACE(sizeof(*msg));
-  int attrlen = hdr->nlmsg_len - ifi_space;
+namespace {
+
+// FindRtAttrAfter returns the attribute attr of the message hdr, whose
+// attributes follow a family specific header of size len.
+const struct rtattr* FindRtAttrAfter(const struct nlmsghdr* hdr, size_t len,
+                                     int16_t attr) {
+  const int space = NLMSG_SPACE(len);
+  int attrlen = hdr->nlmsg_len - space;
   const struct rtattr* rta = reinterpret_cast<const struct rtattr*>(
-      reinterpret_cast<const uint8_t*>(hdr) + NLMSG_ALIGN(ifi_space));
+      reinterpret_cast<const uint8_t*>(hdr) + NLMSG_ALIGN(space));
   for (; RTA_OK(rta, attrlen); rta = RTA_NEXT(rta, attrlen)) {
     if (rta->rta_type == attr) {
       return rta;
@@ -194,5 +198,17 @@ const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
   return nullptr;
 }
 
+}  // namespace
+
+const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
+                                const struct ifinfomsg* msg, int16_t attr) {
+  return FindRtAttrAfter(hdr, sizeof(*msg), attr);
+}
+
+const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
+                                const struct ndmsg* msg, int16_t attr) {
+  return FindRtAttrAfter(hdr, sizeof(*msg), attr);
+}
+
 }  // namespace testing
 }  // namespace gvisor
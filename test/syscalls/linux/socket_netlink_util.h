@@ -18,6 +18,7 @@
 #include <sys/socket.h>
 // socket.h has to be included before if_arp.h.
 #include <linux/if_arp.h>
+#include <linux/neighbour.h>
 #include <linux/netlink.h>
 #include <linux/rtnetlink.h>
 
@@ -64,6 +65,10 @@ PosixError NetlinkRequestAckOrError(const FileDescriptor& fd, uint32_t seq,
 const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
                                 const struct ifinfomsg* msg, int16_t attr);
 
+// Find rtnetlink attribute in neighbor message.
+const struct rtattr* FindRtAttr(const struct nlmsghdr* hdr,
+                                const struct ndmsg* msg, int16_t attr);
+
 }  // namespace testing
 }  // namespace gvisor
 
@@ -308,6 +308,81 @@ TEST_P(NonStreamSocketPairTest, RecvmsgMsgTruncMsgPeekZeroLen) {
   EXPECT_EQ(received_msg.msg_flags & MSG_TRUNC, 0);
 }
 
+// This test tests reading from a socket with MSG_TRUNC | MSG_PEEK and a
+// receive buffer smaller than the message. The full message length is
+// returned, and the whole message remains on the socket.
+TEST_P(NonStreamSocketPairTest, RecvmsgMsgTruncMsgPeekSmallBuffer) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  char sent_data[10];
+  RandomizeBuffer(sent_data, sizeof(sent_data));
+  ASSERT_THAT(
+      RetryEINTR(send)(sockets->first_fd(), sent_data, sizeof(sent_data), 0),
+      SyscallSucceedsWithValue(sizeof(sent_data)));
+
+  char peek_data[sizeof(sent_data) / 2] = {};
+  struct iovec peek_iov;
+  peek_iov.iov_base = peek_data;
+  peek_iov.iov_len = sizeof(peek_data);
+  struct msghdr peek_msg = {};
+  peek_msg.msg_flags = -1;
+  peek_msg.msg_iov = &peek_iov;
+  peek_msg.msg_iovlen = 1;
+
+  // Peeking more than once returns the same result.
+  for (int i = 0; i < 2; i++) {
+    ASSERT_THAT(RetryEINTR(recvmsg)(sockets->second_fd(), &peek_msg,
+                                    MSG_TRUNC | MSG_PEEK),
+                SyscallSucceedsWithValue(sizeof(sent_data)));
+    EXPECT_EQ(peek_msg.msg_flags & MSG_TRUNC, MSG_TRUNC);
+    EXPECT_EQ(0, memcmp(sent_data, peek_data, sizeof(peek_data)));
+  }
+
+  char received_data[sizeof(sent_data)] = {};
+  ASSERT_THAT(RetryEINTR(recv)(sockets->second_fd(), received_data,
+                               sizeof(received_data), 0),
+              SyscallSucceedsWithValue(sizeof(sent_data)));
+  EXPECT_EQ(0, memcmp(sent_data, received_data, sizeof(sent_data)));
+}
+
+// This test tests reading from a socket with a zero length receive buffer and
+// without MSG_TRUNC. The message is discarded, and MSG_TRUNC is set on msghdr
+// flags.
+TEST_P(NonStreamSocketPairTest, RecvmsgZeroLenDiscardsMessage) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  char sent_data1[10];
+  RandomizeBuffer(sent_data1, sizeof(sent_data1));
+  ASSERT_THAT(
+      RetryEINTR(send)(sockets->first_fd(), sent_data1, sizeof(sent_data1), 0),
+      SyscallSucceedsWithValue(sizeof(sent_data1)));
+  char sent_data2[20];
+  RandomizeBuffer(sent_data2, sizeof(sent_data2));
+  ASSERT_THAT(
+      RetryEINTR(send)(sockets->first_fd(), sent_data2, sizeof(sent_data2), 0),
+      SyscallSucceedsWithValue(sizeof(sent_data2)));
+
+  // The receive buffer is of zero length.
+  char received_data[sizeof(sent_data1) + sizeof(sent_data2)] = {};
+  struct iovec iov;
+  iov.iov_base = received_data;
+  iov.iov_len = 0;
+  struct msghdr msg = {};
+  msg.msg_flags = -1;
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+
+  ASSERT_THAT(RetryEINTR(recvmsg)(sockets->second_fd(), &msg, 0),
+              SyscallSucceedsWithValue(0));
+  EXPECT_EQ(msg.msg_flags & MSG_TRUNC, MSG_TRUNC);
+
+  // The first message was consumed.
+  ASSERT_THAT(RetryEINTR(recv)(sockets->second_fd(), received_data,
+                               sizeof(received_data), 0),
+              SyscallSucceedsWithValue(sizeof(sent_data2)));
+  EXPECT_EQ(0, memcmp(sent_data2, received_data, sizeof(sent_data2)));
+}
+
 // This test tests reading from a socket with MSG_TRUNC | MSG_PEEK and a zero
 // length receive buffer and MSG_DONTWAIT. The user should be able to get an
 // EAGAIN or EWOULDBLOCK error response.
@@ -1495,6 +1495,125 @@ TEST_P(UnixSocketPairCmsgTest, FDPassAfterSoPassCredWithoutCredHeaderSpace) {
   EXPECT_EQ(msg.msg_controllen, 0);
 }
 
+TEST_P(UnixSocketPairCmsgTest, PeerSec) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  char label[256] = {};
+  socklen_t len = sizeof(label);
+  int ret =
+      getsockopt(sockets->first_fd(), SOL_SOCKET, SO_PEERSEC, label, &len);
+  if (!IsRunningOnGvisor() && ret < 0 && errno == ENOPROTOOPT) {
+    GTEST_SKIP() << "No LSM providing peer security labels";
+  }
+  ASSERT_THAT(ret, SyscallSucceeds());
+  EXPECT_GT(len, 0);
+
+  // A buffer too small for the label is rejected.
+  char short_label[1];
+  socklen_t short_len = sizeof(short_label);
+  EXPECT_THAT(getsockopt(sockets->first_fd(), SOL_SOCKET, SO_PEERSEC,
+                         short_label, &short_len),
+              SyscallFailsWithErrno(ERANGE));
+}
+
+TEST_P(UnixSocketPairCmsgTest, SoPassSec) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  int val = -1;
+  socklen_t len = sizeof(val);
+  ASSERT_THAT(
+      getsockopt(sockets->second_fd(), SOL_SOCKET, SO_PASSSEC, &val, &len),
+      SyscallSucceeds());
+  EXPECT_EQ(val, 0);
+
+  constexpr int one = 1;
+  ASSERT_THAT(setsockopt(sockets->second_fd(), SOL_SOCKET, SO_PASSSEC, &one,
+                         sizeof(one)),
+              SyscallSucceeds());
+  ASSERT_THAT(
+      getsockopt(sockets->second_fd(), SOL_SOCKET, SO_PASSSEC, &val, &len),
+      SyscallSucceeds());
+  EXPECT_EQ(val, 1);
+}
+
+TEST_P(UnixSocketPairCmsgTest, NoSecurityLabelWithoutSoPassSec) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  char sent_data[20];
+  RandomizeBuffer(sent_data, sizeof(sent_data));
+  ASSERT_THAT(RetryEINTR(send)(sockets->first_fd(), sent_data,
+                               sizeof(sent_data), 0),
+              SyscallSucceedsWithValue(sizeof(sent_data)));
+
+  struct msghdr msg = {};
+  char control[CMSG_SPACE(256)];
+  msg.msg_control = control;
+  msg.msg_controllen = sizeof(control);
+
+  char received_data[20];
+  struct iovec iov;
+  iov.iov_base = received_data;
+  iov.iov_len = sizeof(received_data);
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+
+  ASSERT_THAT(RetryEINTR(recvmsg)(sockets->second_fd(), &msg, 0),
+              SyscallSucceedsWithValue(sizeof(received_data)));
+
+  EXPECT_EQ(0, memcmp(sent_data, received_data, sizeof(sent_data)));
+  EXPECT_EQ(msg.msg_controllen, 0);
+}
+
+TEST_P(UnixSocketPairCmsgTest, SecurityLabelPass) {
+  // Whether Linux delivers SCM_SECURITY depends on the host LSM.
+  SKIP_IF(!IsRunningOnGvisor());
+
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  constexpr int one = 1;
+  ASSERT_THAT(setsockopt(sockets->second_fd(), SOL_SOCKET, SO_PASSSEC, &one,
+                         sizeof(one)),
+              SyscallSucceeds());
+
+  char peer_label[256] = {};
+  socklen_t peer_label_len = sizeof(peer_label);
+  ASSERT_THAT(getsockopt(sockets->second_fd(), SOL_SOCKET, SO_PEERSEC,
+                         peer_label, &peer_label_len),
+              SyscallSucceeds());
+
+  char sent_data[20];
+  RandomizeBuffer(sent_data, sizeof(sent_data));
+  ASSERT_THAT(RetryEINTR(send)(sockets->first_fd(), sent_data,
+                               sizeof(sent_data), 0),
+              SyscallSucceedsWithValue(sizeof(sent_data)));
+
+  struct msghdr msg = {};
+  char control[CMSG_SPACE(256)];
+  msg.msg_control = control;
+  msg.msg_controllen = sizeof(control);
+
+  char received_data[20];
+  struct iovec iov;
+  iov.iov_base = received_data;
+  iov.iov_len = sizeof(received_data);
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+
+  ASSERT_THAT(RetryEINTR(recvmsg)(sockets->second_fd(), &msg, 0),
+              SyscallSucceedsWithValue(sizeof(received_data)));
+  EXPECT_EQ(0, memcmp(sent_data, received_data, sizeof(sent_data)));
+
+  struct cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  ASSERT_NE(cmsg, nullptr);
+  EXPECT_EQ(cmsg->cmsg_level, SOL_SOCKET);
+  EXPECT_EQ(cmsg->cmsg_type, SCM_SECURITY);
+  ASSERT_EQ(cmsg->cmsg_len, CMSG_LEN(peer_label_len));
+  EXPECT_EQ(absl::string_view(reinterpret_cast<char*>(CMSG_DATA(cmsg)),
+                              peer_label_len),
+            absl::string_view(peer_label, peer_label_len));
+  EXPECT_EQ(CMSG_NXTHDR(&msg, cmsg), nullptr);
+}
+
 }  // namespace
 
 }  // namespace testing
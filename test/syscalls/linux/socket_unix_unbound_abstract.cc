@@ -12,8 +12,12 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <ctype.h>
 #include <stdio.h>
+#include <string.h>
+#include <sys/socket.h>
 #include <sys/un.h>
+#include <unistd.h>
 
 #include "gtest/gtest.h"
 #include "test/syscalls/linux/unix_domain_socket_test_util.h"
@@ -148,6 +152,78 @@ TEST_P(UnboundAbstractUnixSocketPairTest, GetSockNamePartialLength) {
   EXPECT_EQ(addr_len, sockets->first_addr_size() - 1);
 }
 
+TEST_P(UnboundAbstractUnixSocketPairTest, Autobind) {
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  struct sockaddr_un addr = {.sun_family = AF_UNIX};
+  ASSERT_THAT(bind(sockets->first_fd(),
+                   reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(sa_family_t)),
+              SyscallSucceeds());
+  ASSERT_THAT(bind(sockets->second_fd(),
+                   reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(sa_family_t)),
+              SyscallSucceeds());
+
+  // Each socket is assigned a distinct abstract name of 5 hex digits.
+  struct sockaddr_un first = {};
+  socklen_t first_len = sizeof(first);
+  ASSERT_THAT(getsockname(sockets->first_fd(),
+                          reinterpret_cast<struct sockaddr*>(&first),
+                          &first_len),
+              SyscallSucceeds());
+  EXPECT_EQ(first_len, sizeof(sa_family_t) + 6);
+  EXPECT_EQ(first.sun_path[0], 0);
+  for (int i = 1; i < 6; i++) {
+    EXPECT_TRUE(isxdigit(first.sun_path[i]));
+  }
+
+  struct sockaddr_un second = {};
+  socklen_t second_len = sizeof(second);
+  ASSERT_THAT(getsockname(sockets->second_fd(),
+                          reinterpret_cast<struct sockaddr*>(&second),
+                          &second_len),
+              SyscallSucceeds());
+  EXPECT_EQ(second_len, first_len);
+  EXPECT_NE(memcmp(first.sun_path, second.sun_path, 6), 0);
+
+  // Autobinding a bound socket is a no-op.
+  ASSERT_THAT(bind(sockets->first_fd(),
+                   reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(sa_family_t)),
+              SyscallSucceeds());
+}
+
+TEST_P(UnboundAbstractUnixSocketPairTest, AutobindConnect) {
+  SKIP_IF((GetParam().type & SOCK_DGRAM) != 0);
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+
+  struct sockaddr_un addr = {.sun_family = AF_UNIX};
+  ASSERT_THAT(bind(sockets->first_fd(),
+                   reinterpret_cast<struct sockaddr*>(&addr),
+                   sizeof(sa_family_t)),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(sockets->first_fd(), 5), SyscallSucceeds());
+
+  socklen_t addr_len = sizeof(addr);
+  ASSERT_THAT(getsockname(sockets->first_fd(),
+                          reinterpret_cast<struct sockaddr*>(&addr),
+                          &addr_len),
+              SyscallSucceeds());
+  ASSERT_THAT(connect(sockets->second_fd(),
+                      reinterpret_cast<struct sockaddr*>(&addr), addr_len),
+              SyscallSucceeds());
+
+  struct ucred creds = {};
+  socklen_t creds_len = sizeof(creds);
+  ASSERT_THAT(getsockopt(sockets->second_fd(), SOL_SOCKET, SO_PEERCRED,
+                         &creds, &creds_len),
+              SyscallSucceeds());
+  EXPECT_EQ(creds.pid, getpid());
+  EXPECT_EQ(creds.uid, geteuid());
+  EXPECT_EQ(creds.gid, getegid());
+}
+
 INSTANTIATE_TEST_SUITE_P(
     AllUnixDomainSockets, UnboundAbstractUnixSocketPairTest,
     ::testing::ValuesIn(ApplyVec<SocketPairKind>(
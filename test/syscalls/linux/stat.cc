@@ -14,6 +14,7 @@
 
 #include <errno.h>
 #include <fcntl.h>
+#include <sys/ioctl.h>
 #include <sys/stat.h>
 #include <sys/statfs.h>
 #include <sys/types.h>
@@ -28,9 +29,11 @@
 #include "absl/strings/str_cat.h"
 #include "absl/strings/string_view.h"
 #include "test/syscalls/linux/file_base.h"
+#include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
 #include "test/util/save_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -523,6 +526,61 @@ TEST_F(StatTest, LinkCountsWithDirChild) {
   EXPECT_EQ(st_parent_after.st_nlink, 2);
 }
 
+// Test that a directory's link count tracks the number of subdirectories
+// across mkdir, rename and rmdir.
+TEST_F(StatTest, LinkCountsWithManyDirChildren) {
+  // See LinkCountsWithDirChild.
+  SKIP_IF(ASSERT_NO_ERRNO_AND_VALUE(IsOverlayfs(GetAbsoluteTestTmpdir())));
+
+  const TempPath dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const TempPath other_dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+
+  auto nlink = [](const std::string& path) -> nlink_t {
+    struct stat st = {};
+    EXPECT_THAT(stat(path.c_str(), &st), SyscallSucceeds());
+    return st.st_nlink;
+  };
+
+  // Each subdirectory adds one link to its parent, from its "..".
+  constexpr int kSubdirs = 4;
+  std::vector<std::string> subdirs;
+  for (int i = 0; i < kSubdirs; i++) {
+    subdirs.push_back(JoinPath(dir.path(), absl::StrCat("sub", i)));
+    ASSERT_THAT(mkdir(subdirs.back().c_str(), 0755), SyscallSucceeds());
+    EXPECT_EQ(nlink(dir.path()), 2 + i + 1);
+  }
+
+  // Regular files don't affect the parent's link count.
+  const TempPath file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(dir.path()));
+  EXPECT_EQ(nlink(dir.path()), 2 + kSubdirs);
+
+  // Renaming within the same directory doesn't change the link count.
+  const std::string renamed = JoinPath(dir.path(), "renamed");
+  ASSERT_THAT(rename(subdirs[0].c_str(), renamed.c_str()), SyscallSucceeds());
+  subdirs[0] = renamed;
+  EXPECT_EQ(nlink(dir.path()), 2 + kSubdirs);
+
+  // Moving a subdirectory to another directory moves its ".." link.
+  const std::string moved = JoinPath(other_dir.path(), "moved");
+  ASSERT_THAT(rename(subdirs[0].c_str(), moved.c_str()), SyscallSucceeds());
+  EXPECT_EQ(nlink(dir.path()), 2 + kSubdirs - 1);
+  EXPECT_EQ(nlink(other_dir.path()), 3);
+
+  // Replacing an empty directory removes the replaced directory's link.
+  ASSERT_THAT(rename(subdirs[1].c_str(), subdirs[2].c_str()),
+              SyscallSucceeds());
+  EXPECT_EQ(nlink(dir.path()), 2 + kSubdirs - 2);
+
+  // Removing the remaining subdirectories drops one link each.
+  ASSERT_THAT(rmdir(subdirs[2].c_str()), SyscallSucceeds());
+  EXPECT_EQ(nlink(dir.path()), 2 + kSubdirs - 3);
+  ASSERT_THAT(rmdir(subdirs[3].c_str()), SyscallSucceeds());
+  EXPECT_EQ(nlink(dir.path()), 2);
+  ASSERT_THAT(rmdir(moved.c_str()), SyscallSucceeds());
+  EXPECT_EQ(nlink(other_dir.path()), 2);
+}
+
 // Test statting a child of a non-directory.
 TEST_F(StatTest, ChildOfNonDir) {
   // Create a path that has a child of a regular file.
@@ -793,6 +851,112 @@ TEST_F(StatTest, StatxInvalidFlags) {
               SyscallFailsWithErrno(EINVAL));
 }
 
+#ifndef FS_IOC_GETFLAGS
+#define FS_IOC_GETFLAGS 2148034049
+#endif  // FS_IOC_GETFLAGS
+
+#ifndef FS_IOC_SETFLAGS
+#define FS_IOC_SETFLAGS 1074292226
+#endif  // FS_IOC_SETFLAGS
+
+#ifndef FS_IMMUTABLE_FL
+#define FS_IMMUTABLE_FL 0x00000010
+#endif  // FS_IMMUTABLE_FL
+
+#ifndef STATX_ATTR_IMMUTABLE
+#define STATX_ATTR_IMMUTABLE 0x00000010
+#endif  // STATX_ATTR_IMMUTABLE
+
+#ifndef STATX_ATTR_APPEND
+#define STATX_ATTR_APPEND 0x00000020
+#endif  // STATX_ATTR_APPEND
+
+TEST(StatxAttributesTest, AttributesMask) {
+  SKIP_IF(!IsRunningOnGvisor() && statx(-1, nullptr, 0, 0, nullptr) < 0 &&
+          errno == ENOSYS);
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  // Use a tmpfs mount, since TEST_TMPDIR may not support inode flags.
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+  const std::string path = JoinPath(dir.path(), "file");
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_RDONLY | O_CREAT, 0644));
+
+  struct kernel_statx stx;
+  ASSERT_THAT(statx(fd.get(), "", AT_EMPTY_PATH, STATX_ALL, &stx),
+              SyscallSucceeds());
+  // Linux's tmpfs only supports inode flags since 6.0.
+  SKIP_IF(!IsRunningOnGvisor() && stx.stx_attributes_mask == 0);
+  EXPECT_NE(stx.stx_attributes_mask & STATX_ATTR_IMMUTABLE, 0);
+  EXPECT_NE(stx.stx_attributes_mask & STATX_ATTR_APPEND, 0);
+  EXPECT_EQ(stx.stx_attributes & (STATX_ATTR_IMMUTABLE | STATX_ATTR_APPEND),
+            0);
+}
+
+TEST(StatxAttributesTest, Immutable) {
+  SKIP_IF(!IsRunningOnGvisor() && statx(-1, nullptr, 0, 0, nullptr) < 0 &&
+          errno == ENOSYS);
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_LINUX_IMMUTABLE)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+  const std::string path = JoinPath(dir.path(), "file");
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_RDONLY | O_CREAT, 0644));
+
+  // Linux's tmpfs only supports inode flags since 6.0.
+  int flags = 0;
+  SKIP_IF(!IsRunningOnGvisor() &&
+          ioctl(fd.get(), FS_IOC_GETFLAGS, &flags) < 0 && errno == ENOTTY);
+  ASSERT_THAT(ioctl(fd.get(), FS_IOC_GETFLAGS, &flags), SyscallSucceeds());
+  EXPECT_EQ(flags & FS_IMMUTABLE_FL, 0);
+
+  struct kernel_statx stx;
+  flags |= FS_IMMUTABLE_FL;
+  ASSERT_THAT(ioctl(fd.get(), FS_IOC_SETFLAGS, &flags), SyscallSucceeds());
+  ASSERT_THAT(ioctl(fd.get(), FS_IOC_GETFLAGS, &flags), SyscallSucceeds());
+  EXPECT_NE(flags & FS_IMMUTABLE_FL, 0);
+  ASSERT_THAT(statx(AT_FDCWD, path.c_str(), 0, STATX_ALL, &stx),
+              SyscallSucceeds());
+  EXPECT_NE(stx.stx_attributes & STATX_ATTR_IMMUTABLE, 0);
+
+  // Immutable files can't be modified or removed.
+  EXPECT_THAT(open(path.c_str(), O_WRONLY), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(chmod(path.c_str(), 0600), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(unlink(path.c_str()), SyscallFailsWithErrno(EPERM));
+
+  flags &= ~FS_IMMUTABLE_FL;
+  ASSERT_THAT(ioctl(fd.get(), FS_IOC_SETFLAGS, &flags), SyscallSucceeds());
+  ASSERT_THAT(statx(AT_FDCWD, path.c_str(), 0, STATX_ALL, &stx),
+              SyscallSucceeds());
+  EXPECT_EQ(stx.stx_attributes & STATX_ATTR_IMMUTABLE, 0);
+  EXPECT_THAT(unlink(path.c_str()), SyscallSucceeds());
+}
+
+TEST(StatxAttributesTest, SetFlagsRequiresCapLinuxImmutable) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_LINUX_IMMUTABLE)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(JoinPath(dir.path(), "file"), O_RDONLY | O_CREAT, 0644));
+
+  int flags = 0;
+  SKIP_IF(!IsRunningOnGvisor() &&
+          ioctl(fd.get(), FS_IOC_GETFLAGS, &flags) < 0 && errno == ENOTTY);
+
+  AutoCapability cap(CAP_LINUX_IMMUTABLE, false);
+  flags = FS_IMMUTABLE_FL;
+  EXPECT_THAT(ioctl(fd.get(), FS_IOC_SETFLAGS, &flags),
+              SyscallFailsWithErrno(EPERM));
+}
+
 }  // namespace
 
 }  // namespace testing
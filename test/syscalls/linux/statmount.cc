@@ -0,0 +1,304 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <linux/magic.h>
+#include <stdint.h>
+#include <sys/mount.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <string>
+#include <vector>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// Definitions from include/uapi/linux/mount.h, which may not be available in
+// the system headers.
+
+#ifndef SYS_statmount
+#define SYS_statmount 457
+#endif
+
+#ifndef SYS_listmount
+#define SYS_listmount 458
+#endif
+
+struct mnt_id_req_v0 {
+  uint32_t size;
+  uint32_t spare;
+  uint64_t mnt_id;
+  uint64_t param;
+};
+
+struct statmount_v0 {
+  uint32_t size;
+  uint32_t spare1;
+  uint64_t mask;
+  uint32_t sb_dev_major;
+  uint32_t sb_dev_minor;
+  uint64_t sb_magic;
+  uint32_t sb_flags;
+  uint32_t fs_type;
+  uint64_t mnt_id;
+  uint64_t mnt_parent_id;
+  uint32_t mnt_id_old;
+  uint32_t mnt_parent_id_old;
+  uint64_t mnt_attr;
+  uint64_t mnt_propagation;
+  uint64_t mnt_peer_group;
+  uint64_t mnt_master;
+  uint64_t propagate_from;
+  uint32_t mnt_root;
+  uint32_t mnt_point;
+  uint64_t spare2[50];
+  char str[];
+};
+
+constexpr uint64_t kStatmountSbBasic = 0x1;
+constexpr uint64_t kStatmountMntBasic = 0x2;
+constexpr uint64_t kStatmountMntRoot = 0x8;
+constexpr uint64_t kStatmountMntPoint = 0x10;
+constexpr uint64_t kStatmountFsType = 0x20;
+constexpr uint64_t kLsmtRoot = 0xffffffffffffffff;
+constexpr uint64_t kMountAttrRdonly = 0x1;
+constexpr uint64_t kMountAttrNoexec = 0x8;
+
+int statmount(uint64_t mnt_id, uint64_t mask, struct statmount_v0* buf,
+              size_t bufsize, unsigned int flags) {
+  struct mnt_id_req_v0 req = {};
+  req.size = sizeof(req);
+  req.mnt_id = mnt_id;
+  req.param = mask;
+  return syscall(SYS_statmount, &req, buf, bufsize, flags);
+}
+
+int listmount(uint64_t mnt_id, uint64_t last_mnt_id, uint64_t* ids,
+              size_t nr_ids) {
+  struct mnt_id_req_v0 req = {};
+  req.size = sizeof(req);
+  req.mnt_id = mnt_id;
+  req.param = last_mnt_id;
+  return syscall(SYS_listmount, &req, ids, nr_ids, 0);
+}
+
+// A statmount result with room for strings.
+struct StatmountBuf {
+  struct statmount_v0 sm;
+  char str[4096];
+};
+
+PosixErrorOr<std::vector<uint64_t>> ListAllMounts() {
+  std::vector<uint64_t> ids(1024);
+  int n = listmount(kLsmtRoot, 0, ids.data(), ids.size());
+  if (n < 0) {
+    return PosixError(errno, "listmount");
+  }
+  ids.resize(n);
+  return ids;
+}
+
+// Returns the ID of the mount whose mount point is path.
+PosixErrorOr<uint64_t> FindMount(const std::string& path) {
+  ASSIGN_OR_RETURN_ERRNO(std::vector<uint64_t> ids, ListAllMounts());
+  for (uint64_t id : ids) {
+    StatmountBuf buf = {};
+    if (statmount(id, kStatmountMntPoint, &buf.sm, sizeof(buf), 0) < 0) {
+      return PosixError(errno, "statmount");
+    }
+    if (path == buf.sm.str + buf.sm.mnt_point) {
+      return id;
+    }
+  }
+  return PosixError(ENOENT, absl::StrCat("no mount at ", path));
+}
+
+class StatmountTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    // VFS1 doesn't implement statmount(2) or listmount(2).
+    SKIP_IF(IsRunningWithVFS1());
+    // statmount(2) and listmount(2) were added in Linux 6.8.
+    SKIP_IF(!IsRunningOnGvisor() &&
+            syscall(SYS_listmount, nullptr, nullptr, 0, 0) < 0 &&
+            errno == ENOSYS);
+  }
+};
+
+TEST_F(StatmountTest, ConsistentWithMountInfo) {
+  std::vector<uint64_t> ids = ASSERT_NO_ERRNO_AND_VALUE(ListAllMounts());
+  ASSERT_FALSE(ids.empty());
+  for (size_t i = 1; i < ids.size(); i++) {
+    EXPECT_LT(ids[i - 1], ids[i]);
+  }
+
+  std::vector<ProcMountInfoEntry> entries =
+      ASSERT_NO_ERRNO_AND_VALUE(ProcSelfMountInfoEntries());
+  for (const auto& e : entries) {
+    if (e.mount_point == "/") {
+      // Whether the root mount is listed depends on the Linux version.
+      continue;
+    }
+    bool found = false;
+    for (uint64_t id : ids) {
+      StatmountBuf buf = {};
+      ASSERT_THAT(statmount(id,
+                            kStatmountMntBasic | kStatmountMntPoint |
+                                kStatmountFsType | kStatmountMntRoot,
+                            &buf.sm, sizeof(buf), 0),
+                  SyscallSucceeds());
+      if (buf.sm.mnt_id_old != e.id) {
+        continue;
+      }
+      found = true;
+      EXPECT_EQ(buf.sm.mnt_id, id);
+      EXPECT_EQ(buf.sm.mnt_parent_id_old, e.parent_id);
+      EXPECT_EQ(buf.sm.str + buf.sm.mnt_point, e.mount_point);
+      EXPECT_EQ(buf.sm.str + buf.sm.fs_type, e.fstype);
+      EXPECT_EQ(buf.sm.str + buf.sm.mnt_root, e.root);
+      break;
+    }
+    EXPECT_TRUE(found) << "mount " << e.id << " at " << e.mount_point
+                       << " not listed";
+  }
+}
+
+TEST_F(StatmountTest, ListmountPagination) {
+  std::vector<uint64_t> all = ASSERT_NO_ERRNO_AND_VALUE(ListAllMounts());
+
+  std::vector<uint64_t> paged;
+  uint64_t last = 0;
+  while (true) {
+    uint64_t id;
+    int n;
+    ASSERT_THAT(n = listmount(kLsmtRoot, last, &id, 1), SyscallSucceeds());
+    if (n == 0) {
+      break;
+    }
+    ASSERT_EQ(n, 1);
+    paged.push_back(id);
+    last = id;
+  }
+  EXPECT_EQ(paged, all);
+}
+
+TEST_F(StatmountTest, Tmpfs) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", MS_RDONLY | MS_NOEXEC, "", 0));
+  uint64_t id = ASSERT_NO_ERRNO_AND_VALUE(FindMount(dir.path()));
+
+  StatmountBuf buf = {};
+  ASSERT_THAT(statmount(id,
+                        kStatmountSbBasic | kStatmountMntBasic |
+                            kStatmountFsType | kStatmountMntPoint,
+                        &buf.sm, sizeof(buf), 0),
+              SyscallSucceeds());
+  EXPECT_EQ(buf.sm.mask, kStatmountSbBasic | kStatmountMntBasic |
+                             kStatmountFsType | kStatmountMntPoint);
+  EXPECT_EQ(buf.sm.mnt_id, id);
+  EXPECT_EQ(buf.sm.sb_magic, TMPFS_MAGIC);
+  EXPECT_EQ(buf.sm.sb_flags & MS_RDONLY, MS_RDONLY);
+  EXPECT_EQ(buf.sm.mnt_attr & (kMountAttrRdonly | kMountAttrNoexec),
+            kMountAttrRdonly | kMountAttrNoexec);
+  if (IsRunningOnGvisor()) {
+    // gVisor doesn't support mount propagation.
+    EXPECT_EQ(buf.sm.mnt_propagation, MS_PRIVATE);
+  }
+  EXPECT_EQ(std::string(buf.sm.str + buf.sm.fs_type), "tmpfs");
+  EXPECT_EQ(std::string(buf.sm.str + buf.sm.mnt_point), dir.path());
+  EXPECT_GE(buf.sm.size, sizeof(buf.sm) + dir.path().size() + 1);
+
+  // The new mount has no mounts below it, and isn't listed itself.
+  uint64_t ids[2];
+  EXPECT_THAT(listmount(id, 0, ids, 2), SyscallSucceedsWithValue(0));
+}
+
+TEST_F(StatmountTest, ListmountSubmounts) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount =
+      ASSERT_NO_ERRNO_AND_VALUE(Mount("", dir.path(), "tmpfs", 0, "", 0));
+  auto const child_dir =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(dir.path()));
+  auto const child_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", child_dir.path(), "tmpfs", 0, "", 0));
+  uint64_t parent_id = ASSERT_NO_ERRNO_AND_VALUE(FindMount(dir.path()));
+  uint64_t child_id = ASSERT_NO_ERRNO_AND_VALUE(FindMount(child_dir.path()));
+
+  // Only the child is listed below the parent.
+  uint64_t ids[2];
+  int n;
+  ASSERT_THAT(n = listmount(parent_id, 0, ids, 2), SyscallSucceeds());
+  ASSERT_EQ(n, 1);
+  EXPECT_EQ(ids[0], child_id);
+}
+
+TEST_F(StatmountTest, BufferTooSmall) {
+  uint64_t id = ASSERT_NO_ERRNO_AND_VALUE(ListAllMounts())[0];
+
+  // No room for strings.
+  StatmountBuf buf = {};
+  EXPECT_THAT(statmount(id, kStatmountMntPoint, &buf.sm, sizeof(buf.sm), 0),
+              SyscallFailsWithErrno(EOVERFLOW));
+
+  // Without strings, a buffer smaller than struct statmount is filled as far
+  // as it goes.
+  constexpr size_t kSmallSize = 32;
+  ASSERT_THAT(statmount(id, kStatmountSbBasic, &buf.sm, kSmallSize, 0),
+              SyscallSucceeds());
+  EXPECT_EQ(buf.sm.size, kSmallSize);
+  EXPECT_EQ(buf.sm.mask, kStatmountSbBasic);
+}
+
+TEST_F(StatmountTest, Invalid) {
+  uint64_t id = ASSERT_NO_ERRNO_AND_VALUE(ListAllMounts())[0];
+  StatmountBuf buf = {};
+
+  EXPECT_THAT(statmount(id, kStatmountMntBasic, &buf.sm, sizeof(buf), 1),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(statmount(kLsmtRoot - 1, kStatmountMntBasic, &buf.sm,
+                        sizeof(buf), 0),
+              SyscallFailsWithErrno(ENOENT));
+
+  struct mnt_id_req_v0 req = {};
+  req.size = sizeof(req) - 1;
+  req.mnt_id = id;
+  req.param = kStatmountMntBasic;
+  EXPECT_THAT(syscall(SYS_statmount, &req, &buf.sm, sizeof(buf), 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  uint64_t ids[1];
+  EXPECT_THAT(listmount(kLsmtRoot - 1, 0, ids, 1),
+              SyscallFailsWithErrno(ENOENT));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
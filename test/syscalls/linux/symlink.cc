@@ -14,6 +14,7 @@
 
 #include <errno.h>
 #include <fcntl.h>
+#include <limits.h>
 #include <string.h>
 #include <unistd.h>
 
@@ -134,6 +135,32 @@ TEST(SymlinkTest, OldnameIsEmpty) {
   EXPECT_THAT(symlink("", newname.c_str()), SyscallFailsWithErrno(ENOENT));
 }
 
+TEST(SymlinkTest, OldnameIsMaxLength) {
+  // The longest valid target is PATH_MAX bytes including the terminating NUL.
+  const std::string target(PATH_MAX - 1, 'a');
+  const std::string newname = NewTempAbsPath();
+  ASSERT_THAT(symlink(target.c_str(), newname.c_str()), SyscallSucceeds());
+
+  std::string buf(PATH_MAX, '\0');
+  ASSERT_THAT(readlink(newname.c_str(), &buf[0], buf.size()),
+              SyscallSucceedsWithValue(target.size()));
+  buf.resize(target.size());
+  EXPECT_EQ(buf, target);
+
+  // This is required for S/R random save tests, which pre-run this test
+  // in the same TEST_TMPDIR, which means that we need to clean it for any
+  // operations exclusively creating files, like symlink above.
+  EXPECT_THAT(unlink(newname.c_str()), SyscallSucceeds());
+}
+
+TEST(SymlinkTest, OldnameIsTooLong) {
+  const std::string target(PATH_MAX, 'a');
+  const std::string newname = NewTempAbsPath();
+  EXPECT_THAT(symlink(target.c_str(), newname.c_str()),
+              SyscallFailsWithErrno(ENAMETOOLONG));
+  EXPECT_THAT(unlink(newname.c_str()), SyscallFailsWithErrno(ENOENT));
+}
+
 TEST(SymlinkTest, OldnameIsDangling) {
   const std::string newname = NewTempAbsPath();
   EXPECT_THAT(symlink("/dangling", newname.c_str()), SyscallSucceeds());
@@ -449,6 +476,39 @@ TEST_P(ParamSymlinkTest, OpenLinkExclFails) {
   ASSERT_THAT(unlink(linkpath.c_str()), SyscallSucceeds());
 }
 
+// Test that opening a symlink to an existing file with O_CREAT|O_EXCL will
+// fail with EEXIST.
+TEST_P(ParamSymlinkTest, OpenValidLinkExclFails) {
+  const std::string target = GetParam();
+  const std::string linkpath = NewTempAbsPath();
+
+  ASSERT_THAT(chdir(GetAbsoluteTestTmpdir().c_str()), SyscallSucceeds());
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(target, O_CREAT, 0666));
+  ASSERT_THAT(symlink(target.c_str(), linkpath.c_str()), SyscallSucceeds());
+
+  EXPECT_THAT(open(linkpath.c_str(), O_CREAT | O_EXCL, 0666),
+              SyscallFailsWithErrno(EEXIST));
+  EXPECT_THAT(open(linkpath.c_str(), O_CREAT | O_EXCL | O_NOFOLLOW, 0666),
+              SyscallFailsWithErrno(EEXIST));
+
+  ASSERT_THAT(unlink(linkpath.c_str()), SyscallSucceeds());
+  ASSERT_THAT(unlink(target.c_str()), SyscallSucceeds());
+}
+
+// Test that opening a self-symlink with O_CREAT|O_EXCL will fail with EEXIST
+// rather than ELOOP, since the symlink is not followed.
+TEST_P(ParamSymlinkTest, OpenSelfLinkExclFails) {
+  ASSERT_THAT(chdir(GetAbsoluteTestTmpdir().c_str()), SyscallSucceeds());
+
+  const std::string linkpath = GetParam();
+  ASSERT_THAT(symlink(linkpath.c_str(), linkpath.c_str()), SyscallSucceeds());
+
+  EXPECT_THAT(open(linkpath.c_str(), O_CREAT | O_EXCL, 0666),
+              SyscallFailsWithErrno(EEXIST));
+
+  ASSERT_THAT(unlink(linkpath.c_str()), SyscallSucceeds());
+}
+
 // Test that opening an existing symlink with O_CREAT|O_NOFOLLOW will fail with
 // ELOOP.
 TEST_P(ParamSymlinkTest, OpenLinkNoFollowFails) {
@@ -14,12 +14,16 @@
 
 #include <fcntl.h>
 #include <stdio.h>
+#include <string.h>
+#include <sys/mman.h>
 #include <sys/syscall.h>
 #include <unistd.h>
 
 #include <string>
 
 #include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
@@ -49,6 +53,37 @@ TEST(SyncTest, SyncFromPipe) {
   EXPECT_THAT(close(pipes[1]), SyscallSucceeds());
 }
 
+TEST(SyncTest, SyncFlushesDirtyData) {
+  constexpr char kData[] = "sync";
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), std::string(kPageSize, '\0'),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDWR));
+
+  // Dirty both cached file data, through a shared mapping, and the file
+  // itself, through write(2).
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(Mmap(
+      nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED, fd.get(), 0));
+  memcpy(m.ptr(), kData, sizeof(kData));
+  ASSERT_THAT(pwrite(fd.get(), kData, sizeof(kData), kPageSize / 2),
+              SyscallSucceedsWithValue(sizeof(kData)));
+
+  EXPECT_THAT(syncfs(fd.get()), SyscallSucceeds());
+  EXPECT_THAT(syscall(SYS_sync), SyscallSucceeds());
+
+  // The data must be visible through an independent file description.
+  const FileDescriptor fd2 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  char buf[sizeof(kData)];
+  ASSERT_THAT(pread(fd2.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+  ASSERT_THAT(pread(fd2.get(), buf, sizeof(buf), kPageSize / 2),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_STREQ(buf, kData);
+}
+
 TEST(SyncTest, CannotSyncFileSystemAtBadFd) {
   EXPECT_THAT(syncfs(-1), SyscallFailsWithErrno(EBADF));
 }
@@ -824,6 +824,146 @@ TEST_P(TcpSocketTest, TimeWaitPollHUP) {
   ASSERT_THAT(poll(&pfd, 1, kTimeout), SyscallSucceedsWithValue(1));
 }
 
+TEST_P(TcpSocketTest, RcvlowatDefault) {
+  int val = 0;
+  socklen_t len = sizeof(val);
+  ASSERT_THAT(getsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, sizeof(val));
+  EXPECT_EQ(val, 1);
+
+  // Zero is treated as 1.
+  val = 0;
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, sizeof(val)),
+      SyscallSucceeds());
+  ASSERT_THAT(getsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(val, 1);
+}
+
+TEST_P(TcpSocketTest, RcvlowatLimitedByReceiveBuffer) {
+  // With an explicitly sized receive buffer, SO_RCVLOWAT is limited to half of
+  // it (the buffer size reported by SO_RCVBUF).
+  int rcvbuf = 8192;
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVBUF, &rcvbuf, sizeof(rcvbuf)),
+      SyscallSucceeds());
+  socklen_t len = sizeof(rcvbuf);
+  ASSERT_THAT(getsockopt(second_fd, SOL_SOCKET, SO_RCVBUF, &rcvbuf, &len),
+              SyscallSucceeds());
+
+  int val = std::numeric_limits<int>::max();
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, sizeof(val)),
+      SyscallSucceeds());
+  len = sizeof(val);
+  ASSERT_THAT(getsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, &len),
+              SyscallSucceeds());
+  EXPECT_GT(val, 1);
+  EXPECT_LE(val, rcvbuf / 2);
+}
+
+TEST_P(TcpSocketTest, RcvlowatPoll) {
+  constexpr int kRcvlowat = 10;
+  int val = kRcvlowat;
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, sizeof(val)),
+      SyscallSucceeds());
+
+  char buf[kRcvlowat] = {};
+  ASSERT_THAT(RetryEINTR(write)(first_fd, buf, kRcvlowat / 2),
+              SyscallSucceedsWithValue(kRcvlowat / 2));
+
+  // Wait for the data to be queued.
+  int size = 0;
+  while (size != kRcvlowat / 2) {
+    ASSERT_THAT(ioctl(second_fd, TIOCINQ, &size), SyscallSucceeds());
+    if (size != kRcvlowat / 2) {
+      absl::SleepFor(absl::Milliseconds(10));
+    }
+  }
+
+  // Fewer than SO_RCVLOWAT bytes are queued, so the socket isn't readable.
+  struct pollfd pfd = {.fd = second_fd, .events = POLLIN};
+  EXPECT_THAT(poll(&pfd, 1, 0), SyscallSucceedsWithValue(0));
+
+  ASSERT_THAT(RetryEINTR(write)(first_fd, buf, kRcvlowat / 2),
+              SyscallSucceedsWithValue(kRcvlowat / 2));
+  constexpr int kTimeout = 10000;
+  ASSERT_THAT(poll(&pfd, 1, kTimeout), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(pfd.revents, POLLIN);
+
+  // EOF makes the socket readable regardless of SO_RCVLOWAT.
+  char rbuf[kRcvlowat];
+  ASSERT_THAT(RetryEINTR(read)(second_fd, rbuf, sizeof(rbuf) - 1),
+              SyscallSucceedsWithValue(sizeof(rbuf) - 1));
+  EXPECT_THAT(poll(&pfd, 1, 0), SyscallSucceedsWithValue(0));
+  ASSERT_THAT(shutdown(first_fd, SHUT_WR), SyscallSucceeds());
+  ASSERT_THAT(poll(&pfd, 1, kTimeout), SyscallSucceedsWithValue(1));
+  EXPECT_TRUE(pfd.revents & POLLIN);
+}
+
+TEST_P(TcpSocketTest, RcvlowatBlockingRecv) {
+  constexpr int kRcvlowat = 10;
+  int val = kRcvlowat;
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, sizeof(val)),
+      SyscallSucceeds());
+
+  const DisableSave disable_save;  // Timing-related.
+  ScopedThread t([&]() {
+    char buf[kRcvlowat / 2] = {};
+    // Give the reader time to block.
+    absl::SleepFor(absl::Milliseconds(250));
+    ASSERT_THAT(RetryEINTR(write)(first_fd, buf, sizeof(buf)),
+                SyscallSucceedsWithValue(sizeof(buf)));
+    absl::SleepFor(absl::Milliseconds(50));
+    ASSERT_THAT(RetryEINTR(write)(first_fd, buf, sizeof(buf)),
+                SyscallSucceedsWithValue(sizeof(buf)));
+  });
+
+  // The read doesn't return until SO_RCVLOWAT bytes are available.
+  char buf[2 * kRcvlowat];
+  EXPECT_THAT(RetryEINTR(recv)(second_fd, buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(kRcvlowat));
+  t.Join();
+
+  // A read smaller than SO_RCVLOWAT returns once it's satisfied.
+  ASSERT_THAT(RetryEINTR(write)(first_fd, buf, kRcvlowat),
+              SyscallSucceedsWithValue(kRcvlowat));
+  EXPECT_THAT(RetryEINTR(recv)(second_fd, buf, kRcvlowat / 2, 0),
+              SyscallSucceedsWithValue(kRcvlowat / 2));
+
+  // MSG_DONTWAIT returns whatever is available.
+  EXPECT_THAT(RetryEINTR(recv)(second_fd, buf, sizeof(buf), MSG_DONTWAIT),
+              SyscallSucceedsWithValue(kRcvlowat / 2));
+}
+
+TEST_P(TcpSocketTest, RcvlowatWithWaitAll) {
+  constexpr int kRcvlowat = 4;
+  int val = kRcvlowat;
+  ASSERT_THAT(
+      setsockopt(second_fd, SOL_SOCKET, SO_RCVLOWAT, &val, sizeof(val)),
+      SyscallSucceeds());
+
+  constexpr int kSize = 64;
+  const DisableSave disable_save;  // Timing-related.
+  ScopedThread t([&]() {
+    char buf[kSize / 2] = {};
+    ASSERT_THAT(RetryEINTR(write)(first_fd, buf, sizeof(buf)),
+                SyscallSucceedsWithValue(sizeof(buf)));
+    absl::SleepFor(absl::Milliseconds(250));
+    ASSERT_THAT(RetryEINTR(write)(first_fd, buf, sizeof(buf)),
+                SyscallSucceedsWithValue(sizeof(buf)));
+  });
+
+  // MSG_WAITALL waits for the whole buffer, not just SO_RCVLOWAT bytes.
+  char buf[kSize];
+  EXPECT_THAT(RetryEINTR(recv)(second_fd, buf, sizeof(buf), MSG_WAITALL),
+              SyscallSucceedsWithValue(kSize));
+}
+
 INSTANTIATE_TEST_SUITE_P(AllInetTests, TcpSocketTest,
                          ::testing::Values(AF_INET, AF_INET6));
 
@@ -1720,6 +1860,44 @@ TEST_P(SimpleTcpSocketTest, SetTCPDeferAcceptGreaterThanZero) {
   EXPECT_EQ(get, kTCPDeferAccept);
 }
 
+TEST_P(SimpleTcpSocketTest, SetTCPDeferAcceptRoundsUp) {
+  FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+
+  // TCP_DEFER_ACCEPT is rounded up to the end of the SYN-ACK retransmission
+  // period it falls in. Retransmissions happen after 1, 3, 7, ... seconds.
+  constexpr int kTCPDeferAccept = 5;
+  ASSERT_THAT(setsockopt(s.get(), IPPROTO_TCP, TCP_DEFER_ACCEPT,
+                         &kTCPDeferAccept, sizeof(kTCPDeferAccept)),
+              SyscallSucceeds());
+  int get = -1;
+  socklen_t get_len = sizeof(get);
+  ASSERT_THAT(
+      getsockopt(s.get(), IPPROTO_TCP, TCP_DEFER_ACCEPT, &get, &get_len),
+      SyscallSucceeds());
+  EXPECT_EQ(get_len, sizeof(get));
+  EXPECT_EQ(get, 7);
+}
+
+TEST_P(SimpleTcpSocketTest, SetTCPDeferAcceptMax) {
+  FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+
+  // TCP_DEFER_ACCEPT spans at most 255 SYN-ACK retransmissions, with the
+  // retransmission timeout capped at 120 seconds.
+  constexpr int kTCPDeferAccept = std::numeric_limits<int>::max();
+  ASSERT_THAT(setsockopt(s.get(), IPPROTO_TCP, TCP_DEFER_ACCEPT,
+                         &kTCPDeferAccept, sizeof(kTCPDeferAccept)),
+              SyscallSucceeds());
+  int get = -1;
+  socklen_t get_len = sizeof(get);
+  ASSERT_THAT(
+      getsockopt(s.get(), IPPROTO_TCP, TCP_DEFER_ACCEPT, &get, &get_len),
+      SyscallSucceeds());
+  EXPECT_EQ(get_len, sizeof(get));
+  EXPECT_EQ(get, 29887);
+}
+
 TEST_P(SimpleTcpSocketTest, RecvOnClosedSocket) {
   auto s =
       ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
@@ -2148,6 +2326,83 @@ TEST_P(TcpSocketTest, SendUnblocksOnSendBufferIncrease) {
   send_thread.Join();
 }
 
+#ifndef IPPROTO_MPTCP
+#define IPPROTO_MPTCP 262
+#endif
+#ifndef SOL_MPTCP
+#define SOL_MPTCP 284
+#endif
+#ifndef MPTCP_INFO
+#define MPTCP_INFO 1
+#endif
+#ifndef MPTCP_INFO_FLAG_FALLBACK
+#define MPTCP_INFO_FLAG_FALLBACK 1
+#endif
+
+// Tests that IPPROTO_MPTCP sockets can connect to a TCP listener, falling back
+// to TCP.
+TEST_P(SimpleTcpSocketTest, MPTCPFallback) {
+  int fd = socket(GetParam(), SOCK_STREAM, IPPROTO_MPTCP);
+  if (fd < 0) {
+    // Linux may not have MPTCP support.
+    SKIP_IF(!IsRunningOnGvisor() && (errno == EPROTONOSUPPORT ||
+                                     errno == ENOPROTOOPT || errno == EINVAL));
+    FAIL() << "socket(IPPROTO_MPTCP) failed: " << errno;
+  }
+  FileDescriptor client(fd);
+
+  int protocol;
+  socklen_t protocol_len = sizeof(protocol);
+  ASSERT_THAT(
+      getsockopt(client.get(), SOL_SOCKET, SO_PROTOCOL, &protocol, &protocol_len),
+      SyscallSucceeds());
+  EXPECT_EQ(protocol, IPPROTO_MPTCP);
+
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+  sockaddr_storage addr =
+      ASSERT_NO_ERRNO_AND_VALUE(InetLoopbackAddr(GetParam()));
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&addr), addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), SOMAXCONN), SyscallSucceeds());
+  ASSERT_THAT(getsockname(listener.get(), AsSockAddr(&addr), &addrlen),
+              SyscallSucceeds());
+
+  ASSERT_THAT(RetryEINTR(connect)(client.get(), AsSockAddr(&addr), addrlen),
+              SyscallSucceeds());
+  FileDescriptor server = ASSERT_NO_ERRNO_AND_VALUE(
+      Accept(listener.get(), nullptr, nullptr));
+
+  char buf = 'a';
+  ASSERT_THAT(RetryEINTR(send)(client.get(), &buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  ASSERT_THAT(RetryEINTR(recv)(server.get(), &buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(buf, 'a');
+
+  // Older versions of Linux don't support MPTCP_INFO once the connection has
+  // fallen back to TCP.
+  if (IsRunningOnGvisor() && !IsRunningWithHostinet()) {
+    // struct mptcp_info from uapi/linux/mptcp.h.
+    struct {
+      uint8_t counters[6];
+      uint32_t flags;
+      uint32_t token;
+      uint64_t seqs[3];
+      uint8_t local_addr_used;
+      uint8_t local_addr_max;
+      uint8_t csum_enabled;
+    } info = {};
+    socklen_t info_len = sizeof(info);
+    ASSERT_THAT(
+        getsockopt(client.get(), SOL_MPTCP, MPTCP_INFO, &info, &info_len),
+        SyscallSucceeds());
+    EXPECT_EQ(info_len, sizeof(info));
+    EXPECT_NE(info.flags & MPTCP_INFO_FLAG_FALLBACK, 0);
+  }
+}
+
 INSTANTIATE_TEST_SUITE_P(AllInetTests, SimpleTcpSocketTest,
                          ::testing::Values(AF_INET, AF_INET6));
 
@@ -15,11 +15,14 @@
 #include <errno.h>
 #include <signal.h>
 #include <sys/resource.h>
+#include <sys/socket.h>
 #include <sys/stat.h>
+#include <sys/un.h>
 #include <sys/vfs.h>
 #include <time.h>
 #include <unistd.h>
 
+#include <cstring>
 #include <iostream>
 #include <string>
 
@@ -238,6 +241,50 @@ TEST(TruncateTest, FtruncateVirtualTmp) {
   EXPECT_THAT(ftruncate(fd.get(), 100), SyscallSucceeds());
 }
 
+TEST(TruncateTest, FtruncatePipe) {
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  const FileDescriptor rfd(fds[0]);
+  const FileDescriptor wfd(fds[1]);
+
+  EXPECT_THAT(ftruncate(rfd.get(), 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(ftruncate(wfd.get(), 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TruncateTest, FtruncateSocket) {
+  int fds[2];
+  ASSERT_THAT(socketpair(AF_UNIX, SOCK_STREAM, 0, fds), SyscallSucceeds());
+  const FileDescriptor fd1(fds[0]);
+  const FileDescriptor fd2(fds[1]);
+
+  EXPECT_THAT(ftruncate(fd1.get(), 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(ftruncate(fd2.get(), 100), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TruncateTest, TruncateBoundSocket) {
+  int sock;
+  ASSERT_THAT(sock = socket(AF_UNIX, SOCK_STREAM, 0), SyscallSucceeds());
+  const FileDescriptor fd(sock);
+  const std::string path = NewTempAbsPath();
+  struct sockaddr_un addr = {};
+  addr.sun_family = AF_UNIX;
+  ASSERT_LT(path.size(), sizeof(addr.sun_path));
+  memcpy(addr.sun_path, path.c_str(), path.size());
+  ASSERT_THAT(
+      bind(fd.get(), reinterpret_cast<struct sockaddr*>(&addr), sizeof(addr)),
+      SyscallSucceeds());
+  const Cleanup unlink_path([&] { unlink(path.c_str()); });
+
+  EXPECT_THAT(truncate(path.c_str(), 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(ftruncate(fd.get(), 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(TruncateTest, FtruncateCharDevice) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_WRONLY));
+  EXPECT_THAT(ftruncate(fd.get(), 0), SyscallFailsWithErrno(EINVAL));
+}
+
 // NOTE: There are additional truncate(2)/ftruncate(2) tests in mknod.cc
 // which are there to avoid running the tests on a number of different
 // filesystems which may not support mknod.
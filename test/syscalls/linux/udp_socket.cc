@@ -1583,6 +1583,54 @@ TEST_P(UdpSocketTest, SoTimestamp) {
   }
 }
 
+TEST_P(UdpSocketTest, SoTimestampTruncated) {
+  ASSERT_NO_ERRNO(BindLoopback());
+  ASSERT_THAT(connect(sock_.get(), bind_addr_, addrlen_), SyscallSucceeds());
+
+  int v = 1;
+  ASSERT_THAT(setsockopt(bind_.get(), SOL_SOCKET, SO_TIMESTAMP, &v, sizeof(v)),
+              SyscallSucceeds());
+
+  char buf[3];
+  for (int i = 0; i < 2; i++) {
+    ASSERT_THAT(RetryEINTR(write)(sock_.get(), buf, sizeof(buf)),
+                SyscallSucceedsWithValue(sizeof(buf)));
+  }
+
+  struct pollfd pfd = {bind_.get(), POLLIN, 0};
+  ASSERT_THAT(RetryEINTR(poll)(&pfd, 1, /*timeout=*/1000),
+              SyscallSucceedsWithValue(1));
+
+  // The control message doesn't fit in the buffer, so it is truncated.
+  constexpr size_t kControlLen = CMSG_LEN(sizeof(struct timeval)) - 1;
+  char cmsgbuf[kControlLen];
+  char received[sizeof(buf)];
+  iovec iov = {received, sizeof(received)};
+  msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = cmsgbuf;
+  msg.msg_controllen = sizeof(cmsgbuf);
+
+  ASSERT_THAT(RetryEINTR(recvmsg)(bind_.get(), &msg, 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(msg.msg_flags & MSG_CTRUNC, MSG_CTRUNC);
+  EXPECT_EQ(msg.msg_controllen, kControlLen);
+  struct cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  ASSERT_NE(cmsg, nullptr);
+  EXPECT_EQ(cmsg->cmsg_level, SOL_SOCKET);
+  EXPECT_EQ(cmsg->cmsg_type, SO_TIMESTAMP);
+  EXPECT_EQ(cmsg->cmsg_len, kControlLen);
+
+  // Without a control buffer, the control message is dropped entirely.
+  msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  ASSERT_THAT(RetryEINTR(recvmsg)(bind_.get(), &msg, 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_EQ(msg.msg_flags & MSG_CTRUNC, MSG_CTRUNC);
+}
+
 TEST_P(UdpSocketTest, WriteShutdownNotConnected) {
   EXPECT_THAT(shutdown(bind_.get(), SHUT_WR), SyscallFailsWithErrno(ENOTCONN));
 }
@@ -2063,6 +2111,53 @@ TEST_P(UdpSocketTest, ConnectToZeroPortConnected) {
               SyscallFailsWithErrno(ENOTCONN));
 }
 
+TEST_P(UdpSocketTest, MTUDiscover) {
+  int level = SOL_IP;
+  int mtu_discover = IP_MTU_DISCOVER;
+  int mtu = IP_MTU;
+  if (GetParam() != AddressFamily::kIpv4) {
+    level = SOL_IPV6;
+    mtu_discover = IPV6_MTU_DISCOVER;
+    mtu = IPV6_MTU;
+  }
+
+  for (int v : {IP_PMTUDISC_DONT, IP_PMTUDISC_WANT, IP_PMTUDISC_DO,
+                IP_PMTUDISC_PROBE}) {
+    ASSERT_THAT(setsockopt(sock_.get(), level, mtu_discover, &v, sizeof(v)),
+                SyscallSucceeds());
+
+    int got = -1;
+    socklen_t len = sizeof(got);
+    ASSERT_THAT(getsockopt(sock_.get(), level, mtu_discover, &got, &len),
+                SyscallSucceeds());
+    EXPECT_EQ(len, sizeof(got));
+    EXPECT_EQ(got, v);
+  }
+
+  // The path MTU is only available on connected sockets.
+  int got = -1;
+  socklen_t len = sizeof(got);
+  EXPECT_THAT(getsockopt(sock_.get(), level, mtu, &got, &len),
+              SyscallFailsWithErrno(ENOTCONN));
+
+  ASSERT_NO_ERRNO(BindLoopback());
+  ASSERT_THAT(connect(sock_.get(), bind_addr_, addrlen_), SyscallSucceeds());
+  ASSERT_THAT(getsockopt(sock_.get(), level, mtu, &got, &len),
+              SyscallSucceeds());
+  EXPECT_EQ(len, sizeof(got));
+  EXPECT_GT(got, 0);
+
+  // A datagram that fits in the path MTU can be sent with DF set.
+  int v = IP_PMTUDISC_DO;
+  ASSERT_THAT(setsockopt(sock_.get(), level, mtu_discover, &v, sizeof(v)),
+              SyscallSucceeds());
+  char buf[512] = {};
+  EXPECT_THAT(send(sock_.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+  EXPECT_THAT(recv(bind_.get(), buf, sizeof(buf), 0),
+              SyscallSucceedsWithValue(sizeof(buf)));
+}
+
 INSTANTIATE_TEST_SUITE_P(AllInetTests, UdpSocketTest,
                          ::testing::Values(AddressFamily::kIpv4,
                                            AddressFamily::kIpv6,
@@ -13,6 +13,7 @@
 // limitations under the License.
 
 #include <fcntl.h>
+#include <sys/mount.h>
 #include <sys/stat.h>
 #include <sys/syscall.h>
 #include <sys/time.h>
@@ -24,8 +25,10 @@
 #include <string>
 
 #include "absl/time/time.h"
+#include "test/util/capability_util.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/fs_util.h"
+#include "test/util/mount_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
@@ -314,6 +317,35 @@ TEST(Utimensat, NullPath) {
   EXPECT_EQ(20, statbuf.st_mtime);
 }
 
+TEST(UtimensatTest, ReadOnlyMount) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", dir.path(), "tmpfs", MS_RDONLY, "mode=0777", 0));
+
+  // Timestamps can't be changed on a read-only mount, even by the owner and
+  // even to the current time.
+  const struct timespec times[2] = {{10, 0}, {20, 0}};
+  EXPECT_THAT(utimensat(AT_FDCWD, dir.path().c_str(), times, 0),
+              SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(utimensat(AT_FDCWD, dir.path().c_str(), nullptr, 0),
+              SyscallFailsWithErrno(EROFS));
+  EXPECT_THAT(utimes(dir.path().c_str(), nullptr),
+              SyscallFailsWithErrno(EROFS));
+
+  // Likewise through a file descriptor.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(syscall(SYS_utimensat, fd.get(), nullptr, times, 0),
+              SyscallFailsWithErrno(EROFS));
+
+  // Omitting both timestamps is a no-op, which succeeds.
+  const struct timespec omit[2] = {{0, UTIME_OMIT}, {0, UTIME_OMIT}};
+  EXPECT_THAT(utimensat(AT_FDCWD, dir.path().c_str(), omit, 0),
+              SyscallSucceeds());
+}
+
 }  // namespace
 
 }  // namespace testing